@@ -0,0 +1,176 @@
+package a11y
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Accessibility settings
+const (
+	ModeAuto = "auto" // On when a screen reader is detected
+	ModeOn   = "on"
+	ModeOff  = "off"
+)
+
+// enabled is set once standard output is routed through the plain filter
+var enabled bool
+
+// terminal is the real standard output, for programs that need the TTY
+var terminal = os.Stdout
+
+// symbolWords replaces status symbols that carry meaning with words a screen
+// reader announces; other emoji are dropped
+var symbolWords = map[rune]string{
+	'✅': "OK:",
+	'❌': "Error:",
+	'⚠': "Warning:",
+	'💡': "Tip:",
+	'🔒': "Locked:",
+	'🔴': "Down:",
+	'🟢': "Up:",
+	'⏭': "Skipped:",
+	'→': "-",
+	'•': "-",
+}
+
+// Enable routes standard output through a filter that rewrites it as plain
+// text for screen readers. The returned function flushes the filter and
+// restores standard output; call it before exiting.
+func Enable() (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable accessible output: %w", err)
+	}
+	os.Stdout = w
+	enabled = true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		copyPlain(terminal, r)
+	}()
+	return func() {
+		w.Close()
+		<-done
+		os.Stdout = terminal
+		enabled = false
+	}, nil
+}
+
+// Enabled reports whether accessible output is on
+func Enabled() bool {
+	return enabled
+}
+
+// Terminal returns the real standard output, for interactive programs such
+// as tmux that must draw on the terminal itself
+func Terminal() *os.File {
+	return terminal
+}
+
+// Announce states what DevOS is doing or waiting for, e.g. "Awaiting
+// confirmation: 3 commands". Sighted users see the same information from
+// layout, so nothing is printed unless accessible output is on.
+func Announce(format string, args ...interface{}) {
+	if enabled {
+		fmt.Printf("[%s]\n", fmt.Sprintf(format, args...))
+	}
+}
+
+// copyPlain copies src to dst line by line through Plain. Lines redrawn with
+// carriage returns, such as progress bars, are only written in their final
+// state; a partial line such as a prompt is written as soon as it arrives.
+func copyPlain(dst io.Writer, src io.Reader) {
+	reader := bufio.NewReader(src)
+	var line strings.Builder
+	redrawn := false
+	written := 0 // Bytes of the current line's plain text already written
+	buf := make([]byte, 4096)
+	for {
+		n, err := reader.Read(buf)
+		for _, r := range string(buf[:n]) {
+			switch r {
+			case '\r':
+				line.Reset()
+				redrawn = true
+			case '\n':
+				raw, text := line.String(), Plain(line.String())
+				switch {
+				case written > 0:
+					fmt.Fprintln(dst, text[min(written, len(text)):])
+				case strings.TrimSpace(text) == "" && strings.TrimSpace(raw) != "":
+					// Rules and box borders carry no content
+				default:
+					fmt.Fprintln(dst, text)
+				}
+				line.Reset()
+				redrawn, written = false, 0
+			default:
+				line.WriteRune(r)
+			}
+		}
+
+		// Show prompts that wait for input without a newline
+		if !redrawn && reader.Buffered() == 0 {
+			if text := Plain(line.String()); len(text) > written {
+				fmt.Fprint(dst, text[written:])
+				written = len(text)
+			}
+		}
+		if err != nil {
+			if written > 0 || line.Len() > 0 {
+				fmt.Fprintln(dst)
+			}
+			return
+		}
+	}
+}
+
+// Plain rewrites one line of output for screen readers: status symbols become
+// words, and other emoji and box-drawing characters are removed along with
+// the spacing that followed them
+func Plain(line string) string {
+	var b strings.Builder
+	removed := false
+	for _, r := range line {
+		if word, ok := symbolWords[r]; ok {
+			b.WriteString(word + " ")
+			removed = true
+			continue
+		}
+		if isBoxDrawing(r) || isEmoji(r) {
+			removed = true
+			continue
+		}
+		if r == ' ' && removed && (b.Len() == 0 || strings.HasSuffix(b.String(), " ")) {
+			continue
+		}
+		removed = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isBoxDrawing reports box-drawing and block characters, used for banners,
+// separators, and progress bars
+func isBoxDrawing(r rune) bool {
+	return r >= 0x2500 && r <= 0x259F
+}
+
+// isEmoji reports pictographs, dingbats, and the joiners and variation
+// selectors that combine them
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // Pictographs, emoticons, transport, flags
+		r >= 0x2600 && r <= 0x27BF, // Miscellaneous symbols and dingbats
+		r >= 0x2B00 && r <= 0x2BFF, // Arrows and stars such as ⭐
+		r >= 0x2300 && r <= 0x23FF, // Technical symbols such as ⏱
+		r >= 0x2190 && r <= 0x21FF, // Arrows such as ↩
+		r == 0x200D, r == 0xFE0F, r == 0x20E3:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrModelNotFound is returned when a provider does not have the model
+var ErrModelNotFound = errors.New("model not found")
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string // user or assistant
+	Content string
+}
+
+// Request asks a provider for a completion
+type Request struct {
+	Model         string // Provider's default if empty
+	System        string
+	Messages      []Message
+	MaxTokens     int
+	Temperature   float64
+	ContextLength int               // Context window to load a local model with; 0 for its default
+	JSON          bool              // Ask for a JSON reply
+	APIKey        string            // Credentials for cloud providers
+	BaseURL       string            // Provider's default if empty
+	Safety        map[string]string // Harm category to blocking threshold, for providers with safety filters
+	Deployment    string            // Deployment serving the model, for providers that route by deployment; Model if empty
+	APIVersion    string            // API version, for providers that version their API per request
+}
+
+// Prompt returns the text of the request's user messages
+func (r Request) Prompt() string {
+	var prompt string
+	for _, m := range r.Messages {
+		if m.Role == "user" {
+			if prompt != "" {
+				prompt += "\n\n"
+			}
+			prompt += m.Content
+		}
+	}
+	return prompt
+}
+
+// Response is a provider's completion
+type Response struct {
+	Text         string
+	Truncated    bool // The reply stopped at MaxTokens
+	InputTokens  int
+	OutputTokens int
+}
+
+// Provider is a backend that completes prompts. Providers register
+// themselves by name, so the executor can call any of them alike.
+type Provider interface {
+	// Name is what "ai_provider" in config selects the provider with
+	Name() string
+	// Complete returns the reply to a request
+	Complete(ctx context.Context, request Request) (*Response, error)
+	// Stream returns the reply to a request like Complete, passing its text
+	// to onText as it arrives. Providers that cannot stream pass the whole
+	// text once it is complete.
+	Stream(ctx context.Context, request Request, onText func(text string)) (*Response, error)
+	// CountTokens returns how many input tokens a request would use
+	CountTokens(ctx context.Context, request Request) (int, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Register makes a provider available by its name, replacing any provider
+// registered under the same name
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Lookup returns the provider registered under name
+func Lookup(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names returns the registered providers' names, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EstimateTokens approximates a request's input tokens, at about four
+// characters per token, for providers that cannot count them
+func EstimateTokens(request Request) int {
+	n := len(request.System)
+	for _, m := range request.Messages {
+		n += len(m.Content)
+	}
+	return (n + 3) / 4
+}
@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"devos/internal/anthropic"
+	"devos/internal/gemini"
+	"devos/internal/ollama"
+	"devos/internal/openai"
+)
+
+// The providers called from Go; the rest go through the Python engine
+func init() {
+	Register(anthropicProvider{})
+	Register(azureOpenAI)
+	Register(openAICompatible)
+	Register(geminiProvider{})
+	Register(ollamaProvider{})
+}
+
+// anthropicProvider calls Anthropic's Messages API
+type anthropicProvider struct{}
+
+func (anthropicProvider) Name() string { return "anthropic" }
+
+func (anthropicProvider) request(request Request) anthropic.Request {
+	messages := make([]anthropic.Message, len(request.Messages))
+	for i, m := range request.Messages {
+		messages[i] = anthropic.Message{Role: m.Role, Content: m.Content}
+	}
+	return anthropic.Request{
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		System:      request.System,
+		Messages:    messages,
+	}
+}
+
+func (anthropicProvider) response(resp *anthropic.Response) *Response {
+	return &Response{
+		Text:         resp.Text(),
+		Truncated:    resp.StopReason == "max_tokens",
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}
+}
+
+func (p anthropicProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	resp, err := anthropic.New(request.APIKey, request.BaseURL).Messages(ctx, p.request(request))
+	if err != nil {
+		return nil, err
+	}
+	return p.response(resp), nil
+}
+
+func (p anthropicProvider) Stream(ctx context.Context, request Request, onText func(string)) (*Response, error) {
+	resp, err := anthropic.New(request.APIKey, request.BaseURL).MessagesStream(ctx, p.request(request), onText)
+	if err != nil {
+		return nil, err
+	}
+	return p.response(resp), nil
+}
+
+func (p anthropicProvider) CountTokens(ctx context.Context, request Request) (int, error) {
+	return anthropic.New(request.APIKey, request.BaseURL).CountTokens(ctx, p.request(request))
+}
+
+// chatProvider calls a chat completions API: an OpenAI-compatible server,
+// or a model deployed to Azure OpenAI
+type chatProvider struct {
+	name        string
+	client      func(request Request) *openai.Client
+	deployments bool // The request's Deployment, if any, names the model
+	jsonMode    bool // The API takes response_format json_object
+}
+
+var (
+	// openAICompatible calls any server with OpenAI's chat completions API,
+	// such as LM Studio, vLLM, OpenRouter, or llama.cpp's server, at the
+	// request's BaseURL. Not all of them take JSON mode, so replies are
+	// JSON by the prompt alone.
+	openAICompatible = chatProvider{name: "openai-compatible", client: func(request Request) *openai.Client {
+		return openai.New(request.APIKey, request.BaseURL)
+	}}
+
+	// azureOpenAI calls a deployment of an Azure OpenAI resource, whose
+	// endpoint is the request's BaseURL
+	azureOpenAI = chatProvider{name: "azure-openai", deployments: true, jsonMode: true, client: func(request Request) *openai.Client {
+		return openai.NewAzure(request.BaseURL, request.APIKey, request.APIVersion)
+	}}
+)
+
+func (p chatProvider) Name() string { return p.name }
+
+func (p chatProvider) request(request Request) openai.Request {
+	var messages []openai.Message
+	if request.System != "" {
+		messages = append(messages, openai.Message{Role: "system", Content: request.System})
+	}
+	for _, m := range request.Messages {
+		messages = append(messages, openai.Message{Role: m.Role, Content: m.Content})
+	}
+	model := request.Model
+	if p.deployments && request.Deployment != "" {
+		model = request.Deployment
+	}
+	return openai.Request{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		JSON:        request.JSON && p.jsonMode,
+	}
+}
+
+func (p chatProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	return p.Stream(ctx, request, nil)
+}
+
+// Stream asks for the reply in one piece when onText is nil
+func (p chatProvider) Stream(ctx context.Context, request Request, onText func(string)) (*Response, error) {
+	client := p.client(request)
+	chat := p.request(request)
+	var resp *openai.Response
+	var err error
+	if onText == nil {
+		resp, err = client.Chat(ctx, chat)
+	} else {
+		resp, err = client.ChatStream(ctx, chat, onText)
+	}
+	if err != nil {
+		var apiErr *openai.Error
+		if errors.As(err, &apiErr) && (apiErr.Code == "DeploymentNotFound" || apiErr.Code == "model_not_found") {
+			if p.deployments {
+				return nil, fmt.Errorf("Azure OpenAI has no deployment %s; check \"azure_deployment\" in config: %w", chat.Model, err)
+			}
+			return nil, fmt.Errorf("the server at %s has no model %s; check \"model\" in config: %w", request.BaseURL, chat.Model, err)
+		}
+		return nil, err
+	}
+	if resp.FinishReason == "content_filter" {
+		return nil, fmt.Errorf("the %s content filter stopped the reply", p.name)
+	}
+	return &Response{
+		Text:         resp.Content,
+		Truncated:    resp.FinishReason == "length",
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+	}, nil
+}
+
+// CountTokens estimates, since chat completions APIs have no endpoint that
+// counts tokens
+func (chatProvider) CountTokens(ctx context.Context, request Request) (int, error) {
+	return EstimateTokens(request), nil
+}
+
+// geminiProvider calls Gemini's generateContent API, passing the request's
+// safety settings along
+type geminiProvider struct{}
+
+func (geminiProvider) Name() string { return "gemini" }
+
+func (geminiProvider) request(request Request) gemini.Request {
+	return gemini.Request{
+		Model:       request.Model,
+		System:      request.System,
+		Prompt:      request.Prompt(),
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		JSON:        request.JSON,
+		Safety:      request.Safety,
+	}
+}
+
+func (p geminiProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	resp, err := gemini.New(request.APIKey, request.BaseURL).Generate(ctx, p.request(request))
+	if err != nil {
+		return nil, err
+	}
+	return p.response(resp)
+}
+
+func (p geminiProvider) Stream(ctx context.Context, request Request, onText func(string)) (*Response, error) {
+	resp, err := gemini.New(request.APIKey, request.BaseURL).GenerateStream(ctx, p.request(request), onText)
+	if err != nil {
+		return nil, err
+	}
+	return p.response(resp)
+}
+
+func (geminiProvider) response(resp *gemini.Response) (*Response, error) {
+	switch {
+	case resp.BlockReason != "":
+		return nil, fmt.Errorf("Gemini blocked the request (%s); see \"gemini_safety\" in config", resp.BlockReason)
+	case resp.FinishReason == "SAFETY":
+		return nil, fmt.Errorf("Gemini's safety filters stopped the reply; see \"gemini_safety\" in config")
+	}
+	return &Response{
+		Text:         resp.Text,
+		Truncated:    resp.FinishReason == "MAX_TOKENS",
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+	}, nil
+}
+
+func (p geminiProvider) CountTokens(ctx context.Context, request Request) (int, error) {
+	return gemini.New(request.APIKey, request.BaseURL).CountTokens(ctx, p.request(request))
+}
+
+// ollamaProvider calls a local Ollama model through its REST API
+type ollamaProvider struct{}
+
+func (ollamaProvider) Name() string { return "ollama" }
+
+func (p ollamaProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	return p.Stream(ctx, request, nil)
+}
+
+// Stream asks for the reply in one piece when onText is nil
+func (ollamaProvider) Stream(ctx context.Context, request Request, onText func(string)) (*Response, error) {
+	var messages []ollama.ChatMessage
+	if request.System != "" {
+		messages = append(messages, ollama.ChatMessage{Role: "system", Content: request.System})
+	}
+	for _, m := range request.Messages {
+		messages = append(messages, ollama.ChatMessage{Role: m.Role, Content: m.Content})
+	}
+	resp, err := ollama.New(request.BaseURL).ChatStream(ctx, ollama.ChatRequest{
+		Model:         request.Model,
+		Messages:      messages,
+		Temperature:   request.Temperature,
+		MaxTokens:     request.MaxTokens,
+		ContextLength: request.ContextLength,
+		JSON:          request.JSON,
+	}, onText)
+	if err != nil {
+		if ollama.IsModelNotFound(err.Error()) {
+			return nil, fmt.Errorf("%w: %s is not pulled in Ollama", ErrModelNotFound, request.Model)
+		}
+		return nil, err
+	}
+	return &Response{
+		Text:         resp.Content,
+		Truncated:    resp.DoneReason == "length",
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+	}, nil
+}
+
+// CountTokens estimates, since Ollama has no endpoint that counts tokens
+// without generating
+func (ollamaProvider) CountTokens(ctx context.Context, request Request) (int, error) {
+	return EstimateTokens(request), nil
+}
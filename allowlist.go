@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"devos/internal/policy"
+)
+
+// AllowlistRule names the allowlist_only check in policy triggers and
+// denials; like the guardrails, it cannot be relaxed by an elevated session
+const AllowlistRule = "allowlist"
+
+// allowlistSeparator splits a command into the commands it runs: chained,
+// piped, backgrounded, or on separate lines
+var allowlistSeparator = regexp.MustCompile(`&&|\|\||[;&|\n]`)
+
+// allowlistUnchecked are shell constructs that run commands the allowlist
+// cannot see, so commands with them are refused outright
+var allowlistUnchecked = []string{"$(", "`", "<(", ">("}
+
+// allowlistRedirect captures the targets of output redirections, which
+// write files whatever the command is
+var allowlistRedirect = regexp.MustCompile(`>{1,2}\s*([^&\s]\S*)`)
+
+// allowlistDuplicate matches the & of redirections such as 2>&1 and &>,
+// which does not separate commands
+var allowlistDuplicate = regexp.MustCompile(`>&|&>`)
+
+// checkAllowlist refuses commands when allowlist_only is set and they run
+// anything allowed_commands does not list
+func (e *Executor) checkAllowlist(commands []string) error {
+	if !e.config.AllowlistOnly {
+		return nil
+	}
+	for _, cmd := range commands {
+		if reason := e.notAllowed(cmd); reason != "" {
+			e.recordTrigger(AllowlistRule, policy.ActionDeny, reason, cmd)
+			return fmt.Errorf("%w: rule %q (allowlist_only): %s", ErrValidationBlocked, AllowlistRule, reason)
+		}
+	}
+	return nil
+}
+
+// notAllowed returns why the allowlist refuses cmd, or "" when every
+// command it runs starts with an allowed_commands entry. An entry is a
+// program ("git"), allowing it with any arguments, or a longer prefix
+// ("git pull", "systemctl status"), matched word by word. Output may only
+// be redirected to /dev/null.
+func (e *Executor) notAllowed(cmd string) string {
+	for _, construct := range allowlistUnchecked {
+		if strings.Contains(cmd, construct) {
+			return fmt.Sprintf("%q uses %s, which runs commands the allowlist cannot check", cmd, construct)
+		}
+	}
+	for _, m := range allowlistRedirect.FindAllStringSubmatch(cmd, -1) {
+		if m[1] != "/dev/null" {
+			return fmt.Sprintf("%q redirects output to %s, which the allowlist does not allow", cmd, m[1])
+		}
+	}
+	for _, segment := range allowlistSeparator.Split(allowlistDuplicate.ReplaceAllString(cmd, ">"), -1) {
+		words := strings.Fields(segment)
+		if len(words) == 0 {
+			continue
+		}
+		if !e.allowed(words) {
+			return fmt.Sprintf("%q is not in allowed_commands", strings.Join(words, " "))
+		}
+	}
+	return ""
+}
+
+// allowed reports whether a command's words start with an allowlist entry
+func (e *Executor) allowed(words []string) bool {
+	for _, entry := range e.config.AllowedCommands {
+		prefix := strings.Fields(entry)
+		if len(prefix) == 0 || len(prefix) > len(words) {
+			continue
+		}
+		if !allowedProgram(words[0], prefix[0]) {
+			continue
+		}
+		matched := true
+		for i := 1; i < len(prefix); i++ {
+			if words[i] != prefix[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedProgram reports whether program is the one an allowlist entry
+// names. An entry with a path allows that path only; one without allows
+// the program the shell finds for it through PATH, by name or by the path
+// it resolves to, never a same-named program elsewhere.
+func allowedProgram(program, entry string) bool {
+	if program == entry {
+		return true
+	}
+	if strings.ContainsAny(entry, `/\`) || !strings.ContainsAny(program, `/\`) {
+		return false
+	}
+	resolved, err := exec.LookPath(entry)
+	if err != nil {
+		return false
+	}
+	resolved, err = filepath.Abs(resolved)
+	return err == nil && resolved == filepath.Clean(program)
+}
@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"devos/internal/config"
+	"devos/internal/logger"
+)
+
+func TestNotAllowed(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	e := &Executor{
+		config: &config.Config{
+			AllowlistOnly:   true,
+			AllowedCommands: []string{"git", "ls", "systemctl status", "/opt/tools/deploy"},
+		},
+		logger: logger.New("error"),
+	}
+	tests := []struct {
+		cmd     string
+		allowed bool
+	}{
+		{"git pull", true},
+		{"git pull && ls -la", true},
+		{"ls\ngit status", true},
+		{"ls 2>&1 | git log", true},
+		{"ls &> /dev/null", true},
+		{"ls > /dev/null", true},
+		{"systemctl status nginx", true},
+		{"/opt/tools/deploy --env staging", true},
+
+		{"systemctl restart nginx", false},
+		{"rm -rf build", false},
+		{"git pull; rm -rf build", false},
+		{"ls & curl https://example.com", false},
+		{"ls > files.txt", false},
+		{"git log >> ~/.bashrc", false},
+		{"echo $(git status)", false},
+		{"ls `pwd`", false},
+		{"deploy --env staging", false},
+		{"/tmp/git status", false},
+		{"gitk", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			reason := e.notAllowed(tt.cmd)
+			if (reason == "") != tt.allowed {
+				t.Errorf("notAllowed(%q) = %q, want allowed %v", tt.cmd, reason, tt.allowed)
+			}
+			err := e.checkAllowlist([]string{tt.cmd})
+			if tt.allowed != (err == nil) || (err != nil && !errors.Is(err, ErrValidationBlocked)) {
+				t.Errorf("checkAllowlist(%q) = %v, want allowed %v", tt.cmd, err, tt.allowed)
+			}
+		})
+	}
+
+	e.config.AllowlistOnly = false
+	if err := e.checkAllowlist([]string{"rm -rf build"}); err != nil {
+		t.Errorf("checkAllowlist without allowlist_only = %v", err)
+	}
+}
+
+func TestAllowedProgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("PATH lookup needs an executable extension on Windows")
+	}
+	bin := t.TempDir()
+	tool := filepath.Join(bin, "tool")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	tests := []struct {
+		program string
+		entry   string
+		want    bool
+	}{
+		{"tool", "tool", true},
+		{tool, "tool", true},
+		{filepath.Join(bin, ".", "tool"), "tool", true},
+		{"/usr/local/bin/tool", "tool", false},
+		{filepath.Join(t.TempDir(), "tool"), "tool", false},
+		{"tool", tool, false},
+		{tool, tool, true},
+		{"other", "tool", false},
+		{"/usr/bin/missing", "missing", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.program+" "+tt.entry, func(t *testing.T) {
+			if got := allowedProgram(tt.program, tt.entry); got != tt.want {
+				t.Errorf("allowedProgram(%q, %q) = %v, want %v", tt.program, tt.entry, got, tt.want)
+			}
+		})
+	}
+}
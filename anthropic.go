@@ -0,0 +1,268 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultURL is the Anthropic API
+const DefaultURL = "https://api.anthropic.com"
+
+// DefaultModel is used when no model is configured
+const DefaultModel = "claude-sonnet-4-5"
+
+// DefaultMaxTokens caps replies when no limit is configured; the API
+// requires one
+const DefaultMaxTokens = 4096
+
+// apiVersion is the Messages API version requests are written against
+const apiVersion = "2023-06-01"
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string `json:"role"` // user or assistant
+	Content string `json:"content"`
+}
+
+// Request asks for the next assistant message
+type Request struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+// Response is the assistant's reply
+type Response struct {
+	Model      string  `json:"model"`
+	StopReason string  `json:"stop_reason"`
+	Content    []Block `json:"content"`
+	Usage      Usage   `json:"usage"`
+}
+
+// Block is one piece of a reply's content
+type Block struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// Usage counts the tokens a request used
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Text returns the reply's text blocks joined together
+func (r *Response) Text() string {
+	var b strings.Builder
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			b.WriteString(block.Text)
+		}
+	}
+	return b.String()
+}
+
+// Error is an error the API returned
+type Error struct {
+	Status  int
+	Type    string // e.g. authentication_error, rate_limit_error, overloaded_error
+	Message string
+}
+
+// HTTPStatus returns the response's status code, for retry decisions
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Anthropic API error %d (%s): %s", e.Status, e.Type, e.Message)
+}
+
+// Client calls the Messages API
+type Client struct {
+	apiKey  string
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a client using apiKey, for the API at baseURL (DefaultURL if
+// empty)
+func New(apiKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &Client{apiKey: apiKey, baseURL: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+}
+
+// Messages sends a conversation and returns the assistant's reply
+func (c *Client) Messages(ctx context.Context, request Request) (*Response, error) {
+	if request.Model == "" {
+		request.Model = DefaultModel
+	}
+	if request.MaxTokens <= 0 {
+		request.MaxTokens = DefaultMaxTokens
+	}
+	var response Response
+	if err := c.post(ctx, "/v1/messages", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CountTokens returns how many input tokens a request would use
+func (c *Client) CountTokens(ctx context.Context, request Request) (int, error) {
+	if request.Model == "" {
+		request.Model = DefaultModel
+	}
+	payload := map[string]interface{}{"model": request.Model, "messages": request.Messages}
+	if request.System != "" {
+		payload["system"] = request.System
+	}
+	var count struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := c.post(ctx, "/v1/messages/count_tokens", payload, &count); err != nil {
+		return 0, err
+	}
+	return count.InputTokens, nil
+}
+
+// MessagesStream sends a conversation, passing the reply's text to onText
+// as it is generated, and returns the whole reply
+func (c *Client) MessagesStream(ctx context.Context, request Request, onText func(text string)) (*Response, error) {
+	if request.Model == "" {
+		request.Model = DefaultModel
+	}
+	if request.MaxTokens <= 0 {
+		request.MaxTokens = DefaultMaxTokens
+	}
+	request.Stream = true
+	resp, err := c.send(ctx, "/v1/messages", request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Server-sent events, each a JSON object on a "data:" line
+	response := &Response{Model: request.Model}
+	var text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type    string   `json:"type"`
+			Message Response `json:"message"`
+			Delta   struct {
+				Type       string `json:"type"`
+				Text       string `json:"text"`
+				StopReason string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage Usage `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse Anthropic event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			response.Model = event.Message.Model
+			response.Usage.InputTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				text.WriteString(event.Delta.Text)
+				onText(event.Delta.Text)
+			}
+		case "message_delta":
+			response.StopReason = event.Delta.StopReason
+			response.Usage.OutputTokens = event.Usage.OutputTokens
+		case "message_stop":
+			response.Content = []Block{{Type: "text", Text: text.String()}}
+			return response, nil
+		case "error":
+			// The stream has already answered 200, so errors such as an
+			// overloaded API arrive as events
+			status := http.StatusInternalServerError
+			if event.Error.Type == "overloaded_error" {
+				status = 529
+			}
+			return nil, &Error{Status: status, Type: event.Error.Type, Message: event.Error.Message}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Anthropic response: %w", err)
+	}
+	return nil, fmt.Errorf("Anthropic's reply ended early")
+}
+
+// post sends payload as JSON to path and decodes the reply into out
+func (c *Client) post(ctx context.Context, path string, payload, out interface{}) error {
+	resp, err := c.send(ctx, path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Anthropic response: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse Anthropic response: %w", err)
+	}
+	return nil
+}
+
+// send posts payload as JSON to path, returning the response when it
+// succeeds; the caller closes its body
+func (c *Client) send(ctx context.Context, path string, payload interface{}) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Anthropic API is not reachable at %s: %w", c.baseURL, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Anthropic response: %w", err)
+	}
+	var failure struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &failure) != nil || failure.Error.Message == "" {
+		failure.Error.Type, failure.Error.Message = "unknown", strings.TrimSpace(string(data))
+	}
+	return nil, &Error{Status: resp.StatusCode, Type: failure.Error.Type, Message: failure.Error.Message}
+}
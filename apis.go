@@ -0,0 +1,30 @@
+package executor
+
+import (
+	"devos/internal/openapi"
+)
+
+// maxAPIOperations bounds how many operations of one API a request carries
+const maxAPIOperations = 25
+
+// APIDir is where registered OpenAPI specs are kept
+func (e *Executor) APIDir() string {
+	return openapi.Dir(e.config.Dir())
+}
+
+// apiContext summarizes the registered APIs a request mentions, with the
+// operations most relevant to it, so plans can call them correctly
+func (e *Executor) apiContext(input string) []openapi.Summary {
+	specs, err := openapi.Load(e.APIDir())
+	if err != nil {
+		e.logger.Warn("Failed to load API specs: %v", err)
+		return nil
+	}
+	var summaries []openapi.Summary
+	for _, spec := range specs {
+		if spec.Mentions(input) {
+			summaries = append(summaries, spec.Summarize(input, maxAPIOperations))
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"devos/internal/executor"
+)
+
+// API versions. Each version's payloads are frozen once released: new or
+// renamed fields go into a new version, and older versions keep being
+// rendered from the same plans so existing clients continue to work.
+const (
+	APIv1 = "v1"
+	APIv2 = "v2"
+
+	// CurrentAPI is served on unversioned paths when a client does not ask
+	// for a version
+	CurrentAPI = APIv2
+)
+
+// SupportedAPIs lists the versions this daemon serves, oldest first
+var SupportedAPIs = []string{APIv1, APIv2}
+
+// versionHeader names the request header clients use to choose a version on
+// unversioned paths; responses always carry the version they were rendered in
+const versionHeader = "DevOS-API-Version"
+
+// PlanV2 is the v2 representation of a plan. Compared to v1 it groups the
+// approval state, renames requested_by to requester and high_risk to risk,
+// and includes the full generated result.
+type PlanV2 struct {
+	SchemaVersion string     `json:"schema_version"`
+	ID            string     `json:"id"`
+	Input         string     `json:"input"`
+	Requester     string     `json:"requester"`
+	Risk          string     `json:"risk"` // "high" or "normal"
+	Environment   string     `json:"environment,omitempty"`
+	Status        string     `json:"status"`
+	Approval      ApprovalV2 `json:"approval"`
+	Result        ResultV2   `json:"result"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ApprovalV2 is a plan's approval state
+type ApprovalV2 struct {
+	Required      int                     `json:"required"`
+	ApprovedBy    []string                `json:"approved_by"`
+	RejectedBy    string                  `json:"rejected_by,omitempty"`
+	Confirmations []executor.Confirmation `json:"confirmations,omitempty"` // What approving confirms the plan past
+}
+
+// ResultV2 is the generated plan as v2 clients see it
+type ResultV2 struct {
+	Output      string             `json:"output"`
+	Commands    []string           `json:"commands"`
+	Steps       []executor.Command `json:"steps,omitempty"`
+	Intent      string             `json:"intent,omitempty"`
+	Warnings    []string           `json:"warnings,omitempty"`
+	PolicyNotes []string           `json:"policy_notes,omitempty"`
+	Changes     *executor.Changes  `json:"changes,omitempty"`
+}
+
+// planListV2 wraps v2 plan lists so fields can be added next to them
+type planListV2 struct {
+	SchemaVersion string   `json:"schema_version"`
+	Plans         []PlanV2 `json:"plans"`
+}
+
+// apiVersion returns the version a request asks for and the path below the
+// version prefix. Unversioned paths negotiate with the DevOS-API-Version
+// header or an Accept type such as application/vnd.devos.v2+json.
+func apiVersion(r *http.Request) (string, string, bool) {
+	path := r.URL.Path
+	for _, v := range SupportedAPIs {
+		if rest, ok := strings.CutPrefix(path, "/"+v+"/"); ok {
+			return v, "/" + rest, true
+		}
+	}
+
+	requested := r.Header.Get(versionHeader)
+	if requested == "" {
+		for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+			media, _, _ := strings.Cut(strings.TrimSpace(accept), ";")
+			if v, ok := strings.CutPrefix(media, "application/vnd.devos."); ok {
+				requested = strings.TrimSuffix(v, "+json")
+				break
+			}
+		}
+	}
+	if requested == "" {
+		return CurrentAPI, path, true
+	}
+	for _, v := range SupportedAPIs {
+		if requested == v {
+			return v, path, true
+		}
+	}
+	return requested, path, false
+}
+
+// renderPlan returns a plan in the given version's shape
+func renderPlan(version string, plan Plan) interface{} {
+	if version == APIv1 {
+		return plan
+	}
+
+	v2 := PlanV2{
+		SchemaVersion: APIv2,
+		ID:            plan.ID,
+		Input:         plan.Input,
+		Requester:     plan.RequestedBy,
+		Risk:          "normal",
+		Environment:   plan.Environment,
+		Status:        plan.Status,
+		Approval: ApprovalV2{
+			Required:      plan.RequiredApprovals,
+			ApprovedBy:    plan.Approvals,
+			RejectedBy:    plan.RejectedBy,
+			Confirmations: plan.Confirmations,
+		},
+		Result:    ResultV2{Output: plan.Output, Commands: plan.Commands},
+		Error:     plan.Error,
+		CreatedAt: plan.CreatedAt,
+		UpdatedAt: plan.UpdatedAt,
+	}
+	if plan.HighRisk {
+		v2.Risk = "high"
+	}
+	if v2.Approval.ApprovedBy == nil {
+		v2.Approval.ApprovedBy = []string{}
+	}
+	if r := plan.result; r != nil {
+		v2.Result.Steps = r.Steps
+		v2.Result.Intent = r.Intent
+		v2.Result.Warnings = r.Warnings
+		v2.Result.PolicyNotes = r.PolicyNotes
+		// Execution records changes; they are safe to read once it finished
+		if plan.Status == StatusSucceeded || plan.Status == StatusFailed {
+			v2.Result.Changes = r.Changes
+		}
+	}
+	return v2
+}
+
+// renderPlans returns a plan list in the given version's shape
+func renderPlans(version string, plans []Plan) interface{} {
+	if version == APIv1 {
+		return plans
+	}
+
+	list := planListV2{SchemaVersion: APIv2, Plans: make([]PlanV2, 0, len(plans))}
+	for _, p := range plans {
+		list.Plans = append(list.Plans, renderPlan(version, p).(PlanV2))
+	}
+	return list
+}
+
+// handleVersions lets clients pick the newest version both sides support
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"versions": SupportedAPIs,
+		"current":  CurrentAPI,
+	})
+}
@@ -0,0 +1,96 @@
+package platform
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// archAliases maps each GOARCH to the names it goes by in download URLs
+var archAliases = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x64"},
+	"arm64": {"arm64", "aarch64"},
+}
+
+// archToken matches architecture names inside URLs and file names
+var archToken = regexp.MustCompile(`(?i)(amd64|x86_64|x64|arm64|aarch64)`)
+
+// downloadPattern matches commands fetching remote artifacts
+var downloadPattern = regexp.MustCompile(`\b(curl|wget|Invoke-WebRequest)\b.*https?://\S+`)
+
+// dockerBuildPattern matches docker image builds without an explicit platform
+var dockerBuildPattern = regexp.MustCompile(`\bdocker\s+(build|buildx\s+build)\b`)
+
+// detectEmulation reports the native architecture when running under
+// translation (e.g. Rosetta 2), or "" when running natively
+func detectEmulation() string {
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "amd64" &&
+		commandOutput("sysctl", "-n", "sysctl.proc_translated") == "1" {
+		return "arm64"
+	}
+	return ""
+}
+
+// EffectiveArch returns the hardware architecture, seeing through emulation
+func (i *Info) EffectiveArch() string {
+	if i.NativeArch != "" {
+		return i.NativeArch
+	}
+	return i.Arch
+}
+
+// ArchWarnings returns architecture pitfalls in cmd for this machine, each
+// with a suggested fix
+func (i *Info) ArchWarnings(cmd string) []string {
+	arch := i.EffectiveArch()
+	var warnings []string
+
+	if downloadPattern.MatchString(cmd) {
+		for _, token := range archToken.FindAllString(cmd, -1) {
+			if foreign := canonicalArch(token); foreign != "" && foreign != arch {
+				suggestion := strings.Replace(cmd, token, archAliasLike(token, arch), 1)
+				warnings = append(warnings, "downloads a "+foreign+" artifact on an "+arch+" machine; try: "+suggestion)
+				break
+			}
+		}
+	}
+
+	if arch == "arm64" && dockerBuildPattern.MatchString(cmd) && !strings.Contains(cmd, "--platform") {
+		suggestion := dockerBuildPattern.ReplaceAllString(cmd, "$0 --platform linux/amd64")
+		warnings = append(warnings, "images built on arm64 will not run on amd64 servers; for deployment use: "+suggestion)
+	}
+
+	return warnings
+}
+
+// canonicalArch returns the GOARCH name for an architecture alias
+func canonicalArch(token string) string {
+	token = strings.ToLower(token)
+	for arch, aliases := range archAliases {
+		for _, alias := range aliases {
+			if alias == token {
+				return arch
+			}
+		}
+	}
+	return ""
+}
+
+// archAliasLike returns the name of arch in the same naming style as token
+// (x86_64 ↔ aarch64, amd64 ↔ arm64)
+func archAliasLike(token, arch string) string {
+	switch strings.ToLower(token) {
+	case "x86_64", "aarch64":
+		if arch == "arm64" {
+			return "aarch64"
+		}
+		return "x86_64"
+	case "x64":
+		if arch == "arm64" {
+			return "arm64"
+		}
+		return "x64"
+	default:
+		return arch
+	}
+}
@@ -0,0 +1,207 @@
+package attach
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"devos/internal/ignore"
+	"devos/internal/project"
+)
+
+// DefaultMaxBytes is the largest file attached whole when the config does
+// not say; larger text files are summarized
+const DefaultMaxBytes = 64 << 10
+
+// Limits on what goes into a prompt: files beyond maxTotal bytes of
+// attachments are left out, and a summarized file keeps its first
+// summaryLines lines
+const (
+	maxTotal     = 256 << 10
+	summaryLines = 200
+	sniffBytes   = 8 << 10
+)
+
+// Minified bundles are mostly a few very long lines
+const (
+	minifiedLine    = 1000
+	minifiedAverage = 300
+)
+
+// File is a file attached to a prompt, whole or summarized
+type File struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"` // Content is the start of the file only
+}
+
+// Excluded is a file left out of a prompt, and why
+type Excluded struct {
+	Path   string
+	Reason string
+}
+
+// String describes the exclusion for the user
+func (x Excluded) String() string {
+	return x.Path + " (" + x.Reason + ")"
+}
+
+// Mentions returns the files an input mentions as @path, in order, skipping
+// mentions that are not existing files (e.g. user@host)
+func Mentions(input string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(input) {
+		path, ok := strings.CutPrefix(word, "@")
+		path = strings.TrimRight(path, ",;:!?)'\"")
+		if !ok || path == "" || seen[path] {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Collect reads files to attach to a prompt. Binaries, minified bundles,
+// files their project's .gitignore or .devosignore ignores, and files that
+// are unreadable or would take the attachments over the total limit are
+// excluded; text files over maxBytes are attached as their first lines.
+func Collect(paths []string, maxBytes int) ([]File, []Excluded) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	var files []File
+	var excluded []Excluded
+	total := 0
+	for _, path := range paths {
+		file, reason := File{}, "ignored by .gitignore or .devosignore"
+		if !ignored(path) {
+			file, reason = read(path, maxBytes)
+		}
+		if reason == "" && total+len(file.Content) > maxTotal {
+			reason = fmt.Sprintf("attachments are limited to %s in total", formatSize(maxTotal))
+		}
+		if reason != "" {
+			excluded = append(excluded, Excluded{Path: path, Reason: reason})
+			continue
+		}
+		total += len(file.Content)
+		files = append(files, file)
+	}
+	return files, excluded
+}
+
+// ignored reports whether the project a file is in ignores it, so that
+// secrets and build output are never sent
+func ignored(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return ignore.New(project.Root(filepath.Dir(abs))).Ignored(abs, false)
+}
+
+// read attaches one file, or returns why it is excluded
+func read(path string, maxBytes int) (File, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, "unreadable"
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return File{}, "unreadable"
+	}
+	size := info.Size()
+
+	// Read no more than is attached, plus enough to tell a binary or
+	// minified file
+	data, err := io.ReadAll(io.LimitReader(f, int64(max(maxBytes, sniffBytes))))
+	if err != nil {
+		return File{}, "unreadable"
+	}
+	head := data[:min(len(data), sniffBytes)]
+	if binary(head) {
+		kind, _, _ := strings.Cut(http.DetectContentType(head), ";")
+		return File{}, fmt.Sprintf("binary, %s, %s", kind, formatSize(size))
+	}
+	if minified(path, data) {
+		return File{}, fmt.Sprintf("minified, %s", formatSize(size))
+	}
+
+	file := File{Path: path, Size: size, Content: string(data)}
+	if size > int64(maxBytes) {
+		file.Content, file.Truncated = summarize(data[:min(len(data), maxBytes)]), true
+	}
+	return file, ""
+}
+
+// binary reports whether the start of a file looks like binary data: it has
+// NUL bytes or is not UTF-8. A multi-byte rune cut off at the end is fine.
+func binary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size == 1 && len(head)-i >= utf8.UTFMax {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// minified reports whether a file is a minified bundle, by name or by its
+// lines being few and very long
+func minified(path string, data []byte) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, suffix := range []string{".min.js", ".min.css", ".min.mjs", ".bundle.js"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	longest := 0
+	for _, line := range lines {
+		longest = max(longest, len(line))
+	}
+	return longest >= minifiedLine && len(data)/len(lines) >= minifiedAverage
+}
+
+// summarize keeps the first lines of an oversized file, noting how much of
+// it is attached
+func summarize(data []byte) string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 1 {
+		// The last line may be cut off
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > summaryLines {
+		lines = lines[:summaryLines]
+	}
+	return strings.Join(lines, "") + fmt.Sprintf("\n[... only the first %d lines are attached ...]\n", len(lines))
+}
+
+// formatSize renders a byte count for people
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
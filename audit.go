@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"devos/internal/timefmt"
+)
+
+// Event is a single security-relevant action recorded in the audit log
+type Event struct {
+	Time   time.Time         `json:"time"`
+	Type   string            `json:"type"`
+	User   string            `json:"user"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Sink receives a copy of every audit event, e.g. a shared database
+type Sink interface {
+	RecordEvent(event Event) error
+}
+
+// Trail appends audit events as JSON lines to a file
+type Trail struct {
+	mu        sync.Mutex
+	file      *os.File
+	user      string
+	sinks     []Sink
+	exporters []*Exporter
+}
+
+// Open opens (or creates) the audit log at the given path
+func Open(path string) (*Trail, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	return &Trail{file: file, user: name}, nil
+}
+
+// Close sends the events still queued for export and closes the audit log
+func (t *Trail) Close() error {
+	if t == nil || t.file == nil {
+		return nil
+	}
+	for _, e := range t.exporters {
+		e.Close()
+	}
+	return t.file.Close()
+}
+
+// AddSink copies subsequent events to sink as well as the log file
+func (t *Trail) AddSink(sink Sink) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sinks = append(t.sinks, sink)
+}
+
+// ReplaceSink drops old from the trail's sinks and adds sink in its place,
+// such as a memory store that was reopened. A nil sink only drops old.
+func (t *Trail) ReplaceSink(old, sink Sink) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, s := range t.sinks {
+		if s == old {
+			t.sinks = append(t.sinks[:i], t.sinks[i+1:]...)
+			break
+		}
+	}
+	if sink != nil {
+		t.sinks = append(t.sinks, sink)
+	}
+}
+
+// Export forwards subsequent events to a SIEM through e until the trail is
+// closed
+func (t *Trail) Export(e *Exporter) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sinks = append(t.sinks, e)
+	t.exporters = append(t.exporters, e)
+}
+
+// Record appends an event to the audit log
+func (t *Trail) Record(eventType string, fields map[string]string) error {
+	if t == nil || t.file == nil {
+		return nil
+	}
+
+	event := Event{
+		Time:   timefmt.Now(),
+		Type:   eventType,
+		User:   t.user,
+		Fields: fields,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := t.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+
+	for _, sink := range t.sinks {
+		if err := sink.RecordEvent(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is
+// configured
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// NewAzure returns a client for the Azure OpenAI resource at endpoint (e.g.
+// https://contoso.openai.azure.com) using apiKey, at apiVersion
+// (DefaultAzureAPIVersion if empty). Requests name a deployment as their
+// model.
+func NewAzure(endpoint, apiKey, apiVersion string) *Client {
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+	c := &Client{service: "Azure OpenAI", baseURL: strings.TrimRight(endpoint, "/"), deployments: true, http: &http.Client{}}
+	c.url = func(deployment string) (string, error) {
+		if c.baseURL == "" {
+			return "", fmt.Errorf("no Azure OpenAI endpoint is configured; set \"azure_endpoint\" in config")
+		}
+		if deployment == "" {
+			return "", fmt.Errorf("no Azure OpenAI deployment is configured; set \"azure_deployment\" in config")
+		}
+		return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			c.baseURL, url.PathEscape(deployment), url.QueryEscape(apiVersion)), nil
+	}
+	c.auth = func(req *http.Request) {
+		req.Header.Set("api-key", apiKey)
+	}
+	return c
+}
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"os/exec"
+	"strings"
+
+	"devos/internal/pkgmgr"
+	"devos/internal/policy"
+)
+
+// shellBuiltins are provided by the shell rather than found on PATH
+var shellBuiltins = map[string]bool{
+	"cd": true, "echo": true, "export": true, "source": true, ".": true, "set": true,
+	"unset": true, "test": true, "[": true, "true": true, "false": true, "exit": true,
+	"alias": true, "eval": true, "exec": true, "read": true, "pwd": true, "type": true,
+	"ulimit": true, "umask": true, "wait": true, "trap": true, "shift": true, "if": true,
+	"then": true, "else": true, "fi": true, "for": true, "do": true, "done": true,
+	"while": true, "case": true, "esac": true, "printf": true, "command": true,
+}
+
+// MissingBinaries returns executables used by commands that are not on PATH.
+// Tools installed by an earlier step of the same plan are not reported.
+func (e *Executor) MissingBinaries(commands []string) []string {
+	seen := make(map[string]bool)
+	var missing []string
+
+	for i, cmd := range commands {
+		for _, program := range policy.Programs(cmd) {
+			if seen[program] || shellBuiltins[program] || e.isPowerShellCmdlet(program) {
+				continue
+			}
+			seen[program] = true
+
+			if _, err := exec.LookPath(program); err == nil {
+				continue
+			}
+			if installedEarlier(commands[:i], program) {
+				continue
+			}
+			missing = append(missing, program)
+		}
+	}
+
+	return missing
+}
+
+// InstallCommand returns a command installing the given binaries with the
+// system package manager, or "" if no supported package manager is found
+func (e *Executor) InstallCommand(binaries []string) string {
+	manager := pkgmgr.Detect()
+	if manager == nil {
+		return ""
+	}
+	return manager.InstallCommand(binaries...)
+}
+
+// isPowerShellCmdlet reports whether program is a Verb-Noun PowerShell cmdlet
+func (e *Executor) isPowerShellCmdlet(program string) bool {
+	return e.config.OS == "windows" && strings.Contains(program, "-")
+}
+
+// installedEarlier reports whether a previous package operation mentions program
+func installedEarlier(previous []string, program string) bool {
+	for _, cmd := range previous {
+		for _, class := range policy.Classify(cmd) {
+			if class == policy.ClassPackage && strings.Contains(strings.ToLower(cmd), strings.ToLower(program)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package budget
+
+import (
+	"strings"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/memory"
+	"devos/internal/timefmt"
+)
+
+// defaultWarnAt is the percentage of a budget used before plans are warned
+const defaultWarnAt = 80
+
+// defaultPrices are blended dollars per million tokens by model name prefix,
+// assuming three input tokens for every output token. The longest matching
+// prefix wins; token_prices in the config overrides or extends them.
+var defaultPrices = map[string]float64{
+	"gpt-4o":            4.38,
+	"gpt-4o-mini":       0.26,
+	"gpt-4.1":           3.50,
+	"gpt-4.1-mini":      0.70,
+	"gpt-4.1-nano":      0.18,
+	"o3":                3.50,
+	"o4-mini":           1.93,
+	"claude-3-5-haiku":  1.60,
+	"claude-3-5-sonnet": 6.00,
+	"claude-3-7-sonnet": 6.00,
+	"claude-sonnet-4":   6.00,
+	"claude-opus-4":     30.00,
+	"gemini-1.5-flash":  0.13,
+	"gemini-1.5-pro":    2.19,
+	"gemini-2.0-flash":  0.18,
+	"gemini-2.5-flash":  0.85,
+	"gemini-2.5-pro":    3.44,
+}
+
+// Price returns the dollars per million tokens of model, or 0 if unknown
+func Price(model string, overrides map[string]float64) float64 {
+	best, price := -1, 0.0
+	for _, prices := range []map[string]float64{defaultPrices, overrides} {
+		for prefix, p := range prices {
+			// Overrides win ties with the defaults
+			if strings.HasPrefix(model, prefix) && len(prefix) >= best {
+				best, price = len(prefix), p
+			}
+		}
+	}
+	return price
+}
+
+// Local reports whether a provider runs models on this machine, which cloud
+// budgets ("*") do not cover
+func Local(provider string) bool {
+	return provider == "ollama" || provider == "builtin"
+}
+
+// Covers reports whether a budget applies to a provider
+func Covers(b config.Budget, provider string) bool {
+	if b.Provider == "*" {
+		return !Local(provider)
+	}
+	return b.Provider == provider
+}
+
+// Status is how much of a budget the current period has used
+type Status struct {
+	Budget   config.Budget
+	Start    time.Time // Start of the current period
+	Tokens   int
+	Dollars  float64
+	Unpriced int // Tokens spent on models without a known price, left out of Dollars
+}
+
+// Percent returns the share of the budget used, by whichever limit is
+// closest to being reached
+func (s Status) Percent() float64 {
+	var percent float64
+	if s.Budget.Tokens > 0 {
+		percent = float64(s.Tokens) * 100 / float64(s.Budget.Tokens)
+	}
+	if s.Budget.Dollars > 0 {
+		if p := s.Dollars * 100 / s.Budget.Dollars; p > percent {
+			percent = p
+		}
+	}
+	return percent
+}
+
+// Near reports whether the budget has reached its warning threshold
+func (s Status) Near() bool {
+	warnAt := s.Budget.WarnAt
+	if warnAt == 0 {
+		warnAt = defaultWarnAt
+	}
+	return s.Percent() >= float64(warnAt)
+}
+
+// Exhausted reports whether the budget is used up
+func (s Status) Exhausted() bool {
+	return s.Percent() >= 100
+}
+
+// PeriodStart returns when the period containing now began, in the
+// configured timezone
+func PeriodStart(period string, now time.Time) time.Time {
+	now = timefmt.In(now)
+	if period == "month" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// Evaluate totals the tokens and estimated dollars each budget's providers
+// used in its current period. tasks must reach back to the start of the
+// month when any budget is monthly.
+func Evaluate(budgets []config.Budget, prices map[string]float64, tasks []memory.Task, now time.Time) []Status {
+	statuses := make([]Status, len(budgets))
+	for i, b := range budgets {
+		s := Status{Budget: b, Start: PeriodStart(b.Period, now)}
+		for _, t := range tasks {
+			if t.Tokens == 0 || t.CreatedAt.Before(s.Start) || !Covers(b, t.Provider) {
+				continue
+			}
+			s.Tokens += t.Tokens
+			if price := Price(t.Model, prices); price > 0 {
+				s.Dollars += float64(t.Tokens) * price / 1e6
+			} else {
+				s.Unpriced += t.Tokens
+			}
+		}
+		statuses[i] = s
+	}
+	return statuses
+}
@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"devos/internal/budget"
+	"devos/internal/config"
+	"devos/internal/models"
+	"devos/internal/timefmt"
+)
+
+// route is the provider and model an AI call goes to, with warnings about
+// the budgets that decided it
+type route struct {
+	provider string
+	model    string
+	warnings []string
+}
+
+// budgetRoute applies the budgets covering a provider: plans are warned
+// when a budget is nearly used up, and once it is, the call is downgraded
+// to the fallback model or refused, as the budget says
+func (e *Executor) budgetRoute(provider, model string) (*route, error) {
+	r := &route{provider: provider, model: model}
+	statuses := e.BudgetStatus()
+
+	for _, s := range statuses {
+		b := s.Budget
+		if !budget.Covers(b, r.provider) || !s.Near() {
+			continue
+		}
+		label := fmt.Sprintf("%s %s budget", budgetProvider(b), periodAdjective(b.Period))
+		if !s.Exhausted() {
+			r.warnings = append(r.warnings, fmt.Sprintf("%.0f%% of the %s is used", s.Percent(), label))
+			continue
+		}
+
+		switch b.Action {
+		case config.BudgetBlock:
+			e.audit.Record("budget_blocked", map[string]string{"provider": r.provider, "period": b.Period})
+			return nil, fmt.Errorf("%w: the %s is used up; it resets %s", ErrBudgetExceeded, label, resetTime(b.Period, s.Start))
+		case config.BudgetDowngrade:
+			provider, model := e.fallback(b.Fallback, r.provider)
+			e.logger.Info("The %s is used up; using %s %s", label, provider, model)
+			e.audit.Record("budget_downgraded", map[string]string{"provider": r.provider, "model": r.model, "fallback": provider + ":" + model})
+			r.warnings = append(r.warnings, fmt.Sprintf("The %s is used up; this plan was made by %s (%s)", label, model, provider))
+			r.provider, r.model = provider, model
+			// Budgets are not applied again to the fallback
+			return r, nil
+		default:
+			r.warnings = append(r.warnings, fmt.Sprintf("The %s is used up (%.0f%%)", label, s.Percent()))
+		}
+	}
+	return r, nil
+}
+
+// apply records where a plan was generated and the budget warnings on it
+func (r *route) apply(result *ExecutionResult) {
+	result.Provider, result.Model = r.provider, r.model
+	result.Warnings = append(result.Warnings, r.warnings...)
+}
+
+// BudgetStatus returns how much of each configured budget is used
+func (e *Executor) BudgetStatus() []budget.Status {
+	if len(e.config.Budgets) == 0 || e.memory == nil {
+		return nil
+	}
+	tasks, err := e.memory.TasksSince(budget.PeriodStart("month", time.Now()))
+	if err != nil {
+		e.logger.Warn("Failed to load task history for budgets: %v", err)
+		return nil
+	}
+	return budget.Evaluate(e.config.Budgets, e.config.TokenPrices, tasks, time.Now())
+}
+
+// fallback resolves a budget's fallback model: "provider:model" for a local
+// provider, or a model of the current provider. "auto" picks the Ollama
+// model recommended for this machine.
+func (e *Executor) fallback(spec, provider string) (string, string) {
+	model := spec
+	if p, m, ok := strings.Cut(spec, ":"); ok && budget.Local(p) {
+		provider, model = p, m
+	}
+	if provider == "ollama" && model == models.Auto {
+		hw := e.platform.Hardware
+		model = models.Recommend(hw.RAM, hw.VRAM()).Ollama
+	}
+	return provider, model
+}
+
+// budgetProvider names a budget's provider for messages
+func budgetProvider(b config.Budget) string {
+	if b.Provider == "*" {
+		return "cloud"
+	}
+	return b.Provider
+}
+
+// periodAdjective turns "day" or "month" into "daily" or "monthly"
+func periodAdjective(period string) string {
+	if period == "month" {
+		return "monthly"
+	}
+	return "daily"
+}
+
+// resetTime describes when a period that began at start resets
+func resetTime(period string, start time.Time) string {
+	if period == "month" {
+		return "on " + timefmt.Date(start.AddDate(0, 1, 0))
+	}
+	return "at midnight"
+}
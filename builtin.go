@@ -0,0 +1,195 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"devos/internal/ai"
+	"devos/internal/llama"
+	"devos/internal/models"
+)
+
+// allGPULayers offloads every layer; llama.cpp caps it at the model's count
+const allGPULayers = 999
+
+// planSystemPrompt asks a model called from Go for the same JSON the Python
+// engine returns, so its plans go through the usual validation and approval
+const planSystemPrompt = `You are DevOS, a developer assistant that turns requests into shell commands.
+Reply with only a JSON object of this form, and nothing else:
+{"intent": "<short label>", "output": "<one-sentence explanation>", "commands": ["<command>", ...], "needs_confirmation": true}
+Commands run in order with %s on %s. Use an empty command list when no commands are needed.
+Optionally add "depends_on": [[<indexes of earlier commands the first command needs>], ...], one list per command, counting from 0.
+Optionally add "undo": ["<command reversing the command>", ...], one per command, "" for commands that change nothing or cannot be reversed; they run last first if a later command fails.
+When one of the runbooks in the context fits the request, reply with {"intent": "runbook", "output": "<one-sentence explanation>", "runbook": {"name": "<runbook>", "params": {"<param>": "<value>", ...}}} instead.
+When the context lists APIs, call them with their servers, paths, parameters, and auth exactly as given (e.g. with curl), and answer questions about them in "output".
+To find out why an earlier DevOS command failed, use the command: devos logs --self --since 24h --diagnose "<question>"
+To debug a gRPC service, use the commands: devos grpc list <host:port> [service], devos grpc describe <host:port> <symbol>, and devos grpc call <host:port> <package.Service/Method> '<json>' (add --plaintext for servers without TLS); list and describe before calling.
+The context below describes the machine, project, and active environment; follow any corrections the user made before, follow plans rated "good" in the examples, and avoid plans rated "bad".`
+
+// modelSettings are engine request fields that configure the model rather
+// than describe the task, so they are left out of the prompt
+var modelSettings = []string{"input", "provider", "model", "api_key", "base_url", "max_tokens", "temperature", "num_ctx"}
+
+// builtinEngine holds the in-process model, loaded on first use and kept
+// for the life of the process
+type builtinEngine struct {
+	mu    sync.Mutex
+	model *llama.Model
+}
+
+// builtinProvider completes prompts with the in-process llama.cpp model,
+// which the executor loads on first use
+type builtinProvider struct {
+	e *Executor
+}
+
+func (builtinProvider) Name() string { return "builtin" }
+
+func (p builtinProvider) Complete(ctx context.Context, request ai.Request) (*ai.Response, error) {
+	model, err := p.e.builtinModel(request.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	// ChatML, the template of the recommended Qwen models
+	var prompt strings.Builder
+	if request.System != "" {
+		fmt.Fprintf(&prompt, "<|im_start|>system\n%s<|im_end|>\n", request.System)
+	}
+	for _, m := range request.Messages {
+		fmt.Fprintf(&prompt, "<|im_start|>%s\n%s<|im_end|>\n", m.Role, m.Content)
+	}
+	prompt.WriteString("<|im_start|>assistant\n")
+	text, err := model.Generate(ctx, prompt.String(), llama.GenerateOptions{
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		Stop:        []string{"<|im_end|>"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ai.Response{Text: text}, nil
+}
+
+func (p builtinProvider) Stream(ctx context.Context, request ai.Request, onText func(string)) (*ai.Response, error) {
+	resp, err := p.Complete(ctx, request)
+	if err == nil && onText != nil {
+		onText(resp.Text)
+	}
+	return resp, err
+}
+
+// CountTokens estimates, since the bindings expose no tokenizer
+func (builtinProvider) CountTokens(ctx context.Context, request ai.Request) (int, error) {
+	return ai.EstimateTokens(request), nil
+}
+
+// planSystem returns the system prompt for a model called from Go: the
+// instructions, and the request's context as JSON
+func (e *Executor) planSystem(request map[string]interface{}) (string, error) {
+	details := map[string]interface{}{}
+	for key, value := range request {
+		details[key] = value
+	}
+	for _, key := range modelSettings {
+		delete(details, key)
+	}
+	// The organization's preamble comes first, ahead of anything the
+	// context could say
+	preamble, _ := details["guardrails"].(string)
+	delete(details, "guardrails")
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	system := fmt.Sprintf(planSystemPrompt, e.shell(), e.platform.Summary()) + "\n\nContext: " + string(detailsJSON)
+	if preamble != "" {
+		system = preamble + "\n\n" + system
+	}
+	return system, nil
+}
+
+// parsePlan reads the plan JSON from a model's reply, or returns nil. Models
+// sometimes wrap the JSON in prose or a code fence.
+func parsePlan(text string) *ExecutionResult {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	var result ExecutionResult
+	if start < 0 || end < start || json.Unmarshal([]byte(text[start:end+1]), &result) != nil {
+		return nil
+	}
+	return &result
+}
+
+// builtinModel loads the named GGUF model on first use
+func (e *Executor) builtinModel(name string) (*llama.Model, error) {
+	e.builtin.mu.Lock()
+	defer e.builtin.mu.Unlock()
+	if e.builtin.model != nil {
+		return e.builtin.model, nil
+	}
+	if !llama.Available {
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, llama.ErrUnavailable)
+	}
+
+	file, err := e.builtinFile(name)
+	if err != nil {
+		return nil, err
+	}
+	hw := e.platform.Hardware
+	opts := llama.Options{ContextLength: e.config.ContextLength}
+	if opts.ContextLength == 0 {
+		opts.ContextLength = models.ContextLength(hw.RAM, hw.VRAM(), uint64(file.Size))
+	}
+	if hw.VRAM() >= uint64(file.Size) {
+		opts.GPULayers = allGPULayers
+	}
+
+	e.logger.Info("Loading builtin model %s (%d-token context, GPU layers: %d)", file.Path, opts.ContextLength, opts.GPULayers)
+	model, err := llama.Load(file.Path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, err)
+	}
+	e.builtin.model = model
+	return model, nil
+}
+
+// builtinFile finds a GGUF model by name: a path, a model in the DevOS
+// models directory, or "auto" for the largest one that fits
+func (e *Executor) builtinFile(name string) (*models.File, error) {
+	dir := filepath.Join(e.config.Dir(), "models")
+
+	if strings.ContainsAny(name, `/\`) {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrModelNotFound, name)
+		}
+		return &models.File{Name: strings.TrimSuffix(filepath.Base(name), ".gguf"), Path: name, Size: info.Size()}, nil
+	}
+
+	if name == models.Auto {
+		files, err := models.Files(dir)
+		if err != nil {
+			return nil, err
+		}
+		hw := e.platform.Hardware
+		if file := models.SelectFile(hw.RAM, hw.VRAM(), files); file != nil {
+			return file, nil
+		}
+		rec := models.Recommend(hw.RAM, hw.VRAM())
+		return nil, fmt.Errorf("%w: no GGUF model in %s fits this machine; pull one with: devos models pull %s", ErrModelNotFound, dir, rec.GGUF)
+	}
+
+	file, err := models.FindFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%w: %s is not in %s; pull it with: devos models pull <url>", ErrModelNotFound, name, dir)
+	}
+	return file, nil
+}
@@ -0,0 +1,302 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"devos/internal/ignore"
+	"devos/internal/pkgmgr"
+	"devos/internal/policy"
+	"devos/internal/project"
+)
+
+// maxTrackedFiles bounds how many files a snapshot hashes per root
+const maxTrackedFiles = 5000
+
+// maxHashedSize is the largest file hashed; larger files compare by size and mtime
+const maxHashedSize = 4 << 20
+
+// untrackedDirs are skipped when snapshotting a directory tree
+var untrackedDirs = map[string]bool{
+	".git": true, "node_modules": true, ".venv": true, "venv": true, "__pycache__": true,
+	"target": true, ".cache": true, ".next": true, "dist": true, "build": true,
+}
+
+// fileState is what a snapshot records about one file
+type fileState struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// snapshot is the state of the files and packages a plan may change
+type snapshot struct {
+	files    map[string]fileState
+	packages map[string]map[string]string // Manager → package → version
+}
+
+// PackageChange is a package a plan installed, removed, or changed
+type PackageChange struct {
+	Manager string `json:"manager"`
+	Name    string `json:"name"`
+	Before  string `json:"before,omitempty"` // Empty when installed
+	After   string `json:"after,omitempty"`  // Empty when removed
+}
+
+// Changes is everything a plan modified, found by comparing snapshots
+// taken before and after it ran
+type Changes struct {
+	Added    []string        `json:"added,omitempty"`
+	Modified []string        `json:"modified,omitempty"`
+	Deleted  []string        `json:"deleted,omitempty"`
+	Packages []PackageChange `json:"packages,omitempty"`
+}
+
+// Empty reports whether nothing changed
+func (c *Changes) Empty() bool {
+	return len(c.Added)+len(c.Modified)+len(c.Deleted)+len(c.Packages) == 0
+}
+
+// takeSnapshot records the package sets the commands may change and, with
+// files, the working directory tree and any other paths they reference
+func (e *Executor) takeSnapshot(ctx context.Context, commands []string, files bool) *snapshot {
+	s := &snapshot{files: map[string]fileState{}, packages: map[string]map[string]string{}}
+
+	var roots []string
+	if files {
+		roots = trackedRoots(commands)
+	}
+	for i, root := range roots {
+		// Only the working directory is walked in full; other paths the
+		// commands mention are tracked one level deep
+		depth := 1
+		if i == 0 {
+			depth = -1
+		}
+		walkFiles(root, depth, true, s.files)
+	}
+
+	for _, manager := range trackedManagers(commands) {
+		if packages, err := installedPackages(ctx, manager); err == nil {
+			s.packages[manager] = packages
+		} else {
+			e.logger.Debug("Not tracking %s packages: %v", manager, err)
+		}
+	}
+	return s
+}
+
+// diff compares the snapshot with a later one
+func (s *snapshot) diff(after *snapshot) *Changes {
+	c := &Changes{}
+	for path, state := range after.files {
+		before, ok := s.files[path]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, path)
+		case before.size != state.size || before.hash != state.hash || (before.hash == "" && !before.modTime.Equal(state.modTime)):
+			c.Modified = append(c.Modified, path)
+		}
+	}
+	for path := range s.files {
+		if _, ok := after.files[path]; !ok {
+			c.Deleted = append(c.Deleted, path)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Modified)
+	sort.Strings(c.Deleted)
+
+	for manager, before := range s.packages {
+		current, ok := after.packages[manager]
+		if !ok {
+			continue
+		}
+		for name, version := range current {
+			if old, ok := before[name]; !ok || old != version {
+				c.Packages = append(c.Packages, PackageChange{Manager: manager, Name: name, Before: old, After: version})
+			}
+		}
+		for name, version := range before {
+			if _, ok := current[name]; !ok {
+				c.Packages = append(c.Packages, PackageChange{Manager: manager, Name: name, Before: version})
+			}
+		}
+	}
+	sort.Slice(c.Packages, func(i, j int) bool {
+		if c.Packages[i].Manager != c.Packages[j].Manager {
+			return c.Packages[i].Manager < c.Packages[j].Manager
+		}
+		return c.Packages[i].Name < c.Packages[j].Name
+	})
+	return c
+}
+
+// trackedRoots returns the working directory first, then existing absolute
+// or home-relative paths the commands mention
+func trackedRoots(commands []string) []string {
+	var roots []string
+	if cwd, err := os.Getwd(); err == nil {
+		roots = append(roots, cwd)
+	}
+	home, _ := os.UserHomeDir()
+
+	for _, cmd := range commands {
+		for _, field := range strings.Fields(cmd) {
+			field = strings.Trim(field, `"'<>|&;()`)
+			if home != "" && strings.HasPrefix(field, "~/") {
+				field = filepath.Join(home, field[2:])
+			}
+			if !filepath.IsAbs(field) || field == "/" || field == home || covered(roots, field) {
+				continue
+			}
+			// Track the file, or the directory it will be created in
+			if _, err := os.Stat(field); err != nil {
+				field = filepath.Dir(field)
+			}
+			if _, err := os.Stat(field); err == nil && field != filepath.Dir(field) && !covered(roots, field) {
+				roots = append(roots, field)
+			}
+		}
+	}
+	return roots
+}
+
+// covered reports whether path is inside one of the roots
+func covered(roots []string, path string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFiles records the state of regular files under root, descending at
+// most depth directories (-1 for no limit) and skipping what the project's
+// .gitignore and .devosignore files ignore. Without hash, files compare by
+// size and mtime only.
+func walkFiles(root string, depth int, hash bool, files map[string]fileState) {
+	count := 0
+	ignored := ignore.New(project.Root(root))
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			if untrackedDirs[d.Name()] || ignored.Ignored(path, true) || (depth >= 0 && strings.Count(rel, string(filepath.Separator)) >= depth-1) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || ignored.Ignored(path, false) {
+			return nil
+		}
+		if count++; count > maxTrackedFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if hash && info.Size() <= maxHashedSize {
+			state.hash = hashFile(path)
+		}
+		files[path] = state
+		return nil
+	})
+}
+
+// hashFile returns the SHA-256 of a file, or "" if it cannot be read
+func hashFile(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// trackedManagers returns the package managers whose state the commands may change
+func trackedManagers(commands []string) []string {
+	var managers []string
+	add := func(name string) {
+		for _, m := range managers {
+			if m == name {
+				return
+			}
+		}
+		managers = append(managers, name)
+	}
+
+	system := pkgmgr.Detect()
+	for _, cmd := range commands {
+		for _, program := range policy.Programs(cmd) {
+			// "apt" is the interactive front end to apt-get's package set
+			if system != nil && (program == system.Name || program+"-get" == system.Name) {
+				add(system.Name)
+			}
+			switch program {
+			case "pip", "pip3":
+				add("pip")
+			case "npm":
+				if strings.Contains(cmd, " -g") || strings.Contains(cmd, "--global") {
+					add("npm")
+				}
+			}
+		}
+	}
+	return managers
+}
+
+// installedPackages lists the packages one manager has installed
+func installedPackages(ctx context.Context, manager string) (map[string]string, error) {
+	switch manager {
+	case "pip":
+		return nameVersionList(ctx, "==", "pip3", "list", "--format=freeze")
+	case "npm":
+		return nameVersionList(ctx, "@", "npm", "ls", "-g", "--depth=0", "--parseable", "--long")
+	}
+	m := pkgmgr.Detect()
+	if m == nil || m.Name != manager {
+		return nil, nil
+	}
+	return m.Installed(ctx)
+}
+
+// nameVersionList runs a command printing one "name<sep>version" per line
+func nameVersionList(ctx context.Context, sep, name string, args ...string) (map[string]string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	packages := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		// npm --parseable --long prints "<path>:<name>@<version>"
+		if i := strings.LastIndex(line, ":"); sep == "@" && i >= 0 {
+			line = line[i+1:]
+		}
+		if i := strings.LastIndex(line, sep); i > 0 {
+			packages[line[:i]] = strings.TrimSpace(line[i+len(sep):])
+		}
+	}
+	return packages, nil
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"devos/internal/memory"
+)
+
+// Kinds of change summary
+const (
+	SummaryChangelog = "changelog"
+	SummaryPR        = "pr"
+)
+
+// maxSummaryDiff bounds how much of the git diff goes to the AI engine
+const maxSummaryDiff = 32 << 10
+
+// summaryInstructions tell the AI engine what to write, by kind
+var summaryInstructions = map[string]string{
+	SummaryChangelog: "Write a CHANGELOG entry for the changes below: markdown bullet points grouped under " +
+		"\"### Added\", \"### Changed\", \"### Fixed\", or \"### Removed\" as they apply, describing what changed " +
+		"for users of the project. Reply with the entry only, without a version heading, and propose no commands.",
+	SummaryPR: "Write a pull request description for the changes below: a one-line title, a blank line, a short " +
+		"summary of what changed and why, and a bullet list of the notable changes. Reply with the description " +
+		"only, and propose no commands.",
+}
+
+// RepoState is a git repository's state, to tell what a task changed in it
+type RepoState struct {
+	Root   string
+	Head   string // Empty before the first commit
+	Status string // git status --porcelain
+}
+
+// Repo returns the state of the git repository the working directory is
+// in, or nil outside one
+func Repo(ctx context.Context) *RepoState {
+	root, err := git(ctx, "", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil
+	}
+	head, err := git(ctx, root, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		head = ""
+	}
+	status, err := git(ctx, root, "status", "--porcelain")
+	if err != nil {
+		return nil
+	}
+	return &RepoState{Root: root, Head: head, Status: status}
+}
+
+// Changed reports whether the repository has changed since s: commits were
+// made, or files changed, were added, or were deleted
+func (s *RepoState) Changed(ctx context.Context) bool {
+	now := Repo(ctx)
+	return now != nil && now.Root == s.Root && (now.Head != s.Head || now.Status != s.Status)
+}
+
+// SummarizeChanges asks the AI engine for a CHANGELOG entry or pull request
+// description of what a task changed in the repository, from the commands
+// the execution journal shows it ran and the git diff since before it
+func (e *Executor) SummarizeChanges(ctx context.Context, kind string, result *ExecutionResult, before *RepoState) (string, error) {
+	instruction, ok := summaryInstructions[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown summary kind: %s (expected changelog or pr)", kind)
+	}
+
+	commands := result.Commands
+	if e.memory != nil {
+		if run, err := e.memory.LastRun(memory.PlanHash(result.Commands)); err == nil && run != nil {
+			commands = run.Commands[:run.Completed]
+		}
+	}
+	base := before.Head
+	if base == "" {
+		// Nothing was committed before the task: diff against the empty tree
+		base, _ = git(ctx, before.Root, "hash-object", "-t", "tree", os.DevNull)
+	}
+	stat, _ := git(ctx, before.Root, "diff", "--stat", base)
+	diff, err := git(ctx, before.Root, "diff", base)
+	if err != nil {
+		return "", fmt.Errorf("failed to read git diff: %w", err)
+	}
+	if len(diff) > maxSummaryDiff {
+		diff = diff[:maxSummaryDiff] + "\n[... diff truncated ...]"
+	}
+	untracked, _ := git(ctx, before.Root, "ls-files", "--others", "--exclude-standard")
+	log := ""
+	if before.Head != "" {
+		log, _ = git(ctx, before.Root, "log", "--format=%s", before.Head+"..HEAD")
+	}
+
+	e.logger.Info("Summarizing changes as %s: %s", kind, result.Prompt)
+	summary, err := e.callAIEngine(ctx, instruction, map[string]interface{}{
+		"task":              result.Prompt,
+		"executed_commands": commands,
+		"git_commits":       lines(log),
+		"git_diff_stat":     stat,
+		"git_diff":          diff,
+		"untracked_files":   lines(untracked),
+	})
+	if err != nil {
+		return "", fmt.Errorf("AI engine error: %w", err)
+	}
+	return strings.TrimSpace(summary.Output), nil
+}
+
+// AddChangelogEntry adds an entry, under a heading with today's date, to
+// the top of the CHANGELOG.md at a repository's root, below its title and
+// introduction; the file is created if there is none. It returns the path.
+func AddChangelogEntry(root, entry string) (string, error) {
+	path := filepath.Join(root, "CHANGELOG.md")
+	if err := addChangelogSection(path, time.Now().Format("2006-01-02"), entry); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// addChangelogSection adds a "## heading" section to the top of a
+// changelog, below its title and introduction, creating the file if needed
+func addChangelogSection(path, heading, entry string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = []byte("# Changelog\n"), nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	section := "## " + heading + "\n\n" + strings.TrimSpace(entry) + "\n\n"
+	text := string(data)
+	at := len(text)
+	if strings.HasPrefix(text, "## ") {
+		at = 0
+	} else if i := strings.Index(text, "\n## "); i >= 0 {
+		at = i + 1
+	} else if !strings.HasSuffix(text, "\n\n") {
+		text = strings.TrimRight(text, "\n") + "\n\n"
+		at = len(text)
+	}
+	text = text[:at] + section + text[at:]
+	if err := os.WriteFile(path, []byte(strings.TrimRight(text, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// lines splits output into its non-empty lines
+func lines(out string) []string {
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
@@ -0,0 +1,247 @@
+package cigen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WorkflowPath is where GitHub Actions workflows are written
+const WorkflowPath = ".github/workflows/ci.yml"
+
+// Options select what the generated pipeline does
+type Options struct {
+	Languages []string // "go", "javascript", "typescript", "python", "rust"
+	Docker    bool     // Build a Docker image after tests pass
+	Image     string   // Image name for docker builds
+}
+
+// Workflow returns a GitHub Actions workflow running tests (and a Docker
+// build) for the given options
+func Workflow(opts Options) string {
+	var b strings.Builder
+	b.WriteString("name: CI\n\non:\n  push:\n    branches: [main]\n  pull_request:\n\njobs:\n")
+	b.WriteString("  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n")
+
+	for _, lang := range opts.Languages {
+		switch lang {
+		case "go":
+			b.WriteString("      - uses: actions/setup-go@v5\n        with:\n          go-version-file: go.mod\n")
+			b.WriteString("      - run: go build ./...\n      - run: go test ./...\n")
+		case "javascript", "typescript":
+			if strings.Contains(b.String(), "setup-node") {
+				continue
+			}
+			b.WriteString("      - uses: actions/setup-node@v4\n        with:\n          node-version: 20\n          cache: npm\n")
+			b.WriteString("      - run: npm ci\n      - run: npm test\n")
+		case "python":
+			b.WriteString("      - uses: actions/setup-python@v5\n        with:\n          python-version: \"3.12\"\n")
+			b.WriteString("      - run: pip install -r requirements.txt\n      - run: python -m pytest\n")
+		case "rust":
+			b.WriteString("      - uses: dtolnay/rust-toolchain@stable\n")
+			b.WriteString("      - run: cargo build --locked\n      - run: cargo test --locked\n")
+		}
+	}
+
+	if opts.Docker {
+		b.WriteString("\n  docker:\n    needs: test\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n")
+		b.WriteString("      - uses: docker/setup-buildx-action@v3\n")
+		b.WriteString("      - uses: docker/build-push-action@v6\n        with:\n          context: .\n          push: false\n")
+		fmt.Fprintf(&b, "          tags: %s:${{ github.sha }}\n", image(opts))
+	}
+
+	return b.String()
+}
+
+// Makefile returns a Makefile with build, test, and (optionally) docker targets
+func Makefile(opts Options) string {
+	var build, test []string
+	for _, lang := range opts.Languages {
+		switch lang {
+		case "go":
+			build = append(build, "go build ./...")
+			test = append(test, "go test ./...")
+		case "javascript", "typescript":
+			if !contains(test, "npm test") {
+				build = append(build, "npm run build --if-present")
+				test = append(test, "npm test")
+			}
+		case "python":
+			test = append(test, "python -m pytest")
+		case "rust":
+			build = append(build, "cargo build")
+			test = append(test, "cargo test")
+		}
+	}
+
+	targets := []string{"build", "test"}
+	if opts.Docker {
+		targets = append(targets, "docker-build")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".PHONY: %s\n\n", strings.Join(targets, " "))
+	writeTarget(&b, "build", build)
+	writeTarget(&b, "test", test)
+	if opts.Docker {
+		writeTarget(&b, "docker-build", []string{"docker build -t " + image(opts) + " ."})
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeTarget writes a make rule; recipes must be tab-indented
+func writeTarget(b *strings.Builder, name string, recipe []string) {
+	fmt.Fprintf(b, "%s:\n", name)
+	if len(recipe) == 0 {
+		recipe = []string{"@echo \"nothing to " + name + "\""}
+	}
+	for _, line := range recipe {
+		fmt.Fprintf(b, "\t%s\n", line)
+	}
+	b.WriteString("\n")
+}
+
+// image returns the configured image name or a default
+func image(opts Options) string {
+	if opts.Image != "" {
+		return opts.Image
+	}
+	return "app"
+}
+
+// Line patterns used by ValidateWorkflow
+var (
+	yamlKey      = regexp.MustCompile(`^(\s*)(- )?([A-Za-z0-9_.-]+):(\s|$)`)
+	usesRef      = regexp.MustCompile(`^\s*(- )?uses:\s*(\S+)`)
+	expression   = regexp.MustCompile(`\$\{\{([^}]*)\}\}`)
+	knownContext = regexp.MustCompile(`^\s*!?\(?\s*(github|env|vars|secrets|inputs|matrix|steps|needs|runner|job|strategy|always\(\)|success\(\)|failure\(\)|cancelled\(\)|contains|startsWith|endsWith|format|join|toJSON|fromJSON|hashFiles)\b`)
+)
+
+// ValidateWorkflow performs actionlint-style checks on a GitHub Actions
+// workflow: YAML indentation, required top-level keys, jobs with runs-on and
+// steps, pinned action references, and known expression contexts
+func ValidateWorkflow(content string) []error {
+	var errs []error
+	fail := func(line int, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("line %d: "+format, append([]interface{}{line}, args...)...))
+	}
+
+	topLevel := map[string]bool{}
+	jobs := map[string]map[string]bool{}
+	var job string
+
+	for i, line := range strings.Split(content, "\n") {
+		n := i + 1
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if strings.Contains(line, "\t") {
+			fail(n, "tabs are not allowed in YAML indentation")
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent%2 != 0 {
+			fail(n, "indentation must be a multiple of two spaces")
+		}
+
+		if m := yamlKey.FindStringSubmatch(line); m != nil {
+			key := m[3]
+			switch {
+			case indent == 0:
+				topLevel[key] = true
+				job = ""
+			case indent == 2 && topLevel["jobs"] && m[2] == "":
+				job = key
+				jobs[job] = map[string]bool{}
+			case indent == 4 && job != "":
+				jobs[job][key] = true
+			}
+		}
+
+		if m := usesRef.FindStringSubmatch(line); m != nil {
+			ref := strings.Trim(m[2], `"'`)
+			if !strings.HasPrefix(ref, "./") && !strings.HasPrefix(ref, "docker://") && !strings.Contains(ref, "@") {
+				fail(n, "action %q must be pinned to a version (e.g. %s@v4)", ref, ref)
+			}
+		}
+
+		for _, m := range expression.FindAllStringSubmatch(line, -1) {
+			if !knownContext.MatchString(m[1]) {
+				fail(n, "unknown expression context in ${{%s}}", m[1])
+			}
+		}
+	}
+
+	for _, key := range []string{"on", "jobs"} {
+		if !topLevel[key] {
+			errs = append(errs, fmt.Errorf("missing top-level %q", key))
+		}
+	}
+	for name, keys := range jobs {
+		if !keys["runs-on"] && !keys["uses"] {
+			errs = append(errs, fmt.Errorf("job %q has no runs-on", name))
+		}
+		if !keys["steps"] && !keys["uses"] {
+			errs = append(errs, fmt.Errorf("job %q has no steps", name))
+		}
+	}
+
+	return errs
+}
+
+// makeRule matches a rule header, e.g. "build: deps"
+var makeRule = regexp.MustCompile(`^([A-Za-z0-9_./-]+(?:\s+[A-Za-z0-9_./-]+)*)\s*:([^=]|$)`)
+
+// ValidateMakefile checks that recipes are tab-indented and every .PHONY
+// target has a rule
+func ValidateMakefile(content string) []error {
+	var errs []error
+	rules := map[string]bool{}
+	var phony []string
+	inRule := false
+
+	for i, line := range strings.Split(content, "\n") {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(line, "\t"):
+			if !inRule {
+				errs = append(errs, fmt.Errorf("line %d: recipe line outside of a rule", n))
+			}
+		case strings.HasPrefix(line, " ") && inRule:
+			errs = append(errs, fmt.Errorf("line %d: recipe lines must start with a tab, not spaces", n))
+		case strings.HasPrefix(trimmed, ".PHONY:"):
+			phony = append(phony, strings.Fields(strings.TrimPrefix(trimmed, ".PHONY:"))...)
+			inRule = false
+		default:
+			if m := makeRule.FindStringSubmatch(line); m != nil {
+				for _, target := range strings.Fields(m[1]) {
+					rules[target] = true
+				}
+				inRule = true
+			} else {
+				inRule = false
+			}
+		}
+	}
+
+	for _, target := range phony {
+		if !rules[target] {
+			errs = append(errs, fmt.Errorf(".PHONY target %q has no rule", target))
+		}
+	}
+	return errs
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
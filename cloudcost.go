@@ -0,0 +1,416 @@
+package cloudcost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HoursPerMonth converts hourly prices to monthly ones
+const HoursPerMonth = 730
+
+// DefaultThreshold is the monthly cost above which a plan's cost must be
+// confirmed, when none is configured
+const DefaultThreshold = 100
+
+// Prices are on-demand Linux prices in dollars per hour, in us-east-1 or the
+// providers' cheapest comparable region, by instance type or by resource for
+// those billed per hour themselves. They cover compute only; storage and
+// traffic are extra.
+var Prices = map[string]float64{
+	// AWS EC2
+	"t3.nano": 0.0052, "t3.micro": 0.0104, "t3.small": 0.0208, "t3.medium": 0.0416,
+	"t3.large": 0.0832, "t3.xlarge": 0.1664, "t3.2xlarge": 0.3328,
+	"t4g.micro": 0.0084, "t4g.small": 0.0168, "t4g.medium": 0.0336, "t4g.large": 0.0672, "t4g.xlarge": 0.1344,
+	"m5.large": 0.096, "m5.xlarge": 0.192, "m5.2xlarge": 0.384, "m5.4xlarge": 0.768,
+	"m6i.large": 0.096, "m6i.xlarge": 0.192, "m6i.2xlarge": 0.384, "m7g.large": 0.0816, "m7g.xlarge": 0.1632,
+	"c5.large": 0.085, "c5.xlarge": 0.17, "c5.2xlarge": 0.34, "c6i.large": 0.085, "c6i.xlarge": 0.17,
+	"r5.large": 0.126, "r5.xlarge": 0.252, "r6i.large": 0.126, "r6i.xlarge": 0.252,
+	"g4dn.xlarge": 0.526, "g5.xlarge": 1.006, "p3.2xlarge": 3.06,
+
+	// AWS RDS and ElastiCache
+	"db.t3.micro": 0.017, "db.t3.small": 0.034, "db.t3.medium": 0.068, "db.t3.large": 0.136,
+	"db.t4g.micro": 0.016, "db.t4g.small": 0.032, "db.t4g.medium": 0.065, "db.t4g.large": 0.129,
+	"db.m5.large": 0.171, "db.m5.xlarge": 0.342, "db.m6g.large": 0.152, "db.r5.large": 0.25, "db.r6g.large": 0.215,
+	"cache.t3.micro": 0.017, "cache.t3.small": 0.034, "cache.t3.medium": 0.068, "cache.m5.large": 0.156,
+
+	// AWS resources billed per hour
+	"aws:eks-cluster":   0.10,
+	"aws:nat-gateway":   0.045,
+	"aws:load-balancer": 0.0225,
+
+	// Google Cloud
+	"e2-micro": 0.0084, "e2-small": 0.0168, "e2-medium": 0.0335,
+	"e2-standard-2": 0.067, "e2-standard-4": 0.134, "e2-standard-8": 0.268,
+	"n1-standard-1": 0.0475, "n1-standard-2": 0.095, "n1-standard-4": 0.19,
+	"n2-standard-2": 0.0971, "n2-standard-4": 0.1942, "n2-standard-8": 0.3885,
+	"db-f1-micro": 0.0105, "db-g1-small": 0.035,
+	"gcp:gke-cluster": 0.10,
+
+	// Azure
+	"Standard_B1s": 0.0104, "Standard_B1ms": 0.0207, "Standard_B2s": 0.0416, "Standard_B2ms": 0.0832,
+	"Standard_DS1_v2": 0.073, "Standard_DS2_v2": 0.146,
+	"Standard_D2s_v3": 0.096, "Standard_D4s_v3": 0.192, "Standard_D2s_v5": 0.096, "Standard_D4s_v5": 0.192,
+	"Standard_E2s_v3": 0.126, "Standard_E4s_v3": 0.252,
+
+	// DigitalOcean
+	"s-1vcpu-1gb": 0.00893, "s-1vcpu-2gb": 0.01786, "s-2vcpu-2gb": 0.02679,
+	"s-2vcpu-4gb": 0.03571, "s-4vcpu-8gb": 0.07143, "s-8vcpu-16gb": 0.14286,
+}
+
+// separator splits a command line into the commands it chains
+var separator = regexp.MustCompile(`&&|\|\||;`)
+
+// desiredSize matches the node count in an EKS node group's scaling config
+var desiredSize = regexp.MustCompile(`desiredSize=(\d+)`)
+
+// Item is a resource a plan creates
+type Item struct {
+	Resource string  // e.g. "aws ec2 instance" or "aws_instance.web"
+	Size     string  // Instance type, or the resource's price key
+	Count    int     // How many are created
+	Monthly  float64 // Dollars per month for all of them; 0 when not priced
+	Priced   bool
+}
+
+// Estimate is the monthly cost of the cloud resources a plan creates
+type Estimate struct {
+	Items []Item
+	Total float64 // Dollars per month of the priced items
+}
+
+// Unpriced returns the items no price is known for
+func (e *Estimate) Unpriced() []Item {
+	var items []Item
+	for _, item := range e.Items {
+		if !item.Priced {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Estimator prices resources, with configured prices taking precedence
+// over the built-in ones
+type Estimator struct {
+	Prices map[string]float64 // Dollars per hour, by instance type or resource
+}
+
+// price returns the hourly price of an instance type or resource
+func (e *Estimator) price(size string) (float64, bool) {
+	if p, ok := e.Prices[size]; ok {
+		return p, true
+	}
+	p, ok := Prices[size]
+	return p, ok
+}
+
+// add adds count resources of a size to an estimate
+func (e *Estimator) add(estimate *Estimate, resource, size string, count int) {
+	if count <= 0 {
+		return
+	}
+	item := Item{Resource: resource, Size: size, Count: count}
+	if hourly, ok := e.price(size); ok {
+		item.Monthly, item.Priced = hourly*HoursPerMonth*float64(count), true
+		estimate.Total += item.Monthly
+	}
+	estimate.Items = append(estimate.Items, item)
+}
+
+// Commands estimates the resources cloud CLI commands create. dir is where
+// the commands run, for the configuration "terraform apply" reads. It
+// returns nil when the commands create nothing it knows the cost of.
+func (e *Estimator) Commands(commands []string, dir string) *Estimate {
+	estimate := &Estimate{}
+	for _, cmd := range commands {
+		cmdDir := dir
+		for _, segment := range separator.Split(cmd, -1) {
+			words := strings.Fields(segment)
+			if len(words) == 2 && words[0] == "cd" {
+				if next := strings.Trim(words[1], `'"`); filepath.IsAbs(next) {
+					cmdDir = next
+				} else {
+					cmdDir = filepath.Join(cmdDir, next)
+				}
+				continue
+			}
+			e.command(estimate, words, cmdDir)
+		}
+	}
+	if len(estimate.Items) == 0 {
+		return nil
+	}
+	return estimate
+}
+
+// command adds the resources one command creates
+func (e *Estimator) command(estimate *Estimate, words []string, dir string) {
+	for len(words) > 0 && (words[0] == "sudo" || strings.Contains(words[0], "=")) {
+		words = words[1:]
+	}
+	if len(words) < 2 {
+		return
+	}
+	flags := parseFlags(words)
+	action := strings.Join(flags.positional, " ")
+
+	switch filepath.Base(words[0]) {
+	case "aws":
+		switch {
+		case strings.HasPrefix(action, "ec2 run-instances"):
+			count, _ := strconv.Atoi(strings.Split(flags.value("count", "1"), ":")[0])
+			e.add(estimate, "aws ec2 instance", flags.value("instance-type", "m1.small"), count)
+		case strings.HasPrefix(action, "rds create-db-instance"):
+			count := 1
+			if flags.has("multi-az") {
+				count = 2
+			}
+			e.add(estimate, "aws rds instance", flags.value("db-instance-class", ""), count)
+		case strings.HasPrefix(action, "elasticache create-cache-cluster"):
+			count, _ := strconv.Atoi(flags.value("num-cache-nodes", "1"))
+			e.add(estimate, "aws elasticache node", flags.value("cache-node-type", ""), count)
+		case strings.HasPrefix(action, "eks create-cluster"):
+			e.add(estimate, "aws eks cluster", "aws:eks-cluster", 1)
+		case strings.HasPrefix(action, "eks create-nodegroup"):
+			size := m(desiredSize, flags.value("scaling-config", ""), "2")
+			count, _ := strconv.Atoi(size)
+			e.add(estimate, "aws eks node", strings.Split(flags.value("instance-types", "t3.medium"), ",")[0], count)
+		case strings.HasPrefix(action, "ec2 create-nat-gateway"):
+			e.add(estimate, "aws nat gateway", "aws:nat-gateway", 1)
+		case strings.HasPrefix(action, "elbv2 create-load-balancer"), strings.HasPrefix(action, "elb create-load-balancer"):
+			e.add(estimate, "aws load balancer", "aws:load-balancer", 1)
+		}
+
+	case "eksctl":
+		if strings.HasPrefix(action, "create cluster") {
+			e.add(estimate, "aws eks cluster", "aws:eks-cluster", 1)
+			count, _ := strconv.Atoi(flags.value("nodes", "2"))
+			e.add(estimate, "aws eks node", flags.value("node-type", "m5.large"), count)
+		}
+
+	case "gcloud":
+		switch {
+		case strings.HasPrefix(action, "compute instances create"):
+			// Each name after "create" is an instance
+			count := len(flags.positional) - 3
+			if count < 1 {
+				count = 1
+			}
+			e.add(estimate, "gcp compute instance", flags.value("machine-type", "n1-standard-1"), count)
+		case strings.HasPrefix(action, "container clusters create"):
+			e.add(estimate, "gcp gke cluster", "gcp:gke-cluster", 1)
+			count, _ := strconv.Atoi(flags.value("num-nodes", "3"))
+			e.add(estimate, "gcp gke node", flags.value("machine-type", "e2-medium"), count)
+		case strings.HasPrefix(action, "sql instances create"):
+			e.add(estimate, "gcp cloud sql instance", flags.value("tier", "db-f1-micro"), 1)
+		}
+
+	case "az":
+		switch {
+		case strings.HasPrefix(action, "vm create"):
+			count, _ := strconv.Atoi(flags.value("count", "1"))
+			e.add(estimate, "azure vm", flags.value("size", "Standard_DS1_v2"), count)
+		case strings.HasPrefix(action, "aks create"):
+			count, _ := strconv.Atoi(flags.value("node-count", "3"))
+			e.add(estimate, "azure aks node", flags.value("node-vm-size", "Standard_DS2_v2"), count)
+		}
+
+	case "doctl":
+		if strings.HasPrefix(action, "compute droplet create") {
+			count := len(flags.positional) - 3
+			if count < 1 {
+				count = 1
+			}
+			e.add(estimate, "digitalocean droplet", flags.value("size", ""), count)
+		}
+
+	case "terraform", "tofu":
+		if len(flags.positional) > 0 && flags.positional[0] == "apply" {
+			if chdir := flags.value("chdir", ""); chdir != "" {
+				if !filepath.IsAbs(chdir) {
+					chdir = filepath.Join(dir, chdir)
+				}
+				dir = chdir
+			}
+			e.terraform(estimate, dir)
+		}
+	}
+}
+
+// flagSet is a command's flags and positional words
+type flagSet struct {
+	values     map[string]string
+	positional []string
+}
+
+// parseFlags splits a command's words after the program into flags, as
+// "--name value" or "--name=value", and positional words. A flag followed
+// by another flag or nothing is a switch.
+func parseFlags(words []string) flagSet {
+	flags := flagSet{values: map[string]string{}}
+	for i := 1; i < len(words); i++ {
+		word := strings.Trim(words[i], `'"`)
+		if !strings.HasPrefix(word, "-") {
+			flags.positional = append(flags.positional, word)
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if !ok && i+1 < len(words) && !strings.HasPrefix(words[i+1], "-") {
+			i++
+			value = strings.Trim(words[i], `'"`)
+		}
+		flags.values[name] = value
+	}
+	return flags
+}
+
+func (f flagSet) has(name string) bool {
+	_, ok := f.values[name]
+	return ok
+}
+
+// value returns a flag's value, or fallback when it is not given
+func (f flagSet) value(name, fallback string) string {
+	if v := f.values[name]; v != "" {
+		return v
+	}
+	return fallback
+}
+
+// m returns the first group pattern captures in s, or fallback
+func m(pattern *regexp.Regexp, s, fallback string) string {
+	if match := pattern.FindStringSubmatch(s); match != nil {
+		return match[1]
+	}
+	return fallback
+}
+
+// Terraform patterns; configuration is matched loosely rather than parsed
+var (
+	tfResource = regexp.MustCompile(`(?m)^\s*resource\s+"(\w+)"\s+"([\w-]+)"\s*\{`)
+	tfVariable = regexp.MustCompile(`(?s)variable\s+"([\w-]+)"\s*\{[^}]*?default\s*=\s*"?([\w.-]+)"?`)
+	tfCount    = regexp.MustCompile(`(?m)^\s*count\s*=\s*(\d+|var\.[\w-]+)`)
+	tfMultiAZ  = regexp.MustCompile(`(?m)^\s*multi_az\s*=\s*true`)
+)
+
+// tfResources are the Terraform resource types priced, with the attribute
+// holding the size (or a fixed price key) and the attribute holding the
+// number of nodes, if any
+var tfResources = map[string]struct {
+	size, nodes string
+	fixed       bool
+}{
+	"aws_instance":                    {size: "instance_type"},
+	"aws_db_instance":                 {size: "instance_class"},
+	"aws_elasticache_cluster":         {size: "node_type", nodes: "num_cache_nodes"},
+	"aws_eks_cluster":                 {size: "aws:eks-cluster", fixed: true},
+	"aws_eks_node_group":              {size: "instance_types", nodes: "desired_size"},
+	"aws_nat_gateway":                 {size: "aws:nat-gateway", fixed: true},
+	"aws_lb":                          {size: "aws:load-balancer", fixed: true},
+	"aws_alb":                         {size: "aws:load-balancer", fixed: true},
+	"google_compute_instance":         {size: "machine_type"},
+	"google_container_cluster":        {size: "gcp:gke-cluster", fixed: true},
+	"google_container_node_pool":      {size: "machine_type", nodes: "node_count"},
+	"google_sql_database_instance":    {size: "tier"},
+	"azurerm_linux_virtual_machine":   {size: "size"},
+	"azurerm_windows_virtual_machine": {size: "size"},
+	"azurerm_kubernetes_cluster":      {size: "vm_size", nodes: "node_count"},
+	"digitalocean_droplet":            {size: "size"},
+}
+
+// terraform adds the priced resources in the Terraform configuration in
+// dir. Every resource in the configuration is counted, including ones that
+// already exist.
+func (e *Estimator) terraform(estimate *Estimate, dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	sort.Strings(files)
+	var config strings.Builder
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil {
+			config.Write(data)
+			config.WriteByte('\n')
+		}
+	}
+	text := config.String()
+
+	defaults := map[string]string{}
+	for _, match := range tfVariable.FindAllStringSubmatch(text, -1) {
+		defaults[match[1]] = match[2]
+	}
+	resolve := func(value string) string {
+		if name, ok := strings.CutPrefix(value, "var."); ok {
+			return defaults[name]
+		}
+		return value
+	}
+
+	for _, loc := range tfResource.FindAllStringSubmatchIndex(text, -1) {
+		kind, name := text[loc[2]:loc[3]], text[loc[4]:loc[5]]
+		spec, ok := tfResources[kind]
+		if !ok {
+			continue
+		}
+		block := blockAt(text, loc[1]-1)
+
+		count := 1
+		if match := tfCount.FindStringSubmatch(block); match != nil {
+			count, _ = strconv.Atoi(resolve(match[1]))
+		}
+		size := spec.size
+		if !spec.fixed {
+			size = resolve(attribute(block, spec.size))
+		}
+		if spec.nodes != "" {
+			nodes, err := strconv.Atoi(resolve(attribute(block, spec.nodes)))
+			if err != nil {
+				nodes = 1
+			}
+			count *= nodes
+		}
+		if kind == "aws_db_instance" && tfMultiAZ.MatchString(block) {
+			count *= 2
+		}
+		if size == "" {
+			size = "unknown " + spec.size
+		}
+		e.add(estimate, kind+"."+name, size, count)
+	}
+}
+
+// blockAt returns the block whose opening brace is at start, braces
+// included, or the rest of text if it is not closed
+func blockAt(text string, start int) string {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return text[start : i+1]
+			}
+		}
+	}
+	return text[start:]
+}
+
+// attribute returns the first value assigned to name in a block, at any
+// depth: a string, number, variable, or the first string of a list
+func attribute(block, name string) string {
+	pattern := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(name) + `\s*=\s*\[?\s*"?([\w.-]+)"?`)
+	return m(pattern, block, "")
+}
+
+// Format returns a monthly cost in dollars, to the cent below $100
+func Format(monthly float64) string {
+	if monthly < 100 {
+		return fmt.Sprintf("$%.2f", monthly)
+	}
+	return fmt.Sprintf("$%.0f", monthly)
+}
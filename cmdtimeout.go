@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultCommandTimeout stops a command when command_timeout is unset
+const defaultCommandTimeout = 10 * time.Minute
+
+// commandTimeout returns how long a command may run, or 0 for no limit
+func (e *Executor) commandTimeout() time.Duration {
+	switch {
+	case e.config.CommandTimeout < 0:
+		return 0
+	case e.config.CommandTimeout == 0:
+		return defaultCommandTimeout
+	}
+	return time.Duration(e.config.CommandTimeout) * time.Second
+}
+
+// withTimeout runs a command, stopping it once it has run for the command
+// timeout. A stopped command fails with an *ErrCommandFailed wrapping
+// ErrCommandTimeout, keeping whatever it printed to stderr.
+func (e *Executor) withTimeout(ctx context.Context, cmdStr string, run func(ctx context.Context) error) error {
+	timeout := e.commandTimeout()
+	if timeout == 0 {
+		return run(ctx)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := run(runCtx)
+	if err == nil || ctx.Err() != nil || !errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	e.logger.Warn("Stopped %s after %s", cmdStr, timeout)
+	e.audit.Record("command_timeout", map[string]string{"command": cmdStr, "timeout": timeout.String()})
+	failed := &ErrCommandFailed{Command: cmdStr, ExitCode: -1, Err: fmt.Errorf("%w after %s", ErrCommandTimeout, timeout)}
+	var cmdErr *ErrCommandFailed
+	if errors.As(err, &cmdErr) {
+		failed.Stderr = cmdErr.Stderr
+	}
+	return failed
+}
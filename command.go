@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Command is a structured command: a program and its arguments executed
+// directly, without a shell, so arguments never need quoting
+type Command struct {
+	Program string            `json:"program"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+}
+
+// safeWord matches arguments that need no quoting in any shell
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// Render returns the equivalent shell command line for the given OS, used for
+// display, validation, and the execution journal
+func (c Command) Render(goos string) string {
+	quote := Quote
+	if goos == "windows" {
+		quote = QuotePowerShell
+	}
+
+	var parts []string
+	if c.Dir != "" {
+		if goos == "windows" {
+			parts = append(parts, "Set-Location "+quote(c.Dir)+";")
+		} else {
+			parts = append(parts, "cd "+quote(c.Dir)+" &&")
+		}
+	}
+
+	for _, key := range c.envKeys() {
+		if goos == "windows" {
+			parts = append(parts, "$env:"+key+"="+quote(c.Env[key])+";")
+		} else {
+			parts = append(parts, key+"="+quote(c.Env[key]))
+		}
+	}
+
+	parts = append(parts, quote(c.Program))
+	for _, arg := range c.Args {
+		parts = append(parts, quote(arg))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Quote quotes a string for POSIX shells
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if safeWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// QuotePowerShell quotes a string for PowerShell
+func QuotePowerShell(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if safeWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// envKeys returns environment variable names in a stable order
+func (c Command) envKeys() []string {
+	keys := make([]string, 0, len(c.Env))
+	for key := range c.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stepCommand prepares a structured command to run without a shell
+func (e *Executor) stepCommand(ctx context.Context, step Command) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, step.Program, step.Args...)
+	cmd.Dir = step.Dir
+	cmd.Env = e.environ()
+	if len(step.Env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		for _, key := range step.envKeys() {
+			cmd.Env = append(cmd.Env, key+"="+step.Env[key])
+		}
+	}
+	return cmd
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"devos/internal/ai"
+)
+
+// streamKey is the context key of the function that receives a plan's
+// explanation as it is generated
+type streamKey struct{}
+
+// outputKey starts the explanation in a plan's JSON
+var outputKey = regexp.MustCompile(`"output"\s*:\s*"`)
+
+// WithStream returns a context in which plans pass their explanation to
+// onText as the provider generates it. Providers that cannot stream pass it
+// once the plan is complete, and the Python engine does not pass it at all.
+func WithStream(ctx context.Context, onText func(text string)) context.Context {
+	return context.WithValue(ctx, streamKey{}, onText)
+}
+
+// outputStream picks the "output" string out of a plan's JSON as it arrives
+// in pieces, and passes it on unescaped
+type outputStream struct {
+	onText func(string)
+	reply  string
+	pos    int // Where decoding continues in reply; 0 until "output" starts
+	done   bool
+}
+
+func (s *outputStream) write(chunk string) {
+	if s.done {
+		return
+	}
+	s.reply += chunk
+	if s.pos == 0 {
+		m := outputKey.FindStringIndex(s.reply)
+		if m == nil {
+			return
+		}
+		s.pos = m[1]
+	}
+
+	var text strings.Builder
+	for s.pos < len(s.reply) {
+		c := s.reply[s.pos]
+		if c == '"' {
+			s.done = true
+			break
+		}
+		if c != '\\' {
+			text.WriteByte(c)
+			s.pos++
+			continue
+		}
+		// Wait for the rest of an escape split across pieces
+		size := 2
+		if s.pos+1 < len(s.reply) && s.reply[s.pos+1] == 'u' {
+			size = 6
+		}
+		if s.pos+size > len(s.reply) {
+			break
+		}
+		switch esc := s.reply[s.pos+1]; esc {
+		case 'n':
+			text.WriteByte('\n')
+		case 't':
+			text.WriteByte('\t')
+		case 'u':
+			if r, err := strconv.ParseUint(s.reply[s.pos+2:s.pos+6], 16, 32); err == nil {
+				text.WriteRune(rune(r))
+			}
+		case 'r', 'b', 'f':
+		default:
+			text.WriteByte(esc)
+		}
+		s.pos += size
+	}
+	if text.Len() > 0 {
+		s.onText(text.String())
+	}
+}
+
+// provider returns the Go provider called name: the builtin model, which
+// belongs to the executor, or one registered with ai. Providers without one
+// go through the Python engine.
+func (e *Executor) provider(name string) (ai.Provider, bool) {
+	if name == "builtin" {
+		return builtinProvider{e}, true
+	}
+	return ai.Lookup(name)
+}
+
+// complete generates a plan with a provider called from Go, without the
+// Python engine
+func (e *Executor) complete(ctx context.Context, provider ai.Provider, engineRequest map[string]interface{}) (*ExecutionResult, error) {
+	system, err := e.planSystem(engineRequest)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, _ := engineRequest["api_key"].(string)
+	baseURL, _ := engineRequest["base_url"].(string)
+	model, _ := engineRequest["model"].(string)
+	input, _ := engineRequest["input"].(string)
+
+	request := ai.Request{
+		Model:         model,
+		System:        system,
+		Messages:      []ai.Message{{Role: "user", Content: input}},
+		MaxTokens:     e.config.MaxTokens,
+		Temperature:   e.config.Temperature,
+		ContextLength: e.config.ContextLength,
+		JSON:          true,
+		APIKey:        apiKey,
+		BaseURL:       baseURL,
+		Safety:        e.config.GeminiSafety,
+		Deployment:    e.config.AzureDeployment,
+		APIVersion:    e.config.AzureAPIVersion,
+	}
+	var resp *ai.Response
+	if onText, ok := ctx.Value(streamKey{}).(func(string)); ok {
+		resp, err = provider.Stream(ctx, request, (&outputStream{onText: onText}).write)
+	} else {
+		resp, err = provider.Complete(ctx, request)
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%w: %s did not answer in time", ErrProviderTimeout, provider.Name())
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ai.ErrModelNotFound) {
+			return nil, fmt.Errorf("%w: %s is not pulled in %s", ErrModelNotFound, model, provider.Name())
+		}
+		// The builtin model already reports errors as the executor's
+		if errors.Is(err, ErrProviderFailed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, err)
+	}
+
+	result := parsePlan(resp.Text)
+	if result == nil {
+		if resp.Truncated {
+			return nil, fmt.Errorf("%w: the plan was cut off at max_tokens (%d); raise \"max_tokens\" in config", ErrProviderFailed, e.config.MaxTokens)
+		}
+		return nil, fmt.Errorf("%w: %s did not return a plan - output: %s", ErrProviderFailed, provider.Name(), resp.Text)
+	}
+	result.TokensUsed = resp.InputTokens + resp.OutputTokens
+	return result, nil
+}
@@ -1,50 +1,555 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"devos/internal/configfmt"
+	"devos/internal/gemini"
+	"devos/internal/retry"
+	"devos/internal/vault"
 )
 
+// ErrInvalidConfig is wrapped by errors caused by malformed or invalid settings
+var ErrInvalidConfig = errors.New("invalid configuration")
+
 // Config represents the DevOS configuration
 type Config struct {
 	// System
 	OS         string `json:"os"`
 	ConfigPath string `json:"config_path"`
+	Shell      string `json:"shell"`              // Unix execution shell: sh, bash, zsh, fish, nu
+	Timezone   string `json:"timezone,omitempty"` // IANA zone for displayed times, e.g. "Europe/Berlin"; empty uses the system zone
+	Locale     string `json:"locale,omitempty"`   // Date format locale, e.g. "en_US"; empty uses LC_TIME/LANG
 
 	// AI Configuration
-	AIProvider string `json:"ai_provider"` // openai, anthropic, gemini, ollama
-	Model      string `json:"model"`
-	APIKey     string `json:"api_key,omitempty"`
-	BaseURL    string `json:"base_url,omitempty"` // For Ollama or custom endpoints
+	AIProvider    string `json:"ai_provider"`              // openai, openai-compatible, azure-openai, anthropic, gemini, ollama
+	Model         string `json:"model"`                    // "auto" picks a local model sized to the machine
+	ContextLength int    `json:"context_length,omitempty"` // Local model context window in tokens; 0 sizes it to free memory
+	APIKey        string `json:"api_key,omitempty"`
+	BaseURL       string `json:"base_url,omitempty"` // For Ollama, OpenAI-compatible servers (e.g. http://localhost:1234/v1), or custom endpoints
+	AITimeout     int    `json:"ai_timeout"`         // Seconds before an AI request is abandoned
+
+	// Largest file mentioned as @path attached whole to a prompt, in bytes
+	// (default 65536); larger text files are attached as their first lines,
+	// and binaries and minified bundles are left out
+	AttachMaxBytes int `json:"attach_max_bytes,omitempty"`
+
+	// Azure OpenAI: the resource endpoint (e.g.
+	// https://contoso.openai.azure.com), the deployment serving the model
+	// (default: model), and the API version (default 2024-10-21)
+	AzureEndpoint   string `json:"azure_endpoint,omitempty"`
+	AzureDeployment string `json:"azure_deployment,omitempty"`
+	AzureAPIVersion string `json:"azure_api_version,omitempty"`
+
+	// Gemini safety settings, passed through as given: harm category (e.g.
+	// HARM_CATEGORY_DANGEROUS_CONTENT) to threshold (e.g. BLOCK_ONLY_HIGH)
+	GeminiSafety map[string]string `json:"gemini_safety,omitempty"`
+
+	// Retries of transient failures: "default" applies to AI providers and
+	// downloads, and "downloads", "commands", or a provider name override
+	// it. Commands are only retried when "commands" is set, and only if
+	// they are safe to re-run, e.g. {"commands": {"attempts": 3,
+	// "backoff": 2, "patterns": ["Could not get lock"]}}; a failed step is
+	// retried before the rest of the plan is given up.
+	Retry map[string]retry.Policy `json:"retry,omitempty"`
+
+	// Providers to try in order when the configured one fails or times out,
+	// as "provider" or "provider:model" (e.g. ["openai", "ollama:auto"]).
+	// Cloud providers other than the configured one take their API key
+	// from the environment (OPENAI_API_KEY, AZURE_OPENAI_API_KEY,
+	// ANTHROPIC_API_KEY, GOOGLE_API_KEY), and openai-compatible its server
+	// from OPENAI_BASE_URL.
+	Failover []string `json:"failover,omitempty"`
+
+	// Spending limits on AI providers, and prices to estimate spend from
+	// token counts (dollars per million tokens by model name prefix)
+	Budgets     []Budget           `json:"budgets,omitempty"`
+	TokenPrices map[string]float64 `json:"token_prices,omitempty"`
+
+	// Projects and paths whose context never leaves this machine: tasks in
+	// them go to the local model, "provider:model" (default "ollama:auto")
+	LocalOnly  []string `json:"local_only,omitempty"`
+	LocalModel string   `json:"local_model,omitempty"`
 
 	// Behavior
-	ConfirmationMode bool   `json:"confirmation_mode"`
-	LogLevel         string `json:"log_level"` // debug, info, warn, error
-	MaxTokens        int    `json:"max_tokens"`
-	Temperature      float64 `json:"temperature"`
+	ConfirmationMode bool      `json:"confirmation_mode"`
+	TrackChanges     bool      `json:"track_changes"`            // Summarize the files and packages each plan changed
+	ObserveMode      bool      `json:"observe_mode"`             // Only read-only commands run without approval
+	DryRun           bool      `json:"dry_run,omitempty"`        // Print plans' resolved commands instead of running them
+	Simulate         bool      `json:"simulate,omitempty"`       // Rehearse plans in a throwaway container before asking to run them
+	SimulateImage    string    `json:"simulate_image,omitempty"` // Image for rehearsals; empty matches the host distribution
+	CommandTimeout   int       `json:"command_timeout"`          // Seconds before a command is stopped; 0 uses the default (600), -1 never
+	LogLevel         string    `json:"log_level"`                // debug, info, warn, error
+	LogSinks         []LogSink `json:"log_sinks,omitempty"`      // Central destinations that also receive log lines
+	MaxTokens        int       `json:"max_tokens"`
+	Temperature      float64   `json:"temperature"`
+
+	// How command output is transformed before display: auto (default),
+	// none, json, table, or strip-ansi, applied in order. The first rule
+	// matching a command overrides the default.
+	OutputProcessors []string     `json:"output_processors,omitempty"`
+	OutputRules      []OutputRule `json:"output_rules,omitempty"`
 
 	// Security
 	SandboxMode     bool     `json:"sandbox_mode"`
 	AllowedCommands []string `json:"allowed_commands,omitempty"`
+	AllowlistOnly   bool     `json:"allowlist_only,omitempty"` // Run only commands starting with an allowed_commands entry, e.g. "git" or "systemctl status"
 	BlockedCommands []string `json:"blocked_commands"`
+	ImageScanner    string   `json:"image_scanner,omitempty"` // Scan images before deploy: trivy, grype, or auto
+
+	// Monthly cost estimates for plans that create cloud resources: hourly
+	// prices in dollars by instance type (e.g. "t3.large") or resource (e.g.
+	// "aws:nat-gateway"), overriding the built-in ones, and the monthly
+	// dollars above which the cost must be confirmed (default 100)
+	CloudPrices   map[string]float64 `json:"cloud_prices,omitempty"`
+	CostThreshold float64            `json:"cost_threshold,omitempty"`
+
+	// Header added to source files the AI creates; {model}, {provider}, and
+	// {date} are substituted
+	GeneratedHeader string `json:"generated_header,omitempty"`
+
+	// Approval policy (evaluated in order, first match wins)
+	ApprovalRules []ApprovalRule `json:"approval_rules,omitempty"`
+
+	// Plans run as transactions: when a step fails, the completed steps are
+	// reversed, last first, by the commands the AI gave for them or those
+	// of the first matching undo rule (checked before the built-in ones).
+	// RollbackOnFailure is "ask" (default), "always", or "never".
+	UndoRules         []UndoRule `json:"undo_rules,omitempty"`
+	RollbackOnFailure string     `json:"rollback_on_failure,omitempty"`
+
+	// Change freezes, during which mutating plans need an explicit override
+	Freezes []Freeze `json:"freezes,omitempty"`
+
+	// Organization guardrails: a signed prompt preamble and policy bundle
+	// injected into every AI request. On a managed machine the managed
+	// config's guardrails are used instead.
+	Guardrails *Guardrails `json:"guardrails,omitempty"`
 
 	// Plugins
-	Plugins       []string `json:"plugins"`
-	PluginPath    string   `json:"plugin_path"`
+	Plugins    []string `json:"plugins"`
+	PluginPath string   `json:"plugin_path"`
 
 	// Memory
-	MemoryPath string `json:"memory_path"`
-	MemorySize int    `json:"memory_size"` // Max context items
+	MemoryBackend string `json:"memory_backend,omitempty"` // sqlite (default) or postgres
+	MemoryPath    string `json:"memory_path"`
+	MemoryDSN     string `json:"memory_dsn,omitempty"` // Postgres connection string for a shared memory database
+	MemorySize    int    `json:"memory_size"`          // Max context items
+
+	// Audit
+	AuditPath    string        `json:"audit_path"`
+	AuditExports []AuditExport `json:"audit_exports,omitempty"` // SIEMs that receive audit events as they are recorded
+
+	// Session
+	IdleTimeout int     `json:"idle_timeout"`          // Minutes of inactivity before the REPL locks (0 disables)
+	Tmux        bool    `json:"tmux"`                  // Run long-running commands as tasks in a DevOS tmux session
+	Speech      *Speech `json:"speech,omitempty"`      // Spoken summaries of task outcomes
+	Accessible  string  `json:"accessible,omitempty"`  // Plain screen-reader output: on, off, or auto (default: on when a screen reader is detected)
+	Autosuggest string  `json:"autosuggest,omitempty"` // Suggest earlier prompts as you type: on, off, or auto (default: on unless output is accessible)
+
+	// After a multi-step task changes the git repository: "ask" (default)
+	// offers to write a CHANGELOG entry or PR description of the changes,
+	// "off" does not
+	ChangeSummary string `json:"change_summary,omitempty"`
+
+	// Named groups of repositories a task can span with "repos <group>
+	// <task>", e.g. {"services": ["~/src/api", "~/src/web", "~/src/worker"]}
+	Repos map[string][]string `json:"repos,omitempty"`
+
+	// The organization's release process for "release <version>"; a
+	// project's .devos/release.json overrides it step by step
+	Release *Release `json:"release,omitempty"`
+
+	// Remote targets (SSH)
+	Targets           []Target `json:"targets,omitempty"`
+	CanaryHealthCheck string   `json:"canary_health_check,omitempty"` // Run on the canary before continuing a rollout
+
+	// Execution environments, e.g. dev, staging, and prod; plans run in the
+	// active one (switched with "env use <name>")
+	Environments []Environment `json:"environments,omitempty"`
+	Environment  string        `json:"environment,omitempty"`
+
+	// Checks of certificates, DNS records, and reachability, run with
+	// "devos monitor" and by the daemon on their schedule
+	Monitors []Monitor `json:"monitors,omitempty"`
+
+	// Daemon (team mode)
+	DaemonSocket      string     `json:"daemon_socket,omitempty"`
+	DaemonAddr        string     `json:"daemon_addr,omitempty"`     // Optional TCP address for the web dashboard
+	DaemonTLSCert     string     `json:"daemon_tls_cert,omitempty"` // Certificate and key serving daemon_addr over HTTPS; required unless it is a loopback address
+	DaemonTLSKey      string     `json:"daemon_tls_key,omitempty"`
+	DaemonAttach      string     `json:"daemon_attach,omitempty"` // auto (default): the REPL attaches to a running daemon; never
+	DaemonUser        string     `json:"daemon_user,omitempty"`   // Team user the REPL attaches as (default: the OS user name)
+	TeamUsers         []TeamUser `json:"team_users,omitempty"`
+	OIDC              *OIDC      `json:"oidc,omitempty"` // Log team users in through an identity provider instead of static tokens
+	TwoPersonApproval bool       `json:"two_person_approval"`
+	SlackWebhookURL   string     `json:"slack_webhook_url,omitempty"`
+
+	// Issue tracker for "devos issue"
+	IssueTracker *IssueTracker `json:"issue_tracker,omitempty"`
+
+	// Sections stored encrypted under "encrypted", with a key from the OS
+	// keychain or a passphrase (see "devos config encrypt")
+	EncryptedSections []string `json:"encrypted_sections,omitempty"`
+
+	vaultKey *vault.Key
+	managed  *managedConfig
+}
+
+// SecretSections are the config keys that can be encrypted, and those
+// encrypted by default
+var SecretSections = []string{"api_key", "slack_webhook_url", "memory_dsn", "issue_tracker", "log_sinks", "team_users", "oidc", "audit_exports", "targets", "environments"}
+
+// sealedKey holds the encrypted sections in the config file
+const sealedKey = "encrypted"
+
+// Target is a remote host reachable over SSH
+type Target struct {
+	Name         string `json:"name"`
+	Host         string `json:"host"`
+	User         string `json:"user,omitempty"`
+	Port         int    `json:"port,omitempty"`
+	IdentityFile string `json:"identity_file,omitempty"`
+	Canary       bool   `json:"canary,omitempty"`       // Run first during rollouts
+	HealthCheck  string `json:"health_check,omitempty"` // Overrides canary_health_check
+}
+
+// Environment binds plans to where they should run: the targets, variables,
+// and Kubernetes context of one stage, and stricter policies for it
+type Environment struct {
+	Name          string            `json:"name"`
+	Targets       []string          `json:"targets,omitempty"`        // Names of the targets rollouts use; empty uses all
+	Env           map[string]string `json:"env,omitempty"`            // Variables set for every command
+	Kubeconfig    string            `json:"kubeconfig,omitempty"`     // Empty uses KUBECONFIG or ~/.kube/config
+	KubeContext   string            `json:"kube_context,omitempty"`   // Context kubectl and helm use, whatever the current one is
+	KubeNamespace string            `json:"kube_namespace,omitempty"` // Namespace cluster changes are expected in
+	Projects      []string          `json:"projects,omitempty"`       // Project roots that belong to this environment
+	ApprovalRules []ApprovalRule    `json:"approval_rules,omitempty"` // Checked before the global approval rules
+	Protected     bool              `json:"protected,omitempty"`      // Changes need the environment's name typed to confirm, and two approvers on the daemon
+}
+
+// ActiveEnvironment returns the environment plans run in, or nil when none
+// is selected
+func (c *Config) ActiveEnvironment() *Environment {
+	for i := range c.Environments {
+		if c.Environments[i].Name == c.Environment {
+			return &c.Environments[i]
+		}
+	}
+	return nil
+}
+
+// UseEnvironment makes the named environment active; "" leaves none active
+func (c *Config) UseEnvironment(name string) error {
+	if name != "" && !slices.ContainsFunc(c.Environments, func(e Environment) bool { return e.Name == name }) {
+		var names []string
+		for _, e := range c.Environments {
+			names = append(names, e.Name)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("unknown environment %q (none are configured under \"environments\")", name)
+		}
+		return fmt.Errorf("unknown environment %q (environments: %s)", name, strings.Join(names, ", "))
+	}
+	c.Environment = name
+	return nil
+}
+
+// ActiveTargets returns the targets of the active environment, or every
+// target when it does not name any
+func (c *Config) ActiveTargets() []Target {
+	env := c.ActiveEnvironment()
+	if env == nil || len(env.Targets) == 0 {
+		return c.Targets
+	}
+	var targets []Target
+	for _, t := range c.Targets {
+		if slices.Contains(env.Targets, t.Name) {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// Retry policy names besides the providers
+const (
+	RetryDefault   = "default"
+	RetryDownloads = "downloads"
+	RetryCommands  = "commands"
+)
+
+// RetryPolicy returns the retry policy for a provider or one of the Retry
+// names: its override on top of the default policy
+func (c *Config) RetryPolicy(name string) retry.Policy {
+	policy := retry.Default.Merge(c.Retry[RetryDefault])
+	if override, ok := c.Retry[name]; ok {
+		return policy.Merge(override)
+	}
+	if name == RetryCommands {
+		policy.Attempts = 1
+	}
+	return policy
+}
+
+// Rules returns the approval rules in effect: the active environment's
+// rules, then the global ones
+func (c *Config) Rules() []ApprovalRule {
+	env := c.ActiveEnvironment()
+	if env == nil || len(env.ApprovalRules) == 0 {
+		return c.ApprovalRules
+	}
+	return append(slices.Clone(env.ApprovalRules), c.ApprovalRules...)
+}
+
+// TeamUser is an authenticated user of the team daemon, by a static token
+// or by logging in through OIDC as their identity
+type TeamUser struct {
+	Name     string `json:"name"`
+	Token    string `json:"token,omitempty"`
+	Identity string `json:"identity,omitempty"` // Value of the OIDC claim that logs in as this user, e.g. an email address
+}
+
+// OIDC configures logging in to the daemon through an OpenID Connect
+// identity provider. A login issues an API token for the team user whose
+// identity matches the ID token's claim.
+type OIDC struct {
+	Issuer       string `json:"issuer"` // e.g. https://accounts.google.com
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RedirectURL  string `json:"redirect_url"`        // The daemon's /auth/callback as the identity provider reaches it back
+	Claim        string `json:"claim,omitempty"`     // ID token claim matched against identities (default email)
+	TokenTTL     string `json:"token_ttl,omitempty"` // How long a login's API token lasts (default 12h)
+}
+
+// Validate checks the OIDC settings
+func (o *OIDC) Validate() error {
+	if o.Issuer == "" || o.ClientID == "" || o.RedirectURL == "" {
+		return fmt.Errorf("oidc requires issuer, client_id, and redirect_url")
+	}
+	if u, err := url.Parse(o.Issuer); err != nil || (u.Scheme != "https" && u.Hostname() != "localhost") {
+		return fmt.Errorf("oidc issuer must be an https URL, got %q", o.Issuer)
+	}
+	if u, err := url.Parse(o.RedirectURL); err != nil || u.Host == "" {
+		return fmt.Errorf("invalid oidc redirect_url: %q", o.RedirectURL)
+	}
+	if o.TokenTTL != "" {
+		if d, err := time.ParseDuration(o.TokenTTL); err != nil || d <= 0 {
+			return fmt.Errorf("invalid oidc token_ttl: %q", o.TokenTTL)
+		}
+	}
+	return nil
+}
+
+// LogSink ships log lines to a central destination, such as syslog or Loki,
+// in addition to the per-day files under the user's home directory
+type LogSink struct {
+	Type    string            `json:"type"`              // syslog, file, http, loki
+	Target  string            `json:"target,omitempty"`  // udp://host:514 or unix:///dev/log, file path, or URL
+	Level   string            `json:"level,omitempty"`   // Minimum level; defaults to log_level
+	Labels  map[string]string `json:"labels,omitempty"`  // Extra Loki stream labels
+	Headers map[string]string `json:"headers,omitempty"` // Extra request headers, e.g. Authorization
+}
+
+// AuditExport forwards audit events to a SIEM in near real time
+type AuditExport struct {
+	Type    string            `json:"type"`              // splunk (HTTP Event Collector), elastic, or http
+	Target  string            `json:"target"`            // Splunk HEC or Elasticsearch base URL, or the http endpoint
+	Format  string            `json:"format,omitempty"`  // json (default) or cef
+	Token   string            `json:"token,omitempty"`   // Splunk HEC token, Elasticsearch API key, or http bearer token
+	Index   string            `json:"index,omitempty"`   // Splunk or Elasticsearch index (default devos-audit)
+	Headers map[string]string `json:"headers,omitempty"` // Extra request headers
+}
+
+// IssueTracker connects DevOS to Jira or Linear, to plan work from
+// assigned tickets and report back on them
+type IssueTracker struct {
+	Type    string `json:"type"`              // jira or linear
+	URL     string `json:"url,omitempty"`     // Jira site, e.g. https://example.atlassian.net
+	Email   string `json:"email,omitempty"`   // Jira Cloud account email; omit to use a Data Center personal access token
+	Token   string `json:"token"`             // Jira API token or Linear API key
+	Comment string `json:"comment,omitempty"` // Post summaries on the ticket when work completes: ask (default), always, or never
+}
+
+// Speech events
+const (
+	SpeakSuccess    = "success"    // A task succeeded
+	SpeakFailure    = "failure"    // A task failed
+	SpeakLong       = "long"       // A task ran longer than long_task seconds, whatever its outcome
+	SpeakBackground = "background" // A tmux task finished
+)
+
+// Speech reads short summaries of task outcomes aloud, for accessibility and
+// hands-free use
+type Speech struct {
+	Enabled  bool     `json:"enabled"`
+	Events   []string `json:"events,omitempty"`    // success, failure, long, background; empty speaks all
+	LongTask int      `json:"long_task,omitempty"` // Seconds before a task counts as long (default 30)
+}
+
+// Speaks reports whether the event should be spoken
+func (s *Speech) Speaks(event string) bool {
+	if s == nil || !s.Enabled {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// LongTaskDuration returns how long a task runs before it counts as long
+func (s *Speech) LongTaskDuration() time.Duration {
+	if s == nil || s.LongTask <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.LongTask) * time.Second
+}
+
+// ApprovalRule declaratively decides whether matching commands run
+// automatically ("allow"), require confirmation ("ask"), or are refused ("deny").
+// All non-empty criteria must match.
+type ApprovalRule struct {
+	Name     string   `json:"name"`
+	Classes  []string `json:"classes,omitempty"`  // read-only, package, file-write, destructive, network, privileged, process, unknown
+	Programs []string `json:"programs,omitempty"` // e.g. ["terraform", "kubectl"]
+	Paths    []string `json:"paths,omitempty"`    // path prefixes, e.g. ["/etc"]
+	Projects []string `json:"projects,omitempty"` // Project roots the rule is limited to
+	Action   string   `json:"action"`             // allow, ask, deny
+
+	// Sandbox lets an allow rule also exempt matching commands from the
+	// built-in blocked-command and dangerous-pattern checks
+	Sandbox bool `json:"sandbox,omitempty"`
+}
+
+// Budget actions, taken once a budget is used up
+const (
+	BudgetWarn      = "warn"      // Keep using the provider, with a warning on every plan
+	BudgetDowngrade = "downgrade" // Switch to the fallback model
+	BudgetBlock     = "block"     // Refuse to call the provider
+)
+
+// Budget limits the tokens or estimated dollars spent on a provider per day
+// or month
+type Budget struct {
+	Provider string  `json:"provider"`           // openai, anthropic, gemini, ...; "*" covers every cloud provider
+	Period   string  `json:"period"`             // day or month
+	Tokens   int     `json:"tokens,omitempty"`   // Token limit for the period
+	Dollars  float64 `json:"dollars,omitempty"`  // Spend limit for the period, estimated from token_prices
+	WarnAt   int     `json:"warn_at,omitempty"`  // Percent used before plans carry a warning (default 80)
+	Action   string  `json:"action,omitempty"`   // Once used up: warn (default), downgrade, or block
+	Fallback string  `json:"fallback,omitempty"` // Model to downgrade to, e.g. "gpt-4o-mini" or "ollama:auto"
+}
+
+// OutputRule picks the output processors for commands matching a pattern
+type OutputRule struct {
+	Match      string   `json:"match"` // Regular expression, e.g. "^kubectl get .* -o json"
+	Processors []string `json:"processors"`
+}
+
+// UndoRule gives the command that reverses commands matching a pattern
+type UndoRule struct {
+	Match string `json:"match"` // Regular expression, e.g. "^terraform workspace new (\\S+)$"
+	Undo  string `json:"undo"`  // $1 or ${name} expand to the match's groups, e.g. "terraform workspace delete $1"
+}
+
+// Release configures the steps of a release: bump, changelog, tag, build,
+// and publish. Commands may use {{version}} and {{tag}}; steps left empty
+// use what DevOS detects for the project.
+type Release struct {
+	Tag       string   `json:"tag,omitempty"`       // Tag name, default "v{{version}}"
+	Bump      []string `json:"bump,omitempty"`      // Commands that set the version, e.g. ["npm version {{version}} --no-git-tag-version"]
+	Changelog string   `json:"changelog,omitempty"` // File the release notes are added to, default CHANGELOG.md
+	Build     []string `json:"build,omitempty"`     // e.g. ["make dist"]
+	Publish   []string `json:"publish,omitempty"`   // Default: push the release commit and tag
+	Skip      []string `json:"skip,omitempty"`      // Steps to leave out, e.g. ["build"]
+}
+
+// ReleaseSteps are the steps of a release, in order
+var ReleaseSteps = []string{"bump", "changelog", "tag", "build", "publish"}
+
+// Validate checks that the steps to skip are release steps
+func (r *Release) Validate() error {
+	for _, step := range r.Skip {
+		if !slices.Contains(ReleaseSteps, step) {
+			return fmt.Errorf("release cannot skip %q (expected %s)", step, strings.Join(ReleaseSteps, ", "))
+		}
+	}
+	return nil
+}
+
+// Freeze is a change-freeze window, given as a cron schedule or an iCal
+// feed whose events are freezes
+type Freeze struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule,omitempty"` // Cron expression (minute hour day month weekday), e.g. "0 17 * * fri"
+	Duration string `json:"duration,omitempty"` // How long each freeze lasts from a schedule match, e.g. "63h"; empty covers only the matching minutes
+	ICal     string `json:"ical,omitempty"`     // iCal feed URL or file
+	Reason   string `json:"reason,omitempty"`   // Shown when a plan runs into the freeze
+}
+
+// Guardrails locates an organization's guardrails bundle: JSON with a
+// prompt "preamble", "blocked_commands", and "approval_rules", signed with
+// Ed25519. The signature is fetched from the URL with ".sig" appended.
+type Guardrails struct {
+	URL       string `json:"url"`               // https URL or file path of the bundle
+	PublicKey string `json:"public_key"`        // Base64 Ed25519 public key the bundle is signed with
+	Refresh   string `json:"refresh,omitempty"` // How long a fetched bundle is used before fetching it again (default 24h)
+}
+
+// Validate checks the bundle location and key
+func (g *Guardrails) Validate() error {
+	if g.URL == "" {
+		return fmt.Errorf("guardrails url is required")
+	}
+	if key, err := base64.StdEncoding.DecodeString(g.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("guardrails public_key must be a base64 Ed25519 public key")
+	}
+	if g.Refresh != "" {
+		if d, err := time.ParseDuration(g.Refresh); err != nil || d <= 0 {
+			return fmt.Errorf("guardrails refresh must be a positive duration")
+		}
+	}
+	return nil
+}
+
+// Monitor is a recurring check. The daemon runs it every interval and posts
+// to the Slack webhook when its status changes.
+type Monitor struct {
+	Name     string   `json:"name"`
+	Check    string   `json:"check"`               // cert, dns, or reachable
+	Target   string   `json:"target"`              // Host, host:port, or URL
+	Record   string   `json:"record,omitempty"`    // DNS record type: A (default), AAAA, CNAME, MX, NS, or TXT
+	Expect   []string `json:"expect,omitempty"`    // DNS values the record must include
+	WarnDays int      `json:"warn_days,omitempty"` // Days before a certificate expires to warn (default 14)
+	Every    string   `json:"every,omitempty"`     // How often the daemon runs the check, e.g. "6h" (default 1h)
 }
 
 // Default configuration values
 var DefaultConfig = Config{
 	AIProvider:       "ollama",
 	Model:            "llama3.2",
+	Shell:            "sh",
+	IdleTimeout:      15,
+	AITimeout:        120,
+	CommandTimeout:   600,
 	ConfirmationMode: true,
+	TrackChanges:     true,
 	LogLevel:         "info",
 	MaxTokens:        2048,
 	Temperature:      0.7,
@@ -56,10 +561,24 @@ var DefaultConfig = Config{
 		"format",
 		":(){:|:&};:",
 	},
-	Plugins:    []string{},
-	MemorySize: 100,
+	// The first matching rule decides, so the path rules come before
+	// auto-approving read-only commands such as cat /etc/shadow
+	ApprovalRules: []ApprovalRule{
+		{Name: "never-touch-etc", Paths: []string{"/etc"}, Action: "deny"},
+		{Name: "ask-ssh-files", Paths: []string{"~/.ssh", "$HOME/.ssh"}, Action: "ask"},
+		{Name: "auto-approve-read-only", Classes: []string{"read-only"}, Action: "allow"},
+		{Name: "ask-package-changes", Classes: []string{"package"}, Action: "ask"},
+	},
+	Plugins:           []string{},
+	MemoryBackend:     "sqlite",
+	MemorySize:        100,
+	TwoPersonApproval: true,
 }
 
+// configNames are the config file names DevOS looks for; the format follows
+// the extension
+var configNames = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
 // Load reads the configuration from the config file or creates a default one
 func Load() (*Config, error) {
 	configDir, err := getConfigDir()
@@ -67,26 +586,38 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	configPath := filepath.Join(configDir, "config.json")
-
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	configPath, err := findConfig(configDir)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := loadManaged()
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	if configPath == "" {
+		configPath = filepath.Join(configDir, "config.json")
 		// Create default config
 		config := DefaultConfig
 		config.OS = runtime.GOOS
 		config.ConfigPath = configPath
 		config.PluginPath = filepath.Join(configDir, "plugins")
 		config.MemoryPath = filepath.Join(configDir, "memory.db")
+		config.AuditPath = filepath.Join(configDir, "audit.log")
 
 		if err := config.Save(); err != nil {
 			return nil, fmt.Errorf("failed to save default config: %w", err)
 		}
 
+		if managed != nil {
+			managed.apply(&config)
+		}
 		return &config, nil
 	}
 
@@ -95,25 +626,77 @@ func Load() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	if data, err = configfmt.ToJSON(data, configfmt.FormatOf(configPath)); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s: %w", ErrInvalidConfig, filepath.Base(configPath), err)
+	}
+	data, sealed, key, err := openSections(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", filepath.Base(configPath), err)
+	}
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse config file: %w", ErrInvalidConfig, err)
+	}
+
+	config.vaultKey = key
+	for _, name := range sealed {
+		if !slices.Contains(config.EncryptedSections, name) {
+			config.EncryptedSections = append(config.EncryptedSections, name)
+		}
 	}
 
 	// Update OS and paths
 	config.OS = runtime.GOOS
 	config.ConfigPath = configPath
+	if config.AuditPath == "" {
+		config.AuditPath = filepath.Join(configDir, "audit.log")
+	}
+	if managed != nil {
+		managed.apply(&config)
+	}
 
 	return &config, nil
 }
 
-// Save writes the configuration to disk
+// findConfig returns the config file in dir, or "" when there is none.
+// Having more than one is an error, since it is unclear which one wins.
+func findConfig(dir string) (string, error) {
+	var found []string
+	for _, name := range configNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return filepath.Join(dir, found[0]), nil
+	default:
+		return "", fmt.Errorf("%w: found %s in %s; keep only one", ErrInvalidConfig, strings.Join(found, " and "), dir)
+	}
+}
+
+// Save writes the configuration to disk in its file's format. YAML and TOML
+// comments are not kept, and settings locked by a managed configuration keep
+// the user's own values.
 func (c *Config) Save() error {
-	data, err := json.MarshalIndent(c, "", "  ")
+	user := c.userSettings()
+	data, err := json.MarshalIndent(&user, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
+	if len(c.EncryptedSections) > 0 {
+		if data, err = c.sealSections(data); err != nil {
+			return fmt.Errorf("failed to encrypt config: %w", err)
+		}
+	}
+	if format := configfmt.FormatOf(c.ConfigPath); format != configfmt.JSON {
+		if data, err = configfmt.FromJSON(data, format); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+	}
 
 	if err := os.WriteFile(c.ConfigPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
@@ -122,6 +705,234 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// openSections decrypts the sealed sections of a config file and puts them
+// back in place, returning their names and the key that opened them
+func openSections(data []byte) ([]byte, []string, *vault.Key, error) {
+	doc, err := configfmt.Decode(data)
+	if err != nil {
+		// Unmarshalling reports the syntax error
+		return data, nil, nil, nil
+	}
+	value, ok := doc.Get(sealedKey)
+	if !ok {
+		return data, nil, nil, nil
+	}
+	sealed, _ := value.(string)
+	key, err := vault.KeyFor(sealed)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	plain, err := key.Open(sealed)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sections, err := configfmt.Decode(plain)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, name := range sections.Keys() {
+		v, _ := sections.Get(name)
+		doc.Set(name, v)
+	}
+	doc.Delete(sealedKey)
+	data, err = json.Marshal(doc)
+	return data, sections.Keys(), key, err
+}
+
+// sealSections moves the encrypted sections of the marshalled config under
+// "encrypted"
+func (c *Config) sealSections(data []byte) ([]byte, error) {
+	if c.vaultKey == nil {
+		return nil, fmt.Errorf("no key to encrypt %s with (run: devos config encrypt)", strings.Join(c.EncryptedSections, ", "))
+	}
+	doc, err := configfmt.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	sections := configfmt.NewMap()
+	for _, name := range c.EncryptedSections {
+		if v, ok := doc.Get(name); ok {
+			sections.Set(name, v)
+			doc.Delete(name)
+		}
+	}
+	plain, err := json.Marshal(sections)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := c.vaultKey.Seal(plain)
+	if err != nil {
+		return nil, err
+	}
+	doc.Set(sealedKey, sealed)
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Encrypt stores the named sections (default: SecretSections) encrypted
+// with key from the next save on
+func (c *Config) Encrypt(sections []string, key *vault.Key) error {
+	if len(sections) == 0 {
+		sections = SecretSections
+	}
+	if err := checkSections(sections); err != nil {
+		return err
+	}
+	c.EncryptedSections = sections
+	c.vaultKey = key
+	return nil
+}
+
+// checkSections returns an error unless every section can be encrypted
+func checkSections(sections []string) error {
+	for _, name := range sections {
+		if !slices.Contains(SecretSections, name) {
+			return fmt.Errorf("%w: cannot encrypt %q (sections: %s)", ErrInvalidConfig, name, strings.Join(SecretSections, ", "))
+		}
+	}
+	return nil
+}
+
+// Decrypt stores every section in plain text from the next save on
+func (c *Config) Decrypt() {
+	c.EncryptedSections = nil
+	c.vaultKey = nil
+}
+
+// EncryptionKey returns the source of the key encrypted sections are sealed
+// with, or "" when nothing is encrypted
+func (c *Config) EncryptionKey() string {
+	if c.vaultKey == nil || len(c.EncryptedSections) == 0 {
+		return ""
+	}
+	return c.vaultKey.Source
+}
+
+// KeepEncryption encrypts the same sections with the same key as another
+// config, e.g. one that replaces it on import
+func (c *Config) KeepEncryption(from *Config) {
+	c.EncryptedSections = from.EncryptedSections
+	c.vaultKey = from.vaultKey
+}
+
+// Convert rewrites the config file in another format, keeping the old file
+// with a .bak suffix, and returns the new file's path. The file's own
+// contents are converted, so settings left out of it stay unset.
+func (c *Config) Convert(format string) (string, error) {
+	from := configfmt.FormatOf(c.ConfigPath)
+	if format == from {
+		return "", fmt.Errorf("%s is already %s", filepath.Base(c.ConfigPath), format)
+	}
+	data, err := os.ReadFile(c.ConfigPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	if data, err = configfmt.ToJSON(data, from); err != nil {
+		return "", fmt.Errorf("%w: failed to parse %s: %w", ErrInvalidConfig, filepath.Base(c.ConfigPath), err)
+	}
+	if data, err = configfmt.FromJSON(data, format); err != nil {
+		return "", fmt.Errorf("failed to convert config: %w", err)
+	}
+
+	path := filepath.Join(c.Dir(), "config"+configfmt.Extension(format))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(c.ConfigPath, c.ConfigPath+".bak"); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to back up %s: %w", filepath.Base(c.ConfigPath), err)
+	}
+	c.ConfigPath = path
+	return path, nil
+}
+
+// WithoutSecrets returns a copy of the configuration with API keys, team,
+// issue tracker, and audit export tokens, the OIDC client secret, webhook
+// URLs, and environment variables named like credentials removed
+func (c Config) WithoutSecrets() Config {
+	c.APIKey = ""
+	c.SlackWebhookURL = ""
+	c.MemoryDSN = ""
+	if c.OIDC != nil {
+		oidc := *c.OIDC
+		oidc.ClientSecret = ""
+		c.OIDC = &oidc
+	}
+	if c.IssueTracker != nil {
+		tracker := *c.IssueTracker
+		tracker.Token = ""
+		c.IssueTracker = &tracker
+	}
+	sinks := make([]LogSink, len(c.LogSinks))
+	for i, sink := range c.LogSinks {
+		sink.Headers = nil
+		sink.Target = WithoutUserinfo(sink.Target)
+		sinks[i] = sink
+	}
+	c.LogSinks = sinks
+	exports := make([]AuditExport, len(c.AuditExports))
+	for i, export := range c.AuditExports {
+		export.Token = ""
+		export.Headers = nil
+		exports[i] = export
+	}
+	c.AuditExports = exports
+	users := make([]TeamUser, len(c.TeamUsers))
+	for i, user := range c.TeamUsers {
+		users[i] = TeamUser{Name: user.Name, Identity: user.Identity}
+	}
+	c.TeamUsers = users
+	envs := make([]Environment, len(c.Environments))
+	for i, env := range c.Environments {
+		vars := make(map[string]string, len(env.Env))
+		for name, value := range env.Env {
+			if !SecretVar(name) {
+				vars[name] = value
+			}
+		}
+		if env.Env != nil {
+			env.Env = vars
+		}
+		envs[i] = env
+	}
+	c.Environments = envs
+	return c
+}
+
+// SecretVar reports whether an environment variable's name suggests its
+// value is a credential
+func SecretVar(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "API_KEY", "ACCESS_KEY", "PRIVATE_KEY", "CREDENTIAL"} {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutUserinfo returns a URL without the user name and password it
+// carries, such as a Loki push URL with basic auth; other targets are
+// returned as they are
+func WithoutUserinfo(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.User == nil {
+		return target
+	}
+	u.User = nil
+	return u.String()
+}
+
+// LocalProvider reports whether the AI provider runs models on this machine
+func (c *Config) LocalProvider() bool {
+	return c.AIProvider == "ollama" || c.AIProvider == "builtin"
+}
+
+// Dir returns the directory holding the config file and the other DevOS data files
+func (c *Config) Dir() string {
+	return filepath.Dir(c.ConfigPath)
+}
+
 // getConfigDir returns the platform-specific configuration directory
 func getConfigDir() (string, error) {
 	var baseDir string
@@ -158,19 +969,82 @@ func getConfigDir() (string, error) {
 func (c *Config) Validate() error {
 	// Check AI provider
 	validProviders := map[string]bool{
-		"openai":    true,
-		"anthropic": true,
-		"gemini":    true,
-		"ollama":    true,
+		"openai":            true,
+		"openai-compatible": true,
+		"azure-openai":      true,
+		"anthropic":         true,
+		"gemini":            true,
+		"ollama":            true,
+		"builtin":           true,
 	}
 
 	if !validProviders[c.AIProvider] {
-		return fmt.Errorf("invalid AI provider: %s", c.AIProvider)
+		return fmt.Errorf("%w: invalid AI provider: %s", ErrInvalidConfig, c.AIProvider)
 	}
 
-	// Check API key for cloud providers
-	if c.AIProvider != "ollama" && c.APIKey == "" {
-		return fmt.Errorf("API key required for provider: %s", c.AIProvider)
+	// Check API key for cloud providers; OpenAI-compatible servers often
+	// need none, but need to be located
+	if c.AIProvider == "openai-compatible" {
+		if c.BaseURL == "" {
+			return fmt.Errorf("%w: base_url required for provider: openai-compatible", ErrInvalidConfig)
+		}
+	} else if !c.LocalProvider() && c.APIKey == "" {
+		return fmt.Errorf("%w: API key required for provider: %s", ErrInvalidConfig, c.AIProvider)
+	}
+
+	// Only local models can be picked to fit the machine
+	if c.Model == "auto" && !c.LocalProvider() {
+		return fmt.Errorf("%w: model \"auto\" requires a local provider, not %s", ErrInvalidConfig, c.AIProvider)
+	}
+	if c.AIProvider == "azure-openai" {
+		if c.AzureEndpoint == "" {
+			return fmt.Errorf("%w: azure_endpoint required for provider: azure-openai", ErrInvalidConfig)
+		}
+		if c.AzureDeployment == "" && c.Model == "" {
+			return fmt.Errorf("%w: azure_deployment required for provider: azure-openai", ErrInvalidConfig)
+		}
+	}
+	if c.ContextLength < 0 {
+		return fmt.Errorf("%w: context_length must not be negative", ErrInvalidConfig)
+	}
+	if c.AttachMaxBytes < 0 {
+		return fmt.Errorf("%w: attach_max_bytes must not be negative", ErrInvalidConfig)
+	}
+	for name, policy := range c.Retry {
+		if name != RetryDefault && name != RetryDownloads && name != RetryCommands && !validProviders[name] {
+			return fmt.Errorf("%w: invalid retry policy name: %s (expected default, downloads, commands, or a provider)", ErrInvalidConfig, name)
+		}
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("%w: retry policy %s: %w", ErrInvalidConfig, name, err)
+		}
+	}
+	for _, spec := range c.Failover {
+		provider, model, _ := strings.Cut(spec, ":")
+		if !validProviders[provider] {
+			return fmt.Errorf("%w: invalid failover provider: %s", ErrInvalidConfig, provider)
+		}
+		if model == "auto" && provider != "ollama" {
+			return fmt.Errorf("%w: failover model \"auto\" requires ollama, not %s", ErrInvalidConfig, provider)
+		}
+		if provider == "azure-openai" && c.AzureEndpoint == "" {
+			return fmt.Errorf("%w: azure_endpoint required for failover provider: azure-openai", ErrInvalidConfig)
+		}
+	}
+	for size, price := range c.CloudPrices {
+		if price < 0 {
+			return fmt.Errorf("%w: cloud_prices for %s must not be negative", ErrInvalidConfig, size)
+		}
+	}
+	if c.CostThreshold < 0 {
+		return fmt.Errorf("%w: cost_threshold must not be negative", ErrInvalidConfig)
+	}
+	for category, threshold := range c.GeminiSafety {
+		if !strings.HasPrefix(category, "HARM_CATEGORY_") {
+			return fmt.Errorf("%w: invalid gemini_safety category: %s (expected HARM_CATEGORY_...)", ErrInvalidConfig, category)
+		}
+		if !slices.Contains(gemini.Thresholds, threshold) {
+			return fmt.Errorf("%w: invalid gemini_safety threshold for %s: %s (expected %s)", ErrInvalidConfig, category, threshold, strings.Join(gemini.Thresholds, ", "))
+		}
 	}
 
 	// Check log level
@@ -182,7 +1056,289 @@ func (c *Config) Validate() error {
 	}
 
 	if !validLevels[c.LogLevel] {
-		return fmt.Errorf("invalid log level: %s", c.LogLevel)
+		return fmt.Errorf("%w: invalid log level: %s", ErrInvalidConfig, c.LogLevel)
+	}
+
+	validSinks := map[string]bool{
+		"syslog": true,
+		"file":   true,
+		"http":   true,
+		"loki":   true,
+	}
+
+	for _, sink := range c.LogSinks {
+		if !validSinks[sink.Type] {
+			return fmt.Errorf("%w: invalid log sink type: %s (expected syslog, file, http, or loki)", ErrInvalidConfig, sink.Type)
+		}
+		if sink.Type != "syslog" && sink.Target == "" {
+			return fmt.Errorf("%w: %s log sink requires a target", ErrInvalidConfig, sink.Type)
+		}
+		if sink.Level != "" && !validLevels[sink.Level] {
+			return fmt.Errorf("%w: invalid level for %s log sink: %s", ErrInvalidConfig, sink.Type, sink.Level)
+		}
+	}
+
+	for _, export := range c.AuditExports {
+		if export.Type != "splunk" && export.Type != "elastic" && export.Type != "http" {
+			return fmt.Errorf("%w: invalid audit export type: %s (expected splunk, elastic, or http)", ErrInvalidConfig, export.Type)
+		}
+		if export.Target == "" {
+			return fmt.Errorf("%w: %s audit export requires a target", ErrInvalidConfig, export.Type)
+		}
+		if export.Format != "" && export.Format != "json" && export.Format != "cef" {
+			return fmt.Errorf("%w: invalid audit export format: %s (expected json or cef)", ErrInvalidConfig, export.Format)
+		}
+	}
+
+	// Check execution shell
+	validShells := map[string]bool{
+		"sh":   true,
+		"bash": true,
+		"zsh":  true,
+		"fish": true,
+		"nu":   true,
+	}
+
+	if c.Shell != "" && !validShells[c.Shell] {
+		return fmt.Errorf("%w: invalid shell: %s (expected sh, bash, zsh, fish, or nu)", ErrInvalidConfig, c.Shell)
+	}
+
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("%w: invalid timezone: %s", ErrInvalidConfig, c.Timezone)
+		}
+	}
+
+	switch c.MemoryBackend {
+	case "", "sqlite":
+	case "postgres":
+		if c.MemoryDSN == "" {
+			return fmt.Errorf("%w: memory_dsn is required for the postgres memory backend", ErrInvalidConfig)
+		}
+	default:
+		return fmt.Errorf("%w: invalid memory backend: %s (expected sqlite or postgres)", ErrInvalidConfig, c.MemoryBackend)
+	}
+
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("%w: idle_timeout must not be negative", ErrInvalidConfig)
+	}
+	if c.CommandTimeout < -1 {
+		return fmt.Errorf("%w: command_timeout must be seconds, 0 for the default, or -1 for none", ErrInvalidConfig)
+	}
+
+	switch c.Accessible {
+	case "", "auto", "on", "off":
+	default:
+		return fmt.Errorf("%w: invalid accessible setting: %s (expected on, off, or auto)", ErrInvalidConfig, c.Accessible)
+	}
+
+	switch c.Autosuggest {
+	case "", "auto", "on", "off":
+	default:
+		return fmt.Errorf("%w: invalid autosuggest setting: %s (expected on, off, or auto)", ErrInvalidConfig, c.Autosuggest)
+	}
+	if c.ChangeSummary != "" && c.ChangeSummary != "ask" && c.ChangeSummary != "off" {
+		return fmt.Errorf("%w: invalid change_summary: %s (expected ask or off)", ErrInvalidConfig, c.ChangeSummary)
+	}
+
+	if c.AllowlistOnly && len(c.AllowedCommands) == 0 {
+		return fmt.Errorf("%w: allowlist_only needs allowed_commands", ErrInvalidConfig)
+	}
+
+	for name, repos := range c.Repos {
+		if len(repos) == 0 {
+			return fmt.Errorf("%w: repo group %q lists no repositories", ErrInvalidConfig, name)
+		}
+	}
+
+	if c.Release != nil {
+		if err := c.Release.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	}
+
+	switch c.RollbackOnFailure {
+	case "", "ask", "always", "never":
+	default:
+		return fmt.Errorf("%w: invalid rollback_on_failure: %s (expected ask, always, or never)", ErrInvalidConfig, c.RollbackOnFailure)
+	}
+	for _, rule := range c.UndoRules {
+		if rule.Match == "" || rule.Undo == "" {
+			return fmt.Errorf("%w: undo rules need a match and an undo command", ErrInvalidConfig)
+		}
+	}
+
+	if c.Speech != nil {
+		validEvents := map[string]bool{SpeakSuccess: true, SpeakFailure: true, SpeakLong: true, SpeakBackground: true}
+		for _, event := range c.Speech.Events {
+			if !validEvents[event] {
+				return fmt.Errorf("%w: invalid speech event: %s (expected success, failure, long, or background)", ErrInvalidConfig, event)
+			}
+		}
+		if c.Speech.LongTask < 0 {
+			return fmt.Errorf("%w: speech long_task must not be negative", ErrInvalidConfig)
+		}
+	}
+
+	if c.OIDC != nil {
+		if err := c.OIDC.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	}
+	if (c.DaemonTLSCert == "") != (c.DaemonTLSKey == "") {
+		return fmt.Errorf("%w: daemon_tls_cert and daemon_tls_key must be set together", ErrInvalidConfig)
+	}
+	if c.DaemonAttach != "" && c.DaemonAttach != "auto" && c.DaemonAttach != "never" {
+		return fmt.Errorf("%w: invalid daemon_attach: %s (expected auto or never)", ErrInvalidConfig, c.DaemonAttach)
+	}
+
+	if c.LocalModel != "" {
+		if provider, model, _ := strings.Cut(c.LocalModel, ":"); (provider != "ollama" && provider != "builtin") || model == "" {
+			return fmt.Errorf("%w: local_model must be ollama:<model> or builtin:<model>, got %q", ErrInvalidConfig, c.LocalModel)
+		}
+	}
+
+	if err := checkSections(c.EncryptedSections); err != nil {
+		return err
+	}
+
+	for _, b := range c.Budgets {
+		if b.Provider != "*" && !validProviders[b.Provider] {
+			return fmt.Errorf("%w: invalid budget provider: %s", ErrInvalidConfig, b.Provider)
+		}
+		if b.Period != "day" && b.Period != "month" {
+			return fmt.Errorf("%w: invalid budget period for %s: %q (expected day or month)", ErrInvalidConfig, b.Provider, b.Period)
+		}
+		if b.Tokens <= 0 && b.Dollars <= 0 {
+			return fmt.Errorf("%w: budget for %s needs a tokens or dollars limit", ErrInvalidConfig, b.Provider)
+		}
+		if b.WarnAt < 0 || b.WarnAt > 100 {
+			return fmt.Errorf("%w: budget warn_at must be a percentage", ErrInvalidConfig)
+		}
+		switch b.Action {
+		case "", BudgetWarn, BudgetBlock:
+		case BudgetDowngrade:
+			if b.Fallback == "" {
+				return fmt.Errorf("%w: budget for %s downgrades but has no fallback model", ErrInvalidConfig, b.Provider)
+			}
+		default:
+			return fmt.Errorf("%w: invalid budget action: %s (expected warn, downgrade, or block)", ErrInvalidConfig, b.Action)
+		}
+	}
+
+	for _, f := range c.Freezes {
+		if f.Name == "" {
+			return fmt.Errorf("%w: every freeze needs a name", ErrInvalidConfig)
+		}
+		if (f.Schedule == "") == (f.ICal == "") {
+			return fmt.Errorf("%w: freeze %s needs either a schedule or an ical feed", ErrInvalidConfig, f.Name)
+		}
+		if f.Duration != "" {
+			if d, err := time.ParseDuration(f.Duration); err != nil || d <= 0 || f.Schedule == "" {
+				return fmt.Errorf("%w: freeze %s: duration must be positive and used with a schedule", ErrInvalidConfig, f.Name)
+			}
+		}
+	}
+
+	if c.Guardrails != nil {
+		if err := c.Guardrails.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	}
+
+	monitors := make(map[string]bool)
+	for _, m := range c.Monitors {
+		if m.Name == "" || monitors[m.Name] {
+			return fmt.Errorf("%w: every monitor needs a unique name", ErrInvalidConfig)
+		}
+		monitors[m.Name] = true
+		switch m.Check {
+		case "cert", "dns", "reachable":
+		default:
+			return fmt.Errorf("%w: monitor %s: invalid check: %s (expected cert, dns, or reachable)", ErrInvalidConfig, m.Name, m.Check)
+		}
+		if m.Target == "" {
+			return fmt.Errorf("%w: monitor %s needs a target", ErrInvalidConfig, m.Name)
+		}
+		switch strings.ToUpper(m.Record) {
+		case "", "A", "AAAA", "CNAME", "MX", "NS", "TXT":
+		default:
+			return fmt.Errorf("%w: monitor %s: invalid DNS record type: %s", ErrInvalidConfig, m.Name, m.Record)
+		}
+		if m.WarnDays < 0 {
+			return fmt.Errorf("%w: monitor %s: warn_days must not be negative", ErrInvalidConfig, m.Name)
+		}
+		if m.Every != "" {
+			if d, err := time.ParseDuration(m.Every); err != nil || d < time.Minute {
+				return fmt.Errorf("%w: monitor %s: every must be a duration of at least 1m", ErrInvalidConfig, m.Name)
+			}
+		}
+	}
+
+	if t := c.IssueTracker; t != nil {
+		switch t.Type {
+		case "jira":
+			if t.URL == "" {
+				return fmt.Errorf("%w: issue_tracker url is required for jira", ErrInvalidConfig)
+			}
+		case "linear":
+		default:
+			return fmt.Errorf("%w: invalid issue tracker: %s (expected jira or linear)", ErrInvalidConfig, t.Type)
+		}
+		switch t.Comment {
+		case "", "ask", "always", "never":
+		default:
+			return fmt.Errorf("%w: invalid issue_tracker comment setting: %s (expected ask, always, or never)", ErrInvalidConfig, t.Comment)
+		}
+	}
+
+	// Check image scanner
+	validScanners := map[string]bool{
+		"":      true,
+		"auto":  true,
+		"trivy": true,
+		"grype": true,
+	}
+
+	if !validScanners[c.ImageScanner] {
+		return fmt.Errorf("%w: invalid image scanner: %s", ErrInvalidConfig, c.ImageScanner)
+	}
+
+	// Check environments
+	environments := map[string]bool{}
+	for _, env := range c.Environments {
+		if env.Name == "" || environments[env.Name] {
+			return fmt.Errorf("%w: environments need unique names (got %q twice or empty)", ErrInvalidConfig, env.Name)
+		}
+		environments[env.Name] = true
+		for _, target := range env.Targets {
+			if !slices.ContainsFunc(c.Targets, func(t Target) bool { return t.Name == target }) {
+				return fmt.Errorf("%w: environment %q uses unknown target %q", ErrInvalidConfig, env.Name, target)
+			}
+		}
+	}
+	if c.Environment != "" && !environments[c.Environment] {
+		return fmt.Errorf("%w: active environment %q is not configured", ErrInvalidConfig, c.Environment)
+	}
+
+	// Check approval rules
+	validActions := map[string]bool{
+		"allow": true,
+		"ask":   true,
+		"deny":  true,
+	}
+
+	rules := c.ApprovalRules
+	for _, env := range c.Environments {
+		rules = append(rules, env.ApprovalRules...)
+	}
+	for _, rule := range rules {
+		if !validActions[rule.Action] {
+			return fmt.Errorf("%w: invalid action %q in approval rule %q", ErrInvalidConfig, rule.Action, rule.Name)
+		}
+		if rule.Sandbox && rule.Action != "allow" {
+			return fmt.Errorf("%w: approval rule %q can only exempt commands from the sandbox with action allow", ErrInvalidConfig, rule.Name)
+		}
 	}
 
 	return nil
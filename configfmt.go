@@ -0,0 +1,198 @@
+package configfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Config file formats
+const (
+	JSON = "json"
+	YAML = "yaml"
+	TOML = "toml"
+)
+
+// Formats lists the supported formats
+var Formats = []string{JSON, YAML, TOML}
+
+// FormatOf returns the format of a file from its extension; anything other
+// than .yaml, .yml, or .toml is JSON
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	default:
+		return JSON
+	}
+}
+
+// Extension returns the file extension for a format
+func Extension(format string) string {
+	return "." + format
+}
+
+// ToJSON converts a document in the given format to JSON, keeping the order
+// of its keys
+func ToJSON(data []byte, format string) ([]byte, error) {
+	var doc *Map
+	var err error
+	switch format {
+	case JSON:
+		return data, nil
+	case YAML:
+		doc, err = parseYAML(data)
+	case TOML:
+		doc, err = parseTOML(data)
+	default:
+		return nil, fmt.Errorf("unknown config format %q (expected json, yaml, or toml)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// FromJSON converts a JSON object to the given format, keeping the order of
+// its keys
+func FromJSON(data []byte, format string) ([]byte, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case JSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case YAML:
+		var b bytes.Buffer
+		writeYAMLMap(&b, doc, "")
+		return b.Bytes(), nil
+	case TOML:
+		var b bytes.Buffer
+		if err := writeTOMLTable(&b, doc, nil); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown config format %q (expected json, yaml, or toml)", format)
+	}
+}
+
+// Map is an object that keeps its keys in the order they were written.
+// Values are strings, int64, float64, json.Number, bool, nil, []interface{},
+// or *Map.
+type Map struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewMap returns an empty map
+func NewMap() *Map {
+	return &Map{values: map[string]interface{}{}}
+}
+
+// Set adds or replaces a key
+func (m *Map) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns a key's value
+func (m *Map) Get(key string) (interface{}, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Delete removes a key
+func (m *Map) Delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// Keys returns the keys in order
+func (m *Map) Keys() []string {
+	return m.keys
+}
+
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Decode reads a JSON object into a Map
+func Decode(data []byte) (*Map, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := v.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("config must be an object")
+	}
+	return doc, nil
+}
+
+// decodeJSONValue reads the next value from the decoder
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := NewMap()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key.(string), value)
+		}
+		_, err := dec.Token()
+		return m, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"devos/internal/timefmt"
+)
+
+// Kinds of Confirmation, named after the audit events that record them
+const (
+	ConfirmFreeze      = "freeze_override"
+	ConfirmKubeContext = "kube_context_confirmed"
+	ConfirmCost        = "cost_confirmed"
+)
+
+// Confirmation is a check a plan must be confirmed past, beyond approving
+// it, before it runs: the REPL has it typed out, and the daemon needs a
+// team member other than the requester to approve the plan
+type Confirmation struct {
+	Kind     string   `json:"kind"`
+	Detail   string   `json:"detail"`
+	Commands []string `json:"commands,omitempty"`
+}
+
+// Confirmations returns what a plan's commands must be confirmed past: a
+// change freeze in effect, a cluster that does not fit the project's
+// environment, or a cost over the threshold
+func (e *Executor) Confirmations(ctx context.Context, commands []string) []Confirmation {
+	var confirmations []Confirmation
+	if frozen := FrozenCommands(commands); len(frozen) > 0 {
+		if window := e.ActiveFreeze(ctx); window != nil {
+			detail := fmt.Sprintf("change freeze %q is in effect until %s", window.Name, timefmt.DateTime(window.End))
+			if window.Reason != "" {
+				detail += ": " + window.Reason
+			}
+			confirmations = append(confirmations, Confirmation{Kind: ConfirmFreeze, Detail: detail, Commands: frozen})
+		}
+	}
+	if kube := e.KubeTarget(ctx, commands); kube != nil && kube.Mismatch != "" {
+		detail := fmt.Sprintf("context %s (namespace %s) is changed in the %s environment, but %s", kube.Context, kube.Namespace, kube.Environment, kube.Mismatch)
+		confirmations = append(confirmations, Confirmation{Kind: ConfirmKubeContext, Detail: detail, Commands: kube.Commands})
+	}
+	if cost := e.EstimateCost(commands); cost != nil && cost.Total > e.CostThreshold() {
+		detail := fmt.Sprintf("the plan adds about $%.0f/month, over the $%.0f threshold", math.Ceil(cost.Total), e.CostThreshold())
+		confirmations = append(confirmations, Confirmation{Kind: ConfirmCost, Detail: detail})
+	}
+	return confirmations
+}
+
+// RecordConfirmation audits who confirmed a plan past a check
+func (e *Executor) RecordConfirmation(c Confirmation, confirmedBy []string) {
+	e.audit.Record(c.Kind, map[string]string{
+		"detail":       c.Detail,
+		"commands":     strings.Join(c.Commands, "\n"),
+		"confirmed_by": strings.Join(confirmedBy, ","),
+	})
+}
@@ -0,0 +1,19 @@
+package executor
+
+import "devos/internal/cloudcost"
+
+// EstimateCost returns the monthly cost of the cloud resources a plan's
+// commands create, or nil when they create none
+func (e *Executor) EstimateCost(commands []string) *cloudcost.Estimate {
+	estimator := &cloudcost.Estimator{Prices: e.config.CloudPrices}
+	return estimator.Commands(commands, currentDir())
+}
+
+// CostThreshold returns the monthly cost above which a plan's cost must be
+// confirmed
+func (e *Executor) CostThreshold() float64 {
+	if e.config.CostThreshold > 0 {
+		return e.config.CostThreshold
+	}
+	return cloudcost.DefaultThreshold
+}
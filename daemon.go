@@ -0,0 +1,549 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/executor"
+	"devos/internal/logger"
+	"devos/internal/monitor"
+	"devos/internal/oidc"
+	"devos/internal/policy"
+	"devos/internal/timefmt"
+)
+
+// Plan statuses
+const (
+	StatusPendingApproval = "pending_approval"
+	StatusRunning         = "running"
+	StatusSucceeded       = "succeeded"
+	StatusFailed          = "failed"
+	StatusRejected        = "rejected"
+)
+
+// Plan is a generated command plan awaiting approval or execution
+type Plan struct {
+	ID                string                  `json:"id"`
+	Input             string                  `json:"input"`
+	Output            string                  `json:"output"`
+	Commands          []string                `json:"commands"`
+	RequestedBy       string                  `json:"requested_by"`
+	HighRisk          bool                    `json:"high_risk"`
+	Environment       string                  `json:"environment,omitempty"`
+	RequiredApprovals int                     `json:"required_approvals"`
+	Confirmations     []executor.Confirmation `json:"confirmations,omitempty"` // Checks, such as a change freeze, that approving confirms the plan past
+	Approvals         []string                `json:"approvals"`
+	RejectedBy        string                  `json:"rejected_by,omitempty"`
+	Status            string                  `json:"status"`
+	Error             string                  `json:"error,omitempty"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+
+	// result is the generated plan, executed once approved
+	result *executor.ExecutionResult
+}
+
+// Server is the team daemon exposing DevOS over HTTP
+type Server struct {
+	config   *config.Config
+	executor *executor.Executor
+	logger   *logger.Logger
+
+	mu    sync.Mutex
+	plans map[string]*Plan
+
+	// execMu serializes executor use; the executor is not safe for concurrent use
+	execMu sync.Mutex
+
+	monitors *monitor.Scheduler
+
+	// OIDC login: the provider, discovered on first use, logins in progress
+	// by state, and the key signing the API tokens logins issue
+	authMu     sync.Mutex
+	oidc       *oidc.Provider
+	logins     map[string]oidc.Login
+	sessionKey []byte
+}
+
+// New creates a new daemon server
+func New(cfg *config.Config, exec *executor.Executor, log *logger.Logger) *Server {
+	s := &Server{
+		config:   cfg,
+		executor: exec,
+		logger:   log,
+		plans:    make(map[string]*Plan),
+		logins:   make(map[string]oidc.Login),
+	}
+	s.monitors = monitor.NewScheduler(cfg.Monitors, s.notifyMonitor)
+	return s
+}
+
+// SocketPath returns the daemon's Unix socket path
+func SocketPath(cfg *config.Config) string {
+	if cfg.DaemonSocket != "" {
+		return cfg.DaemonSocket
+	}
+	return filepath.Join(filepath.Dir(cfg.ConfigPath), "devos.sock")
+}
+
+// ListenAndServe serves the API on the Unix socket and, if configured, a TCP
+// address: over HTTPS, or over plain HTTP only on a loopback address
+func (s *Server) ListenAndServe() error {
+	if len(s.config.TeamUsers) == 0 {
+		return fmt.Errorf("no team_users configured; the daemon requires authenticated users")
+	}
+	if s.config.DaemonAddr != "" && s.config.DaemonTLSCert == "" && !loopback(s.config.DaemonAddr) {
+		return fmt.Errorf("daemon_addr %s is not a loopback address; set daemon_tls_cert and daemon_tls_key so tokens are not sent in the clear", s.config.DaemonAddr)
+	}
+
+	if s.config.OIDC != nil {
+		key, err := loadSessionKey(sessionKeyPath(s.config))
+		if err != nil {
+			return err
+		}
+		s.sessionKey = key
+	}
+
+	socket := SocketPath(s.config)
+	os.Remove(socket)
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to secure socket: %w", err)
+	}
+
+	handler := s.routes()
+	errs := make(chan error, 2)
+
+	if len(s.config.Monitors) > 0 {
+		s.logger.Info("Running %d monitor(s)", len(s.config.Monitors))
+		go s.monitors.Run(context.Background())
+	}
+
+	s.logger.Info("Daemon listening on %s", socket)
+	go func() { errs <- http.Serve(listener, handler) }()
+
+	switch {
+	case s.config.DaemonAddr == "":
+	case s.config.DaemonTLSCert != "":
+		s.logger.Info("Daemon listening on %s (HTTPS)", s.config.DaemonAddr)
+		go func() {
+			errs <- http.ListenAndServeTLS(s.config.DaemonAddr, s.config.DaemonTLSCert, s.config.DaemonTLSKey, handler)
+		}()
+	default:
+		s.logger.Info("Daemon listening on %s", s.config.DaemonAddr)
+		go func() { errs <- http.ListenAndServe(s.config.DaemonAddr, handler) }()
+	}
+
+	return <-errs
+}
+
+// loopback reports whether a listen address only accepts connections from
+// this machine
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// routes builds the HTTP handler. Every version is served under its own
+// prefix; unversioned paths negotiate a version per request.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/versions", s.handleVersions)
+	mux.HandleFunc(dashboardPath, s.handleDashboard)
+	if s.config.OIDC != nil {
+		mux.HandleFunc("/auth/login", s.handleLogin)
+		mux.HandleFunc("/auth/callback", s.handleCallback)
+	}
+	for _, prefix := range append([]string{""}, SupportedAPIs...) {
+		if prefix != "" {
+			prefix = "/" + prefix
+		}
+		mux.HandleFunc(prefix+"/plans", s.authenticated(s.handlePlans))
+		mux.HandleFunc(prefix+"/plans/", s.authenticated(s.handlePlan))
+		mux.HandleFunc(prefix+"/monitors", s.authenticated(s.handleMonitors))
+	}
+	return mux
+}
+
+// authenticated resolves the bearer token, or a login's cookie, to a team
+// user
+func (s *Server) authenticated(next func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, _, ok := apiVersion(r)
+		if !ok {
+			writeError(w, http.StatusNotAcceptable, fmt.Sprintf("unsupported API version %q (supported: %s)", version, strings.Join(SupportedAPIs, ", ")))
+			return
+		}
+		w.Header().Set(versionHeader, version)
+
+		token := requestToken(r)
+		for _, user := range s.config.TeamUsers {
+			if user.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(user.Token)) == 1 {
+				next(w, r, user.Name)
+				return
+			}
+		}
+		if user, ok := s.tokenUser(token); ok {
+			next(w, r, user)
+			return
+		}
+		writeError(w, http.StatusUnauthorized, "invalid or missing token")
+	}
+}
+
+// handlePlans lists plans (GET) or creates a new plan (POST)
+func (s *Server) handlePlans(w http.ResponseWriter, r *http.Request, user string) {
+	version, _, _ := apiVersion(r)
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		plans := make([]Plan, 0, len(s.plans))
+		for _, p := range s.plans {
+			plans = append(plans, *p)
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, renderPlans(version, plans))
+
+	case http.MethodPost:
+		var req struct {
+			Input string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Input == "" {
+			writeError(w, http.StatusBadRequest, "request body must contain an input")
+			return
+		}
+
+		plan, err := s.createPlan(r.Context(), req.Input, user)
+		if err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, renderPlan(version, s.snapshot(plan)))
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handlePlan returns a plan or approves/rejects it
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request, user string) {
+	version, path, _ := apiVersion(r)
+	parts := strings.Split(strings.TrimPrefix(path, "/plans/"), "/")
+	id := parts[0]
+
+	s.mu.Lock()
+	plan, ok := s.plans[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "plan not found")
+		return
+	}
+
+	if len(parts) == 1 && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, renderPlan(version, s.snapshot(plan)))
+		return
+	}
+
+	if len(parts) != 2 || r.Method != http.MethodPost {
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+		return
+	}
+
+	var err error
+	switch parts[1] {
+	case "approve":
+		err = s.approve(plan, user)
+	case "reject":
+		err = s.reject(plan, user)
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, renderPlan(version, s.snapshot(plan)))
+}
+
+// handleMonitors returns the latest result of each monitor (GET)
+func (s *Server) handleMonitors(w http.ResponseWriter, r *http.Request, user string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.monitors.Results())
+}
+
+// createPlan generates a plan and registers it for approval
+func (s *Server) createPlan(ctx context.Context, input, user string) (*Plan, error) {
+	s.execMu.Lock()
+	result, err := s.executor.Execute(ctx, input)
+	var confirmations []executor.Confirmation
+	if err == nil {
+		confirmations = s.executor.Confirmations(ctx, result.Commands)
+	}
+	s.execMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		ID:            newID(),
+		Input:         input,
+		Output:        result.Output,
+		Commands:      result.Commands,
+		result:        result,
+		RequestedBy:   user,
+		Status:        StatusPendingApproval,
+		Confirmations: confirmations,
+		CreatedAt:     timefmt.Now(),
+		UpdatedAt:     timefmt.Now(),
+	}
+
+	for _, cmd := range result.Commands {
+		if policy.IsHighRisk(cmd) {
+			plan.HighRisk = true
+			break
+		}
+	}
+
+	// Every plan needs one sign-off; high-risk plans, and plans changing a
+	// protected environment, need two distinct users, so at least one of
+	// them is not the requester. The requester never signs off on their own
+	// high-risk plan (see approve).
+	plan.RequiredApprovals = 1
+	if plan.HighRisk && s.config.TwoPersonApproval {
+		plan.RequiredApprovals = 2
+	}
+	if env := s.config.ActiveEnvironment(); env != nil {
+		plan.Environment = env.Name
+		if env.Protected && !readOnly(plan.Commands) {
+			plan.RequiredApprovals = 2
+		}
+	}
+
+	s.mu.Lock()
+	s.plans[plan.ID] = plan
+	s.mu.Unlock()
+
+	s.logger.Info("Plan %s created by %s (high risk: %v)", plan.ID, user, plan.HighRisk)
+	s.notify(plan)
+
+	return plan, nil
+}
+
+// approve records a sign-off and starts execution once enough approvals
+// exist. High-risk plans, and plans with confirmations, are approved by
+// users other than the requester, whether or not two-person approval is on.
+func (s *Server) approve(plan *Plan, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if plan.Status != StatusPendingApproval {
+		return fmt.Errorf("plan is %s", plan.Status)
+	}
+	if plan.HighRisk && user == plan.RequestedBy {
+		return fmt.Errorf("%s requested this high-risk plan, so another team member must approve it", user)
+	}
+	if len(plan.Confirmations) > 0 && user == plan.RequestedBy {
+		return fmt.Errorf("%s requested this plan, so another team member must confirm it: %s", user, plan.Confirmations[0].Detail)
+	}
+
+	for _, approver := range plan.Approvals {
+		if approver == user {
+			return fmt.Errorf("%s has already approved this plan", user)
+		}
+	}
+
+	plan.Approvals = append(plan.Approvals, user)
+	plan.UpdatedAt = timefmt.Now()
+	s.logger.Info("Plan %s approved by %s (%d/%d)", plan.ID, user, len(plan.Approvals), plan.RequiredApprovals)
+
+	if len(plan.Approvals) >= plan.RequiredApprovals {
+		plan.Status = StatusRunning
+		go s.run(plan)
+	}
+
+	return nil
+}
+
+// reject cancels a pending plan
+func (s *Server) reject(plan *Plan, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if plan.Status != StatusPendingApproval {
+		return fmt.Errorf("plan is %s", plan.Status)
+	}
+
+	plan.Status = StatusRejected
+	plan.RejectedBy = user
+	plan.UpdatedAt = timefmt.Now()
+	s.logger.Info("Plan %s rejected by %s", plan.ID, user)
+	return nil
+}
+
+// run executes an approved plan, auditing its approvers as having
+// confirmed it past its confirmations
+func (s *Server) run(plan *Plan) {
+	s.execMu.Lock()
+	for _, c := range plan.Confirmations {
+		s.executor.RecordConfirmation(c, plan.Approvals)
+	}
+	err := s.executor.ExecutePlan(context.Background(), plan.result)
+	s.execMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan.UpdatedAt = timefmt.Now()
+	if err != nil {
+		plan.Status = StatusFailed
+		plan.Error = err.Error()
+		s.logger.Error("Plan %s failed: %v", plan.ID, err)
+		return
+	}
+	plan.Status = StatusSucceeded
+	s.logger.Info("Plan %s completed", plan.ID)
+}
+
+// readOnly reports whether every command only inspects state
+func readOnly(commands []string) bool {
+	for _, cmd := range commands {
+		if !policy.IsReadOnly(cmd) {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns a copy of a plan safe to serialize
+func (s *Server) snapshot(plan *Plan) Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *plan
+}
+
+// notify posts a pending plan to the configured Slack webhook
+func (s *Server) notify(plan *Plan) {
+	if s.config.SlackWebhookURL == "" {
+		return
+	}
+
+	text := fmt.Sprintf("DevOS plan %s from %s needs %d approval(s):\n```%s```",
+		plan.ID, plan.RequestedBy, plan.RequiredApprovals, strings.Join(plan.Commands, "\n"))
+	if plan.Environment != "" {
+		text = fmt.Sprintf("[%s] %s", plan.Environment, text)
+	}
+	if plan.HighRisk {
+		text = ":warning: HIGH RISK " + text
+	}
+	for _, c := range plan.Confirmations {
+		text += "\nApproving confirms: " + c.Detail
+	}
+	if dashboard := s.dashboardURL(); dashboard != "" {
+		text += "\nApprove or reject it at " + dashboard
+	} else {
+		text += fmt.Sprintf("\nApprove or reject it on the dashboard, or with: devos plans approve %s", plan.ID)
+	}
+
+	s.postSlack(text)
+}
+
+// notifyMonitor logs a monitor whose status changed and posts it to the
+// configured Slack webhook
+func (s *Server) notifyMonitor(result monitor.Result, previous string) {
+	text := fmt.Sprintf("DevOS monitor %s (%s %s) is %s: %s", result.Name, result.Check, result.Target, result.Status, result.Detail)
+	switch {
+	case result.Status == monitor.StatusOK:
+		text = ":white_check_mark: " + text
+		s.logger.Info("Monitor %s recovered: %s", result.Name, result.Detail)
+	case result.Status == monitor.StatusFail:
+		text = ":rotating_light: " + text
+		s.logger.Error("Monitor %s failed: %s", result.Name, result.Detail)
+	default:
+		text = ":warning: " + text
+		s.logger.Warn("Monitor %s: %s", result.Name, result.Detail)
+	}
+	if s.config.SlackWebhookURL == "" {
+		return
+	}
+	if previous != "" {
+		text += fmt.Sprintf(" (was %s)", previous)
+	}
+	s.postSlack(text)
+}
+
+// postSlack posts text to the configured Slack webhook in the background
+func (s *Server) postSlack(text string) {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	go func() {
+		resp, err := http.Post(s.config.SlackWebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			s.logger.Warn("Failed to notify Slack: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// newID returns a random plan identifier
+func newID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errorStatus maps executor errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, executor.ErrValidationBlocked), errors.Is(err, executor.ErrPolicyDenied):
+		return http.StatusForbidden
+	case errors.Is(err, executor.ErrProviderTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, executor.ErrProviderFailed):
+		return http.StatusBadGateway
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
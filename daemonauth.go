@@ -0,0 +1,224 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/oidc"
+	"devos/internal/timefmt"
+)
+
+// defaultTokenTTL is how long the API token from a login lasts when the
+// OIDC config does not say
+const defaultTokenTTL = 12 * time.Hour
+
+// loginTimeout is how long a login may take at the identity provider
+const loginTimeout = 10 * time.Minute
+
+// sessionCookie carries a login's API token for browsers
+const sessionCookie = "devos_session"
+
+// tokenPrefix marks API tokens issued by the daemon from a login
+const tokenPrefix = "dvs1."
+
+// sessionKeyPath returns where the key signing login tokens is kept.
+// Deleting it revokes every token issued from a login.
+func sessionKeyPath(cfg *config.Config) string {
+	return filepath.Join(filepath.Dir(cfg.ConfigPath), "daemon.key")
+}
+
+// loadSessionKey reads the key signing login tokens, creating it on first use
+func loadSessionKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil && len(key) >= 32 {
+		return key, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read daemon session key: %w", err)
+	}
+	key = make([]byte, 32)
+	rand.Read(key)
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write daemon session key: %w", err)
+	}
+	return key, nil
+}
+
+// tokenClaims is what a login token asserts
+type tokenClaims struct {
+	User    string `json:"u"`
+	Expires int64  `json:"exp"`
+}
+
+// issueToken returns an API token for user, signed with the session key
+func (s *Server) issueToken(user string, ttl time.Duration) (string, time.Time) {
+	expires := time.Now().Add(ttl)
+	payload, _ := json.Marshal(tokenClaims{User: user, Expires: expires.Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return tokenPrefix + encoded + "." + s.sign(encoded), expires
+}
+
+// tokenUser returns the team user a login token was issued to, if it is
+// genuine, unexpired, and the user is still a team user
+func (s *Server) tokenUser(token string) (string, bool) {
+	encoded, sig, ok := strings.Cut(strings.TrimPrefix(token, tokenPrefix), ".")
+	if !ok || !strings.HasPrefix(token, tokenPrefix) || s.sessionKey == nil {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(s.sign(encoded))) != 1 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || time.Now().Unix() >= claims.Expires {
+		return "", false
+	}
+	for _, user := range s.config.TeamUsers {
+		if user.Name == claims.User && user.Identity != "" {
+			return user.Name, true
+		}
+	}
+	return "", false
+}
+
+// sign returns the base64 HMAC of a token payload
+func (s *Server) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.sessionKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// identityProvider discovers the OIDC provider on first use, so the daemon
+// starts while the provider is unreachable
+func (s *Server) identityProvider(ctx context.Context) (*oidc.Provider, error) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	if s.oidc != nil {
+		return s.oidc, nil
+	}
+	provider, err := oidc.Discover(ctx, *s.config.OIDC)
+	if err != nil {
+		return nil, err
+	}
+	s.oidc = provider
+	return provider, nil
+}
+
+// handleLogin sends the browser to the identity provider
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	provider, err := s.identityProvider(r.Context())
+	if err != nil {
+		s.logger.Error("OIDC login unavailable: %v", err)
+		writeError(w, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+
+	login := oidc.NewLogin()
+	s.authMu.Lock()
+	for state, l := range s.logins {
+		if time.Since(l.Started) > loginTimeout {
+			delete(s.logins, state)
+		}
+	}
+	s.logins[login.State] = login
+	s.authMu.Unlock()
+	http.Redirect(w, r, provider.AuthURL(login), http.StatusFound)
+}
+
+// handleCallback completes a login: the identity provider's answer is
+// verified, matched to a team user, and exchanged for an API token, set as
+// a cookie and shown for use with the API
+func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.authMu.Lock()
+	login, ok := s.logins[q.Get("state")]
+	delete(s.logins, q.Get("state"))
+	provider := s.oidc
+	s.authMu.Unlock()
+	if !ok || provider == nil || time.Since(login.Started) > loginTimeout {
+		writeError(w, http.StatusBadRequest, "unknown or expired login; start again at /auth/login")
+		return
+	}
+	if msg := q.Get("error"); msg != "" {
+		writeError(w, http.StatusUnauthorized, "login failed: "+msg+" "+q.Get("error_description"))
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), login, q.Get("code"))
+	if err != nil {
+		s.logger.Warn("OIDC login failed: %v", err)
+		writeError(w, http.StatusUnauthorized, "login failed")
+		return
+	}
+	identity, err := provider.Identity(claims)
+	if err != nil {
+		s.logger.Warn("OIDC login failed: %v", err)
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	user := ""
+	for _, u := range s.config.TeamUsers {
+		if u.Identity != "" && strings.EqualFold(u.Identity, identity) {
+			user = u.Name
+		}
+	}
+	if user == "" {
+		s.logger.Warn("OIDC login by %s, who is not a team user", identity)
+		writeError(w, http.StatusForbidden, identity+" is not a DevOS team user")
+		return
+	}
+
+	ttl := defaultTokenTTL
+	if d, err := time.ParseDuration(s.config.OIDC.TokenTTL); err == nil && d > 0 {
+		ttl = d
+	}
+	token, expires := s.issueToken(user, ttl)
+	s.logger.Info("%s logged in as %s via OIDC", identity, user)
+
+	redirect, _ := url.Parse(s.config.OIDC.RedirectURL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   redirect != nil && redirect.Scheme == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintf(w, `<!doctype html><title>DevOS</title>
+<p>Logged in as <b>%s</b>.</p>
+<p>API token, valid until %s (send it as <code>Authorization: Bearer &lt;token&gt;</code>):</p>
+<pre>%s</pre>
+`, html.EscapeString(user), html.EscapeString(timefmt.DateTime(expires)), html.EscapeString(token))
+}
+
+// requestToken returns the bearer token of a request, or its login cookie
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"time"
+
+	"devos/internal/config"
+)
+
+// ErrNotRunning is returned by Dial when no daemon answers on the socket
+var ErrNotRunning = errors.New("daemon is not running")
+
+// dialTimeout bounds checking whether a daemon answers on the socket
+const dialTimeout = 2 * time.Second
+
+// pollInterval is how often Wait checks on a running plan
+const pollInterval = 500 * time.Millisecond
+
+// Client is a local session attached to a running daemon over its Unix
+// socket, so plans, approvals, memory, and jobs live in the daemon
+type Client struct {
+	Socket string
+	User   string // Team user the session acts as
+
+	token string
+	http  *http.Client
+}
+
+// Dial attaches to the daemon on the configured socket as the team user
+// named by daemon_user, or by the OS user name
+func Dial(ctx context.Context, cfg *config.Config) (*Client, error) {
+	socket := SocketPath(cfg)
+	if _, err := os.Stat(socket); err != nil {
+		return nil, ErrNotRunning
+	}
+
+	name := cfg.DaemonUser
+	if name == "" {
+		if u, err := user.Current(); err == nil {
+			name = u.Username
+		}
+	}
+	c := &Client{Socket: socket, User: name}
+	for _, u := range cfg.TeamUsers {
+		if u.Name == name {
+			c.token = u.Token
+		}
+	}
+	if c.token == "" {
+		return nil, fmt.Errorf("no team_users entry for %q to attach to the daemon as (set daemon_user)", name)
+	}
+
+	c.http = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	// A socket left behind by a daemon that exited does not answer
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://devos/versions", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	resp.Body.Close()
+	return c, nil
+}
+
+// CreatePlan asks the daemon to generate a plan for input
+func (c *Client) CreatePlan(ctx context.Context, input string) (*PlanV2, error) {
+	var plan PlanV2
+	if err := c.do(ctx, http.MethodPost, "/plans", map[string]string{"input": input}, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Plans lists the daemon's plans
+func (c *Client) Plans(ctx context.Context) ([]PlanV2, error) {
+	var list planListV2
+	if err := c.do(ctx, http.MethodGet, "/plans", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Plans, nil
+}
+
+// Plan returns one plan
+func (c *Client) Plan(ctx context.Context, id string) (*PlanV2, error) {
+	var plan PlanV2
+	if err := c.do(ctx, http.MethodGet, "/plans/"+id, nil, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Approve signs off on a plan as the session's user
+func (c *Client) Approve(ctx context.Context, id string) (*PlanV2, error) {
+	var plan PlanV2
+	if err := c.do(ctx, http.MethodPost, "/plans/"+id+"/approve", nil, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Reject cancels a pending plan
+func (c *Client) Reject(ctx context.Context, id string) (*PlanV2, error) {
+	var plan PlanV2
+	if err := c.do(ctx, http.MethodPost, "/plans/"+id+"/reject", nil, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Wait polls a plan until it is no longer running
+func (c *Client) Wait(ctx context.Context, id string) (*PlanV2, error) {
+	for {
+		plan, err := c.Plan(ctx, id)
+		if err != nil || plan.Status != StatusRunning {
+			return plan, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// do sends a v2 API request and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://devos/"+APIv2+path, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		if e.Error == "" {
+			e.Error = resp.Status
+		}
+		return fmt.Errorf("daemon: %s", e.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,202 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatText    = "text"
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// Formats lists the export formats
+var Formats = []string{FormatText, FormatDOT, FormatMermaid}
+
+// Graph is a plan's steps and the earlier steps each one depends on
+type Graph struct {
+	Steps []string
+	Deps  [][]int // Deps[i] are indexes of earlier steps step i needs
+}
+
+// New builds a graph, dropping dependencies that are out of range, point
+// forward, or are implied by others
+func New(steps []string, deps [][]int) *Graph {
+	g := &Graph{Steps: steps, Deps: make([][]int, len(steps))}
+	for i := range steps {
+		if i >= len(deps) {
+			continue
+		}
+		seen := map[int]bool{}
+		for _, d := range deps[i] {
+			if d >= 0 && d < i && !seen[d] {
+				seen[d] = true
+				g.Deps[i] = append(g.Deps[i], d)
+			}
+		}
+		sort.Ints(g.Deps[i])
+	}
+	g.reduce()
+	return g
+}
+
+// reduce removes dependencies reachable through another dependency, so
+// only the edges that order steps remain
+func (g *Graph) reduce() {
+	reach := make([]map[int]bool, len(g.Steps))
+	for i := range g.Steps {
+		reach[i] = map[int]bool{}
+		for _, d := range g.Deps[i] {
+			reach[i][d] = true
+			for r := range reach[d] {
+				reach[i][r] = true
+			}
+		}
+	}
+	for i, deps := range g.Deps {
+		var direct []int
+		for _, d := range deps {
+			implied := false
+			for _, other := range deps {
+				if other != d && reach[other][d] {
+					implied = true
+					break
+				}
+			}
+			if !implied {
+				direct = append(direct, d)
+			}
+		}
+		g.Deps[i] = direct
+	}
+}
+
+// Stages groups the steps by how many steps must finish before them; the
+// steps of one stage can run in parallel
+func (g *Graph) Stages() [][]int {
+	level := make([]int, len(g.Steps))
+	var stages [][]int
+	for i := range g.Steps {
+		for _, d := range g.Deps[i] {
+			if level[d]+1 > level[i] {
+				level[i] = level[d] + 1
+			}
+		}
+		for len(stages) <= level[i] {
+			stages = append(stages, nil)
+		}
+		stages[level[i]] = append(stages[level[i]], i)
+	}
+	return stages
+}
+
+// Parallel reports whether any steps could run at the same time
+func (g *Graph) Parallel() bool {
+	return len(g.Stages()) < len(g.Steps)
+}
+
+// Render returns the graph in one of Formats
+func (g *Graph) Render(format string, ascii bool) (string, error) {
+	switch format {
+	case FormatText, "":
+		return g.Text(ascii), nil
+	case FormatDOT:
+		return g.DOT(), nil
+	case FormatMermaid:
+		return g.Mermaid(), nil
+	}
+	return "", fmt.Errorf("unknown graph format %q (formats: %s)", format, strings.Join(Formats, ", "))
+}
+
+// Text draws the graph stage by stage, with the steps each one waits for.
+// ascii avoids box-drawing characters for terminals and screen readers
+// that do not handle them.
+func (g *Graph) Text(ascii bool) string {
+	node, branch, last, arrow := "●", "├─", "└─", "◀"
+	if ascii {
+		node, branch, last, arrow = "*", "|-", "`-", "<-"
+	}
+
+	stages := g.Stages()
+	var b strings.Builder
+	width := 0
+	for _, step := range g.Steps {
+		if len(step) > width {
+			width = len(step)
+		}
+	}
+	if width > 60 {
+		width = 60
+	}
+
+	for s, stage := range stages {
+		label := fmt.Sprintf("Stage %d", s+1)
+		if len(stage) > 1 {
+			label += fmt.Sprintf(" (%d in parallel)", len(stage))
+		}
+		fmt.Fprintf(&b, "  %s\n", label)
+		for n, i := range stage {
+			prefix := ""
+			if len(stage) > 1 {
+				prefix = branch
+				if n == len(stage)-1 {
+					prefix = last
+				}
+			}
+			line := fmt.Sprintf("    %s%s %2d. %-*s", prefix, node, i+1, width, truncate(g.Steps[i], width))
+			if len(g.Deps[i]) > 0 {
+				var after []string
+				for _, d := range g.Deps[i] {
+					after = append(after, fmt.Sprint(d+1))
+				}
+				line += fmt.Sprintf("  %s %s", arrow, strings.Join(after, ", "))
+			}
+			b.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+	}
+	return b.String()
+}
+
+// DOT returns the graph in Graphviz's DOT language
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph plan {\n  rankdir=TB;\n  node [shape=box, fontname=\"monospace\"];\n")
+	for i, step := range g.Steps {
+		label := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fmt.Sprintf("%d. %s", i+1, step))
+		fmt.Fprintf(&b, "  s%d [label=\"%s\"];\n", i+1, label)
+	}
+	for i, deps := range g.Deps {
+		for _, d := range deps {
+			fmt.Fprintf(&b, "  s%d -> s%d;\n", d+1, i+1)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid returns the graph as a Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for i, step := range g.Steps {
+		label := strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(fmt.Sprintf("%d. %s", i+1, step))
+		fmt.Fprintf(&b, "  s%d[\"%s\"]\n", i+1, label)
+	}
+	for i, deps := range g.Deps {
+		for _, d := range deps {
+			fmt.Fprintf(&b, "  s%d --> s%d\n", d+1, i+1)
+		}
+	}
+	return b.String()
+}
+
+// truncate shortens s to n characters with an ellipsis
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// dashboardPath is where the daemon serves its web dashboard
+const dashboardPath = "/dashboard"
+
+// dashboardPage lists the daemon's plans, newest first, and lets a team user
+// approve or reject the pending ones. It calls the v2 API with the login
+// cookie, or with a token pasted into the page and kept for the tab.
+const dashboardPage = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DevOS plans</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; max-width: 60em; }
+.plan { border: 1px solid #ccc; border-radius: 6px; padding: 1em; margin: 1em 0; }
+.high { border-color: #c00; }
+pre { background: #f4f4f4; padding: .5em; white-space: pre-wrap; }
+.confirm { color: #a60; }
+#error { color: #c00; }
+</style>
+</head>
+<body>
+<h1>DevOS plans</h1>
+<p id="auth" hidden>
+  <input id="token" type="password" placeholder="API token" size="40">
+  <button id="save">Use token</button>
+  <span id="login"></span>
+</p>
+<p id="error"></p>
+<div id="plans"></div>
+<script>
+const token = () => sessionStorage.getItem("devos-token");
+
+async function api(method, path) {
+  const headers = {};
+  if (token()) headers["Authorization"] = "Bearer " + token();
+  const resp = await fetch("/v2" + path, {method, headers, credentials: "same-origin"});
+  const body = await resp.json();
+  if (resp.status === 401) document.getElementById("auth").hidden = false;
+  if (!resp.ok) throw new Error(body.error || resp.statusText);
+  return body;
+}
+
+function el(tag, text, cls) {
+  const e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (cls) e.className = cls;
+  return e;
+}
+
+async function decide(id, action) {
+  try {
+    await api("POST", "/plans/" + id + "/" + action);
+    load();
+  } catch (err) {
+    document.getElementById("error").textContent = err.message;
+  }
+}
+
+async function load() {
+  let list;
+  try {
+    list = await api("GET", "/plans");
+  } catch (err) {
+    document.getElementById("error").textContent = err.message;
+    return;
+  }
+  document.getElementById("error").textContent = "";
+  const root = document.getElementById("plans");
+  root.replaceChildren();
+  list.plans.sort((a, b) => b.created_at.localeCompare(a.created_at));
+  for (const p of list.plans) {
+    const div = el("div", undefined, p.risk === "high" ? "plan high" : "plan");
+    div.append(el("h3", p.input));
+    div.append(el("p", p.status + " · requested by " + p.requester + " · " +
+      p.approval.approved_by.length + "/" + p.approval.required + " approvals" +
+      (p.risk === "high" ? " · HIGH RISK" : "") + (p.environment ? " · " + p.environment : "")));
+    div.append(el("pre", p.result.commands.join("\n")));
+    for (const c of p.approval.confirmations || []) {
+      div.append(el("p", "Approving confirms: " + c.detail, "confirm"));
+    }
+    if (p.error) div.append(el("p", p.error, "error"));
+    if (p.status === "pending_approval") {
+      const approve = el("button", "Approve");
+      approve.onclick = () => decide(p.id, "approve");
+      const reject = el("button", "Reject");
+      reject.onclick = () => decide(p.id, "reject");
+      div.append(approve, " ", reject);
+    }
+    root.append(div);
+  }
+}
+
+document.getElementById("save").onclick = () => {
+  sessionStorage.setItem("devos-token", document.getElementById("token").value);
+  document.getElementById("auth").hidden = true;
+  load();
+};
+if (LOGIN) {
+  const a = el("a", "or log in");
+  a.href = "/auth/login";
+  document.getElementById("login").append(a);
+}
+load();
+setInterval(load, 5000);
+</script>
+</body>
+</html>
+`
+
+// handleDashboard serves the web dashboard. The page itself holds no data;
+// everything it shows comes from the authenticated API.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != dashboardPath || r.Method != http.MethodGet {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	login := "false"
+	if s.config.OIDC != nil {
+		login = "true"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.Write([]byte(strings.Replace(dashboardPage, "if (LOGIN)", "if ("+login+")", 1)))
+}
+
+// dashboardURL returns the dashboard's address for notifications, when the
+// daemon's public address is known from the OIDC redirect URL
+func (s *Server) dashboardURL() string {
+	if s.config.OIDC == nil {
+		return ""
+	}
+	redirect, err := url.Parse(s.config.OIDC.RedirectURL)
+	if err != nil || redirect.Host == "" {
+		return ""
+	}
+	return redirect.Scheme + "://" + redirect.Host + dashboardPath
+}
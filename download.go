@@ -0,0 +1,308 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/policy"
+	"devos/internal/retry"
+)
+
+// maxDownloadSize caps artifacts fetched for inspection
+const maxDownloadSize = 200 << 20
+
+// previewLines is how much of a downloaded script is shown before it runs
+const previewLines = 40
+
+// pipeToInterpreter matches `curl URL | sh`-style commands
+var pipeToInterpreter = regexp.MustCompile(`^\s*(?:curl|wget)\b[^|]*?(https?://[^\s'"|]+)[^|]*\|\s*(sudo\s+)?(sh|bash|zsh|python3?|perl|ruby|node)\b(.*)$`)
+
+// downloadToFile matches `curl -o FILE URL` and `wget -O FILE URL` in either order
+var downloadToFile = regexp.MustCompile(`^\s*(?:curl\b[^|;&]*?\s-o|wget\b[^|;&]*?\s-O)\s*['"]?([^\s'"]+)['"]?`)
+
+// downloadURL extracts the URL from a download command
+var downloadURL = regexp.MustCompile(`https?://[^\s'"|;&]+`)
+
+// hexDigest matches a SHA-256 digest
+var hexDigest = regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`)
+
+// scriptRisks are patterns in downloaded scripts that deserve a closer look
+var scriptRisks = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`base64\s+(-d|--decode)[^|]*\|\s*(sh|bash)`), "decodes and runs an embedded payload"},
+	{regexp.MustCompile(`(curl|wget)\b[^|]*\|\s*(sudo\s+)?(sh|bash)`), "pipes another download into a shell"},
+	{regexp.MustCompile(`authorized_keys`), "modifies SSH authorized keys"},
+	{regexp.MustCompile(`/etc/sudoers`), "modifies sudoers"},
+	{regexp.MustCompile(`crontab\s|/etc/cron`), "installs scheduled jobs"},
+	{regexp.MustCompile(`\beval\s+"?\$\(`), "evaluates generated code"},
+	{regexp.MustCompile(`/dev/tcp/`), "opens raw network connections"},
+	{regexp.MustCompile(`(?i)history\s+-c|unset\s+HISTFILE`), "clears shell history"},
+}
+
+// Download is an artifact fetched and checked before a plan runs it
+type Download struct {
+	URL       string
+	Path      string // Local copy the rewritten command uses
+	SHA256    string
+	Checksum  string   // "verified", "not published", or "mismatch"
+	Signature string   // "verified", "not published", "failed", or "gpg not installed"
+	Findings  []string // Static analysis findings for scripts
+	Preview   []string // First lines of a script
+	Original  string
+	Rewritten string
+}
+
+// InspectDownloads fetches artifacts that the plan downloads, verifies any
+// published checksums and signatures, and statically scans scripts. Commands
+// are rewritten to use the inspected copy so what runs is exactly what was
+// shown, and the plan is marked as needing confirmation.
+func (e *Executor) InspectDownloads(ctx context.Context, result *ExecutionResult) ([]*Download, error) {
+	if e.config.OS == "windows" {
+		return nil, nil
+	}
+
+	var downloads []*Download
+	for i, cmd := range result.Commands {
+		var d *Download
+		var err error
+
+		if m := pipeToInterpreter.FindStringSubmatch(cmd); m != nil {
+			d, err = e.fetch(ctx, m[1])
+			if err == nil {
+				d.Rewritten = strings.TrimSpace(m[2] + m[3] + " " + Quote(d.Path) + " " + interpreterArgs(m[4]))
+			}
+		} else if m := downloadToFile.FindStringSubmatch(cmd); m != nil && m[1] != "-" && !strings.ContainsAny(cmd, "|;&") {
+			url := downloadURL.FindString(cmd)
+			if url == "" {
+				continue
+			}
+			d, err = e.fetch(ctx, url)
+			if err == nil {
+				d.Rewritten = "cp " + Quote(d.Path) + " " + Quote(m[1])
+			}
+		} else {
+			continue
+		}
+
+		if err != nil {
+			return downloads, fmt.Errorf("failed to inspect download in %q: %w", cmd, err)
+		}
+		if d.Checksum == "mismatch" {
+			e.audit.Record("download_blocked", map[string]string{"url": d.URL, "sha256": d.SHA256})
+			return downloads, fmt.Errorf("%w: checksum mismatch for %s", ErrValidationBlocked, d.URL)
+		}
+
+		d.Original = cmd
+		result.Commands[i] = d.Rewritten
+		result.Steps = nil
+		result.NeedsConfirmation = true
+		downloads = append(downloads, d)
+
+		e.audit.Record("download_inspected", map[string]string{
+			"url": d.URL, "sha256": d.SHA256, "checksum": d.Checksum, "signature": d.Signature,
+		})
+	}
+
+	return downloads, nil
+}
+
+// fetch downloads url to a temporary file and checks it
+func (e *Executor) fetch(ctx context.Context, url string) (*Download, error) {
+	var body []byte
+	err := retry.Do(ctx, e.config.RetryPolicy(config.RetryDownloads), nil,
+		func(attempt int, err error, wait time.Duration) {
+			e.logger.Warn("Download failed (attempt %d), retrying in %s: %v", attempt, wait.Round(100*time.Millisecond), err)
+		},
+		func(ctx context.Context) error {
+			var err error
+			body, err = httpGet(ctx, url, maxDownloadSize)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	name, _, _ := strings.Cut(path.Base(url), "?")
+	file, err := os.CreateTemp("", "devos-download-*-"+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store download: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to store download: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	d := &Download{
+		URL:    url,
+		Path:   file.Name(),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	d.Checksum = publishedChecksum(ctx, url, d.SHA256)
+	d.Signature = verifySignature(ctx, url, d.Path)
+
+	if isText(body) {
+		d.Findings = scanScript(string(body))
+		d.Preview = head(string(body), previewLines)
+	}
+
+	return d, nil
+}
+
+// publishedChecksum compares digest against checksum files published next to url
+func publishedChecksum(ctx context.Context, url, digest string) string {
+	dir, name := path.Dir(url), path.Base(url)
+	candidates := []string{url + ".sha256", url + ".sha256sum", dir + "/SHA256SUMS", dir + "/checksums.txt"}
+
+	for _, candidate := range candidates {
+		body, err := httpGet(ctx, candidate, 1<<20)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(body), "\n") {
+			published := hexDigest.FindString(line)
+			if published == "" {
+				continue
+			}
+			// Sum files list many artifacts; only compare the matching line
+			if strings.Count(string(body), "\n") > 1 && !strings.Contains(line, name) {
+				continue
+			}
+			if strings.EqualFold(published, digest) {
+				return "verified"
+			}
+			return "mismatch"
+		}
+	}
+	return "not published"
+}
+
+// verifySignature checks a detached GPG signature published next to url
+func verifySignature(ctx context.Context, url, file string) string {
+	for _, ext := range []string{".asc", ".sig"} {
+		sig, err := httpGet(ctx, url+ext, 1<<20)
+		if err != nil {
+			continue
+		}
+		if _, err := exec.LookPath("gpg"); err != nil {
+			return "gpg not installed"
+		}
+
+		sigFile := file + ext
+		if err := os.WriteFile(sigFile, sig, 0600); err != nil {
+			return "failed"
+		}
+		defer os.Remove(sigFile)
+
+		if err := exec.CommandContext(ctx, "gpg", "--verify", sigFile, file).Run(); err != nil {
+			return "failed"
+		}
+		return "verified"
+	}
+	return "not published"
+}
+
+// scanScript returns static analysis findings for a downloaded script
+func scanScript(script string) []string {
+	var findings []string
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, risk := range scriptRisks {
+			if risk.pattern.MatchString(line) {
+				findings = append(findings, fmt.Sprintf("line %d %s: %s", n, risk.reason, line))
+			}
+		}
+		if policy.IsHighRisk(line) {
+			findings = append(findings, fmt.Sprintf("line %d is high-risk: %s", n, line))
+		}
+	}
+	return findings
+}
+
+// httpGet fetches url, refusing bodies larger than limit
+func httpGet(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{url: url, status: resp.Status, code: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", url, limit)
+	}
+	return body, nil
+}
+
+// httpStatusError is an unsuccessful HTTP response
+type httpStatusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.url, e.status)
+}
+
+// HTTPStatus returns the response's status code, for retry decisions
+func (e *httpStatusError) HTTPStatus() int {
+	return e.code
+}
+
+// interpreterArgs drops the stdin flags of a piped interpreter invocation,
+// e.g. "-s -- --yes" becomes "--yes"
+func interpreterArgs(args string) string {
+	fields := strings.Fields(args)
+	for len(fields) > 0 && (fields[0] == "-s" || fields[0] == "-" || fields[0] == "--") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
+
+// isText reports whether data looks like a script rather than a binary
+func isText(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return !bytes.Contains(data, []byte{0})
+}
+
+// head returns the first n lines of s
+func head(s string, n int) []string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return lines
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"context"
+	"os"
+
+	"devos/internal/project"
+)
+
+// maxDriftPaths bounds how many changed paths of each kind are sent to the AI engine
+const maxDriftPaths = 50
+
+// snapshotWorkspace records the project the plan was generated against, so
+// edits made while the plan awaits approval can be noticed. Files compare
+// by size and mtime, which keeps this cheap enough to run for every plan.
+func (e *Executor) snapshotWorkspace(result *ExecutionResult) {
+	if len(result.Commands) == 0 {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	s := &snapshot{files: map[string]fileState{}}
+	walkFiles(project.Root(cwd), -1, false, s.files)
+	result.workspace = s
+}
+
+// WorkspaceDrift returns the project files that changed since the plan was
+// generated, or nil if none did
+func (e *Executor) WorkspaceDrift(result *ExecutionResult) *Changes {
+	if result.workspace == nil {
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	now := &snapshot{files: map[string]fileState{}}
+	walkFiles(project.Root(cwd), -1, false, now.files)
+	if drift := result.workspace.diff(now); !drift.Empty() {
+		return drift
+	}
+	return nil
+}
+
+// Regenerate asks the AI engine for a fresh plan for the same request,
+// telling it which files changed since the stale plan was made. Runbook
+// plans are instantiated again instead.
+func (e *Executor) Regenerate(ctx context.Context, result *ExecutionResult, drift *Changes) (*ExecutionResult, error) {
+	if result.Runbook != nil {
+		return e.Runbook(result.Runbook.Name, result.Runbook.Params)
+	}
+	e.logger.Info("Re-planning after workspace changes: %s", result.Prompt)
+	return e.generate(ctx, result.Prompt, map[string]interface{}{
+		"workspace_changes": map[string][]string{
+			"modified": capPaths(drift.Modified),
+			"added":    capPaths(drift.Added),
+			"deleted":  capPaths(drift.Deleted),
+		},
+		"stale_commands": result.Commands,
+	})
+}
+
+// capPaths keeps at most maxDriftPaths paths
+func capPaths(paths []string) []string {
+	if len(paths) > maxDriftPaths {
+		return paths[:maxDriftPaths]
+	}
+	return paths
+}
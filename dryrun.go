@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+)
+
+// Resolved is a command as it would run: the process started, where, with
+// which variables set on top of DevOS's own environment, and for how long
+type Resolved struct {
+	Command string        `json:"command"`
+	Argv    []string      `json:"argv"`
+	Dir     string        `json:"dir"`
+	Env     []string      `json:"env,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty"` // 0 for no limit
+	Tmux    bool          `json:"tmux,omitempty"`    // Handed to tmux as a task
+}
+
+// Resolve returns how each of commands would run. Steps, when non-nil, are
+// their structured form.
+func (e *Executor) Resolve(commands []string, steps []Command) []Resolved {
+	cwd, _ := os.Getwd()
+	// Clipped, so commands adding their own variables get copies
+	vars := slices.Clip(e.environmentVars())
+	resolved := make([]Resolved, len(commands))
+	for i, cmd := range commands {
+		r := Resolved{Command: cmd, Dir: cwd, Env: vars, Timeout: e.commandTimeout()}
+		switch {
+		case steps != nil:
+			step := steps[i]
+			r.Argv = append([]string{step.Program}, step.Args...)
+			if step.Dir != "" {
+				r.Dir = step.Dir
+			}
+			for _, key := range step.envKeys() {
+				r.Env = append(r.Env, key+"="+step.Env[key])
+			}
+		case e.useTmux(cmd):
+			r.Argv, r.Tmux, r.Timeout = []string{e.shell(), "-c", cmd}, true, 0
+		case e.config.OS == "windows":
+			r.Argv = []string{"powershell", "-Command", cmd}
+		default:
+			r.Argv = []string{e.shell(), "-c", cmd}
+		}
+		resolved[i] = r
+		// Later commands would run where this one's cd leaves its shell
+		if steps == nil {
+			for _, target := range cdTargets(cmd) {
+				if dir, err := e.cdPath(cwd, target); err == nil {
+					cwd = dir
+				}
+			}
+		} else if cdPrograms[steps[i].Program] && len(steps[i].Args) > 0 {
+			if dir, err := e.cdPath(cwd, steps[i].Args[len(steps[i].Args)-1]); err == nil {
+				cwd = dir
+			}
+		}
+	}
+	return resolved
+}
+
+// DryRun prints how a plan's commands would run, without running them
+func (e *Executor) DryRun(result *ExecutionResult) {
+	steps := result.Steps
+	if len(steps) != len(result.Commands) {
+		steps = nil
+	}
+	e.printDryRun(e.Resolve(result.Commands, steps))
+	e.audit.Record("dry_run", map[string]string{"commands": strings.Join(result.Commands, "\n")})
+}
+
+// printDryRun lists resolved commands, with the values of variables that
+// look like secrets masked
+func (e *Executor) printDryRun(resolved []Resolved) {
+	fmt.Println("\n🧪 Dry run: nothing was executed. These commands would run:")
+	for i, r := range resolved {
+		fmt.Printf("\n  %d. %s\n", i+1, r.Command)
+		fmt.Printf("     exec:    %s\n", strings.Join(quoteAll(r.Argv), " "))
+		fmt.Printf("     dir:     %s\n", r.Dir)
+		for _, v := range r.Env {
+			name, value, _ := strings.Cut(v, "=")
+			if config.SecretVar(name) {
+				value = "********"
+			}
+			fmt.Printf("     env:     %s=%s\n", name, value)
+		}
+		switch {
+		case r.Tmux:
+			fmt.Println("     runs:    in tmux, in the background")
+		case r.Timeout > 0:
+			fmt.Printf("     timeout: %s\n", r.Timeout)
+		}
+	}
+}
+
+// quoteAll quotes arguments that need it for the shell
+func quoteAll(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return quoted
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+)
+
+// SandboxRule names the built-in blocked-command and dangerous-pattern checks
+// so they can be relaxed by an elevated session like any approval rule
+const SandboxRule = "sandbox"
+
+// GuardrailsRule names the organization guardrails' blocked commands in
+// policy triggers; unlike the sandbox, they cannot be relaxed
+const GuardrailsRule = "guardrails"
+
+// maxElevation caps how long a session may stay elevated
+const maxElevation = 4 * time.Hour
+
+// elevation is a temporary relaxation of policy rules
+type elevation struct {
+	rules   map[string]bool
+	reason  string
+	expires time.Time
+	timer   *time.Timer
+}
+
+// Unlock temporarily relaxes the named policy rules for the given duration.
+// Rules must be named, so the sandbox is only ever relaxed on purpose, and
+// never when the managed configuration locks it. A reason is mandatory and
+// is audited.
+func (e *Executor) Unlock(duration time.Duration, rules []string, reason string) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("name the policy rules to unlock")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("a reason is required to unlock")
+	}
+	if duration <= 0 || duration > maxElevation {
+		return fmt.Errorf("unlock duration must be between 0 and %s", maxElevation)
+	}
+
+	known := map[string]bool{SandboxRule: true}
+	for _, rule := range e.config.Rules() {
+		known[rule.Name] = true
+	}
+
+	relaxed := make(map[string]bool)
+	for _, rule := range rules {
+		if !known[rule] {
+			return fmt.Errorf("unknown policy rule: %s", rule)
+		}
+		if rule == SandboxRule && e.sandboxLocked() {
+			return fmt.Errorf("the %s rule cannot be unlocked: sandbox_mode is locked by %s", SandboxRule, e.config.ManagedSource())
+		}
+		relaxed[rule] = true
+	}
+
+	e.Lock()
+
+	elev := &elevation{
+		rules:   relaxed,
+		reason:  reason,
+		expires: time.Now().Add(duration),
+	}
+	elev.timer = time.AfterFunc(duration, func() {
+		e.logger.Info("Elevated session expired")
+		e.audit.Record("session_relocked", map[string]string{"cause": "timeout"})
+	})
+	e.elevation = elev
+
+	scope := strings.Join(rules, ",")
+	e.logger.Warn("Session elevated for %s (rules: %s): %s", duration, scope, reason)
+	e.audit.Record("session_unlocked", map[string]string{
+		"duration": duration.String(),
+		"rules":    scope,
+		"reason":   reason,
+	})
+
+	return nil
+}
+
+// Lock ends an elevated session immediately
+func (e *Executor) Lock() {
+	if e.elevation == nil {
+		return
+	}
+
+	if e.elevation.timer.Stop() {
+		e.audit.Record("session_relocked", map[string]string{"cause": "manual"})
+	}
+	e.elevation = nil
+}
+
+// Elevated returns when the current elevation expires, if the session is elevated
+func (e *Executor) Elevated() (time.Time, bool) {
+	if !e.isElevated() {
+		return time.Time{}, false
+	}
+	return e.elevation.expires, true
+}
+
+// isElevated reports whether an unexpired elevation is active
+func (e *Executor) isElevated() bool {
+	if e.elevation == nil {
+		return false
+	}
+	if time.Now().After(e.elevation.expires) {
+		e.elevation = nil
+		return false
+	}
+	return true
+}
+
+// relaxed reports whether the named rule is relaxed by the current elevation
+func (e *Executor) relaxed(rule string) bool {
+	if !e.isElevated() || (rule == SandboxRule && e.sandboxLocked()) {
+		return false
+	}
+	return e.elevation.rules[rule]
+}
+
+// sandboxLocked reports whether the managed configuration locks
+// sandbox_mode, so no elevation may relax the sandbox
+func (e *Executor) sandboxLocked() bool {
+	return slices.Contains(e.config.Locked(), "sandbox_mode")
+}
+
+// activeRules returns the approval rules not relaxed by the current
+// elevation, after the organization's, which are never relaxed
+func (e *Executor) activeRules() []config.ApprovalRule {
+	var rules []config.ApprovalRule
+	if e.orgGuardrails != nil {
+		rules = append(rules, e.orgGuardrails.ApprovalRules...)
+	}
+	if !e.isElevated() {
+		return append(rules, e.config.Rules()...)
+	}
+
+	for _, rule := range e.config.Rules() {
+		if !e.relaxed(rule.Name) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"devos/internal/audit"
+	"devos/internal/config"
+	"devos/internal/logger"
+)
+
+// elevationExecutor returns an executor with two approval rules, auditing
+// to a temporary file
+func elevationExecutor(t *testing.T) *Executor {
+	t.Helper()
+	trail, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { trail.Close() })
+	return &Executor{
+		config: &config.Config{ApprovalRules: []config.ApprovalRule{
+			{Name: "ask-ssh-files", Paths: []string{"~/.ssh"}, Action: "ask"},
+			{Name: "ask-package-changes", Classes: []string{"package"}, Action: "ask"},
+		}},
+		logger: logger.New("error"),
+		audit:  trail,
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		rules    []string
+		reason   string
+		ok       bool
+	}{
+		{"one rule", time.Hour, []string{"ask-ssh-files"}, "rotating keys", true},
+		{"sandbox", time.Minute, []string{SandboxRule, "ask-package-changes"}, "cleanup", true},
+		{"longest", maxElevation, []string{"ask-ssh-files"}, "migration", true},
+		{"no rules", time.Hour, nil, "rotating keys", false},
+		{"no reason", time.Hour, []string{"ask-ssh-files"}, "  ", false},
+		{"no duration", 0, []string{"ask-ssh-files"}, "rotating keys", false},
+		{"too long", maxElevation + time.Minute, []string{"ask-ssh-files"}, "rotating keys", false},
+		{"unknown rule", time.Hour, []string{"ask-everything"}, "rotating keys", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := elevationExecutor(t)
+			err := e.Unlock(tt.duration, tt.rules, tt.reason)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Unlock = %v, want success %v", err, tt.ok)
+			}
+			defer e.Lock()
+
+			named := make(map[string]bool)
+			for _, rule := range tt.rules {
+				named[rule] = tt.ok
+			}
+			for _, rule := range []string{SandboxRule, "ask-ssh-files", "ask-package-changes"} {
+				if got := e.relaxed(rule); got != named[rule] {
+					t.Errorf("relaxed(%q) = %v, want %v", rule, got, named[rule])
+				}
+			}
+			if _, elevated := e.Elevated(); elevated != tt.ok {
+				t.Errorf("Elevated = %v, want %v", elevated, tt.ok)
+			}
+			if active := len(e.activeRules()); tt.ok && active == 2 {
+				t.Errorf("activeRules kept all %d rules while elevated", active)
+			}
+		})
+	}
+}
+
+func TestElevationEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(e *Executor)
+	}{
+		{"expiry", func(e *Executor) { e.elevation.expires = time.Now().Add(-time.Second) }},
+		{"lock", func(e *Executor) { e.Lock() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := elevationExecutor(t)
+			if err := e.Unlock(time.Hour, []string{"ask-ssh-files", SandboxRule}, "rotating keys"); err != nil {
+				t.Fatal(err)
+			}
+			defer e.Lock()
+			tt.end(e)
+
+			if _, elevated := e.Elevated(); elevated {
+				t.Error("session still elevated")
+			}
+			for _, rule := range []string{"ask-ssh-files", SandboxRule} {
+				if e.relaxed(rule) {
+					t.Errorf("rule %q still relaxed", rule)
+				}
+			}
+			if active := len(e.activeRules()); active != 2 {
+				t.Errorf("activeRules = %d rules, want all 2", active)
+			}
+		})
+	}
+}
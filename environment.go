@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"devos/internal/config"
+)
+
+// environmentVars returns the variables the active environment sets for
+// every command, as KEY=value, or nil when no environment is active
+func (e *Executor) environmentVars() []string {
+	env := e.config.ActiveEnvironment()
+	if env == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(env.Env))
+	for key := range env.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vars := []string{"DEVOS_ENV=" + env.Name}
+	for _, key := range keys {
+		vars = append(vars, key+"="+env.Env[key])
+	}
+	kubeconfig, err := e.kubeconfig(env)
+	if err != nil {
+		e.logger.Warn("Not pinning Kubernetes context %q: %v", env.KubeContext, err)
+	}
+	if kubeconfig != "" {
+		vars = append(vars, "KUBECONFIG="+kubeconfig)
+	}
+	return vars
+}
+
+// environ returns the environment commands run with: this process's, with
+// the active environment's variables on top. nil leaves it unchanged.
+func (e *Executor) environ() []string {
+	vars := e.environmentVars()
+	if vars == nil {
+		return nil
+	}
+	return append(os.Environ(), vars...)
+}
+
+// kubeconfig returns the KUBECONFIG for an environment, or "" to leave it
+// alone. kubectl and helm take the current context from the first listed
+// file that sets one, so a context is pinned by listing a file setting only
+// that ahead of the real configuration.
+func (e *Executor) kubeconfig(env *config.Environment) (string, error) {
+	files := os.Getenv("KUBECONFIG")
+	if env.Kubeconfig != "" {
+		files = expandHome(env.Kubeconfig)
+	}
+	if env.KubeContext == "" {
+		return files, nil
+	}
+	if files == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		files = filepath.Join(home, ".kube", "config")
+	}
+
+	overlay := filepath.Join(filepath.Dir(e.config.ConfigPath), "environments", env.Name+".kubeconfig")
+	data := fmt.Sprintf("apiVersion: v1\nkind: Config\ncurrent-context: %q\n", env.KubeContext)
+	if current, err := os.ReadFile(overlay); err != nil || string(current) != data {
+		if err := os.MkdirAll(filepath.Dir(overlay), 0700); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(overlay, []byte(data), 0600); err != nil {
+			return "", err
+		}
+	}
+	return overlay + string(os.PathListSeparator) + files, nil
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
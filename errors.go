@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"devos/internal/config"
+)
+
+// Sentinel errors returned (wrapped) by the executor so callers can branch
+// with errors.Is instead of matching message text
+var (
+	ErrValidationBlocked = errors.New("blocked by security validation")
+	ErrPolicyDenied      = errors.New("denied by approval policy")
+	ErrProviderTimeout   = errors.New("AI provider timed out")
+	ErrProviderFailed    = errors.New("AI provider failed")
+
+	// ErrModelNotFound means a local provider has not pulled the configured model
+	ErrModelNotFound = fmt.Errorf("%w: model not found", ErrProviderFailed)
+
+	// ErrBudgetExceeded means a blocking budget for the provider is used up
+	ErrBudgetExceeded = errors.New("AI budget exceeded")
+
+	// ErrCommandTimeout means a command was stopped for running past its timeout
+	ErrCommandTimeout = errors.New("command timed out")
+)
+
+// Process exit codes for non-interactive invocations
+const (
+	ExitOK       = 0
+	ExitFailure  = 1
+	ExitConfig   = 2
+	ExitBlocked  = 3
+	ExitProvider = 4
+	ExitTimeout  = 5
+
+	// ExitInterrupted follows the shell convention of 128 + SIGINT
+	ExitInterrupted = 130
+)
+
+// ErrCommandFailed reports a shell command that exited unsuccessfully
+type ErrCommandFailed struct {
+	Command  string
+	ExitCode int // -1 if the process did not exit normally
+	Stderr   string
+	Err      error
+}
+
+func (e *ErrCommandFailed) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("%v: %s", e.Err, e.Stderr)
+	}
+	return e.Err.Error()
+}
+
+func (e *ErrCommandFailed) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode maps an error to a process exit code. Failed commands propagate
+// their own exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var cmdErr *ErrCommandFailed
+	switch {
+	case errors.As(err, &cmdErr) && cmdErr.ExitCode > 0:
+		return cmdErr.ExitCode
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, config.ErrInvalidConfig):
+		return ExitConfig
+	case errors.Is(err, ErrValidationBlocked), errors.Is(err, ErrPolicyDenied), errors.Is(err, ErrBudgetExceeded):
+		return ExitBlocked
+	case errors.Is(err, ErrProviderTimeout), errors.Is(err, ErrCommandTimeout):
+		return ExitTimeout
+	case errors.Is(err, ErrProviderFailed):
+		return ExitProvider
+	default:
+		return ExitFailure
+	}
+}
+
+// failureText returns the most specific description of a failure, preferring
+// the command's stderr so equivalent failures share a signature
+func failureText(err error) string {
+	var cmdErr *ErrCommandFailed
+	if errors.As(err, &cmdErr) && cmdErr.Stderr != "" {
+		return cmdErr.Stderr
+	}
+	return err.Error()
+}
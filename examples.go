@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"devos/internal/memory"
+	"devos/internal/privacy"
+	"devos/internal/project"
+)
+
+// maxFewShotExamples limits how many rated plans are sent to the AI engine
+const maxFewShotExamples = 3
+
+// feedbackWindow is how many recent ratings are searched for examples
+const feedbackWindow = 200
+
+// minExampleSimilarity is the word overlap a rated request needs with the
+// current one to be used as an example
+const minExampleSimilarity = 0.3
+
+// ratedExample is a plan the user rated for a request like the current
+// one: good plans to follow, bad ones (with the reason) to avoid
+type ratedExample struct {
+	Input    string   `json:"input"`
+	Commands []string `json:"commands"`
+	Rating   string   `json:"rating"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// ratedExamples picks the rated plans whose requests are most like input,
+// leaving out local-only projects' plans when the request goes to the cloud
+func (e *Executor) ratedExamples(input string, cloud bool) []ratedExample {
+	if e.memory == nil {
+		return nil
+	}
+	feedback, err := e.memory.RecentFeedback(feedbackWindow)
+	if err != nil {
+		e.logger.Warn("Failed to load feedback: %v", err)
+		return nil
+	}
+
+	type candidate struct {
+		example ratedExample
+		score   float64
+	}
+	var candidates []candidate
+	seen := map[string]bool{}
+	words := wordSet(input)
+	for _, f := range feedback {
+		if cloud && privacy.Match(e.config.LocalOnly, f.Project) != "" {
+			continue
+		}
+		// Ratings arrive newest first; a later rating of the same plan wins
+		key := f.Input + "\x00" + strings.Join(f.Commands, "\n")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if score := similarity(words, wordSet(f.Input)); score >= minExampleSimilarity {
+			candidates = append(candidates, candidate{
+				example: ratedExample{Input: f.Input, Commands: f.Commands, Rating: f.Rating, Reason: f.Reason},
+				score:   score,
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	var examples []ratedExample
+	for _, c := range candidates {
+		if len(examples) == maxFewShotExamples {
+			break
+		}
+		examples = append(examples, c.example)
+	}
+	return examples
+}
+
+// wordSet returns the lowercase words of a request
+func wordSet(s string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[w] = true
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two word sets
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// RecordFeedback stores the user's rating of a plan, which guides the plans
+// made for similar requests
+func (e *Executor) RecordFeedback(input string, result *ExecutionResult, rating, reason string) error {
+	if e.memory == nil {
+		return fmt.Errorf("memory is not available")
+	}
+	f := memory.Feedback{
+		Input:    input,
+		Output:   result.Output,
+		Commands: result.Commands,
+		Rating:   rating,
+		Reason:   reason,
+		Provider: result.Provider,
+		Model:    result.Model,
+	}
+	if f.Provider == "" {
+		f.Provider, f.Model = e.config.AIProvider, e.config.Model
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		f.Project = project.Root(cwd)
+	}
+	return e.memory.AddFeedback(f)
+}
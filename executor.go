@@ -2,139 +2,1103 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"devos/internal/attach"
+	"devos/internal/audit"
+	"devos/internal/budget"
 	"devos/internal/config"
+	"devos/internal/freeze"
+	"devos/internal/guardrail"
+	"devos/internal/helm"
+	"devos/internal/issues"
 	"devos/internal/logger"
+	"devos/internal/memory"
+	"devos/internal/ollama"
+	"devos/internal/platform"
+	"devos/internal/policy"
+	"devos/internal/privacy"
+	"devos/internal/project"
+	"devos/internal/projectlock"
+	"devos/internal/retry"
+	"devos/internal/sshsetup"
 )
 
+// defaultAITimeout bounds a single AI engine call when ai_timeout is unset
+const defaultAITimeout = 120 * time.Second
+
+// maxFewShotCorrections limits how many past corrections are sent to the AI engine
+const maxFewShotCorrections = 5
+
 // ExecutionResult represents the result of command execution
 type ExecutionResult struct {
 	Output            string   `json:"output"`
 	Commands          []string `json:"commands"`
 	NeedsConfirmation bool     `json:"needs_confirmation"`
 	Error             string   `json:"error,omitempty"`
+	Intent            string   `json:"intent,omitempty"`
+	TokensUsed        int      `json:"tokens_used,omitempty"`
+	Provider          string   `json:"provider,omitempty"` // Where the plan was generated, when a budget moved it
+	Model             string   `json:"model,omitempty"`
+	PolicyNotes       []string `json:"policy_notes,omitempty"`
+	Warnings          []string `json:"warnings,omitempty"`
+
+	// DependsOn optionally lists, for each command, the indexes of earlier
+	// commands it needs; without it, dependencies are inferred for display
+	DependsOn [][]int `json:"depends_on,omitempty"`
+
+	// Steps optionally gives each command in structured form. When present,
+	// Commands is derived from Steps and execution bypasses the shell.
+	Steps []Command `json:"steps,omitempty"`
+
+	// Prompt is the request the plan was generated for
+	Prompt string `json:"prompt,omitempty"`
+
+	// Runbook names a runbook to instantiate in place of Commands
+	Runbook *RunbookCall `json:"runbook,omitempty"`
+
+	// Changes lists what executing the plan modified, when tracked
+	Changes *Changes `json:"changes,omitempty"`
+
+	// Undo optionally gives, for each command, the command that reverses
+	// it, or "" for none; commands without one fall back to the undo rules
+	Undo []string `json:"undo,omitempty"`
+
+	// Rollback reverses what the plan's completed steps did, last step
+	// first, then restores the package versions from before it ran
+	Rollback []string `json:"rollback,omitempty"`
+
+	// Irreversible lists the completed steps that changed things but that
+	// Rollback cannot reverse
+	Irreversible []string `json:"irreversible,omitempty"`
+
+	// workspace is the project as it was when the plan was generated
+	workspace *snapshot
 }
 
 // Executor handles command execution and AI integration
 type Executor struct {
 	config *config.Config
 	logger *logger.Logger
+	memory memory.MemoryStore
+	audit  *audit.Trail
+
+	// platform describes the local machine for command generation
+	platform *platform.Info
+
+	// elevation is the active time-boxed policy relaxation, if any
+	elevation *elevation
+
+	// pendingFailure holds the last failure awaiting a successful resolution
+	pendingFailure *failure
+
+	// builtin is the in-process model for the "builtin" provider
+	builtin builtinEngine
+
+	// freezes are the configured change-freeze windows
+	freezes *freeze.Checker
+
+	// output transforms command output before it is displayed
+	output *outputProcessors
+
+	// undoRules give compensating commands for steps without their own
+	undoRules []undoRule
+
+	// jobs are the plans running in the background of the session
+	jobs *JobManager
+
+	// orgGuardrails is the organization's guardrails bundle last loaded, and
+	// guardrailsTried when loading it was last tried
+	orgGuardrails   *guardrail.Bundle
+	guardrailsTried time.Time
+
+	// prevDir is the working directory before the last cd, for "cd -"
+	prevDir string
+}
+
+// failure describes a failed command whose fix has not been learned yet
+type failure struct {
+	command   string
+	signature string
+	sample    string
 }
 
 // New creates a new executor instance
-func New(cfg *config.Config, log *logger.Logger) (*Executor, error) {
+func New(cfg *config.Config, log *logger.Logger, mem memory.MemoryStore, trail *audit.Trail) (*Executor, error) {
+	freezes, err := newFreezeChecker(cfg.Freezes)
+	if err != nil {
+		return nil, err
+	}
+	output, err := newOutputProcessors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	undoRules, err := newUndoRules(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &Executor{
-		config: cfg,
-		logger: log,
+		config:    cfg,
+		logger:    log,
+		memory:    mem,
+		audit:     trail,
+		platform:  platform.Detect(),
+		freezes:   freezes,
+		output:    output,
+		undoRules: undoRules,
+		jobs:      NewJobManager(filepath.Join(cfg.Dir(), "jobs")),
 	}, nil
 }
 
+// SetMemory switches the executor to mem, such as after a profile import
+// replaced the store it was using
+func (e *Executor) SetMemory(mem memory.MemoryStore) {
+	e.memory = mem
+}
+
+// detached returns an executor for work that runs alongside the session,
+// such as a background job. It shares the logger, audit trail, memory
+// store, freeze checker, and jobs, which are safe for concurrent use, and
+// has its own copy of the config and the session's elevation, so the
+// session changing them does not race with it.
+func (e *Executor) detached() *Executor {
+	cfg := *e.config
+	d := &Executor{
+		config:          &cfg,
+		logger:          e.logger,
+		memory:          e.memory,
+		audit:           e.audit,
+		platform:        e.platform,
+		freezes:         e.freezes,
+		output:          e.output,
+		undoRules:       e.undoRules,
+		jobs:            e.jobs,
+		orgGuardrails:   e.orgGuardrails,
+		guardrailsTried: e.guardrailsTried,
+		prevDir:         e.prevDir,
+	}
+	if e.isElevated() {
+		elev := *e.elevation
+		d.elevation = &elev
+	}
+	return d
+}
+
 // Execute processes a natural language command through the AI engine
-func (e *Executor) Execute(input string) (*ExecutionResult, error) {
+func (e *Executor) Execute(ctx context.Context, input string) (*ExecutionResult, error) {
 	e.logger.Info("Executing command: %s", input)
 
+	// After a failure, consult the failure knowledge base before calling the AI
+	if fix := e.pendingFix(); fix != nil {
+		return e.prepare(&ExecutionResult{
+			Output:            fmt.Sprintf("💡 Known fix (used %d times before):", fix.Hits),
+			Commands:          fix.Commands,
+			NeedsConfirmation: true,
+			Prompt:            input,
+		})
+	}
+
+	return e.generate(ctx, input, nil)
+}
+
+// Replan asks the AI engine for a new plan that avoids unavailable tools
+func (e *Executor) Replan(ctx context.Context, input string, unavailable []string) (*ExecutionResult, error) {
+	e.logger.Info("Re-planning without: %s", strings.Join(unavailable, ", "))
+	return e.generate(ctx, input, map[string]interface{}{"unavailable_tools": unavailable})
+}
+
+// Diagnose asks the AI engine to explain a problem from collected evidence,
+// such as a summarized log excerpt ("log_excerpt"), DevOS's own log and last
+// error ("devos_logs", "devos_last_error"), or network checks ("net_report")
+func (e *Executor) Diagnose(ctx context.Context, question string, evidence map[string]interface{}) (*ExecutionResult, error) {
+	e.logger.Info("Diagnosing: %s", question)
+	return e.generate(ctx, question, evidence)
+}
+
+// ExecuteIssue plans work on a tracker issue, giving the AI engine its
+// description as context ("issue")
+func (e *Executor) ExecuteIssue(ctx context.Context, input string, issue *issues.Issue) (*ExecutionResult, error) {
+	e.logger.Info("Planning work on %s: %s", issue.Key, input)
+	return e.generate(ctx, input, map[string]interface{}{"issue": issue})
+}
+
+// generate calls the AI engine and prepares its plan; extra fields are
+// added to the engine request
+func (e *Executor) generate(ctx context.Context, input string, extra map[string]interface{}) (*ExecutionResult, error) {
+	// Attach the files the input mentions as @path, as far as they are fit
+	// to send
+	var files []attach.File
+	var excluded []attach.Excluded
+	if paths := attach.Mentions(input); len(paths) > 0 {
+		files, excluded = attach.Collect(paths, e.config.AttachMaxBytes)
+		extra = maps.Clone(extra)
+		if extra == nil {
+			extra = map[string]interface{}{}
+		}
+		extra["files"] = files
+	}
+
 	// Call Python AI engine
-	result, err := e.callAIEngine(input)
+	result, err := e.callAIEngine(ctx, input, extra)
 	if err != nil {
 		return nil, fmt.Errorf("AI engine error: %w", err)
 	}
+	result.Prompt = input
+	for _, x := range excluded {
+		result.Warnings = append(result.Warnings, "Not attached to the prompt: "+x.String())
+	}
+	for _, f := range files {
+		if f.Truncated {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Only the start of %s was attached to the prompt (%d of %d KB)", f.Path, len(f.Content)>>10, f.Size>>10))
+		}
+	}
+	if result.Runbook != nil {
+		if err := e.instantiateRunbook(result); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.prepare(result)
+}
+
+// prepare normalizes, validates, and applies the approval policy to a plan
+func (e *Executor) prepare(result *ExecutionResult) (*ExecutionResult, error) {
+	// Structured steps are authoritative; derive the display form from them
+	if len(result.Steps) > 0 {
+		result.Commands = make([]string, len(result.Steps))
+		for i, step := range result.Steps {
+			result.Commands[i] = step.Render(e.config.OS)
+		}
+	}
 
 	// Validate commands for security
 	if err := e.validateCommands(result.Commands); err != nil {
 		return nil, fmt.Errorf("security validation failed: %w", err)
 	}
+	e.checkUndo(result)
+
+	// Apply approval policy
+	if err := e.applyPolicy(result); err != nil {
+		return nil, err
+	}
+
+	// Flag architecture-specific pitfalls
+	for _, cmd := range result.Commands {
+		result.Warnings = append(result.Warnings, e.platform.ArchWarnings(cmd)...)
+	}
+
+	// Flag plans made during a change freeze; feeds are fetched with their
+	// own timeout
+	e.flagFreeze(context.Background(), result)
 
+	e.snapshotWorkspace(result)
 	return result, nil
 }
 
+// Validate runs the security checks against a set of (possibly user-edited) commands
+func (e *Executor) Validate(commands []string) error {
+	if err := e.validateCommands(commands); err != nil {
+		return fmt.Errorf("security validation failed: %w", err)
+	}
+
+	for _, cmd := range commands {
+		if d := policy.Evaluate(e.activeRules(), cmd, policy.ActionAllow); d.Action == policy.ActionDeny {
+			e.recordTrigger(d.Rule, d.Action, "", cmd)
+			return fmt.Errorf("%w: rule %q: %s", ErrPolicyDenied, d.Rule, cmd)
+		}
+	}
+
+	return nil
+}
+
+// RecordCorrections stores commands the user edited before approving them;
+// commands they dropped are not corrections and are skipped
+func (e *Executor) RecordCorrections(input string, original, edited []string) {
+	if e.memory == nil {
+		return
+	}
+
+	for i := range original {
+		if i >= len(edited) || edited[i] == "" || original[i] == edited[i] {
+			continue
+		}
+		correction := memory.Correction{Input: input, Original: original[i], Edited: edited[i]}
+		if cwd, err := os.Getwd(); err == nil {
+			correction.Project = project.Root(cwd)
+		}
+		if err := e.memory.AddCorrection(correction); err != nil {
+			e.logger.Warn("Failed to record correction: %v", err)
+		}
+	}
+}
+
 // ExecuteCommands executes a list of shell commands
-func (e *Executor) ExecuteCommands(commands []string) error {
-	for i, cmdStr := range commands {
+func (e *Executor) ExecuteCommands(ctx context.Context, commands []string) error {
+	if e.config.DryRun {
+		e.DryRun(&ExecutionResult{Commands: commands})
+		return nil
+	}
+	// Rollbacks and installs DevOS writes itself are held to the allowlist
+	// like plans
+	if err := e.checkAllowlist(commands); err != nil {
+		return err
+	}
+	_, err := e.runCommands(ctx, e.startRun(commands, nil), commands, nil, 0)
+	return err
+}
+
+// ExecutePlan executes a plan, running structured steps without a shell when
+// the plan provides them. In dry-run mode the plan is only printed.
+func (e *Executor) ExecutePlan(ctx context.Context, result *ExecutionResult) error {
+	if e.config.DryRun {
+		e.DryRun(result)
+		return nil
+	}
+	steps := result.Steps
+	if len(steps) != len(result.Commands) {
+		steps = nil
+	}
+
+	// Note which source files the plan creates, for provenance headers
+	created := createdFiles(result.Commands)
+
+	// Package versions are always recorded so the plan can be undone
+	var before *snapshot
+	if e.config.TrackChanges || len(trackedManagers(result.Commands)) > 0 {
+		before = e.takeSnapshot(ctx, result.Commands, e.config.TrackChanges)
+	}
+	completed, err := e.runCommands(ctx, e.startRun(result.Commands, steps), result.Commands, steps, 0)
+	if errors.Is(err, ErrCommandTimeout) {
+		result.Error = err.Error()
+	}
+	e.recordProvenance(result.Prompt, created)
+
+	// The plan runs as a transaction: what its completed steps did can be
+	// reversed, after a failure or later with "undo"
+	result.Rollback, result.Irreversible = e.compensate(result, completed)
+	if before != nil {
+		// Snapshot even after a failure: partial changes matter most then
+		changes := before.diff(e.takeSnapshot(context.WithoutCancel(ctx), result.Commands, e.config.TrackChanges))
+		result.Rollback = append(result.Rollback, rollbackCommands(changes.Packages)...)
+		if e.config.TrackChanges {
+			result.Changes = changes
+		}
+	}
+	return err
+}
+
+// ResumeRun continues a journaled run after its last completed step
+func (e *Executor) ResumeRun(ctx context.Context, run *memory.Run) error {
+	e.logger.Info("Resuming run %d at step %d/%d", run.ID, run.Completed+1, len(run.Commands))
+	var steps []Command
+	if len(run.Steps) > 0 {
+		if err := json.Unmarshal(run.Steps, &steps); err != nil || len(steps) != len(run.Commands) {
+			steps = nil
+		}
+	}
+	if e.config.DryRun {
+		result := &ExecutionResult{Commands: run.Commands[run.Completed:]}
+		if steps != nil {
+			result.Steps = steps[run.Completed:]
+		}
+		e.DryRun(result)
+		return nil
+	}
+	_, err := e.runCommands(ctx, run.ID, run.Commands, steps, run.Completed)
+	return err
+}
+
+// PartialRun returns the previous execution of the same plan if it stopped
+// after some steps had already succeeded
+func (e *Executor) PartialRun(commands []string) *memory.Run {
+	if e.memory == nil {
+		return nil
+	}
+
+	run, err := e.memory.LastRun(memory.PlanHash(commands))
+	if err != nil {
+		e.logger.Warn("Failed to read execution journal: %v", err)
+		return nil
+	}
+	if run == nil || run.Status == memory.RunSucceeded || run.Completed == 0 {
+		return nil
+	}
+	return run
+}
+
+// runCommands executes commands from index start, journaling progress under
+// runID, and returns how many of the commands have completed. Steps, when
+// non-nil, are the structured form of commands.
+func (e *Executor) runCommands(ctx context.Context, runID int64, commands []string, steps []Command, start int) (int, error) {
+	release, err := e.lockProject(commands[start:])
+	if err != nil {
+		e.updateRun(runID, start, memory.RunFailed)
+		return start, err
+	}
+	defer release()
+
+	scanned := make(map[string]bool)
+	for i := start; i < len(commands); i++ {
+		if err := ctx.Err(); err != nil {
+			e.updateRun(runID, i, memory.RunFailed)
+			return i, fmt.Errorf("execution interrupted before step %d: %w", i+1, err)
+		}
+
+		cmdStr := commands[i]
+
+		// Scan images built earlier in the plan before deploying them
+		if err := e.scanBeforeDeploy(ctx, cmdStr, plannedImages(commands[:i]), scanned); err != nil {
+			e.updateRun(runID, i, memory.RunFailed)
+			return i, err
+		}
 		e.logger.Info("Executing command %d/%d: %s", i+1, len(commands), cmdStr)
 
-		// Execute command based on OS
-		output, err := e.executeShellCommand(cmdStr)
+		// Hand long-running commands to tmux so the plan can continue
+		if e.useTmux(cmdStr) {
+			task, err := e.startTask(ctx, cmdStr)
+			if err == nil {
+				fmt.Printf("  🖥️  Running in tmux as task %q (\"attach %s\" to watch)\n", task.Name, task.Name)
+				e.updateRun(runID, i+1, memory.RunRunning)
+				continue
+			}
+			e.logger.Warn("Running in the foreground instead: %v", err)
+		}
+
+		// cd is a shell builtin: as a step, it changes the session's directory
+		if steps != nil && cdPrograms[steps[i].Program] {
+			dir := ""
+			if n := len(steps[i].Args); n > 0 {
+				dir = steps[i].Args[n-1]
+			}
+			dir, err := e.Chdir(dir)
+			e.recordExecution(cmdStr, err)
+			if err != nil {
+				e.updateRun(runID, i, memory.RunFailed)
+				return i, fmt.Errorf("command failed: %s - %w", cmdStr, err)
+			}
+			fmt.Printf("  📂 Now in %s\n", dir)
+			e.updateRun(runID, i+1, memory.RunRunning)
+			continue
+		}
+
+		// Execute structured steps directly, raw commands through the OS shell
+		var step *Command
+		if steps != nil {
+			step = &steps[i]
+		}
+		output, err := e.runCommand(ctx, cmdStr, step, "", nil)
+		e.recordExecution(cmdStr, err)
 		if err != nil {
+			if ctx.Err() != nil {
+				e.updateRun(runID, i, memory.RunFailed)
+				return i, fmt.Errorf("command interrupted: %s: %w", cmdStr, ctx.Err())
+			}
 			e.logger.Error("Command failed: %s - Error: %v", cmdStr, err)
-			return fmt.Errorf("command failed: %s - %w", cmdStr, err)
+			e.updateRun(runID, i, memory.RunFailed)
+			e.rememberFailure(cmdStr, failureText(err))
+			return i, fmt.Errorf("command failed: %s - %w", cmdStr, err)
 		}
+		e.updateRun(runID, i+1, memory.RunRunning)
 
 		if output != "" {
-			fmt.Printf("  Output: %s\n", output)
+			e.showOutput(cmdStr, output)
+		}
+		// Later commands run where this one's cd left its shell
+		if steps == nil {
+			e.followCd(cmdStr)
+		}
+	}
+
+	e.updateRun(runID, len(commands), memory.RunSucceeded)
+	e.learnResolution(commands[start:])
+
+	return len(commands), nil
+}
+
+// runCommand runs a command as plans run them: retried when the "commands"
+// retry policy allows it, and stopped at the command timeout
+func (e *Executor) runCommand(ctx context.Context, cmdStr string, step *Command, dir string, out io.Writer) (string, error) {
+	var output string
+	err := e.retryCommand(ctx, cmdStr, func(ctx context.Context) error {
+		return e.withTimeout(ctx, cmdStr, func(ctx context.Context) error {
+			var err error
+			output, err = e.attempt(ctx, cmdStr, step, dir, out)
+			return err
+		})
+	})
+	return output, err
+}
+
+// attempt runs a command once: a structured step without a shell, anything
+// else with the OS shell. It runs in dir, or the session's directory when
+// dir is empty. With out, the command's output goes there as it runs;
+// without, its stdout is returned and a failure carries its stderr.
+func (e *Executor) attempt(ctx context.Context, cmdStr string, step *Command, dir string, out io.Writer) (string, error) {
+	var cmd *exec.Cmd
+	if step != nil {
+		cmd = e.stepCommand(ctx, *step)
+	} else {
+		var err error
+		if cmd, err = e.shellCommand(ctx, cmdStr); err != nil {
+			return "", err
+		}
+		cmd.Env = e.environ()
+	}
+	if dir != "" {
+		if cmd.Dir == "" {
+			cmd.Dir = dir
+		} else if !filepath.IsAbs(cmd.Dir) {
+			cmd.Dir = filepath.Join(dir, cmd.Dir)
+		}
+	}
+	if out == nil {
+		return runProcess(cmd, cmdStr)
+	}
+	cmd.Stdout, cmd.Stderr = out, out
+	killGroupOnCancel(cmd)
+	return "", cmd.Run()
+}
+
+// retryCommand runs a command, retrying transient failures such as network
+// timeouts when the "commands" retry policy allows it and the command is
+// safe to re-run
+func (e *Executor) retryCommand(ctx context.Context, cmdStr string, run func(ctx context.Context) error) error {
+	p := e.config.RetryPolicy(config.RetryCommands)
+	if p.Attempts <= 1 || policy.NonIdempotentReason(cmdStr) != "" {
+		return run(ctx)
+	}
+	return retry.Do(ctx, p, commandErrorClass,
+		func(attempt int, err error, wait time.Duration) {
+			kind := "an error matching a retry pattern"
+			if class := commandErrorClass(err); class != "" {
+				kind = "a " + strings.ReplaceAll(class, "_", " ") + " error"
+			}
+			fmt.Printf("  🔁 Failed with %s; retrying in %s (attempt %d of %d)\n",
+				kind, wait.Round(100*time.Millisecond), attempt+1, p.Attempts)
+			e.logger.Warn("Retrying %s after attempt %d: %v", cmdStr, attempt, err)
+		}, run)
+}
+
+// commandErrorClass classifies a failed command by what it printed; one
+// stopped at its timeout timed out
+func commandErrorClass(err error) string {
+	if errors.Is(err, ErrCommandTimeout) {
+		return retry.ClassTimeout
+	}
+	var failed *ErrCommandFailed
+	if errors.As(err, &failed) {
+		return retry.ClassifyText(failed.Stderr)
+	}
+	return retry.Classify(err)
+}
+
+// lockProject locks the working directory's project while commands change
+// it, so other sessions and the daemon cannot run plans there at the same time
+func (e *Executor) lockProject(commands []string) (release func(), err error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return func() {}, nil
+	}
+	return e.lockProjectAt(cwd, commands)
+}
+
+// lockProjectAt locks the project dir is in while commands change it
+func (e *Executor) lockProjectAt(dir string, commands []string) (release func(), err error) {
+	changing := false
+	for _, cmd := range commands {
+		if !policy.IsReadOnly(cmd) {
+			changing = true
+			break
+		}
+	}
+	if !changing || e.config.ConfigPath == "" {
+		return func() {}, nil
+	}
+
+	task := commands[0]
+	if len(commands) > 1 {
+		task += fmt.Sprintf(" (+%d more)", len(commands)-1)
+	}
+	return projectlock.Acquire(projectlock.Dir(e.config), project.Root(dir), task)
+}
+
+// startRun opens an execution journal entry, returning 0 if journaling is unavailable
+func (e *Executor) startRun(commands []string, steps []Command) int64 {
+	if e.memory == nil {
+		return 0
+	}
+
+	var stepData []byte
+	if steps != nil {
+		stepData, _ = json.Marshal(steps)
+	}
+
+	id, err := e.memory.StartRun(commands, stepData)
+	if err != nil {
+		e.logger.Warn("Failed to journal run: %v", err)
+		return 0
+	}
+	return id
+}
+
+// updateRun records run progress in the execution journal
+func (e *Executor) updateRun(id int64, completed int, status string) {
+	if id == 0 {
+		return
+	}
+	if err := e.memory.UpdateRun(id, completed, status); err != nil {
+		e.logger.Warn("Failed to journal run progress: %v", err)
+	}
+}
+
+// recordExecution writes an executed command to the audit log
+func (e *Executor) recordExecution(cmd string, err error) {
+	fields := map[string]string{"command": cmd, "status": "succeeded"}
+	if err != nil {
+		fields["status"] = "failed"
+		fields["error"] = err.Error()
+	}
+	if e.isElevated() {
+		fields["elevated_reason"] = e.elevation.reason
+	}
+	// Changes made during a freeze stand out in the audit log
+	if !policy.IsReadOnly(cmd) {
+		if window := e.ActiveFreeze(context.Background()); window != nil {
+			fields["freeze"] = window.Name
 		}
 	}
+	e.audit.Record("command_executed", fields)
+}
 
+// Platform returns the detected platform information
+func (e *Executor) Platform() *platform.Info {
+	return e.platform
+}
+
+// KnownFix returns commands that previously resolved an error like err
+func (e *Executor) KnownFix(err error) []string {
+	if fix := e.lookupFix(failureText(err)); fix != nil {
+		return fix.Commands
+	}
 	return nil
 }
 
-// callAIEngine calls the Python AI engine for command interpretation
-func (e *Executor) callAIEngine(input string) (*ExecutionResult, error) {
-	// Prepare request payload
+// pendingFix returns the known fix for the last failure, if there is one
+func (e *Executor) pendingFix() *memory.Resolution {
+	if e.pendingFailure == nil {
+		return nil
+	}
+	return e.lookupSignature(e.pendingFailure.signature)
+}
+
+// lookupFix consults the failure knowledge base for an error message
+func (e *Executor) lookupFix(message string) *memory.Resolution {
+	return e.lookupSignature(memory.ErrorSignature(message))
+}
+
+// lookupSignature consults the failure knowledge base for an error signature
+func (e *Executor) lookupSignature(signature string) *memory.Resolution {
+	if e.memory == nil || signature == "" {
+		return nil
+	}
+
+	fix, err := e.memory.LookupResolution(signature)
+	if err != nil {
+		e.logger.Warn("Failed to consult failure knowledge base: %v", err)
+		return nil
+	}
+	if fix != nil {
+		e.logger.Info("Found known fix for failure signature %s", signature)
+	}
+	return fix
+}
+
+// rememberFailure marks a failed command so the next successful plan that
+// retries it is stored as the fix for its error
+func (e *Executor) rememberFailure(command, message string) {
+	if signature := memory.ErrorSignature(message); signature != "" {
+		e.pendingFailure = &failure{command: command, signature: signature, sample: message}
+	}
+}
+
+// learnResolution stores successfully executed commands as the fix for the
+// pending failure. A plan that does not retry the failed command resolved
+// nothing that is known, so it is not learned.
+func (e *Executor) learnResolution(commands []string) {
+	if e.pendingFailure == nil || e.memory == nil || !retries(commands, e.pendingFailure.command) {
+		return
+	}
+
+	if err := e.memory.SaveResolution(e.pendingFailure.signature, e.pendingFailure.sample, commands); err != nil {
+		e.logger.Warn("Failed to save resolution: %v", err)
+	} else {
+		e.logger.Info("Learned resolution for failure signature %s", e.pendingFailure.signature)
+	}
+	e.pendingFailure = nil
+}
+
+// retries reports whether commands include command, ignoring differences
+// in spacing
+func retries(commands []string, command string) bool {
+	want := strings.Join(strings.Fields(command), " ")
+	for _, cmd := range commands {
+		if strings.Join(strings.Fields(cmd), " ") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// callAIEngine asks the AI provider to interpret a request: from Go for the
+// builtin model and the providers registered with ai, and through the
+// Python AI engine otherwise. When the provider fails or times out, the
+// providers in the failover chain are tried in turn.
+func (e *Executor) callAIEngine(ctx context.Context, input string, extra map[string]interface{}) (*ExecutionResult, error) {
+	// Local-only context stays on this machine; otherwise budgets may refuse
+	// the call or send it to a cheaper model
+	private := e.localTag(extra) != ""
+	first := e.localRoute(extra)
+	if first == nil {
+		var err error
+		if first, err = e.budgetRoute(e.config.AIProvider, e.config.Model); err != nil {
+			return nil, err
+		}
+	}
+
+	bundle, err := e.guardrails(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare request payload; the provider's fields are added per route
 	request := map[string]interface{}{
 		"input":       input,
 		"os":          e.config.OS,
-		"provider":    e.config.AIProvider,
-		"model":       e.config.Model,
-		"api_key":     e.config.APIKey,
-		"base_url":    e.config.BaseURL,
+		"shell":       e.shell(),
 		"max_tokens":  e.config.MaxTokens,
 		"temperature": e.config.Temperature,
+		"num_ctx":     e.config.ContextLength,
+		"platform":    e.platform,
+		"runbooks":    e.runbookCatalog(),
+	}
+	if env := e.config.ActiveEnvironment(); env != nil {
+		request["environment"] = map[string]interface{}{
+			"name":         env.Name,
+			"protected":    env.Protected,
+			"kube_context": env.KubeContext,
+			"targets":      env.Targets,
+		}
+	}
+	if apis := e.apiContext(input); len(apis) > 0 {
+		request["apis"] = apis
+	}
+	if bundle != nil && bundle.Preamble != "" {
+		request["guardrails"] = bundle.Preamble
+	}
+	if e.config.AllowlistOnly {
+		// Plans are refused unless every command they run is allowed
+		request["allowed_commands"] = e.config.AllowedCommands
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		layout := project.Detect(cwd)
+		request["project"] = layout
+		request["helm_charts"] = helm.FindCharts(layout.Root)
+	}
+	for key, value := range extra {
+		request[key] = value
+	}
+
+	timeout := defaultAITimeout
+	if e.config.AITimeout > 0 {
+		timeout = time.Duration(e.config.AITimeout) * time.Second
+	}
+
+	routes := append([]*route{first}, e.failoverRoutes(first, private)...)
+	for i, r := range routes {
+		if i > 0 {
+			e.logger.Warn("%s failed, failing over to %s: %v", routes[i-1].provider, r.provider, err)
+			e.audit.Record("provider_failover", map[string]string{"provider": routes[i-1].provider, "next": r.provider, "error": err.Error()})
+		}
+		var result *ExecutionResult
+		if result, err = e.requestRoute(ctx, r, request, timeout); err != nil {
+			if ctx.Err() != nil || !failsOver(err) {
+				return nil, err
+			}
+			continue
+		}
+		if len(routes) > 1 {
+			e.logger.Info("Plan generated by %s (%s)", r.provider, r.model)
+		}
+		if i > 0 {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s did not answer; this plan was made by %s (%s)", first.provider, r.model, r.provider))
+		}
+		r.apply(result)
+		return result, nil
+	}
+	return nil, err
+}
+
+// requestRoute asks the route's provider for a plan, retrying transient
+// failures with each attempt given the full timeout
+func (e *Executor) requestRoute(ctx context.Context, r *route, base map[string]interface{}, timeout time.Duration) (*ExecutionResult, error) {
+	if !e.config.AllowsProvider(r.provider) {
+		e.audit.Record("provider_denied", map[string]string{"provider": r.provider})
+		return nil, fmt.Errorf("%w: %s is not an allowed provider on this managed machine", ErrPolicyDenied, r.provider)
+	}
+	apiKey, baseURL := e.credentials(r.provider)
+	input, _ := base["input"].(string)
+	cloud := !budget.Local(r.provider)
+
+	request := make(map[string]interface{}, len(base)+6)
+	for key, value := range base {
+		request[key] = value
 	}
+	request["provider"] = r.provider
+	request["model"] = r.model
+	request["api_key"] = apiKey
+	request["base_url"] = baseURL
+	request["corrections"] = e.recentCorrections(cloud)
+	request["examples"] = e.ratedExamples(input, cloud)
 
 	requestData, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	var result *ExecutionResult
+	err = retry.Do(ctx, e.config.RetryPolicy(r.provider), providerErrorClass,
+		func(attempt int, err error, wait time.Duration) {
+			e.logger.Warn("%s request failed (attempt %d), retrying in %s: %v", r.provider, attempt, wait.Round(100*time.Millisecond), err)
+		},
+		func(ctx context.Context) error {
+			var err error
+			result, err = e.requestPlan(ctx, r, request, requestData, timeout)
+			return err
+		})
+	return result, err
+}
+
+// requestPlan makes one request to the AI provider, giving up after timeout
+func (e *Executor) requestPlan(ctx context.Context, route *route, request map[string]interface{}, requestData []byte, timeout time.Duration) (*ExecutionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Providers called from Go need no Python engine
+	if provider, ok := e.provider(route.provider); ok {
+		return e.complete(ctx, provider, request)
+	}
+
 	// Call Python AI engine
-	cmd := exec.Command("python3", "-m", "ai_engine.core.processor", string(requestData))
-	
+	cmd := exec.CommandContext(ctx, "python3", "-m", "ai_engine.core.processor", string(requestData))
+
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("AI engine execution failed: %w - stderr: %s", err, stderr.String())
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%w after %s", ErrProviderTimeout, timeout)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if route.provider == "ollama" && ollama.IsModelNotFound(stdout.String()+stderr.String()) {
+			return nil, fmt.Errorf("%w: %s is not pulled in Ollama", ErrModelNotFound, route.model)
+		}
+		// The engine reports provider errors only as text
+		err = fmt.Errorf("%w: AI engine execution failed: %w - stderr: %s", ErrProviderFailed, err, stderr.String())
+		return nil, retry.Mark(err, retry.ClassifyText(stderr.String()))
 	}
 
 	// Parse response
 	var result ExecutionResult
 	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %w - output: %s", err, stdout.String())
+		return nil, fmt.Errorf("%w: failed to parse AI response: %w - output: %s", ErrProviderFailed, err, stdout.String())
 	}
-
 	return &result, nil
 }
 
+// providerErrorClass classifies a failed AI request for its retry policy
+func providerErrorClass(err error) string {
+	if errors.Is(err, ErrProviderTimeout) {
+		return retry.ClassTimeout
+	}
+	return retry.Classify(err)
+}
+
+// recentCorrections returns past user corrections to use as few-shot
+// examples, leaving out those made in local-only projects when the request
+// goes to a cloud provider
+func (e *Executor) recentCorrections(cloud bool) []memory.Correction {
+	if e.memory == nil {
+		return nil
+	}
+
+	corrections, err := e.memory.RecentCorrections(maxFewShotCorrections)
+	if err != nil {
+		e.logger.Warn("Failed to load corrections: %v", err)
+		return nil
+	}
+	if !cloud || len(e.config.LocalOnly) == 0 {
+		return corrections
+	}
+	shared := corrections[:0]
+	for _, c := range corrections {
+		if privacy.Match(e.config.LocalOnly, c.Project) == "" {
+			shared = append(shared, c)
+		}
+	}
+	return shared
+}
+
 // validateCommands checks if commands are safe to execute
 func (e *Executor) validateCommands(commands []string) error {
+	// The organization's blocked commands apply even outside the sandbox
+	bundle, err := e.guardrails(context.Background())
+	if err != nil {
+		return err
+	}
+	if bundle != nil {
+		for _, cmd := range commands {
+			if blocked := bundle.Blocked(cmd); blocked != "" {
+				e.recordTrigger(GuardrailsRule, policy.ActionDeny, blocked, cmd)
+				return fmt.Errorf("%w: blocked by organization guardrails: %s", ErrValidationBlocked, blocked)
+			}
+		}
+	}
+
+	// So does allowlist_only, which an elevated session cannot relax
+	if err := e.checkAllowlist(commands); err != nil {
+		return err
+	}
+
+	// And the blocked commands of the managed configuration
+	for _, cmd := range commands {
+		for _, blocked := range e.config.ManagedBlockedCommands() {
+			if strings.Contains(strings.ToLower(cmd), strings.ToLower(blocked)) {
+				e.recordTrigger(SandboxRule, policy.ActionDeny, blocked, cmd)
+				return fmt.Errorf("%w: blocked command detected: %s", ErrValidationBlocked, blocked)
+			}
+		}
+	}
+
 	if !e.config.SandboxMode {
 		return nil
 	}
 
+	if e.relaxed(SandboxRule) {
+		e.audit.Record("sandbox_bypassed", map[string]string{"commands": strings.Join(commands, "\n")})
+		return nil
+	}
+
 	for _, cmd := range commands {
+		// Project exceptions created from policy stats
+		if rule := policy.SandboxExemption(e.activeRules(), cmd); rule != "" {
+			e.audit.Record("sandbox_exempted", map[string]string{"command": cmd, "rule": rule})
+			continue
+		}
+
 		// Check against blocked commands
 		for _, blocked := range e.config.BlockedCommands {
 			if strings.Contains(strings.ToLower(cmd), strings.ToLower(blocked)) {
-				return fmt.Errorf("blocked command detected: %s", blocked)
+				e.recordTrigger(SandboxRule, policy.ActionDeny, blocked, cmd)
+				return fmt.Errorf("%w: blocked command detected: %s", ErrValidationBlocked, blocked)
 			}
 		}
 
 		// Check for dangerous patterns
-		if e.isDangerous(cmd) {
-			return fmt.Errorf("potentially dangerous command detected: %s", cmd)
+		if pattern := dangerousPattern(cmd); pattern != "" {
+			e.recordTrigger(SandboxRule, policy.ActionDeny, pattern, cmd)
+			return fmt.Errorf("%w: potentially dangerous command detected: %s", ErrValidationBlocked, cmd)
+		}
+
+		// Never overwrite existing SSH keys
+		if key := sshsetup.OverwritesKey(cmd); key != "" {
+			e.recordTrigger(SandboxRule, policy.ActionDeny, "ssh key overwrite", cmd)
+			return fmt.Errorf("%w: would overwrite existing SSH key %s", ErrValidationBlocked, key)
+		}
+	}
+
+	return nil
+}
+
+// applyPolicy evaluates the approval rules for each command and decides
+// whether the plan runs automatically, needs confirmation, or is refused
+func (e *Executor) applyPolicy(result *ExecutionResult) error {
+	// Without a matching rule, fall back to the AI engine's assessment
+	defaultAction := policy.ActionAllow
+	if e.config.ConfirmationMode && result.NeedsConfirmation {
+		defaultAction = policy.ActionAsk
+	}
+
+	decisions := make([]policy.Decision, 0, len(result.Commands))
+	for _, cmd := range result.Commands {
+		d := policy.Evaluate(e.activeRules(), cmd, defaultAction)
+		if e.config.ObserveMode && d.Action != policy.ActionDeny {
+			d = observe(d, cmd)
+		}
+		if d.Rule != "" && d.Rule != "observe_mode" && d.Action != policy.ActionAllow {
+			e.recordTrigger(d.Rule, d.Action, "", cmd)
+		}
+		if d.Action == policy.ActionDeny {
+			e.logger.Warn("Command denied by approval rule %q: %s", d.Rule, cmd)
+			e.audit.Record("command_denied", map[string]string{"command": cmd, "rule": d.Rule})
+			return fmt.Errorf("%w: rule %q: %s", ErrPolicyDenied, d.Rule, cmd)
+		}
+		if d.Rule != "" {
+			result.PolicyNotes = append(result.PolicyNotes, fmt.Sprintf("%s → %s (rule %q)", cmd, d.Action, d.Rule))
 		}
+		decisions = append(decisions, d)
 	}
 
+	result.NeedsConfirmation = policy.Strictest(decisions) == policy.ActionAsk
 	return nil
 }
 
-// isDangerous checks if a command contains dangerous patterns
-func (e *Executor) isDangerous(cmd string) bool {
+// recordTrigger notes that a rule blocked or held a command, so policy stats
+// can show which rules get in the way; triggers never leave this machine
+func (e *Executor) recordTrigger(rule string, action policy.Action, detail, cmd string) {
+	if e.memory == nil {
+		return
+	}
+
+	trigger := memory.PolicyTrigger{Rule: rule, Action: string(action), Detail: detail, Command: cmd}
+	if cwd, err := os.Getwd(); err == nil {
+		trigger.Project = project.Root(cwd)
+	}
+	if err := e.memory.RecordPolicyTrigger(trigger); err != nil {
+		e.logger.Warn("Failed to record policy trigger: %v", err)
+	}
+}
+
+// observe adjusts a decision for observation mode: read-only commands the
+// rules leave to the default run automatically, and anything that may
+// mutate state needs explicit approval regardless of the rules
+func observe(d policy.Decision, cmd string) policy.Decision {
+	switch {
+	case policy.IsReadOnly(cmd):
+		if d.Rule == "" {
+			d.Action = policy.ActionAllow
+		}
+	case d.Action == policy.ActionAllow:
+		d.Action = policy.ActionAsk
+		d.Rule = "observe_mode"
+	}
+	return d
+}
+
+// dangerousPattern returns the dangerous pattern a command contains, or ""
+func dangerousPattern(cmd string) string {
 	dangerousPatterns := []string{
 		"rm -rf",
 		"rm -fr",
@@ -152,39 +1116,72 @@ func (e *Executor) isDangerous(cmd string) bool {
 	cmdLower := strings.ToLower(cmd)
 	for _, pattern := range dangerousPatterns {
 		if strings.Contains(cmdLower, pattern) {
-			return true
+			return pattern
 		}
 	}
 
-	return false
+	return ""
 }
 
 // executeShellCommand executes a shell command based on the OS
-func (e *Executor) executeShellCommand(cmdStr string) (string, error) {
-	var cmd *exec.Cmd
+func (e *Executor) executeShellCommand(ctx context.Context, cmdStr string) (string, error) {
+	cmd, err := e.shellCommand(ctx, cmdStr)
+	if err != nil {
+		return "", err
+	}
+	cmd.Env = e.environ()
+
+	return runProcess(cmd, cmdStr)
+}
 
+// shellCommand prepares cmdStr to run with the OS shell
+func (e *Executor) shellCommand(ctx context.Context, cmdStr string) (*exec.Cmd, error) {
 	switch e.config.OS {
 	case "windows":
-		cmd = exec.Command("powershell", "-Command", cmdStr)
+		return exec.CommandContext(ctx, "powershell", "-Command", cmdStr), nil
 	case "darwin", "linux":
-		cmd = exec.Command("sh", "-c", cmdStr)
+		return exec.CommandContext(ctx, e.shell(), "-c", cmdStr), nil
 	default:
-		return "", fmt.Errorf("unsupported OS: %s", e.config.OS)
+		return nil, fmt.Errorf("unsupported OS: %s", e.config.OS)
+	}
+}
+
+// shell returns the shell commands are run with, so the AI engine can emit
+// matching syntax (fish and nu reject bashisms)
+func (e *Executor) shell() string {
+	if e.config.OS == "windows" {
+		return "powershell"
+	}
+	if e.config.Shell == "" {
+		return "sh"
 	}
+	return e.config.Shell
+}
 
+// runProcess runs a prepared command and returns its trimmed stdout, or an
+// *ErrCommandFailed carrying the exit code and stderr. When the command's
+// context ends, its whole process group is killed.
+func runProcess(cmd *exec.Cmd, cmdStr string) (string, error) {
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	killGroupOnCancel(cmd)
 
 	err := cmd.Run()
 	output := strings.TrimSpace(stdout.String())
 
 	if err != nil {
-		errOutput := strings.TrimSpace(stderr.String())
-		if errOutput != "" {
-			return "", fmt.Errorf("%s: %s", err, errOutput)
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		return "", &ErrCommandFailed{
+			Command:  cmdStr,
+			ExitCode: exitCode,
+			Stderr:   strings.TrimSpace(stderr.String()),
+			Err:      err,
 		}
-		return "", err
 	}
 
 	return output, nil
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"devos/internal/budget"
+	"devos/internal/models"
+)
+
+// apiKeyEnv names the environment variable holding a cloud provider's API
+// key, for providers other than the configured one
+var apiKeyEnv = map[string]string{
+	"openai":            "OPENAI_API_KEY",
+	"openai-compatible": "OPENAI_API_KEY",
+	"azure-openai":      "AZURE_OPENAI_API_KEY",
+	"anthropic":         "ANTHROPIC_API_KEY",
+	"gemini":            "GOOGLE_API_KEY",
+}
+
+// failoverRoutes returns the routes to try, in order, when first fails: the
+// configured failover chain, leaving out first's provider. A request kept
+// local for privacy, or made in a local-only project, only fails over to
+// other local providers, and cloud providers are subject to their budgets.
+func (e *Executor) failoverRoutes(first *route, private bool) []*route {
+	private = private || e.LocalOnly() != ""
+	var routes []*route
+	for _, spec := range e.config.Failover {
+		provider, model, _ := strings.Cut(spec, ":")
+		if provider == first.provider || (private && !budget.Local(provider)) {
+			continue
+		}
+		if model == "" && provider == e.config.AIProvider {
+			model = e.config.Model
+		}
+		if provider == "ollama" && (model == "" || model == models.Auto) {
+			hw := e.platform.Hardware
+			model = models.Recommend(hw.RAM, hw.VRAM()).Ollama
+		}
+		r, err := e.budgetRoute(provider, model)
+		if err != nil {
+			e.logger.Warn("Leaving %s out of the failover chain: %v", provider, err)
+			continue
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// credentials returns the API key and base URL to call a provider with:
+// the configured ones for the configured provider, and otherwise the key
+// from the provider's usual environment variable. Azure OpenAI is always
+// called at the configured resource endpoint, and other OpenAI-compatible
+// servers at OPENAI_BASE_URL, as OpenAI's own clients do.
+func (e *Executor) credentials(provider string) (string, string) {
+	apiKey, baseURL := os.Getenv(apiKeyEnv[provider]), ""
+	if provider == "openai-compatible" {
+		baseURL = os.Getenv("OPENAI_BASE_URL")
+	}
+	if provider == e.config.AIProvider {
+		apiKey, baseURL = e.config.APIKey, e.config.BaseURL
+	}
+	if provider == "azure-openai" {
+		baseURL = e.config.AzureEndpoint
+	}
+	return apiKey, baseURL
+}
+
+// failsOver reports whether a failed request goes on to the next provider
+// in the failover chain: failures of the provider itself do, while refusals
+// such as a used-up budget do not
+func failsOver(err error) bool {
+	return errors.Is(err, ErrProviderFailed) || errors.Is(err, ErrProviderTimeout)
+}
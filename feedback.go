@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"time"
+
+	"devos/internal/timefmt"
+)
+
+// Ratings given with the "good" and "bad" REPL commands
+const (
+	RatingGood = "good"
+	RatingBad  = "bad"
+)
+
+// Feedback is the user's rating of a plan the AI engine proposed, kept with
+// the request and the plan so similar requests can learn from it
+type Feedback struct {
+	Input     string    `json:"input"`
+	Output    string    `json:"output"`
+	Commands  []string  `json:"commands"`
+	Rating    string    `json:"rating"`
+	Reason    string    `json:"reason,omitempty"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Project   string    `json:"project,omitempty"` // Root of the project the plan was made in
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddFeedback stores a rating
+func (s *Store) AddFeedback(f Feedback) error {
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = timefmt.Now()
+	}
+
+	_, err := s.exec(
+		`INSERT INTO feedback (input, output, commands, rating, reason, provider, model, project, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.Input, f.Output, strings.Join(f.Commands, "\n"), f.Rating, f.Reason,
+		f.Provider, f.Model, f.Project, f.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save feedback: %w", err)
+	}
+
+	return s.prune("feedback")
+}
+
+// RecentFeedback returns the most recent ratings, newest first
+func (s *Store) RecentFeedback(limit int) ([]Feedback, error) {
+	rows, err := s.query(
+		`SELECT input, output, commands, rating, reason, provider, model, project, created_at
+		FROM feedback ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var feedback []Feedback
+	for rows.Next() {
+		var f Feedback
+		var commands string
+		if err := rows.Scan(&f.Input, &f.Output, &commands, &f.Rating, &f.Reason,
+			&f.Provider, &f.Model, &f.Project, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read feedback: %w", err)
+		}
+		if commands != "" {
+			f.Commands = strings.Split(commands, "\n")
+		}
+		feedback = append(feedback, f)
+	}
+
+	return feedback, rows.Err()
+}
+
+// Patterns replaced when feedback is anonymized, most specific first
+var anonymizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`), "://<credentials>@"},
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`), "<email>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`), "<ip>"},
+	{regexp.MustCompile(`\b[A-Za-z0-9_\-+/=]{32,}\b`), "<secret>"},
+}
+
+// Anonymized returns the feedback without the project, the date's time of
+// day, or personal details in its text: the home directory and user name,
+// emails, IP addresses, URL credentials, and token-like strings
+func (f Feedback) Anonymized() Feedback {
+	home, _ := os.UserHomeDir()
+	var name *regexp.Regexp
+	if u, err := user.Current(); err == nil && len(u.Username) > 2 {
+		name = regexp.MustCompile(`\b` + regexp.QuoteMeta(u.Username) + `\b`)
+	}
+	clean := func(s string) string {
+		if home != "" && home != "/" {
+			s = strings.ReplaceAll(s, home, "~")
+		}
+		if name != nil {
+			s = name.ReplaceAllString(s, "<user>")
+		}
+		for _, a := range anonymizers {
+			s = a.pattern.ReplaceAllString(s, a.replacement)
+		}
+		return s
+	}
+
+	anonymized := Feedback{
+		Input:     clean(f.Input),
+		Output:    clean(f.Output),
+		Rating:    f.Rating,
+		Reason:    clean(f.Reason),
+		Provider:  f.Provider,
+		Model:     f.Model,
+		CreatedAt: f.CreatedAt.UTC().Truncate(24 * time.Hour),
+	}
+	for _, cmd := range f.Commands {
+		anonymized.Commands = append(anonymized.Commands, clean(cmd))
+	}
+	return anonymized
+}
@@ -0,0 +1,242 @@
+package freeze
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedTTL is how long a fetched iCal feed is reused
+const feedTTL = 15 * time.Minute
+
+// feedTimeout bounds fetching an iCal feed
+const feedTimeout = 10 * time.Second
+
+// maxRun bounds how far a schedule without a duration is followed to find
+// where its window starts and ends
+const maxRun = 7 * 24 * time.Hour
+
+// Definition is a configured freeze: a cron schedule, or an iCal feed whose
+// events are freezes
+type Definition struct {
+	Name     string
+	Schedule string        // Cron expression; see parseSchedule
+	Duration time.Duration // How long a freeze lasts from each match; 0 covers only matching minutes
+	ICal     string        // Feed URL (http, https, or webcal) or file path
+	Reason   string
+}
+
+// Window is one occurrence of a freeze
+type Window struct {
+	Name   string
+	Reason string
+	Start  time.Time
+	End    time.Time
+}
+
+// Checker finds the freezes in effect at a given time
+type Checker struct {
+	freezes []compiled
+	loc     *time.Location
+
+	mu    sync.Mutex
+	feeds map[string]feed
+}
+
+// compiled is a definition with its schedule parsed
+type compiled struct {
+	Definition
+	schedule *schedule
+}
+
+// feed is a fetched iCal feed
+type feed struct {
+	events  []event
+	fetched time.Time
+}
+
+// New parses the freeze definitions. Schedules are evaluated, and floating
+// iCal times read, in loc.
+func New(defs []Definition, loc *time.Location) (*Checker, error) {
+	c := &Checker{loc: loc, feeds: map[string]feed{}}
+	for _, d := range defs {
+		f := compiled{Definition: d}
+		if d.Schedule != "" {
+			s, err := parseSchedule(d.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("freeze %s: %w", d.Name, err)
+			}
+			f.schedule = s
+		}
+		c.freezes = append(c.freezes, f)
+	}
+	return c, nil
+}
+
+// Empty reports whether no freezes are configured
+func (c *Checker) Empty() bool {
+	return len(c.freezes) == 0
+}
+
+// Active returns the freeze in effect at t, or nil. Feeds that cannot be
+// read are reported in the error while the other freezes are still checked.
+func (c *Checker) Active(ctx context.Context, t time.Time) (*Window, error) {
+	var errs []string
+	for _, f := range c.freezes {
+		if f.schedule != nil {
+			if w := c.scheduleWindow(f, t); w != nil {
+				return w, nil
+			}
+			continue
+		}
+		events, err := c.events(ctx, f.ICal)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, e := range events {
+			if !t.Before(e.start) && t.Before(e.end) {
+				return f.window(e), nil
+			}
+		}
+	}
+	return nil, joinErrors(errs)
+}
+
+// Upcoming returns the next occurrence of each freeze that starts after t
+// and within the given period, soonest first
+func (c *Checker) Upcoming(ctx context.Context, t time.Time, within time.Duration) ([]Window, error) {
+	var windows []Window
+	var errs []string
+	for _, f := range c.freezes {
+		if f.schedule != nil {
+			for m := t.In(c.loc).Truncate(time.Minute).Add(time.Minute); m.Sub(t) <= within; m = m.Add(time.Minute) {
+				if f.schedule.matches(m) {
+					if w := c.scheduleWindow(f, m); w != nil {
+						windows = append(windows, *w)
+					}
+					break
+				}
+			}
+			continue
+		}
+		events, err := c.events(ctx, f.ICal)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		var next *event
+		for i, e := range events {
+			if e.start.After(t) && e.start.Sub(t) <= within && (next == nil || e.start.Before(next.start)) {
+				next = &events[i]
+			}
+		}
+		if next != nil {
+			windows = append(windows, *f.window(*next))
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, joinErrors(errs)
+}
+
+// scheduleWindow returns the window of a scheduled freeze containing t, or nil
+func (c *Checker) scheduleWindow(f compiled, t time.Time) *Window {
+	t = t.In(c.loc).Truncate(time.Minute)
+	if f.Duration > 0 {
+		// The most recent start within the duration decides when it ends
+		for m := t; t.Sub(m) < f.Duration; m = m.Add(-time.Minute) {
+			if f.schedule.matches(m) {
+				return &Window{Name: f.Name, Reason: f.Reason, Start: m, End: m.Add(f.Duration)}
+			}
+		}
+		return nil
+	}
+
+	if !f.schedule.matches(t) {
+		return nil
+	}
+	start, end := t, t.Add(time.Minute)
+	for start.Sub(t) > -maxRun && f.schedule.matches(start.Add(-time.Minute)) {
+		start = start.Add(-time.Minute)
+	}
+	for end.Sub(t) < maxRun && f.schedule.matches(end) {
+		end = end.Add(time.Minute)
+	}
+	return &Window{Name: f.Name, Reason: f.Reason, Start: start, End: end}
+}
+
+// window turns a feed event into a window, named after the event
+func (f compiled) window(e event) *Window {
+	name := f.Name
+	if e.summary != "" {
+		name += ": " + e.summary
+	}
+	return &Window{Name: name, Reason: f.Reason, Start: e.start, End: e.end}
+}
+
+// events returns a feed's events, fetching it at most every feedTTL. A feed
+// that fails to refresh keeps its last events.
+func (c *Checker) events(ctx context.Context, source string) ([]event, error) {
+	c.mu.Lock()
+	cached, ok := c.feeds[source]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetched) < feedTTL {
+		return cached.events, nil
+	}
+
+	data, err := fetch(ctx, source)
+	if err == nil {
+		var events []event
+		if events, err = parseICal(bytes.NewReader(data), c.loc); err == nil {
+			c.mu.Lock()
+			c.feeds[source] = feed{events: events, fetched: time.Now()}
+			c.mu.Unlock()
+			return events, nil
+		}
+	}
+	if ok {
+		return cached.events, nil
+	}
+	return nil, fmt.Errorf("freeze calendar %s: %w", source, err)
+}
+
+// fetch reads a feed from a URL or file
+func fetch(ctx context.Context, source string) ([]byte, error) {
+	if strings.HasPrefix(source, "webcal://") {
+		source = "https://" + strings.TrimPrefix(source, "webcal://")
+	}
+	if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+		return os.ReadFile(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, feedTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// joinErrors combines feed errors into one, or returns nil
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/freeze"
+	"devos/internal/policy"
+	"devos/internal/timefmt"
+)
+
+// newFreezeChecker compiles the configured change freezes
+func newFreezeChecker(freezes []config.Freeze) (*freeze.Checker, error) {
+	defs := make([]freeze.Definition, len(freezes))
+	for i, f := range freezes {
+		defs[i] = freeze.Definition{Name: f.Name, Schedule: f.Schedule, ICal: f.ICal, Reason: f.Reason}
+		if f.Duration != "" {
+			defs[i].Duration, _ = time.ParseDuration(f.Duration) // Checked by Validate
+		}
+	}
+	checker, err := freeze.New(defs, timefmt.Location())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", config.ErrInvalidConfig, err)
+	}
+	return checker, nil
+}
+
+// ActiveFreeze returns the change freeze in effect now, or nil. Calendars
+// that cannot be read are logged and skipped.
+func (e *Executor) ActiveFreeze(ctx context.Context) *freeze.Window {
+	if e.freezes.Empty() {
+		return nil
+	}
+	window, err := e.freezes.Active(ctx, time.Now())
+	if err != nil {
+		e.logger.Warn("Failed to check change freezes: %v", err)
+	}
+	return window
+}
+
+// UpcomingFreezes returns the freezes starting within the given period
+func (e *Executor) UpcomingFreezes(ctx context.Context, within time.Duration) ([]freeze.Window, error) {
+	return e.freezes.Upcoming(ctx, time.Now(), within)
+}
+
+// FrozenCommands returns the commands a freeze holds back: all but the
+// read-only ones
+func FrozenCommands(commands []string) []string {
+	var frozen []string
+	for _, cmd := range commands {
+		if !policy.IsReadOnly(cmd) {
+			frozen = append(frozen, cmd)
+		}
+	}
+	return frozen
+}
+
+// flagFreeze warns about a plan that would change things during a freeze and
+// makes it wait for confirmation
+func (e *Executor) flagFreeze(ctx context.Context, result *ExecutionResult) {
+	if len(FrozenCommands(result.Commands)) == 0 {
+		return
+	}
+	window := e.ActiveFreeze(ctx)
+	if window == nil {
+		return
+	}
+	warning := fmt.Sprintf("Change freeze %q is in effect until %s; running this plan needs an override", window.Name, timefmt.DateTime(window.End))
+	if window.Reason != "" {
+		warning += " (" + window.Reason + ")"
+	}
+	result.Warnings = append(result.Warnings, warning)
+	result.NeedsConfirmation = true
+}
@@ -0,0 +1,286 @@
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultURL is the Gemini API
+const DefaultURL = "https://generativelanguage.googleapis.com"
+
+// DefaultModel is used when no model is configured
+const DefaultModel = "gemini-2.5-flash"
+
+// Thresholds are the blocking levels a safety setting can use
+var Thresholds = []string{"BLOCK_NONE", "BLOCK_ONLY_HIGH", "BLOCK_MEDIUM_AND_ABOVE", "BLOCK_LOW_AND_ABOVE", "HARM_BLOCK_THRESHOLD_UNSPECIFIED", "OFF"}
+
+// Request asks for generated content
+type Request struct {
+	Model       string
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+	JSON        bool              // Ask for a JSON reply
+	Safety      map[string]string // Harm category to blocking threshold, passed through as given
+}
+
+// Response is the generated content
+type Response struct {
+	Text         string
+	FinishReason string // STOP, MAX_TOKENS, SAFETY, ...
+	BlockReason  string // Why the prompt itself was blocked, if it was
+	InputTokens  int
+	OutputTokens int
+}
+
+// Error is an error the API returned
+type Error struct {
+	Status  int
+	Code    string // e.g. INVALID_ARGUMENT, PERMISSION_DENIED, RESOURCE_EXHAUSTED
+	Message string
+}
+
+// HTTPStatus returns the response's status code, for retry decisions
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Gemini API error %d (%s): %s", e.Status, e.Code, e.Message)
+}
+
+// Client calls the generateContent API
+type Client struct {
+	apiKey  string
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a client using apiKey, for the API at baseURL (DefaultURL if
+// empty)
+func New(apiKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &Client{apiKey: apiKey, baseURL: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+}
+
+// part is text in a content's parts
+type part struct {
+	Text string `json:"text"`
+}
+
+// content is a turn of the conversation
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+// Generate returns the model's reply to a prompt
+func (c *Client) Generate(ctx context.Context, request Request) (*Response, error) {
+	var reply reply
+	if err := c.post(ctx, modelName(request.Model), "generateContent", generatePayload(request), &reply); err != nil {
+		return nil, err
+	}
+	response := &Response{}
+	reply.addTo(response)
+	return response, nil
+}
+
+// GenerateStream returns the model's reply to a prompt, passing its text to
+// onText as it is generated
+func (c *Client) GenerateStream(ctx context.Context, request Request, onText func(text string)) (*Response, error) {
+	resp, err := c.send(ctx, modelName(request.Model), "streamGenerateContent?alt=sse", generatePayload(request))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Server-sent events, each a partial reply on a "data:" line
+	response := &Response{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var chunk reply
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse Gemini event: %w", err)
+		}
+		before := len(response.Text)
+		chunk.addTo(response)
+		if text := response.Text[before:]; text != "" {
+			onText(text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Gemini response: %w", err)
+	}
+	return response, nil
+}
+
+// reply is a generateContent response, or one chunk of a streamed one
+type reply struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// addTo adds the reply's text to response, and takes its finish reason and
+// token counts, which a streamed reply reports in its last chunk
+func (r *reply) addTo(response *Response) {
+	if r.PromptFeedback.BlockReason != "" {
+		response.BlockReason = r.PromptFeedback.BlockReason
+	}
+	if r.UsageMetadata.PromptTokenCount > 0 {
+		response.InputTokens = r.UsageMetadata.PromptTokenCount
+		response.OutputTokens = r.UsageMetadata.CandidatesTokenCount
+	}
+	if len(r.Candidates) == 0 {
+		return
+	}
+	candidate := r.Candidates[0]
+	if candidate.FinishReason != "" {
+		response.FinishReason = candidate.FinishReason
+	}
+	for _, p := range candidate.Content.Parts {
+		response.Text += p.Text
+	}
+}
+
+// generatePayload returns the generateContent payload for a request
+func generatePayload(request Request) map[string]interface{} {
+	config := map[string]interface{}{"temperature": request.Temperature}
+	if request.MaxTokens > 0 {
+		config["maxOutputTokens"] = request.MaxTokens
+	}
+	if request.JSON {
+		config["responseMimeType"] = "application/json"
+	}
+	payload := contents(request)
+	payload["generationConfig"] = config
+	if len(request.Safety) > 0 {
+		categories := make([]string, 0, len(request.Safety))
+		for category := range request.Safety {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		var settings []map[string]string
+		for _, category := range categories {
+			settings = append(settings, map[string]string{"category": category, "threshold": request.Safety[category]})
+		}
+		payload["safetySettings"] = settings
+	}
+	return payload
+}
+
+// CountTokens returns how many input tokens a request would use
+func (c *Client) CountTokens(ctx context.Context, request Request) (int, error) {
+	model := modelName(request.Model)
+	generate := contents(request)
+	generate["model"] = "models/" + model
+	var count struct {
+		TotalTokens int `json:"totalTokens"`
+	}
+	if err := c.post(ctx, model, "countTokens", map[string]interface{}{"generateContentRequest": generate}, &count); err != nil {
+		return 0, err
+	}
+	return count.TotalTokens, nil
+}
+
+// modelName returns the model to call, without the "models/" prefix
+func modelName(model string) string {
+	if model == "" {
+		return DefaultModel
+	}
+	return strings.TrimPrefix(model, "models/")
+}
+
+// contents returns the request's prompt and system instruction as a
+// generateContent payload
+func contents(request Request) map[string]interface{} {
+	payload := map[string]interface{}{
+		"contents": []content{{Role: "user", Parts: []part{{Text: request.Prompt}}}},
+	}
+	if request.System != "" {
+		payload["systemInstruction"] = content{Parts: []part{{Text: request.System}}}
+	}
+	return payload
+}
+
+// post calls a model method with payload as JSON and decodes the reply into
+// out
+func (c *Client) post(ctx context.Context, model, method string, payload, out interface{}) error {
+	resp, err := c.send(ctx, model, method, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Gemini response: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	return nil
+}
+
+// send calls a model method with payload as JSON, returning the response
+// when it succeeds; the caller closes its body
+func (c *Client) send(ctx context.Context, model, method string, payload interface{}) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:%s", c.baseURL, url.PathEscape(model), method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Gemini API is not reachable at %s: %w", c.baseURL, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Gemini response: %w", err)
+	}
+	var failure struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &failure) != nil || failure.Error.Message == "" {
+		failure.Error.Status, failure.Error.Message = "UNKNOWN", strings.TrimSpace(string(data))
+	}
+	return nil, &Error{Status: resp.StatusCode, Code: failure.Error.Status, Message: failure.Error.Message}
+}
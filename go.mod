@@ -3,5 +3,6 @@ module devos
 go 1.21
 
 require (
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.18
 )
@@ -0,0 +1,140 @@
+package grpcreflect
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ErrUnavailable is returned when grpcurl is not installed
+var ErrUnavailable = errors.New("grpcurl is not installed (see https://github.com/fullstorydev/grpcurl)")
+
+// inputType matches the request message in a method's description, e.g.
+// "rpc SayHello ( .helloworld.HelloRequest ) returns ( ... );"
+var inputType = regexp.MustCompile(`rpc\s+\w+\s*\(\s*(?:stream\s+)?\.?([\w.]+)\s*\)`)
+
+// Options says how to reach a server
+type Options struct {
+	Plaintext bool     // Connect without TLS, as internal services often expect
+	Insecure  bool     // Use TLS but skip certificate verification
+	Headers   []string // Metadata sent with each request, as "name: value"
+}
+
+// args returns grpcurl's connection flags
+func (o Options) args() []string {
+	var args []string
+	if o.Plaintext {
+		args = append(args, "-plaintext")
+	}
+	if o.Insecure {
+		args = append(args, "-insecure")
+	}
+	for _, header := range o.Headers {
+		args = append(args, "-H", header)
+	}
+	return args
+}
+
+// Available reports whether grpcurl is installed
+func Available() bool {
+	_, err := exec.LookPath("grpcurl")
+	return err == nil
+}
+
+// Services returns the services a server exposes through reflection,
+// leaving out the reflection service itself
+func Services(ctx context.Context, address string, opts Options) ([]string, error) {
+	out, err := run(ctx, "", append(opts.args(), address, "list")...)
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "grpc.reflection.") {
+			services = append(services, line)
+		}
+	}
+	return services, nil
+}
+
+// Methods returns a service's fully qualified methods
+func Methods(ctx context.Context, address, service string, opts Options) ([]string, error) {
+	out, err := run(ctx, "", append(opts.args(), address, "list", service)...)
+	if err != nil {
+		return nil, err
+	}
+	var methods []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			methods = append(methods, line)
+		}
+	}
+	return methods, nil
+}
+
+// Describe returns the protobuf definition of a service, method, or message
+func Describe(ctx context.Context, address, symbol string, opts Options) (string, error) {
+	return run(ctx, "", append(opts.args(), address, "describe", symbol)...)
+}
+
+// Template returns a JSON request for a method with every field filled in
+// with an example value, to edit into a call
+func Template(ctx context.Context, address, method string, opts Options) (string, error) {
+	description, err := Describe(ctx, address, symbol(method), opts)
+	if err != nil {
+		return "", err
+	}
+	m := inputType.FindStringSubmatch(description)
+	if m == nil {
+		return "", fmt.Errorf("%s is not a method", method)
+	}
+	out, err := run(ctx, "", append(opts.args(), "-msg-template", address, "describe", m[1])...)
+	if err != nil {
+		return "", err
+	}
+	if _, template, ok := strings.Cut(out, "Message template:"); ok {
+		return strings.TrimSpace(template), nil
+	}
+	return "", fmt.Errorf("grpcurl printed no template for %s", m[1])
+}
+
+// Call invokes a unary or server-streaming method with a JSON request and
+// returns the JSON responses
+func Call(ctx context.Context, address, method, request string, opts Options) (string, error) {
+	if request == "" {
+		request = "{}"
+	}
+	return run(ctx, request, append(opts.args(), "-d", "@", address, method)...)
+}
+
+// symbol returns a method written as "pkg.Service/Method" in the dotted
+// form describe expects
+func symbol(method string) string {
+	return strings.Replace(method, "/", ".", 1)
+}
+
+// run runs grpcurl with stdin as its input and returns its output
+func run(ctx context.Context, stdin string, args ...string) (string, error) {
+	if !Available() {
+		return "", ErrUnavailable
+	}
+	cmd := exec.CommandContext(ctx, "grpcurl", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("grpcurl failed: %s", message)
+		}
+		return "", fmt.Errorf("grpcurl failed: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,282 @@
+package guardrail
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+)
+
+// DefaultRefresh is how long a fetched bundle is used before it is fetched
+// again, unless the guardrails say otherwise
+const DefaultRefresh = 24 * time.Hour
+
+// maxBundleSize bounds a downloaded bundle or signature
+const maxBundleSize = 1 << 20
+
+// ErrUnverified means a bundle's signature does not match the configured key
+var ErrUnverified = errors.New("guardrails bundle signature does not verify")
+
+// ErrRollback means a bundle is older than one already seen, such as an
+// outdated but validly signed bundle served in place of the current one
+var ErrRollback = errors.New("guardrails bundle is older than one already in use")
+
+// Bundle is an organization's guardrails: a preamble injected into every AI
+// request, and policy enforced on every plan ahead of the user's own
+type Bundle struct {
+	Version         string                `json:"version,omitempty"`
+	Preamble        string                `json:"preamble"`
+	BlockedCommands []string              `json:"blocked_commands,omitempty"`
+	ApprovalRules   []config.ApprovalRule `json:"approval_rules,omitempty"`
+
+	// FetchedAt is when the bundle was downloaded and verified
+	FetchedAt time.Time `json:"-"`
+}
+
+// cache is the last verified bundle as kept on disk. The fetch time is
+// stored with it rather than taken from the file's modification time, which
+// copying or restoring the file changes.
+type cache struct {
+	Bundle    []byte    `json:"bundle"`
+	Signature []byte    `json:"signature"`
+	FetchedAt time.Time `json:"fetched_at"`
+
+	// HighestVersion is the newest bundle version seen, below which fetched
+	// bundles are refused
+	HighestVersion string `json:"highest_version,omitempty"`
+}
+
+// Dir returns where the last verified bundle is cached under the config
+// directory
+func Dir(configDir string) string {
+	return filepath.Join(configDir, "guardrails")
+}
+
+// Load returns the guardrails bundle, fetching it when the cached copy in
+// dir is older than the refresh interval. When fetching fails, a cached
+// bundle that still verifies is returned along with the error, so plans
+// keep their guardrails while the bundle's server is unreachable.
+func Load(ctx context.Context, g config.Guardrails, dir string) (*Bundle, error) {
+	cached, highest, cacheErr := readCache(g, dir)
+	if cached != nil && cached.Fresh(g) {
+		return cached, nil
+	}
+
+	data, sig, err := fetch(ctx, g.URL)
+	if err == nil {
+		var bundle *Bundle
+		if bundle, err = verify(data, sig, g.PublicKey); err == nil {
+			if compareVersions(bundle.Version, highest) < 0 {
+				err = fmt.Errorf("%w: fetched version %q, already seen %q", ErrRollback, bundle.Version, highest)
+			} else {
+				bundle.FetchedAt = time.Now()
+				if err := writeCache(dir, cache{Bundle: data, Signature: sig, FetchedAt: bundle.FetchedAt, HighestVersion: bundle.Version}); err != nil {
+					return bundle, fmt.Errorf("failed to cache guardrails: %w", err)
+				}
+				return bundle, nil
+			}
+		}
+	}
+	if cached != nil {
+		return cached, fmt.Errorf("failed to refresh guardrails from %s, using the copy from %s: %w", display(g.URL), cached.FetchedAt.Format(time.RFC3339), err)
+	}
+	if cacheErr != nil && !errors.Is(cacheErr, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w (cached copy: %v)", err, cacheErr)
+	}
+	return nil, err
+}
+
+// Fresh reports whether the bundle was fetched within the guardrails'
+// refresh interval
+func (b *Bundle) Fresh(g config.Guardrails) bool {
+	refresh := DefaultRefresh
+	if d, err := time.ParseDuration(g.Refresh); err == nil && d > 0 {
+		refresh = d
+	}
+	return time.Since(b.FetchedAt) < refresh
+}
+
+// verify checks data's signature with the base64 public key and parses it
+func verify(data, sig []byte, publicKey string) (*Bundle, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid guardrails public key")
+	}
+	// Signatures are published raw or base64-encoded
+	if len(sig) != ed25519.SignatureSize {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+			sig = decoded
+		}
+	}
+	if len(sig) != ed25519.SignatureSize || !ed25519.Verify(key, data, sig) {
+		return nil, ErrUnverified
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse guardrails bundle: %w", err)
+	}
+	return &bundle, nil
+}
+
+// fetch downloads a bundle and its signature from an https URL, or reads
+// them from a file
+func fetch(ctx context.Context, location string) ([]byte, []byte, error) {
+	data, err := read(ctx, location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch guardrails: %w", err)
+	}
+	sig, err := read(ctx, signatureLocation(location))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch guardrails signature: %w", err)
+	}
+	return data, sig, nil
+}
+
+// signatureLocation appends ".sig" to a bundle's path, keeping a URL's query
+// string, which signed URLs carry their credentials in
+func signatureLocation(location string) string {
+	if base, query, ok := strings.Cut(location, "?"); ok && strings.Contains(base, "://") {
+		return base + ".sig?" + query
+	}
+	return location + ".sig"
+}
+
+// display leaves the query string out of a URL for messages, since signed
+// URLs carry credentials there
+func display(location string) string {
+	base, _, _ := strings.Cut(location, "?")
+	return base
+}
+
+// read returns the contents of a URL or file
+func read(ctx context.Context, location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(strings.TrimPrefix(location, "file://"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid guardrails URL %s", display(location))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Without the URL, which the error would repeat in full
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("%s is not reachable: %w", display(location), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", display(location), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBundleSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", display(location), maxBundleSize)
+	}
+	return data, nil
+}
+
+// readCache returns the cached bundle if its signature still verifies with
+// the configured key, and the highest bundle version seen. The version is
+// returned even when the bundle does not verify, so a changed key does not
+// reset rollback protection.
+func readCache(g config.Guardrails, dir string) (*Bundle, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		return nil, "", err
+	}
+	var c cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, "", fmt.Errorf("invalid guardrails cache: %w", err)
+	}
+	bundle, err := verify(c.Bundle, c.Signature, g.PublicKey)
+	if err != nil {
+		return nil, c.HighestVersion, err
+	}
+	bundle.FetchedAt = c.FetchedAt
+	highest := c.HighestVersion
+	if compareVersions(bundle.Version, highest) > 0 {
+		highest = bundle.Version
+	}
+	return bundle, highest, nil
+}
+
+// writeCache keeps a verified bundle and its signature for offline use
+func writeCache(dir string, c cache) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written whole and renamed, so a reader never sees a partial cache
+	path := filepath.Join(dir, "cache.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// compareVersions orders bundle versions such as "2025-06" or "v1.10.2",
+// comparing their parts numerically where both are numbers. It returns -1,
+// 0, or 1. An unversioned bundle is older than any versioned one.
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(strings.TrimPrefix(v, "v"), func(r rune) bool {
+			return r == '.' || r == '-' || r == '_' || r == '+'
+		})
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && as[i] != bs[i]:
+			if as[i] < bs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// Blocked returns the bundle's blocked command that cmd contains, or ""
+func (b *Bundle) Blocked(cmd string) string {
+	lower := strings.ToLower(cmd)
+	for _, blocked := range b.BlockedCommands {
+		if blocked != "" && strings.Contains(lower, strings.ToLower(blocked)) {
+			return blocked
+		}
+	}
+	return ""
+}
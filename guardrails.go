@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devos/internal/guardrail"
+)
+
+// guardrailsRetry is how long a stale bundle is used before fetching it is
+// tried again
+const guardrailsRetry = time.Minute
+
+// guardrails returns the organization's guardrails bundle, or nil when none
+// is configured. Without a verified bundle, AI requests and plans are
+// refused rather than run without the organization's policy.
+func (e *Executor) guardrails(ctx context.Context) (*guardrail.Bundle, error) {
+	g := e.config.ActiveGuardrails()
+	if g == nil {
+		return nil, nil
+	}
+	if e.orgGuardrails != nil && (e.orgGuardrails.Fresh(*g) || time.Since(e.guardrailsTried) < guardrailsRetry) {
+		return e.orgGuardrails, nil
+	}
+
+	e.guardrailsTried = time.Now()
+	bundle, err := guardrail.Load(ctx, *g, guardrail.Dir(e.config.Dir()))
+	if bundle == nil {
+		e.audit.Record("guardrails_unavailable", map[string]string{"error": err.Error()})
+		return nil, fmt.Errorf("%w: organization guardrails are required but unavailable: %w", ErrPolicyDenied, err)
+	}
+	if err != nil {
+		e.logger.Warn("%v", err)
+	}
+	if e.orgGuardrails == nil || e.orgGuardrails.Version != bundle.Version {
+		e.logger.Info("Loaded organization guardrails %s", bundle.Version)
+		e.audit.Record("guardrails_loaded", map[string]string{"version": bundle.Version, "managed": fmt.Sprint(e.config.Managed())})
+	}
+	e.orgGuardrails = bundle
+	return bundle, nil
+}
+
+// Guardrails returns the organization's guardrails bundle in effect, or nil
+// when none is configured
+func (e *Executor) Guardrails(ctx context.Context) (*guardrail.Bundle, error) {
+	return e.guardrails(ctx)
+}
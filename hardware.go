@@ -0,0 +1,178 @@
+package platform
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// GPU acceleration backends
+const (
+	BackendCUDA   = "cuda"
+	BackendMetal  = "metal"
+	BackendROCm   = "rocm"
+	BackendVulkan = "vulkan"
+)
+
+// GPU is a graphics processor usable for local inference
+type GPU struct {
+	Name    string `json:"name"`
+	Backend string `json:"backend"`          // cuda, metal, rocm, or vulkan
+	Memory  uint64 `json:"memory,omitempty"` // Bytes; on Apple silicon, the share of unified memory Metal may use
+	Unified bool   `json:"unified,omitempty"`
+}
+
+// Hardware describes the memory and accelerators available for local models
+type Hardware struct {
+	RAM  uint64 `json:"ram"` // Bytes, 0 if unknown
+	GPUs []GPU  `json:"gpus,omitempty"`
+}
+
+// DetectHardware finds system RAM and GPUs. Only tools already installed
+// are consulted, so this is cheap on machines without a GPU.
+func DetectHardware() *Hardware {
+	hw := &Hardware{RAM: SystemMemory()}
+	hw.GPUs = append(hw.GPUs, nvidiaGPUs()...)
+
+	switch runtime.GOOS {
+	case "darwin":
+		if runtime.GOARCH == "arm64" {
+			// Metal may wire about three quarters of unified memory
+			hw.GPUs = append(hw.GPUs, GPU{Name: appleChip(), Backend: BackendMetal, Memory: hw.RAM / 4 * 3, Unified: true})
+		}
+	case "linux":
+		hw.GPUs = append(hw.GPUs, amdGPUs()...)
+	}
+	return hw
+}
+
+// VRAM returns the memory of the largest GPU, or 0 without one
+func (h *Hardware) VRAM() uint64 {
+	var largest uint64
+	for _, gpu := range h.GPUs {
+		if gpu.Memory > largest {
+			largest = gpu.Memory
+		}
+	}
+	return largest
+}
+
+// Summary describes the hardware in one line, e.g.
+// "32.0 GB RAM, NVIDIA GeForce RTX 4090 (24.0 GB, cuda)"
+func (h *Hardware) Summary() string {
+	parts := []string{"unknown RAM"}
+	if h.RAM > 0 {
+		parts[0] = formatBytes(h.RAM) + " RAM"
+	}
+	if len(h.GPUs) == 0 {
+		parts = append(parts, "no GPU acceleration")
+	}
+	for _, gpu := range h.GPUs {
+		details := gpu.Backend
+		if gpu.Memory > 0 {
+			memory := formatBytes(gpu.Memory)
+			if gpu.Unified {
+				memory += " unified"
+			}
+			details = memory + ", " + details
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", gpu.Name, details))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SystemMemory returns the total RAM in bytes, or 0 if it cannot be read
+func SystemMemory() uint64 {
+	switch runtime.GOOS {
+	case "linux":
+		file, err := os.Open("/proc/meminfo")
+		if err != nil {
+			return 0
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			// MemTotal:       16303460 kB
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "MemTotal:" {
+				kb, _ := strconv.ParseUint(fields[1], 10, 64)
+				return kb * 1024
+			}
+		}
+		return 0
+	case "darwin":
+		n, _ := strconv.ParseUint(commandOutput("sysctl", "-n", "hw.memsize"), 10, 64)
+		return n
+	case "windows":
+		n, _ := strconv.ParseUint(commandOutput("powershell", "-NoProfile", "-Command",
+			"(Get-CimInstance Win32_ComputerSystem).TotalPhysicalMemory"), 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
+
+// nvidiaGPUs lists NVIDIA GPUs with nvidia-smi, which ships with the driver
+func nvidiaGPUs() []GPU {
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return nil
+	}
+	var gpus []GPU
+	// e.g. "NVIDIA GeForce RTX 4090, 24564" (MiB)
+	out := commandOutput("nvidia-smi", "--query-gpu=name,memory.total", "--format=csv,noheader,nounits")
+	for _, line := range strings.Split(out, "\n") {
+		name, mib, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		n, _ := strconv.ParseUint(strings.TrimSpace(mib), 10, 64)
+		gpus = append(gpus, GPU{Name: strings.TrimSpace(name), Backend: BackendCUDA, Memory: n << 20})
+	}
+	return gpus
+}
+
+// amdGPUs lists AMD GPUs driven by amdgpu, which reports VRAM in sysfs.
+// ROCm is used when installed; otherwise llama.cpp can still use Vulkan.
+func amdGPUs() []GPU {
+	backend := BackendVulkan
+	if fileExists("/opt/rocm") {
+		backend = BackendROCm
+	}
+
+	var gpus []GPU
+	cards, _ := filepath.Glob("/sys/class/drm/card*/device/mem_info_vram_total")
+	for _, path := range cards {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		n, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if n == 0 {
+			continue
+		}
+		name := "AMD GPU"
+		if product, err := os.ReadFile(filepath.Join(filepath.Dir(path), "product_name")); err == nil && len(product) > 0 {
+			name = strings.TrimSpace(string(product))
+		}
+		gpus = append(gpus, GPU{Name: name, Backend: backend, Memory: n})
+	}
+	return gpus
+}
+
+// appleChip returns the Apple silicon chip name, e.g. "Apple M2 Pro"
+func appleChip() string {
+	if name := commandOutput("sysctl", "-n", "machdep.cpu.brand_string"); name != "" {
+		return name
+	}
+	return "Apple silicon"
+}
+
+// formatBytes renders a byte count in binary units, e.g. "15.5 GB"
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+}
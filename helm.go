@@ -0,0 +1,260 @@
+package helm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"devos/internal/ignore"
+	"devos/internal/textdiff"
+)
+
+// maxDepth bounds how deep FindCharts looks for Chart.yaml
+const maxDepth = 4
+
+// skipDirs are never searched for charts
+var skipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, ".venv": true, "dist": true, "build": true,
+}
+
+// Chart is a Helm chart found in the project
+type Chart struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version,omitempty"`
+	AppVersion  string   `json:"app_version,omitempty"`
+	Path        string   `json:"path"`
+	ValuesFiles []string `json:"values_files,omitempty"`
+}
+
+// FindCharts returns the charts under root, a project root, leaving out
+// what its .gitignore and .devosignore files ignore
+func FindCharts(root string) []Chart {
+	var charts []Chart
+	ignored := ignore.New(root)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			if skipDirs[d.Name()] || ignored.Ignored(path, true) || strings.Count(rel, string(filepath.Separator)) >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "Chart.yaml" {
+			if chart, err := loadChart(filepath.Dir(path)); err == nil {
+				charts = append(charts, *chart)
+			}
+		}
+		return nil
+	})
+	return charts
+}
+
+// loadChart reads a chart's metadata and values files
+func loadChart(dir string) (*Chart, error) {
+	fields, err := scalars(filepath.Join(dir, "Chart.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	chart := &Chart{
+		Name:       fields["name"],
+		Version:    fields["version"],
+		AppVersion: fields["appVersion"],
+		Path:       dir,
+	}
+	for _, pattern := range []string{"values*.yaml", "values*.yml", "*-values.yaml", "values/*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		chart.ValuesFiles = append(chart.ValuesFiles, matches...)
+	}
+	return chart, nil
+}
+
+// ValuesFile returns the values file for an environment, e.g. "staging"
+// matches values-staging.yaml, values.staging.yaml, staging-values.yaml,
+// or values/staging.yaml. An empty env returns values.yaml.
+func (c *Chart) ValuesFile(env string) (string, error) {
+	candidates := []string{"values.yaml"}
+	if env != "" {
+		candidates = []string{
+			"values-" + env + ".yaml", "values." + env + ".yaml",
+			env + "-values.yaml", filepath.Join("values", env+".yaml"),
+		}
+	}
+	for _, name := range candidates {
+		path := filepath.Join(c.Path, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no values file for %q in chart %s", env, c.Name)
+}
+
+// Template renders the chart with helm template, layering valuesFile over
+// the chart defaults
+func (c *Chart) Template(ctx context.Context, valuesFile string) (string, error) {
+	args := []string{"template", c.Name, c.Path}
+	if valuesFile != "" && filepath.Base(valuesFile) != "values.yaml" {
+		args = append(args, "-f", valuesFile)
+	}
+
+	out, err := exec.CommandContext(ctx, "helm", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("helm template failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("helm template failed: %w", err)
+	}
+	return string(out), nil
+}
+
+// GetValue returns the scalar at a dotted key (e.g. "image.tag") in a values file
+func GetValue(path, key string) (string, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return "", err
+	}
+	i := findKey(lines, key)
+	if i < 0 {
+		return "", fmt.Errorf("%s not found in %s", key, path)
+	}
+	_, value, _ := strings.Cut(lines[i], ":")
+	value, _, _ = strings.Cut(value, " #")
+	return strings.Trim(strings.TrimSpace(value), `"'`), nil
+}
+
+// SetValue replaces the scalar at a dotted key in a values file, keeping
+// the file's formatting, quoting, and trailing comments. It returns the
+// previous value. Keys that do not exist are not created.
+func SetValue(path, key, value string) (string, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return "", err
+	}
+	i := findKey(lines, key)
+	if i < 0 {
+		return "", fmt.Errorf("%s not found in %s", key, path)
+	}
+
+	prefix, rest, _ := strings.Cut(lines[i], ":")
+	old, comment, hasComment := strings.Cut(rest, " #")
+	old = strings.TrimSpace(old)
+	if old == "" || old == "|" || old == ">" {
+		return "", fmt.Errorf("%s in %s is not a scalar", key, path)
+	}
+
+	quoted := value
+	if strings.HasPrefix(old, `"`) || strings.HasPrefix(old, "'") {
+		quoted = old[:1] + value + old[:1]
+	}
+	lines[i] = prefix + ": " + quoted
+	if hasComment {
+		lines[i] += " #" + comment
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode()); err != nil {
+		return "", err
+	}
+	return strings.Trim(old, `"'`), nil
+}
+
+// findKey returns the line index of a dotted key in block-style YAML, or -1
+func findKey(lines []string, key string) int {
+	parts := strings.Split(key, ".")
+	depth := 0
+	parentIndent, childIndent := -1, 0 // Top-level keys are unindented
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent <= parentIndent {
+			return -1 // Left the matched parent without finding the key
+		}
+		if childIndent < 0 {
+			childIndent = indent
+		}
+		if indent != childIndent {
+			continue
+		}
+
+		name, _, ok := strings.Cut(trimmed, ":")
+		if !ok || strings.Trim(name, `"'`) != parts[depth] {
+			continue
+		}
+		if depth == len(parts)-1 {
+			return i
+		}
+		depth++
+		parentIndent, childIndent = indent, -1
+	}
+	return -1
+}
+
+// Diff returns a line diff of two renderings, showing only changed lines
+// with their nearest "# Source:" header for context
+func Diff(before, after string) string {
+	var out strings.Builder
+	source, shown := "", ""
+	for _, op := range textdiff.Lines(before, after) {
+		if op.Kind == ' ' {
+			if strings.HasPrefix(op.Text, "# Source:") {
+				source = strings.TrimSpace(strings.TrimPrefix(op.Text, "# Source:"))
+			}
+			continue
+		}
+		if source != shown {
+			out.WriteString("@@ " + source + "\n")
+			shown = source
+		}
+		out.WriteString(string(op.Kind) + " " + op.Text + "\n")
+	}
+	return out.String()
+}
+
+// scalars reads the top-level scalar fields of a simple YAML file
+func scalars(path string) (map[string]string, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	for _, line := range lines {
+		if line == "" || line[0] == ' ' || line[0] == '#' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return fields, nil
+}
+
+// readLines reads a file as lines
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,109 @@
+package freeze
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// event is a VEVENT from an iCal feed
+type event struct {
+	summary    string
+	start, end time.Time
+}
+
+// parseICal reads the events of an iCalendar (RFC 5545) feed. Recurring
+// events contribute their first occurrence only; use a schedule for
+// freezes that repeat.
+func parseICal(r io.Reader, loc *time.Location) ([]event, error) {
+	var events []event
+	var current *event
+	var allDay bool
+	for _, line := range unfold(r) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				current, allDay = &event{}, false
+			}
+		case "END":
+			if strings.EqualFold(value, "VEVENT") && current != nil {
+				if current.end.IsZero() && allDay {
+					current.end = current.start.AddDate(0, 0, 1)
+				}
+				if !current.start.IsZero() && current.end.After(current.start) {
+					events = append(events, *current)
+				}
+				current = nil
+			}
+		case "SUMMARY":
+			if current != nil {
+				current.summary = unescape(value)
+			}
+		case "DTSTART", "DTEND":
+			if current == nil {
+				continue
+			}
+			t, date, err := icalTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				current.start, allDay = t, date
+			} else {
+				current.end = t
+			}
+		}
+	}
+	return events, nil
+}
+
+// unfold joins RFC 5545 continuation lines, which start with a space or tab
+func unfold(r io.Reader) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// icalTime parses a DATE-TIME in UTC ("...Z"), in the zone named by a TZID
+// parameter, or floating in loc, or a DATE; date reports the latter
+func icalTime(value, params string, loc *time.Location) (time.Time, bool, error) {
+	for _, param := range strings.Split(params, ";") {
+		if key, tzid, ok := strings.Cut(param, "="); ok && strings.EqualFold(key, "TZID") {
+			if zone, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				loc = zone
+			}
+		}
+	}
+	switch {
+	case len(value) == 8:
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	default:
+		t, err := time.ParseInLocation("20060102T150405", value, loc)
+		return t, false, err
+	}
+}
+
+// unescape undoes RFC 5545 text escaping
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nonIdempotentPattern describes a command shape that is unsafe to re-run
+type nonIdempotentPattern struct {
+	pattern *regexp.Regexp
+	reason  string
+}
+
+// nonIdempotentPatterns are checked in order; the first match explains the risk
+var nonIdempotentPatterns = []nonIdempotentPattern{
+	{regexp.MustCompile(`>>|\btee\s+(-a|--append)\b|Add-Content`), "appends to a file; re-running duplicates content"},
+	{regexp.MustCompile(`\bgit\s+clone\b`), "fails if the target directory already exists"},
+	{regexp.MustCompile(`\bgit\s+commit\b`), "creates a new commit on every run"},
+	{regexp.MustCompile(`\bgit\s+tag\b|\bnpm\s+version\b`), "fails if the tag already exists"},
+	{regexp.MustCompile(`\bkubectl\s+create\b`), "fails if the resource exists (kubectl apply is idempotent)"},
+	{regexp.MustCompile(`\bdocker\s+run\b.*--name\b`), "fails if a container with that name already exists"},
+	{regexp.MustCompile(`\bdocker\s+(network|volume)\s+create\b`), "fails if the resource already exists"},
+	{regexp.MustCompile(`\b(useradd|adduser|groupadd)\b`), "fails if the user or group already exists"},
+	{regexp.MustCompile(`\bcreatedb\b|(?i)\bcreate\s+(table|database)\s+(?:[^i]|i[^f])`), "fails if the database object already exists"},
+	{regexp.MustCompile(`\bln\s+-s\s`), "fails if the link already exists (use ln -sf)"},
+	{regexp.MustCompile(`^\s*(mv|Move-Item)\s`), "the source no longer exists after the first run"},
+}
+
+// NonIdempotentReason explains why re-running cmd may not be safe, or returns
+// an empty string if the command looks idempotent
+func NonIdempotentReason(cmd string) string {
+	for _, segment := range segments(cmd) {
+		program, args := splitProgram(segment)
+		if program == "mkdir" && !hasFlag(args, "-p", "--parents") {
+			return "fails if the directory already exists (use mkdir -p)"
+		}
+	}
+
+	for _, p := range nonIdempotentPatterns {
+		if p.pattern.MatchString(cmd) {
+			return p.reason
+		}
+	}
+
+	return ""
+}
+
+// hasFlag reports whether args contain any of the given flags
+func hasFlag(args []string, flags ...string) bool {
+	for _, arg := range args {
+		for _, flag := range flags {
+			if strings.EqualFold(arg, flag) {
+				return true
+			}
+		}
+	}
+	return false
+}
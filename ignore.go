@@ -0,0 +1,196 @@
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Files whose patterns a directory's paths are matched against, in order;
+// later files win. .devosignore keeps paths git tracks out of DevOS's
+// scans, e.g. directories of secrets committed encrypted.
+var ignoreFiles = []string{".gitignore", ".devosignore"}
+
+// alwaysIgnored are skipped in every project, ignore files or not
+var alwaysIgnored = map[string]bool{".git": true, "node_modules": true}
+
+// rule is one pattern of an ignore file
+type rule struct {
+	pattern *regexp.Regexp // Matches paths relative to the ignore file's directory
+	negate  bool           // "!pattern" re-includes what an earlier pattern ignored
+	dirOnly bool           // "pattern/" only matches directories
+}
+
+// Matcher reports whether paths in a project are ignored by its .gitignore
+// and .devosignore files, at the root and in subdirectories, and
+// .git/info/exclude. It reads ignore files as directories are first
+// matched against, and is not safe for concurrent use.
+type Matcher struct {
+	root  string
+	rules map[string][]rule // By directory relative to root, "." for the root
+}
+
+// New returns a Matcher for the project at root
+func New(root string) *Matcher {
+	m := &Matcher{root: filepath.Clean(root), rules: map[string][]rule{}}
+	m.rules["."] = append(readRules(filepath.Join(root, ".git", "info", "exclude")), m.dirRules(".")...)
+	return m
+}
+
+// Ignored reports whether path, absolute or relative to the root, is
+// ignored, or is inside an ignored directory. Paths outside the root are
+// never ignored.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil {
+			return false
+		}
+		path = rel
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == "." || path == ".." || strings.HasPrefix(path, "../") {
+		return false
+	}
+
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		if m.matches(segments[:i+1], isDir || i < len(segments)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the rules of the directories above a path, the deepest
+// last, and reports whether the last rule matching it ignores it
+func (m *Matcher) matches(segments []string, isDir bool) bool {
+	if alwaysIgnored[segments[len(segments)-1]] {
+		return true
+	}
+	ignored := false
+	for depth := 0; depth < len(segments); depth++ {
+		dir := "."
+		if depth > 0 {
+			dir = strings.Join(segments[:depth], "/")
+		}
+		rules, ok := m.rules[dir]
+		if !ok {
+			rules = m.dirRules(dir)
+			m.rules[dir] = rules
+		}
+		rel := strings.Join(segments[depth:], "/")
+		for _, r := range rules {
+			if (!r.dirOnly || isDir) && r.pattern.MatchString(rel) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// dirRules reads the ignore files of a directory relative to the root
+func (m *Matcher) dirRules(dir string) []rule {
+	var rules []rule
+	for _, name := range ignoreFiles {
+		rules = append(rules, readRules(filepath.Join(m.root, filepath.FromSlash(dir), name))...)
+	}
+	return rules
+}
+
+// readRules parses an ignore file; a missing file has no rules
+func readRules(path string) []rule {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var rules []rule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if r, ok := parseRule(scanner.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// parseRule parses one line of an ignore file, in gitignore syntax
+func parseRule(line string) (rule, bool) {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if strings.HasSuffix(line, `\`) {
+		line += " "
+	}
+	var r rule
+	switch {
+	case line == "" || strings.HasPrefix(line, "#"):
+		return r, false
+	case strings.HasPrefix(line, "!"):
+		r.negate, line = true, line[1:]
+	case strings.HasPrefix(line, `\#`), strings.HasPrefix(line, `\!`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly, line = true, strings.TrimSuffix(line, "/")
+	}
+	// A pattern with a slash is relative to its file's directory; one
+	// without matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return r, false
+	}
+
+	expr := globRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return r, false
+	}
+	r.pattern = re
+	return r, true
+}
+
+// globRegexp translates a gitignore glob into a regular expression: * and ?
+// stay within a path segment, and ** spans segments
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// imageBuild matches image builds and pulls, capturing the image reference
+var imageBuild = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:docker|podman)\s+(?:buildx\s+)?build\b.*?\s(?:-t|--tag)[\s=]+['"]?([^\s'"]+)`),
+	regexp.MustCompile(`\b(?:docker|podman)\s+pull\s+(?:-\S+\s+)*['"]?([^\s'"]+)`),
+}
+
+// deployStep matches commands that ship an image somewhere it will run
+var deployStep = regexp.MustCompile(`\b(?:docker\s+push|podman\s+push|kubectl\s+(?:apply|create|set\s+image|rollout)|helm\s+(?:install|upgrade)|docker\s+compose\s+up|docker-compose\s+up)\b`)
+
+// Vulnerability is a high-severity finding reported by an image scanner
+type Vulnerability struct {
+	ID       string
+	Package  string
+	Version  string
+	Severity string
+	FixedIn  string
+}
+
+// plannedImages returns the images built or pulled by commands
+func plannedImages(commands []string) []string {
+	var images []string
+	for _, cmd := range commands {
+		for _, pattern := range imageBuild {
+			if m := pattern.FindStringSubmatch(cmd); m != nil {
+				images = append(images, m[1])
+			}
+		}
+	}
+	return images
+}
+
+// scanBeforeDeploy scans images built or pulled earlier in the plan when cmd
+// is a deployment step, failing if any have high-severity vulnerabilities.
+// Each image is scanned once per run.
+func (e *Executor) scanBeforeDeploy(ctx context.Context, cmd string, images []string, scanned map[string]bool) error {
+	if e.config.ImageScanner == "" || !deployStep.MatchString(cmd) {
+		return nil
+	}
+
+	scanner := e.imageScanner()
+	if scanner == "" {
+		e.logger.Warn("Image scanning enabled but neither trivy nor grype is installed")
+		return nil
+	}
+
+	for _, image := range images {
+		if scanned[image] {
+			continue
+		}
+		scanned[image] = true
+
+		fmt.Printf("  🔍 Scanning %s with %s\n", image, scanner)
+		vulns, err := scanImage(ctx, scanner, image)
+		if err != nil {
+			return fmt.Errorf("image scan of %s failed: %w", image, err)
+		}
+		if len(vulns) == 0 {
+			continue
+		}
+
+		fmt.Printf("  ⚠️  %s has %d high-severity vulnerabilities:\n", image, len(vulns))
+		for _, v := range vulns {
+			fixed := ""
+			if v.FixedIn != "" {
+				fixed = " (fixed in " + v.FixedIn + ")"
+			}
+			fmt.Printf("    • %s %s %s %s%s\n", v.Severity, v.ID, v.Package, v.Version, fixed)
+		}
+
+		e.audit.Record("image_vulnerable", map[string]string{"image": image, "count": fmt.Sprint(len(vulns))})
+		return fmt.Errorf("%w: %s has %d high-severity vulnerabilities; not deploying", ErrValidationBlocked, image, len(vulns))
+	}
+
+	return nil
+}
+
+// imageScanner returns the configured scanner, resolving "auto" to whichever
+// of trivy or grype is installed
+func (e *Executor) imageScanner() string {
+	candidates := []string{e.config.ImageScanner}
+	if e.config.ImageScanner == "auto" {
+		candidates = []string{"trivy", "grype"}
+	}
+	for _, name := range candidates {
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// scanImage runs an image scanner and returns HIGH and CRITICAL findings
+func scanImage(ctx context.Context, scanner, image string) ([]Vulnerability, error) {
+	var args []string
+	switch scanner {
+	case "trivy":
+		args = []string{"image", "--quiet", "--severity", "HIGH,CRITICAL", "--format", "json", image}
+	case "grype":
+		args = []string{image, "--output", "json"}
+	default:
+		return nil, fmt.Errorf("unsupported image scanner: %s", scanner)
+	}
+
+	out, err := runProcess(exec.CommandContext(ctx, scanner, args...), scanner+" "+strings.Join(args, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	if scanner == "trivy" {
+		return parseTrivy([]byte(out))
+	}
+	return parseGrype([]byte(out))
+}
+
+// parseTrivy extracts findings from `trivy image --format json` output
+func parseTrivy(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+
+	var vulns []Vulnerability
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			if highSeverity(v.Severity) {
+				vulns = append(vulns, Vulnerability{v.VulnerabilityID, v.PkgName, v.InstalledVersion, v.Severity, v.FixedVersion})
+			}
+		}
+	}
+	return vulns, nil
+}
+
+// parseGrype extracts findings from `grype -o json` output
+func parseGrype(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+				Fix      struct {
+					Versions []string `json:"versions"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse grype output: %w", err)
+	}
+
+	var vulns []Vulnerability
+	for _, m := range report.Matches {
+		if highSeverity(m.Vulnerability.Severity) {
+			vulns = append(vulns, Vulnerability{
+				ID:       m.Vulnerability.ID,
+				Package:  m.Artifact.Name,
+				Version:  m.Artifact.Version,
+				Severity: strings.ToUpper(m.Vulnerability.Severity),
+				FixedIn:  strings.Join(m.Vulnerability.Fix.Versions, ", "),
+			})
+		}
+	}
+	return vulns, nil
+}
+
+// highSeverity reports whether a severity is HIGH or CRITICAL
+func highSeverity(severity string) bool {
+	s := strings.ToUpper(severity)
+	return s == "HIGH" || s == "CRITICAL"
+}
@@ -0,0 +1,87 @@
+package issues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tracker types
+const (
+	TypeJira   = "jira"
+	TypeLinear = "linear"
+)
+
+// requestTimeout bounds each call to a tracker's API
+const requestTimeout = 20 * time.Second
+
+// ErrNotFound is returned for an issue the tracker does not know
+var ErrNotFound = errors.New("issue not found")
+
+// Issue is a ticket in Jira or Linear
+type Issue struct {
+	Key         string `json:"key"` // e.g. "OPS-142" or "ENG-7"
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status,omitempty"`
+	URL         string `json:"url,omitempty"`
+
+	// id is the tracker's internal ID, needed by Linear to comment
+	id string
+}
+
+// Tracker fetches issues and posts comments on them
+type Tracker interface {
+	// Assigned lists open issues assigned to the authenticated user
+	Assigned(ctx context.Context) ([]Issue, error)
+	// Issue fetches one issue by key
+	Issue(ctx context.Context, key string) (*Issue, error)
+	// Comment posts a comment on an issue
+	Comment(ctx context.Context, key, body string) error
+}
+
+// Options configures a tracker
+type Options struct {
+	Type  string // jira or linear
+	URL   string // Jira site, e.g. https://example.atlassian.net; unused for Linear
+	Email string // Jira Cloud account email; empty sends the token as a bearer token
+	Token string // Jira API token or personal access token, or Linear API key
+}
+
+// New returns the tracker described by opts
+func New(opts Options) (Tracker, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("no %s token configured", opts.Type)
+	}
+	client := &http.Client{Timeout: requestTimeout}
+	switch opts.Type {
+	case TypeJira:
+		if opts.URL == "" {
+			return nil, fmt.Errorf("no Jira URL configured")
+		}
+		return &jira{baseURL: strings.TrimRight(opts.URL, "/"), email: opts.Email, token: opts.Token, http: client}, nil
+	case TypeLinear:
+		return &linear{endpoint: linearEndpoint, token: opts.Token, http: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown issue tracker %q (expected jira or linear)", opts.Type)
+	}
+}
+
+// check turns an unsuccessful response into an error naming the API
+func check(resp *http.Response, api string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s rejected the credentials (%s); check the token in issue_tracker", api, resp.Status)
+	case http.StatusNotFound:
+		return ErrNotFound
+	}
+	return fmt.Errorf("%s returned %s: %s", api, resp.Status, strings.TrimSpace(string(body)))
+}
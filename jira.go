@@ -0,0 +1,133 @@
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// assignedJQL selects the user's unfinished issues, most recently updated first
+const assignedJQL = "assignee = currentUser() AND statusCategory != Done ORDER BY updated DESC"
+
+// jira talks to the Jira REST API v2, which Cloud and Data Center both serve
+// and which takes and returns descriptions and comments as plain text
+type jira struct {
+	baseURL string
+	email   string
+	token   string
+	http    *http.Client
+}
+
+// jiraIssue is an issue as the REST API returns it
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+	} `json:"fields"`
+}
+
+func (j *jira) Assigned(ctx context.Context) ([]Issue, error) {
+	query := url.Values{"jql": {assignedJQL}, "fields": {"summary,status"}, "maxResults": {"50"}}
+	var result struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	// Cloud replaced /search with /search/jql; Data Center only has /search
+	err := j.do(ctx, http.MethodGet, "/rest/api/2/search/jql?"+query.Encode(), nil, &result)
+	if errors.Is(err, ErrNotFound) {
+		err = j.do(ctx, http.MethodGet, "/rest/api/2/search?"+query.Encode(), nil, &result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]Issue, len(result.Issues))
+	for i, issue := range result.Issues {
+		issues[i] = j.convert(issue)
+	}
+	return issues, nil
+}
+
+func (j *jira) Issue(ctx context.Context, key string) (*Issue, error) {
+	var issue jiraIssue
+	err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=summary,description,status", nil, &issue)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	converted := j.convert(issue)
+	return &converted, nil
+}
+
+func (j *jira) Comment(ctx context.Context, key, body string) error {
+	err := j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body}, nil)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return err
+}
+
+// convert maps an API issue to an Issue
+func (j *jira) convert(issue jiraIssue) Issue {
+	return Issue{
+		Key:         issue.Key,
+		Title:       issue.Fields.Summary,
+		Description: issue.Fields.Description,
+		Status:      issue.Fields.Status.Name,
+		URL:         j.baseURL + "/browse/" + issue.Key,
+	}
+}
+
+// do sends a request with the configured credentials and decodes the JSON
+// response into out, if given
+func (j *jira) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Cloud API tokens go with the account email; Data Center personal
+	// access tokens are bearer tokens
+	if j.email != "" {
+		req.SetBasicAuth(j.email, j.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+
+	resp, err := j.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("Jira is not reachable at %s: %w", j.baseURL, err)
+	}
+	defer resp.Body.Close()
+	if err := check(resp, "Jira"); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Jira response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devos/internal/memory"
+)
+
+// jobPoll is how often a followed job's log is checked for new output
+const jobPoll = 200 * time.Millisecond
+
+// maxTail bounds how much of a job's log is read to find its last lines
+const maxTail = 64 << 10
+
+// Job is a plan running in the background of the session: its commands run
+// one after another, with their output going to a log file, until one
+// fails or the job is killed
+type Job struct {
+	ID       int
+	Commands []string
+	Prompt   string // The request the job's plan was generated for
+	Started  time.Time
+	Log      string // Path to the output of the job's commands
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	killed   bool // Guarded by the JobManager's lock
+	reported bool // Guarded by the JobManager's lock
+
+	// Set once, as the job finishes; see Outcome
+	mu           sync.Mutex
+	exitCode     int // -1 when the job was killed or a command could not start
+	completed    int
+	rollback     []string
+	irreversible []string
+}
+
+// Command returns the job's commands as one line
+func (j *Job) Command() string {
+	return strings.Join(j.Commands, " && ")
+}
+
+// Done is closed when the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Running reports whether the job is still running
+func (j *Job) Running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// ExitCode returns the exit code of the job's last command, once it has
+// finished
+func (j *Job) ExitCode() int {
+	<-j.done
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.exitCode
+}
+
+// Outcome returns, once the job has finished, how many of its commands
+// completed, what reverses them, last first, and the completed commands
+// that nothing reverses
+func (j *Job) Outcome() (completed int, rollback, irreversible []string) {
+	<-j.done
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.completed, j.rollback, j.irreversible
+}
+
+// JobManager runs plans in the background of a session, so a dev server or
+// a long build does not block the REPL. Jobs belong to the session: Close
+// kills those still running and removes their logs.
+type JobManager struct {
+	mu   sync.Mutex
+	dir  string
+	jobs []*Job
+	next int
+}
+
+// NewJobManager returns a JobManager keeping job logs in dir
+func NewJobManager(dir string) *JobManager {
+	return &JobManager{dir: dir, next: 1}
+}
+
+// Start runs commands as a background job, each with run, which runs
+// command i, writes its output to out, and stops it when ctx ends. finish,
+// when set, is called with how many commands completed before the job is
+// done, and returns what reverses them and what nothing reverses.
+func (m *JobManager) Start(commands []string, run func(ctx context.Context, i int, out io.Writer) error, finish func(completed int) (rollback, irreversible []string)) (*Job, error) {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := filepath.Join(m.dir, fmt.Sprintf("%d-%d.log", os.Getpid(), m.next))
+	log, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job log: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{ID: m.next, Commands: commands, Started: time.Now(), Log: path, cancel: cancel, done: make(chan struct{})}
+	m.next++
+	m.jobs = append(m.jobs, job)
+
+	go func() {
+		defer close(job.done)
+		defer log.Close()
+		defer cancel()
+
+		completed, exitCode := 0, 0
+		for i := range commands {
+			err := run(ctx, i, log)
+			if err == nil {
+				completed++
+				continue
+			}
+			exitCode = -1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && ctx.Err() == nil {
+				exitCode = exitErr.ExitCode()
+			} else if ctx.Err() == nil {
+				fmt.Fprintf(log, "devos: %v\n", err)
+			}
+			break
+		}
+
+		var rollback, irreversible []string
+		if finish != nil {
+			rollback, irreversible = finish(completed)
+		}
+		job.mu.Lock()
+		job.exitCode, job.completed, job.rollback, job.irreversible = exitCode, completed, rollback, irreversible
+		job.mu.Unlock()
+	}()
+	return job, nil
+}
+
+// List returns the session's jobs, oldest first
+func (m *JobManager) List() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Job(nil), m.jobs...)
+}
+
+// Get returns a job by its ID, optionally written %ID as in shells; an
+// empty ID is the newest running job
+func (m *JobManager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == "" {
+		for i := len(m.jobs) - 1; i >= 0; i-- {
+			if m.jobs[i].Running() {
+				return m.jobs[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no running jobs")
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "%"))
+	if err == nil {
+		for _, job := range m.jobs {
+			if job.ID == n {
+				return job, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no such job: %s", id)
+}
+
+// State describes a job: running, done, failed, or killed
+func (m *JobManager) State(job *Job) string {
+	if job.Running() {
+		return "running"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case job.killed:
+		return "killed"
+	case job.ExitCode() == 0:
+		return "done"
+	default:
+		return "failed"
+	}
+}
+
+// Kill stops a running job, with the processes its command started, and
+// waits for it to finish
+func (m *JobManager) Kill(job *Job) error {
+	if !job.Running() {
+		return fmt.Errorf("job %d has already finished", job.ID)
+	}
+	m.mu.Lock()
+	job.killed = true
+	job.reported = true
+	m.mu.Unlock()
+	job.cancel()
+	select {
+	case <-job.done:
+		return nil
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("job %d did not stop", job.ID)
+	}
+}
+
+// Finished returns the jobs that finished since they were last reported;
+// killed jobs are not reported
+func (m *JobManager) Finished() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var finished []*Job
+	for _, job := range m.jobs {
+		if !job.reported && !job.Running() {
+			job.reported = true
+			finished = append(finished, job)
+		}
+	}
+	return finished
+}
+
+// Tail returns the last n lines a job has written
+func (m *JobManager) Tail(job *Job, n int) (string, error) {
+	f, err := os.Open(job.Log)
+	if err != nil {
+		return "", fmt.Errorf("failed to read job output: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read job output: %w", err)
+	}
+	from := max(info.Size()-maxTail, 0)
+	data, err := io.ReadAll(io.NewSectionReader(f, from, info.Size()-from))
+	if err != nil {
+		return "", fmt.Errorf("failed to read job output: %w", err)
+	}
+	if from > 0 {
+		// The first line read may be cut off
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines[max(len(lines)-n, 0):], ""), nil
+}
+
+// Follow copies what a job writes to w from the end of its log until the
+// job finishes, or until ctx ends, when it returns ctx's error
+func (m *JobManager) Follow(ctx context.Context, job *Job, w io.Writer) error {
+	f, err := os.Open(job.Log)
+	if err != nil {
+		return fmt.Errorf("failed to read job output: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to read job output: %w", err)
+	}
+
+	ticker := time.NewTicker(jobPoll)
+	defer ticker.Stop()
+	for {
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-job.done:
+			// Copy what was written after the last poll
+			_, err := io.Copy(w, f)
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close kills the jobs still running and removes the session's job logs
+func (m *JobManager) Close() {
+	for _, job := range m.List() {
+		if job.Running() {
+			m.Kill(job)
+		}
+		os.Remove(job.Log)
+	}
+}
+
+// Jobs returns the session's background jobs
+func (e *Executor) Jobs() *JobManager {
+	return e.jobs
+}
+
+// Background runs an approved plan as a background job, in the working
+// directory and environment it would have run in, on its own copy of the
+// executor, since the session goes on using this one. Like the shell, a job
+// follows cd from one of its commands to the next; the session stays put.
+// The job holds the project's lock while it runs and is journaled, so it
+// can be resumed, and undone once done, like a plan run in the foreground.
+// Jobs are how long-running commands run, so the command timeout does not
+// stop them; kill does.
+func (e *Executor) Background(result *ExecutionResult) (*Job, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	steps := result.Steps
+	if len(steps) != len(result.Commands) {
+		steps = nil
+	}
+	release, err := e.lockProjectAt(dir, result.Commands)
+	if err != nil {
+		return nil, err
+	}
+	runID := e.startRun(result.Commands, steps)
+
+	bg := e.detached()
+	scanned := make(map[string]bool)
+	job, err := bg.jobs.Start(result.Commands, func(ctx context.Context, i int, out io.Writer) error {
+		cmdStr := result.Commands[i]
+		err := bg.scanBeforeDeploy(ctx, cmdStr, plannedImages(result.Commands[:i]), scanned)
+		if err == nil && steps != nil && cdPrograms[steps[i].Program] {
+			// cd is a shell builtin: as a step, it changes the job's directory
+			var target string
+			if target, err = bg.stepDir(dir, steps[i]); err == nil {
+				dir = target
+			}
+		} else if err == nil {
+			var step *Command
+			if steps != nil {
+				step = &steps[i]
+			}
+			err = bg.retryCommand(ctx, cmdStr, func(ctx context.Context) error {
+				_, err := bg.attempt(ctx, cmdStr, step, dir, out)
+				return err
+			})
+			if err == nil && step == nil {
+				dir = bg.shellDir(dir, cmdStr)
+			}
+		}
+		bg.recordExecution(cmdStr, err)
+		if err != nil {
+			bg.updateRun(runID, i, memory.RunFailed)
+			return err
+		}
+		bg.updateRun(runID, i+1, memory.RunRunning)
+		return nil
+	}, func(completed int) ([]string, []string) {
+		defer release()
+		if completed == len(result.Commands) {
+			bg.updateRun(runID, completed, memory.RunSucceeded)
+		}
+		return bg.compensate(result, completed)
+	})
+	if err != nil {
+		release()
+		e.updateRun(runID, 0, memory.RunFailed)
+		return nil, err
+	}
+	job.Prompt = result.Prompt
+	e.logger.Info("Started job %d: %s", job.ID, job.Command())
+	e.audit.Record("job_started", map[string]string{"job": strconv.Itoa(job.ID), "commands": strings.Join(result.Commands, "\n")})
+	return job, nil
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"devos/internal/timefmt"
+)
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// Run is an entry in the execution journal: one execution of a plan
+type Run struct {
+	ID        int64     `json:"id"`
+	PlanHash  string    `json:"plan_hash"`
+	Commands  []string  `json:"commands"`
+	Steps     []byte    `json:"steps,omitempty"` // Structured form of Commands, if any (JSON)
+	Completed int       `json:"completed"`       // Number of steps that succeeded
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PlanHash identifies a plan by its commands
+func PlanHash(commands []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(commands, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// StartRun records the start of a plan execution
+func (s *Store) StartRun(commands []string, steps []byte) (int64, error) {
+	data, err := json.Marshal(commands)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal commands: %w", err)
+	}
+
+	now := timefmt.Now()
+	id, err := s.insert(
+		`INSERT INTO runs (plan_hash, commands, steps, completed, status, created_at, updated_at) VALUES (?, ?, ?, 0, ?, ?, ?)`,
+		PlanHash(commands), string(data), string(steps), RunRunning, now, now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start run: %w", err)
+	}
+
+	return id, nil
+}
+
+// UpdateRun records progress of a plan execution
+func (s *Store) UpdateRun(id int64, completed int, status string) error {
+	_, err := s.exec(
+		`UPDATE runs SET completed = ?, status = ?, updated_at = ? WHERE id = ?`,
+		completed, status, timefmt.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update run: %w", err)
+	}
+	return nil
+}
+
+// LastRun returns the most recent execution of the plan with the given hash
+func (s *Store) LastRun(planHash string) (*Run, error) {
+	row := s.queryRow(
+		`SELECT id, plan_hash, commands, steps, completed, status, created_at, updated_at
+		FROM runs WHERE plan_hash = ? ORDER BY id DESC LIMIT 1`,
+		planHash,
+	)
+	return scanRun(row)
+}
+
+// LatestUnfinishedRun returns the most recent run that was interrupted or
+// failed, if it is also the most recent run overall
+func (s *Store) LatestUnfinishedRun() (*Run, error) {
+	row := s.queryRow(
+		`SELECT id, plan_hash, commands, steps, completed, status, created_at, updated_at
+		FROM runs ORDER BY id DESC LIMIT 1`,
+	)
+
+	run, err := scanRun(row)
+	if err != nil || run == nil || run.Status == RunSucceeded {
+		return nil, err
+	}
+	return run, nil
+}
+
+// scanRun reads a run from a query row, returning nil when there is none
+func scanRun(row *sql.Row) (*Run, error) {
+	var r Run
+	var commands, steps string
+
+	err := row.Scan(&r.ID, &r.PlanHash, &commands, &steps, &r.Completed, &r.Status, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(commands), &r.Commands); err != nil {
+		return nil, fmt.Errorf("failed to parse run commands: %w", err)
+	}
+	if steps != "" {
+		r.Steps = []byte(steps)
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrNoKeychain is returned when the OS keychain cannot be used, e.g. on
+// Windows or a Linux machine without secret-tool
+var ErrNoKeychain = errors.New("no OS keychain available (macOS security or Linux secret-tool); use a passphrase")
+
+// Keychain item the config key is stored under
+const (
+	keychainService = "devos"
+	keychainAccount = "config-key"
+)
+
+// KeychainKey returns the config key from the OS keychain. With create, a
+// random key is generated and stored when there is none yet; only a
+// missing key counts, not a keychain that cannot be read.
+func KeychainKey(create bool) (*Key, error) {
+	encoded, err := keychainRead()
+	if err != nil && !(create && errors.Is(err, errNotFound)) {
+		return nil, err
+	}
+	if err == nil {
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(secret) != 32 {
+			return nil, fmt.Errorf("keychain item %s/%s is not a DevOS key", keychainService, keychainAccount)
+		}
+		return &Key{Source: SourceKeychain, secret: secret}, nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := keychainWrite(base64.StdEncoding.EncodeToString(secret)); err != nil {
+		return nil, err
+	}
+	return &Key{Source: SourceKeychain, secret: secret}, nil
+}
+
+// errNotFound is returned when the keychain has no DevOS key
+var errNotFound = errors.New("no DevOS key in the OS keychain")
+
+// keychainRead returns the stored key
+func keychainRead() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+	case "windows":
+		return "", ErrNoKeychain
+	default:
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return "", ErrNoKeychain
+		}
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && itemNotFound(exitErr.ExitCode(), stderr.String()) {
+		return "", errNotFound
+	}
+	if err != nil {
+		// A locked keychain or a denied prompt must not pass for a missing
+		// key, or a new key would replace the one sealing the config
+		return "", fmt.Errorf("failed to read the key from the keychain: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	encoded := strings.TrimSpace(string(out))
+	if encoded == "" {
+		return "", errNotFound
+	}
+	return encoded, nil
+}
+
+// itemNotFound reports whether a keychain tool's exit status means the item
+// is missing: security exits 44 (errSecItemNotFound), and secret-tool
+// exits 1 without an error message
+func itemNotFound(code int, stderr string) bool {
+	if runtime.GOOS == "darwin" {
+		return code == 44
+	}
+	return code == 1 && strings.TrimSpace(stderr) == ""
+}
+
+// keychainWrite stores the key
+func keychainWrite(encoded string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security only takes the password as an argument; the key is
+		// visible in the process list for the moment the command runs
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", keychainAccount, "-w", encoded)
+	case "windows":
+		return ErrNoKeychain
+	default:
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return ErrNoKeychain
+		}
+		cmd = exec.Command("secret-tool", "store", "--label=DevOS config key", "service", keychainService, "account", keychainAccount)
+		cmd.Stdin = strings.NewReader(encoded)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to store the key in the keychain: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
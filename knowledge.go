@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"devos/internal/timefmt"
+)
+
+// Resolution is a set of commands that previously fixed a known failure
+type Resolution struct {
+	Signature string    `json:"signature"`
+	Sample    string    `json:"sample"`
+	Commands  []string  `json:"commands"`
+	Hits      int       `json:"hits"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Patterns stripped from error output so that equivalent failures hash identically
+var (
+	quotedPattern = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+	pathPattern   = regexp.MustCompile(`(?:[a-zA-Z]:)?[\\/][^\s:]+`)
+	hexPattern    = regexp.MustCompile(`\b0x[0-9a-f]+\b|\b[0-9a-f]{8,}\b`)
+	numberPattern = regexp.MustCompile(`\d+`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// ErrorSignature returns a stable hash of an error message with volatile
+// details (paths, quoted names, numbers, addresses) removed
+func ErrorSignature(stderr string) string {
+	normalized := strings.ToLower(stderr)
+	normalized = quotedPattern.ReplaceAllString(normalized, "<str>")
+	normalized = pathPattern.ReplaceAllString(normalized, "<path>")
+	normalized = hexPattern.ReplaceAllString(normalized, "<hex>")
+	normalized = numberPattern.ReplaceAllString(normalized, "<n>")
+	normalized = strings.TrimSpace(spacePattern.ReplaceAllString(normalized, " "))
+	if normalized == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SaveResolution records the commands that resolved a failure signature
+func (s *Store) SaveResolution(signature, sample string, commands []string) error {
+	data, err := json.Marshal(commands)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resolution: %w", err)
+	}
+
+	_, err = s.exec(
+		`INSERT INTO failures (signature, sample, commands, hits, updated_at) VALUES (?, ?, ?, 0, ?)
+		ON CONFLICT(signature) DO UPDATE SET sample = excluded.sample, commands = excluded.commands, updated_at = excluded.updated_at`,
+		signature, sample, string(data), timefmt.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save resolution: %w", err)
+	}
+
+	return nil
+}
+
+// LookupResolution returns the known resolution for a failure signature
+func (s *Store) LookupResolution(signature string) (*Resolution, error) {
+	var r Resolution
+	var commands string
+
+	err := s.queryRow(
+		`SELECT signature, sample, commands, hits, updated_at FROM failures WHERE signature = ?`,
+		signature,
+	).Scan(&r.Signature, &r.Sample, &commands, &r.Hits, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failure knowledge base: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(commands), &r.Commands); err != nil {
+		return nil, fmt.Errorf("failed to parse resolution: %w", err)
+	}
+
+	if _, err := s.exec(`UPDATE failures SET hits = hits + 1 WHERE signature = ?`, signature); err != nil {
+		return nil, fmt.Errorf("failed to update resolution hits: %w", err)
+	}
+	r.Hits++
+
+	return &r, nil
+}
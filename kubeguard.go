@@ -0,0 +1,131 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"devos/internal/config"
+	"devos/internal/policy"
+	"devos/internal/privacy"
+	"devos/internal/project"
+)
+
+// Flags selecting the kubeconfig context and namespace of one command
+var (
+	contextFlag   = regexp.MustCompile(`(?:--context|--kube-context)[=\s]+['"]?([^\s'"]+)`)
+	namespaceFlag = regexp.MustCompile(`(?:\s-n|--namespace)[=\s]+['"]?([^\s'"]+)`)
+)
+
+// KubeTarget is the cluster a plan's kubectl and helm commands would change
+type KubeTarget struct {
+	Context     string   // Kubeconfig context the commands use; empty if unknown
+	Namespace   string   // Namespace they change
+	Environment string   // Environment the project declares, or the active one
+	Mismatch    string   // Why the context looks wrong for that environment; empty when it fits
+	Commands    []string // The commands that change the cluster
+}
+
+// KubeTarget returns where the plan's cluster-changing commands would apply
+// and whether that fits the project's environment, or nil when no command
+// changes a cluster
+func (e *Executor) KubeTarget(ctx context.Context, commands []string) *KubeTarget {
+	target := &KubeTarget{}
+	for _, cmd := range commands {
+		if changesCluster(cmd) {
+			target.Commands = append(target.Commands, cmd)
+		}
+	}
+	if len(target.Commands) == 0 {
+		return nil
+	}
+
+	for _, cmd := range target.Commands {
+		if m := contextFlag.FindStringSubmatch(cmd); m != nil && target.Context == "" {
+			target.Context = m[1]
+		}
+		if m := namespaceFlag.FindStringSubmatch(cmd); m != nil && target.Namespace == "" {
+			target.Namespace = m[1]
+		}
+	}
+	if target.Namespace == "" {
+		// The namespace comes from the context the commands use
+		args := []string{"view", "--minify", "--output", "jsonpath={..namespace}"}
+		if target.Context != "" {
+			args = append(args, "--context", target.Context)
+		}
+		target.Namespace = e.kubectlConfig(ctx, args...)
+	}
+	if target.Context == "" {
+		target.Context = e.kubectlConfig(ctx, "current-context")
+	}
+	if target.Namespace == "" {
+		target.Namespace = "default"
+	}
+
+	env := e.projectEnvironment()
+	if env == nil || target.Context == "" {
+		return target
+	}
+	target.Environment = env.Name
+
+	switch {
+	case env.KubeContext != "" && target.Context != env.KubeContext:
+		target.Mismatch = fmt.Sprintf("the %s environment uses context %s", env.Name, env.KubeContext)
+	case env.KubeContext == "" && !strings.Contains(target.Context, env.Name):
+		// Without a declared context, flag contexts named after another stage
+		for _, other := range e.config.Environments {
+			if other.Name != env.Name && strings.Contains(target.Context, other.Name) {
+				target.Mismatch = fmt.Sprintf("context %s looks like the %s environment", target.Context, other.Name)
+				break
+			}
+		}
+	}
+	if target.Mismatch == "" && env.KubeNamespace != "" && target.Namespace != env.KubeNamespace {
+		target.Mismatch = fmt.Sprintf("the %s environment uses namespace %s", env.Name, env.KubeNamespace)
+	}
+	return target
+}
+
+// changesCluster reports whether a command is a kubectl or helm invocation
+// that changes cluster state
+func changesCluster(cmd string) bool {
+	if policy.IsReadOnly(cmd) {
+		return false
+	}
+	for _, program := range policy.Programs(cmd) {
+		if program == "kubectl" || program == "helm" {
+			return true
+		}
+	}
+	return false
+}
+
+// kubectlConfig runs "kubectl config" with the environment commands get, so
+// a context the active environment pins is what it reports
+func (e *Executor) kubectlConfig(ctx context.Context, args ...string) string {
+	cmd := exec.CommandContext(ctx, "kubectl", append([]string{"config"}, args...)...)
+	cmd.Env = e.environ()
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// projectEnvironment returns the environment the current project belongs
+// to, or the active environment when no environment lists the project
+func (e *Executor) projectEnvironment() *config.Environment {
+	if cwd, err := os.Getwd(); err == nil {
+		root := project.Root(cwd)
+		for i, env := range e.config.Environments {
+			if privacy.Match(env.Projects, root) != "" {
+				return &e.config.Environments[i]
+			}
+		}
+	}
+	return e.config.ActiveEnvironment()
+}
@@ -0,0 +1,150 @@
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// linearEndpoint is Linear's GraphQL API
+const linearEndpoint = "https://api.linear.app/graphql"
+
+// linearFields are the issue fields DevOS reads
+const linearFields = "id identifier title description url state { name }"
+
+// linear talks to the Linear GraphQL API with a personal API key
+type linear struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+// linearIssue is an issue as the GraphQL API returns it
+type linearIssue struct {
+	ID          string `json:"id"`
+	Identifier  string `json:"identifier"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	State       struct {
+		Name string `json:"name"`
+	} `json:"state"`
+}
+
+func (l *linear) Assigned(ctx context.Context) ([]Issue, error) {
+	query := `query { viewer { assignedIssues(first: 50, orderBy: updatedAt, filter: { state: { type: { nin: ["completed", "canceled"] } } }) { nodes { ` + linearFields + ` } } } }`
+	var data struct {
+		Viewer struct {
+			AssignedIssues struct {
+				Nodes []linearIssue `json:"nodes"`
+			} `json:"assignedIssues"`
+		} `json:"viewer"`
+	}
+	if err := l.do(ctx, query, nil, &data); err != nil {
+		return nil, err
+	}
+
+	nodes := data.Viewer.AssignedIssues.Nodes
+	issues := make([]Issue, len(nodes))
+	for i, node := range nodes {
+		issues[i] = node.convert()
+	}
+	return issues, nil
+}
+
+func (l *linear) Issue(ctx context.Context, key string) (*Issue, error) {
+	query := `query($id: String!) { issue(id: $id) { ` + linearFields + ` } }`
+	var data struct {
+		Issue *linearIssue `json:"issue"`
+	}
+	if err := l.do(ctx, query, map[string]interface{}{"id": key}, &data); err != nil {
+		if strings.Contains(err.Error(), "Entity not found") {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, err
+	}
+	if data.Issue == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	issue := data.Issue.convert()
+	return &issue, nil
+}
+
+func (l *linear) Comment(ctx context.Context, key, body string) error {
+	// Comments are created against the internal ID, not the identifier
+	issue, err := l.Issue(ctx, key)
+	if err != nil {
+		return err
+	}
+	query := `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success } }`
+	var data struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	input := map[string]interface{}{"input": map[string]string{"issueId": issue.id, "body": body}}
+	if err := l.do(ctx, query, input, &data); err != nil {
+		return err
+	}
+	if !data.CommentCreate.Success {
+		return fmt.Errorf("Linear did not create the comment on %s", key)
+	}
+	return nil
+}
+
+// convert maps an API issue to an Issue
+func (i linearIssue) convert() Issue {
+	return Issue{
+		Key:         i.Identifier,
+		Title:       i.Title,
+		Description: i.Description,
+		Status:      i.State.Name,
+		URL:         i.URL,
+		id:          i.ID,
+	}
+}
+
+// do runs a GraphQL operation and decodes its data into out
+func (l *linear) do(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Personal API keys are sent as is; OAuth tokens carry their own "Bearer"
+	req.Header.Set("Authorization", l.token)
+
+	resp, err := l.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("Linear is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := check(resp, "Linear"); err != nil {
+		return err
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse Linear response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("Linear returned an error: %s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(result.Data, out)
+}
@@ -0,0 +1,349 @@
+package lineedit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned when Ctrl-C abandons the line
+var ErrInterrupted = errors.New("interrupted")
+
+// Bracketed paste markers, sent by terminals with bracketed paste enabled
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// Editor reads a line from a terminal, showing how it could be completed as
+// dim text after the cursor, fish-style. Right arrow or End accepts the
+// whole suggestion, Tab its next word.
+type Editor struct {
+	In  *os.File
+	Out io.Writer
+
+	// Suggest returns the text completing line, or "" for no suggestion
+	Suggest func(line string) string
+
+	buf     []rune
+	cursor  int
+	drawn   int // Cursor position after the last redraw
+	suggest string
+}
+
+// Supported reports whether lines can be edited on f: a terminal on a
+// system with stty
+func Supported(f *os.File) bool {
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	_, err = exec.LookPath("stty")
+	return err == nil
+}
+
+// ReadLine reads one line after the prompt the caller printed. It returns
+// io.EOF on Ctrl-D at an empty line and ErrInterrupted on Ctrl-C. A
+// multi-line paste is returned at once, wrapped in the bracketed paste
+// markers.
+func (e *Editor) ReadLine() (string, error) {
+	restore, err := e.rawMode()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.buf, e.cursor, e.drawn, e.suggest = nil, 0, 0, ""
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			if len(e.buf) > 0 && errors.Is(err, io.EOF) {
+				break
+			}
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			e.suggest = ""
+			e.redraw()
+			fmt.Fprint(e.Out, "\n")
+			return string(e.buf), nil
+		case 0x03: // Ctrl-C
+			e.suggest = ""
+			e.redraw()
+			fmt.Fprint(e.Out, "^C\n")
+			return "", ErrInterrupted
+		case 0x04: // Ctrl-D
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.Out, "\n")
+				return "", io.EOF
+			}
+			e.delete(e.cursor, e.cursor+1)
+		case 0x7f, 0x08: // Backspace
+			e.delete(e.cursor-1, e.cursor)
+		case 0x01: // Ctrl-A
+			e.cursor = 0
+		case 0x05: // Ctrl-E
+			e.end()
+		case 0x0b: // Ctrl-K
+			e.delete(e.cursor, len(e.buf))
+		case 0x15: // Ctrl-U
+			e.delete(0, e.cursor)
+		case 0x17: // Ctrl-W
+			start := e.cursor
+			for start > 0 && e.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.buf[start-1] != ' ' {
+				start--
+			}
+			e.delete(start, e.cursor)
+		case '\t':
+			e.acceptWord()
+		case 0x1b:
+			if line, done := e.escape(); done {
+				return line, nil
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+		e.update()
+	}
+	fmt.Fprint(e.Out, "\n")
+	return string(e.buf), nil
+}
+
+// escape handles an escape sequence: cursor keys, Delete, and pastes. It
+// returns the line when a multi-line paste completes it.
+func (e *Editor) escape() (string, bool) {
+	seq := ""
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			return "", false
+		}
+		seq += string(r)
+		// Sequences end with a letter or ~ after the introducer
+		if len(seq) > 1 && (unicode.IsLetter(r) || r == '~') {
+			break
+		}
+		if len(seq) == 1 && r != '[' && r != 'O' {
+			return "", false // Alt-<key>
+		}
+	}
+
+	switch seq {
+	case "[C", "OC": // Right
+		if e.cursor == len(e.buf) {
+			e.end()
+		} else {
+			e.cursor++
+		}
+	case "[D", "OD": // Left
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case "[H", "OH", "[1~", "[7~": // Home
+		e.cursor = 0
+	case "[F", "OF", "[4~", "[8~": // End
+		e.end()
+	case "[3~": // Delete
+		e.delete(e.cursor, e.cursor+1)
+	case "[200~":
+		text := e.readPaste()
+		if !strings.Contains(text, "\n") {
+			e.insert([]rune(text))
+			return "", false
+		}
+		// A multi-line paste is a prompt of its own
+		e.suggest = ""
+		e.redraw()
+		fmt.Fprintf(e.Out, "%s\n", text)
+		return string(e.buf) + pasteStart + text + pasteEnd, true
+	}
+	return "", false
+}
+
+// readPaste reads pasted text up to the end-of-paste marker
+func (e *Editor) readPaste() string {
+	var b strings.Builder
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			return b.String()
+		}
+		if r == '\r' {
+			r = '\n'
+		}
+		b.WriteRune(r)
+		if text := b.String(); strings.HasSuffix(text, pasteEnd) {
+			return strings.TrimSuffix(text, pasteEnd)
+		}
+	}
+}
+
+// end moves the cursor to the end of the line, accepting the suggestion
+// when it is already there
+func (e *Editor) end() {
+	if e.cursor == len(e.buf) && e.suggest != "" {
+		e.insert([]rune(e.suggest))
+	}
+	e.cursor = len(e.buf)
+}
+
+// acceptWord accepts the suggestion up to the end of its next word
+func (e *Editor) acceptWord() {
+	if e.cursor != len(e.buf) || e.suggest == "" {
+		return
+	}
+	s := []rune(e.suggest)
+	n := 0
+	for n < len(s) && s[n] == ' ' {
+		n++
+	}
+	for n < len(s) && s[n] != ' ' {
+		n++
+	}
+	e.insert(s[:n])
+}
+
+// insert adds runes at the cursor
+func (e *Editor) insert(runes []rune) {
+	e.buf = append(e.buf[:e.cursor], append(runes, e.buf[e.cursor:]...)...)
+	e.cursor += len(runes)
+}
+
+// delete removes the runes from start up to end
+func (e *Editor) delete(start, end int) {
+	start, end = max(start, 0), min(end, len(e.buf))
+	if start >= end {
+		return
+	}
+	e.buf = append(e.buf[:start], e.buf[end:]...)
+	if e.cursor > end {
+		e.cursor -= end - start
+	} else if e.cursor > start {
+		e.cursor = start
+	}
+}
+
+// update refreshes the suggestion and redraws the line
+func (e *Editor) update() {
+	e.suggest = ""
+	if e.Suggest != nil && e.cursor == len(e.buf) && strings.TrimSpace(string(e.buf)) != "" {
+		e.suggest = e.Suggest(string(e.buf))
+	}
+	e.redraw()
+}
+
+// redraw rewrites the line after the prompt, with the suggestion dimmed,
+// and puts the cursor back in place
+func (e *Editor) redraw() {
+	var b strings.Builder
+	if e.drawn > 0 {
+		// Back to the start of the line from wherever the cursor was
+		fmt.Fprintf(&b, "\x1b[%dD", e.drawn)
+	}
+	b.WriteString("\x1b[K")
+	b.WriteString(string(e.buf))
+	if e.suggest != "" {
+		fmt.Fprintf(&b, "\x1b[2m%s\x1b[0m", e.suggest)
+	}
+	shown := len(e.buf) + utf8.RuneCountInString(e.suggest)
+	if back := shown - e.cursor; back > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", back)
+	}
+	fmt.Fprint(e.Out, b.String())
+	e.drawn = e.cursor
+}
+
+// readRune reads one UTF-8 character, a byte at a time so nothing typed
+// ahead is consumed from the terminal
+func (e *Editor) readRune() (rune, error) {
+	var b [utf8.UTFMax]byte
+	for n := 0; n < len(b); n++ {
+		if _, err := io.ReadFull(e.In, b[n:n+1]); err != nil {
+			return 0, err
+		}
+		if utf8.FullRune(b[:n+1]) {
+			r, _ := utf8.DecodeRune(b[:n+1])
+			return r, nil
+		}
+	}
+	return utf8.RuneError, nil
+}
+
+// rawMode switches the terminal to reading keys as they are typed, without
+// echo or signals, and returns a function restoring its settings
+func (e *Editor) rawMode() (func(), error) {
+	saved, err := e.stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal settings: %w", err)
+	}
+	if _, err := e.stty("-icanon", "-echo", "-isig", "min", "1", "time", "0"); err != nil {
+		return nil, fmt.Errorf("failed to set terminal mode: %w", err)
+	}
+	return func() { e.stty(strings.TrimSpace(saved)) }, nil
+}
+
+// stty runs stty on the editor's terminal
+func (e *Editor) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = e.In
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// History suggests earlier lines that start with what has been typed, the
+// most recent first
+type History struct {
+	lines []string // Newest first, without duplicates
+}
+
+// NewHistory returns a history of lines, given oldest first
+func NewHistory(lines []string) *History {
+	h := &History{}
+	for _, line := range lines {
+		h.Add(line)
+	}
+	return h
+}
+
+// Add records a line as the most recent; blank and multi-line input is not
+// suggested
+func (h *History) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.Contains(line, "\n") {
+		return
+	}
+	for i, l := range h.lines {
+		if l == line {
+			h.lines = append(h.lines[:i], h.lines[i+1:]...)
+			break
+		}
+	}
+	h.lines = append([]string{line}, h.lines...)
+}
+
+// Suggest returns the rest of the most recent line starting with line,
+// ignoring case, or ""
+func (h *History) Suggest(line string) string {
+	for _, l := range h.lines {
+		if len(l) > len(line) && strings.EqualFold(l[:len(line)], line) {
+			return l[len(line):]
+		}
+	}
+	return ""
+}
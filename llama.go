@@ -0,0 +1,34 @@
+package llama
+
+import "errors"
+
+// This package runs GGUF models in-process with llama.cpp, so DevOS can
+// generate plans with no Ollama server and no Python engine.
+//
+// llama.cpp is linked with cgo and only included when building with the
+// "llama" tag, against a go-llama.cpp checkout whose libbinding.a is built:
+//
+//	git clone --recurse-submodules https://github.com/go-skynet/go-llama.cpp
+//	make -C go-llama.cpp libbinding.a
+//	go mod edit -replace github.com/go-skynet/go-llama.cpp=./go-llama.cpp
+//	go get github.com/go-skynet/go-llama.cpp
+//	C_INCLUDE_PATH=$PWD/go-llama.cpp LIBRARY_PATH=$PWD/go-llama.cpp go build -tags llama
+//
+// Add BUILD_TYPE=cublas (CUDA), metal, or hipblas (ROCm) to the make command
+// for GPU offload. Builds without the tag report ErrUnavailable.
+
+// ErrUnavailable is returned when DevOS was built without llama.cpp
+var ErrUnavailable = errors.New("this build of DevOS does not include llama.cpp (rebuild with -tags llama)")
+
+// Options configures a loaded model
+type Options struct {
+	ContextLength int // Tokens; 0 uses the model's default
+	GPULayers     int // Layers to offload to the GPU; 0 runs on the CPU
+}
+
+// GenerateOptions configures one completion
+type GenerateOptions struct {
+	MaxTokens   int
+	Temperature float64
+	Stop        []string // Generation ends at the first of these
+}
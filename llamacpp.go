@@ -0,0 +1,66 @@
+//go:build llama
+
+package llama
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	binding "github.com/go-skynet/go-llama.cpp"
+)
+
+// Available reports whether this build includes llama.cpp
+const Available = true
+
+// Model is a GGUF model loaded into memory. llama.cpp contexts are not
+// safe for concurrent use, so completions run one at a time.
+type Model struct {
+	mu  sync.Mutex
+	llm *binding.LLama
+}
+
+// Load reads the GGUF model at path, memory-mapping its weights
+func Load(path string, opts Options) (*Model, error) {
+	modelOpts := []binding.ModelOption{binding.SetMMap(true)}
+	if opts.ContextLength > 0 {
+		modelOpts = append(modelOpts, binding.SetContext(opts.ContextLength))
+	}
+	if opts.GPULayers > 0 {
+		modelOpts = append(modelOpts, binding.SetGPULayers(opts.GPULayers))
+	}
+	llm, err := binding.New(path, modelOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return &Model{llm: llm}, nil
+}
+
+// Generate completes prompt. Cancelling ctx stops generation at the next token.
+func (m *Model) Generate(ctx context.Context, prompt string, opts GenerateOptions) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	text, err := m.llm.Predict(prompt,
+		binding.SetTokens(opts.MaxTokens),
+		binding.SetTemperature(float32(opts.Temperature)),
+		binding.SetThreads(runtime.NumCPU()),
+		binding.SetStopWords(opts.Stop...),
+		binding.SetTokenCallback(func(string) bool { return ctx.Err() == nil }),
+	)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return "", fmt.Errorf("llama.cpp generation failed: %w", err)
+	}
+	return text, nil
+}
+
+// Close frees the model's memory
+func (m *Model) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.llm.Free()
+}
@@ -0,0 +1,24 @@
+//go:build !llama
+
+package llama
+
+import "context"
+
+// Available reports whether this build includes llama.cpp
+const Available = false
+
+// Model is a placeholder in builds without llama.cpp
+type Model struct{}
+
+// Load always fails with ErrUnavailable
+func Load(path string, opts Options) (*Model, error) {
+	return nil, ErrUnavailable
+}
+
+// Generate always fails with ErrUnavailable
+func (m *Model) Generate(ctx context.Context, prompt string, opts GenerateOptions) (string, error) {
+	return "", ErrUnavailable
+}
+
+// Close does nothing
+func (m *Model) Close() {}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"devos/internal/attach"
+	"devos/internal/budget"
+	"devos/internal/models"
+	"devos/internal/privacy"
+)
+
+// contextFileKeys are request extras naming a file or repository whose
+// contents the request carries; files attached to the prompt ("files")
+// count too
+var contextFileKeys = []string{"log_file", "repo"}
+
+// LocalOnly returns the local_only entry covering the current directory, or
+// "" when its context may go to cloud providers
+func (e *Executor) LocalOnly() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return privacy.Match(e.config.LocalOnly, cwd)
+}
+
+// localTag returns the local_only entry covering the project a call runs
+// in or a file whose contents it carries, or "" when its context may leave
+// the machine
+func (e *Executor) localTag(extra map[string]interface{}) string {
+	if len(e.config.LocalOnly) == 0 {
+		return ""
+	}
+	tag := e.LocalOnly()
+	for _, key := range contextFileKeys {
+		if file, ok := extra[key].(string); ok && tag == "" && file != "" {
+			tag = privacy.Match(e.config.LocalOnly, absPath(file))
+		}
+	}
+	files, _ := extra["files"].([]attach.File)
+	for _, f := range files {
+		if tag == "" {
+			tag = privacy.Match(e.config.LocalOnly, absPath(f.Path))
+		}
+	}
+	return tag
+}
+
+// localRoute sends a call with local-only context to the local model. It
+// returns nil when the context may leave the machine or the configured
+// provider is local already.
+func (e *Executor) localRoute(extra map[string]interface{}) *route {
+	if budget.Local(e.config.AIProvider) {
+		return nil
+	}
+	tag := e.localTag(extra)
+	if tag == "" {
+		return nil
+	}
+
+	spec := e.config.LocalModel
+	if spec == "" {
+		spec = "ollama:" + models.Auto
+	}
+	provider, model := e.fallback(spec, "")
+	e.audit.Record("privacy_routed", map[string]string{"local_only": tag, "provider": provider, "model": model})
+	return &route{
+		provider: provider,
+		model:    model,
+		warnings: []string{fmt.Sprintf("%s is local-only; this plan was made by %s (%s)", tag, model, provider)},
+	}
+}
+
+// absPath makes a path absolute, leaving it as is if that fails
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -7,7 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"time"
+
+	"devos/internal/timefmt"
 )
 
 // LogLevel represents the logging level
@@ -25,30 +28,35 @@ type Logger struct {
 	level      LogLevel
 	fileLogger *log.Logger
 	file       *os.File
+	host       string
+
+	mu    sync.Mutex
+	sinks []levelSink
 }
 
 // New creates a new logger instance
 func New(levelStr string) *Logger {
 	level := parseLevel(levelStr)
+	host, _ := os.Hostname()
 
 	// Create log directory
 	logDir, err := getLogDir()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to get log directory: %v\n", err)
-		return &Logger{level: level}
+		return &Logger{level: level, host: host}
 	}
 
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to create log directory: %v\n", err)
-		return &Logger{level: level}
+		return &Logger{level: level, host: host}
 	}
 
 	// Create log file
-	logFile := filepath.Join(logDir, fmt.Sprintf("devos-%s.log", time.Now().Format("2006-01-02")))
+	logFile := filepath.Join(logDir, fmt.Sprintf("devos-%s.log", timefmt.In(time.Now()).Format("2006-01-02")))
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to open log file: %v\n", err)
-		return &Logger{level: level}
+		return &Logger{level: level, host: host}
 	}
 
 	// Create multi-writer for both file and stdout (for debug mode)
@@ -63,11 +71,20 @@ func New(levelStr string) *Logger {
 		level:      level,
 		fileLogger: log.New(writer, "", 0),
 		file:       file,
+		host:       host,
 	}
 }
 
-// Close closes the log file
+// Close flushes and closes the sinks and the log file
 func (l *Logger) Close() error {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.sinks = nil
+	l.mu.Unlock()
+	for _, s := range sinks {
+		s.sink.Close()
+	}
+
 	if l.file != nil {
 		return l.file.Close()
 	}
@@ -76,35 +93,37 @@ func (l *Logger) Close() error {
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
-	if l.level <= DEBUG {
-		l.log(DEBUG, format, args...)
-	}
+	l.log(DEBUG, format, args...)
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, args ...interface{}) {
-	if l.level <= INFO {
-		l.log(INFO, format, args...)
-	}
+	l.log(INFO, format, args...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, args ...interface{}) {
-	if l.level <= WARN {
-		l.log(WARN, format, args...)
-	}
+	l.log(WARN, format, args...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
-	if l.level <= ERROR {
-		l.log(ERROR, format, args...)
-	}
+	l.log(ERROR, format, args...)
 }
 
-// log is the internal logging function
+// log is the internal logging function. The local file gets messages at
+// or above the logger's level; each sink applies its own level.
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if l.fileLogger == nil {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.mu.Unlock()
+
+	toFile := l.fileLogger != nil && level >= l.level
+	toSinks := false
+	for _, s := range sinks {
+		toSinks = toSinks || level >= s.level
+	}
+	if !toFile && !toSinks {
 		return
 	}
 
@@ -115,14 +134,21 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
 	}
 
-	// Format message
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	now := time.Now()
 	levelStr := levelString(level)
 	message := fmt.Sprintf(format, args...)
 
-	logLine := fmt.Sprintf("[%s] [%s] [%s] %s", timestamp, levelStr, caller, message)
+	if toFile {
+		timestamp := timefmt.In(now).Format("2006-01-02 15:04:05 MST")
+		l.fileLogger.Println(fmt.Sprintf("[%s] [%s] [%s] %s", timestamp, levelStr, caller, message))
+	}
 
-	l.fileLogger.Println(logLine)
+	entry := Entry{Time: now, Level: levelStr, Host: l.host, Caller: caller, Message: message}
+	for _, s := range sinks {
+		if level >= s.level {
+			s.sink.Write(entry) // Delivery failures must not interrupt the caller
+		}
+	}
 }
 
 // parseLevel converts a string level to LogLevel
@@ -157,6 +183,11 @@ func levelString(level LogLevel) string {
 	}
 }
 
+// Dir returns the directory holding DevOS's own log files, one per day
+func Dir() (string, error) {
+	return getLogDir()
+}
+
 // getLogDir returns the platform-specific log directory
 func getLogDir() (string, error) {
 	var baseDir string
@@ -0,0 +1,382 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sink types
+const (
+	SinkSyslog = "syslog"
+	SinkFile   = "file"
+	SinkHTTP   = "http"
+	SinkLoki   = "loki"
+)
+
+// Batching limits for HTTP and Loki sinks; entries beyond sinkQueue are
+// dropped rather than blocking the caller
+const (
+	sinkBatch    = 100
+	sinkQueue    = 1000
+	sinkInterval = 2 * time.Second
+)
+
+// Entry is one log record as shipped to sinks
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Host    string    `json:"host"`
+	Caller  string    `json:"caller"`
+	Message string    `json:"message"`
+}
+
+// line renders the entry like the local log file
+func (e Entry) line() string {
+	return fmt.Sprintf("[%s] [%s] [%s] %s", e.Time.UTC().Format(time.RFC3339), e.Level, e.Caller, e.Message)
+}
+
+// Sink receives log entries in addition to the local log file
+type Sink interface {
+	Write(entry Entry) error
+	Close() error
+}
+
+// SinkOptions configures a sink
+type SinkOptions struct {
+	Type    string            // syslog, file, http, or loki
+	Target  string            // Syslog address, file path, or URL
+	Level   string            // Minimum level; empty for the logger's level
+	Labels  map[string]string // Extra Loki stream labels
+	Headers map[string]string // Extra HTTP and Loki request headers, e.g. Authorization
+}
+
+// levelSink is a sink with its own minimum level
+type levelSink struct {
+	sink  Sink
+	level LogLevel
+}
+
+// OpenSink creates the sink described by opts
+func OpenSink(opts SinkOptions) (Sink, error) {
+	switch opts.Type {
+	case SinkSyslog:
+		return newSyslogSink(opts.Target)
+	case SinkFile:
+		return newFileSink(opts.Target)
+	case SinkHTTP:
+		return newBatchSink(opts.Target, opts.Headers, jsonBatch)
+	case SinkLoki:
+		endpoint, err := url.JoinPath(opts.Target, "/loki/api/v1/push")
+		if err != nil {
+			return nil, fmt.Errorf("invalid Loki URL: %w", err)
+		}
+		return newBatchSink(endpoint, opts.Headers, lokiBatch(opts.Labels))
+	default:
+		return nil, fmt.Errorf("unknown log sink type: %s", opts.Type)
+	}
+}
+
+// AddSink ships entries at or above level (or the logger's level, if empty)
+// to sink until the logger is closed
+func (l *Logger) AddSink(sink Sink, level string) {
+	min := l.level
+	if level != "" {
+		min = parseLevel(level)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks = append(l.sinks, levelSink{sink: sink, level: min})
+}
+
+// syslogSink writes RFC 5424 messages to a syslog daemon
+type syslogSink struct {
+	mu      sync.Mutex
+	network string
+	address string
+	conn    net.Conn
+}
+
+// newSyslogSink connects to target, e.g. "udp://logs:514", "tcp://logs:601",
+// or "unix:///dev/log"; empty uses the local daemon
+func newSyslogSink(target string) (*syslogSink, error) {
+	s := &syslogSink{network: "unixgram", address: "/dev/log"}
+	if target != "" {
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" {
+			return nil, fmt.Errorf("invalid syslog address %q (use udp://host:514, tcp://host:601, or unix:///dev/log)", target)
+		}
+		s.network, s.address = u.Scheme, u.Host
+		if u.Scheme == "unix" || u.Scheme == "unixgram" {
+			s.network, s.address = "unixgram", u.Path
+		}
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect (re)opens the connection to the syslog daemon
+func (s *syslogSink) connect() error {
+	conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog at %s: %w", s.address, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// syslogSeverity maps levels to syslog severities
+var syslogSeverity = map[string]int{"DEBUG": 7, "INFO": 6, "WARN": 4, "ERROR": 3}
+
+// Write sends the entry with the user facility, reconnecting once if the
+// daemon restarted
+func (s *syslogSink) Write(entry Entry) error {
+	const facilityUser = 1
+	msg := fmt.Sprintf("<%d>1 %s %s devos %d - - [%s] %s",
+		facilityUser*8+syslogSeverity[entry.Level], entry.Time.UTC().Format(time.RFC3339), entry.Host,
+		os.Getpid(), entry.Caller, entry.Message)
+	if s.network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg // RFC 6587 octet counting
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.conn.Close()
+		if err := s.connect(); err != nil {
+			s.conn = nil
+			return err
+		}
+		_, err = s.conn.Write([]byte(msg))
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// fileSink appends lines to a single shared file, e.g. on a mounted volume
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newFileSink opens path for appending
+func newFileSink(path string) (*fileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file log sink requires a target path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log sink directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log sink file: %w", err)
+	}
+	return &fileSink{file: file}, nil
+}
+
+// Write appends the entry with its host, so several machines can share a file
+func (s *fileSink) Write(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintf(s.file, "%s %s\n", entry.Host, entry.line())
+	return err
+}
+
+// Close closes the file
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// encodeBatch renders a batch of entries as a request body
+type encodeBatch func(entries []Entry) ([]byte, error)
+
+// jsonBatch posts entries as a JSON array
+func jsonBatch(entries []Entry) ([]byte, error) {
+	return json.Marshal(entries)
+}
+
+// lokiBatch posts entries to Loki's push API, one stream per level
+func lokiBatch(labels map[string]string) encodeBatch {
+	return func(entries []Entry) ([]byte, error) {
+		type stream struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		}
+		streams := map[string]*stream{}
+		var order []string
+		for _, e := range entries {
+			st, ok := streams[e.Level]
+			if !ok {
+				st = &stream{Stream: map[string]string{"job": "devos", "host": e.Host, "level": e.Level}}
+				for k, v := range labels {
+					st.Stream[k] = v
+				}
+				streams[e.Level] = st
+				order = append(order, e.Level)
+			}
+			st.Values = append(st.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), "[" + e.Caller + "] " + e.Message})
+		}
+
+		push := struct {
+			Streams []*stream `json:"streams"`
+		}{}
+		for _, level := range order {
+			push.Streams = append(push.Streams, streams[level])
+		}
+		return json.Marshal(push)
+	}
+}
+
+// batchSink posts entries in the background, in batches, so a slow or
+// unreachable endpoint never stalls DevOS
+type batchSink struct {
+	url     string
+	headers map[string]string
+	encode  encodeBatch
+	client  *http.Client
+	done    chan struct{}
+	failing bool // Only the sender goroutine touches this
+
+	mu     sync.Mutex // Guards queue against sends after Close
+	queue  chan Entry
+	closed bool
+}
+
+// newBatchSink starts posting batches to endpoint
+func newBatchSink(endpoint string, headers map[string]string, encode encodeBatch) (*batchSink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid log sink URL: %q", endpoint)
+	}
+
+	s := &batchSink{
+		url:     endpoint,
+		headers: headers,
+		encode:  encode,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   make(chan Entry, sinkQueue),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues the entry, dropping it if the queue is full
+func (s *batchSink) Write(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("log sink closed")
+	}
+	select {
+	case s.queue <- entry:
+		return nil
+	default:
+		return fmt.Errorf("log sink queue full; dropped entry")
+	}
+}
+
+// Close flushes queued entries and stops the sender
+func (s *batchSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+// run sends a batch when it fills up or sinkInterval passes
+func (s *batchSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(sinkInterval)
+	defer ticker.Stop()
+
+	var batch []Entry
+	for {
+		select {
+		case entry, ok := <-s.queue:
+			if !ok {
+				s.send(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= sinkBatch {
+				s.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			s.send(batch)
+			batch = nil
+		}
+	}
+}
+
+// send posts one batch. The logger cannot log its own delivery errors, so
+// the first failure is reported on stderr, and then recovery.
+func (s *batchSink) send(batch []Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	err := s.post(batch)
+	if err != nil && !s.failing {
+		fmt.Fprintf(os.Stderr, "Warning: failed to ship logs to %s: %v\n", s.url, err)
+	} else if err == nil && s.failing {
+		fmt.Fprintf(os.Stderr, "Log shipping to %s recovered\n", s.url)
+	}
+	s.failing = err != nil
+}
+
+// post sends one batch to the endpoint
+func (s *batchSink) post(batch []Entry) error {
+	body, err := s.encode(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,524 @@
+package logsource
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"devos/internal/logger"
+	"devos/internal/timefmt"
+)
+
+// Syslog priorities; lower is more severe
+const (
+	PriorityCritical = 2
+	PriorityError    = 3
+	PriorityWarning  = 4
+	PriorityInfo     = 6
+)
+
+// defaultLines bounds how many entries a query returns
+const defaultLines = 200
+
+// errorWords mark plain-text log lines as errors
+var errorWords = regexp.MustCompile(`(?i)\b(error|err|fail(ed|ure)?|fatal|panic|crit(ical)?|segfault|killed|oom)\b`)
+
+// volatile matches the parts of a message that differ between repeats,
+// e.g. PIDs, ports, and hex addresses
+var volatile = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+
+// Query selects log entries
+type Query struct {
+	Unit   string    // Service/unit or event source; empty for all
+	Since  time.Time // Zero for no lower bound
+	Until  time.Time // Zero for no upper bound
+	Errors bool      // Only errors and worse
+	Lines  int       // Maximum entries, newest kept; 0 for the default
+	File   string    // Read this file instead of the system log
+	Self   bool      // Read DevOS's own log instead of the system log
+}
+
+// Entry is one log record
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"` // journald, eventlog, oslog, or a file path
+	Unit     string    `json:"unit,omitempty"`
+	Priority int       `json:"priority"`
+	Message  string    `json:"message"`
+}
+
+// Read returns entries matching q from the platform's system log, oldest first
+func Read(ctx context.Context, q Query) ([]Entry, error) {
+	if q.Lines <= 0 {
+		q.Lines = defaultLines
+	}
+	if q.File != "" {
+		return readFile(q.File, q)
+	}
+	if q.Self {
+		return readSelf(q)
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return readEventLog(ctx, q)
+	case "darwin":
+		return readOSLog(ctx, q)
+	default:
+		if _, err := exec.LookPath("journalctl"); err == nil {
+			return readJournal(ctx, q)
+		}
+		for _, path := range []string{"/var/log/syslog", "/var/log/messages"} {
+			if _, err := os.Stat(path); err == nil {
+				return readFile(path, q)
+			}
+		}
+		return nil, fmt.Errorf("no system log found (journalctl, /var/log/syslog, /var/log/messages)")
+	}
+}
+
+// ParseSince parses a time bound relative to now: a duration such as "2h"
+// or "3d", "today", "yesterday", or an absolute "2006-01-02[ 15:04]" or
+// RFC 3339 time
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	case "yesterday", "last night":
+		y, m, d := now.AddDate(0, 0, -1).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 2h, 3d, yesterday, or 2006-01-02 15:04)", s)
+}
+
+// readJournal queries systemd's journal with journalctl
+func readJournal(ctx context.Context, q Query) ([]Entry, error) {
+	args := []string{"-o", "json", "--no-pager", "-n", strconv.Itoa(q.Lines)}
+	if q.Unit != "" {
+		args = append(args, "-u", q.Unit)
+	}
+	if !q.Since.IsZero() {
+		args = append(args, "--since", q.Since.Format("2006-01-02 15:04:05"))
+	}
+	if !q.Until.IsZero() {
+		args = append(args, "--until", q.Until.Format("2006-01-02 15:04:05"))
+	}
+	if q.Errors {
+		args = append(args, "-p", "err")
+	}
+
+	out, err := exec.CommandContext(ctx, "journalctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl: %s", stderrText(err))
+	}
+
+	var entries []Entry
+	for _, line := range strings.Split(string(out), "\n") {
+		var record struct {
+			Timestamp string          `json:"__REALTIME_TIMESTAMP"`
+			Unit      string          `json:"_SYSTEMD_UNIT"`
+			Ident     string          `json:"SYSLOG_IDENTIFIER"`
+			Priority  string          `json:"PRIORITY"`
+			Message   json.RawMessage `json:"MESSAGE"`
+		}
+		if json.Unmarshal([]byte(line), &record) != nil {
+			continue
+		}
+		entry := Entry{Source: "journald", Unit: record.Unit, Priority: PriorityInfo, Message: journalMessage(record.Message)}
+		if entry.Unit == "" {
+			entry.Unit = record.Ident
+		}
+		if p, err := strconv.Atoi(record.Priority); err == nil {
+			entry.Priority = p
+		}
+		if usec, err := strconv.ParseInt(record.Timestamp, 10, 64); err == nil {
+			entry.Time = time.UnixMicro(usec)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// journalMessage decodes MESSAGE, which journald encodes as a byte array
+// when it is not valid UTF-8
+func journalMessage(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return strings.ToValidUTF8(string(b), "?")
+}
+
+// readEventLog queries the System and Application event logs with Get-WinEvent
+func readEventLog(ctx context.Context, q Query) ([]Entry, error) {
+	filter := "LogName='System','Application'"
+	if !q.Since.IsZero() {
+		filter += "; StartTime=[datetime]'" + q.Since.Format(time.RFC3339) + "'"
+	}
+	if !q.Until.IsZero() {
+		filter += "; EndTime=[datetime]'" + q.Until.Format(time.RFC3339) + "'"
+	}
+	if q.Errors {
+		filter += "; Level=1,2"
+	}
+
+	script := "Get-WinEvent -FilterHashtable @{" + filter + "} -ErrorAction SilentlyContinue"
+	if q.Unit != "" {
+		unit := strings.ReplaceAll(q.Unit, "'", "''")
+		script += " | Where-Object { $_.ProviderName -like '*" + unit + "*' -or $_.Message -like '*" + unit + "*' }"
+	}
+	script += " | Select-Object -First " + strconv.Itoa(q.Lines) +
+		" @{n='Time';e={$_.TimeCreated.ToString('o')}},ProviderName,Level,Message | ConvertTo-Json"
+
+	out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Get-WinEvent: %s", stderrText(err))
+	}
+
+	trimmed := strings.TrimSpace(string(out))
+	if strings.HasPrefix(trimmed, "{") {
+		trimmed = "[" + trimmed + "]" // A single result is not wrapped in an array
+	}
+	var records []struct {
+		Time         string
+		ProviderName string
+		Level        int // 1 critical, 2 error, 3 warning, 4 information
+		Message      string
+	}
+	if trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &records); err != nil {
+			return nil, fmt.Errorf("failed to parse Get-WinEvent output: %w", err)
+		}
+	}
+
+	levels := map[int]int{1: PriorityCritical, 2: PriorityError, 3: PriorityWarning}
+	entries := make([]Entry, 0, len(records))
+	for _, r := range records {
+		entry := Entry{Source: "eventlog", Unit: r.ProviderName, Priority: PriorityInfo, Message: r.Message}
+		if p, ok := levels[r.Level]; ok {
+			entry.Priority = p
+		}
+		entry.Time, _ = time.Parse(time.RFC3339Nano, r.Time)
+		entries = append(entries, entry)
+	}
+	// Get-WinEvent returns newest first
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// readOSLog queries the macOS unified log with log show
+func readOSLog(ctx context.Context, q Query) ([]Entry, error) {
+	args := []string{"show", "--style", "ndjson"}
+	if q.Since.IsZero() {
+		args = append(args, "--last", "1h")
+	} else {
+		args = append(args, "--start", q.Since.Format("2006-01-02 15:04:05"))
+	}
+	if !q.Until.IsZero() {
+		args = append(args, "--end", q.Until.Format("2006-01-02 15:04:05"))
+	}
+	var predicates []string
+	if q.Unit != "" {
+		unit := strings.ReplaceAll(q.Unit, `"`, `\"`)
+		predicates = append(predicates, `(process CONTAINS[c] "`+unit+`" OR subsystem CONTAINS[c] "`+unit+`")`)
+	}
+	if q.Errors {
+		predicates = append(predicates, `messageType IN {"error", "fault"}`)
+	}
+	if len(predicates) > 0 {
+		args = append(args, "--predicate", strings.Join(predicates, " AND "))
+	}
+
+	out, err := exec.CommandContext(ctx, "log", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("log show: %s", stderrText(err))
+	}
+
+	levels := map[string]int{"fault": PriorityCritical, "error": PriorityError}
+	var entries []Entry
+	for _, line := range strings.Split(string(out), "\n") {
+		var record struct {
+			Timestamp   string `json:"timestamp"`
+			Process     string `json:"processImagePath"`
+			MessageType string `json:"messageType"`
+			Message     string `json:"eventMessage"`
+		}
+		if json.Unmarshal([]byte(line), &record) != nil || record.Message == "" {
+			continue
+		}
+		entry := Entry{Source: "oslog", Unit: record.Process, Priority: PriorityInfo, Message: record.Message}
+		if i := strings.LastIndex(entry.Unit, "/"); i >= 0 {
+			entry.Unit = entry.Unit[i+1:]
+		}
+		if p, ok := levels[strings.ToLower(record.MessageType)]; ok {
+			entry.Priority = p
+		}
+		entry.Time, _ = time.Parse("2006-01-02 15:04:05.000000-0700", record.Timestamp)
+		entries = append(entries, entry)
+	}
+	return tail(entries, q.Lines), nil
+}
+
+// readFile tails a plain-text log such as /var/log/syslog. Lines whose
+// timestamp cannot be parsed are kept when they match the other filters.
+func readFile(path string, q Query) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || (q.Unit != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(q.Unit))) {
+			continue
+		}
+		entry := Entry{Source: path, Unit: q.Unit, Priority: PriorityInfo, Message: line}
+		if errorWords.MatchString(line) {
+			entry.Priority = PriorityError
+		}
+		if q.Errors && entry.Priority > PriorityError {
+			continue
+		}
+		if t, rest, ok := lineTime(line, now); ok {
+			if (!q.Since.IsZero() && t.Before(q.Since)) || (!q.Until.IsZero() && t.After(q.Until)) {
+				continue
+			}
+			entry.Time, entry.Message = t, rest
+		}
+		entries = append(entries, entry)
+		if len(entries) > 2*q.Lines {
+			entries = tail(entries, q.Lines)
+		}
+	}
+	return tail(entries, q.Lines), scanner.Err()
+}
+
+// selfLine matches a line of DevOS's own log: time, level, caller, message
+var selfLine = regexp.MustCompile(`^\[([^\]]+)\] \[(DEBUG|INFO|WARN|ERROR)\] \[([^\]]*)\] (.*)$`)
+
+// readSelf reads DevOS's own daily log files. Lines without a header, such
+// as the rest of a multi-line message, belong to the entry before them.
+func readSelf(q Query) ([]Entry, error) {
+	dir, err := logger.Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "devos-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var entries []Entry
+	for _, path := range files {
+		day, err := time.ParseInLocation("2006-01-02", strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "devos-"), ".log"), timefmt.Location())
+		if err == nil && !q.Since.IsZero() && day.AddDate(0, 0, 1).Before(q.Since) {
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var current *Entry
+		keep := func() {
+			if current != nil && (!q.Errors || current.Priority <= PriorityError) {
+				entries = append(entries, *current)
+			}
+			current = nil
+		}
+		for scanner.Scan() {
+			line := scanner.Text()
+			m := selfLine.FindStringSubmatch(line)
+			if m == nil {
+				if current != nil && strings.TrimSpace(line) != "" {
+					current.Message += "\n" + line
+				}
+				continue
+			}
+			keep()
+			t, err := time.ParseInLocation("2006-01-02 15:04:05 MST", m[1], timefmt.Location())
+			if err != nil || (!q.Since.IsZero() && t.Before(q.Since)) || (!q.Until.IsZero() && t.After(q.Until)) {
+				continue
+			}
+			if q.Unit != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(q.Unit)) {
+				continue
+			}
+			current = &Entry{Time: t, Source: path, Priority: selfPriority(m[2]), Message: fmt.Sprintf("%s [%s] %s", m[2], m[3], m[4])}
+		}
+		keep()
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(entries) > 2*q.Lines {
+			entries = tail(entries, q.Lines)
+		}
+	}
+	return tail(entries, q.Lines), nil
+}
+
+// selfPriority maps DevOS log levels to syslog priorities
+func selfPriority(level string) int {
+	switch level {
+	case "ERROR":
+		return PriorityError
+	case "WARN":
+		return PriorityWarning
+	default:
+		return PriorityInfo
+	}
+}
+
+// LastError returns the most recent error entry, or nil when there is none
+func LastError(entries []Entry) *Entry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Priority <= PriorityError {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// lineTime parses a leading RFC 3339 or classic syslog ("Jan _2 15:04:05")
+// timestamp, returning it and the rest of the line
+func lineTime(line string, now time.Time) (time.Time, string, bool) {
+	if field, rest, ok := strings.Cut(line, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, field); err == nil {
+			return t, rest, true
+		}
+	}
+	if len(line) > 15 {
+		if t, err := time.ParseInLocation(time.Stamp, line[:15], now.Location()); err == nil {
+			// Syslog omits the year; assume the most recent occurrence
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, strings.TrimSpace(line[15:]), true
+		}
+	}
+	return time.Time{}, line, false
+}
+
+// tail returns the last n entries
+func tail(entries []Entry, n int) []Entry {
+	if len(entries) > n {
+		return entries[len(entries)-n:]
+	}
+	return entries
+}
+
+// Summarize condenses entries into at most max lines for display and for
+// the AI engine: repeated messages are collapsed with a count, and when
+// there are too many, the most severe and most recent are kept
+func Summarize(entries []Entry, max int) string {
+	type group struct {
+		first, last Entry
+		count       int
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for _, e := range entries {
+		key := e.Unit + "\x00" + volatile.ReplaceAllString(e.Message, "#")
+		if g, ok := byKey[key]; ok {
+			g.count++
+			g.last = e
+			continue
+		}
+		g := &group{first: e, last: e, count: 1}
+		byKey[key] = g
+		groups = append(groups, g)
+	}
+
+	if len(groups) > max {
+		ranked := make([]*group, len(groups))
+		copy(ranked, groups)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if ranked[i].first.Priority != ranked[j].first.Priority {
+				return ranked[i].first.Priority < ranked[j].first.Priority
+			}
+			return ranked[i].last.Time.After(ranked[j].last.Time)
+		})
+		keep := map[*group]bool{}
+		for _, g := range ranked[:max] {
+			keep[g] = true
+		}
+		var kept []*group
+		for _, g := range groups {
+			if keep[g] {
+				kept = append(kept, g)
+			}
+		}
+		groups = kept
+	}
+
+	var out strings.Builder
+	for _, g := range groups {
+		if !g.first.Time.IsZero() {
+			out.WriteString(timefmt.In(g.first.Time).Format("2006-01-02 15:04:05") + " ")
+		}
+		if g.first.Unit != "" {
+			out.WriteString(g.first.Unit + ": ")
+		}
+		out.WriteString(strings.TrimSpace(g.first.Message))
+		if g.count > 1 {
+			fmt.Fprintf(&out, " (×%d", g.count)
+			if !g.last.Time.IsZero() {
+				out.WriteString(", last " + timefmt.In(g.last.Time).Format("15:04:05"))
+			}
+			out.WriteString(")")
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// stderrText returns the stderr of a failed command, or the error itself
+func stderrText(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return strings.TrimSpace(string(exitErr.Stderr))
+	}
+	return err.Error()
+}
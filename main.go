@@ -2,15 +2,76 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"devos/internal/a11y"
+	"devos/internal/audit"
+	"devos/internal/cigen"
+	"devos/internal/cloudcost"
 	"devos/internal/config"
+	"devos/internal/configfmt"
+	"devos/internal/daemon"
+	"devos/internal/dag"
 	"devos/internal/executor"
+	"devos/internal/grpcreflect"
+	"devos/internal/helm"
+	"devos/internal/issues"
+	"devos/internal/lineedit"
 	"devos/internal/logger"
+	"devos/internal/logsource"
+	"devos/internal/memory"
+	"devos/internal/models"
+	"devos/internal/monitor"
+	"devos/internal/netdiag"
+	"devos/internal/ollama"
+	"devos/internal/openapi"
+	"devos/internal/platform"
+	"devos/internal/policy"
+	"devos/internal/privacy"
+	"devos/internal/profile"
+	"devos/internal/project"
+	"devos/internal/projectlock"
+	"devos/internal/report"
+	"devos/internal/runbook"
+	"devos/internal/services"
+	"devos/internal/simulate"
+	"devos/internal/sshsetup"
+	"devos/internal/table"
+	"devos/internal/timefmt"
+	"devos/internal/vault"
 )
 
+// Bracketed paste control sequences (xterm and compatibles)
+const (
+	pasteEnable  = "\x1b[?2004h"
+	pasteDisable = "\x1b[?2004l"
+	pasteStart   = "\x1b[200~"
+	pasteEnd     = "\x1b[201~"
+)
+
+// simulateTimeout bounds a plan rehearsal, including pulling the image
+const simulateTimeout = 15 * time.Minute
+
 const (
 	Version = "0.1.0"
 	Banner  = `
@@ -34,6 +95,42 @@ type CLI struct {
 	config   *config.Config
 	executor *executor.Executor
 	logger   *logger.Logger
+	memory   memory.MemoryStore
+	audit    *audit.Trail
+	scanner  *bufio.Scanner
+
+	// daemon is the running daemon this REPL is attached to, if any; plans
+	// are then generated, approved, and run there
+	daemon *daemon.Client
+
+	// lastInput and lastPlan are the latest request and its plan, for the
+	// "good" and "bad" ratings and "try again"
+	lastInput string
+	lastPlan  *executor.ExecutionResult
+
+	// replacing is the plan the next one regenerates, to show what changed
+	replacing *executor.ExecutionResult
+
+	// background runs the plan being processed as a background job
+	background bool
+
+	// checkpoints are the session states saved with "checkpoint save", in
+	// the order they were saved
+	checkpoints []*checkpoint
+
+	lastActive time.Time // Last user input, for the idle timeout
+	locked     bool      // Set after an idle timeout until the user re-confirms
+	modelTuned bool      // The local model and context length were fitted to the hardware
+
+	restoreOutput func() // Flushes accessible output; call before exiting
+
+	// editor reads prompts with suggestions from history; nil reads plain
+	// lines from the scanner
+	editor  *lineedit.Editor
+	history *lineedit.History
+
+	// streamed is the plan explanation already shown as it was generated
+	streamed string
 }
 
 func NewCLI() (*CLI, error) {
@@ -43,62 +140,581 @@ func NewCLI() (*CLI, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Render times in the configured timezone and locale; everything is stored in UTC
+	if err := timefmt.Configure(cfg.Timezone, cfg.Locale); err != nil {
+		return nil, fmt.Errorf("%w: %w", config.ErrInvalidConfig, err)
+	}
+
 	// Initialize logger
 	log := logger.New(cfg.LogLevel)
+	for _, s := range cfg.LogSinks {
+		sink, err := logger.OpenSink(logger.SinkOptions{Type: s.Type, Target: s.Target, Level: s.Level, Labels: s.Labels, Headers: s.Headers})
+		if err != nil {
+			// Local logging still works; a missing sink should not block DevOS
+			fmt.Fprintf(os.Stderr, "Warning: log sink %s: %v\n", s.Type, err)
+			continue
+		}
+		log.AddSink(sink, s.Level)
+	}
+
+	// Initialize memory store
+	mem, err := memory.OpenBackend(cfg.MemoryBackend, cfg.MemoryPath, cfg.MemoryDSN, cfg.MemorySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory: %w", err)
+	}
+
+	// Initialize audit trail
+	trail, err := audit.Open(cfg.AuditPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if cfg.MemoryBackend == memory.BackendPostgres {
+		// A shared database keeps every user's audit events in one place
+		trail.AddSink(mem)
+	}
+	for _, e := range cfg.AuditExports {
+		exporter, err := audit.NewExporter(audit.ExportOptions{Type: e.Type, Target: e.Target, Format: e.Format, Token: e.Token, Index: e.Index, Headers: e.Headers, Version: Version})
+		if err != nil {
+			// The local audit log still works; a missing export should not block DevOS
+			fmt.Fprintf(os.Stderr, "Warning: audit export %s: %v\n", e.Type, err)
+			continue
+		}
+		trail.Export(exporter)
+	}
 
 	// Initialize executor
-	exec, err := executor.New(cfg, log)
+	exec, err := executor.New(cfg, log, mem, trail)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize executor: %w", err)
 	}
 
+	// Plain output for screen readers
+	restore := func() {}
+	if accessibleOutput(cfg.Accessible) {
+		if r, err := a11y.Enable(); err != nil {
+			log.Warn("%v", err)
+		} else {
+			restore = r
+		}
+	}
+
 	return &CLI{
-		config:   cfg,
-		executor: exec,
-		logger:   log,
+		config:        cfg,
+		executor:      exec,
+		logger:        log,
+		memory:        mem,
+		audit:         trail,
+		scanner:       bufio.NewScanner(os.Stdin),
+		restoreOutput: restore,
+		lastActive:    time.Now(),
 	}, nil
 }
 
+// accessibleOutput decides whether to write plain screen-reader output
+func accessibleOutput(mode string) bool {
+	switch mode {
+	case a11y.ModeOn:
+		return true
+	case a11y.ModeOff:
+		return false
+	default:
+		return platform.ScreenReader()
+	}
+}
+
+// startEditor turns on suggestions from earlier prompts and snippets as
+// the user types, when the terminal and settings allow it
+func (c *CLI) startEditor() {
+	switch c.config.Autosuggest {
+	case "off":
+		return
+	case "", "auto":
+		// Screen readers would read the suggestion as if it were typed
+		if a11y.Enabled() {
+			return
+		}
+	}
+	if !lineedit.Supported(os.Stdin) {
+		return
+	}
+
+	lines := c.snippets()
+	tasks, err := c.memory.TasksSince(time.Time{})
+	if err != nil {
+		c.logger.Warn("No suggestions from history: %v", err)
+	}
+	for _, task := range tasks {
+		lines = append(lines, task.Input)
+	}
+	c.history = lineedit.NewHistory(lines)
+	c.editor = &lineedit.Editor{In: os.Stdin, Out: os.Stdout, Suggest: c.history.Suggest}
+}
+
+// snippets returns the prompts saved in the snippets directory, one per
+// line; lines starting with # are comments
+func (c *CLI) snippets() []string {
+	dir := filepath.Join(c.config.Dir(), "snippets")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			c.logger.Warn("Skipping snippet %s: %v", entry.Name(), err)
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
 func (c *CLI) Start() error {
 	fmt.Printf(Banner, Version)
 	fmt.Println("\n🚀 DevOS is ready. Type 'help' for commands or use natural language.")
 	fmt.Println("💡 Examples:")
 	fmt.Println("   - setup fastapi project with docker")
 	fmt.Println("   - analyze system performance")
-	fmt.Println("   - fix build error\n")
+	fmt.Print("   - fix build error\n\n")
+
+	// Ask the terminal to bracket pastes so a multi-line paste arrives as
+	// one prompt instead of one AI call per line
+	if isTerminal(os.Stdin) {
+		fmt.Print(pasteEnable)
+		defer fmt.Print(pasteDisable)
+	}
+	c.startEditor()
+
+	if c.config.DaemonAttach != "never" {
+		client, err := daemon.Dial(context.Background(), c.config)
+		switch {
+		case err == nil:
+			c.daemon = client
+			c.audit.Record("daemon_attached", map[string]string{"socket": client.Socket, "as": client.User})
+			fmt.Printf("🔗 Attached to the DevOS daemon at %s as %s; plans and approvals are shared\n\n", client.Socket, client.User)
+		case !errors.Is(err, daemon.ErrNotRunning):
+			fmt.Printf("⚠️  Not attaching to the running daemon: %v\n\n", err)
+		}
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
+	if c.config.AIProvider == "ollama" && c.daemon == nil {
+		c.tuneLocalModel(context.Background())
+		c.checkModel(context.Background())
+	}
 
+	c.lastActive = time.Now()
 	for {
-		fmt.Print("devos> ")
-		if !scanner.Scan() {
+		c.notifyFinishedTasks()
+		fmt.Print(c.prompt())
+		input, multiline, ok := c.readInput()
+		if !ok {
 			break
 		}
-
-		input := strings.TrimSpace(scanner.Text())
+		c.checkIdle()
+		if c.locked {
+			c.confirmResume()
+			continue
+		}
 		if input == "" {
 			continue
 		}
+		if c.history != nil && !multiline {
+			c.history.Add(input)
+		}
 
 		// Handle built-in commands
-		if c.handleBuiltinCommand(input) {
+		if !multiline && c.handleBuiltinCommand(input) {
 			continue
 		}
 
-		// Process natural language command through AI engine
-		if err := c.processCommand(input); err != nil {
+		// Process natural language command through AI engine; Ctrl-C cancels
+		// the running command instead of exiting DevOS
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		input, c.background = backgroundRequest(input)
+		err := c.processCommand(ctx, input)
+		c.background = false
+		stop()
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("\n⏹️  Interrupted")
+			continue
+		}
+		if err != nil {
 			c.logger.Error("Command execution failed: %v", err)
 			fmt.Printf("❌ Error: %v\n", err)
 		}
 	}
 
-	return scanner.Err()
+	c.stopJobs()
+	return c.scanner.Err()
+}
+
+// prompt returns the REPL prompt, flagging the active environment (in
+// capitals when protected) and elevated sessions
+func (c *CLI) prompt() string {
+	var flags []string
+	if env := c.config.ActiveEnvironment(); env != nil {
+		if env.Protected {
+			flags = append(flags, "🔴 "+strings.ToUpper(env.Name))
+		} else {
+			flags = append(flags, "🌍 "+env.Name)
+		}
+	}
+	if expires, ok := c.executor.Elevated(); ok {
+		flags = append(flags, fmt.Sprintf("🔓 %s", time.Until(expires).Round(time.Minute)))
+	} else if c.config.ObserveMode {
+		flags = append(flags, "👁")
+	}
+	if c.config.DryRun {
+		flags = append(flags, "🧪 dry-run")
+	}
+	if len(flags) == 0 {
+		return "devos> "
+	}
+	return "devos[" + strings.Join(flags, " ") + "]> "
 }
 
 func (c *CLI) handleBuiltinCommand(input string) bool {
+	fields := strings.Fields(input)
+	if m := releasePhrase.FindStringSubmatch(strings.TrimSpace(input)); m != nil {
+		fields = []string{"release", m[1]}
+	}
+	switch strings.ToLower(fields[0]) {
+	case "unlock":
+		if len(fields) > 1 && !startsWithDigit(fields[1]) {
+			return false
+		}
+		if err := c.unlock(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "rollout":
+		if len(fields) < 2 {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.rollout(ctx, strings.Join(fields[1:], " ")); err != nil {
+			c.logger.Error("Rollout failed: %v", err)
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "release":
+		// Only a version may follow, so "release the lock on my project"
+		// still reaches the AI
+		if len(fields) != 2 {
+			return false
+		}
+		if _, ok := executor.ReleaseVersion(fields[1]); !ok {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.release(ctx, fields[1:]); err != nil {
+			c.logger.Error("Release failed: %v", err)
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "repos":
+		// Only a repo group or directories may follow, so "repos with stale
+		// branches" still reaches the AI
+		if len(fields) > 1 && !c.repoSpec(fields[1]) {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.repos(ctx, fields[1:]); err != nil {
+			c.logger.Error("Multi-repo task failed: %v", err)
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "observe":
+		if len(fields) > 2 {
+			return false
+		}
+		c.observe(fields[1:])
+		return true
+	case "dryrun":
+		if len(fields) > 2 {
+			return false
+		}
+		c.dryRun(fields[1:])
+		return true
+	case "speech":
+		if len(fields) > 2 {
+			return false
+		}
+		c.speech(fields[1:])
+		return true
+	case "cd":
+		dir, err := c.executor.Chdir(strings.Trim(strings.TrimSpace(input[len(fields[0]):]), `"'`))
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return true
+		}
+		fmt.Printf("📂 %s\n", dir)
+		return true
+	case "pwd":
+		if len(fields) > 1 {
+			return false
+		}
+		if dir, err := os.Getwd(); err == nil {
+			fmt.Printf("📂 %s\n", dir)
+		}
+		return true
+	case "checkpoint", "checkpoints":
+		if len(fields) > 1 && !slices.Contains([]string{"save", "restore", "list", "delete"}, fields[1]) {
+			return false
+		}
+		if err := c.checkpoint(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "timeout":
+		if len(fields) > 2 {
+			return false
+		}
+		if err := c.commandTimeout(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "policy":
+		if len(fields) < 2 || (fields[1] != "stats" && fields[1] != "allow") {
+			return false
+		}
+		if err := c.policy(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "freezes":
+		if len(fields) > 1 {
+			return false
+		}
+		if err := c.showFreezes(context.Background()); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "budget":
+		if len(fields) > 1 {
+			return false
+		}
+		c.showBudgets()
+		return true
+	case "good", "bad":
+		// "bad gateway from nginx" is a request unless there is a plan to rate
+		rating := strings.ToLower(fields[0])
+		if len(fields) > 1 && (rating == memory.RatingGood || c.lastPlan == nil) {
+			return false
+		}
+		reason := strings.TrimSpace(strings.TrimPrefix(input, fields[0]))
+		if err := c.rate(rating, reason); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "feedback":
+		if len(fields) > 1 && fields[1] != "list" && fields[1] != "export" && !strings.HasPrefix(fields[1], "-") {
+			return false
+		}
+		if err := c.feedback(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "env":
+		if len(fields) > 1 && fields[1] != "list" && fields[1] != "use" && fields[1] != "off" && !strings.HasPrefix(fields[1], "-") {
+			return false
+		}
+		if err := c.environment(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "graph":
+		if len(fields) > 1 && !slices.Contains(dag.Formats, fields[1]) && !strings.HasPrefix(fields[1], "-") {
+			return false
+		}
+		if err := c.graph(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "undo":
+		if len(fields) > 1 {
+			return false
+		}
+		if err := c.undo(context.Background()); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "locks":
+		if len(fields) > 1 && !strings.HasPrefix(fields[1], "-") {
+			return false
+		}
+		if err := c.showLocks(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "force-unlock":
+		if len(fields) > 2 {
+			return false
+		}
+		if err := c.forceUnlock(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "config":
+		if len(fields) > 1 && fields[1] != "convert" && fields[1] != "encrypt" && fields[1] != "decrypt" {
+			return false
+		}
+		if err := c.configCommand(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "privacy":
+		if len(fields) > 1 && fields[1] != "list" && fields[1] != "local" && fields[1] != "cloud" {
+			return false
+		}
+		if err := c.privacy(fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "issue":
+		if len(fields) > 1 && fields[1] != "list" && fields[1] != "show" && fields[1] != "work" && fields[1] != "comment" {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.issue(ctx, fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "plans":
+		if len(fields) > 1 && fields[1] != "show" && fields[1] != "approve" && fields[1] != "reject" {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.daemonPlans(ctx, fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "models":
+		if len(fields) > 1 && !strings.HasPrefix(fields[1], "-") && !slices.Contains([]string{"list", "pull", "remove", "rm", "verify"}, fields[1]) {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.models(ctx, fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "api":
+		if len(fields) > 1 && !slices.Contains([]string{"list", "add", "show", "remove", "rm"}, fields[1]) {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.api(ctx, fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "runbook":
+		if len(fields) > 1 && fields[1] != "list" && fields[1] != "show" && fields[1] != "run" && fields[1] != "customize" {
+			return false
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.runbook(ctx, fields[1:]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "open":
+		if len(fields) != 2 || (!strings.Contains(fields[1], "://") && !fileExists(fields[1])) {
+			return false
+		}
+		if err := platform.Open(fields[1]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "bg":
+		if len(fields) > 1 {
+			return false
+		}
+		fmt.Println("Usage: bg <task> (or end the request with &)")
+		return true
+	case "fg", "tail", "kill":
+		// Only job IDs may follow, so "kill the process on port 3000" and
+		// "tail the nginx log" still reach the AI
+		args := fields[1:]
+		follow := strings.ToLower(fields[0]) == "tail" && len(args) > 0 && args[0] == "-f"
+		if follow {
+			args = args[1:]
+		}
+		if len(args) > 1 || (len(args) == 1 && !jobID.MatchString(args[0])) || (len(args) == 0 && strings.ToLower(fields[0]) != "fg") {
+			return false
+		}
+		id := ""
+		if len(args) == 1 {
+			id = args[0]
+		}
+		if err := c.job(strings.ToLower(fields[0]), id, follow); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "attach":
+		if len(fields) != 2 {
+			return false
+		}
+		if err := c.attach(fields[1]); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "status", "tasks", "jobs", "history", "plugins":
+		// Only flags may follow, so "status of nginx" still reaches the AI
+		if !onlyFlags(fields[1:]) {
+			return false
+		}
+		var err error
+		switch strings.ToLower(fields[0]) {
+		case "status":
+			err = c.showStatus(fields[1:])
+		case "tasks":
+			err = c.showTasks(fields[1:])
+		case "jobs":
+			err = c.showJobs(fields[1:])
+		case "history":
+			err = c.showHistory(fields[1:])
+		case "plugins":
+			err = c.showPlugins(fields[1:])
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "lock":
+		if len(fields) > 1 {
+			return false
+		}
+		c.executor.Lock()
+		fmt.Println("🔒 Session locked; all policy rules are enforced")
+		return true
+	}
+
 	switch strings.ToLower(input) {
 	case "exit", "quit", "q":
+		c.stopJobs()
 		fmt.Println("👋 Goodbye!")
+		if isTerminal(os.Stdin) {
+			fmt.Print(pasteDisable)
+		}
+		c.memory.Close()
+		c.audit.Close()
+		c.restoreOutput()
+		c.logger.Close()
 		os.Exit(0)
 		return true
 	case "help", "h":
@@ -107,70 +723,4509 @@ func (c *CLI) handleBuiltinCommand(input string) bool {
 	case "version", "v":
 		fmt.Printf("DevOS version %s\n", Version)
 		return true
-	case "status":
-		c.showStatus()
+	case "targets":
+		c.showTargets()
 		return true
-	case "config":
-		c.showConfig()
+	case "resume":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := c.resume(ctx); err != nil {
+			c.logger.Error("Resume failed: %v", err)
+			fmt.Printf("❌ Error: %v\n", err)
+		}
+		return true
+	case "report":
+		if err := c.runReport(nil); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+		}
 		return true
 	default:
 		return false
 	}
 }
 
-func (c *CLI) processCommand(input string) error {
-	c.logger.Info("Processing command: %s", input)
+// showTasks lists the long-running commands DevOS started in tmux
+func (c *CLI) showTasks(args []string) error {
+	flags := flag.NewFlagSet("tasks", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
-	// Execute through AI engine
-	result, err := c.executor.Execute(input)
+	tasks, err := c.executor.Tasks()
 	if err != nil {
 		return err
 	}
+	if len(tasks) == 0 && *format == table.FormatTable {
+		fmt.Println("No tasks (set \"tmux\": true in config.json to run long commands as tasks)")
+		return nil
+	}
+	t := table.New("name", "state", "exit_code", "started", "command")
+	for _, task := range tasks {
+		state := "running"
+		switch {
+		case task.Running:
+		case task.ExitCode == 0:
+			state = "done"
+		case task.ExitCode < 0:
+			state = "killed"
+		default:
+			state = "failed"
+		}
+		var exitCode interface{}
+		if !task.Running {
+			exitCode = task.ExitCode
+		}
+		t.Add(task.Name, state, exitCode, task.Started, task.Command)
+	}
+	return render(t, *format, *columns)
+}
 
-	// Display result
-	fmt.Printf("\n%s\n", result.Output)
+// releasePhrase matches asking for a release in words, e.g. "cut a release
+// 1.4.0" or "ship version v2.0.0-rc.1", capturing the version
+var releasePhrase = regexp.MustCompile(`(?i)^(?:cut|make|do|ship|create|prepare|tag)\s+(?:a\s+|the\s+)?(?:new\s+)?(?:release|version)\s+(?:of\s+)?(?:version\s+)?(v?\d+\.\d+\.\d+\S*)$`)
 
-	if result.NeedsConfirmation {
-		fmt.Print("\n⚠️  Proceed with execution? (yes/no): ")
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() {
-			response := strings.ToLower(strings.TrimSpace(scanner.Text()))
-			if response != "yes" && response != "y" {
-				fmt.Println("❌ Operation cancelled")
-				return nil
-			}
-		}
+// jobID matches a background job's ID, optionally written %ID
+var jobID = regexp.MustCompile(`^%?[0-9]+$`)
+
+// backgroundRequest returns the request an input asks to run in the
+// background, as "bg <task>" or "<task> &", and whether it does
+func backgroundRequest(input string) (string, bool) {
+	trimmed := strings.TrimSpace(input)
+	if task, ok := strings.CutPrefix(trimmed, "bg "); ok && strings.TrimSpace(task) != "" {
+		return strings.TrimSpace(task), true
+	}
+	if task, ok := strings.CutSuffix(trimmed, "&"); ok && !strings.HasSuffix(task, "&") && strings.TrimSpace(task) != "" {
+		return strings.TrimSpace(task), true
 	}
+	return input, false
+}
 
-	if len(result.Commands) > 0 {
-		fmt.Println("\n📋 Executing commands:")
-		for _, cmd := range result.Commands {
-			fmt.Printf("  → %s\n", cmd)
+// showJobs lists the session's background jobs
+func (c *CLI) showJobs(args []string) error {
+	flags := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	jobs := c.executor.Jobs()
+	list := jobs.List()
+	if len(list) == 0 && *format == table.FormatTable {
+		fmt.Println("No jobs (run a task in the background with: bg <task>, or <task> &)")
+		return nil
+	}
+	t := table.New("id", "state", "exit_code", "started", "command")
+	for _, job := range list {
+		var exitCode interface{}
+		if !job.Running() {
+			exitCode = job.ExitCode()
 		}
+		t.Add(job.ID, jobs.State(job), exitCode, job.Started, job.Command())
+	}
+	return render(t, *format, *columns)
+}
+
+// job runs the fg, tail, and kill builtins on a background job. fg and
+// tail -f follow its output until it finishes; Ctrl-C stops a job in the
+// foreground, as in a shell, but only stops tail -f following it.
+func (c *CLI) job(action, id string, follow bool) error {
+	jobs := c.executor.Jobs()
+	job, err := jobs.Get(id)
+	if err != nil {
+		return err
+	}
 
-		if err := c.executor.ExecuteCommands(result.Commands); err != nil {
+	switch action {
+	case "kill":
+		if err := jobs.Kill(job); err != nil {
 			return err
 		}
+		c.audit.Record("job_killed", map[string]string{"job": strconv.Itoa(job.ID), "commands": strings.Join(job.Commands, "\n")})
+		fmt.Printf("⏹️  Killed job %d: %s\n", job.ID, job.Command())
+		c.recordJob(job)
+		return nil
+	case "fg":
+		follow = true
+		fmt.Printf("%s\n", job.Command())
+	}
 
-		fmt.Println("\n✅ Execution completed successfully")
+	lines := 20
+	if follow {
+		lines = 10
+	}
+	tail, err := jobs.Tail(job, lines)
+	if err != nil {
+		return err
+	}
+	fmt.Print(tail)
+	if !follow || !job.Running() {
+		if !job.Running() {
+			fmt.Printf("[job %d %s, exit %d]\n", job.ID, jobs.State(job), job.ExitCode())
+		}
+		return nil
 	}
 
-	return nil
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = jobs.Follow(ctx, job, os.Stdout)
+	if errors.Is(err, context.Canceled) {
+		if action == "fg" {
+			return c.job("kill", strconv.Itoa(job.ID), false)
+		}
+		fmt.Println()
+		return nil
+	}
+	if err == nil {
+		fmt.Printf("[job %d %s, exit %d]\n", job.ID, jobs.State(job), job.ExitCode())
+	}
+	return err
 }
 
-func (c *CLI) showHelp() {
-	help := `
+// stopJobs kills the background jobs still running when the session ends
+func (c *CLI) stopJobs() {
+	var running []*executor.Job
+	for _, job := range c.executor.Jobs().List() {
+		if job.Running() {
+			running = append(running, job)
+		}
+	}
+	if len(running) > 0 {
+		fmt.Printf("⏹️  Stopping %s\n", plural(len(running), "background job"))
+	}
+	c.executor.Jobs().Close()
+	for _, job := range running {
+		c.recordJob(job)
+	}
+}
+
+// recordJob records a finished or killed background job as a task, so
+// "undo" reverses the commands it completed
+func (c *CLI) recordJob(job *executor.Job) {
+	select {
+	case <-job.Done():
+	default:
+		return
+	}
+	completed, rollback, irreversible := job.Outcome()
+	result := &executor.ExecutionResult{Commands: job.Commands, Rollback: rollback, Irreversible: irreversible}
+	c.recordTask(job.Prompt, result, completed, completed == len(job.Commands), time.Since(job.Started))
+	if len(rollback) > 0 {
+		fmt.Printf("↩️  Run \"undo\" to reverse job %d\n", job.ID)
+	}
+}
+
+// attach shows a running task live, or the output captured when it finished
+func (c *CLI) attach(name string) error {
+	task, err := c.executor.Task(name)
+	if err != nil {
+		return err
+	}
+	if task.Running {
+		return c.executor.Attach(task)
+	}
+
+	output, err := os.ReadFile(task.Output)
+	if err != nil {
+		return fmt.Errorf("task %s finished but its output was not captured", name)
+	}
+	fmt.Printf("📄 %s (exit %d): %s\n", task.Name, task.ExitCode, task.Command)
+	fmt.Print(strings.TrimRight(string(output), "\n") + "\n")
+	return nil
+}
+
+// notifyFinishedTasks reports tmux tasks and background jobs that finished
+// since the last prompt
+func (c *CLI) notifyFinishedTasks() {
+	for _, job := range c.executor.Jobs().Finished() {
+		icon := "✅"
+		if job.ExitCode() != 0 {
+			icon = "❌"
+		}
+		fmt.Printf("%s Job %d finished (exit %d): %s; \"tail %d\" shows its output\n", icon, job.ID, job.ExitCode(), job.Command(), job.ID)
+		c.recordJob(job)
+		if c.config.Speech.Speaks(config.SpeakBackground) {
+			outcome := "finished"
+			if job.ExitCode() != 0 {
+				outcome = fmt.Sprintf("failed with exit code %d", job.ExitCode())
+			}
+			c.say(fmt.Sprintf("Background job %d %s.", job.ID, outcome))
+		}
+	}
+	for _, task := range c.executor.FinishedTasks() {
+		icon := "✅"
+		if task.ExitCode != 0 {
+			icon = "❌"
+		}
+		fmt.Printf("%s Task %s finished (exit %d); \"attach %s\" shows its output\n", icon, task.Name, task.ExitCode, task.Name)
+		if c.config.Speech.Speaks(config.SpeakBackground) {
+			outcome := "finished"
+			if task.ExitCode != 0 {
+				outcome = fmt.Sprintf("failed with exit code %d", task.ExitCode)
+			}
+			c.say(fmt.Sprintf("Background task %s %s.", task.Name, outcome))
+		}
+	}
+}
+
+// speech shows, toggles, or tests spoken summaries for this session
+func (c *CLI) speech(args []string) {
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "on", "off":
+			if c.config.Speech == nil {
+				c.config.Speech = &config.Speech{}
+			}
+			c.config.Speech.Enabled = strings.ToLower(args[0]) == "on"
+		case "test":
+			if err := platform.Speak("DevOS speech is working."); err != nil {
+				fmt.Printf("❌ %v\n", err)
+			}
+			return
+		default:
+			fmt.Println("Usage: speech [on|off|test]")
+			return
+		}
+	}
+
+	if s := c.config.Speech; s != nil && s.Enabled {
+		events := "all outcomes"
+		if len(s.Events) > 0 {
+			events = strings.Join(s.Events, ", ")
+		}
+		fmt.Printf("🔊 Speech on: %s (long tasks: over %s)\n", events, s.LongTaskDuration())
+	} else {
+		fmt.Println("🔇 Speech off")
+	}
+}
+
+// speakOutcome reads a short summary of a finished task aloud when its
+// outcome is one of the configured speech events
+func (c *CLI) speakOutcome(input string, executed int, err error, duration time.Duration) {
+	s := c.config.Speech
+	long := duration >= s.LongTaskDuration()
+	switch {
+	case err != nil && (s.Speaks(config.SpeakFailure) || long && s.Speaks(config.SpeakLong)):
+		message, _, _ := strings.Cut(err.Error(), "\n")
+		c.say(fmt.Sprintf("Task failed after %s: %s. %s", spokenDuration(duration), spokenInput(input), spokenInput(message)))
+	case err == nil && (s.Speaks(config.SpeakSuccess) || long && s.Speaks(config.SpeakLong)):
+		c.say(fmt.Sprintf("Task done in %s: %s. %s ran.", spokenDuration(duration), spokenInput(input), plural(executed, "command")))
+	}
+}
+
+// say speaks text, logging rather than printing failures so a missing
+// synthesizer does not clutter every result
+func (c *CLI) say(text string) {
+	if err := platform.Speak(text); err != nil {
+		c.logger.Warn("Speech failed: %v", err)
+	}
+}
+
+// spokenInput shortens text to a phrase that is quick to listen to
+func spokenInput(text string) string {
+	const max = 80
+	if len(text) <= max {
+		return text
+	}
+	if i := strings.LastIndex(text[:max], " "); i > 0 {
+		return text[:i]
+	}
+	return text[:max]
+}
+
+// spokenDuration renders a duration in words, e.g. "2 minutes 5 seconds"
+func spokenDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds < 60 {
+		return plural(seconds, "second")
+	}
+	minutes, seconds := seconds/60, seconds%60
+	if seconds == 0 {
+		return plural(minutes, "minute")
+	}
+	return plural(minutes, "minute") + " " + plural(seconds, "second")
+}
+
+// plural renders a count with its noun, e.g. "1 second" or "3 seconds"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// observe shows or toggles observation mode
+func (c *CLI) observe(args []string) {
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			c.config.ObserveMode = true
+		case "off":
+			c.config.ObserveMode = false
+		default:
+			fmt.Println("Usage: observe [on|off]")
+			return
+		}
+		c.audit.Record("observe_mode_changed", map[string]string{"enabled": fmt.Sprint(c.config.ObserveMode)})
+	}
+
+	if c.config.ObserveMode {
+		fmt.Println("👁️  Observation mode on: only read-only commands run without approval")
+	} else {
+		fmt.Println("👁️  Observation mode off")
+	}
+}
+
+// dryRun turns dry-run mode on or off, or shows whether it is on
+func (c *CLI) dryRun(args []string) {
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			c.config.DryRun = true
+		case "off":
+			c.config.DryRun = false
+		default:
+			fmt.Println("Usage: dryrun [on|off]")
+			return
+		}
+		c.audit.Record("dry_run_changed", map[string]string{"enabled": fmt.Sprint(c.config.DryRun)})
+	}
+
+	if c.config.DryRun {
+		fmt.Println("🧪 Dry-run mode on: plans show the commands they would run, and nothing runs")
+	} else {
+		fmt.Println("🧪 Dry-run mode off")
+	}
+}
+
+// commandTimeout shows or changes, for this session, how long a command may
+// run before it is stopped: timeout [<duration>|off]
+func (c *CLI) commandTimeout(args []string) error {
+	if len(args) == 1 {
+		seconds, err := timeoutSeconds(args[0])
+		if err != nil {
+			return fmt.Errorf("usage: timeout [<duration>|off]  (e.g. timeout 30m)")
+		}
+		c.config.CommandTimeout = seconds
+	}
+
+	switch seconds := c.config.CommandTimeout; {
+	case seconds < 0:
+		fmt.Println("⏱️  Commands run without a time limit")
+	case seconds == 0:
+		fmt.Println("⏱️  Commands are stopped after 10m (the default)")
+	default:
+		fmt.Printf("⏱️  Commands are stopped after %s\n", time.Duration(seconds)*time.Second)
+	}
+	return nil
+}
+
+// checkpoint is a session state to return to: the last request and plan,
+// which "try again", ratings, and graphs refer to, where commands run, the
+// environment they run in, and the session's modes
+type checkpoint struct {
+	name           string
+	saved          time.Time
+	dir            string
+	environment    string   // Active execution environment
+	env            []string // Process environment variables
+	observe        bool
+	dryRun         bool
+	commandTimeout int
+	lastInput      string
+	lastPlan       *executor.ExecutionResult
+}
+
+// checkpoint saves, restores, lists, or deletes named session states:
+// checkpoint [list] | save <name> | restore <name> | delete <name>. They
+// last for the session, and complement undo, which reverses the last
+// task's steps and package changes rather than the session.
+func (c *CLI) checkpoint(args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		if len(c.checkpoints) == 0 {
+			fmt.Println("No checkpoints (save one with: checkpoint save <name>)")
+			return nil
+		}
+		fmt.Println("\n📍 Checkpoints")
+		for _, cp := range c.checkpoints {
+			fmt.Printf("  %-20s %s  %s\n", cp.name, timefmt.Clock(cp.saved), cp.dir)
+		}
+		return nil
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: checkpoint [list] | save <name> | restore <name> | delete <name>")
+	}
+
+	name := args[1]
+	i := slices.IndexFunc(c.checkpoints, func(cp *checkpoint) bool { return cp.name == name })
+	switch args[0] {
+	case "save":
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		cp := &checkpoint{
+			name:           name,
+			saved:          time.Now(),
+			dir:            dir,
+			environment:    c.config.Environment,
+			env:            os.Environ(),
+			observe:        c.config.ObserveMode,
+			dryRun:         c.config.DryRun,
+			commandTimeout: c.config.CommandTimeout,
+			lastInput:      c.lastInput,
+			lastPlan:       c.lastPlan,
+		}
+		if i >= 0 {
+			c.checkpoints = slices.Delete(c.checkpoints, i, i+1)
+		}
+		c.checkpoints = append(c.checkpoints, cp)
+		c.audit.Record("checkpoint_saved", map[string]string{"name": name, "dir": dir})
+		fmt.Printf("📍 Saved checkpoint %q\n", name)
+		return nil
+	case "restore":
+		if i < 0 {
+			return fmt.Errorf("no checkpoint named %q", name)
+		}
+		return c.restoreCheckpoint(c.checkpoints[i])
+	case "delete":
+		if i < 0 {
+			return fmt.Errorf("no checkpoint named %q", name)
+		}
+		c.checkpoints = slices.Delete(c.checkpoints, i, i+1)
+		fmt.Printf("🗑️  Deleted checkpoint %q\n", name)
+		return nil
+	default:
+		return fmt.Errorf("usage: checkpoint [list] | save <name> | restore <name> | delete <name>")
+	}
+}
+
+// restoreCheckpoint returns the session to a checkpoint's state, listing
+// what changed
+func (c *CLI) restoreCheckpoint(cp *checkpoint) error {
+	var changed []string
+	if cwd, _ := os.Getwd(); cwd != cp.dir {
+		if _, err := c.executor.Chdir(cp.dir); err != nil {
+			return err
+		}
+		changed = append(changed, "directory "+cp.dir)
+	}
+	if c.config.Environment != cp.environment {
+		if err := c.config.UseEnvironment(cp.environment); err != nil {
+			return err
+		}
+		if cp.environment == "" {
+			changed = append(changed, "no environment")
+		} else {
+			changed = append(changed, "environment "+cp.environment)
+		}
+	}
+	if vars := restoreEnv(cp.env); vars > 0 {
+		changed = append(changed, plural(vars, "environment variable"))
+	}
+	if c.config.ObserveMode != cp.observe || c.config.DryRun != cp.dryRun || c.config.CommandTimeout != cp.commandTimeout {
+		c.config.ObserveMode, c.config.DryRun, c.config.CommandTimeout = cp.observe, cp.dryRun, cp.commandTimeout
+		changed = append(changed, "observe, dry-run, and timeout modes")
+	}
+	if c.lastInput != cp.lastInput || c.lastPlan != cp.lastPlan {
+		c.lastInput, c.lastPlan = cp.lastInput, cp.lastPlan
+		changed = append(changed, "last request")
+	}
+	c.audit.Record("checkpoint_restored", map[string]string{"name": cp.name, "dir": cp.dir})
+
+	if len(changed) == 0 {
+		fmt.Printf("📍 Already at checkpoint %q\n", cp.name)
+		return nil
+	}
+	fmt.Printf("📍 Restored checkpoint %q (saved %s): %s\n", cp.name, timefmt.Clock(cp.saved), strings.Join(changed, ", "))
+	if cp.lastInput != "" {
+		fmt.Printf("   Last request: %s\n", cp.lastInput)
+	}
+	return nil
+}
+
+// restoreEnv sets the process environment to saved, returning how many
+// variables it changed, set, or unset
+func restoreEnv(saved []string) int {
+	want := map[string]string{}
+	for _, kv := range saved {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			want[k] = v
+		}
+	}
+	changed := 0
+	for _, kv := range os.Environ() {
+		if k, _, ok := strings.Cut(kv, "="); ok {
+			if _, keep := want[k]; !keep {
+				os.Unsetenv(k)
+				changed++
+			}
+		}
+	}
+	for k, v := range want {
+		if current, ok := os.LookupEnv(k); !ok || current != v {
+			os.Setenv(k, v)
+			changed++
+		}
+	}
+	return changed
+}
+
+// timeoutSeconds parses a command timeout as command_timeout's seconds:
+// a duration of at least a second, or "off" (-1)
+func timeoutSeconds(arg string) (int, error) {
+	if strings.ToLower(arg) == "off" {
+		return -1, nil
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("invalid timeout %q: expected a duration such as 30m, or off", arg)
+	}
+	return int(d.Seconds()), nil
+}
+
+// startsWithDigit reports whether s begins with a digit, e.g. a duration argument
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// unlock elevates the session: unlock <duration> <rule...>
+func (c *CLI) unlock(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: unlock <duration> <rule...>  (e.g. unlock 15m never-touch-etc)")
+	}
+
+	duration, err := time.ParseDuration(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", args[0], err)
+	}
+
+	fmt.Print("📝 Reason for elevation (recorded in audit log): ")
+	reason := c.readLine()
+
+	if err := c.executor.Unlock(duration, args[1:], reason); err != nil {
+		return err
+	}
+
+	fmt.Printf("🔓 Session elevated for %s; it re-locks automatically (or type 'lock')\n", duration)
+	return nil
+}
+
+// rollout plans a task and runs it on all remote targets, canary first
+func (c *CLI) rollout(ctx context.Context, input string) error {
+	result, err := c.executor.Execute(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s\n", result.Output)
+	if len(result.Commands) == 0 {
+		return nil
+	}
+
+	targets := c.config.ActiveTargets()
+	fmt.Printf("\n📋 Commands to roll out to %d target(s):\n", len(targets))
+	for _, cmd := range result.Commands {
+		fmt.Printf("  → %s\n", cmd)
+	}
+
+	fmt.Print("\n⚠️  Proceed with rollout? (yes/no): ")
+	response := strings.ToLower(c.readLine())
+	if response != "yes" && response != "y" {
+		fmt.Println("❌ Rollout cancelled")
+		return nil
+	}
+	if !c.overrideFreeze(ctx, result.Commands) || !c.confirmEnvironment(result.Commands) {
+		return nil
+	}
+
+	results, err := c.executor.Rollout(ctx, result.Commands, targets)
+
+	fmt.Println("\n📊 Rollout Summary")
+	for _, r := range results {
+		label := r.Target
+		if r.Canary {
+			label += " (canary)"
+		}
+		switch {
+		case r.Skipped:
+			fmt.Printf("  ⏭️  %s skipped\n", label)
+		case r.Err != nil:
+			fmt.Printf("  ❌ %s: %v\n", label, r.Err)
+		default:
+			fmt.Printf("  ✅ %s\n", label)
+		}
+	}
+
+	return err
+}
+
+// repoSpec reports whether a word names repositories: a configured group,
+// or directories separated by commas
+func (c *CLI) repoSpec(word string) bool {
+	_, group := c.config.Repos[word]
+	return group || strings.ContainsAny(word, ",/~")
+}
+
+// repos lists the configured repo groups, or plans a task in each of a
+// group's repositories with that repository's context, runs the approved
+// plans in parallel, and reports how each went
+func (c *CLI) repos(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		c.showRepoGroups()
+		return nil
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: repos <group|dir,dir,...> <task>")
+	}
+	repos, err := c.executor.ResolveRepos(args[0])
+	if err != nil {
+		return err
+	}
+	input := strings.Join(args[1:], " ")
+
+	fmt.Printf("\n🗂️  Planning in %s...\n", plural(len(repos), "repository"))
+	plans := c.executor.PlanRepos(ctx, input, repos)
+	var commands []string
+	planned := 0
+	for _, p := range plans {
+		fmt.Printf("\n📁 %s\n", p.Repo)
+		if p.Err != nil {
+			fmt.Printf("  ❌ %v\n", p.Err)
+			continue
+		}
+		if output := strings.TrimSpace(p.Result.Output); output != "" {
+			fmt.Printf("  %s\n", output)
+		}
+		for _, cmd := range p.Result.Commands {
+			fmt.Printf("  → %s\n", cmd)
+		}
+		for _, warning := range p.Result.Warnings {
+			fmt.Printf("  ⚠️  %s\n", warning)
+		}
+		for _, note := range p.Result.PolicyNotes {
+			fmt.Printf("  🛡️  %s\n", note)
+		}
+		if len(p.Result.Commands) > 0 {
+			commands = append(commands, p.Result.Commands...)
+			planned++
+		}
+	}
+	if planned == 0 {
+		fmt.Println("\nNothing to run")
+		return nil
+	}
+	if c.config.DryRun {
+		fmt.Println("\n🧪 Dry run: nothing was run")
+		return nil
+	}
+
+	fmt.Printf("\n⚠️  Run these plans in %s? (yes/no): ", plural(planned, "repository"))
+	if response := strings.ToLower(c.readLine()); response != "yes" && response != "y" {
+		fmt.Println("❌ Operation cancelled")
+		return nil
+	}
+	if !c.overrideFreeze(ctx, commands) || !c.confirmEnvironment(commands) || !c.confirmKubeContext(ctx, commands) {
+		return nil
+	}
+
+	fmt.Printf("\n📋 Running in %s, up to %d at a time:\n", plural(planned, "repository"), executor.MaxParallelRepos)
+	results := c.executor.ExecuteRepos(ctx, plans, func(r executor.RepoResult) {
+		icon := "✅"
+		if r.Err != nil {
+			icon = "❌"
+		}
+		fmt.Printf("  %s %s (%d/%d steps, %s)\n", icon, filepath.Base(r.Repo), r.Completed, r.Steps, r.Duration.Round(time.Second))
+	})
+	return c.showRepoResults(input, results)
+}
+
+// showRepoResults prints the combined status report of a multi-repo task,
+// with the end of the output of each repository where it failed
+func (c *CLI) showRepoResults(input string, results []executor.RepoResult) error {
+	fmt.Println("\n📊 Multi-repo Summary")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	var failed []string
+	for _, r := range results {
+		name := filepath.Base(r.Repo)
+		switch {
+		case r.Steps == 0 && r.Err == nil:
+			fmt.Printf("  ⏭️  %-20s nothing to do\n", name)
+		case r.Steps == 0:
+			fmt.Printf("  ❌ %-20s not planned: %v\n", name, r.Err)
+			failed = append(failed, name)
+		case r.Err != nil:
+			fmt.Printf("  ❌ %-20s %d/%d steps, %s: %v\n", name, r.Completed, r.Steps, r.Duration.Round(time.Second), r.Err)
+			failed = append(failed, name)
+			if output := strings.TrimSpace(r.Output); output != "" {
+				lines := strings.Split(output, "\n")
+				for _, line := range lines[max(len(lines)-10, 0):] {
+					fmt.Printf("       %s\n", line)
+				}
+			}
+		default:
+			fmt.Printf("  ✅ %-20s %d/%d steps, %s\n", name, r.Completed, r.Steps, r.Duration.Round(time.Second))
+		}
+		for _, url := range r.Reviews {
+			fmt.Printf("     🔗 %s\n", url)
+		}
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	c.audit.Record("multi_repo_task", map[string]string{
+		"input":  input,
+		"repos":  strconv.Itoa(len(results)),
+		"failed": strings.Join(failed, ", "),
+	})
+	if len(failed) > 0 {
+		return fmt.Errorf("task failed in: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// showRepoGroups lists the repo groups multi-repo tasks can span
+func (c *CLI) showRepoGroups() {
+	if len(c.config.Repos) == 0 {
+		fmt.Println("No repo groups (add \"repos\" to config.json, or name directories: repos ./api,./web <task>)")
+		return
+	}
+	fmt.Println("\n🗂️  Repo Groups")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	names := make([]string, 0, len(c.config.Repos))
+	for name := range c.config.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-16s %s\n", name, strings.Join(c.config.Repos[name], ", "))
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+}
+
+// release cuts a release: it plans the release's steps, then asks before
+// each one, which can be run, skipped, or stop the release. When a step
+// fails or the release is stopped, the completed steps can be rolled back;
+// a finished release can be reversed with "undo" as far as it is
+// reversible.
+func (c *CLI) release(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: release <version>, e.g. release 1.4.0")
+	}
+	rel, err := c.executor.PlanRelease(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	input := "release " + rel.Version
+
+	fmt.Printf("\n🚀 Release %s of %s, tagged %s (process: %s)\n", rel.Version, rel.Root, rel.Tag, rel.Source)
+	var commands []string
+	for i, step := range rel.Steps {
+		fmt.Printf("  %d. %s\n", i+1, step.Description)
+		for _, cmd := range step.Commands {
+			fmt.Printf("     → %s\n", cmd)
+		}
+		commands = append(commands, step.Commands...)
+	}
+	if len(rel.Warnings) > 0 {
+		fmt.Println("\n⚠️  Warnings:")
+		for _, warning := range rel.Warnings {
+			fmt.Printf("  • %s\n", warning)
+		}
+	}
+	if c.config.DryRun {
+		fmt.Println("\n🧪 Dry run: nothing was run")
+		return nil
+	}
+	if !c.overrideFreeze(ctx, commands) || !c.confirmEnvironment(commands) {
+		return nil
+	}
+	if cwd, _ := os.Getwd(); cwd != rel.Root {
+		dir, err := c.executor.Chdir(rel.Root)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📂 Now in %s\n", dir)
+	}
+
+	// The steps that ran, as one task, so the release can be rolled back
+	// and undone as a whole
+	start := time.Now()
+	done := &executor.ExecutionResult{Prompt: input, Intent: "release"}
+	executed := 0
+	for i, step := range rel.Steps {
+		fmt.Printf("\n🚀 Step %d/%d: %s\n", i+1, len(rel.Steps), step.Description)
+		if step.Notes != "" {
+			fmt.Printf("\n%s\n", step.Notes)
+		}
+		for _, cmd := range step.Commands {
+			fmt.Printf("  → %s\n", cmd)
+		}
+		fmt.Print("\n⚠️  Run this step? (yes/skip/stop): ")
+		decision := "stop"
+		switch strings.ToLower(c.readLine()) {
+		case "yes", "y":
+			decision = "run"
+		case "skip", "s":
+			decision = "skip"
+		}
+		c.audit.Record("release_step", map[string]string{"version": rel.Version, "step": step.Name, "decision": decision})
+		if decision == "skip" {
+			fmt.Println("⏭️  Skipped")
+			continue
+		}
+		if decision == "stop" {
+			fmt.Println("❌ Release stopped")
+			c.offerRollback(ctx, done)
+			c.recordTask(input, done, executed, false, time.Since(start))
+			return nil
+		}
+
+		result, err := c.executor.RunReleaseStep(ctx, rel, i)
+		done.Commands = append(done.Commands, result.Commands...)
+		done.Rollback = append(slices.Clone(result.Rollback), done.Rollback...)
+		done.Irreversible = append(slices.Clone(result.Irreversible), done.Irreversible...)
+		if err != nil {
+			c.offerRollback(ctx, done)
+			c.recordTask(input, done, executed, false, time.Since(start))
+			return fmt.Errorf("release %s failed at the %s step: %w", rel.Version, step.Name, err)
+		}
+		executed += len(result.Commands)
+	}
+
+	c.recordTask(input, done, executed, true, time.Since(start))
+	c.audit.Record("release_finished", map[string]string{"version": rel.Version, "tag": rel.Tag, "commands": strings.Join(done.Commands, "\n")})
+	fmt.Printf("\n✅ Release %s finished\n", rel.Version)
+	if len(done.Rollback) > 0 {
+		fmt.Println("↩️  Run \"undo\" to reverse this release")
+	}
+	return nil
+}
+
+// showTargets lists configured remote targets
+func (c *CLI) showTargets() {
+	fmt.Println("\n🖥️  Remote Targets")
+	if env := c.config.ActiveEnvironment(); env != nil && len(env.Targets) > 0 {
+		fmt.Printf("   (those of the %s environment)\n", env.Name)
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if len(c.config.Targets) == 0 {
+		fmt.Println("  No targets configured (add \"targets\" to config.json)")
+	}
+	for _, t := range c.config.ActiveTargets() {
+		canary := ""
+		if t.Canary {
+			canary = " 🐤"
+		}
+		fmt.Printf("  %-16s %s%s\n", t.Name, t.Host, canary)
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+}
+
+// Run executes a single non-interactive invocation, e.g. `devos report`
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "report":
+		return c.runReport(args[1:])
+	case "resume":
+		return c.resume(ctx)
+	case "daemon":
+		return daemon.New(c.config, c.executor, c.logger).ListenAndServe()
+	case "generate":
+		return c.generate(args[1:])
+	case "helm":
+		return c.helm(ctx, args[1:])
+	case "ssh":
+		return c.ssh(ctx, args[1:])
+	case "service":
+		return c.service(ctx, args[1:])
+	case "logs":
+		return c.logs(ctx, args[1:])
+	case "net":
+		return c.net(ctx, args[1:])
+	case "grpc":
+		return c.grpc(ctx, args[1:])
+	case "monitor":
+		return c.monitor(ctx, args[1:])
+	case "status":
+		return c.showStatus(args[1:])
+	case "tasks":
+		return c.showTasks(args[1:])
+	case "history":
+		return c.showHistory(args[1:])
+	case "plugins":
+		return c.showPlugins(args[1:])
+	case "models":
+		return c.models(ctx, args[1:])
+	case "policy":
+		return c.policy(args[1:])
+	case "runbook":
+		return c.runbook(ctx, args[1:])
+	case "api":
+		return c.api(ctx, args[1:])
+	case "issue":
+		return c.issue(ctx, args[1:])
+	case "freezes":
+		return c.showFreezes(ctx)
+	case "guardrails":
+		return c.showGuardrails(ctx)
+	case "budget":
+		c.showBudgets()
+		return nil
+	case "privacy":
+		return c.privacy(args[1:])
+	case "feedback":
+		return c.feedback(args[1:])
+	case "undo":
+		return c.undo(ctx)
+	case "env":
+		return c.environment(args[1:])
+	case "locks":
+		return c.showLocks(args[1:])
+	case "force-unlock":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: devos force-unlock [path]")
+		}
+		return c.forceUnlock(args[1:])
+	case "config":
+		return c.configCommand(args[1:])
+	case "export-profile":
+		return c.exportProfile(args[1:])
+	case "import-profile":
+		return c.importProfile(args[1:])
+	case "open":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: devos open <url|file|folder>")
+		}
+		return platform.Open(args[1])
+	case "repos":
+		return c.repos(ctx, args[1:])
+	case "release":
+		return c.release(ctx, args[1:])
+	case "bg":
+		// Jobs belong to a session, and this one ends with the command
+		return fmt.Errorf("background jobs run in the interactive session: start devos, then use: bg <task>")
+	case "attach":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: devos attach <task>")
+		}
+		return c.attach(args[1])
+	case "--dry-run":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: devos --dry-run <command>")
+		}
+		c.config.DryRun = true
+		return c.Run(ctx, args[1:])
+	case "--timeout":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: devos --timeout <duration|off> <command>")
+		}
+		seconds, err := timeoutSeconds(args[1])
+		if err != nil {
+			return err
+		}
+		c.config.CommandTimeout = seconds
+		return c.Run(ctx, args[2:])
+	default:
+		return c.processCommand(ctx, strings.Join(args, " "))
+	}
+}
+
+func (c *CLI) processCommand(ctx context.Context, input string) error {
+	// "try again" repeats the last request for a different plan; both it and
+	// a tweaked request show how the new plan differs from the last
+	previous := c.lastPlan
+	request, again := c.retryRequest(input)
+	if again || (previous != nil && len(previous.Commands) > 0 && similarRequest(c.lastInput, input)) {
+		c.replacing = previous
+	}
+
+	// The daemon runs plans itself, so dry runs are planned locally
+	if c.daemon != nil && !c.config.DryRun {
+		c.replacing = nil
+		return c.runOnDaemon(ctx, request)
+	}
+	_, _, err := c.runPlan(ctx, request, func() (*executor.ExecutionResult, error) {
+		// Execute through AI engine
+		c.tuneLocalModel(ctx)
+		streamCtx, stop := c.streamOutput(ctx)
+		var result *executor.ExecutionResult
+		var err error
+		if again {
+			result, err = c.executor.TryAgain(streamCtx, request, previous)
+		} else {
+			result, err = c.executor.Execute(streamCtx, input)
+		}
+		stop()
+		if errors.Is(err, executor.ErrModelNotFound) && c.config.AIProvider == "ollama" && c.offerModel(ctx) {
+			result, err = c.executor.Execute(ctx, input)
+		}
+		return result, err
+	})
+	return err
+}
+
+// retryPhrase asks for another plan for the last request, optionally with a
+// tweak: "try again", "retry, but use podman", "again: without sudo"
+var retryPhrase = regexp.MustCompile(`(?i)^(?:please\s+)?(?:try\s+again|again|retry|regenerate)(?:\s*[,:;.!-]\s*(?:but\s+)?(.*)|\s+but\s+(.*))?\s*$`)
+
+// retryRequest returns the request a "try again" input repeats, with any
+// tweak given, and whether input was one. Without a last plan it is not.
+func (c *CLI) retryRequest(input string) (string, bool) {
+	m := retryPhrase.FindStringSubmatch(strings.TrimSpace(input))
+	if m == nil || c.lastPlan == nil || c.lastInput == "" {
+		return input, false
+	}
+	if tweak := strings.TrimSpace(m[1] + m[2]); tweak != "" {
+		return c.lastInput + " (" + tweak + ")", true
+	}
+	return c.lastInput, true
+}
+
+// similarRequest reports whether input looks like a tweak of the last
+// request: most of their words are the same
+func similarRequest(last, input string) bool {
+	words := func(s string) map[string]bool {
+		set := map[string]bool{}
+		for _, w := range strings.Fields(strings.ToLower(s)) {
+			set[strings.Trim(w, ".,;:!?\"'")] = true
+		}
+		return set
+	}
+	a, b := words(last), words(input)
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	return union > 0 && float64(common)/float64(union) >= 0.6
+}
+
+// showPlanDiff shows how a regenerated plan's commands differ from the
+// previous plan's
+func showPlanDiff(before, after *executor.ExecutionResult) {
+	changes := executor.DiffPlans(before.Commands, after.Commands)
+	if len(changes) == 0 {
+		fmt.Println("\n🔁 Same commands as the previous plan")
+		return
+	}
+	fmt.Println("\n🔁 Changes from the previous plan:")
+	for _, change := range changes {
+		switch change.Kind {
+		case executor.PlanAdded:
+			fmt.Printf("  + %s\n", change.After)
+		case executor.PlanRemoved:
+			fmt.Printf("  - %s\n", change.Before)
+		default:
+			fmt.Printf("  ~ %s\n    → %s\n", change.Before, change.After)
+		}
+	}
+}
+
+// streamOutput shows the plan's explanation as the provider writes it, with
+// a spinner until the first words arrive, which for providers that cannot
+// stream is until the plan is complete. The returned function stops both.
+func (c *CLI) streamOutput(ctx context.Context) (context.Context, func()) {
+	c.streamed = ""
+	// Screen readers and pipes get the plan in one piece
+	if !isTerminal(os.Stdout) {
+		return ctx, func() {}
+	}
+
+	var mu sync.Mutex
+	spinning := true
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			mu.Lock()
+			if spinning {
+				fmt.Printf("\r%s Thinking...", frames[i%len(frames)])
+			}
+			mu.Unlock()
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	onText := func(text string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if spinning {
+			spinning = false
+			fmt.Print("\r\x1b[K\n")
+		}
+		fmt.Print(text)
+		c.streamed += text
+	}
+	return executor.WithStream(ctx, onText), func() {
+		close(done)
+		<-stopped
+		if spinning {
+			fmt.Print("\r\x1b[K")
+		} else {
+			fmt.Println()
+		}
+	}
+}
+
+// runOnDaemon has the attached daemon plan input, then approves or rejects
+// the plan as this session's team user and follows it until it finishes
+func (c *CLI) runOnDaemon(ctx context.Context, input string) error {
+	c.logger.Info("Processing command on the daemon: %s", input)
+	plan, err := c.daemon.CreatePlan(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s\n", plan.Result.Output)
+	if len(plan.Result.Warnings) > 0 {
+		fmt.Println("\n⚠️  Warnings:")
+		for _, warning := range plan.Result.Warnings {
+			fmt.Printf("  • %s\n", warning)
+		}
+	}
+	if len(plan.Result.PolicyNotes) > 0 {
+		fmt.Println("\n🛡️  Approval policy:")
+		for _, note := range plan.Result.PolicyNotes {
+			fmt.Printf("  • %s\n", note)
+		}
+	}
+	if len(plan.Result.Commands) == 0 {
+		return nil
+	}
+	fmt.Printf("\n📋 Plan %s:\n", plan.ID)
+	for i, cmd := range plan.Result.Commands {
+		fmt.Printf("  %d. %s\n", i+1, cmd)
+	}
+	for _, confirmation := range plan.Approval.Confirmations {
+		fmt.Printf("\n⚠️  Needs confirming: %s\n", confirmation.Detail)
+	}
+	if plan.Risk == "high" || len(plan.Approval.Confirmations) > 0 {
+		fmt.Printf("\n⚠️  This plan needs %s from team members other than you\n", plural(plan.Approval.Required, "approval"))
+		fmt.Printf("⏳ Plan %s is waiting for approval (plans approve %s)\n", plan.ID, plan.ID)
+		return nil
+	}
+
+	fmt.Print("\n⚠️  Approve and run on the daemon? (yes/no): ")
+	if response := strings.ToLower(c.readLine()); response != "yes" && response != "y" {
+		if _, err := c.daemon.Reject(ctx, plan.ID); err != nil {
+			return err
+		}
+		fmt.Println("❌ Operation cancelled")
+		return nil
+	}
+	return c.followPlan(ctx, plan.ID, true)
+}
+
+// followPlan approves a daemon plan, if asked to, and waits for it to finish
+func (c *CLI) followPlan(ctx context.Context, id string, approve bool) error {
+	plan, err := c.daemon.Plan(ctx, id)
+	if approve {
+		plan, err = c.daemon.Approve(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	if plan.Status == daemon.StatusPendingApproval {
+		remaining := plan.Approval.Required - len(plan.Approval.ApprovedBy)
+		fmt.Printf("⏳ Plan %s is waiting for %s from another team member (plans approve %s)\n", plan.ID, plural(remaining, "more approval"), plan.ID)
+		return nil
+	}
+
+	fmt.Printf("📋 Running plan %s on the daemon...\n", plan.ID)
+	if plan, err = c.daemon.Wait(ctx, id); err != nil {
+		return err
+	}
+	if plan.Result.Changes != nil {
+		showChangedFiles(plan.Result.Changes, 20)
+	}
+	switch plan.Status {
+	case daemon.StatusSucceeded:
+		fmt.Println("\n✅ Execution completed successfully")
+		return nil
+	case daemon.StatusFailed:
+		return fmt.Errorf("plan %s failed: %s", plan.ID, plan.Error)
+	default:
+		fmt.Printf("Plan %s is %s\n", plan.ID, plan.Status)
+		return nil
+	}
+}
+
+// daemonPlans lists the attached daemon's plans, or approves or rejects one
+func (c *CLI) daemonPlans(ctx context.Context, args []string) error {
+	if c.daemon == nil {
+		return fmt.Errorf("not attached to a daemon; start one with: devos daemon")
+	}
+	switch {
+	case len(args) == 0:
+		plans, err := c.daemon.Plans(ctx)
+		if err != nil {
+			return err
+		}
+		if len(plans) == 0 {
+			fmt.Println("No plans on the daemon")
+			return nil
+		}
+		sort.Slice(plans, func(i, j int) bool { return plans[i].CreatedAt.Before(plans[j].CreatedAt) })
+		fmt.Println("\n🗂️  Daemon Plans")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		for _, p := range plans {
+			risk := ""
+			if p.Risk == "high" {
+				risk = " ⚠️  high risk"
+			}
+			fmt.Printf("  %s  %-16s %s by %s, %d/%d approvals%s\n", p.ID, p.Status, timefmt.Clock(p.CreatedAt), p.Requester, len(p.Approval.ApprovedBy), p.Approval.Required, risk)
+			fmt.Printf("      %s\n", p.Input)
+		}
+		fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+		return nil
+	case len(args) == 2 && args[0] == "approve":
+		return c.followPlan(ctx, args[1], true)
+	case len(args) == 2 && args[0] == "reject":
+		plan, err := c.daemon.Reject(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("❌ Plan %s rejected\n", plan.ID)
+		return nil
+	case len(args) == 2 && args[0] == "show":
+		plan, err := c.daemon.Plan(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n%s (%s, requested by %s)\n%s\n", plan.ID, plan.Status, plan.Requester, plan.Input)
+		for i, cmd := range plan.Result.Commands {
+			fmt.Printf("  %d. %s\n", i+1, cmd)
+		}
+		for _, confirmation := range plan.Approval.Confirmations {
+			fmt.Printf("⚠️  Approving confirms: %s\n", confirmation.Detail)
+		}
+		if plan.Error != "" {
+			fmt.Printf("❌ %s\n", plan.Error)
+		}
+		return nil
+	default:
+		return fmt.Errorf("usage: plans [show|approve|reject <id>]")
+	}
+}
+
+// runPlan gets a plan for input, reviews it with the user, and executes it.
+// It returns the final plan and how many of its commands ran successfully.
+func (c *CLI) runPlan(ctx context.Context, input string, plan func() (*executor.ExecutionResult, error)) (result *executor.ExecutionResult, executed int, err error) {
+	c.logger.Info("Processing command: %s", input)
+
+	start := time.Now()
+	defer func() {
+		c.recordTask(input, result, executed, err == nil, time.Since(start))
+		c.speakOutcome(input, executed, err, time.Since(start))
+		if result != nil {
+			c.lastInput, c.lastPlan = input, result
+		}
+	}()
+
+	previous := c.replacing
+	c.replacing = nil
+	result, err = plan()
+	if err != nil {
+		return result, executed, err
+	}
+
+	// Review the plan, regenerating it if the workspace changes before approval
+review:
+	for {
+		// Offer to install or plan around tools that are not installed
+		if missing := c.executor.MissingBinaries(result.Commands); len(missing) > 0 {
+			before := result
+			result, err = c.resolveMissing(ctx, input, result, missing)
+			if err != nil || result == nil {
+				return result, executed, err
+			}
+			if result != before {
+				previous = before
+			}
+		}
+
+		// Fetch and verify downloaded artifacts before anything runs them
+		downloads, err := c.executor.InspectDownloads(ctx, result)
+		if err != nil {
+			return result, executed, err
+		}
+
+		// Validate Kubernetes manifests now rather than at apply time
+		manifests := c.executor.CheckManifests(ctx, result)
+
+		// Move servers off ports that are already taken
+		c.resolvePortConflicts(ctx, result)
+
+		// Display result, apart from any explanation already streamed
+		if rest, ok := strings.CutPrefix(result.Output, c.streamed); ok && c.streamed != "" {
+			if rest = strings.TrimLeft(rest, "\n"); rest != "" {
+				fmt.Printf("%s\n", rest)
+			}
+		} else {
+			fmt.Printf("\n%s\n", result.Output)
+		}
+		c.streamed = ""
+		if graph := executor.PlanGraph(result); len(result.Commands) >= graphMinSteps && graph.Parallel() {
+			fmt.Println("\n🕸️  Execution order:")
+			fmt.Print(graph.Text(a11y.Enabled()))
+		}
+		if previous != nil {
+			showPlanDiff(previous, result)
+		}
+		c.showDownloads(downloads)
+		c.showManifestChecks(manifests)
+
+		if len(result.Warnings) > 0 {
+			fmt.Println("\n⚠️  Warnings:")
+			for _, warning := range result.Warnings {
+				fmt.Printf("  • %s\n", warning)
+			}
+		}
+
+		if len(result.PolicyNotes) > 0 {
+			fmt.Println("\n🛡️  Approval policy:")
+			for _, note := range result.PolicyNotes {
+				fmt.Printf("  • %s\n", note)
+			}
+		}
+
+		if kube := c.executor.KubeTarget(ctx, result.Commands); kube != nil {
+			c.showKubeTarget(kube)
+		}
+		if cost := c.executor.EstimateCost(result.Commands); cost != nil {
+			c.showCost(cost)
+		}
+
+		if c.config.DryRun && len(result.Commands) > 0 {
+			c.executor.DryRun(result)
+			return result, executed, nil
+		}
+
+		if result.NeedsConfirmation {
+			if a11y.Enabled() {
+				// The commands are otherwise listed only after approval
+				a11y.Announce("Awaiting confirmation: %s", plural(len(result.Commands), "command"))
+				for i, cmd := range result.Commands {
+					fmt.Printf("  %d. %s\n", i+1, cmd)
+				}
+			}
+			if c.config.Simulate && len(result.Commands) > 0 {
+				c.simulate(ctx, result.Commands)
+			}
+		confirm:
+			for {
+				fmt.Print("\n⚠️  Proceed with execution? (yes/no/edit/simulate/graph): ")
+				switch strings.ToLower(c.readLine()) {
+				case "yes", "y":
+					break confirm
+				case "simulate", "s":
+					c.simulate(ctx, result.Commands)
+				case "graph", "g":
+					fmt.Println("\n🕸️  Execution order:")
+					fmt.Print(executor.PlanGraph(result).Text(a11y.Enabled()))
+				case "edit", "e":
+					edited, err := c.editPlan(input, result.Commands)
+					if err != nil {
+						return result, executed, err
+					}
+					if edited == nil {
+						fmt.Println("❌ Operation cancelled")
+						return result, executed, nil
+					}
+					if slices.Equal(edited, result.Commands) {
+						break confirm
+					}
+					// Review the edited plan as fully as a generated one
+					before := *result
+					previous = &before
+					result.Commands = edited
+					result.Steps = nil
+					result.DependsOn = nil
+					result.Undo = nil
+					continue review
+				default:
+					fmt.Println("❌ Operation cancelled")
+					return result, executed, nil
+				}
+			}
+		}
+
+		drift := c.executor.WorkspaceDrift(result)
+		if drift == nil {
+			break
+		}
+		choice := c.confirmDrift(drift)
+		if choice == driftCancel {
+			return result, executed, nil
+		}
+		if choice == driftContinue {
+			break
+		}
+		previous = result
+		result, err = c.executor.Regenerate(ctx, result, drift)
+		if err != nil {
+			return result, executed, err
+		}
+	}
+
+	if len(result.Commands) > 0 && !c.overrideFreeze(ctx, result.Commands) {
+		return result, executed, nil
+	}
+	if len(result.Commands) > 0 && !c.confirmEnvironment(result.Commands) {
+		return result, executed, nil
+	}
+	if len(result.Commands) > 0 && !c.confirmKubeContext(ctx, result.Commands) {
+		return result, executed, nil
+	}
+	if len(result.Commands) > 0 && !c.confirmCost(result.Commands) {
+		return result, executed, nil
+	}
+
+	if len(result.Commands) > 0 && c.background {
+		job, err := c.executor.Background(result)
+		if err != nil {
+			return result, executed, err
+		}
+		fmt.Printf("\n🔙 Running in the background as job %d: %s\n", job.ID, job.Command())
+		fmt.Printf("  \"tail %d\" shows its output, \"fg %d\" follows it, and \"kill %d\" stops it\n", job.ID, job.ID, job.ID)
+		return result, executed, nil
+	}
+
+	if len(result.Commands) > 0 {
+		run := c.executor.PartialRun(result.Commands)
+		if run != nil && !c.confirmRerun(run) {
+			run = nil
+		}
+
+		fmt.Println("\n📋 Executing commands:")
+		for i, cmd := range result.Commands {
+			if run != nil && i < run.Completed {
+				fmt.Printf("  ⏭️  %s (already completed)\n", cmd)
+				continue
+			}
+			fmt.Printf("  → %s\n", cmd)
+		}
+
+		execute := func() error { return c.executor.ExecutePlan(ctx, result) }
+		if run != nil {
+			execute = func() error { return c.executor.ResumeRun(ctx, run) }
+		}
+		// Note the repository's state, to offer a summary of what changed
+		var repo *executor.RepoState
+		if len(result.Commands) > 1 && c.config.ChangeSummary != "off" && isTerminal(os.Stdin) {
+			repo = executor.Repo(ctx)
+		}
+
+		a11y.Announce("Running %s", plural(len(result.Commands), "command"))
+		err := execute()
+		c.showChanges(result.Changes)
+		if err != nil {
+			a11y.Announce("Execution failed")
+			c.offerRollback(ctx, result)
+			if fix := c.executor.KnownFix(err); fix != nil {
+				return result, executed, c.offerKnownFix(ctx, err, fix)
+			}
+			return result, executed, err
+		}
+
+		executed = len(result.Commands)
+		a11y.Announce("Finished: %s succeeded", plural(executed, "command"))
+		fmt.Println("\n✅ Execution completed successfully")
+		if len(result.Rollback) > 0 {
+			fmt.Println("↩️  Run \"undo\" to reverse this task")
+		}
+		c.offerPreview(ctx, result.Commands)
+		if repo != nil && repo.Changed(ctx) {
+			c.offerChangeSummary(ctx, result, repo)
+		}
+	}
+
+	return result, executed, nil
+}
+
+// overrideFreeze asks for an explicit override before commands change
+// anything during a change freeze, and records the decision in the audit log
+func (c *CLI) overrideFreeze(ctx context.Context, commands []string) bool {
+	frozen := executor.FrozenCommands(commands)
+	if len(frozen) == 0 {
+		return true
+	}
+	window := c.executor.ActiveFreeze(ctx)
+	if window == nil {
+		return true
+	}
+
+	fmt.Printf("\n🧊 Change freeze %q is in effect until %s\n", window.Name, timefmt.DateTime(window.End))
+	if window.Reason != "" {
+		fmt.Printf("  %s\n", window.Reason)
+	}
+	fmt.Println("  These commands would change things:")
+	for _, cmd := range frozen {
+		fmt.Printf("  • %s\n", cmd)
+	}
+
+	details := map[string]string{"freeze": window.Name, "commands": strings.Join(frozen, "\n")}
+	fmt.Print("\n⚠️  Type 'override' to run them anyway: ")
+	if strings.ToLower(c.readLine()) != "override" {
+		c.audit.Record("freeze_blocked", details)
+		fmt.Println("❌ Operation cancelled")
+		return false
+	}
+	fmt.Print("📝 Reason for the override (recorded in audit log): ")
+	details["reason"] = c.readLine()
+	c.audit.Record("freeze_override", details)
+	return true
+}
+
+// confirmEnvironment asks for the name of a protected environment to be
+// typed before commands change anything in it, and audits the decision
+func (c *CLI) confirmEnvironment(commands []string) bool {
+	env := c.config.ActiveEnvironment()
+	if env == nil || !env.Protected {
+		return true
+	}
+	var changing []string
+	for _, cmd := range commands {
+		if !policy.IsReadOnly(cmd) {
+			changing = append(changing, cmd)
+		}
+	}
+	if len(changing) == 0 {
+		return true
+	}
+
+	fmt.Printf("\n🔴 These commands change the protected %s environment:\n", strings.ToUpper(env.Name))
+	for _, cmd := range changing {
+		fmt.Printf("  • %s\n", cmd)
+	}
+
+	details := map[string]string{"environment": env.Name, "commands": strings.Join(changing, "\n")}
+	fmt.Printf("\n⚠️  Type '%s' to run them: ", env.Name)
+	if c.readLine() != env.Name {
+		c.audit.Record("environment_blocked", details)
+		fmt.Println("❌ Operation cancelled")
+		return false
+	}
+	c.audit.Record("environment_confirmed", details)
+	return true
+}
+
+// showCost shows the monthly cost of the cloud resources a plan creates
+func (c *CLI) showCost(cost *cloudcost.Estimate) {
+	fmt.Printf("\n💰 Estimated cost: about %s/month\n", cloudcost.Format(cost.Total))
+	for _, item := range cost.Items {
+		size := item.Size
+		if size == "" {
+			size = "unknown size"
+		}
+		price := "no price known"
+		if item.Priced {
+			price = cloudcost.Format(item.Monthly) + "/month"
+		}
+		fmt.Printf("  • %d × %s (%s): %s\n", item.Count, item.Resource, size, price)
+	}
+	if len(cost.Unpriced()) > 0 {
+		fmt.Println("  Set prices for the rest with \"cloud_prices\" in config (dollars per hour).")
+	}
+	fmt.Println("  On-demand compute only; storage and traffic are extra.")
+}
+
+// confirmCost asks for the monthly cost to be typed before a plan creates
+// cloud resources costing more than the threshold, and audits the decision
+func (c *CLI) confirmCost(commands []string) bool {
+	cost := c.executor.EstimateCost(commands)
+	threshold := c.executor.CostThreshold()
+	if cost == nil || cost.Total <= threshold {
+		return true
+	}
+
+	amount := fmt.Sprintf("%.0f", math.Ceil(cost.Total))
+	fmt.Printf("\n💰 This plan adds about $%s/month, over the $%.0f threshold\n", amount, threshold)
+	details := map[string]string{"monthly": amount, "threshold": fmt.Sprintf("%.0f", threshold), "commands": strings.Join(commands, "\n")}
+	fmt.Printf("\n⚠️  Type '%s' to accept the cost: ", amount)
+	if strings.TrimPrefix(c.readLine(), "$") != amount {
+		c.audit.Record("cost_blocked", details)
+		fmt.Println("❌ Operation cancelled")
+		return false
+	}
+	c.audit.Record("cost_confirmed", details)
+	return true
+}
+
+// showKubeTarget shows the cluster a plan would change
+func (c *CLI) showKubeTarget(kube *executor.KubeTarget) {
+	name := kube.Context
+	if name == "" {
+		name = "unknown (kubectl config current-context failed)"
+	}
+	fmt.Printf("\n☸️  Cluster: context %s, namespace %s\n", name, kube.Namespace)
+	if kube.Mismatch != "" {
+		fmt.Printf("  ⚠️  This project is in the %s environment, but %s\n", kube.Environment, kube.Mismatch)
+	}
+}
+
+// confirmKubeContext asks for the context name to be typed before commands
+// change a cluster that does not fit the project's environment, and audits
+// the decision
+func (c *CLI) confirmKubeContext(ctx context.Context, commands []string) bool {
+	kube := c.executor.KubeTarget(ctx, commands)
+	if kube == nil || kube.Mismatch == "" {
+		return true
+	}
+
+	fmt.Printf("\n☸️  These commands would change context %s (namespace %s):\n", kube.Context, kube.Namespace)
+	for _, cmd := range kube.Commands {
+		fmt.Printf("  • %s\n", cmd)
+	}
+	fmt.Printf("  This project is in the %s environment, but %s.\n", kube.Environment, kube.Mismatch)
+
+	details := map[string]string{
+		"context":     kube.Context,
+		"namespace":   kube.Namespace,
+		"environment": kube.Environment,
+		"mismatch":    kube.Mismatch,
+		"commands":    strings.Join(kube.Commands, "\n"),
+	}
+	fmt.Printf("\n⚠️  Type '%s' to run them against this context anyway: ", kube.Context)
+	if c.readLine() != kube.Context {
+		c.audit.Record("kube_context_blocked", details)
+		fmt.Println("❌ Operation cancelled")
+		return false
+	}
+	c.audit.Record("kube_context_confirmed", details)
+	return true
+}
+
+// environment lists the execution environments, or switches to one
+func (c *CLI) environment(args []string) error {
+	if len(args) > 0 && (args[0] == "use" || args[0] == "off") {
+		name := ""
+		if args[0] == "use" {
+			if len(args) != 2 {
+				return fmt.Errorf("usage: devos env use <name>")
+			}
+			name = args[1]
+		} else if len(args) != 1 {
+			return fmt.Errorf("usage: devos env off")
+		}
+		return c.useEnvironment(name)
+	}
+	if len(args) > 0 && args[0] == "list" {
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("env", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(c.config.Environments) == 0 && *format == table.FormatTable {
+		fmt.Println("No environments configured (add \"environments\" to the config, e.g. dev, staging, and prod)")
+		return nil
+	}
+	t := table.New("name", "active", "protected", "targets", "kube_context", "variables", "rules")
+	for _, env := range c.config.Environments {
+		vars := make([]string, 0, len(env.Env))
+		for key := range env.Env {
+			vars = append(vars, key)
+		}
+		sort.Strings(vars)
+		t.Add(env.Name, env.Name == c.config.Environment, env.Protected, strings.Join(env.Targets, ","),
+			env.KubeContext, strings.Join(vars, ","), len(env.ApprovalRules))
+	}
+	return render(t, *format, *columns)
+}
+
+// useEnvironment makes an environment active for this and later sessions;
+// "" leaves none active
+func (c *CLI) useEnvironment(name string) error {
+	saved, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := saved.UseEnvironment(name); err != nil {
+		return err
+	}
+	if err := saved.Save(); err != nil {
+		return err
+	}
+	previous := c.config.Environment
+	c.config.UseEnvironment(name)
+	c.audit.Record("environment_changed", map[string]string{"from": previous, "to": name})
+
+	env := c.config.ActiveEnvironment()
+	switch {
+	case env == nil:
+		fmt.Println("🌍 No environment active; plans use the global settings")
+	case env.Protected:
+		fmt.Printf("🔴 Now in %s: changes need the name typed to confirm, and two approvers on the daemon\n", strings.ToUpper(env.Name))
+	default:
+		fmt.Printf("🌍 Now in %s\n", env.Name)
+	}
+	return nil
+}
+
+// privacy lists local-only projects and paths, or marks one local-only or
+// clears the mark
+func (c *CLI) privacy(args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		if len(c.config.LocalOnly) == 0 {
+			fmt.Println("No local-only projects (mark one with: devos privacy local [path])")
+			return nil
+		}
+		fmt.Println("\n🔒 Local-only Projects")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		for _, path := range c.config.LocalOnly {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+		if tag := c.executor.LocalOnly(); tag != "" {
+			fmt.Printf("This directory is local-only (%s)\n", tag)
+		}
+		return nil
+	}
+	if len(args) > 2 || (args[0] != "local" && args[0] != "cloud") {
+		return fmt.Errorf("usage: devos privacy [list|local [path]|cloud [path]]")
+	}
+
+	path := ""
+	if len(args) == 2 {
+		path = privacy.Expand(args[1])
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		path = project.Root(cwd)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	// Save on top of the file rather than this session's settings
+	saved, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if args[0] == "local" {
+		if tag := privacy.Match(saved.LocalOnly, path); tag != "" {
+			fmt.Printf("%s is already local-only (%s)\n", path, tag)
+			return nil
+		}
+		saved.LocalOnly = append(saved.LocalOnly, path)
+	} else {
+		var kept []string
+		for _, p := range saved.LocalOnly {
+			if privacy.Expand(p) != path {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == len(saved.LocalOnly) {
+			if tag := privacy.Match(saved.LocalOnly, path); tag != "" {
+				return fmt.Errorf("%s is covered by %s; remove that entry instead", path, tag)
+			}
+			fmt.Printf("%s is not local-only\n", path)
+			return nil
+		}
+		saved.LocalOnly = kept
+	}
+	if err := saved.Save(); err != nil {
+		return err
+	}
+	c.config.LocalOnly = saved.LocalOnly
+	c.audit.Record("privacy_changed", map[string]string{"path": path, "tier": args[0]})
+	if args[0] == "local" {
+		fmt.Printf("🔒 %s is local-only; its context will only go to the local model\n", path)
+	} else {
+		fmt.Printf("☁️  %s may use cloud providers again\n", path)
+	}
+	return nil
+}
+
+// showBudgets prints how much of each AI budget the current period used
+func (c *CLI) showBudgets() {
+	if len(c.config.Budgets) == 0 {
+		fmt.Println("No AI budgets configured (add \"budgets\" to config.json)")
+		return
+	}
+	fmt.Println("\n💰 AI Budgets")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for _, s := range c.executor.BudgetStatus() {
+		b := s.Budget
+		provider := b.Provider
+		if provider == "*" {
+			provider = "cloud"
+		}
+		icon := "✅"
+		if s.Exhausted() {
+			icon = "⛔"
+		} else if s.Near() {
+			icon = "⚠️ "
+		}
+		var used []string
+		if b.Tokens > 0 {
+			used = append(used, fmt.Sprintf("%d of %d tokens", s.Tokens, b.Tokens))
+		}
+		if b.Dollars > 0 {
+			used = append(used, fmt.Sprintf("$%.2f of $%.2f", s.Dollars, b.Dollars))
+		}
+		action := b.Action
+		if action == "" {
+			action = config.BudgetWarn
+		}
+		if action == config.BudgetDowngrade {
+			action += " to " + b.Fallback
+		}
+		fmt.Printf("  %s %s per %s: %s (%.0f%%), then %s\n", icon, provider, b.Period, strings.Join(used, ", "), s.Percent(), action)
+		if b.Dollars > 0 && s.Unpriced > 0 {
+			fmt.Printf("     %d tokens on models without a price are not in the dollar total (set \"token_prices\")\n", s.Unpriced)
+		}
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+}
+
+// showFreezes lists the change freeze in effect and those starting within
+// the next week
+func (c *CLI) showFreezes(ctx context.Context) error {
+	if len(c.config.Freezes) == 0 {
+		fmt.Println("No change freezes configured (add \"freezes\" to config.json)")
+		return nil
+	}
+	fmt.Println("\n🧊 Change Freezes")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if window := c.executor.ActiveFreeze(ctx); window != nil {
+		fmt.Printf("  In effect: %s until %s\n", window.Name, timefmt.DateTime(window.End))
+		if window.Reason != "" {
+			fmt.Printf("             %s\n", window.Reason)
+		}
+	} else {
+		fmt.Println("  No freeze in effect")
+	}
+	upcoming, err := c.executor.UpcomingFreezes(ctx, 7*24*time.Hour)
+	for _, window := range upcoming {
+		fmt.Printf("  Upcoming:  %s, %s to %s\n", window.Name, timefmt.DateTime(window.Start), timefmt.DateTime(window.End))
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	return nil
+}
+
+// showGuardrails shows the organization's guardrails bundle in effect and
+// where it comes from
+func (c *CLI) showGuardrails(ctx context.Context) error {
+	g := c.config.ActiveGuardrails()
+	if g == nil {
+		fmt.Println("No organization guardrails configured (add \"guardrails\" to config.json)")
+		return nil
+	}
+	bundle, err := c.executor.Guardrails(ctx)
+	if err != nil {
+		return err
+	}
+	source := c.config.ConfigPath
+	if c.config.Managed() {
+		source = config.ManagedPath() + " (managed; config.json cannot change it)"
+	}
+	url, _, _ := strings.Cut(g.URL, "?")
+
+	fmt.Println("\n🛡️  Organization Guardrails")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("  Source:   %s\n", source)
+	fmt.Printf("  Bundle:   %s\n", url)
+	if bundle.Version != "" {
+		fmt.Printf("  Version:  %s\n", bundle.Version)
+	}
+	fmt.Printf("  Verified: %s\n", timefmt.DateTime(bundle.FetchedAt))
+	fmt.Printf("  Policy:   %s, %s\n", plural(len(bundle.BlockedCommands), "blocked command"), plural(len(bundle.ApprovalRules), "approval rule"))
+	if bundle.Preamble != "" {
+		fmt.Println("  Preamble:")
+		for _, line := range strings.Split(strings.TrimSpace(bundle.Preamble), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	return nil
+}
+
+// simulate rehearses a plan in a throwaway container holding a copy of the
+// project and reports how far it got, so failures surface before the host
+// is touched
+func (c *CLI) simulate(ctx context.Context, commands []string) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	info := c.executor.Platform()
+	image := c.config.SimulateImage
+	if image == "" {
+		image = simulate.ImageFor(info)
+	}
+
+	fmt.Printf("\n🧪 Rehearsing %s in a throwaway %s container...\n", plural(len(commands), "command"), image)
+	ctx, cancel := context.WithTimeout(ctx, simulateTimeout)
+	defer cancel()
+	report, err := simulate.Run(ctx, simulate.Options{Image: image, Root: project.Root(cwd), Dir: cwd, Commands: commands}, info)
+	if err != nil && report == nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return
+	}
+
+	outcome := "passed"
+	for _, step := range report.Steps {
+		if step.ExitCode == 0 {
+			fmt.Printf("  ✅ %s\n", step.Command)
+			continue
+		}
+		outcome = "failed"
+		fmt.Printf("  ❌ %s (exit %d)\n", step.Command, step.ExitCode)
+		lines := strings.Split(step.Output, "\n")
+		if len(lines) > 15 {
+			lines = lines[len(lines)-15:]
+		}
+		for _, line := range lines {
+			fmt.Printf("     │ %s\n", line)
+		}
+	}
+	for _, cmd := range commands[len(report.Steps):] {
+		fmt.Printf("  ⏭️  %s (not reached)\n", cmd)
+	}
+	if err != nil {
+		outcome = "incomplete"
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	c.audit.Record("plan_simulated", map[string]string{"commands": strings.Join(commands, "\n"), "image": image, "outcome": outcome})
+
+	switch {
+	case report.Succeeded():
+		fmt.Printf("✅ Rehearsal passed in %s; the plan should also work on this machine\n", report.Duration.Round(time.Second))
+	case outcome == "failed":
+		fmt.Println("❌ Rehearsal failed; consider editing the plan before running it here")
+	}
+}
+
+// resolvePortConflicts asks how to handle each server the plan would start
+// on a port that is already in use, rewriting the plan to match
+func (c *CLI) resolvePortConflicts(ctx context.Context, result *executor.ExecutionResult) {
+	for _, conflict := range c.executor.PortConflicts(ctx, result) {
+		fmt.Printf("\n⚠️  Port %d is already in use by %s\n", conflict.Port, conflict.Owner())
+		fmt.Printf("   Step: %s\n", result.Commands[conflict.Step])
+
+		var options []string
+		if conflict.FreePort > 0 {
+			options = append(options, fmt.Sprintf("[f]ree port %d", conflict.FreePort))
+		}
+		if conflict.PID > 0 {
+			options = append(options, "[s]top "+conflict.Process)
+		}
+		options = append(options, "[k]eep")
+		fmt.Printf("   %s: ", strings.Join(options, " / "))
+
+		switch strings.ToLower(c.readLine()) {
+		case "f", "free":
+			if conflict.FreePort > 0 {
+				c.executor.UsePort(result, conflict, conflict.FreePort)
+				fmt.Printf("   → %s\n", result.Commands[conflict.Step])
+			}
+		case "s", "stop":
+			if err := c.executor.StopPortOwner(conflict); err != nil {
+				fmt.Printf("❌ %v\n", err)
+			} else {
+				fmt.Printf("   Stopped %s\n", conflict.Owner())
+			}
+		}
+	}
+}
+
+// tuneLocalModel fits the local model to the hardware once per session:
+// "model": "auto" becomes the best pulled model for the machine's memory,
+// and an unset context_length is sized to the memory the model leaves free
+func (c *CLI) tuneLocalModel(ctx context.Context) {
+	if c.config.AIProvider != "ollama" || c.modelTuned {
+		return
+	}
+	c.modelTuned = true
+
+	hw := c.executor.Platform().Hardware
+	ram, vram := hw.RAM, hw.VRAM()
+	pulled, err := ollama.New(c.config.BaseURL).Models(ctx)
+	if err != nil {
+		c.logger.Warn("Cannot list Ollama models to fit the hardware: %v", err)
+	}
+	if c.config.Model == models.Auto {
+		c.config.Model = models.Select(ram, vram, pulled)
+		c.logger.Info("Selected model %s for %s", c.config.Model, hw.Summary())
+	}
+	if c.config.ContextLength == 0 {
+		if m := ollama.Find(pulled, c.config.Model); m != nil {
+			c.config.ContextLength = models.ContextLength(ram, vram, uint64(m.Size))
+			c.logger.Info("Context length %d tokens for %s", c.config.ContextLength, c.config.Model)
+		}
+	}
+}
+
+// checkModel warns before the first prompt if Ollama is down or the
+// configured model has not been pulled
+func (c *CLI) checkModel(ctx context.Context) {
+	models, err := ollama.New(c.config.BaseURL).Models(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  %v\n   Start it with: ollama serve\n\n", err)
+		return
+	}
+	if ollama.Find(models, c.config.Model) == nil {
+		c.offerModel(ctx)
+	}
+}
+
+// offerModel offers to pull the configured Ollama model or switch to one
+// that is already pulled; it reports whether a usable model is now set
+func (c *CLI) offerModel(ctx context.Context) bool {
+	client := ollama.New(c.config.BaseURL)
+	models, err := client.Models(ctx)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return false
+	}
+
+	fmt.Printf("\n⚠️  Ollama model %q is not pulled\n", c.config.Model)
+	options := []string{"[p]ull it"}
+	fallback := ollama.Fallback(models, c.config.Model)
+	if fallback != "" {
+		options = append(options, fmt.Sprintf("[u]se %s", fallback))
+	}
+	options = append(options, "[c]ancel")
+	fmt.Printf("   %s: ", strings.Join(options, " / "))
+
+	switch strings.ToLower(c.readLine()) {
+	case "p", "pull":
+		if err := c.pullModel(ctx, client, c.config.Model); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return false
+		}
+		return true
+	case "u", "use":
+		if fallback == "" {
+			return false
+		}
+		c.config.Model = fallback
+		fmt.Printf("✅ Using %s for this session (set \"model\" in config.json to keep it)\n", fallback)
+		return true
+	}
+	return false
+}
+
+// pullModel downloads a model with a progress bar
+func (c *CLI) pullModel(ctx context.Context, client *ollama.Client, name string) error {
+	fmt.Printf("⬇️  Pulling %s\n", name)
+	status := ""
+	err := client.Pull(ctx, name, func(p ollama.Progress) {
+		if p.Status != status && status != "" {
+			fmt.Println()
+		}
+		status = p.Status
+		fmt.Printf("\r   %-30s %s", p.Status, ollama.Bar(p, 24))
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Pulled %s\n", name)
+	return nil
+}
+
+// showChanges summarizes the files and packages a plan modified
+func (c *CLI) showChanges(changes *executor.Changes) {
+	if changes == nil {
+		return
+	}
+	if changes.Empty() {
+		fmt.Println("\n🔍 No file or package changes detected")
+		return
+	}
+
+	fmt.Println("\n🔍 What changed:")
+	showChangedFiles(changes, 20)
+	for _, pkg := range changes.Packages {
+		switch {
+		case pkg.Before == "":
+			fmt.Printf("  📦 %s: installed %s %s\n", pkg.Manager, pkg.Name, pkg.After)
+		case pkg.After == "":
+			fmt.Printf("  📦 %s: removed %s %s\n", pkg.Manager, pkg.Name, pkg.Before)
+		default:
+			fmt.Printf("  📦 %s: %s %s → %s\n", pkg.Manager, pkg.Name, pkg.Before, pkg.After)
+		}
+	}
+}
+
+// showChangedFiles lists added, modified, and deleted files relative to the
+// working directory, at most limit of each
+func showChangedFiles(changes *executor.Changes, limit int) {
+	cwd, _ := os.Getwd()
+	show := func(icon string, paths []string) {
+		for i, path := range paths {
+			if i == limit {
+				fmt.Printf("  … and %d more\n", len(paths)-limit)
+				break
+			}
+			if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			fmt.Printf("  %s %s\n", icon, path)
+		}
+	}
+	show("+", changes.Added)
+	show("~", changes.Modified)
+	show("-", changes.Deleted)
+}
+
+// Answers to the workspace drift prompt
+const (
+	driftRegenerate = "regenerate"
+	driftContinue   = "continue"
+	driftCancel     = "cancel"
+)
+
+// confirmDrift warns that files changed while the plan awaited approval and
+// asks whether to regenerate it, run it anyway, or cancel
+func (c *CLI) confirmDrift(drift *executor.Changes) string {
+	n := len(drift.Added) + len(drift.Modified) + len(drift.Deleted)
+	fmt.Printf("\n⚠️  The workspace changed since this plan was generated (%s):\n", plural(n, "file"))
+	showChangedFiles(drift, 10)
+
+	choice := driftCancel
+	fmt.Print("\n🔄 Regenerate the plan against the current files? (regenerate/continue/cancel): ")
+	switch strings.ToLower(c.readLine()) {
+	case "regenerate", "r", "yes", "y":
+		choice = driftRegenerate
+	case "continue", "c":
+		choice = driftContinue
+	default:
+		fmt.Println("❌ Operation cancelled")
+	}
+	c.audit.Record("plan_drift", map[string]string{"files": strconv.Itoa(n), "choice": choice})
+	return choice
+}
+
+// offerPreview offers to open dev servers the plan started in the browser
+func (c *CLI) offerPreview(ctx context.Context, commands []string) {
+	for _, url := range executor.ServerURLs(commands) {
+		if !executor.WaitForServer(ctx, url, 15*time.Second) {
+			continue
+		}
+		fmt.Printf("\n🌐 Your dev server is running at %s — open it? (yes/no): ", url)
+		response := strings.ToLower(c.readLine())
+		if response != "yes" && response != "y" {
+			continue
+		}
+		if err := platform.Open(url); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+	}
+}
+
+// offerRollback reverses the completed steps of a plan that failed, so it
+// is not left half-applied, as rollback_on_failure says: after asking
+// (default), always, or never. Rolled back plans cannot be undone again.
+func (c *CLI) offerRollback(ctx context.Context, result *executor.ExecutionResult) {
+	if len(result.Rollback) == 0 {
+		return
+	}
+	fmt.Println("\n↩️  To roll back the completed steps, last first:")
+	for _, cmd := range result.Rollback {
+		fmt.Printf("  → %s\n", cmd)
+	}
+	for _, cmd := range result.Irreversible {
+		fmt.Printf("  ⚠️  Not reversible: %s\n", cmd)
+	}
+
+	switch c.config.RollbackOnFailure {
+	case "never":
+		fmt.Println("Run \"undo\" to roll back")
+		return
+	case "always":
+	default:
+		fmt.Print("\n⚠️  Roll back now? (yes/no): ")
+		if response := strings.ToLower(c.readLine()); response != "yes" && response != "y" {
+			fmt.Println("Run \"undo\" to roll back later")
+			return
+		}
+	}
+
+	err := c.executor.ExecuteCommands(ctx, result.Rollback)
+	c.audit.Record("task_rolled_back", map[string]string{
+		"input":    result.Prompt,
+		"commands": strings.Join(result.Rollback, "; "),
+		"success":  fmt.Sprint(err == nil),
+	})
+	if err != nil {
+		fmt.Printf("❌ Rollback failed: %v\nRun \"undo\" to try again\n", err)
+		return
+	}
+	result.Rollback = nil
+	fmt.Println("✅ Rolled back the completed steps")
+}
+
+// offerChangeSummary offers, after a multi-step task changed the
+// repository, to write a CHANGELOG entry or pull request description of
+// its changes
+func (c *CLI) offerChangeSummary(ctx context.Context, result *executor.ExecutionResult, before *executor.RepoState) {
+	fmt.Print("\n📝 The repository changed. Summarize it as a CHANGELOG entry or PR description? (changelog/pr/no): ")
+	kind := strings.ToLower(c.readLine())
+	switch kind {
+	case "changelog", "c":
+		kind = executor.SummaryChangelog
+	case "pr", "p":
+		kind = executor.SummaryPR
+	default:
+		return
+	}
+
+	summary, err := c.executor.SummarizeChanges(ctx, kind, result, before)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Printf("\n%s\n", summary)
+	if kind != executor.SummaryChangelog {
+		return
+	}
+	fmt.Print("\n📝 Add this entry to CHANGELOG.md? (yes/no): ")
+	if answer := strings.ToLower(c.readLine()); answer != "yes" && answer != "y" {
+		return
+	}
+	path, err := executor.AddChangelogEntry(before.Root, summary)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Added to %s\n", path)
+}
+
+// recordTask stores the outcome of a processed command for reporting
+func (c *CLI) recordTask(input string, result *executor.ExecutionResult, executed int, success bool, duration time.Duration) {
+	task := memory.Task{
+		Input:    input,
+		Provider: c.config.AIProvider,
+		Model:    c.config.Model,
+		Commands: executed,
+		Success:  success,
+		Duration: duration,
+	}
+	if result != nil {
+		task.Category = result.Intent
+		task.Tokens = result.TokensUsed
+		if result.Provider != "" {
+			task.Provider, task.Model = result.Provider, result.Model
+		}
+		task.Rollback = result.Rollback
+	}
+
+	if err := c.memory.RecordTask(task); err != nil {
+		c.logger.Warn("Failed to record task: %v", err)
+	}
+}
+
+// runReport prints an activity and savings report
+func (c *CLI) runReport(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	days := flags.Int("days", 7, "number of days to include")
+	format := flags.String("format", "terminal", "output format: terminal or markdown")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	until := time.Now()
+	since := until.AddDate(0, 0, -*days)
+
+	tasks, err := c.memory.TasksSince(since)
+	if err != nil {
+		return err
+	}
+
+	r := report.Build(tasks, since, until)
+	switch *format {
+	case "terminal":
+		fmt.Print(r.Terminal())
+	case "markdown", "md":
+		fmt.Print(r.Markdown())
+	default:
+		return fmt.Errorf("unknown report format: %s", *format)
+	}
+
+	return nil
+}
+
+// ruleStats summarizes how often one policy rule triggered
+type ruleStats struct {
+	rule     string
+	denied   int
+	held     int
+	programs map[string]int
+	details  map[string]int
+	projects map[string]bool
+	last     time.Time
+}
+
+// policy shows which rules block the user or creates a project exception:
+// policy stats [--days N] | policy allow <rule> [--program NAME]
+func (c *CLI) policy(args []string) error {
+	command := "stats"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "stats":
+		flags := flag.NewFlagSet("policy stats", flag.ContinueOnError)
+		days := flags.Int("days", 30, "number of days to include")
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		stats, err := c.policyStats(time.Now().AddDate(0, 0, -*days))
+		if err != nil {
+			return err
+		}
+		showPolicyStats(stats, *days)
+		return nil
+
+	case "allow":
+		flags := flag.NewFlagSet("policy allow", flag.ContinueOnError)
+		program := flags.String("program", "", "program to allow (default: the one the rule blocks most here)")
+		rest, err := parseInterspersed(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: devos policy allow <rule> [--program NAME]")
+		}
+		return c.allowInProject(rest[0], *program)
+
+	default:
+		return fmt.Errorf("usage: devos policy stats [--days N] | allow <rule> [--program NAME]")
+	}
+}
+
+// policyStats groups the policy triggers recorded since the given time by
+// rule, most frequent first
+func (c *CLI) policyStats(since time.Time) ([]*ruleStats, error) {
+	triggers, err := c.memory.PolicyTriggersSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	byRule := map[string]*ruleStats{}
+	var stats []*ruleStats
+	for _, t := range triggers {
+		s, ok := byRule[t.Rule]
+		if !ok {
+			s = &ruleStats{rule: t.Rule, programs: map[string]int{}, details: map[string]int{}, projects: map[string]bool{}}
+			byRule[t.Rule] = s
+			stats = append(stats, s)
+		}
+		if t.Action == string(policy.ActionDeny) {
+			s.denied++
+		} else {
+			s.held++
+		}
+		if programs := policy.Programs(t.Command); len(programs) > 0 {
+			s.programs[programs[0]]++
+		}
+		if t.Detail != "" {
+			s.details[t.Detail]++
+		}
+		s.projects[t.Project] = true
+		if t.CreatedAt.After(s.last) {
+			s.last = t.CreatedAt
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].denied+stats[i].held > stats[j].denied+stats[j].held
+	})
+	return stats, nil
+}
+
+// showPolicyStats prints the rules that triggered, with what they matched
+func showPolicyStats(stats []*ruleStats, days int) {
+	fmt.Printf("\n🛡️  Policy triggers (last %d days, recorded on this machine only)\n", days)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if len(stats) == 0 {
+		fmt.Print("  No commands were blocked or held for confirmation\n\n")
+		return
+	}
+
+	for _, s := range stats {
+		fmt.Printf("  %-24s %4d blocked  %4d held  (%s, last %s)\n",
+			s.rule, s.denied, s.held, plural(len(s.projects), "project"), timefmt.Date(s.last))
+		if top := topCounts(s.programs, 3); top != "" {
+			fmt.Printf("    programs: %s\n", top)
+		}
+		if top := topCounts(s.details, 3); top != "" {
+			fmt.Printf("    matched:  %s\n", top)
+		}
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Print("💡 Allow a rule's most blocked program in this project: devos policy allow <rule> [--program NAME]\n\n")
+}
+
+// topCounts renders the n most frequent keys, e.g. "rm (8), curl (3)"
+func topCounts(counts map[string]int, n int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s (%d)", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// allowInProject adds an approval rule that lets program run without
+// approval in the current project, ahead of the rule that blocked it. For
+// the sandbox, the exception also skips the built-in checks.
+func (c *CLI) allowInProject(rule, program string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root := project.Root(cwd)
+
+	known := rule == executor.SandboxRule
+	for _, r := range c.config.ApprovalRules {
+		known = known || r.Name == rule
+	}
+	if !known {
+		return fmt.Errorf("unknown policy rule: %s", rule)
+	}
+
+	if program == "" {
+		triggers, err := c.memory.PolicyTriggersSince(time.Now().AddDate(0, 0, -30))
+		if err != nil {
+			return err
+		}
+		counts := map[string]int{}
+		for _, t := range triggers {
+			if programs := policy.Programs(t.Command); t.Rule == rule && t.Project == root && len(programs) > 0 {
+				counts[programs[0]]++
+			}
+		}
+		program, _, _ = strings.Cut(topCounts(counts, 1), " ")
+		if program == "" {
+			return fmt.Errorf("rule %s has not blocked anything in %s recently; name the program with --program", rule, root)
+		}
+	}
+
+	exception := config.ApprovalRule{
+		Name:     fmt.Sprintf("%s-exception-%s-%s", rule, program, filepath.Base(root)),
+		Programs: []string{program},
+		Projects: []string{root},
+		Action:   string(policy.ActionAllow),
+		Sandbox:  rule == executor.SandboxRule,
+	}
+	fmt.Printf("\n🛡️  New rule %q: %s commands in %s run without approval", exception.Name, program, root)
+	if exception.Sandbox {
+		fmt.Print(", skipping the sandbox checks")
+	}
+	fmt.Print("\n⚠️  Add it to your config? (yes/no): ")
+	if response := strings.ToLower(c.readLine()); response != "yes" && response != "y" {
+		fmt.Println("❌ Cancelled")
+		return nil
+	}
+
+	// Save on top of the file rather than this session's settings
+	saved, err := config.Load()
+	if err != nil {
+		return err
+	}
+	saved.ApprovalRules = append([]config.ApprovalRule{exception}, saved.ApprovalRules...)
+	if err := saved.Save(); err != nil {
+		return err
+	}
+	c.config.ApprovalRules = append([]config.ApprovalRule{exception}, c.config.ApprovalRules...)
+	c.audit.Record("policy_exception_created", map[string]string{"rule": exception.Name, "for": rule, "program": program, "project": root})
+	fmt.Printf("✅ Added rule %s to %s\n", exception.Name, saved.ConfigPath)
+	return nil
+}
+
+// issue lists, shows, and works on tickets from the configured Jira or
+// Linear tracker
+func (c *CLI) issue(ctx context.Context, args []string) error {
+	usage := fmt.Errorf("usage: devos issue list | show <key> | work <key> [instructions...] | comment <key> <text...>")
+	if c.config.IssueTracker == nil {
+		return fmt.Errorf("no issue tracker configured; add \"issue_tracker\" to %s", c.config.ConfigPath)
+	}
+	t := c.config.IssueTracker
+	tracker, err := issues.New(issues.Options{Type: t.Type, URL: t.URL, Email: t.Email, Token: t.Token})
+	if err != nil {
+		return err
+	}
+
+	command := "list"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "list":
+		assigned, err := tracker.Assigned(ctx)
+		if err != nil {
+			return err
+		}
+		if len(assigned) == 0 {
+			fmt.Println("No open issues assigned to you")
+			return nil
+		}
+		fmt.Println("\n🎫 Assigned Issues")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		for _, issue := range assigned {
+			fmt.Printf("  %-12s %-14s %s\n", issue.Key, issue.Status, issue.Title)
+		}
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Print("💡 Start on one with: devos issue work <key>\n\n")
+		return nil
+
+	case "show":
+		if len(args) != 2 {
+			return usage
+		}
+		issue, err := tracker.Issue(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n🎫 %s: %s\n", issue.Key, issue.Title)
+		fmt.Printf("  Status: %s\n  %s\n", issue.Status, issue.URL)
+		if issue.Description != "" {
+			fmt.Printf("\n%s\n", issue.Description)
+		}
+		fmt.Println()
+		return nil
+
+	case "work":
+		if len(args) < 2 {
+			return usage
+		}
+		issue, err := tracker.Issue(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n🎫 %s: %s\n", issue.Key, issue.Title)
+		input := strings.Join(args[2:], " ")
+		if input == "" {
+			input = issue.Title
+		}
+		result, executed, err := c.runPlan(ctx, input, func() (*executor.ExecutionResult, error) {
+			c.tuneLocalModel(ctx)
+			return c.executor.ExecuteIssue(ctx, input, issue)
+		})
+		if result != nil && (executed > 0 || err != nil) {
+			c.reportToIssue(ctx, tracker, issue, input, result, executed, err)
+		}
+		return err
+
+	case "comment":
+		if len(args) < 3 {
+			return usage
+		}
+		if err := tracker.Comment(ctx, args[1], strings.Join(args[2:], " ")); err != nil {
+			return err
+		}
+		c.audit.Record("issue_commented", map[string]string{"issue": args[1], "tracker": t.Type})
+		fmt.Printf("✅ Commented on %s\n", args[1])
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// reportToIssue posts a summary of the work done for an issue, with the
+// pull request for the current branch if there is one, as the tracker's
+// "comment" setting allows
+func (c *CLI) reportToIssue(ctx context.Context, tracker issues.Tracker, issue *issues.Issue, input string, result *executor.ExecutionResult, executed int, runErr error) {
+	setting := c.config.IssueTracker.Comment
+	if setting == "never" {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "DevOS worked on this issue: %s\n\n", input)
+	if runErr != nil {
+		fmt.Fprintf(&b, "Result: failed: %v\n", runErr)
+	} else {
+		fmt.Fprintf(&b, "Result: succeeded (%s)\n", plural(executed, "command"))
+	}
+	if len(result.Commands) > 0 {
+		b.WriteString("\nCommands:\n")
+		for _, cmd := range result.Commands {
+			fmt.Fprintf(&b, "- %s\n", cmd)
+		}
+	}
+	if changes := result.Changes; changes != nil && !changes.Empty() {
+		fmt.Fprintf(&b, "\nFiles: %d added, %d modified, %d deleted\n", len(changes.Added), len(changes.Modified), len(changes.Deleted))
+	}
+	if pr := pullRequestURL(ctx); pr != "" {
+		fmt.Fprintf(&b, "\nPull request: %s\n", pr)
+	}
+	summary := strings.TrimSpace(b.String())
+
+	if setting != "always" {
+		fmt.Printf("\n🎫 Summary for %s:\n%s\n", issue.Key, summary)
+		fmt.Printf("\n⚠️  Post this summary on %s? (yes/no): ", issue.Key)
+		if response := strings.ToLower(c.readLine()); response != "yes" && response != "y" {
+			return
+		}
+	}
+	if err := tracker.Comment(ctx, issue.Key, summary); err != nil {
+		fmt.Printf("⚠️  Failed to comment on %s: %v\n", issue.Key, err)
+		return
+	}
+	c.audit.Record("issue_commented", map[string]string{"issue": issue.Key, "tracker": c.config.IssueTracker.Type})
+	fmt.Printf("✅ Posted a summary on %s\n", issue.URL)
+}
+
+// pullRequestURL returns the pull request for the current branch, found with
+// the GitHub CLI, or "" if there is none
+func pullRequestURL(ctx context.Context) string {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "gh", "pr", "view", "--json", "url", "--jq", ".url").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// api registers OpenAPI specs so plans can call those APIs correctly, and
+// lists, shows, and removes them
+func (c *CLI) api(ctx context.Context, args []string) error {
+	command := "list"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	dir := c.executor.APIDir()
+	usage := fmt.Errorf("usage: devos api list | add <file|url> [--name NAME] | show <name> [words...] | remove <name>")
+
+	switch command {
+	case "list":
+		specs, err := openapi.Load(dir)
+		if err != nil {
+			return err
+		}
+		if len(specs) == 0 {
+			fmt.Println("No APIs registered (add one with \"devos api add <spec.yaml|url>\")")
+			return nil
+		}
+		t := table.New("name", "title", "version", "operations", "servers")
+		for _, spec := range specs {
+			t.Add(spec.Name, spec.Title, spec.Version, len(spec.Operations), strings.Join(spec.Servers, ", "))
+		}
+		return render(t, table.FormatTable, "")
+
+	case "add":
+		flags := flag.NewFlagSet("api add", flag.ContinueOnError)
+		name := flags.String("name", "", "name to refer to the API by (default: the file name)")
+		rest, err := parseInterspersed(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(rest) != 1 {
+			return usage
+		}
+		spec, err := openapi.Add(ctx, dir, *name, rest[0])
+		if err != nil {
+			return err
+		}
+		c.audit.Record("api_added", map[string]string{"name": spec.Name, "source": rest[0]})
+		fmt.Printf("✅ Registered %s (%s %s, %d operations); mention %q in a request to use it\n",
+			spec.Name, spec.Title, spec.Version, len(spec.Operations), spec.Name)
+		return nil
+
+	case "show":
+		if len(args) < 2 {
+			return usage
+		}
+		spec, err := openapi.Find(dir, args[1])
+		if err != nil {
+			return err
+		}
+		// Words after the name rank operations as a request would
+		summary := spec.Summarize(strings.Join(args[2:], " "), 0)
+		fmt.Printf("\n🔌 %s: %s %s\n", spec.Name, spec.Title, spec.Version)
+		if len(spec.Servers) > 0 {
+			fmt.Printf("  Servers: %s\n", strings.Join(spec.Servers, ", "))
+		}
+		if len(spec.Auth) > 0 {
+			fmt.Printf("  Auth:    %s\n", strings.Join(spec.Auth, "; "))
+		}
+		fmt.Println("\nOperations:")
+		for _, op := range summary.Operations {
+			fmt.Printf("  • %s\n", op)
+		}
+		fmt.Println()
+		return nil
+
+	case "remove", "rm":
+		if len(args) != 2 {
+			return usage
+		}
+		if err := openapi.Remove(dir, args[1]); err != nil {
+			return err
+		}
+		c.audit.Record("api_removed", map[string]string{"name": args[1]})
+		fmt.Printf("🗑️  Removed %s\n", args[1])
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// runbook lists, shows, customizes, and runs the parameterized runbooks
+func (c *CLI) runbook(ctx context.Context, args []string) error {
+	command := "list"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	dir := c.executor.RunbookDir()
+	usage := fmt.Errorf("usage: devos runbook list | show <name> | run <name> [param=value...] | customize <name>")
+
+	switch command {
+	case "list":
+		runbooks, err := runbook.Load(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n📓 Runbooks")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		for _, r := range runbooks {
+			marker := " "
+			if r.Path != "" {
+				marker = "*"
+			}
+			fmt.Printf(" %s %-20s %s\n", marker, r.Name, r.Description)
+		}
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("* saved in %s\n\n", dir)
+		return nil
+
+	case "show":
+		if len(args) != 2 {
+			return usage
+		}
+		r, err := runbook.Find(dir, args[1])
+		if err != nil {
+			return err
+		}
+		showRunbook(r)
+		return nil
+
+	case "run":
+		if len(args) < 2 {
+			return usage
+		}
+		params := map[string]string{}
+		for _, arg := range args[2:] {
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("expected param=value, got %q", arg)
+			}
+			params[name] = value
+		}
+		input := strings.Join(append([]string{"runbook"}, args[1:]...), " ")
+		_, _, err := c.runPlan(ctx, input, func() (*executor.ExecutionResult, error) {
+			return c.executor.Runbook(args[1], params)
+		})
+		return err
+
+	case "customize":
+		if len(args) != 2 {
+			return usage
+		}
+		path, err := runbook.Customize(dir, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Edit %s to customize %s; the saved copy replaces the built-in runbook\n", path, args[1])
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// showRunbook prints a runbook's parameters and steps
+func showRunbook(r *runbook.Runbook) {
+	fmt.Printf("\n📓 %s: %s\n", r.Name, r.Description)
+	if r.Path != "" {
+		fmt.Printf("  Saved in %s\n", r.Path)
+	}
+	if len(r.Params) > 0 {
+		fmt.Println("\nParameters:")
+		for _, p := range r.Params {
+			detail := "optional"
+			switch {
+			case p.Required:
+				detail = "required"
+			case p.Default != "":
+				detail = "default " + p.Default
+			}
+			fmt.Printf("  %-12s %s (%s)\n", p.Name, p.Description, detail)
+		}
+	}
+	fmt.Println("\nSteps:")
+	for i, step := range r.Steps {
+		condition := ""
+		if step.If != "" {
+			condition = fmt.Sprintf(" (when %s is set)", step.If)
+		}
+		fmt.Printf("  %d. %s%s\n     %s\n", i+1, step.Description, condition, step.Command)
+	}
+	fmt.Println()
+}
+
+// generate writes a validated CI workflow or Makefile for the current
+// project, or validates an existing one with "check"
+func (c *CLI) generate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: devos generate ci|makefile|check <file> [--docker] [--image name] [--force]")
+	}
+
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	docker := flags.Bool("docker", false, "build a Docker image after tests")
+	image := flags.String("image", "", "image name for Docker builds")
+	force := flags.Bool("force", false, "overwrite an existing file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	opts := cigen.Options{
+		Languages: project.Detect(cwd).Languages,
+		Docker:    *docker || fileExists("Dockerfile"),
+		Image:     *image,
+	}
+
+	var path, content string
+	var problems []error
+	switch args[0] {
+	case "ci":
+		path, content = cigen.WorkflowPath, cigen.Workflow(opts)
+		problems = cigen.ValidateWorkflow(content)
+	case "makefile":
+		path, content = "Makefile", cigen.Makefile(opts)
+		problems = cigen.ValidateMakefile(content)
+	case "check":
+		if flags.NArg() == 0 {
+			return fmt.Errorf("usage: devos generate check <file>")
+		}
+		data, err := os.ReadFile(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(filepath.Base(flags.Arg(0)), "Makefile") {
+			problems = cigen.ValidateMakefile(string(data))
+		} else {
+			problems = cigen.ValidateWorkflow(string(data))
+		}
+		if len(problems) == 0 {
+			fmt.Printf("✅ %s is valid\n", flags.Arg(0))
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown generator: %s", args[0])
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("  ✗ %v\n", problem)
+		}
+		return fmt.Errorf("%w: %d validation problem(s)", executor.ErrValidationBlocked, len(problems))
+	}
+
+	if fileExists(path) && !*force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Wrote %s (validated)\n", path)
+	return nil
+}
+
+// helm lists charts, reads or bumps values, and renders charts. Setting a
+// value shows the diff of the rendered manifests it causes.
+func (c *CLI) helm(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("helm", flag.ContinueOnError)
+	chartName := flags.String("chart", "", "chart name (default: the only chart)")
+	env := flags.String("env", "", "environment values file, e.g. staging")
+	if len(args) == 0 {
+		return fmt.Errorf("usage: devos helm list|get <key>|set <key> <value>|template [--chart name] [--env name]")
+	}
+	positional, err := parseInterspersed(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	charts := helm.FindCharts(project.Detect(cwd).Root)
+
+	if args[0] == "list" {
+		if len(charts) == 0 {
+			fmt.Println("No Helm charts found")
+		}
+		for _, chart := range charts {
+			fmt.Printf("⎈ %s %s (%s)\n", chart.Name, chart.Version, chart.Path)
+			for _, values := range chart.ValuesFiles {
+				fmt.Printf("    %s\n", values)
+			}
+		}
+		return nil
+	}
+
+	var chart *helm.Chart
+	for i := range charts {
+		if charts[i].Name == *chartName || (*chartName == "" && len(charts) == 1) {
+			chart = &charts[i]
+		}
+	}
+	if chart == nil {
+		return fmt.Errorf("chart not found; use --chart to pick one of %d chart(s)", len(charts))
+	}
+	values, err := chart.ValuesFile(*env)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: devos helm get <key>")
+		}
+		value, err := helm.GetValue(values, positional[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+	case "set":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: devos helm set <key> <value>")
+		}
+		before, renderErr := chart.Template(ctx, values)
+		old, err := helm.SetValue(values, positional[0], positional[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✏️  %s: %s → %s (%s)\n", positional[0], old, positional[1], values)
+		if renderErr != nil {
+			fmt.Printf("⚠️  Rendered diff unavailable: %v\n", renderErr)
+			return nil
+		}
+		after, err := chart.Template(ctx, values)
+		if err != nil {
+			return err
+		}
+		fmt.Print("\n📄 Rendered manifest changes:\n" + helm.Diff(before, after))
+	case "template":
+		out, err := chart.Template(ctx, values)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+	default:
+		return fmt.Errorf("unknown helm command: %s", args[0])
+	}
+	return nil
+}
+
+// ssh is the guided SSH assistant: it generates keys without ever
+// overwriting existing ones, loads keys into the agent, and edits
+// ~/.ssh/config only after showing the exact diff
+func (c *CLI) ssh(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("ssh", flag.ContinueOnError)
+	name := flags.String("name", "", "key name suffix, e.g. github")
+	keyType := flags.String("type", "ed25519", "key type")
+	comment := flags.String("comment", "", "key comment")
+	hostname := flags.String("hostname", "", "host name or address")
+	user := flags.String("user", "", "remote user")
+	port := flags.Int("port", 0, "remote port")
+	identity := flags.String("identity", "", "identity file")
+	if len(args) == 0 {
+		return fmt.Errorf("usage: devos ssh keygen [--name n] [--type t] [--comment c] | agent-add <key> | host <alias> --hostname h [--user u] [--port p] [--identity f]")
+	}
+	positional, err := parseInterspersed(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "keygen":
+		path, err := sshsetup.KeyPath(*keyType, *name)
+		if err != nil {
+			return err
+		}
+		if err := sshsetup.CheckNewKey(path); err != nil {
+			return fmt.Errorf("%w; choose another --name", err)
+		}
+		fmt.Printf("🔑 Generating %s key at %s\n", *keyType, path)
+		if err := sshsetup.GenerateKey(ctx, path, *keyType, *comment); err != nil {
+			return err
+		}
+		c.audit.Record("ssh_key_generated", map[string]string{"path": path, "type": *keyType})
+		fmt.Printf("✅ Public key: %s.pub\n", path)
+
+	case "agent-add":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: devos ssh agent-add <key>")
+		}
+		if err := sshsetup.AddToAgent(ctx, positional[0]); err != nil {
+			return err
+		}
+		c.audit.Record("ssh_key_added_to_agent", map[string]string{"path": positional[0]})
+
+	case "host":
+		if len(positional) != 1 || *hostname == "" {
+			return fmt.Errorf("usage: devos ssh host <alias> --hostname h [--user u] [--port p] [--identity f]")
+		}
+		change, err := sshsetup.UpsertHost(sshsetup.HostEntry{
+			Alias: positional[0], HostName: *hostname, User: *user, Port: *port, IdentityFile: *identity,
+		})
+		if err != nil {
+			return err
+		}
+		diff := change.Diff()
+		if diff == "" {
+			fmt.Println("✅ ~/.ssh/config already has this entry")
+			return nil
+		}
+		fmt.Printf("\n📝 Changes to %s:\n%s", change.Path, diff)
+		fmt.Print("\n⚠️  Write these changes? (yes/no): ")
+		response := strings.ToLower(c.readLine())
+		if response != "yes" && response != "y" {
+			fmt.Println("❌ No changes written")
+			return nil
+		}
+		if err := change.Apply(); err != nil {
+			return err
+		}
+		c.audit.Record("ssh_config_changed", map[string]string{"path": change.Path, "host": positional[0]})
+		fmt.Println("✅ Updated (previous version saved as config.bak)")
+
+	default:
+		return fmt.Errorf("unknown ssh command: %s", args[0])
+	}
+	return nil
+}
+
+// service queries and controls system services through the platform's
+// service manager (systemd, launchd, or Windows services)
+func (c *CLI) service(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: devos service status <name> | list [filter] | start|stop|restart <name>")
+	}
+
+	switch args[0] {
+	case "list":
+		filter := ""
+		if len(args) > 1 {
+			filter = args[1]
+		}
+		list, err := services.List(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			icon := "⚪"
+			if s.Active {
+				icon = "🟢"
+			}
+			fmt.Printf("%s %-40s %s\n", icon, s.Name, s.State)
+		}
+		return nil
+
+	case "status":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: devos service status <name>")
+		}
+		s, err := services.Status(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		printService(s)
+		if !s.Healthy() {
+			c.showServiceLogs(ctx, s)
+		}
+		return nil
+
+	case "start", "stop", "restart":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: devos service %s <name>", args[0])
+		}
+		fmt.Printf("⚠️  %s %s via %s? (yes/no): ", args[0], args[1], services.Manager())
+		response := strings.ToLower(c.readLine())
+		if response != "yes" && response != "y" {
+			fmt.Println("❌ Cancelled")
+			return nil
+		}
+		err := services.Control(ctx, args[0], args[1])
+		c.audit.Record("service_"+args[0], map[string]string{"service": args[1], "ok": fmt.Sprint(err == nil)})
+		if err != nil {
+			return err
+		}
+		if s, err := services.Status(ctx, args[1]); err == nil {
+			printService(s)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown service command: %s", args[0])
+	}
+}
+
+// models lists, pulls, removes, and verifies local models: Ollama models and
+// GGUF files in the DevOS models directory
+func (c *CLI) models(ctx context.Context, args []string) error {
+	command, listArgs := "list", args
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, listArgs = args[0], args[1:]
+	}
+	dir := filepath.Join(c.config.Dir(), "models")
+	client := ollama.New(c.config.BaseURL)
+
+	switch command {
+	case "list":
+		return c.listModels(ctx, client, dir, listArgs)
+
+	case "pull":
+		flags := flag.NewFlagSet("models pull", flag.ContinueOnError)
+		checksum := flags.String("sha256", "", "expected SHA-256 of a GGUF download")
+		rest, err := parseInterspersed(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: devos models pull <ollama-model | https://.../model.gguf> [--sha256 HEX]")
+		}
+		if !models.IsFileSource(rest[0]) {
+			return c.pullModel(ctx, client, rest[0])
+		}
+		fmt.Printf("⬇️  Downloading %s\n", rest[0])
+		status := ""
+		file, err := models.PullFile(ctx, rest[0], dir, *checksum, func(p ollama.Progress) {
+			if p.Status != status && status != "" {
+				fmt.Println()
+			}
+			status = p.Status
+			fmt.Printf("\r   %-30s %s", p.Status, ollama.Bar(p, 24))
+		})
+		fmt.Println()
+		if err != nil {
+			return err
+		}
+		c.audit.Record("model_pulled", map[string]string{"model": file.Name, "source": rest[0], "sha256": file.SHA256})
+		fmt.Printf("✅ Saved %s (%s, sha256 %s)\n", file.Path, ollama.FormatSize(file.Size), file.SHA256)
+		return nil
+
+	case "remove", "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: devos models remove <name>")
+		}
+		file, err := models.FindFile(dir, args[1])
+		if err != nil {
+			return err
+		}
+		what := args[1] + " from Ollama"
+		if file != nil {
+			what = file.Path
+		}
+		fmt.Printf("⚠️  Remove %s? (yes/no): ", what)
+		response := strings.ToLower(c.readLine())
+		if response != "yes" && response != "y" {
+			fmt.Println("❌ Cancelled")
+			return nil
+		}
+		if file != nil {
+			err = models.RemoveFile(file)
+		} else {
+			err = client.Delete(ctx, args[1])
+		}
+		c.audit.Record("model_removed", map[string]string{"model": args[1], "ok": fmt.Sprint(err == nil)})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Removed %s\n", args[1])
+		return nil
+
+	case "verify":
+		files, err := models.Files(dir)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("No GGUF models to verify (Ollama checks its own downloads)")
+			return nil
+		}
+		failed := 0
+		for i, f := range files {
+			if len(args) > 1 && f.Name != strings.TrimSuffix(args[1], ".gguf") {
+				continue
+			}
+			switch err := models.Verify(&files[i]); {
+			case err != nil:
+				failed++
+				fmt.Printf("❌ %s: %v\n", f.Name, err)
+			case f.SHA256 == "":
+				fmt.Printf("⚠️  %s: valid GGUF header, no recorded checksum\n", f.Name)
+			default:
+				fmt.Printf("✅ %s: checksum matches\n", f.Name)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d model(s) failed verification", failed)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("usage: devos models [list] | pull <name|url> | remove <name> | verify [name]")
+	}
+}
+
+// listModels shows local models with their disk usage and the model
+// recommended for this machine's memory
+func (c *CLI) listModels(ctx context.Context, client *ollama.Client, dir string, args []string) error {
+	flags := flag.NewFlagSet("models list", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	t := table.New("source", "name", "size", "modified", "active")
+	t.Group = "source"
+	var total int64
+	pulled, err := client.Models(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+	for _, m := range pulled {
+		active := c.config.AIProvider == "ollama" && ollama.Find([]ollama.Model{m}, c.config.Model) != nil
+		t.Add("ollama", m.Name, ollama.FormatSize(m.Size), m.ModifiedAt, active)
+		total += m.Size
+	}
+
+	files, err := models.Files(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		active := c.config.AIProvider == "builtin" && strings.TrimSuffix(c.config.Model, ".gguf") == f.Name
+		t.Add("gguf", f.Name, ollama.FormatSize(f.Size), f.Modified, active)
+		total += f.Size
+	}
+
+	if *format != table.FormatTable {
+		return render(t, *format, *columns)
+	}
+	if len(t.Rows) == 0 {
+		fmt.Printf("No models in Ollama or %s\n", dir)
+	} else if err := render(t, *format, *columns); err != nil {
+		return err
+	}
+	fmt.Printf("\n💾 Disk usage: %s\n", ollama.FormatSize(total))
+
+	hw := c.executor.Platform().Hardware
+	if hw.RAM > 0 {
+		rec := models.Recommend(hw.RAM, hw.VRAM())
+		fmt.Printf("💡 Recommended for %s: %s\n", hw.Summary(), rec.Ollama)
+		fmt.Printf("   devos models pull %s\n   devos models pull %s\n", rec.Ollama, rec.GGUF)
+	}
+	return nil
+}
+
+// printService shows a service's state
+func printService(s *services.Service) {
+	icon := "🟢"
+	if !s.Healthy() {
+		icon = "🔴"
+	}
+	fmt.Printf("%s %s (%s)\n", icon, s.Name, s.Manager)
+	fmt.Printf("  State:     %s\n", s.State)
+	if s.Enabled != "" {
+		fmt.Printf("  At boot:   %s\n", s.Enabled)
+	}
+	if s.PID > 0 {
+		fmt.Printf("  PID:       %d\n", s.PID)
+	}
+	if !s.Since.IsZero() {
+		fmt.Printf("  Since:     %s (%s ago)\n", timefmt.DateTime(s.Since), time.Since(s.Since).Round(time.Minute))
+	}
+	if s.Result != "" && s.Result != "success" {
+		fmt.Printf("  Last stop: %s (exit status %d)\n", s.Result, s.ExitCode)
+	}
+	if s.Restarts > 0 {
+		fmt.Printf("  Restarts:  %d\n", s.Restarts)
+	}
+}
+
+// showServiceLogs prints recent errors logged by an unhealthy service
+func (c *CLI) showServiceLogs(ctx context.Context, s *services.Service) {
+	since := time.Now().Add(-24 * time.Hour)
+	if !s.Since.IsZero() && s.Since.Before(since) {
+		since = s.Since.Add(-time.Hour)
+	}
+	entries, err := logsource.Read(ctx, logsource.Query{Unit: s.Name, Since: since, Errors: true, Lines: 100})
+	if err != nil {
+		c.logger.Warn("Failed to read logs for %s: %v", s.Name, err)
+		return
+	}
+	if len(entries) > 0 {
+		fmt.Printf("\n📜 Errors logged since %s:\n%s", timefmt.DateTime(since), logsource.Summarize(entries, 15))
+	}
+}
+
+// logs shows a summarized excerpt of the system log, optionally asking the
+// AI engine to diagnose the problem from it
+func (c *CLI) logs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	unit := flags.String("unit", "", "service, unit, or event source")
+	since := flags.String("since", "1h", "start of the range, e.g. 2h, 3d, yesterday, 2006-01-02 15:04")
+	until := flags.String("until", "", "end of the range")
+	errorsOnly := flags.Bool("errors", false, "only errors and worse")
+	lines := flags.Int("lines", 200, "maximum entries to read")
+	file := flags.String("file", "", "read a log file instead of the system log")
+	self := flags.Bool("self", false, "read DevOS's own log, e.g. to find out why a command failed")
+	diagnose := flags.String("diagnose", "", "question for the AI to answer from the excerpt")
+	if _, err := parseInterspersed(flags, args); err != nil {
+		return err
+	}
+	if *self && *file != "" {
+		return fmt.Errorf("--self and --file cannot be combined")
+	}
+
+	q := logsource.Query{Unit: *unit, Errors: *errorsOnly, Lines: *lines, File: *file, Self: *self}
+	var err error
+	now := timefmt.In(time.Now())
+	if q.Since, err = logsource.ParseSince(*since, now); err != nil {
+		return err
+	}
+	if q.Until, err = logsource.ParseSince(*until, now); err != nil {
+		return err
+	}
+
+	entries, err := logsource.Read(ctx, q)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No matching log entries")
+		return nil
+	}
+	excerpt := logsource.Summarize(entries, 40)
+	fmt.Print(excerpt)
+
+	if *diagnose == "" {
+		return nil
+	}
+	evidence := map[string]interface{}{"log_excerpt": excerpt}
+	if *file != "" {
+		evidence["log_file"] = *file
+	}
+	if *self {
+		// DevOS's own log is diagnosed against its own failure modes
+		evidence = map[string]interface{}{"devos_logs": excerpt}
+		if last := logsource.LastError(entries); last != nil {
+			evidence["devos_last_error"] = timefmt.DateTime(last.Time) + " " + last.Message
+		}
+	}
+	return c.diagnose(ctx, *diagnose, evidence)
+}
+
+// diagnose shows the AI engine's interpretation of collected evidence and
+// the follow-up commands it suggests
+func (c *CLI) diagnose(ctx context.Context, question string, evidence map[string]interface{}) error {
+	result, err := c.executor.Diagnose(ctx, question, evidence)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%s\n", result.Output)
+	if len(result.Commands) > 0 {
+		fmt.Println("\nSuggested commands:")
+		for _, cmd := range result.Commands {
+			fmt.Printf("  $ %s\n", cmd)
+		}
+	}
+	return nil
+}
+
+// net diagnoses connectivity to a host layer by layer (DNS, TCP, TLS), or
+// runs a single check
+func (c *CLI) net(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("net", flag.ContinueOnError)
+	trace := flags.Bool("trace", false, "traceroute when the host is unreachable")
+	asJSON := flags.Bool("json", false, "print the structured report")
+	diagnose := flags.String("diagnose", "", "question for the AI to answer from the results")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: devos net [dns|ping|trace|tls] <host[:port]|url> [--trace] [--json] [--diagnose \"question\"]")
+	}
+
+	mode := "all"
+	switch positional[0] {
+	case "dns", "ping", "trace", "tls":
+		mode = positional[0]
+		positional = positional[1:]
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: devos net %s <host>", mode)
+	}
+	target, err := netdiag.ParseTarget(positional[0])
+	if err != nil {
+		return err
+	}
+
+	var report *netdiag.Report
+	switch mode {
+	case "dns":
+		lookup, err := netdiag.Resolve(ctx, target.Host)
+		if err != nil {
+			return err
+		}
+		report = &netdiag.Report{Target: target, Lookup: lookup}
+	case "ping":
+		ping, err := netdiag.TCPPing(ctx, net.JoinHostPort(target.Host, strconv.Itoa(target.Port)), 5)
+		report = &netdiag.Report{Target: target, Ping: ping}
+		if err != nil && !*asJSON {
+			fmt.Printf("❌ %s: %v\n", ping.Address, err)
+		}
+	case "trace":
+		hops, err := netdiag.Traceroute(ctx, target.Host, 30)
+		if err != nil {
+			return err
+		}
+		report = &netdiag.Report{Target: target, Hops: hops}
+	case "tls":
+		cert, err := netdiag.CheckTLS(ctx, target.Host, target.Port)
+		report = &netdiag.Report{Target: target, Certificate: cert}
+		if err != nil && !*asJSON {
+			fmt.Printf("❌ TLS: %v\n", err)
+		}
+	default:
+		report = netdiag.Diagnose(ctx, target, *trace)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printNetReport(report)
+	}
+
+	if *diagnose == "" {
+		return nil
+	}
+	return c.diagnose(ctx, *diagnose, map[string]interface{}{"net_report": report})
+}
+
+// monitor runs certificate, DNS, and reachability checks now: the
+// configured monitors, or a single check given on the command line. It
+// fails when any check does, so it can gate scripts.
+func (c *CLI) monitor(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	warnDays := flags.Int("warn-days", monitor.DefaultWarnDays, "days before certificate expiry to warn")
+	record := flags.String("record", "A", "DNS record type: A, AAAA, CNAME, MX, NS, or TXT")
+	var expect []string
+	flags.Func("expect", "DNS value the record must include (repeatable)", func(value string) error {
+		expect = append(expect, value)
+		return nil
+	})
+	format, columns := outputFlags(flags)
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var monitors []config.Monitor
+	if len(positional) > 0 && (positional[0] == "cert" || positional[0] == "dns" || positional[0] == "reachable") {
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: devos monitor %s <target>", positional[0])
+		}
+		monitors = []config.Monitor{{
+			Name: positional[1], Check: positional[0], Target: positional[1],
+			Record: *record, Expect: expect, WarnDays: *warnDays,
+		}}
+	} else {
+		for _, m := range c.config.Monitors {
+			if len(positional) == 0 || slices.Contains(positional, m.Name) {
+				monitors = append(monitors, m)
+			}
+		}
+		if len(monitors) == 0 {
+			if len(positional) > 0 {
+				return fmt.Errorf("no monitor named %s (see \"monitors\" in config.json)", strings.Join(positional, ", "))
+			}
+			fmt.Println("No monitors configured (add \"monitors\" to config.json, or run devos monitor cert|dns|reachable <target>)")
+			return nil
+		}
+	}
+
+	t := table.New("name", "check", "target", "status", "detail")
+	failed := 0
+	for _, m := range monitors {
+		r := monitor.Run(ctx, m)
+		if r.Status == monitor.StatusFail {
+			failed++
+		}
+		t.Add(r.Name, r.Check, r.Target, r.Status, r.Detail)
+	}
+	if err := render(t, *format, *columns); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed", plural(failed, "check"))
+	}
+	return nil
+}
+
+// grpc inspects and calls gRPC services through server reflection, so the
+// agent can debug internal services without their .proto files
+func (c *CLI) grpc(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("grpc", flag.ContinueOnError)
+	var opts grpcreflect.Options
+	flags.BoolVar(&opts.Plaintext, "plaintext", false, "connect without TLS")
+	flags.BoolVar(&opts.Insecure, "insecure", false, "skip TLS certificate verification")
+	flags.Func("H", "header to send, as \"name: value\" (repeatable)", func(value string) error {
+		opts.Headers = append(opts.Headers, value)
+		return nil
+	})
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	usage := fmt.Errorf("usage: devos grpc list <host:port> [service] | describe <host:port> <symbol> | call <host:port> <service/method> ['<json>'] [--plaintext] [--insecure] [-H 'name: value']")
+	if len(positional) < 2 {
+		return usage
+	}
+	command, address := positional[0], positional[1]
+
+	switch command {
+	case "list":
+		if len(positional) > 3 {
+			return usage
+		}
+		if len(positional) == 3 {
+			methods, err := grpcreflect.Methods(ctx, address, positional[2], opts)
+			if err != nil {
+				return err
+			}
+			for _, method := range methods {
+				fmt.Println(method)
+			}
+			return nil
+		}
+		services, err := grpcreflect.Services(ctx, address, opts)
+		if err != nil {
+			return err
+		}
+		if len(services) == 0 {
+			fmt.Printf("%s exposes no services through reflection\n", address)
+		}
+		for _, service := range services {
+			fmt.Println(service)
+		}
+		return nil
+
+	case "describe":
+		if len(positional) != 3 {
+			return usage
+		}
+		description, err := grpcreflect.Describe(ctx, address, positional[2], opts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(description)
+		return nil
+
+	case "call":
+		if len(positional) < 3 || len(positional) > 4 {
+			return usage
+		}
+		method := positional[2]
+		if len(positional) == 3 {
+			// Without a request, show one to fill in
+			template, err := grpcreflect.Template(ctx, address, method, opts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Request template for %s:\n%s\n\nCall it with: devos grpc call %s %s '<json>'\n", method, template, address, method)
+			return nil
+		}
+		c.audit.Record("grpc_call", map[string]string{"address": address, "method": method})
+		out, err := grpcreflect.Call(ctx, address, method, positional[3], opts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// printNetReport shows a network diagnosis
+func printNetReport(r *netdiag.Report) {
+	fmt.Printf("🌐 %s port %d\n", r.Target.Host, r.Target.Port)
+	for _, check := range r.Checks {
+		icon := "✅"
+		if !check.OK {
+			icon = "❌"
+		}
+		fmt.Printf("  %s %-4s %s\n", icon, strings.ToUpper(check.Name), check.Detail)
+	}
+	if len(r.Checks) == 0 && r.Lookup != nil {
+		fmt.Printf("  %s\n", strings.Join(r.Lookup.Addresses, "\n  "))
+		if r.Lookup.CNAME != "" {
+			fmt.Printf("  (canonical name %s)\n", r.Lookup.CNAME)
+		}
+	}
+	if len(r.Checks) == 0 && r.Ping != nil {
+		fmt.Printf("  %d/%d connected (%.0f%% loss), avg %s\n", r.Ping.Received, r.Ping.Sent, r.Ping.Loss()*100, r.Ping.Average().Round(100*time.Microsecond))
+	}
+	if len(r.Checks) == 0 && r.Certificate != nil {
+		cert := r.Certificate
+		fmt.Printf("  %s for %s, issued by %s\n", cert.Version, strings.Join(cert.DNSNames, ", "), cert.Issuer)
+		fmt.Printf("  Valid %s to %s\n", timefmt.Date(cert.NotBefore), timefmt.Date(cert.NotAfter))
+	}
+	if len(r.Hops) > 0 {
+		fmt.Println("  Route:")
+		for _, hop := range r.Hops {
+			address := hop.Address
+			if address == "" {
+				address = "*"
+			}
+			rtt := ""
+			if len(hop.RTTs) > 0 {
+				rtt = hop.RTTs[0].Round(100 * time.Microsecond).String()
+			}
+			fmt.Printf("   %2d  %-40s %s\n", hop.TTL, address, rtt)
+		}
+	}
+}
+
+// exportProfile writes an encrypted archive of the config, memory, and
+// data directories for moving to another machine or sharing a team baseline
+func (c *CLI) exportProfile(args []string) error {
+	flags := flag.NewFlagSet("export-profile", flag.ContinueOnError)
+	out := flags.String("out", "devos-profile-"+time.Now().Format("20060102")+".devos", "archive to write")
+	noSecrets := flags.Bool("no-secrets", false, "leave out API keys, team tokens, and webhook URLs")
+	if _, err := parseInterspersed(flags, args); err != nil {
+		return err
+	}
+	if fileExists(*out) {
+		return fmt.Errorf("%s already exists", *out)
+	}
+
+	passphrase := c.readSecret("🔑 Passphrase to encrypt the profile: ")
+	if passphrase == "" || passphrase != c.readSecret("🔑 Repeat passphrase: ") {
+		return fmt.Errorf("passphrases are empty or do not match")
+	}
+
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	manifest, err := profile.Export(c.config, c.memory, file, passphrase, !*noSecrets)
+	if err != nil {
+		os.Remove(*out)
+		return err
+	}
+
+	c.audit.Record("profile_exported", map[string]string{"path": *out, "secrets": fmt.Sprint(manifest.SecretsIncluded)})
+	fmt.Printf("✅ Exported %d files to %s", len(manifest.Files), *out)
+	if !manifest.SecretsIncluded {
+		fmt.Print(" (without secrets)")
+	}
+	fmt.Println()
+	return nil
+}
+
+// importProfile replaces this machine's config, memory, and data
+// directories with an exported profile
+func (c *CLI) importProfile(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: devos import-profile <file>")
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	passphrase := c.readSecret("🔑 Profile passphrase: ")
+	files, err := profile.Read(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	var manifest profile.Manifest
+	json.Unmarshal(files["manifest.json"], &manifest)
+	fmt.Printf("📦 Profile from %s (%s), %s\n", manifest.Host, manifest.OS, timefmt.DateTime(manifest.Created))
+	for _, name := range manifest.Files {
+		fmt.Printf("  • %s\n", name)
+	}
+	if !manifest.SecretsIncluded {
+		fmt.Println("  (no secrets; your current API key and tokens are kept)")
+	}
+	fmt.Print("\n⚠️  Replace your config and memory with this profile? (yes/no): ")
+	response := strings.ToLower(c.readLine())
+	if response != "yes" && response != "y" {
+		fmt.Println("❌ Cancelled")
+		return nil
+	}
+
+	c.memory.Close()
+	_, err = profile.Import(c.config, bytes.NewReader(data), passphrase)
+	// Reopen from whichever config is now in place, so a failed import
+	// leaves the session on its previous store rather than a closed one
+	if reopenErr := c.reopenMemory(); reopenErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (and reopening memory failed: %v)", err, reopenErr)
+		}
+		return reopenErr
+	}
+	if err != nil {
+		return err
+	}
+	c.audit.Record("profile_imported", map[string]string{"path": args[0], "host": manifest.Host})
+	fmt.Printf("✅ Imported profile; previous memory kept as %s.bak\n", c.config.MemoryPath)
+	return nil
+}
+
+// reopenMemory opens the memory store named by the current config and
+// hands it to the executor and audit trail in place of the closed one
+func (c *CLI) reopenMemory() error {
+	mem, err := memory.OpenBackend(c.config.MemoryBackend, c.config.MemoryPath, c.config.MemoryDSN, c.config.MemorySize)
+	if err != nil {
+		return fmt.Errorf("failed to open memory: %w", err)
+	}
+	var sink audit.Sink
+	if c.config.MemoryBackend == memory.BackendPostgres {
+		sink = mem
+	}
+	c.audit.ReplaceSink(c.memory, sink)
+	c.memory = mem
+	c.executor.SetMemory(mem)
+	return nil
+}
+
+// readSecret prompts for a value without echoing it on Unix terminals
+func (c *CLI) readSecret(prompt string) string {
+	fmt.Print(prompt)
+	if runtime.GOOS == "windows" || !isTerminal(os.Stdin) {
+		return c.readLine()
+	}
+	stty := func(arg string) {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		cmd.Run()
+	}
+	stty("-echo")
+	defer func() {
+		stty("echo")
+		fmt.Println()
+	}()
+	return c.readLine()
+}
+
+// parseInterspersed parses flags that may follow positional arguments,
+// e.g. "set image.tag 1.2 --env staging", returning the positionals
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// resume continues the last interrupted plan from its last successful step
+func (c *CLI) resume(ctx context.Context) error {
+	run, err := c.memory.LatestUnfinishedRun()
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		fmt.Println("✅ Nothing to resume; the last plan completed")
+		return nil
+	}
+
+	fmt.Printf("\n⏯️  Interrupted plan from %s (%d of %d steps completed):\n",
+		timefmt.DateTime(run.UpdatedAt), run.Completed, len(run.Commands))
+	for i, cmd := range run.Commands {
+		marker := "→"
+		if i < run.Completed {
+			marker = "✓"
+		}
+		fmt.Printf("  %s %s\n", marker, cmd)
+	}
+
+	if err := c.executor.Validate(run.Commands[run.Completed:]); err != nil {
+		return err
+	}
+
+	fmt.Print("\n⚠️  Resume from step ", run.Completed+1, "? (yes/no): ")
+	response := strings.ToLower(c.readLine())
+	if response != "yes" && response != "y" {
+		fmt.Println("❌ Resume cancelled")
+		return nil
+	}
+	if !c.overrideFreeze(ctx, run.Commands[run.Completed:]) {
+		return nil
+	}
+
+	if err := c.executor.ResumeRun(ctx, run); err != nil {
+		return err
+	}
+
+	fmt.Println("\n✅ Plan resumed and completed successfully")
+	return nil
+}
+
+// resolveMissing handles plans that use executables missing from PATH by
+// adding an install step or asking the AI to re-plan without them. A nil
+// result means the user cancelled.
+func (c *CLI) resolveMissing(ctx context.Context, input string, result *executor.ExecutionResult, missing []string) (*executor.ExecutionResult, error) {
+	fmt.Printf("\n🔍 Required tools not found on PATH: %s\n", strings.Join(missing, ", "))
+
+	install := c.executor.InstallCommand(missing)
+	options := "replan/continue/cancel"
+	if install != "" {
+		fmt.Printf("   Install with: %s\n", install)
+		options = "install/" + options
+	}
+
+	fmt.Printf("\n❓ How should DevOS proceed? (%s): ", options)
+	switch strings.ToLower(c.readLine()) {
+	case "install", "i":
+		if install == "" {
+			return nil, fmt.Errorf("no supported package manager found")
+		}
+		commands := append([]string{install}, result.Commands...)
+		if err := c.executor.Validate(commands); err != nil {
+			return nil, err
+		}
+		result.Commands = commands
+		result.Steps = nil
+		result.NeedsConfirmation = true
+		return result, nil
+	case "replan", "r":
+		return c.executor.Replan(ctx, input, missing)
+	case "continue", "c":
+		return result, nil
+	default:
+		fmt.Println("❌ Operation cancelled")
+		return nil, nil
+	}
+}
+
+// confirmRerun warns that a plan already partially succeeded and asks whether
+// to skip the completed steps
+func (c *CLI) confirmRerun(run *memory.Run) bool {
+	fmt.Printf("\n🔁 This plan ran before (%s) and stopped after %d of %d steps.\n",
+		timefmt.DateTime(run.UpdatedAt), run.Completed, len(run.Commands))
+
+	var hints []string
+	for _, cmd := range run.Commands[:run.Completed] {
+		if reason := policy.NonIdempotentReason(cmd); reason != "" {
+			hints = append(hints, fmt.Sprintf("  ⚠️  %s — %s", cmd, reason))
+		}
+	}
+	if len(hints) > 0 {
+		fmt.Println("   Completed steps that are not safe to re-run:")
+		for _, hint := range hints {
+			fmt.Println(hint)
+		}
+	}
+
+	fmt.Printf("\n⏭️  Skip the %d completed step(s)? (yes/no): ", run.Completed)
+	response := strings.ToLower(c.readLine())
+	return response == "yes" || response == "y"
+}
+
+// showDownloads prints what inspected downloads contain and how they checked out
+func (c *CLI) showDownloads(downloads []*executor.Download) {
+	for _, d := range downloads {
+		fmt.Printf("\n📥 Download: %s\n", d.URL)
+		fmt.Printf("  SHA-256:   %s\n", d.SHA256)
+		fmt.Printf("  Checksum:  %s\n", d.Checksum)
+		fmt.Printf("  Signature: %s\n", d.Signature)
+		fmt.Printf("  Runs as:   %s\n", d.Rewritten)
+
+		if len(d.Findings) > 0 {
+			fmt.Println("  ⚠️  Findings:")
+			for _, finding := range d.Findings {
+				fmt.Printf("    • %s\n", finding)
+			}
+		}
+
+		if len(d.Preview) > 0 {
+			fmt.Println("  ── script preview ──")
+			for _, line := range d.Preview {
+				fmt.Printf("  │ %s\n", line)
+			}
+		}
+	}
+}
+
+// showManifestChecks prints Kubernetes manifest validation results
+func (c *CLI) showManifestChecks(checks []executor.ManifestCheck) {
+	for _, check := range checks {
+		if len(check.Errors) == 0 {
+			fmt.Printf("\n☸️  %s: valid (%s)\n", check.Path, check.Method)
+			continue
+		}
+		fmt.Printf("\n☸️  %s: invalid (%s)\n", check.Path, check.Method)
+		for _, e := range check.Errors {
+			fmt.Printf("  ✗ %s\n", e)
+		}
+	}
+}
+
+// offerKnownFix proposes a resolution from the failure knowledge base
+func (c *CLI) offerKnownFix(ctx context.Context, cause error, fix []string) error {
+	fmt.Printf("❌ Error: %v\n", cause)
+	fmt.Println("\n💡 This failure has been fixed before with:")
+	for _, cmd := range fix {
+		fmt.Printf("  → %s\n", cmd)
+	}
+
+	fmt.Print("\n⚠️  Apply known fix? (yes/no): ")
+	response := strings.ToLower(c.readLine())
+	if response != "yes" && response != "y" {
+		return nil
+	}
+
+	if err := c.executor.Validate(fix); err != nil {
+		return err
+	}
+	if !c.overrideFreeze(ctx, fix) {
+		return nil
+	}
+	if err := c.executor.ExecuteCommands(ctx, fix); err != nil {
+		return err
+	}
+
+	fmt.Println("\n✅ Known fix applied successfully")
+	return nil
+}
+
+// editPlan lets the user edit a plan's commands: as a document in $VISUAL
+// or $EDITOR when set, and otherwise line by line. It returns nil when no
+// commands are left to run.
+func (c *CLI) editPlan(input string, commands []string) ([]string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		return c.editCommands(input, commands)
+	}
+
+	doc := planDocument(input, commands)
+	for {
+		var err error
+		if doc, err = runEditor(editor, doc); err != nil {
+			return nil, err
+		}
+		edited := parsePlanDocument(doc)
+		if len(edited) == 0 {
+			return nil, nil
+		}
+		err = c.executor.Validate(edited)
+		if err == nil {
+			var original, corrected []string
+			for _, change := range executor.DiffPlans(commands, edited) {
+				if change.Kind == executor.PlanChanged {
+					original, corrected = append(original, change.Before), append(corrected, change.After)
+				}
+			}
+			c.executor.RecordCorrections(input, original, corrected)
+			return edited, nil
+		}
+		fmt.Printf("❌ %v\n", err)
+		fmt.Print("✏️  Edit the plan again? (yes/no): ")
+		if answer := strings.ToLower(c.readLine()); answer != "yes" && answer != "y" {
+			return nil, nil
+		}
+	}
+}
+
+// planDocument renders a plan's commands for editing: one per line, with the
+// further lines of a multi-line command indented by four spaces
+func planDocument(input string, commands []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# DevOS plan for: %s\n", strings.ReplaceAll(input, "\n", " "))
+	b.WriteString(`#
+# One command per line, run in order. Change, reorder, add, or delete
+# commands; lines indented by four spaces continue the command above, and
+# lines starting with # are ignored. Save and close the editor to validate
+# and run the plan, or delete every command to cancel.
+
+`)
+	for _, cmd := range commands {
+		b.WriteString(strings.ReplaceAll(cmd, "\n", "\n    ") + "\n")
+	}
+	return b.String()
+}
+
+// parsePlanDocument reads the commands back from an edited plan document
+func parsePlanDocument(doc string) []string {
+	var commands []string
+	for _, line := range strings.Split(strings.ReplaceAll(doc, "\r\n", "\n"), "\n") {
+		if rest, ok := strings.CutPrefix(line, "    "); ok && len(commands) > 0 {
+			commands[len(commands)-1] += "\n" + rest
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			commands = append(commands, line)
+		}
+	}
+	return commands
+}
+
+// runEditor opens text in the user's editor and returns it as saved
+func runEditor(editor, text string) (string, error) {
+	file, err := os.CreateTemp("", "devos-plan-*.sh")
+	if err != nil {
+		return "", fmt.Errorf("failed to create plan file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write plan file: %w", err)
+	}
+
+	args := append(strings.Fields(editor), file.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read plan file: %w", err)
+	}
+	return string(data), nil
+}
+
+// editCommands lets the user rewrite proposed commands before they run.
+// Edits are re-validated and remembered as corrections for future prompts.
+func (c *CLI) editCommands(input string, commands []string) ([]string, error) {
+	fmt.Println("\n✏️  Edit commands (press Enter to keep, '-' to drop):")
+
+	edited := make([]string, len(commands))
+	for i, cmd := range commands {
+		fmt.Printf("  [%d] %s\n  edit> ", i+1, cmd)
+		line := c.readLine()
+		if c.locked {
+			return nil, fmt.Errorf("session locked during edit")
+		}
+		switch line {
+		case "":
+			edited[i] = cmd
+		case "-":
+			edited[i] = ""
+		default:
+			edited[i] = line
+		}
+	}
+
+	var kept []string
+	for _, cmd := range edited {
+		if cmd != "" {
+			kept = append(kept, cmd)
+		}
+	}
+
+	if err := c.executor.Validate(kept); err != nil {
+		return nil, err
+	}
+
+	c.executor.RecordCorrections(input, commands, edited)
+	return kept, nil
+}
+
+// readInput reads one prompt from the REPL. A trailing backslash continues
+// the input on the next line, and a ``` fence collects everything up to the
+// closing fence verbatim, so whole scripts or stack traces can be pasted as
+// a single prompt.
+func (c *CLI) readInput() (input string, multiline bool, ok bool) {
+	var lines []string
+	for {
+		line, more := c.readPromptLine(len(lines) == 0)
+		if !more {
+			return strings.Join(lines, "\n"), len(lines) > 1, len(lines) > 0
+		}
+
+		if before, after, found := strings.Cut(line, pasteStart); found {
+			lines = append(lines, strings.TrimSpace(before))
+			input := strings.TrimSpace(strings.Join(append(lines, c.readPaste(after)...), "\n"))
+			return input, strings.Contains(input, "\n"), true
+		}
+		line = strings.TrimSpace(line)
+
+		if before, after, found := strings.Cut(line, "```"); found && !strings.Contains(after, "```") {
+			if before = strings.TrimSpace(before); before != "" {
+				lines = append(lines, before)
+			}
+			return strings.Join(append(lines, c.readFence()...), "\n"), true, true
+		}
+
+		if !strings.HasSuffix(line, "\\") {
+			lines = append(lines, line)
+			return strings.Join(lines, "\n"), len(lines) > 1, true
+		}
+
+		lines = append(lines, strings.TrimSpace(strings.TrimSuffix(line, "\\")))
+		fmt.Print("...> ")
+	}
+}
+
+// readPromptLine reads a line of a prompt. The first line goes through the
+// editor, when there is one, so it can be completed from history; Ctrl-C
+// there abandons the line.
+func (c *CLI) readPromptLine(first bool) (string, bool) {
+	if first && c.editor != nil {
+		line, err := c.editor.ReadLine()
+		switch {
+		case err == nil:
+			return line, true
+		case errors.Is(err, lineedit.ErrInterrupted):
+			return "", true
+		case !errors.Is(err, io.EOF):
+			// The terminal could not be switched; read plain lines from now on
+			c.logger.Warn("Suggestions disabled: %v", err)
+			c.editor = nil
+			return c.readPromptLine(first)
+		}
+		return "", false
+	}
+	if !c.scanner.Scan() {
+		return "", false
+	}
+	return c.scanner.Text(), true
+}
+
+// readFence reads raw lines up to a closing ``` fence (or end of input)
+func (c *CLI) readFence() []string {
+	var lines []string
+	for {
+		fmt.Print("```> ")
+		if !c.scanner.Scan() {
+			return lines
+		}
+		line := c.scanner.Text()
+		if strings.TrimSpace(line) == "```" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// readPaste collects a bracketed paste starting with first, up to the
+// terminal's end-of-paste marker
+func (c *CLI) readPaste(first string) []string {
+	line := first
+	var lines []string
+	for {
+		if before, _, found := strings.Cut(line, pasteEnd); found {
+			return append(lines, before)
+		}
+		lines = append(lines, line)
+		if !c.scanner.Scan() {
+			return lines
+		}
+		line = c.scanner.Text()
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readLine reads a trimmed line of user input. An answer given after the
+// idle timeout counts as no answer, so stale approvals are cancelled.
+func (c *CLI) readLine() string {
+	if !c.scanner.Scan() {
+		return ""
+	}
+	if c.checkIdle() {
+		fmt.Println("\n⏰ Pending approval cancelled after inactivity")
+		return ""
+	}
+	return strings.TrimSpace(c.scanner.Text())
+}
+
+// checkIdle locks the session if the user has been idle longer than the
+// configured timeout, and records the activity. It reports whether the
+// session was locked by this call.
+func (c *CLI) checkIdle() bool {
+	now := time.Now()
+	idle := now.Sub(c.lastActive)
+	c.lastActive = now
+
+	timeout := time.Duration(c.config.IdleTimeout) * time.Minute
+	if timeout == 0 || c.locked || idle < timeout {
+		return false
+	}
+
+	c.locked = true
+	c.executor.Lock()
+	c.audit.Record("session_idle_locked", map[string]string{"idle": idle.Round(time.Second).String()})
+	c.logger.Warn("Session locked after %s idle", idle.Round(time.Minute))
+	return true
+}
+
+// confirmResume asks the user to re-confirm a session locked by the idle
+// timeout. The input that woke the session is discarded either way.
+func (c *CLI) confirmResume() {
+	fmt.Print("🔒 Session locked after inactivity. Resume? (yes/no): ")
+	if !c.scanner.Scan() {
+		return
+	}
+	c.lastActive = time.Now()
+
+	response := strings.ToLower(strings.TrimSpace(c.scanner.Text()))
+	if response != "yes" && response != "y" {
+		return
+	}
+
+	c.locked = false
+	c.audit.Record("session_resumed", nil)
+	fmt.Println("🔓 Session resumed; please re-enter your request")
+}
+
+func (c *CLI) showHelp() {
+	help := `
 DevOS - AI-Native Developer Operating Layer
 
 USAGE:
   devos                    Start interactive mode
   devos [command]          Execute a single command
+                           (mention files as @path to attach them; binaries, minified
+                           bundles, and files over "attach_max_bytes" are left out or cut)
+  devos --dry-run [command]  ...printing the resolved commands, environment, and
+                           directory instead of running anything
+  devos --timeout <dur> [command]  ...stopping any step that runs longer than dur
+                           (default "command_timeout" seconds; "off" for no limit)
+  devos report             Show activity report (--days N, --format terminal|markdown)
+  devos status             Show system status
+  devos history            List recent tasks (--limit N)
+  devos tasks              List long-running commands started in tmux
+  devos repos <group|dir,dir,...> <task>  Run a task across repositories (see repos below)
+  devos release <version>  Cut a release, approving each step (see release below)
+  devos plugins            List configured plugins and whether they are installed
+                           (status, history, tasks, plugins, and models list take
+                           --format table|json|yaml and --columns a,b,c)
+  devos resume             Continue the last interrupted plan
+  devos daemon             Run the team daemon (two-person approval for high-risk plans;
+                           with "oidc" set, team users log in at /auth/login)
+  devos generate ci|makefile  Write a validated CI workflow or Makefile (--docker, --force)
+  devos generate check <file> Validate an existing workflow or Makefile
+  devos helm list|get|set|template  Inspect charts and values; "set" shows the rendered diff
+  devos ssh keygen|agent-add|host   Guided SSH keys and config (never overwrites keys)
+  devos service status|list|start|stop|restart  Query and manage system services
+  devos models [list]      Show local Ollama and GGUF models, disk usage, and the
+                           model recommended for this machine's RAM/VRAM
+  devos models pull <name|url>  Pull an Ollama model or download a GGUF (--sha256)
+  devos models remove|verify <name>  Delete a model, or check a GGUF's integrity
+  devos policy stats       Show which policy rules block or hold commands (--days N)
+  devos policy allow <rule>  Allow the program a rule blocks most, in this project
+                           only (--program NAME)
+  devos runbook [list]     List built-in and saved runbooks (certificate rotation,
+                           volume resize, service restart with health checks)
+  devos runbook show|customize <name>  Show a runbook, or save a copy to edit
+  devos runbook run <name> [param=value...]  Plan a runbook's commands for approval
+  devos api add <file|url> [--name NAME]  Register an OpenAPI spec; requests that
+                           mention the API get its relevant operations as context
+  devos api [list]|show <name>|remove <name>  Manage registered API specs
+  devos issue [list]       List open Jira or Linear issues assigned to you ("issue_tracker")
+  devos issue show <key>   Show an issue's description
+  devos issue work <key> [instructions]  Plan work using the issue as context, then
+                           offer to post a summary and PR link on it
+  devos issue comment <key> <text>  Post a comment on an issue
+  devos freezes            Show the change freeze in effect and those coming up
+  devos guardrails         Show the organization's guardrails bundle: the preamble
+                           added to every AI request, and its policy
+  devos budget             Show how much of each AI budget is used ("budgets" in config)
+  devos privacy [list]     Show the projects and paths kept off cloud providers
+  devos privacy local [path]  Mark a project (default: this one) local-only, so its
+                           context only goes to the local model ("local_model")
+  devos privacy cloud [path]  Allow a local-only project's context to go to the cloud
+  devos config             Show the configuration; config.json, config.yaml, or
+                           config.toml in the config directory is used
+  devos config convert json|yaml|toml  Rewrite the config file in another format
+  devos config encrypt [section...]  Encrypt API keys, tokens, and targets at rest with
+                           a key in the OS keychain (--passphrase to use one instead,
+                           read from DEVOS_CONFIG_PASSPHRASE at startup)
+  devos config decrypt     Store every config section in plain text again
+  devos feedback [list]    List the ratings given to plans with "good" and "bad"
+  devos feedback export    Write the ratings anonymized as JSON lines (--out FILE)
+  devos env [list]         List execution environments (targets, variables, kube
+                           context, and approval rules per stage)
+  devos env use <name>     Run plans in an environment; protected ones need their
+                           name typed to confirm changes, and two daemon approvers
+  devos env off            Stop using an environment
+                           (kubectl and helm changes show the cluster context first,
+                           and need it typed when it does not fit the environment
+                           whose "projects" include the current one)
+  devos undo               Reverse the last task: undo its steps, last first, and
+                           restore the package versions from before it
+                           that installed, removed, or upgraded packages
+  devos locks              List projects locked by sessions running plans in them
+  devos force-unlock [path]  Remove the lock on the project at path (default: the
+                           current directory) left by a stuck session
+  devos export-profile     Write an encrypted archive of config and memory (--out, --no-secrets)
+  devos import-profile <file>  Restore an exported profile on this machine
+  devos open <target>      Open a URL, file, or folder in the default application
+  devos logs               Summarize system logs (--unit, --since, --until, --errors,
+                           --file, --diagnose "question" to ask the AI about them)
+  devos logs --self        Summarize DevOS's own log; with --diagnose, find out why
+                           an earlier devos command failed
+  devos net [dns|ping|trace|tls] <host>  Check DNS, TCP, and TLS to a host (--trace,
+                           --json, --diagnose "question")
+  devos grpc list <host:port> [service]  List a gRPC server's services or methods
+                           through reflection (needs grpcurl; --plaintext, -H)
+  devos grpc describe <host:port> <symbol>  Show a service, method, or message
+  devos grpc call <host:port> <method> ['<json>']  Call a method; without a
+                           request, print a template to fill in
+  devos monitor [name...]  Run the "monitors" in config now; the daemon runs them
+                           on schedule and posts status changes to Slack
+  devos monitor cert|dns|reachable <target>  Run one check (--warn-days,
+                           --record, --expect)
 
 BUILT-IN COMMANDS:
   help, h                  Show this help message
   version, v               Show version information
   status                   Show system status
-  config                   Show current configuration
+  history                  List recent tasks
+  config [convert <fmt>]   Show current configuration, or convert the config file
+                           to json, yaml, or toml
+  config encrypt|decrypt   Encrypt secret config sections at rest, or stop
+  report                   Show weekly activity and savings report
+  unlock <dur> <rule...>   Temporarily relax policy rules (reason is audited)
+  observe [on|off]         Only auto-run read-only commands; ask for anything else
+  dryrun [on|off]          Print what plans would run (commands, environment, directory)
+                           instead of running them
+  timeout [<dur>|off]      Show or change how long a command may run this session
+  checkpoint save|restore|delete <name>, checkpoint list
+                           Save the session (directory, environment and variables, modes,
+                           last request and plan) and return to it later (undo reverses tasks)
+  cd [dir], pwd            Change or show the directory commands run in; plans that cd
+                           also move the session, so later commands start where they left off
+  speech [on|off|test]     Read task outcomes aloud (events set by "speech" in config)
+  lock                     End an elevated session immediately
+                           (sessions also lock after "idle_timeout" minutes idle)
+  targets                  List remote SSH targets
+  tasks                    List long-running commands started in tmux ("tmux": true)
+  plugins                  List configured plugins
+                           (these listings take --format table|json|yaml and --columns)
+  models [list|pull <name>|remove <name>|verify]
+                           Manage local Ollama and GGUF models without leaving the REPL
+  attach <task>            Watch a running task live, or show a finished task's output
+  bg <task>, <task> &      Run the task's plan in the background once approved, so a
+                           dev server or long build does not block the prompt
+  jobs                     List background jobs (--format, --columns)
+  fg [id], tail [-f] <id>  Follow a job's output until it finishes, or show its last lines;
+                           Ctrl-C stops a job in fg, but only stops tail -f following
+  kill <id>                Stop a background job and the processes it started
+                           (jobs stop when DevOS exits)
+  open <url|file|folder>   Open in the default browser or application
+  resume                   Continue the last interrupted plan
+  rollout <task>           Run a task on all targets, canary host first
+  repos [<group|dir,dir,...> <task>]  Plan a task in each repository of a group
+                           ("repos" in config) with its own context, run the plans
+                           in parallel, and report how each went, with PR links
+  release <version>        Cut a release ("cut a release 1.4.0" works too): bump the
+                           version, add release notes to the changelog, commit and
+                           tag, build, and publish, approving or skipping each step;
+                           configured by "release" in config, and per project by
+                           .devos/release.json
+  runbook [list|show|run|customize]  Use parameterized runbooks (see devos runbook)
+  api [list|add|show|remove]  Register OpenAPI specs for API-aware plans (see devos api)
+  issue [list|show|work|comment]     Work on Jira or Linear tickets (see devos issue)
+  freezes                  Show current and upcoming change freezes
+  budget                   Show AI token and dollar budgets
+  privacy [list|local|cloud] [path]  Keep projects' context off cloud providers
+  plans [show|approve|reject <id>]   List or sign off on plans when attached to a daemon
+                           (the REPL attaches to a running "devos daemon" automatically;
+                           set "daemon_attach": "never" to plan locally)
+  try again [, but <tweak>]  Ask for a different plan for the last request; the new plan,
+                           like one for a tweaked request, shows what it changed
+  good                     Rate the last plan good; similar requests follow it
+  bad <reason>             Rate the last plan bad; similar requests avoid it
+  feedback [list|export]   List or export plan ratings (see devos feedback)
+  env [list|use <name>|off]  Show or switch the execution environment
+  graph [text|dot|mermaid] Draw the last plan's step dependencies (--out FILE, --ascii)
+  undo                     Reverse the last task's steps and package changes
+  locks                    List projects where other sessions are running plans
+  force-unlock [path]      Remove a stuck session's lock on a project
   exit, quit, q            Exit DevOS
 
 NATURAL LANGUAGE COMMANDS:
@@ -184,9 +5239,20 @@ NATURAL LANGUAGE COMMANDS:
     • create kubernetes deployment config
     • optimize docker image size
 
+  Multi-line input: end a line with \ to continue it, or wrap a pasted
+  script or stack trace in triple-backtick fences to send it as one prompt.
+
+  Suggestions: earlier prompts, and those saved one per line in files under
+  snippets/ in the config directory, appear dimmed as you type; press → or
+  End to accept one, or Tab for its next word ("autosuggest": "off" in config
+  turns them off).
+
 MODES:
   Interactive Mode:        Default mode with continuous command input
   Confirmation Mode:       Prompts before executing destructive operations
+                           (fine-tune with "approval_rules" in config.json)
+                           (answer "edit" to correct commands, in $VISUAL or $EDITOR when
+                           set; edited plans are reviewed again, and DevOS learns from edits)
   Offline Mode:           Uses local LLM (requires Ollama)
 
 For more information, visit: https://github.com/devos-ai/devos
@@ -194,37 +5260,563 @@ For more information, visit: https://github.com/devos-ai/devos
 	fmt.Println(help)
 }
 
-func (c *CLI) showStatus() {
+func (c *CLI) showStatus(args []string) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var status table.Record
+	status.Set("OS", c.config.OS)
+	status.Set("Platform", c.executor.Platform().Summary())
+	hw := c.executor.Platform().Hardware
+	status.Set("Hardware", hw.Summary())
+	if hw.RAM > 0 {
+		rec := models.Recommend(hw.RAM, hw.VRAM())
+		status.Set("Local Model", fmt.Sprintf("%s with %d-token context recommended",
+			rec.Ollama, models.ContextLength(hw.RAM, hw.VRAM(), rec.Size)))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if layout := project.Detect(cwd); layout.Monorepo {
+			status.Set("Monorepo", fmt.Sprintf("%s (%d members)", strings.Join(layout.Kinds, ", "), len(layout.Members)))
+		}
+	}
+	status.Set("AI Provider", c.config.AIProvider)
+	if tag := c.executor.LocalOnly(); tag != "" {
+		status.Set("Privacy", fmt.Sprintf("local-only (%s)", tag))
+	}
+	if c.daemon != nil {
+		status.Set("Daemon", fmt.Sprintf("attached at %s as %s", c.daemon.Socket, c.daemon.User))
+	}
+	status.Set("Confirmation", c.config.ConfirmationMode)
+	status.Set("Log Level", c.config.LogLevel)
+	status.Set("Plugins Loaded", len(c.config.Plugins))
+
+	selected, err := status.Select(splitColumns(*columns))
+	if err != nil {
+		return err
+	}
+	if *format != table.FormatTable {
+		return selected.Render(os.Stdout, *format)
+	}
 	fmt.Println("\n📊 System Status")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("  OS:              %s\n", c.config.OS)
-	fmt.Printf("  AI Provider:     %s\n", c.config.AIProvider)
-	fmt.Printf("  Confirmation:    %v\n", c.config.ConfirmationMode)
-	fmt.Printf("  Log Level:       %s\n", c.config.LogLevel)
-	fmt.Printf("  Plugins Loaded:  %d\n", len(c.config.Plugins))
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	selected.Render(os.Stdout, *format)
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	return nil
+}
+
+// outputFlags adds the --format and --columns flags every listing accepts
+func outputFlags(flags *flag.FlagSet) (format, columns *string) {
+	format = flags.String("format", table.FormatTable, "output format: table, json, or yaml")
+	columns = flags.String("columns", "", "comma-separated columns to show")
+	return format, columns
+}
+
+// onlyFlags reports whether args are flags and their values, such as
+// "--format json --columns name"
+func onlyFlags(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			return false
+		}
+		if !strings.Contains(args[i], "=") {
+			i++ // Skip the flag's value
+		}
+	}
+	return true
+}
+
+// splitColumns parses a --columns value
+func splitColumns(columns string) []string {
+	var names []string
+	for _, name := range strings.Split(columns, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// render writes a listing in the requested format with the chosen columns
+func render(t *table.Table, format, columns string) error {
+	if err := table.CheckFormat(format); err != nil {
+		return err
+	}
+	selected, err := t.Select(splitColumns(columns))
+	if err != nil {
+		return err
+	}
+	return selected.Render(os.Stdout, format)
+}
+
+// showHistory lists recent tasks from memory
+func (c *CLI) showHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "most recent tasks to show")
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	tasks, err := c.memory.TasksSince(time.Time{})
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(tasks) > *limit {
+		tasks = tasks[len(tasks)-*limit:]
+	}
+	if len(tasks) == 0 && *format == table.FormatTable {
+		fmt.Println("No tasks yet")
+		return nil
+	}
+
+	t := table.New("time", "task", "category", "provider", "model", "commands", "success", "duration", "tokens")
+	for _, task := range tasks {
+		t.Add(task.CreatedAt, task.Input, task.Category, task.Provider, task.Model, task.Commands, task.Success, task.Duration, task.Tokens)
+	}
+	if *columns == "" && *format == table.FormatTable {
+		*columns = "time,task,commands,success,duration"
+	}
+	return render(t, *format, *columns)
+}
+
+// rate records the user's rating of the latest plan
+func (c *CLI) rate(rating, reason string) error {
+	if c.lastPlan == nil {
+		return fmt.Errorf("no plan to rate yet")
+	}
+	if err := c.executor.RecordFeedback(c.lastInput, c.lastPlan, rating, reason); err != nil {
+		return err
+	}
+	c.audit.Record("feedback_recorded", map[string]string{"input": c.lastInput, "rating": rating, "reason": reason})
+	c.lastPlan = nil
+
+	if rating == memory.RatingGood {
+		fmt.Println("👍 Thanks; similar requests will follow this plan")
+	} else {
+		fmt.Println("👎 Thanks; similar requests will avoid this plan")
+	}
+	return nil
+}
+
+// feedback lists the ratings given with "good" and "bad", or exports them
+// anonymized as JSON lines
+func (c *CLI) feedback(args []string) error {
+	if len(args) > 0 && args[0] == "export" {
+		return c.exportFeedback(args[1:])
+	}
+	if len(args) > 0 && args[0] == "list" {
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet("feedback", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "most recent ratings to show")
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	feedback, err := c.memory.RecentFeedback(*limit)
+	if err != nil {
+		return err
+	}
+	if len(feedback) == 0 && *format == table.FormatTable {
+		fmt.Println("No feedback yet (rate a plan with \"good\" or \"bad <reason>\" after it runs)")
+		return nil
+	}
+
+	t := table.New("time", "rating", "task", "reason", "commands", "provider", "model")
+	for i := len(feedback) - 1; i >= 0; i-- {
+		f := feedback[i]
+		t.Add(f.CreatedAt, f.Rating, f.Input, f.Reason, f.Commands, f.Provider, f.Model)
+	}
+	if *columns == "" && *format == table.FormatTable {
+		*columns = "time,rating,task,reason"
+	}
+	return render(t, *format, *columns)
+}
+
+// exportFeedback writes all stored ratings, oldest first and anonymized, as
+// JSON lines
+func (c *CLI) exportFeedback(args []string) error {
+	flags := flag.NewFlagSet("feedback export", flag.ContinueOnError)
+	out := flags.String("out", "", "file to write (default: standard output)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	feedback, err := c.memory.RecentFeedback(math.MaxInt32)
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	enc := json.NewEncoder(w)
+	for i := len(feedback) - 1; i >= 0; i-- {
+		if err := enc.Encode(feedback[i].Anonymized()); err != nil {
+			return err
+		}
+	}
+	if *out != "" {
+		fmt.Printf("✅ Exported %s to %s (paths, user names, addresses, and tokens removed)\n", plural(len(feedback), "rating"), *out)
+	}
+	return nil
+}
+
+// graphMinSteps is the plan length from which the execution order is shown
+// before approval, when some steps could run in parallel
+const graphMinSteps = 4
+
+// graph draws the last plan's steps and their dependencies, or exports them
+// as DOT or Mermaid
+func (c *CLI) graph(args []string) error {
+	format := dag.FormatText
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		format, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	out := flags.String("out", "", "file to write the graph to")
+	ascii := flags.Bool("ascii", a11y.Enabled(), "draw without box-drawing characters")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if c.lastPlan == nil || len(c.lastPlan.Commands) == 0 {
+		return fmt.Errorf("no plan to draw yet")
+	}
+
+	text, err := executor.PlanGraph(c.lastPlan).Render(format, *ascii)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(*out, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Wrote the plan graph to %s\n", *out)
+	return nil
+}
+
+// undo runs the rollback commands recorded with the last task that can be
+// undone, reversing its steps and restoring the package versions installed
+// before it ran
+func (c *CLI) undo(ctx context.Context) error {
+	task, err := c.memory.LastUndoable()
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		fmt.Println("Nothing to undo (no task has recorded rollback commands since the last undo)")
+		return nil
+	}
+
+	fmt.Printf("\n↩️  Undo \"%s\" (%s)\n", task.Input, timefmt.DateTime(task.CreatedAt))
+	for _, cmd := range task.Rollback {
+		fmt.Printf("  → %s\n", cmd)
+	}
+	fmt.Print("\n⚠️  Run these commands? (yes/no): ")
+	if response := strings.ToLower(c.readLine()); response != "yes" && response != "y" {
+		fmt.Println("❌ Undo cancelled")
+		return nil
+	}
+
+	err = c.executor.ExecuteCommands(ctx, task.Rollback)
+	c.audit.Record("task_undone", map[string]string{
+		"input":    task.Input,
+		"commands": strings.Join(task.Rollback, "; "),
+		"success":  fmt.Sprint(err == nil),
+	})
+	if err != nil {
+		return fmt.Errorf("undo failed: %w", err)
+	}
+	if err := c.memory.MarkUndone(task.ID); err != nil {
+		return err
+	}
+	fmt.Println("\n✅ Task reversed")
+	return nil
+}
+
+// showLocks lists the projects sessions have locked while running plans
+func (c *CLI) showLocks(args []string) error {
+	flags := flag.NewFlagSet("locks", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	holders, err := projectlock.List(projectlock.Dir(c.config))
+	if err != nil {
+		return err
+	}
+	if len(holders) == 0 && *format == table.FormatTable {
+		fmt.Println("No projects are locked")
+		return nil
+	}
+	t := table.New("project", "session", "command", "pid", "host", "user", "since", "task")
+	for _, h := range holders {
+		session := h.Session
+		if session == projectlock.Session() {
+			session += " (this session)"
+		}
+		t.Add(h.Project, session, h.Command, h.PID, h.Host, h.User, h.Since, h.Task)
+	}
+	return render(t, *format, *columns)
+}
+
+// forceUnlock removes the lock on a project whichever session holds it
+func (c *CLI) forceUnlock(args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	root := project.Root(abs)
+
+	holder, err := projectlock.ForceUnlock(projectlock.Dir(c.config), root)
+	if err != nil {
+		return err
+	}
+	if holder == nil {
+		fmt.Printf("%s is not locked\n", root)
+		return nil
+	}
+	c.audit.Record("project_force_unlocked", map[string]string{
+		"project": root,
+		"session": holder.Session,
+		"holder":  fmt.Sprintf("%s@%s pid %d", holder.User, holder.Host, holder.PID),
+		"task":    holder.Task,
+	})
+	fmt.Printf("🔓 Removed session %s's lock on %s (it was running %q)\n", holder.Session, root, holder.Task)
+	return nil
+}
+
+// showPlugins lists the configured plugins and whether they are installed
+func (c *CLI) showPlugins(args []string) error {
+	flags := flag.NewFlagSet("plugins", flag.ContinueOnError)
+	format, columns := outputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(c.config.Plugins) == 0 && *format == table.FormatTable {
+		fmt.Println("No plugins configured (add \"plugins\" to config.json)")
+		return nil
+	}
+
+	dir := c.config.PluginPath
+	if dir == "" {
+		dir = filepath.Join(c.config.Dir(), "plugins")
+	}
+	t := table.New("name", "path", "installed")
+	for _, name := range c.config.Plugins {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, name)
+		}
+		t.Add(name, path, fileExists(path))
+	}
+	return render(t, *format, *columns)
+}
+
+// configCommand shows the configuration, rewrites the config file as JSON,
+// YAML, or TOML, or encrypts its secret sections
+func (c *CLI) configCommand(args []string) error {
+	if len(args) == 0 {
+		c.showConfig()
+		return nil
+	}
+	switch args[0] {
+	case "convert":
+		return c.convertConfig(args[1:])
+	case "encrypt":
+		return c.encryptConfig(args[1:])
+	case "decrypt":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: devos config decrypt")
+		}
+		return c.decryptConfig()
+	default:
+		return fmt.Errorf("usage: devos config [convert json|yaml|toml | encrypt [--passphrase] [section...] | decrypt]")
+	}
+}
+
+// convertConfig rewrites the config file in another format
+func (c *CLI) convertConfig(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: devos config convert json|yaml|toml")
+	}
+	format := strings.ToLower(args[0])
+	if format == "yml" {
+		format = configfmt.YAML
+	}
+	if !slices.Contains(configfmt.Formats, format) {
+		return fmt.Errorf("unknown config format %q (expected %s)", args[0], strings.Join(configfmt.Formats, ", "))
+	}
+
+	old := c.config.ConfigPath
+	path, err := c.config.Convert(format)
+	if err != nil {
+		return err
+	}
+	c.audit.Record("config_converted", map[string]string{"from": old, "to": path})
+	fmt.Printf("✅ Wrote %s (the old file is kept as %s.bak)\n", path, filepath.Base(old))
+	if format != configfmt.JSON {
+		fmt.Println("   Note: commands that change settings (such as privacy) rewrite it without comments")
+	}
+	return nil
+}
+
+// encryptConfig stores secret config sections encrypted, with a key kept
+// in the OS keychain or derived from a passphrase
+func (c *CLI) encryptConfig(args []string) error {
+	flags := flag.NewFlagSet("config encrypt", flag.ContinueOnError)
+	usePassphrase := flags.Bool("passphrase", false, "derive the key from a passphrase instead of the OS keychain")
+	sections, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var key *vault.Key
+	if !*usePassphrase {
+		// A new key must never replace the one already sealing sections
+		key, err = vault.KeychainKey(len(c.config.EncryptedSections) == 0)
+		if errors.Is(err, vault.ErrNoKeychain) {
+			fmt.Printf("⚠️  %v\n", err)
+		} else if err != nil {
+			return err
+		}
+	}
+	if key == nil {
+		passphrase := c.readSecret("🔑 Passphrase to encrypt the config: ")
+		if passphrase == "" || passphrase != c.readSecret("🔑 Repeat passphrase: ") {
+			return fmt.Errorf("passphrases are empty or do not match")
+		}
+		key = vault.PassphraseKey(passphrase)
+	}
+
+	// Save on top of the file rather than this session's settings
+	saved, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := saved.Encrypt(sections, key); err != nil {
+		return err
+	}
+	if err := saved.Save(); err != nil {
+		return err
+	}
+	c.config.KeepEncryption(saved)
+
+	c.audit.Record("config_encrypted", map[string]string{"sections": strings.Join(saved.EncryptedSections, ","), "key": key.Source})
+	fmt.Printf("🔒 Encrypted %s in %s\n", strings.Join(saved.EncryptedSections, ", "), saved.ConfigPath)
+	if key.Source == vault.SourcePassphrase {
+		fmt.Printf("   Set %s when starting DevOS so it can read them\n", vault.PassphraseEnv)
+	}
+	return nil
+}
+
+// decryptConfig stores every config section in plain text again
+func (c *CLI) decryptConfig() error {
+	saved, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if len(saved.EncryptedSections) == 0 {
+		fmt.Println("Nothing in the config is encrypted")
+		return nil
+	}
+	sections := strings.Join(saved.EncryptedSections, ", ")
+	saved.Decrypt()
+	if err := saved.Save(); err != nil {
+		return err
+	}
+	c.config.Decrypt()
+
+	c.audit.Record("config_decrypted", map[string]string{"sections": sections})
+	fmt.Printf("🔓 Stored %s in plain text in %s\n", sections, saved.ConfigPath)
+	return nil
 }
 
 func (c *CLI) showConfig() {
 	fmt.Println("\n⚙️  Configuration")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("  Config File:     %s\n", c.config.ConfigPath)
+	if source := c.config.EncryptionKey(); source != "" {
+		fmt.Printf("  Encrypted:       %s (%s key)\n", strings.Join(c.config.EncryptedSections, ", "), source)
+	}
+	if c.config.Managed() {
+		fmt.Printf("  Managed:         %s\n", c.config.ManagedSource())
+		if locked := c.config.Locked(); len(locked) > 0 {
+			fmt.Printf("  Locked:          %s\n", strings.Join(locked, ", "))
+		}
+	}
+	if env := c.config.ActiveEnvironment(); env != nil {
+		protected := ""
+		if env.Protected {
+			protected = " (protected)"
+		}
+		fmt.Printf("  Environment:     %s%s\n", env.Name, protected)
+	}
 	fmt.Printf("  AI Provider:     %s\n", c.config.AIProvider)
 	fmt.Printf("  Model:           %s\n", c.config.Model)
+	if c.config.ContextLength > 0 {
+		fmt.Printf("  Context Length:  %d tokens\n", c.config.ContextLength)
+	}
+	fmt.Printf("  Shell:           %s\n", c.config.Shell)
+	fmt.Printf("  Timezone:        %s (%s)\n", timefmt.Location(), timefmt.DateTime(time.Now()))
+	fmt.Printf("  tmux Tasks:      %v\n", c.config.Tmux)
 	fmt.Printf("  Confirmation:    %v\n", c.config.ConfirmationMode)
+	if c.config.AllowlistOnly {
+		fmt.Printf("  Allowlist Only:  %s\n", strings.Join(c.config.AllowedCommands, ", "))
+	}
 	fmt.Printf("  Max Tokens:      %d\n", c.config.MaxTokens)
 	fmt.Printf("  Temperature:     %.2f\n", c.config.Temperature)
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 }
 
 func main() {
 	cli, err := NewCLI()
 	if err != nil {
+		// Keep the failure in DevOS's own log for "devos logs --self"
+		log := logger.New("error")
+		log.Error("Failed to initialize DevOS: %v", err)
+		log.Close()
 		fmt.Fprintf(os.Stderr, "Failed to initialize DevOS: %v\n", err)
-		os.Exit(1)
+		os.Exit(executor.ExitCode(err))
+	}
+
+	if len(os.Args) > 1 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		err := cli.Run(ctx, os.Args[1:])
+		if err != nil {
+			cli.logger.Error("devos %s failed: %v", strings.Join(os.Args[1:], " "), err)
+		}
+		cli.logger.Close() // Flush log sinks
+		cli.audit.Close()  // and audit exports
+		cli.restoreOutput()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(executor.ExitCode(err))
+		}
+		return
 	}
 
-	if err := cli.Start(); err != nil {
+	err = cli.Start()
+	cli.restoreOutput()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// managedRegistryKey holds the managed configuration on Windows, as JSON in
+// its "Config" value, where group policy can deploy it
+const managedRegistryKey = `HKLM\SOFTWARE\Policies\DevOS`
+
+// managedConfig is the system-wide configuration an organization installs
+// on managed machines. Its settings win over the user's config file, which
+// cannot turn them off: blocked commands are added to the user's, and the
+// rest replace the user's values. Providers that are not allowed are
+// replaced by the first allowed one, or dropped from failover.
+type managedConfig struct {
+	Guardrails       *Guardrails `json:"guardrails,omitempty"`
+	SandboxMode      *bool       `json:"sandbox_mode,omitempty"`
+	ConfirmationMode *bool       `json:"confirmation_mode,omitempty"`
+	BlockedCommands  []string    `json:"blocked_commands,omitempty"`
+	AllowlistOnly    *bool       `json:"allowlist_only,omitempty"`
+	AllowedCommands  []string    `json:"allowed_commands,omitempty"`
+	AIProvider       string      `json:"ai_provider,omitempty"`
+	BaseURL          string      `json:"base_url,omitempty"`
+	AllowedProviders []string    `json:"allowed_providers,omitempty"` // Providers plans may go to, including failover and local-only ones
+
+	// source is where the managed configuration was read from
+	source string
+	// user holds the user's own values of the locked settings, which are
+	// what is saved to their config file
+	user lockedSettings
+}
+
+// lockedSettings are the settings a managed configuration can lock
+type lockedSettings struct {
+	SandboxMode      bool
+	ConfirmationMode bool
+	BlockedCommands  []string
+	AllowlistOnly    bool
+	AllowedCommands  []string
+	AIProvider       string
+	BaseURL          string
+	Failover         []string
+	LocalModel       string
+}
+
+// ManagedPath returns where the system-wide managed configuration file is
+// installed. On Windows, the registry is read first.
+func ManagedPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("ProgramData")
+		if base == "" {
+			base = `C:\ProgramData`
+		}
+		return filepath.Join(base, "DevOS", "managed.json")
+	case "darwin":
+		return "/Library/Application Support/DevOS/managed.json"
+	default:
+		return "/etc/devos/managed.json"
+	}
+}
+
+// loadManaged reads the managed configuration, or returns nil when the
+// machine is not managed
+func loadManaged() (*managedConfig, error) {
+	source := ManagedPath()
+	data, err := os.ReadFile(source)
+	if runtime.GOOS == "windows" {
+		if policy, ok := registryConfig(); ok {
+			source, data, err = managedRegistryKey, policy, nil
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read managed config: %w", err)
+	}
+
+	var managed managedConfig
+	if err := json.Unmarshal(data, &managed); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s: %w", ErrInvalidConfig, source, err)
+	}
+	managed.source = source
+	if g := managed.Guardrails; g != nil {
+		if err := g.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, source, err)
+		}
+	}
+	return &managed, nil
+}
+
+// registryConfig reads the managed configuration's JSON from the registry
+func registryConfig() ([]byte, bool) {
+	out, err := exec.Command("reg", "query", managedRegistryKey, "/v", "Config").Output()
+	if err != nil {
+		return nil, false
+	}
+	// "    Config    REG_SZ    {...}"
+	for _, line := range strings.Split(string(out), "\n") {
+		if _, value, ok := strings.Cut(line, "REG_SZ"); ok && strings.HasPrefix(strings.TrimSpace(line), "Config") {
+			return []byte(strings.TrimSpace(value)), true
+		}
+	}
+	return nil, false
+}
+
+// apply locks the managed settings in c, keeping the user's own values to
+// save
+func (m *managedConfig) apply(c *Config) {
+	m.user = lockedSettings{
+		SandboxMode:      c.SandboxMode,
+		ConfirmationMode: c.ConfirmationMode,
+		BlockedCommands:  c.BlockedCommands,
+		AllowlistOnly:    c.AllowlistOnly,
+		AllowedCommands:  c.AllowedCommands,
+		AIProvider:       c.AIProvider,
+		BaseURL:          c.BaseURL,
+		Failover:         c.Failover,
+		LocalModel:       c.LocalModel,
+	}
+	c.managed = m
+	if m.SandboxMode != nil {
+		c.SandboxMode = *m.SandboxMode
+	}
+	if m.ConfirmationMode != nil {
+		c.ConfirmationMode = *m.ConfirmationMode
+	}
+	if len(m.BlockedCommands) > 0 {
+		blocked := slices.Clone(m.BlockedCommands)
+		for _, cmd := range c.BlockedCommands {
+			if !slices.Contains(blocked, cmd) {
+				blocked = append(blocked, cmd)
+			}
+		}
+		c.BlockedCommands = blocked
+	}
+	if m.AllowlistOnly != nil {
+		c.AllowlistOnly = *m.AllowlistOnly
+	}
+	if len(m.AllowedCommands) > 0 {
+		c.AllowedCommands = slices.Clone(m.AllowedCommands)
+	}
+	if m.AIProvider != "" {
+		c.AIProvider = m.AIProvider
+	}
+	if m.BaseURL != "" {
+		c.BaseURL = m.BaseURL
+	}
+
+	if len(m.AllowedProviders) == 0 {
+		return
+	}
+	if !m.allows(c.AIProvider) {
+		c.AIProvider = m.AllowedProviders[0]
+	}
+	var failover []string
+	for _, spec := range c.Failover {
+		if provider, _, _ := strings.Cut(spec, ":"); m.allows(provider) {
+			failover = append(failover, spec)
+		}
+	}
+	c.Failover = failover
+	if provider, _, _ := strings.Cut(c.LocalModel, ":"); c.LocalModel != "" && !m.allows(provider) {
+		c.LocalModel = ""
+	}
+}
+
+// allows reports whether plans may go to provider
+func (m *managedConfig) allows(provider string) bool {
+	return len(m.AllowedProviders) == 0 || slices.Contains(m.AllowedProviders, provider)
+}
+
+// userSettings returns c with the locked settings put back to the user's
+// own values, as they are saved to the user's config file
+func (c Config) userSettings() Config {
+	if c.managed == nil {
+		return c
+	}
+	user := c.managed.user
+	c.SandboxMode = user.SandboxMode
+	c.ConfirmationMode = user.ConfirmationMode
+	c.BlockedCommands = user.BlockedCommands
+	c.AllowlistOnly = user.AllowlistOnly
+	c.AllowedCommands = user.AllowedCommands
+	c.AIProvider = user.AIProvider
+	c.BaseURL = user.BaseURL
+	c.Failover = user.Failover
+	c.LocalModel = user.LocalModel
+	return c
+}
+
+// KeepManaged locks the same managed settings in c as in another config,
+// e.g. one that c replaces on import
+func (c *Config) KeepManaged(from *Config) {
+	if from.managed != nil {
+		managed := *from.managed
+		managed.apply(c)
+	}
+}
+
+// AllowsProvider reports whether plans may go to provider; a managed
+// configuration can restrict them
+func (c *Config) AllowsProvider(provider string) bool {
+	return c.managed == nil || c.managed.allows(provider)
+}
+
+// Managed reports whether an organization manages this machine's
+// configuration
+func (c *Config) Managed() bool {
+	return c.managed != nil
+}
+
+// ManagedSource returns where the managed configuration was read from, or
+// "" when the machine is not managed
+func (c *Config) ManagedSource() string {
+	if c.managed == nil {
+		return ""
+	}
+	return c.managed.source
+}
+
+// Locked returns the settings the managed configuration locks, by their
+// config names
+func (c *Config) Locked() []string {
+	m := c.managed
+	if m == nil {
+		return nil
+	}
+	var locked []string
+	if m.Guardrails != nil {
+		locked = append(locked, "guardrails")
+	}
+	if m.SandboxMode != nil {
+		locked = append(locked, "sandbox_mode")
+	}
+	if m.ConfirmationMode != nil {
+		locked = append(locked, "confirmation_mode")
+	}
+	if len(m.BlockedCommands) > 0 {
+		locked = append(locked, "blocked_commands")
+	}
+	if m.AllowlistOnly != nil {
+		locked = append(locked, "allowlist_only")
+	}
+	if len(m.AllowedCommands) > 0 {
+		locked = append(locked, "allowed_commands")
+	}
+	if m.AIProvider != "" {
+		locked = append(locked, "ai_provider")
+	}
+	if m.BaseURL != "" {
+		locked = append(locked, "base_url")
+	}
+	if len(m.AllowedProviders) > 0 {
+		locked = append(locked, "allowed_providers")
+	}
+	return locked
+}
+
+// ManagedBlockedCommands returns the blocked commands the managed
+// configuration adds, which apply even when the sandbox is relaxed
+func (c *Config) ManagedBlockedCommands() []string {
+	if c.managed == nil {
+		return nil
+	}
+	return c.managed.BlockedCommands
+}
+
+// ActiveGuardrails returns the guardrails bundle in effect: the managed
+// one on a managed machine, whatever the user's config says, and otherwise
+// the user's, if any
+func (c *Config) ActiveGuardrails() *Guardrails {
+	if c.managed != nil && c.managed.Guardrails != nil {
+		return c.managed.Guardrails
+	}
+	return c.Guardrails
+}
@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// kubectlApply matches kubectl apply/create/replace with -f, capturing the
+// subcommand and the manifest path
+var kubectlApply = regexp.MustCompile(`^\s*kubectl\b(.*?)\s(apply|create|replace)\b(.*?)\s(?:-f|--filename)[\s=]+['"]?([^\s'"]+)`)
+
+// documentSeparator splits multi-document YAML
+var documentSeparator = regexp.MustCompile(`(?m)^---.*$`)
+
+// bundledAPIVersions are the served API versions for common kinds, used when
+// the cluster cannot be reached for a server-side dry-run
+var bundledAPIVersions = map[string][]string{
+	"Pod": {"v1"}, "Service": {"v1"}, "ConfigMap": {"v1"}, "Secret": {"v1"},
+	"Namespace": {"v1"}, "ServiceAccount": {"v1"}, "PersistentVolumeClaim": {"v1"},
+	"PersistentVolume": {"v1"}, "Deployment": {"apps/v1"}, "StatefulSet": {"apps/v1"},
+	"DaemonSet": {"apps/v1"}, "ReplicaSet": {"apps/v1"}, "Job": {"batch/v1"},
+	"CronJob": {"batch/v1"}, "Ingress": {"networking.k8s.io/v1"},
+	"NetworkPolicy": {"networking.k8s.io/v1"}, "HorizontalPodAutoscaler": {"autoscaling/v2", "autoscaling/v1"},
+	"Role": {"rbac.authorization.k8s.io/v1"}, "RoleBinding": {"rbac.authorization.k8s.io/v1"},
+	"ClusterRole": {"rbac.authorization.k8s.io/v1"}, "ClusterRoleBinding": {"rbac.authorization.k8s.io/v1"},
+	"PodDisruptionBudget": {"policy/v1"},
+}
+
+// ManifestCheck is the validation outcome for the manifests one plan step applies
+type ManifestCheck struct {
+	Command string
+	Path    string
+	Method  string // "server dry-run" or "bundled schemas"
+	Errors  []string
+}
+
+// CheckManifests validates Kubernetes manifests the plan applies, using a
+// server-side dry-run against the target cluster and falling back to the
+// bundled schemas when the cluster is unreachable. Plans with invalid
+// manifests are marked as needing confirmation.
+func (e *Executor) CheckManifests(ctx context.Context, result *ExecutionResult) []ManifestCheck {
+	var checks []ManifestCheck
+	for _, cmd := range result.Commands {
+		m := kubectlApply.FindStringSubmatch(cmd)
+		if m == nil || m[4] == "-" || strings.ContainsAny(cmd, "|;&") {
+			continue
+		}
+
+		check := ManifestCheck{Command: cmd, Path: m[4], Method: "server dry-run"}
+		dryRun := "kubectl" + m[1] + " " + m[2] + " --dry-run=server" + m[3] + " -f " + Quote(m[4])
+		if _, err := e.executeShellCommand(ctx, dryRun); err != nil {
+			if message := failureText(err); reachedCluster(message) {
+				check.Errors = append(check.Errors, message)
+			} else {
+				e.logger.Warn("Server-side dry-run unavailable, using bundled schemas: %s", message)
+				check.Method = "bundled schemas"
+				check.Errors = validateManifestPath(m[4])
+			}
+		}
+
+		if len(check.Errors) > 0 {
+			result.NeedsConfirmation = true
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// reachedCluster reports whether a kubectl failure came from the API server
+// rejecting the manifests rather than from connectivity or configuration
+func reachedCluster(message string) bool {
+	for _, s := range []string{"connection refused", "no configuration has been provided", "Unable to connect", "dial tcp", "i/o timeout", "executable file not found"} {
+		if strings.Contains(message, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateManifestPath checks a manifest file, or every YAML file in a directory
+func validateManifestPath(path string) []string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return nil
+	}
+
+	files := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		yml, _ := filepath.Glob(filepath.Join(path, "*.yml"))
+		yaml, _ := filepath.Glob(filepath.Join(path, "*.yaml"))
+		files = append(yml, yaml...)
+	}
+
+	var errs []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, problem := range validateManifest(string(data)) {
+			errs = append(errs, file+": "+problem)
+		}
+	}
+	return errs
+}
+
+// validateManifest checks each YAML document for the fields every object
+// needs and for API versions the bundled schemas know are wrong
+func validateManifest(content string) []string {
+	var errs []string
+	for i, doc := range documentSeparator.Split(content, -1) {
+		fields := topLevelFields(doc)
+		if len(fields) == 0 {
+			continue
+		}
+
+		where := fmt.Sprintf("document %d", i+1)
+		kind, apiVersion := fields["kind"], fields["apiVersion"]
+		if kind == "" {
+			errs = append(errs, where+": missing kind")
+		}
+		if apiVersion == "" {
+			errs = append(errs, where+": missing apiVersion")
+		}
+		if _, ok := fields["metadata"]; !ok || (!strings.Contains(doc, "\n  name:") && !strings.Contains(doc, "\n  generateName:")) {
+			errs = append(errs, where+": missing metadata.name")
+		}
+		if strings.Contains(doc, "\t") {
+			errs = append(errs, where+": tabs are not allowed in YAML")
+		}
+
+		if versions, ok := bundledAPIVersions[kind]; ok && apiVersion != "" && !containsString(versions, apiVersion) {
+			errs = append(errs, fmt.Sprintf("%s: %s is not served as %s (use %s)", where, kind, apiVersion, versions[0]))
+		}
+	}
+	return errs
+}
+
+// topLevelFields returns the unindented scalar keys of a YAML document
+func topLevelFields(doc string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '#' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return fields
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
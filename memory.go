@@ -0,0 +1,370 @@
+package memory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"devos/internal/audit"
+	"devos/internal/timefmt"
+)
+
+// Memory backends
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+// ErrBackupUnsupported is returned by Backup for backends whose data lives
+// in a server rather than a local file
+var ErrBackupUnsupported = errors.New("backup is not supported by this memory backend")
+
+// MemoryStore is the persistence DevOS needs for corrections, feedback,
+// task history, the execution journal, and the failure knowledge base
+type MemoryStore interface {
+	Close() error
+	Backup(path string) error
+
+	AddCorrection(c Correction) error
+	RecentCorrections(limit int) ([]Correction, error)
+
+	AddFeedback(f Feedback) error
+	RecentFeedback(limit int) ([]Feedback, error)
+
+	RecordTask(t Task) error
+	TasksSince(since time.Time) ([]Task, error)
+	LastUndoable() (*Task, error)
+	MarkUndone(id int64) error
+
+	StartRun(commands []string, steps []byte) (int64, error)
+	UpdateRun(id int64, completed int, status string) error
+	LastRun(planHash string) (*Run, error)
+	LatestUnfinishedRun() (*Run, error)
+
+	SaveResolution(signature, sample string, commands []string) error
+	LookupResolution(signature string) (*Resolution, error)
+
+	RecordPolicyTrigger(t PolicyTrigger) error
+	PolicyTriggersSince(since time.Time) ([]PolicyTrigger, error)
+
+	// RecordEvent stores an audit event alongside the memory data
+	RecordEvent(event audit.Event) error
+}
+
+// Store persists DevOS memory (corrections, history, knowledge) in SQLite,
+// or in Postgres when several users share a daemon
+type Store struct {
+	db      *sql.DB
+	backend string
+	maxSize int
+}
+
+// OpenBackend opens the configured memory backend: the SQLite database at
+// path, or the Postgres database at dsn
+func OpenBackend(backend, path, dsn string, maxSize int) (MemoryStore, error) {
+	switch backend {
+	case "", BackendSQLite:
+		return Open(path, maxSize)
+	case BackendPostgres:
+		return OpenPostgres(dsn, maxSize)
+	default:
+		return nil, fmt.Errorf("unknown memory backend: %s", backend)
+	}
+}
+
+// Correction is a proposed command the user edited before approving it
+type Correction struct {
+	Input     string    `json:"input"`
+	Original  string    `json:"original"`
+	Edited    string    `json:"edited"`
+	Project   string    `json:"project,omitempty"` // Root of the project the command was edited in
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// schema holds the statements required to initialize the database
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS corrections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		input TEXT NOT NULL,
+		original TEXT NOT NULL,
+		edited TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS failures (
+		signature TEXT PRIMARY KEY,
+		sample TEXT NOT NULL,
+		commands TEXT NOT NULL,
+		hits INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		input TEXT NOT NULL,
+		category TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		commands INTEGER NOT NULL,
+		success BOOLEAN NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		tokens INTEGER NOT NULL,
+		rollback TEXT NOT NULL DEFAULT '',
+		undone BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		plan_hash TEXT NOT NULL,
+		commands TEXT NOT NULL,
+		steps TEXT NOT NULL DEFAULT '',
+		completed INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS runs_plan_hash ON runs (plan_hash)`,
+	`CREATE TABLE IF NOT EXISTS policy_triggers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule TEXT NOT NULL,
+		action TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		command TEXT NOT NULL,
+		project TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS feedback (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		input TEXT NOT NULL,
+		output TEXT NOT NULL,
+		commands TEXT NOT NULL,
+		rating TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		project TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		username TEXT NOT NULL,
+		fields TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+}
+
+// column is a column added to an existing table after its initial release
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addedColumns are applied to databases created by older versions
+var addedColumns = []column{
+	{"runs", "steps", "TEXT NOT NULL DEFAULT ''"},
+	{"corrections", "project", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "rollback", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "undone", "BOOLEAN NOT NULL DEFAULT FALSE"},
+}
+
+// addColumn adds a column to a SQLite table unless it already exists
+func addColumn(db *sql.DB, col column) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", col.table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == col.name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.name, col.definition))
+	return err
+}
+
+// Open opens (or creates) the memory database at the given path
+func Open(path string, maxSize int) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory database: %w", err)
+	}
+
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize memory database: %w", err)
+		}
+	}
+
+	for _, col := range addedColumns {
+		if err := addColumn(db, col); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate memory database: %w", err)
+		}
+	}
+
+	if maxSize <= 0 {
+		maxSize = 100
+	}
+
+	return &Store{db: db, backend: BackendSQLite, maxSize: maxSize}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Backup writes a consistent copy of the database to path, which must not exist
+func (s *Store) Backup(path string) error {
+	if s.backend != BackendSQLite {
+		return ErrBackupUnsupported
+	}
+	if _, err := s.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up memory database: %w", err)
+	}
+	return nil
+}
+
+// AddCorrection records an original/edited command pair
+func (s *Store) AddCorrection(c Correction) error {
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = timefmt.Now()
+	}
+
+	_, err := s.exec(
+		`INSERT INTO corrections (input, original, edited, project, created_at) VALUES (?, ?, ?, ?, ?)`,
+		c.Input, c.Original, c.Edited, c.Project, c.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save correction: %w", err)
+	}
+
+	return s.prune("corrections")
+}
+
+// RecentCorrections returns the most recent corrections, newest first
+func (s *Store) RecentCorrections(limit int) ([]Correction, error) {
+	rows, err := s.query(
+		`SELECT input, original, edited, project, created_at FROM corrections ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corrections: %w", err)
+	}
+	defer rows.Close()
+
+	var corrections []Correction
+	for rows.Next() {
+		var c Correction
+		if err := rows.Scan(&c.Input, &c.Original, &c.Edited, &c.Project, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read correction: %w", err)
+		}
+		corrections = append(corrections, c)
+	}
+
+	return corrections, rows.Err()
+}
+
+// prune keeps at most maxSize rows in the given table
+func (s *Store) prune(table string) error {
+	query := fmt.Sprintf(
+		`DELETE FROM %s WHERE id NOT IN (SELECT id FROM %s ORDER BY id DESC LIMIT ?)`,
+		table, table,
+	)
+	if _, err := s.exec(query, s.maxSize); err != nil {
+		return fmt.Errorf("failed to prune %s: %w", table, err)
+	}
+	return nil
+}
+
+// RecordEvent stores an audit event
+func (s *Store) RecordEvent(event audit.Event) error {
+	fields, err := json.Marshal(event.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	_, err = s.exec(
+		`INSERT INTO audit_events (type, username, fields, created_at) VALUES (?, ?, ?, ?)`,
+		event.Type, event.User, string(fields), event.Time.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// exec runs a statement written with ? placeholders on any backend
+func (s *Store) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.rebind(query), args...)
+}
+
+// query runs a query written with ? placeholders on any backend
+func (s *Store) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.rebind(query), args...)
+}
+
+// queryRow runs a single-row query written with ? placeholders on any backend
+func (s *Store) queryRow(query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRow(s.rebind(query), args...)
+}
+
+// insert runs an INSERT and returns the new row's id
+func (s *Store) insert(query string, args ...interface{}) (int64, error) {
+	if s.backend == BackendPostgres {
+		// lib/pq does not implement LastInsertId
+		var id int64
+		err := s.queryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := s.exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// rebind rewrites ? placeholders to Postgres' $1, $2, ... The queries in
+// this package never contain a literal question mark.
+func (s *Store) rebind(query string) string {
+	if s.backend != BackendPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,354 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"devos/internal/ollama"
+)
+
+// ggufMagic starts every GGUF model file
+var ggufMagic = []byte("GGUF")
+
+// ErrCorrupt is returned when a model file fails its integrity check
+var ErrCorrupt = errors.New("model file is corrupt")
+
+// File is a GGUF model stored in the DevOS models directory
+type File struct {
+	Name     string // File name without .gguf
+	Path     string
+	Size     int64
+	Modified time.Time
+	SHA256   string // Recorded when the file was pulled; empty if unknown
+}
+
+// Auto is the model name that lets DevOS pick a model for the machine
+const Auto = "auto"
+
+// Recommendation is a model suited to the machine's memory
+type Recommendation struct {
+	Ollama    string // Ollama model name
+	GGUF      string // Download URL of a quantized GGUF build
+	Size      uint64 // Approximate size of the weights, in bytes
+	MinMemory uint64 // Memory the model needs to run comfortably, in bytes
+}
+
+// recommendations are ordered from largest to smallest. Sizes assume 4-bit
+// quantization with room for the context and the rest of the system.
+var recommendations = []Recommendation{
+	{"qwen2.5-coder:32b", "https://huggingface.co/Qwen/Qwen2.5-Coder-32B-Instruct-GGUF/resolve/main/qwen2.5-coder-32b-instruct-q4_k_m.gguf", 20 << 30, 24 << 30},
+	{"qwen2.5-coder:14b", "https://huggingface.co/Qwen/Qwen2.5-Coder-14B-Instruct-GGUF/resolve/main/qwen2.5-coder-14b-instruct-q4_k_m.gguf", 9 << 30, 12 << 30},
+	{"qwen2.5-coder:7b", "https://huggingface.co/Qwen/Qwen2.5-Coder-7B-Instruct-GGUF/resolve/main/qwen2.5-coder-7b-instruct-q4_k_m.gguf", 5 << 30, 8 << 30},
+	{"qwen2.5-coder:3b", "https://huggingface.co/Qwen/Qwen2.5-Coder-3B-Instruct-GGUF/resolve/main/qwen2.5-coder-3b-instruct-q4_k_m.gguf", 2 << 30, 4 << 30},
+	{"qwen2.5-coder:1.5b", "https://huggingface.co/Qwen/Qwen2.5-Coder-1.5B-Instruct-GGUF/resolve/main/qwen2.5-coder-1.5b-instruct-q4_k_m.gguf", 1 << 30, 0},
+}
+
+// Recommend picks the largest model that fits in the memory budget
+func Recommend(ram, vram uint64) Recommendation {
+	budget := memoryBudget(ram, vram)
+	for _, r := range recommendations {
+		if budget >= r.MinMemory {
+			return r
+		}
+	}
+	return recommendations[len(recommendations)-1]
+}
+
+// Select resolves "auto" to a model name: the recommended model if it is
+// pulled, otherwise the largest pulled model that fits in memory. With
+// nothing suitable pulled it returns the recommendation, which the caller
+// can offer to pull.
+func Select(ram, vram uint64, pulled []ollama.Model) string {
+	rec := Recommend(ram, vram)
+	if ollama.Find(pulled, rec.Ollama) != nil {
+		return rec.Ollama
+	}
+
+	budget := memoryBudget(ram, vram)
+	best := ""
+	var bestSize int64
+	for _, m := range pulled {
+		if fits(m.Size, budget) && m.Size > bestSize {
+			best, bestSize = m.Name, m.Size
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return rec.Ollama
+}
+
+// SelectFile resolves "auto" for GGUF models: the largest file in files that
+// fits in memory, or nil if none does
+func SelectFile(ram, vram uint64, files []File) *File {
+	budget := memoryBudget(ram, vram)
+	var best *File
+	for i, f := range files {
+		if fits(f.Size, budget) && (best == nil || f.Size > best.Size) {
+			best = &files[i]
+		}
+	}
+	return best
+}
+
+// fits reports whether a model of size bytes leaves a quarter of the budget
+// for the context and runtime; an unknown budget fits anything
+func fits(size int64, budget uint64) bool {
+	return budget == 0 || uint64(size) <= budget/4*3
+}
+
+// contextLengths are the context windows ContextLength picks from, with the
+// memory their KV cache needs for a typical 4-bit model at that size
+var contextLengths = []struct {
+	tokens int
+	memory uint64
+}{
+	{32768, 6 << 30},
+	{16384, 3 << 30},
+	{8192, 3 << 29},
+	{4096, 0},
+}
+
+// ContextLength sizes the context window to the memory left once a model of
+// modelSize bytes is loaded, or returns 0 when memory is unknown
+func ContextLength(ram, vram, modelSize uint64) int {
+	if ram == 0 && vram == 0 {
+		return 0
+	}
+	var free uint64
+	if budget := memoryBudget(ram, vram); budget > modelSize {
+		free = budget - modelSize
+	}
+	for _, c := range contextLengths {
+		if free >= c.memory {
+			return c.tokens
+		}
+	}
+	return contextLengths[len(contextLengths)-1].tokens
+}
+
+// memoryBudget is the memory a local model may use. A GPU with enough memory
+// runs the model on its own; otherwise it has to share system RAM, of which
+// half is assumed available.
+func memoryBudget(ram, vram uint64) uint64 {
+	if vram > ram/2 {
+		return vram
+	}
+	return ram / 2
+}
+
+// IsFileSource reports whether a pull source is a GGUF download rather than
+// an Ollama model name
+func IsFileSource(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// Files lists the GGUF models in dir, largest first
+func Files(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".gguf")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		f := File{Name: name, Path: filepath.Join(dir, entry.Name()), Size: info.Size(), Modified: info.ModTime()}
+		if sum, err := os.ReadFile(f.Path + ".sha256"); err == nil {
+			f.SHA256 = strings.TrimSpace(string(sum))
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	return files, nil
+}
+
+// FindFile returns the GGUF model in dir called name (with or without .gguf)
+func FindFile(dir, name string) (*File, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSuffix(name, ".gguf")
+	for i, f := range files {
+		if f.Name == name {
+			return &files[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// PullFile downloads a GGUF model into dir. The download is checked against
+// checksum (hex SHA-256) if given, or else the SHA-256 Hugging Face reports
+// for the file, and the hash is kept next to the model for later checks.
+func PullFile(ctx context.Context, source, dir, checksum string, progress func(ollama.Progress)) (*File, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model URL: %w", err)
+	}
+	name := strings.TrimSuffix(path.Base(u.Path), ".gguf")
+	if name == "" || name == "." || name == "/" {
+		return nil, fmt.Errorf("cannot name a model after %s", source)
+	}
+	dest := filepath.Join(dir, name+".gguf")
+	if _, err := os.Stat(dest); err == nil {
+		return nil, fmt.Errorf("%s is already pulled", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", source, resp.Status)
+	}
+	if checksum == "" {
+		// Hugging Face serves large files from LFS, whose ETag is the SHA-256
+		checksum = strings.Trim(resp.Header.Get("X-Linked-Etag"), `"`)
+		if len(checksum) != sha256.Size*2 {
+			checksum = ""
+		}
+	}
+
+	part := dest + ".part"
+	out, err := os.Create(part)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(part) // No-op once renamed
+
+	hash := sha256.New()
+	status := "downloading " + name
+	counter := &progressWriter{total: resp.ContentLength, report: func(done, total int64) {
+		if progress != nil {
+			progress(ollama.Progress{Status: status, Total: total, Completed: done})
+		}
+	}}
+	_, err = io.Copy(io.MultiWriter(out, hash, counter), resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && !strings.EqualFold(sum, checksum) {
+		return nil, fmt.Errorf("%w: %s has SHA-256 %s, expected %s", ErrCorrupt, name, sum, checksum)
+	}
+	if err := checkMagic(part); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(dest+".sha256", []byte(sum+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		progress(ollama.Progress{Status: "success"})
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Name: name, Path: dest, Size: info.Size(), Modified: info.ModTime(), SHA256: sum}, nil
+}
+
+// Verify checks that a GGUF model is intact: it must start with the GGUF
+// header and match the hash recorded when it was pulled
+func Verify(f *File) error {
+	if err := checkMagic(f.Path); err != nil {
+		return err
+	}
+	if f.SHA256 == "" {
+		return nil
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != f.SHA256 {
+		return fmt.Errorf("%w: %s has SHA-256 %s, expected %s", ErrCorrupt, f.Name, sum, f.SHA256)
+	}
+	return nil
+}
+
+// RemoveFile deletes a GGUF model and its recorded hash
+func RemoveFile(f *File) error {
+	if err := os.Remove(f.Path); err != nil {
+		return err
+	}
+	os.Remove(f.Path + ".sha256")
+	return nil
+}
+
+// checkMagic fails unless the file at path starts with the GGUF header,
+// e.g. when a download saved an HTML error page
+func checkMagic(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(ggufMagic))
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, ggufMagic) {
+		return fmt.Errorf("%w: %s is not a GGUF file", ErrCorrupt, filepath.Base(strings.TrimSuffix(path, ".part")))
+	}
+	return nil
+}
+
+// progressWriter reports bytes written at most every 100ms
+type progressWriter struct {
+	done, total int64
+	last        time.Time
+	report      func(done, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	if time.Since(w.last) >= 100*time.Millisecond || w.done == w.total {
+		w.last = time.Now()
+		w.report(w.done, w.total)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,275 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/netdiag"
+	"devos/internal/timefmt"
+)
+
+// Statuses of a check
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// DefaultWarnDays is how long before a certificate expires it is warned
+// about when the monitor does not say
+const DefaultWarnDays = 14
+
+// DefaultEvery is how often the daemon runs a monitor that does not say
+const DefaultEvery = time.Hour
+
+// checkTimeout bounds a single check
+const checkTimeout = 15 * time.Second
+
+// Result is the outcome of running a monitor
+type Result struct {
+	Name      string    `json:"name"`
+	Check     string    `json:"check"`
+	Target    string    `json:"target"`
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Run runs one monitor's check
+func Run(ctx context.Context, m config.Monitor) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	r := Result{Name: m.Name, Check: m.Check, Target: m.Target, CheckedAt: time.Now()}
+	target, err := netdiag.ParseTarget(m.Target)
+	if err != nil {
+		r.Status, r.Detail = StatusFail, err.Error()
+		return r
+	}
+	switch m.Check {
+	case "cert":
+		r.Status, r.Detail = checkCert(ctx, target, m.WarnDays)
+	case "dns":
+		r.Status, r.Detail = checkDNS(ctx, target.Host, m.Record, m.Expect)
+	case "reachable":
+		r.Status, r.Detail = checkReachable(ctx, m.Target, target)
+	default:
+		r.Status, r.Detail = StatusFail, "unknown check: "+m.Check
+	}
+	return r
+}
+
+// checkCert warns when the certificate expires within warnDays, and fails
+// when it has expired or does not verify
+func checkCert(ctx context.Context, target netdiag.Target, warnDays int) (string, string) {
+	if warnDays == 0 {
+		warnDays = DefaultWarnDays
+	}
+	cert, err := netdiag.CheckTLS(ctx, target.Host, target.Port)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	days := int(time.Until(cert.NotAfter).Hours() / 24)
+	detail := fmt.Sprintf("expires %s (%d days), issued by %s", timefmt.Date(cert.NotAfter), days, cert.Issuer)
+	if days < warnDays {
+		return StatusWarn, detail
+	}
+	return StatusOK, detail
+}
+
+// checkDNS fails when host has no records of the type, or they leave out
+// any of the expected values
+func checkDNS(ctx context.Context, host, record string, expect []string) (string, string) {
+	record = strings.ToUpper(record)
+	if record == "" {
+		record = "A"
+	}
+	values, err := lookup(ctx, host, record)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	if len(values) == 0 {
+		return StatusFail, fmt.Sprintf("no %s records for %s", record, host)
+	}
+
+	found := make(map[string]bool, len(values))
+	for _, v := range values {
+		found[normalize(v)] = true
+	}
+	var missing []string
+	for _, want := range expect {
+		if !found[normalize(want)] {
+			missing = append(missing, want)
+		}
+	}
+	detail := fmt.Sprintf("%s %s", record, strings.Join(values, ", "))
+	if len(missing) > 0 {
+		return StatusFail, fmt.Sprintf("missing %s; found %s", strings.Join(missing, ", "), detail)
+	}
+	return StatusOK, detail
+}
+
+// lookup returns host's records of one type
+func lookup(ctx context.Context, host, record string) ([]string, error) {
+	resolver := net.DefaultResolver
+	var values []string
+	switch record {
+	case "A", "AAAA":
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			if (a.IP.To4() != nil) == (record == "A") {
+				values = append(values, a.IP.String())
+			}
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if normalize(cname) != normalize(host) {
+			values = append(values, strings.TrimSuffix(cname, "."))
+		}
+	case "MX":
+		records, err := resolver.LookupMX(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			values = append(values, strings.TrimSuffix(mx.Host, "."))
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range records {
+			values = append(values, strings.TrimSuffix(ns.Host, "."))
+		}
+	case "TXT":
+		return resolver.LookupTXT(ctx, host)
+	default:
+		return nil, fmt.Errorf("unsupported DNS record type: %s", record)
+	}
+	return values, nil
+}
+
+// normalize compares DNS values without case or a trailing dot
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), "."))
+}
+
+// checkReachable fetches an http(s) URL, failing on an error status, and
+// otherwise connects to the host's port
+func checkReachable(ctx context.Context, raw string, target netdiag.Target) (string, string) {
+	if target.Scheme == "http" || target.Scheme == "https" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		resp.Body.Close()
+		detail := fmt.Sprintf("HTTP %d in %s", resp.StatusCode, time.Since(start).Round(time.Millisecond))
+		if resp.StatusCode >= 400 {
+			return StatusFail, detail
+		}
+		return StatusOK, detail
+	}
+
+	ping, err := netdiag.TCPPing(ctx, net.JoinHostPort(target.Host, strconv.Itoa(target.Port)), 3)
+	if ping.Received == 0 {
+		return StatusFail, err.Error()
+	}
+	detail := fmt.Sprintf("%d/%d connects to port %d, avg %s", ping.Received, ping.Sent, target.Port, ping.Average().Round(100*time.Microsecond))
+	if ping.Received < ping.Sent {
+		return StatusWarn, detail
+	}
+	return StatusOK, detail
+}
+
+// Every returns how often the daemon runs a monitor
+func Every(m config.Monitor) time.Duration {
+	if d, err := time.ParseDuration(m.Every); err == nil && d > 0 {
+		return d
+	}
+	return DefaultEvery
+}
+
+// Scheduler runs monitors on their intervals, reporting each result whose
+// status differs from the one before, and a first result that is not OK
+type Scheduler struct {
+	monitors []config.Monitor
+	onChange func(result Result, previous string)
+
+	mu   sync.Mutex
+	last map[string]Result
+}
+
+// NewScheduler returns a scheduler for monitors that calls onChange with a
+// changed result and the previous status ("" for the first result)
+func NewScheduler(monitors []config.Monitor, onChange func(result Result, previous string)) *Scheduler {
+	return &Scheduler{monitors: monitors, onChange: onChange, last: make(map[string]Result)}
+}
+
+// Run runs the monitors that are due every minute until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		s.runDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue runs the monitors whose interval has passed since their last run
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	for _, m := range s.monitors {
+		s.mu.Lock()
+		previous, ran := s.last[m.Name]
+		s.mu.Unlock()
+		if ran && now.Sub(previous.CheckedAt) < Every(m) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		result := Run(ctx, m)
+		s.mu.Lock()
+		s.last[m.Name] = result
+		s.mu.Unlock()
+		if result.Status != previous.Status && (ran || result.Status != StatusOK) {
+			s.onChange(result, previous.Status)
+		}
+	}
+}
+
+// Results returns the latest result of each monitor that has run
+func (s *Scheduler) Results() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]Result, 0, len(s.last))
+	for _, m := range s.monitors {
+		if r, ok := s.last[m.Name]; ok {
+			results = append(results, r)
+		}
+	}
+	return results
+}
@@ -0,0 +1,219 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"devos/internal/helm"
+	"devos/internal/memory"
+	"devos/internal/project"
+)
+
+// MaxParallelRepos bounds how many repositories a multi-repo task runs in
+// at once
+const MaxParallelRepos = 4
+
+// reviewURL matches links to the pull or merge requests a command opened on
+// GitHub, GitLab, Bitbucket, or Gitea
+var reviewURL = regexp.MustCompile(`https?://\S+/(?:pull|pulls|merge_requests|pull-requests)/\d+`)
+
+// RepoPlan is the plan for one repository of a multi-repo task
+type RepoPlan struct {
+	Repo   string
+	Result *ExecutionResult
+	Err    error // Why the repository has no plan
+}
+
+// RepoResult is the outcome of a multi-repo task in one repository
+type RepoResult struct {
+	Repo      string
+	Steps     int
+	Completed int
+	Duration  time.Duration
+	Output    string   // What the commands printed, stdout and stderr together
+	Reviews   []string // Pull or merge requests the commands opened
+	Err       error
+}
+
+// ResolveRepos returns the repositories spec names, as their project roots:
+// a group from the "repos" config, or directories separated by commas
+func (e *Executor) ResolveRepos(spec string) ([]string, error) {
+	dirs, ok := e.config.Repos[spec]
+	if !ok {
+		dirs = strings.Split(spec, ",")
+	}
+	var repos []string
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(expandHome(dir))
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("not a directory: %s", dir)
+		}
+		if root := project.Root(abs); !seen[root] {
+			seen[root] = true
+			repos = append(repos, root)
+		}
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no repositories in %q", spec)
+	}
+	return repos, nil
+}
+
+// PlanRepos asks the AI engine for a plan per repository, each with that
+// repository's project context ("repo", "project", "helm_charts"), so a task
+// spanning repositories fits how each is laid out. Every plan is told the
+// other repositories ("repos") so it can refer to them.
+func (e *Executor) PlanRepos(ctx context.Context, input string, repos []string) []RepoPlan {
+	plans := make([]RepoPlan, len(repos))
+	for i, repo := range repos {
+		plans[i].Repo = repo
+		if err := ctx.Err(); err != nil {
+			plans[i].Err = err
+			continue
+		}
+		e.logger.Info("Planning in %s: %s", repo, input)
+		layout := project.Detect(repo)
+		plans[i].Result, plans[i].Err = e.generate(ctx, input, map[string]interface{}{
+			"repo":        repo,
+			"repos":       repos,
+			"project":     layout,
+			"helm_charts": helm.FindCharts(layout.Root),
+		})
+	}
+	return plans
+}
+
+// ExecuteRepos runs the plans of a multi-repo task in their repositories,
+// up to MaxParallelRepos at a time. Each repository's commands run in
+// order until one fails, with their output collected rather than
+// interleaved, on an executor of their own; progress, when set, is called
+// as each repository finishes.
+// Repositories without a plan are reported with why.
+func (e *Executor) ExecuteRepos(ctx context.Context, plans []RepoPlan, progress func(RepoResult)) []RepoResult {
+	results := make([]RepoResult, len(plans))
+	slots := make(chan struct{}, MaxParallelRepos)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, plan := range plans {
+		if plan.Err != nil || plan.Result == nil || len(plan.Result.Commands) == 0 {
+			results[i] = RepoResult{Repo: plan.Repo, Err: plan.Err}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, plan RepoPlan, e *Executor) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = e.runInRepo(ctx, plan.Repo, plan.Result)
+			if progress != nil {
+				mu.Lock()
+				progress(results[i])
+				mu.Unlock()
+			}
+		}(i, plan, e.detached())
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	e.audit.Record("multi_repo_executed", map[string]string{
+		"repos":  fmt.Sprint(len(results)),
+		"failed": fmt.Sprint(failed),
+	})
+	return results
+}
+
+// runInRepo runs a plan in a repository, holding its project lock, and
+// stops at the first failure. Each command runs as runCommands runs it,
+// journaled, and commands follow each other's cd, as in one shell.
+func (e *Executor) runInRepo(ctx context.Context, repo string, result *ExecutionResult) RepoResult {
+	start := time.Now()
+	commands := result.Commands
+	r := RepoResult{Repo: repo, Steps: len(commands)}
+	release, err := e.lockProjectAt(repo, commands)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer release()
+
+	steps := result.Steps
+	if len(steps) != len(commands) {
+		steps = nil
+	}
+	runID := e.startRun(commands, steps)
+	scanned := make(map[string]bool)
+	var out bytes.Buffer
+	dir := repo
+	for i, cmdStr := range commands {
+		if err := ctx.Err(); err != nil {
+			e.updateRun(runID, i, memory.RunFailed)
+			r.Err = fmt.Errorf("interrupted before step %d: %w", i+1, err)
+			break
+		}
+		if err := e.scanBeforeDeploy(ctx, cmdStr, plannedImages(commands[:i]), scanned); err != nil {
+			e.updateRun(runID, i, memory.RunFailed)
+			r.Err = err
+			break
+		}
+		e.logger.Info("Executing command %d/%d in %s: %s", i+1, len(commands), repo, cmdStr)
+
+		var step *Command
+		if steps != nil {
+			step = &steps[i]
+		}
+		if step != nil && cdPrograms[step.Program] {
+			// cd is a shell builtin: as a step, it changes the repository's directory
+			var target string
+			if target, err = e.stepDir(dir, *step); err == nil {
+				dir = target
+			}
+		} else {
+			_, err = e.runCommand(ctx, cmdStr, step, dir, &out)
+			if err == nil && step == nil {
+				dir = e.shellDir(dir, cmdStr)
+			}
+		}
+		e.recordExecution(filepath.Base(repo)+": "+cmdStr, err)
+		if err != nil {
+			e.updateRun(runID, i, memory.RunFailed)
+			r.Err = fmt.Errorf("command failed: %s - %w", cmdStr, err)
+			break
+		}
+		r.Completed++
+		e.updateRun(runID, i+1, memory.RunRunning)
+	}
+	if r.Completed == len(commands) {
+		e.updateRun(runID, r.Completed, memory.RunSucceeded)
+	}
+
+	r.Duration = time.Since(start)
+	r.Output = out.String()
+	seen := map[string]bool{}
+	for _, url := range reviewURL.FindAllString(r.Output, -1) {
+		if !seen[url] {
+			seen[url] = true
+			r.Reviews = append(r.Reviews, url)
+		}
+	}
+	return r
+}
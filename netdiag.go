@@ -0,0 +1,386 @@
+package netdiag
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// dialTimeout bounds each connection attempt
+const dialTimeout = 5 * time.Second
+
+// hopLine matches a traceroute/tracert hop: number, then addresses or "*"
+var hopLine = regexp.MustCompile(`^\s*(\d+)\s+(.*)$`)
+
+// hopRTT matches round-trip times such as "12.3 ms" or "<1 ms"
+var hopRTT = regexp.MustCompile(`<?(\d+(?:\.\d+)?)\s*ms`)
+
+// hopAddress matches an IPv4 or IPv6 address in a hop line
+var hopAddress = regexp.MustCompile(`\b(\d{1,3}(?:\.\d{1,3}){3}|[0-9a-fA-F]*:[0-9a-fA-F:]+)\b`)
+
+// Failure kinds, so the AI engine can interpret results without parsing messages
+const (
+	FailNoSuchHost  = "no_such_host"
+	FailDNS         = "dns_error"
+	FailRefused     = "connection_refused"
+	FailTimeout     = "timeout"
+	FailUnreachable = "unreachable"
+	FailExpired     = "certificate_expired"
+	FailHostname    = "certificate_hostname_mismatch"
+	FailUntrusted   = "certificate_untrusted"
+	FailTLS         = "tls_error"
+	FailOther       = "error"
+)
+
+// Target is a host and port to diagnose
+type Target struct {
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// ParseTarget accepts a URL, host:port, or bare host (port 443)
+func ParseTarget(s string) (Target, error) {
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid URL %q: %w", s, err)
+		}
+		t := Target{Host: u.Hostname(), Scheme: u.Scheme, Port: 443}
+		if u.Scheme == "http" {
+			t.Port = 80
+		}
+		if u.Port() != "" {
+			t.Port, _ = strconv.Atoi(u.Port())
+		}
+		return t, nil
+	}
+
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return Target{Host: strings.Trim(s, "[]"), Port: 443}, nil
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n <= 0 || n > 65535 {
+		return Target{}, fmt.Errorf("invalid port %q", port)
+	}
+	return Target{Host: host, Port: n}, nil
+}
+
+// TLSEnabled reports whether the target is expected to speak TLS
+func (t Target) TLSEnabled() bool {
+	return t.Scheme == "https" || (t.Scheme == "" && t.Port == 443)
+}
+
+// Check is the outcome of one diagnostic step
+type Check struct {
+	Name     string        `json:"name"` // dns, tcp, tls
+	OK       bool          `json:"ok"`
+	Detail   string        `json:"detail"`
+	Failure  string        `json:"failure,omitempty"` // One of the Fail* kinds
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Lookup is a DNS resolution result
+type Lookup struct {
+	Host      string   `json:"host"`
+	CNAME     string   `json:"cname,omitempty"`
+	Addresses []string `json:"addresses"`
+}
+
+// Ping is the result of repeated TCP connects, which unlike ICMP needs no
+// privileges and goes through the same firewalls as the real traffic
+type Ping struct {
+	Address  string          `json:"address"`
+	Sent     int             `json:"sent"`
+	Received int             `json:"received"`
+	RTTs     []time.Duration `json:"rtts_ns"`
+}
+
+// Loss returns the fraction of attempts that failed
+func (p *Ping) Loss() float64 {
+	if p.Sent == 0 {
+		return 0
+	}
+	return float64(p.Sent-p.Received) / float64(p.Sent)
+}
+
+// Average returns the mean round-trip time of successful attempts
+func (p *Ping) Average() time.Duration {
+	if len(p.RTTs) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, rtt := range p.RTTs {
+		total += rtt
+	}
+	return total / time.Duration(len(p.RTTs))
+}
+
+// Certificate summarizes a server's leaf certificate
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Version   string    `json:"tls_version"`
+}
+
+// Hop is one router on the path to a host
+type Hop struct {
+	TTL     int             `json:"ttl"`
+	Address string          `json:"address,omitempty"` // Empty when the hop did not answer
+	RTTs    []time.Duration `json:"rtts_ns,omitempty"`
+}
+
+// Report is the full diagnosis of a target
+type Report struct {
+	Target      Target       `json:"target"`
+	Checks      []Check      `json:"checks"`
+	Lookup      *Lookup      `json:"lookup,omitempty"`
+	Ping        *Ping        `json:"ping,omitempty"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+	Hops        []Hop        `json:"hops,omitempty"`
+}
+
+// OK reports whether every check passed
+func (r *Report) OK() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Diagnose checks DNS, then TCP reachability, then TLS, stopping at the
+// first layer that fails. With trace, a failed connection also runs a
+// traceroute to show where packets stop.
+func Diagnose(ctx context.Context, target Target, trace bool) *Report {
+	r := &Report{Target: target}
+
+	start := time.Now()
+	lookup, err := Resolve(ctx, target.Host)
+	check := Check{Name: "dns", OK: err == nil, Duration: time.Since(start)}
+	if err != nil {
+		check.Detail, check.Failure = err.Error(), classify(err)
+		r.Checks = append(r.Checks, check)
+		return r
+	}
+	check.Detail = strings.Join(lookup.Addresses, ", ")
+	if lookup.CNAME != "" {
+		check.Detail = lookup.CNAME + " → " + check.Detail
+	}
+	r.Lookup = lookup
+	r.Checks = append(r.Checks, check)
+
+	address := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+	ping, err := TCPPing(ctx, address, 3)
+	r.Ping = ping
+	check = Check{Name: "tcp", OK: ping.Received > 0, Duration: ping.Average()}
+	if check.OK {
+		check.Detail = fmt.Sprintf("%d/%d connects to port %d, avg %s", ping.Received, ping.Sent, target.Port, ping.Average().Round(100*time.Microsecond))
+	} else {
+		check.Detail, check.Failure = err.Error(), classify(err)
+	}
+	r.Checks = append(r.Checks, check)
+	if !check.OK {
+		if trace {
+			r.Hops, _ = Traceroute(ctx, target.Host, 20)
+		}
+		return r
+	}
+
+	if target.TLSEnabled() {
+		start = time.Now()
+		cert, err := CheckTLS(ctx, target.Host, target.Port)
+		check = Check{Name: "tls", OK: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			check.Detail, check.Failure = err.Error(), classify(err)
+		} else {
+			check.Detail = fmt.Sprintf("%s, issued by %s, expires %s (%d days)",
+				cert.Version, cert.Issuer, cert.NotAfter.Format("2006-01-02"), int(time.Until(cert.NotAfter).Hours()/24))
+		}
+		r.Certificate = cert
+		r.Checks = append(r.Checks, check)
+	}
+	return r
+}
+
+// Resolve looks up a host's addresses and canonical name
+func Resolve(ctx context.Context, host string) (*Lookup, error) {
+	l := &Lookup{Host: host}
+	if ip := net.ParseIP(host); ip != nil {
+		l.Addresses = []string{ip.String()}
+		return l, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	l.Addresses = addrs
+	if cname, err := net.DefaultResolver.LookupCNAME(ctx, host); err == nil && strings.TrimSuffix(cname, ".") != host {
+		l.CNAME = strings.TrimSuffix(cname, ".")
+	}
+	return l, nil
+}
+
+// TCPPing connects to address count times, returning the last error if
+// every attempt failed
+func TCPPing(ctx context.Context, address string, count int) (*Ping, error) {
+	p := &Ping{Address: address}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	var lastErr error
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			return p, ctx.Err()
+		}
+		p.Sent++
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		p.RTTs = append(p.RTTs, time.Since(start))
+		p.Received++
+		conn.Close()
+	}
+	if p.Received == 0 {
+		return p, lastErr
+	}
+	return p, nil
+}
+
+// CheckTLS performs a verified handshake and returns the leaf certificate.
+// On verification failure the certificate is still returned when available.
+func CheckTLS(ctx context.Context, host string, port int) (*Certificate, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout},
+		Config:    &tls.Config{ServerName: host},
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err == nil {
+		defer conn.Close()
+		state := conn.(*tls.Conn).ConnectionState()
+		return certificate(state.PeerCertificates[0], state.Version), nil
+	}
+
+	// Retry without verification to show what the server presented
+	insecure := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	if raw, rawErr := insecure.DialContext(ctx, "tcp", address); rawErr == nil {
+		defer raw.Close()
+		state := raw.(*tls.Conn).ConnectionState()
+		if len(state.PeerCertificates) > 0 {
+			return certificate(state.PeerCertificates[0], state.Version), err
+		}
+	}
+	return nil, err
+}
+
+// certificate summarizes a certificate
+func certificate(c *x509.Certificate, version uint16) *Certificate {
+	return &Certificate{
+		Subject:   c.Subject.CommonName,
+		Issuer:    c.Issuer.CommonName,
+		DNSNames:  c.DNSNames,
+		NotBefore: c.NotBefore,
+		NotAfter:  c.NotAfter,
+		Version:   tls.VersionName(version),
+	}
+}
+
+// Traceroute runs the platform's traceroute and parses its hops
+func Traceroute(ctx context.Context, host string, maxHops int) ([]Hop, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "tracert", "-d", "-h", strconv.Itoa(maxHops), "-w", "1000", host)
+	} else {
+		cmd = exec.CommandContext(ctx, "traceroute", "-n", "-m", strconv.Itoa(maxHops), "-w", "1", "-q", "1", host)
+	}
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("traceroute failed: %w", err)
+	}
+	return parseHops(string(out)), nil
+}
+
+// parseHops reads hops from traceroute or tracert output
+func parseHops(out string) []Hop {
+	var hops []Hop
+	for _, line := range strings.Split(out, "\n") {
+		m := hopLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		hop := Hop{}
+		hop.TTL, _ = strconv.Atoi(m[1])
+		// Drop RTTs first so "12.3 ms" is not mistaken for an address
+		rest := hopRTT.ReplaceAllStringFunc(m[2], func(rtt string) string {
+			if v, err := strconv.ParseFloat(hopRTT.FindStringSubmatch(rtt)[1], 64); err == nil {
+				hop.RTTs = append(hop.RTTs, time.Duration(v*float64(time.Millisecond)))
+			}
+			return ""
+		})
+		if addr := hopAddress.FindString(rest); addr != "" {
+			hop.Address = addr
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// classify maps a network error to a failure kind
+func classify(err error) string {
+	var dnsErr *net.DNSError
+	var certErr x509.CertificateInvalidError
+	var hostErr x509.HostnameError
+	var authErr x509.UnknownAuthorityError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return FailNoSuchHost
+	case errors.As(err, &dnsErr):
+		return FailDNS
+	case errors.As(err, &hostErr):
+		return FailHostname
+	case errors.As(err, &certErr) && certErr.Reason == x509.Expired:
+		return FailExpired
+	case errors.As(err, &authErr), errors.As(err, &certErr):
+		return FailUntrusted
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailRefused
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return FailUnreachable
+	case errors.Is(err, context.DeadlineExceeded), isTimeout(err):
+		return FailTimeout
+	case strings.Contains(err.Error(), "tls:"):
+		return FailTLS
+	default:
+		return FailOther
+	}
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"devos/internal/config"
+)
+
+// testProvider is an identity provider serving discovery and one P-256
+// signing key
+type testProvider struct {
+	*httptest.Server
+	key        *ecdsa.PrivateKey
+	keyFetches atomic.Int32
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 tp.URL,
+			"authorization_endpoint": tp.URL + "/authorize",
+			"token_endpoint":         tp.URL + "/token",
+			"jwks_uri":               tp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		tp.keyFetches.Add(1)
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": {{
+			Kty: "EC",
+			Kid: "key-1",
+			Use: "sig",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	tp.Server = httptest.NewServer(mux)
+	t.Cleanup(tp.Close)
+	return tp
+}
+
+// sign returns an ES256 ID token with the claims, signed by key
+func sign(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerify(t *testing.T) {
+	tp := newTestProvider(t)
+	p, err := Discover(context.Background(), config.OIDC{Issuer: tp.URL, ClientID: "devos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   tp.URL,
+			"aud":   "devos",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "n-1",
+			"email": "dev@example.com",
+		}
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := valid()
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", sign(t, tp.key, "key-1", valid()), true},
+		{"audience list", sign(t, tp.key, "key-1", with("aud", []string{"other", "devos"})), true},
+		{"within clock skew", sign(t, tp.key, "key-1", with("exp", time.Now().Add(-clockSkew/2).Unix())), true},
+		{"expired", sign(t, tp.key, "key-1", with("exp", time.Now().Add(-time.Hour).Unix())), false},
+		{"other issuer", sign(t, tp.key, "key-1", with("iss", "https://evil.example.com")), false},
+		{"other audience", sign(t, tp.key, "key-1", with("aud", "someone-else")), false},
+		{"other nonce", sign(t, tp.key, "key-1", with("nonce", "n-2")), false},
+		{"other key", sign(t, other, "key-1", valid()), false},
+		{"unsigned", "eyJhbGciOiJub25lIiwia2lkIjoia2V5LTEifQ.e30.", false},
+		{"not a JWT", "abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.Verify(context.Background(), tt.token, "n-1")
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if id, err := p.Identity(claims); err != nil || id != "dev@example.com" {
+					t.Errorf("Identity = %q, %v", id, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestUnknownKeyRefetch(t *testing.T) {
+	tp := newTestProvider(t)
+	p, err := Discover(context.Background(), config.OIDC{Issuer: tp.URL, ClientID: "devos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{"iss": tp.URL, "aud": "devos", "exp": time.Now().Add(time.Hour).Unix(), "nonce": "n"}
+
+	// Made-up key IDs fetch the key set once per keysRefresh, not per token
+	for i := 0; i < 5; i++ {
+		if _, err := p.Verify(context.Background(), sign(t, tp.key, "made-up", claims), "n"); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("Verify with an unknown key ID = %v, want ErrInvalidToken", err)
+		}
+	}
+	if n := tp.keyFetches.Load(); n != 1 {
+		t.Errorf("key set fetched %d times, want 1", n)
+	}
+
+	// Known keys need no fetch; after the interval, an unknown key ID
+	// fetches the key set again, as when the provider has rotated keys
+	if _, err := p.Verify(context.Background(), sign(t, tp.key, "key-1", claims), "n"); err != nil {
+		t.Errorf("Verify with a known key: %v", err)
+	}
+	p.keysFetched = time.Now().Add(-keysRefresh)
+	p.Verify(context.Background(), sign(t, tp.key, "made-up", claims), "n")
+	if n := tp.keyFetches.Load(); n != 2 {
+		t.Errorf("key set fetched %d times, want 2", n)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name   string
+		claim  string
+		claims map[string]interface{}
+		want   string
+	}{
+		{"email", "", map[string]interface{}{"email": "dev@example.com"}, "dev@example.com"},
+		{"verified email", "", map[string]interface{}{"email": "dev@example.com", "email_verified": true}, "dev@example.com"},
+		{"unverified email", "", map[string]interface{}{"email": "dev@example.com", "email_verified": false}, ""},
+		{"missing claim", "", map[string]interface{}{"sub": "123"}, ""},
+		{"configured claim", "preferred_username", map[string]interface{}{"preferred_username": "dev"}, "dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{config: config.OIDC{Claim: tt.claim}}
+			got, err := p.Identity(tt.claims)
+			if (err == nil) != (tt.want != "") || got != tt.want {
+				t.Errorf("Identity(%v) = %q, %v, want %q", tt.claims, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultURL is where a local Ollama server listens
+const DefaultURL = "http://localhost:11434"
+
+// notFound matches Ollama's error for a model that has not been pulled, e.g.
+// `model "llama3.2" not found, try pulling it first`
+var notFound = regexp.MustCompile(`model ['"]?[^'"\s]+['"]? not found`)
+
+// IsModelNotFound reports whether an error message says the model is not pulled
+func IsModelNotFound(message string) bool {
+	return notFound.MatchString(message)
+}
+
+// Model is a locally available model
+type Model struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// Progress is one status update while pulling a model
+type Progress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Client talks to an Ollama server's management API
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a client for the server at baseURL (DefaultURL if empty)
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+}
+
+// Models lists the pulled models, most recently modified first
+func (c *Client) Models(ctx context.Context) ([]Model, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama is not reachable at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama returned %s", resp.Status)
+	}
+
+	var tags struct {
+		Models []Model `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to parse Ollama model list: %w", err)
+	}
+	sort.Slice(tags.Models, func(i, j int) bool { return tags.Models[i].ModifiedAt.After(tags.Models[j].ModifiedAt) })
+	return tags.Models, nil
+}
+
+// Find returns the pulled model matching name; a name without a tag
+// matches ":latest"
+func Find(models []Model, name string) *Model {
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	for i, m := range models {
+		if m.Name == name {
+			return &models[i]
+		}
+	}
+	return nil
+}
+
+// Fallback picks a pulled model to use instead of name: another tag of the
+// same model if there is one, otherwise the most recently used model
+func Fallback(models []Model, name string) string {
+	family, _, _ := strings.Cut(name, ":")
+	for _, m := range models {
+		if f, _, _ := strings.Cut(m.Name, ":"); f == family {
+			return m.Name
+		}
+	}
+	if len(models) > 0 {
+		return models[0].Name
+	}
+	return ""
+}
+
+// Pull downloads a model, reporting progress as layers arrive
+func (c *Client) Pull(ctx context.Context, name string, progress func(Progress)) error {
+	body, _ := json.Marshal(map[string]interface{}{"model": name, "stream": true})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("Ollama is not reachable at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var p Progress
+		if json.Unmarshal(scanner.Bytes(), &p) != nil {
+			continue
+		}
+		if p.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", name, p.Error)
+		}
+		if progress != nil {
+			progress(p)
+		}
+		if p.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to pull %s: Ollama returned %s", name, resp.Status)
+	}
+	return fmt.Errorf("failed to pull %s: download ended early", name)
+}
+
+// ChatMessage is one turn of a chat
+type ChatMessage struct {
+	Role    string `json:"role"` // system, user, or assistant
+	Content string `json:"content"`
+}
+
+// ChatRequest asks a model for the next assistant message
+type ChatRequest struct {
+	Model         string
+	Messages      []ChatMessage
+	Temperature   float64
+	MaxTokens     int  // 0 leaves the model's default
+	ContextLength int  // 0 leaves the model's default
+	JSON          bool // Constrain the reply to JSON
+}
+
+// ChatResponse is the assistant's reply
+type ChatResponse struct {
+	Content      string
+	DoneReason   string // stop, length, ...
+	InputTokens  int
+	OutputTokens int
+}
+
+// Error is an error the server returned, such as a model that is not pulled
+type Error struct {
+	Status  int
+	Message string
+}
+
+// HTTPStatus returns the response's status code, for retry decisions
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Ollama returned %d: %s", e.Status, e.Message)
+}
+
+// Chat sends a conversation to a model and waits for the whole reply
+func (c *Client) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+	return c.ChatStream(ctx, request, nil)
+}
+
+// ChatStream sends a conversation to a model, passing the reply's text to
+// onText as it is generated, and returns the whole reply. A nil onText
+// asks for the reply in one piece.
+func (c *Client) ChatStream(ctx context.Context, request ChatRequest, onText func(text string)) (*ChatResponse, error) {
+	options := map[string]interface{}{"temperature": request.Temperature}
+	if request.MaxTokens > 0 {
+		options["num_predict"] = request.MaxTokens
+	}
+	if request.ContextLength > 0 {
+		options["num_ctx"] = request.ContextLength
+	}
+	payload := map[string]interface{}{
+		"model":    request.Model,
+		"messages": request.Messages,
+		"stream":   onText != nil,
+		"options":  options,
+	}
+	if request.JSON {
+		payload["format"] = "json"
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama is not reachable at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	// A streamed reply is a JSON object per chunk, the last one marked done
+	var content strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var reply struct {
+			Message         ChatMessage `json:"message"`
+			Done            bool        `json:"done"`
+			DoneReason      string      `json:"done_reason"`
+			PromptEvalCount int         `json:"prompt_eval_count"`
+			EvalCount       int         `json:"eval_count"`
+			Error           string      `json:"error"`
+		}
+		if err := decoder.Decode(&reply); err != nil && resp.StatusCode == http.StatusOK {
+			if err == io.EOF {
+				return nil, fmt.Errorf("Ollama's reply ended early")
+			}
+			return nil, fmt.Errorf("failed to parse Ollama response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK || reply.Error != "" {
+			if reply.Error == "" {
+				reply.Error = resp.Status
+			}
+			return nil, &Error{Status: resp.StatusCode, Message: reply.Error}
+		}
+
+		content.WriteString(reply.Message.Content)
+		if onText != nil && reply.Message.Content != "" {
+			onText(reply.Message.Content)
+		}
+		if reply.Done || onText == nil {
+			return &ChatResponse{
+				Content:      content.String(),
+				DoneReason:   reply.DoneReason,
+				InputTokens:  reply.PromptEvalCount,
+				OutputTokens: reply.EvalCount,
+			}, nil
+		}
+	}
+}
+
+// Delete removes a pulled model
+func (c *Client) Delete(ctx context.Context, name string) error {
+	body, _ := json.Marshal(map[string]string{"model": name})
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.baseURL+"/api/delete", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("Ollama is not reachable at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("model not found: %s", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to remove %s: Ollama returned %s", name, resp.Status)
+	}
+	return nil
+}
+
+// Bar renders a pull's progress as a fixed-width bar with byte counts, or
+// "" for steps without a size
+func Bar(p Progress, width int) string {
+	if p.Total <= 0 {
+		return ""
+	}
+	done := int(int64(width) * p.Completed / p.Total)
+	if done > width {
+		done = width
+	}
+	return fmt.Sprintf("[%s%s] %3d%% %s/%s", strings.Repeat("█", done), strings.Repeat("░", width-done),
+		100*p.Completed/p.Total, FormatSize(p.Completed), FormatSize(p.Total))
+}
+
+// FormatSize renders a byte count as e.g. "4.7 GB"
+func FormatSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
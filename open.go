@@ -0,0 +1,74 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// urlScheme matches targets that are URLs rather than paths
+var urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://|^mailto:`)
+
+// Open shows a URL, file, or folder in the user's default application
+func Open(target string) error {
+	if !urlScheme.MatchString(target) {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("cannot open %s: %w", target, err)
+		}
+		target = abs
+	}
+
+	name, args, err := opener()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(name, append(args, target)...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// opener returns the command that hands a target to the desktop
+func opener() (string, []string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "open", nil, nil
+	case "windows":
+		return "rundll32", []string{"url.dll,FileProtocolHandler"}, nil
+	}
+
+	if isWSL() {
+		if _, err := exec.LookPath("wslview"); err == nil {
+			return "wslview", nil, nil
+		}
+		return "cmd.exe", []string{"/c", "start", ""}, nil
+	}
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return "", nil, fmt.Errorf("no graphical display available (DISPLAY and WAYLAND_DISPLAY are unset)")
+	}
+	for _, name := range []string{"xdg-open", "gio", "sensible-browser"} {
+		if _, err := exec.LookPath(name); err == nil {
+			if name == "gio" {
+				return name, []string{"open"}, nil
+			}
+			return name, nil, nil
+		}
+	}
+	return "", nil, fmt.Errorf("no opener found (install xdg-utils)")
+}
+
+// isWSL reports whether Linux is running under Windows Subsystem for Linux
+func isWSL() bool {
+	data, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(data)), "microsoft")
+}
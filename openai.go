@@ -0,0 +1,254 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultURL is OpenAI's API; OpenAI-compatible servers such as LM Studio,
+// vLLM, OpenRouter, and llama.cpp's server serve the same paths elsewhere
+const DefaultURL = "https://api.openai.com/v1"
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string `json:"role"` // system, user, or assistant
+	Content string `json:"content"`
+}
+
+// Request asks for the next assistant message
+type Request struct {
+	Model       string // The deployment, for Azure OpenAI
+	Messages    []Message
+	MaxTokens   int
+	Temperature float64
+	JSON        bool // Ask for a JSON object as the reply
+}
+
+// Response is the assistant's reply
+type Response struct {
+	Content      string
+	FinishReason string // stop, length, or content_filter
+	InputTokens  int
+	OutputTokens int
+}
+
+// Error is an error the API returned
+type Error struct {
+	Service string // Who answered, for messages
+	Status  int
+	Code    string // e.g. model_not_found, DeploymentNotFound, rate_limit_exceeded
+	Message string
+}
+
+// HTTPStatus returns the response's status code, for retry decisions
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error %d (%s): %s", e.Service, e.Status, e.Code, e.Message)
+}
+
+// Client calls a chat completions API
+type Client struct {
+	service string
+	baseURL string
+	// url returns where to send a request for a model
+	url func(model string) (string, error)
+	// auth sets the credentials on a request
+	auth func(req *http.Request)
+	// deployments means the model is chosen by the URL, not the body
+	deployments bool
+	http        *http.Client
+}
+
+// New returns a client for the OpenAI-compatible API at baseURL
+// (DefaultURL if empty), sending apiKey as a bearer token unless it is
+// empty, as local servers often need no key
+func New(apiKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	c := &Client{service: "OpenAI-compatible API", baseURL: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+	c.url = func(string) (string, error) {
+		return c.baseURL + "/chat/completions", nil
+	}
+	c.auth = func(req *http.Request) {
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+	return c
+}
+
+// chatRequest is the request body
+type chatRequest struct {
+	Model          string            `json:"model,omitempty"`
+	Messages       []Message         `json:"messages"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+	Stream         bool              `json:"stream,omitempty"`
+	StreamOptions  map[string]bool   `json:"stream_options,omitempty"`
+}
+
+// chatResponse is a completion, or one chunk of a streamed completion
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (r *chatResponse) addTo(response *Response) {
+	if r.Usage != nil {
+		response.InputTokens = r.Usage.PromptTokens
+		response.OutputTokens = r.Usage.CompletionTokens
+	}
+	if len(r.Choices) > 0 && r.Choices[0].FinishReason != "" {
+		response.FinishReason = r.Choices[0].FinishReason
+	}
+}
+
+func (c *Client) payload(request Request, stream bool) chatRequest {
+	body := chatRequest{
+		Model:       request.Model,
+		Messages:    request.Messages,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		Stream:      stream,
+	}
+	if request.JSON {
+		body.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+	if c.deployments {
+		body.Model = ""
+	}
+	if stream {
+		// Usage is only reported in a final chunk, and only when asked for
+		body.StreamOptions = map[string]bool{"include_usage": true}
+	}
+	return body
+}
+
+// Chat sends a conversation and returns the reply
+func (c *Client) Chat(ctx context.Context, request Request) (*Response, error) {
+	resp, err := c.send(ctx, request.Model, c.payload(request, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.service, err)
+	}
+	var reply chatResponse
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", c.service, err)
+	}
+	response := &Response{}
+	reply.addTo(response)
+	if len(reply.Choices) > 0 {
+		response.Content = reply.Choices[0].Message.Content
+	}
+	return response, nil
+}
+
+// ChatStream sends a conversation, passing the reply's text to onText as it
+// is generated, and returns the whole reply
+func (c *Client) ChatStream(ctx context.Context, request Request, onText func(text string)) (*Response, error) {
+	resp, err := c.send(ctx, request.Model, c.payload(request, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Server-sent events, each a JSON chunk on a "data:" line, ending with
+	// "data: [DONE]"
+	response := &Response{}
+	var text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			response.Content = text.String()
+			return response, nil
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse %s event: %w", c.service, err)
+		}
+		chunk.addTo(response)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+			onText(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.service, err)
+	}
+	return nil, fmt.Errorf("the %s's reply ended early", c.service)
+}
+
+// send posts body to the chat completions endpoint for model, returning the
+// response when it succeeds; the caller closes its body
+func (c *Client) send(ctx context.Context, model string, body chatRequest) (*http.Response, error) {
+	endpoint, err := c.url(model)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.auth(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not reachable at %s: %w", c.service, c.baseURL, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.service, err)
+	}
+	var failure struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &failure) != nil || failure.Error.Message == "" {
+		failure.Error.Code, failure.Error.Message = "unknown", strings.TrimSpace(string(data))
+	}
+	return nil, &Error{Service: c.service, Status: resp.StatusCode, Code: failure.Error.Code, Message: failure.Error.Message}
+}
@@ -0,0 +1,485 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"devos/internal/configfmt"
+)
+
+// ErrNotFound is returned for an API that is not registered
+var ErrNotFound = errors.New("API not registered")
+
+// maxSpecSize caps specs fetched from a URL
+const maxSpecSize = 20 << 20
+
+// validName is what registered API names may look like
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// methods are the operations a path item can have, in display order
+var methods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+// Spec is the part of an OpenAPI (or Swagger 2) document the assistant
+// needs to call the API
+type Spec struct {
+	Name       string      `json:"name"`
+	Title      string      `json:"title"`
+	Version    string      `json:"version"`
+	Servers    []string    `json:"servers,omitempty"`
+	Auth       []string    `json:"auth,omitempty"` // Security schemes, e.g. "bearerAuth: http bearer"
+	Operations []Operation `json:"operations"`
+}
+
+// Operation is one method on one path
+type Operation struct {
+	Method  string   `json:"method"`
+	Path    string   `json:"path"`
+	ID      string   `json:"id,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Params  []Param  `json:"params,omitempty"`
+	Body    string   `json:"body,omitempty"` // Content type and schema of the request body
+}
+
+// Param is an operation parameter
+type Param struct {
+	Name     string `json:"name"`
+	In       string `json:"in"` // path, query, header, or cookie
+	Required bool   `json:"required,omitempty"`
+	Type     string `json:"type,omitempty"`
+}
+
+// String describes an operation on one line
+func (o Operation) String() string {
+	s := strings.ToUpper(o.Method) + " " + o.Path
+	if o.ID != "" {
+		s += " (" + o.ID + ")"
+	}
+	if o.Summary != "" {
+		s += " - " + o.Summary
+	}
+	var params []string
+	for _, p := range o.Params {
+		param := p.Name + " in " + p.In
+		if p.Type != "" {
+			param += " " + p.Type
+		}
+		if p.Required {
+			param += ", required"
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		s += "; params: " + strings.Join(params, "; ")
+	}
+	if o.Body != "" {
+		s += "; body: " + o.Body
+	}
+	return s
+}
+
+// Summary is a spec trimmed to what one request needs, for the AI engine
+type Summary struct {
+	Name       string   `json:"name"`
+	Title      string   `json:"title"`
+	Servers    []string `json:"servers,omitempty"`
+	Auth       []string `json:"auth,omitempty"`
+	Operations []string `json:"operations"`
+	Omitted    int      `json:"omitted,omitempty"` // Operations left out as less relevant
+}
+
+// Summarize returns the spec with at most limit operations, those sharing
+// the most words with query first
+func (s *Spec) Summarize(query string, limit int) Summary {
+	words := keywords(query)
+	type scored struct {
+		op    Operation
+		score int
+	}
+	ops := make([]scored, len(s.Operations))
+	for i, op := range s.Operations {
+		text := strings.ToLower(op.Path + " " + op.ID + " " + op.Summary + " " + strings.Join(op.Tags, " "))
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				ops[i].score++
+			}
+		}
+		ops[i].op = op
+	}
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].score > ops[j].score })
+
+	summary := Summary{Name: s.Name, Title: s.Title, Servers: s.Servers, Auth: s.Auth}
+	for i, op := range ops {
+		if limit > 0 && i == limit {
+			summary.Omitted = len(ops) - limit
+			break
+		}
+		summary.Operations = append(summary.Operations, op.op.String())
+	}
+	return summary
+}
+
+// Mentions reports whether a request is about this API: it names the API
+// or its title, or one of its operation IDs
+func (s *Spec) Mentions(input string) bool {
+	input = strings.ToLower(input)
+	if strings.Contains(input, s.Name) || (s.Title != "" && strings.Contains(input, strings.ToLower(s.Title))) {
+		return true
+	}
+	for _, op := range s.Operations {
+		if op.ID != "" && strings.Contains(input, strings.ToLower(op.ID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywords returns the lowercase words of a request worth matching
+func keywords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		if len(word) > 2 {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// Parse reads an OpenAPI 3 or Swagger 2 document in JSON or YAML
+func Parse(name string, data []byte, format string) (*Spec, error) {
+	data, err := configfmt.ToJSON(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	if doc["openapi"] == nil && doc["swagger"] == nil {
+		return nil, fmt.Errorf("not an OpenAPI document: no \"openapi\" or \"swagger\" version")
+	}
+
+	info := object(doc["info"])
+	spec := &Spec{Name: name, Title: text(info["title"]), Version: text(info["version"])}
+
+	// Where to send requests
+	for _, server := range list(doc["servers"]) {
+		if url := text(object(server)["url"]); url != "" {
+			spec.Servers = append(spec.Servers, url)
+		}
+	}
+	if host := text(doc["host"]); host != "" {
+		scheme := "https"
+		if schemes := list(doc["schemes"]); len(schemes) > 0 {
+			scheme = text(schemes[0])
+		}
+		spec.Servers = append(spec.Servers, scheme+"://"+host+text(doc["basePath"]))
+	}
+
+	// How to authenticate
+	schemes := object(object(doc["components"])["securitySchemes"])
+	if len(schemes) == 0 {
+		schemes = object(doc["securityDefinitions"])
+	}
+	for _, name := range sortedKeys(schemes) {
+		scheme := object(schemes[name])
+		auth := name + ": " + text(scheme["type"])
+		if s := text(scheme["scheme"]); s != "" {
+			auth += " " + s
+		}
+		if in := text(scheme["in"]); in != "" {
+			auth += " in " + in + " " + text(scheme["name"])
+		}
+		spec.Auth = append(spec.Auth, auth)
+	}
+
+	// What it can do
+	paths := object(doc["paths"])
+	for _, p := range sortedKeys(paths) {
+		item := object(paths[p])
+		shared := params(doc, list(item["parameters"]))
+		for _, method := range methods {
+			op := object(item[method])
+			if op == nil {
+				continue
+			}
+			operation := Operation{
+				Method:  method,
+				Path:    p,
+				ID:      text(op["operationId"]),
+				Summary: text(op["summary"]),
+				Params:  mergeParams(shared, params(doc, list(op["parameters"]))),
+			}
+			if operation.Summary == "" {
+				operation.Summary = firstLine(text(op["description"]))
+			}
+			for _, tag := range list(op["tags"]) {
+				operation.Tags = append(operation.Tags, text(tag))
+			}
+			operation.Body = requestBody(doc, op, &operation)
+			spec.Operations = append(spec.Operations, operation)
+		}
+	}
+	if len(spec.Operations) == 0 {
+		return nil, fmt.Errorf("the spec defines no operations")
+	}
+	return spec, nil
+}
+
+// params reads parameter objects, following $refs to shared parameters
+func params(doc map[string]interface{}, raw []interface{}) []Param {
+	var result []Param
+	for _, r := range raw {
+		p := resolve(doc, object(r))
+		typ := text(p["type"])
+		if typ == "" {
+			typ = schemaName(object(p["schema"]))
+		}
+		result = append(result, Param{
+			Name:     text(p["name"]),
+			In:       text(p["in"]),
+			Required: p["required"] == true,
+			Type:     typ,
+		})
+	}
+	return result
+}
+
+// mergeParams returns the path's parameters overridden by the operation's
+func mergeParams(shared, own []Param) []Param {
+	result := append([]Param(nil), own...)
+	for _, s := range shared {
+		overridden := false
+		for _, o := range own {
+			if o.Name == s.Name && o.In == s.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// requestBody describes an operation's body: OpenAPI 3's requestBody, or a
+// Swagger 2 "body" parameter, which is moved out of the parameter list
+func requestBody(doc, op map[string]interface{}, operation *Operation) string {
+	if body := resolve(doc, object(op["requestBody"])); body != nil {
+		content := object(body["content"])
+		for _, contentType := range sortedKeys(content) {
+			schema := object(object(content[contentType])["schema"])
+			return strings.TrimSpace(contentType + " " + schemaName(schema))
+		}
+	}
+	for i, p := range operation.Params {
+		if p.In == "body" {
+			operation.Params = append(operation.Params[:i], operation.Params[i+1:]...)
+			return strings.TrimSpace("application/json " + p.Type)
+		}
+	}
+	return ""
+}
+
+// resolve follows a local $ref such as #/components/parameters/limit
+func resolve(doc, value map[string]interface{}) map[string]interface{} {
+	ref := text(value["$ref"])
+	if !strings.HasPrefix(ref, "#/") {
+		return value
+	}
+	var node interface{} = doc
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		node = object(node)[part]
+	}
+	if resolved := object(node); resolved != nil {
+		return resolved
+	}
+	return value
+}
+
+// schemaName names a schema: its $ref's last part, or its type
+func schemaName(schema map[string]interface{}) string {
+	if ref := text(schema["$ref"]); ref != "" {
+		return path.Base(ref)
+	}
+	typ := text(schema["type"])
+	if typ == "array" {
+		if items := schemaName(object(schema["items"])); items != "" {
+			return items + "[]"
+		}
+	}
+	return typ
+}
+
+// object returns a JSON object, or nil
+func object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// list returns a JSON array, or nil
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+// text returns a JSON scalar as a string
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedKeys returns an object's keys in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// Dir is where registered specs are kept, under the config directory
+func Dir(configDir string) string {
+	return filepath.Join(configDir, "apis")
+}
+
+// Add registers the spec at source, a file or an http(s) URL, under name
+// (derived from the source when empty) and returns it
+func Add(ctx context.Context, dir, name, source string) (*Spec, error) {
+	data, err := read(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		base := path.Base(strings.SplitN(source, "?", 2)[0])
+		name = strings.ToLower(strings.TrimSuffix(base, path.Ext(base)))
+	}
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid API name %q: use lowercase letters, digits, '.', '_', and '-' (set one with --name)", name)
+	}
+
+	format := configfmt.FormatOf(source)
+	if format == configfmt.JSON && !strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		format = configfmt.YAML // Served without an extension
+	}
+	spec, err := Parse(name, data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Specs are stored as JSON so loading them needs no YAML parsing
+	stored, err := configfmt.ToJSON(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), stored, 0600); err != nil {
+		return nil, fmt.Errorf("failed to save spec: %w", err)
+	}
+	return spec, nil
+}
+
+// read returns a spec's contents from a file or URL
+func read(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", source, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSpecSize {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", source, maxSpecSize)
+	}
+	return data, nil
+}
+
+// Load returns the registered APIs, by name
+func Load(dir string) ([]*Spec, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var specs []*Spec
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		spec, err := Parse(strings.TrimSuffix(filepath.Base(p), ".json"), data, configfmt.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(p), err)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// Find returns the registered API called name
+func Find(dir, name string) (*Spec, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(name, data, configfmt.JSON)
+}
+
+// Remove unregisters an API
+func Remove(dir, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	err := os.Remove(filepath.Join(dir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return err
+}
@@ -0,0 +1,187 @@
+package pkgmgr
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Manager describes a system package manager
+type Manager struct {
+	Name    string
+	install string            // Install command prefix, e.g. "sudo apt-get install -y"
+	names   map[string]string // Binary → package name overrides for this manager
+	list    []string          // Lists installed packages, one "name version" per line
+
+	// Commands that undo package changes: remove is the uninstall prefix,
+	// restore the prefix that installs given versions (downgrading when
+	// needed), and version formats one package's name and version for it
+	remove  string
+	restore string
+	version string
+	single  bool // restore takes one package per command
+}
+
+// apkPackage splits apk's "name-version-rN" package identifiers
+var apkPackage = regexp.MustCompile(`^(.+)-(\d[^-]*-r\d+)$`)
+
+// rpmList lists packages on RPM-based distributions
+var rpmList = []string{"rpm", "-qa", "--qf", "%{NAME} %{VERSION}-%{RELEASE}\\n"}
+
+// managers are probed in order of preference per platform
+var managers = map[string][]Manager{
+	"linux": {
+		{Name: "apt-get", install: "sudo apt-get install -y", names: map[string]string{
+			"rg": "ripgrep", "fd": "fd-find", "node": "nodejs", "docker": "docker.io",
+			"pip3": "python3-pip", "dig": "dnsutils", "nslookup": "dnsutils",
+		}, list: []string{"dpkg-query", "-W", "-f=${Package} ${Version}\\n"},
+			remove: "sudo apt-get remove -y", restore: "sudo apt-get install -y --allow-downgrades", version: "%s=%s"},
+		{Name: "dnf", install: "sudo dnf install -y", names: map[string]string{
+			"rg": "ripgrep", "fd": "fd-find", "node": "nodejs", "dig": "bind-utils", "pip3": "python3-pip",
+		}, list: rpmList, remove: "sudo dnf remove -y", restore: "sudo dnf install -y", version: "%s-%s"},
+		{Name: "yum", install: "sudo yum install -y", names: map[string]string{
+			"node": "nodejs", "dig": "bind-utils", "pip3": "python3-pip",
+		}, list: rpmList, remove: "sudo yum remove -y", restore: "sudo yum install -y", version: "%s-%s"},
+		{Name: "pacman", install: "sudo pacman -S --noconfirm", names: map[string]string{
+			"rg": "ripgrep", "node": "nodejs", "dig": "bind", "pip3": "python-pip",
+		}, list: []string{"pacman", "-Q"},
+			// Only cached packages can be reinstalled at an earlier version
+			remove: "sudo pacman -R --noconfirm", restore: "sudo pacman -U --noconfirm", version: "/var/cache/pacman/pkg/%s-%s-*.pkg.tar.zst"},
+		{Name: "apk", install: "sudo apk add", names: map[string]string{
+			"rg": "ripgrep", "node": "nodejs", "dig": "bind-tools", "pip3": "py3-pip",
+		}, list: []string{"apk", "info", "-v"},
+			remove: "sudo apk del", restore: "sudo apk add", version: "%s=%s"},
+		{Name: "zypper", install: "sudo zypper install -y", names: map[string]string{
+			"rg": "ripgrep", "node": "nodejs", "dig": "bind-utils",
+		}, list: rpmList, remove: "sudo zypper remove -y", restore: "sudo zypper install -y --oldpackage", version: "%s=%s"},
+	},
+	"darwin": {
+		{Name: "brew", install: "brew install", names: map[string]string{
+			"rg": "ripgrep", "node": "node", "pip3": "python", "python3": "python", "dig": "bind",
+		}, list: []string{"brew", "list", "--versions"},
+			// Homebrew installs only the current version of a formula
+			remove: "brew uninstall", restore: "brew install", version: "%[1]s"},
+	},
+	"windows": {
+		{Name: "winget", install: "winget install --silent", names: map[string]string{
+			"rg": "BurntSushi.ripgrep.MSVC", "node": "OpenJS.NodeJS", "git": "Git.Git",
+			"python": "Python.Python.3", "python3": "Python.Python.3", "docker": "Docker.DockerDesktop",
+		}, remove: "winget uninstall --silent", restore: "winget install --silent", version: "%s --version %s", single: true},
+		{Name: "choco", install: "choco install -y", names: map[string]string{
+			"rg": "ripgrep", "node": "nodejs", "python3": "python",
+		}, list: []string{"choco", "list", "--limit-output"},
+			remove: "choco uninstall -y", restore: "choco install -y --allow-downgrade", version: "%s --version %s", single: true},
+	},
+}
+
+// Detect returns the first package manager available on this machine
+func Detect() *Manager {
+	for _, m := range managers[runtime.GOOS] {
+		if _, err := exec.LookPath(m.Name); err == nil {
+			m := m
+			return &m
+		}
+	}
+	return nil
+}
+
+// Available returns the names of all package managers found on PATH
+func Available() []string {
+	var found []string
+	for _, m := range managers[runtime.GOOS] {
+		if _, err := exec.LookPath(m.Name); err == nil {
+			found = append(found, m.Name)
+		}
+	}
+	return found
+}
+
+// PackageFor returns the package providing a binary
+func (m *Manager) PackageFor(binary string) string {
+	if pkg, ok := m.names[binary]; ok {
+		return pkg
+	}
+	return binary
+}
+
+// InstallCommand returns the command installing the packages for binaries
+func (m *Manager) InstallCommand(binaries ...string) string {
+	cmd := m.install
+	for _, binary := range binaries {
+		cmd += " " + m.PackageFor(binary)
+	}
+	return cmd
+}
+
+// Lookup returns the package manager with the given name on this platform,
+// whether or not it is installed
+func Lookup(name string) *Manager {
+	for _, m := range managers[runtime.GOOS] {
+		if m.Name == name {
+			m := m
+			return &m
+		}
+	}
+	return nil
+}
+
+// RemoveCommand returns the command uninstalling packages
+func (m *Manager) RemoveCommand(packages ...string) string {
+	return m.remove + " " + strings.Join(packages, " ")
+}
+
+// RestoreCommands returns the commands installing packages at the given
+// versions, upgrading or downgrading them as needed
+func (m *Manager) RestoreCommands(versions map[string]string) []string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var specs []string
+	for _, name := range names {
+		specs = append(specs, fmt.Sprintf(m.version, name, versions[name]))
+	}
+	if !m.single {
+		return []string{m.restore + " " + strings.Join(specs, " ")}
+	}
+	commands := make([]string, len(specs))
+	for i, spec := range specs {
+		commands[i] = m.restore + " " + spec
+	}
+	return commands
+}
+
+// Installed returns the installed packages and their versions
+func (m *Manager) Installed(ctx context.Context) (map[string]string, error) {
+	if len(m.list) == 0 {
+		return nil, fmt.Errorf("%s cannot list installed packages", m.Name)
+	}
+	out, err := exec.CommandContext(ctx, m.list[0], m.list[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(m.list, " "), err)
+	}
+
+	packages := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if m.Name == "choco" {
+			line = strings.Replace(line, "|", " ", 1)
+		}
+		if m.Name == "apk" {
+			if parts := apkPackage.FindStringSubmatch(line); parts != nil {
+				packages[parts[1]] = parts[2]
+			}
+			continue
+		}
+		if name, version, ok := strings.Cut(line, " "); ok {
+			packages[name] = strings.TrimSpace(version)
+		}
+	}
+	return packages, nil
+}
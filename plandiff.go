@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"strings"
+
+	"devos/internal/textdiff"
+)
+
+// Kinds of PlanChange
+const (
+	PlanAdded   = "added"
+	PlanRemoved = "removed"
+	PlanChanged = "changed"
+)
+
+// PlanChange is a command a regenerated plan added, removed, or changed
+type PlanChange struct {
+	Kind   string `json:"kind"`
+	Before string `json:"before,omitempty"` // Empty when added
+	After  string `json:"after,omitempty"`  // Empty when removed
+}
+
+// TryAgain asks the AI engine for another plan for input, showing it the
+// previous plan's commands ("previous_commands") so it can take a
+// different approach
+func (e *Executor) TryAgain(ctx context.Context, input string, previous *ExecutionResult) (*ExecutionResult, error) {
+	e.logger.Info("Re-planning: %s", input)
+	return e.generate(ctx, input, map[string]interface{}{"previous_commands": previous.Commands})
+}
+
+// DiffPlans returns how the commands of a regenerated plan differ from the
+// previous plan's, in plan order. A removed command followed by an added
+// one running the same program is a change to it.
+func DiffPlans(before, after []string) []PlanChange {
+	var changes []PlanChange
+	var removed, added []string
+	flush := func() {
+		for _, old := range removed {
+			change := PlanChange{Kind: PlanRemoved, Before: old}
+			for i, cmd := range added {
+				if program(cmd) == program(old) {
+					change = PlanChange{Kind: PlanChanged, Before: old, After: cmd}
+					added = append(added[:i], added[i+1:]...)
+					break
+				}
+			}
+			changes = append(changes, change)
+		}
+		for _, cmd := range added {
+			changes = append(changes, PlanChange{Kind: PlanAdded, After: cmd})
+		}
+		removed, added = nil, nil
+	}
+
+	if len(before) == 0 || len(after) == 0 {
+		removed, added = before, after
+		flush()
+		return changes
+	}
+	// Commands are diffed as lines, so their own line breaks are hidden
+	lines := func(commands []string) string {
+		escaped := make([]string, len(commands))
+		for i, cmd := range commands {
+			escaped[i] = strings.ReplaceAll(cmd, "\n", "\x00")
+		}
+		return strings.Join(escaped, "\n")
+	}
+	for _, op := range textdiff.Lines(lines(before), lines(after)) {
+		op.Text = strings.ReplaceAll(op.Text, "\x00", "\n")
+		switch op.Kind {
+		case '-':
+			removed = append(removed, op.Text)
+		case '+':
+			added = append(added, op.Text)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return changes
+}
+
+// program returns the first word of a command
+func program(cmd string) string {
+	if fields := strings.Fields(cmd); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"devos/internal/dag"
+	"devos/internal/policy"
+)
+
+// Patterns finding what a command creates for later steps to use
+var (
+	redirectTarget = regexp.MustCompile(`>>?\s*([^\s;&|<>]+)`)
+	outputFlag     = regexp.MustCompile(`(?:\s-o|--output|\s-t|--tag)[=\s]+['"]?([^\s'"]+)`)
+	teeTarget      = regexp.MustCompile(`\btee\s+(?:-a\s+)?([^\s;&|]+)`)
+	shellSegment   = regexp.MustCompile(`\s*(?:&&|\|\||;|\|)\s*`)
+)
+
+// Programs whose arguments name what they create, and programs whose last
+// argument does
+var (
+	creatingPrograms    = map[string]bool{"mkdir": true, "touch": true, "New-Item": true}
+	destinationPrograms = map[string]bool{"cp": true, "mv": true, "ln": true, "rsync": true, "scp": true}
+)
+
+// PlanGraph returns the plan's steps and their dependencies: those the AI
+// engine gave, or else ones inferred from the commands
+func PlanGraph(result *ExecutionResult) *dag.Graph {
+	if len(result.DependsOn) == len(result.Commands) {
+		return dag.New(result.Commands, result.DependsOn)
+	}
+	return dag.New(result.Commands, inferDependencies(result.Commands))
+}
+
+// inferDependencies guesses which earlier commands each command needs: one
+// that creates something it mentions, or one changing state with the same
+// tool, whose order must be kept
+func inferDependencies(commands []string) [][]int {
+	outputs := make([][]string, len(commands))
+	changes := make([]bool, len(commands))
+	programs := make([][]string, len(commands))
+	for i, cmd := range commands {
+		outputs[i] = commandOutputs(cmd)
+		changes[i] = !policy.IsReadOnly(cmd)
+		programs[i] = policy.Programs(cmd)
+	}
+
+	deps := make([][]int, len(commands))
+	for j, cmd := range commands {
+		words := commandWords(cmd)
+		for i := 0; i < j; i++ {
+			if usesAny(words, outputs[i]) || (changes[i] && changes[j] && sharesProgram(programs[i], programs[j])) {
+				deps[j] = append(deps[j], i)
+			}
+		}
+	}
+	return deps
+}
+
+// commandOutputs returns the files, directories, images, and packages a
+// command creates
+func commandOutputs(cmd string) []string {
+	var outputs []string
+	for _, re := range []*regexp.Regexp{redirectTarget, outputFlag, teeTarget} {
+		for _, m := range re.FindAllStringSubmatch(cmd, -1) {
+			if m[1] != "/dev/null" && !strings.HasPrefix(m[1], "&") {
+				outputs = append(outputs, m[1])
+			}
+		}
+	}
+
+	for _, segment := range shellSegment.Split(cmd, -1) {
+		fields := strings.Fields(segment)
+		for len(fields) > 0 && (fields[0] == "sudo" || strings.Contains(fields[0], "=")) {
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		args := nonFlags(fields[1:])
+		switch program := fields[0]; {
+		case creatingPrograms[program]:
+			outputs = append(outputs, args...)
+		case destinationPrograms[program] && len(args) > 1:
+			outputs = append(outputs, args[len(args)-1])
+		case program == "git" && len(args) > 1 && args[0] == "clone":
+			if len(args) > 2 {
+				outputs = append(outputs, args[2])
+			} else {
+				outputs = append(outputs, strings.TrimSuffix(path.Base(args[1]), ".git"))
+			}
+		case slices.Contains(policy.Classify(segment), policy.ClassPackage) && len(args) > 1:
+			// Installed packages provide programs later steps run
+			outputs = append(outputs, args[1:]...)
+		}
+	}
+
+	// An image tag is also used without its version
+	for _, out := range outputs {
+		if name, _, ok := strings.Cut(out, ":"); ok && name != "" && !strings.Contains(out, "/") {
+			outputs = append(outputs, name)
+		}
+	}
+	return outputs
+}
+
+// commandWords returns a command's words without quotes or shell punctuation
+func commandWords(cmd string) []string {
+	var words []string
+	for _, field := range strings.Fields(cmd) {
+		if word := strings.Trim(field, `"'();&|<>`); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// usesAny reports whether a command's words mention one of the outputs, or
+// a path inside one
+func usesAny(words, outputs []string) bool {
+	for _, out := range outputs {
+		out = strings.TrimSuffix(strings.TrimPrefix(out, "./"), "/")
+		if out == "" || out == "." {
+			continue
+		}
+		for _, word := range words {
+			word = strings.TrimPrefix(word, "./")
+			if word == out || strings.HasPrefix(word, out+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesProgram reports whether two commands run a program in common,
+// other than shell builtins such as cd
+func sharesProgram(a, b []string) bool {
+	for _, program := range a {
+		if !shellBuiltins[program] && slices.Contains(b, program) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonFlags returns the arguments that are not flags
+func nonFlags(args []string) []string {
+	var rest []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, strings.Trim(arg, `"'`))
+		}
+	}
+	return rest
+}
@@ -0,0 +1,157 @@
+package platform
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"devos/internal/pkgmgr"
+)
+
+// Info describes the machine commands will run on
+type Info struct {
+	OS              string    `json:"os"`
+	Distro          string    `json:"distro,omitempty"`
+	DistroVersion   string    `json:"distro_version,omitempty"`
+	DistroFamily    string    `json:"distro_family,omitempty"` // e.g. "debian", "rhel"
+	Kernel          string    `json:"kernel,omitempty"`
+	Arch            string    `json:"arch"`
+	NativeArch      string    `json:"native_arch,omitempty"` // Set when running under emulation
+	Shell           string    `json:"shell"`
+	PackageManagers []string  `json:"package_managers,omitempty"`
+	Container       bool      `json:"container"`
+	VM              bool      `json:"vm"`
+	Hardware        *Hardware `json:"hardware,omitempty"` // Memory and GPUs for local models
+}
+
+// Detect gathers platform information for the current machine
+func Detect() *Info {
+	info := &Info{
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		NativeArch:      detectEmulation(),
+		Shell:           detectShell(),
+		PackageManagers: pkgmgr.Available(),
+		Hardware:        DetectHardware(),
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		detectLinux(info)
+	case "darwin":
+		info.Distro = "macOS"
+		info.DistroVersion = commandOutput("sw_vers", "-productVersion")
+		info.Kernel = commandOutput("uname", "-r")
+		info.VM = strings.Contains(commandOutput("sysctl", "-n", "machdep.cpu.features"), "VMM")
+	case "windows":
+		info.Distro = "Windows"
+		info.DistroVersion = strings.TrimSpace(strings.TrimPrefix(commandOutput("cmd", "/c", "ver"), "Microsoft Windows"))
+	}
+
+	return info
+}
+
+// Summary returns a one-line description, e.g. "ubuntu 22.04 (arm64, bash, apt-get)"
+func (i *Info) Summary() string {
+	name := i.OS
+	if i.Distro != "" {
+		name = strings.TrimSpace(i.Distro + " " + i.DistroVersion)
+	}
+
+	arch := i.Arch
+	if i.NativeArch != "" {
+		arch += " on " + i.NativeArch
+	}
+
+	details := []string{arch, i.Shell}
+	details = append(details, i.PackageManagers...)
+	if i.Container {
+		details = append(details, "container")
+	}
+	if i.VM {
+		details = append(details, "vm")
+	}
+
+	return name + " (" + strings.Join(details, ", ") + ")"
+}
+
+// detectLinux fills in distribution, container, and VM details on Linux
+func detectLinux(info *Info) {
+	release := parseOSRelease("/etc/os-release")
+	info.Distro = release["ID"]
+	info.DistroVersion = release["VERSION_ID"]
+	info.DistroFamily = strings.Fields(release["ID_LIKE"] + " " + release["ID"])[0]
+	info.Kernel = commandOutput("uname", "-r")
+
+	info.Container = fileExists("/.dockerenv") ||
+		fileExists("/run/.containerenv") ||
+		os.Getenv("KUBERNETES_SERVICE_HOST") != "" ||
+		fileContainsAny("/proc/1/cgroup", "docker", "kubepods", "containerd", "lxc")
+
+	info.VM = fileContainsAny("/proc/cpuinfo", "hypervisor") ||
+		fileContainsAny("/sys/class/dmi/id/sys_vendor", "VMware", "QEMU", "innotek", "Xen", "Microsoft", "Amazon EC2", "Google")
+}
+
+// parseOSRelease reads KEY=value pairs from an os-release file
+func parseOSRelease(path string) map[string]string {
+	values := map[string]string{"ID": "linux"}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			values[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return values
+}
+
+// detectShell returns the user's interactive shell
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+	return "sh"
+}
+
+// commandOutput runs a command and returns its trimmed output, or ""
+func commandOutput(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// fileContainsAny reports whether the file at path contains any of the substrings
+func fileContainsAny(path string, substrings ...string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	content := string(data)
+	for _, s := range substrings {
+		if strings.Contains(content, s) {
+			return true
+		}
+	}
+	return false
+}
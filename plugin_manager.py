@@ -5,11 +5,17 @@ Provides dynamic plugin loading and execution
 
 import os
 import json
+import time
+import logging
+import threading
 import importlib.util
+from concurrent.futures import Future, TimeoutError as FutureTimeout
 from typing import Dict, List, Any, Optional
 from abc import ABC, abstractmethod
 from pathlib import Path
 
+logger = logging.getLogger("devos.plugins")
+
 
 class PluginInterface(ABC):
     """Base interface for DevOS plugins"""
@@ -82,16 +88,90 @@ class PluginManifest:
         self.dependencies = data.get('dependencies', [])
         self.config = data.get('config', {})
 
+        # Seconds a call may take: "timeout" for the plugin, and "timeouts"
+        # per command pattern (tool) it handles
+        self.timeout = data.get('timeout')
+        self.timeouts = data.get('timeouts', {})
+
+        # When to stop calling a failing plugin: {"failures": N, "cooldown": seconds}
+        self.circuit_breaker = data.get('circuit_breaker', {})
+
+
+class PluginTimeoutError(Exception):
+    """A plugin call did not finish in time"""
+
+
+class CircuitOpenError(Exception):
+    """A plugin is disabled after repeated failures"""
+
+
+class CircuitBreaker:
+    """
+    Stops calling a plugin after consecutive failures. After the cooldown one
+    trial call is let through: success closes the circuit again, failure
+    reopens it for another cooldown.
+    """
+
+    def __init__(self, name: str, failures: int = 3, cooldown: float = 60.0):
+        self.name = name
+        self.failures = failures
+        self.cooldown = cooldown
+        self.consecutive = 0
+        self.opened_at: Optional[float] = None
+        self._lock = threading.Lock()
+
+    @property
+    def state(self) -> str:
+        """closed, open, or half-open"""
+        if self.opened_at is None:
+            return 'closed'
+        if time.monotonic() - self.opened_at >= self.cooldown:
+            return 'half-open'
+        return 'open'
+
+    def check(self):
+        """Raise CircuitOpenError while the plugin is disabled"""
+        with self._lock:
+            if self.state == 'open':
+                remaining = self.cooldown - (time.monotonic() - self.opened_at)
+                raise CircuitOpenError(
+                    f"Plugin {self.name} is disabled after {self.consecutive} failures; "
+                    f"retrying in {remaining:.0f}s"
+                )
+
+    def record_success(self):
+        with self._lock:
+            if self.opened_at is not None:
+                logger.info("Plugin %s recovered; circuit closed", self.name)
+            self.consecutive = 0
+            self.opened_at = None
+
+    def record_failure(self, error: Exception):
+        with self._lock:
+            self.consecutive += 1
+            if self.opened_at is not None or self.consecutive >= self.failures:
+                self.opened_at = time.monotonic()
+                logger.warning(
+                    "Plugin %s disabled for %gs after %d consecutive failures (last: %s)",
+                    self.name, self.cooldown, self.consecutive, error,
+                )
+
 
 class PluginManager:
     """Manages plugin lifecycle"""
     
-    def __init__(self, plugin_dir: str = None):
+    def __init__(self, plugin_dir: str = None, timeout: float = 30.0,
+                 failures: int = 3, cooldown: float = 60.0, max_stuck: int = 2):
         """
         Initialize plugin manager
         
         Args:
             plugin_dir: Directory containing plugins
+            timeout: Default seconds a plugin call may take
+            failures: Default consecutive failures that disable a plugin
+            cooldown: Default seconds a disabled plugin stays disabled
+            max_stuck: Timed-out calls a plugin may have still running before
+                it gets no new ones
         """
         if plugin_dir is None:
             config_dir = self._get_config_dir()
@@ -100,6 +180,13 @@ class PluginManager:
         self.plugin_dir = plugin_dir
         self.plugins: Dict[str, PluginInterface] = {}
         self.manifests: Dict[str, PluginManifest] = {}
+        self.breakers: Dict[str, CircuitBreaker] = {}
+        self.timeout = timeout
+        self.failures = failures
+        self.cooldown = cooldown
+        self.max_stuck = max_stuck
+        self._stuck: Dict[str, int] = {}
+        self._stuck_lock = threading.Lock()
         
         # Ensure plugin directory exists
         os.makedirs(self.plugin_dir, exist_ok=True)
@@ -170,6 +257,12 @@ class PluginManager:
             
             # Store plugin
             self.plugins[plugin_name] = plugin_instance
+            breaker = manifest.circuit_breaker
+            self.breakers[plugin_name] = CircuitBreaker(
+                plugin_name,
+                failures=breaker.get('failures', self.failures),
+                cooldown=breaker.get('cooldown', self.cooldown),
+            )
             
             return True
             
@@ -194,6 +287,7 @@ class PluginManager:
             del self.plugins[plugin_name]
         if plugin_name in self.manifests:
             del self.manifests[plugin_name]
+        self.breakers.pop(plugin_name, None)
     
     def get_plugin(self, plugin_name: str) -> Optional[PluginInterface]:
         """
@@ -217,12 +311,85 @@ class PluginManager:
         
         Returns:
             Execution result
+
+        Raises:
+            PluginTimeoutError: The call took longer than the plugin's timeout
+            CircuitOpenError: The plugin is disabled after repeated failures
         """
         plugin = self.get_plugin(plugin_name)
         if not plugin:
             raise ValueError(f"Plugin not found: {plugin_name}")
-        
-        return plugin.execute(context)
+
+        breaker = self.breakers[plugin_name]
+        breaker.check()
+        timeout = self.call_timeout(plugin_name, context.get('command', ''))
+
+        with self._stuck_lock:
+            stuck = self._stuck.get(plugin_name, 0)
+        if stuck >= self.max_stuck:
+            raise PluginTimeoutError(
+                f"Plugin {plugin_name} has {stuck} timed-out calls still running; "
+                f"not starting another"
+            )
+
+        future = self._start_call(plugin_name, plugin, context)
+        try:
+            result = future.result(timeout=timeout)
+        except FutureTimeout:
+            error = PluginTimeoutError(f"Plugin {plugin_name} did not finish within {timeout:g}s")
+            self._mark_stuck(plugin_name, future)
+            breaker.record_failure(error)
+            raise error
+        except Exception as e:
+            breaker.record_failure(e)
+            raise
+
+        breaker.record_success()
+        return result
+
+    def _start_call(self, plugin_name: str, plugin: PluginInterface,
+                    context: Dict[str, Any]) -> Future:
+        """
+        Run a call in a thread of its own. Python threads cannot be killed, so
+        a call that times out keeps running; with a thread per call it holds
+        up no other call, and max_stuck bounds how many a plugin leaves behind.
+        """
+        future: Future = Future()
+
+        def run():
+            try:
+                future.set_result(plugin.execute(context))
+            except BaseException as e:
+                future.set_exception(e)
+            with self._stuck_lock:
+                if getattr(future, 'stuck', False):
+                    self._stuck[plugin_name] -= 1
+                    logger.info("Plugin %s finished a call that had timed out", plugin_name)
+
+        threading.Thread(target=run, name=f"plugin-{plugin_name}", daemon=True).start()
+        return future
+
+    def _mark_stuck(self, plugin_name: str, future: Future):
+        """Count a timed-out call until its thread finishes"""
+        with self._stuck_lock:
+            if future.done():
+                return
+            future.stuck = True
+            self._stuck[plugin_name] = self._stuck.get(plugin_name, 0) + 1
+
+    def call_timeout(self, plugin_name: str, command: str) -> float:
+        """
+        Seconds a call may take: the manifest's timeout for the first command
+        pattern (tool) matching the command, else the plugin's, else the default
+        """
+        manifest = self.manifests.get(plugin_name)
+        if manifest:
+            for pattern, seconds in manifest.timeouts.items():
+                if pattern.lower() in command.lower():
+                    return float(seconds)
+            if manifest.timeout:
+                return float(manifest.timeout)
+        return self.timeout
     
     def find_plugin_for_command(self, command: str) -> Optional[str]:
         """
@@ -251,12 +418,14 @@ class PluginManager:
         result = []
         for plugin_name, plugin in self.plugins.items():
             manifest = self.manifests.get(plugin_name)
+            breaker = self.breakers.get(plugin_name)
             result.append({
                 'name': plugin.name,
                 'version': plugin.version,
                 'description': plugin.description,
                 'author': manifest.author if manifest else 'Unknown',
-                'commands': plugin.get_commands()
+                'commands': plugin.get_commands(),
+                'circuit': breaker.state if breaker else 'closed'
             })
         
         return result
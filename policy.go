@@ -0,0 +1,514 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"devos/internal/config"
+)
+
+// Class is a category of side effect a command may have
+type Class string
+
+const (
+	ClassReadOnly    Class = "read-only"
+	ClassPackage     Class = "package"
+	ClassFileWrite   Class = "file-write"
+	ClassDestructive Class = "destructive"
+	ClassNetwork     Class = "network"
+	ClassPrivileged  Class = "privileged"
+	ClassProcess     Class = "process"
+	ClassUnknown     Class = "unknown"
+)
+
+// Action is what an approval rule decides for a matching command
+type Action string
+
+const (
+	ActionAllow Action = "allow"
+	ActionAsk   Action = "ask"
+	ActionDeny  Action = "deny"
+)
+
+// Decision is the outcome of evaluating the approval rules for one command
+type Decision struct {
+	Command string
+	Classes []Class
+	Action  Action
+	Rule    string // Name of the matching rule, empty for the default
+}
+
+// redirectTarget captures the target of output redirections
+var redirectTarget = regexp.MustCompile(`>{1,2}\s*([^&\s]\S*)`)
+
+// segmentSeparator splits compound shell commands into simple commands:
+// chained, piped, backgrounded, or on separate lines
+var segmentSeparator = regexp.MustCompile(`&&|\|\||[;&|\n]`)
+
+// redirectAmpersand matches the & of redirections such as 2>&1 and &>,
+// which does not separate commands
+var redirectAmpersand = regexp.MustCompile(`>&|&>`)
+
+// substitutions are shell constructs that run commands hidden inside
+// another command's arguments, so a command using them is never read-only
+var substitutions = []string{"$(", "`", "<(", ">("}
+
+// segments splits a command into the simple commands it runs
+func segments(cmd string) []string {
+	return segmentSeparator.Split(redirectAmpersand.ReplaceAllString(cmd, ">"), -1)
+}
+
+// readOnlyPrograms never modify the system, unless given one of their
+// writeFlags or more operands than operandLimit allows
+var readOnlyPrograms = map[string]bool{
+	"ls": true, "cat": true, "head": true, "tail": true,
+	"grep": true, "rg": true, "wc": true, "sort": true, "uniq": true, "diff": true,
+	"ps": true, "top": true, "df": true, "du": true, "free": true, "pwd": true,
+	"echo": true, "whoami": true, "id": true, "uname": true, "which": true,
+	"hostname": true, "date": true, "uptime": true, "stat": true, "file": true,
+	"vm_stat": true, "lsof": true, "netstat": true, "ss": true, "tree": true,
+	"get-childitem": true, "get-content": true, "get-process": true, "get-service": true,
+	"get-psdrive": true, "get-wmiobject": true, "get-location": true, "select-object": true,
+}
+
+// readOnlySubcommands lists subcommands of multi-purpose tools that only inspect state
+var readOnlySubcommands = map[string]map[string]bool{
+	"git":       {"status": true, "log": true, "diff": true, "show": true, "branch": true, "remote": true, "blame": true},
+	"kubectl":   {"get": true, "describe": true, "logs": true, "top": true, "version": true, "explain": true},
+	"docker":    {"ps": true, "images": true, "logs": true, "inspect": true, "version": true, "info": true, "stats": true},
+	"helm":      {"list": true, "status": true, "template": true, "history": true, "get": true},
+	"systemctl": {"status": true, "is-active": true, "list-units": true, "show": true},
+}
+
+// writeFlags are the flags with which an otherwise read-only program, or
+// "program subcommand", writes files, changes the system, or runs commands
+var writeFlags = map[string][]string{
+	"sort":          {"-o", "--output"},
+	"tree":          {"-o"},
+	"date":          {"-s", "--set"},
+	"find":          {"-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls"},
+	"git log":       {"--output"},
+	"git diff":      {"--output"},
+	"git show":      {"--output"},
+	"helm template": {"--output-dir"},
+}
+
+// operandLimit is how many operands a read-only program takes before the
+// next one is written: "uniq IN OUT" writes OUT, "hostname NAME" sets it
+var operandLimit = map[string]int{
+	"uniq":     1,
+	"hostname": 0,
+}
+
+// listingSubcommands are read-only subcommands that change things when
+// given arguments, as "git branch -D" and "git remote add" do
+var listingSubcommands = map[string]map[string]bool{
+	"git": {"branch": true, "remote": true},
+}
+
+// packageManagers maps package tools to the subcommands that change installed packages
+var packageManagers = map[string]map[string]bool{
+	"apt":     {"install": true, "remove": true, "purge": true, "upgrade": true, "autoremove": true},
+	"apt-get": {"install": true, "remove": true, "purge": true, "upgrade": true, "dist-upgrade": true},
+	"dnf":     {"install": true, "remove": true, "upgrade": true, "erase": true},
+	"yum":     {"install": true, "remove": true, "update": true, "erase": true},
+	"pacman":  {"-s": true, "-r": true, "-syu": true},
+	"apk":     {"add": true, "del": true, "upgrade": true},
+	"brew":    {"install": true, "uninstall": true, "upgrade": true, "remove": true},
+	"pip":     {"install": true, "uninstall": true},
+	"pip3":    {"install": true, "uninstall": true},
+	"npm":     {"install": true, "i": true, "uninstall": true, "remove": true, "update": true},
+	"yarn":    {"add": true, "remove": true, "install": true, "upgrade": true},
+	"pnpm":    {"add": true, "remove": true, "install": true, "update": true},
+	"cargo":   {"install": true, "uninstall": true, "add": true},
+	"go":      {"install": true, "get": true},
+	"gem":     {"install": true, "uninstall": true},
+	"snap":    {"install": true, "remove": true},
+	"choco":   {"install": true, "uninstall": true, "upgrade": true},
+	"winget":  {"install": true, "uninstall": true, "upgrade": true},
+}
+
+// destructivePrograms delete data or devices
+var destructivePrograms = map[string]bool{
+	"rm": true, "rmdir": true, "dd": true, "mkfs": true, "shred": true, "truncate": true,
+	"remove-item": true, "format": true, "fdisk": true, "wipefs": true,
+}
+
+// destructiveSubcommands lists subcommands that delete resources
+var destructiveSubcommands = map[string]map[string]bool{
+	"git":     {"reset": true, "clean": true, "push": true},
+	"kubectl": {"delete": true, "drain": true},
+	"docker":  {"rm": true, "rmi": true, "prune": true, "system": true},
+	"helm":    {"uninstall": true, "delete": true},
+}
+
+// networkPrograms reach out to remote hosts
+var networkPrograms = map[string]bool{
+	"curl": true, "wget": true, "ssh": true, "scp": true, "rsync": true, "ping": true,
+	"nc": true, "telnet": true, "ftp": true, "invoke-webrequest": true, "invoke-restmethod": true,
+}
+
+// processPrograms start, stop, or signal processes and services
+var processPrograms = map[string]bool{
+	"kill": true, "pkill": true, "killall": true, "systemctl": true, "service": true,
+	"launchctl": true, "stop-process": true, "start-process": true, "restart-service": true,
+}
+
+// privilegedPrograms escalate privileges
+var privilegedPrograms = map[string]bool{
+	"sudo": true, "su": true, "doas": true, "runas": true,
+}
+
+// Classify returns the side-effect classes of a shell command
+func Classify(cmd string) []Class {
+	seen := make(map[Class]bool)
+	var classes []Class
+	add := func(c Class) {
+		if !seen[c] {
+			seen[c] = true
+			classes = append(classes, c)
+		}
+	}
+
+	for _, m := range redirectTarget.FindAllStringSubmatch(cmd, -1) {
+		if target := strings.ToLower(m[1]); target != "/dev/null" && target != "$null" && target != "nul" {
+			add(ClassFileWrite)
+		}
+	}
+
+	readOnly := true
+	for _, construct := range substitutions {
+		if strings.Contains(cmd, construct) {
+			add(ClassUnknown)
+			readOnly = false
+			break
+		}
+	}
+	for _, segment := range segments(cmd) {
+		program, args := splitProgram(segment)
+		if program == "" {
+			continue
+		}
+
+		if privilegedPrograms[program] {
+			add(ClassPrivileged)
+			program, args = splitProgram(strings.Join(args, " "))
+		}
+
+		sub := ""
+		if len(args) > 0 {
+			sub = strings.ToLower(args[0])
+		}
+
+		switch {
+		case readOnlyPrograms[program] && !writes(program, args):
+			// read-only
+		case readOnlySubcommands[program][sub] && !(listingSubcommands[program][sub] && len(args) > 1) && !writes(program+" "+sub, args[1:]):
+			// read-only
+		case program == "find" && !writes(program, args):
+			// read-only
+		case packageManagers[program][sub]:
+			add(ClassPackage)
+			readOnly = false
+		case destructivePrograms[program], destructiveSubcommands[program][sub]:
+			add(ClassDestructive)
+			readOnly = false
+		case networkPrograms[program]:
+			add(ClassNetwork)
+			readOnly = false
+		case processPrograms[program]:
+			add(ClassProcess)
+			readOnly = false
+		default:
+			add(ClassUnknown)
+			readOnly = false
+		}
+	}
+
+	if readOnly && !seen[ClassFileWrite] && !seen[ClassPrivileged] {
+		return []Class{ClassReadOnly}
+	}
+
+	return classes
+}
+
+// writes reports whether a read-only program or subcommand writes when run
+// with args, by one of its writeFlags or by an operand past its limit
+func writes(program string, args []string) bool {
+	operands := 0
+	skip := false
+	for _, arg := range args {
+		if skip {
+			skip = false
+			continue
+		}
+		if strings.ContainsAny(arg, "<>") {
+			// A redirection, whose target Classify checks; "> FILE" takes the next word
+			skip = strings.TrimLeft(arg, "0123456789<>") == ""
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			operands++
+			continue
+		}
+		for _, flag := range writeFlags[program] {
+			if hasWriteFlag(arg, flag) {
+				return true
+			}
+		}
+	}
+	limit, limited := operandLimit[program]
+	return limited && operands > limit
+}
+
+// hasWriteFlag reports whether arg is flag: exactly, with an =value, or,
+// for a one-letter flag, among combined short flags such as -ro
+func hasWriteFlag(arg, flag string) bool {
+	if arg == flag || strings.HasPrefix(arg, flag+"=") {
+		return true
+	}
+	if len(flag) == 2 && !strings.HasPrefix(arg, "--") {
+		return strings.Contains(arg[1:], flag[1:])
+	}
+	return false
+}
+
+// IsReadOnly reports whether a command only inspects state
+func IsReadOnly(cmd string) bool {
+	classes := Classify(cmd)
+	return len(classes) == 1 && classes[0] == ClassReadOnly
+}
+
+// IsHighRisk reports whether a command is destructive or escalates privileges
+func IsHighRisk(cmd string) bool {
+	for _, c := range Classify(cmd) {
+		if c == ClassDestructive || c == ClassPrivileged {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the decision of the first rule matching the command.
+// When no rule matches, the default action is returned.
+func Evaluate(rules []config.ApprovalRule, cmd string, defaultAction Action) Decision {
+	classes := Classify(cmd)
+	decision := Decision{Command: cmd, Classes: classes, Action: defaultAction}
+
+	for _, rule := range rules {
+		if matches(rule, cmd, classes) {
+			decision.Action = Action(rule.Action)
+			decision.Rule = rule.Name
+			return decision
+		}
+	}
+
+	return decision
+}
+
+// SandboxExemption returns the name of the first allow rule that exempts the
+// command from the sandbox checks, or ""
+func SandboxExemption(rules []config.ApprovalRule, cmd string) string {
+	classes := Classify(cmd)
+	for _, rule := range rules {
+		if rule.Sandbox && Action(rule.Action) == ActionAllow && matches(rule, cmd, classes) {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// Strictest returns the most restrictive action among decisions
+func Strictest(decisions []Decision) Action {
+	action := ActionAllow
+	for _, d := range decisions {
+		switch {
+		case d.Action == ActionDeny:
+			return ActionDeny
+		case d.Action == ActionAsk:
+			action = ActionAsk
+		}
+	}
+	return action
+}
+
+// matches reports whether all of a rule's criteria apply to the command
+func matches(rule config.ApprovalRule, cmd string, classes []Class) bool {
+	if len(rule.Classes) == 0 && len(rule.Programs) == 0 && len(rule.Paths) == 0 {
+		return false
+	}
+
+	if len(rule.Classes) > 0 && !hasAnyClass(classes, rule.Classes) {
+		return false
+	}
+
+	if len(rule.Programs) > 0 && !usesProgram(cmd, rule.Programs) {
+		return false
+	}
+
+	if len(rule.Paths) > 0 && !touchesPath(cmd, rule.Paths) {
+		return false
+	}
+
+	if len(rule.Projects) > 0 && !inProject(rule.Projects) {
+		return false
+	}
+
+	return true
+}
+
+// inProject reports whether the working directory is inside one of the
+// project roots
+func inProject(roots []string) bool {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	cwd = filepath.ToSlash(filepath.Clean(cwd))
+	for _, root := range roots {
+		root = filepath.ToSlash(filepath.Clean(root))
+		if cwd == root || strings.HasPrefix(cwd, strings.TrimSuffix(root, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyClass reports whether classes contains one of the wanted class names
+func hasAnyClass(classes []Class, wanted []string) bool {
+	for _, c := range classes {
+		for _, w := range wanted {
+			if string(c) == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesProgram reports whether any segment of cmd invokes one of the programs
+func usesProgram(cmd string, programs []string) bool {
+	for _, segment := range segments(cmd) {
+		program, args := splitProgram(segment)
+		if privilegedPrograms[program] {
+			program, _ = splitProgram(strings.Join(args, " "))
+		}
+		for _, p := range programs {
+			if strings.EqualFold(program, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// touchesPath reports whether cmd references a path under one of the
+// prefixes. Paths compare as cleaned absolute paths, with ~ and $HOME
+// expanded and relative paths taken from the directory the command is in
+// by then, following its cd.
+func touchesPath(cmd string, prefixes []string) bool {
+	dir, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	home, _ := os.UserHomeDir()
+	under := func(path string) bool {
+		path = filepath.ToSlash(absolute(path, dir, home))
+		for _, prefix := range prefixes {
+			prefix = filepath.ToSlash(absolute(prefix, dir, home))
+			if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if under(dir) {
+		return true
+	}
+	for _, segment := range segments(cmd) {
+		fields := strings.Fields(segment)
+		for i, field := range fields {
+			if at := strings.LastIndexAny(field, "<>="); at >= 0 {
+				field = field[at+1:]
+			}
+			field = strings.Trim(field, `"'|&;()`)
+			if i == 0 || field == "" || strings.HasPrefix(field, "-") {
+				continue
+			}
+			if under(field) {
+				return true
+			}
+		}
+		if program, args := splitProgram(segment); program == "cd" {
+			target := "~"
+			if len(args) > 0 {
+				target = strings.Trim(args[len(args)-1], `"'`)
+			}
+			if dir = absolute(target, dir, home); under(dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// absolute returns path cleaned and made absolute: ~ and $HOME are the
+// home directory, and relative paths are taken from dir
+func absolute(path, dir, home string) string {
+	for _, h := range []string{"~", "$HOME", "${HOME}"} {
+		if path == h {
+			return home
+		}
+		if rest, ok := strings.CutPrefix(path, h+"/"); ok {
+			return filepath.Join(home, rest)
+		}
+		if rest, ok := strings.CutPrefix(path, h+`\`); ok {
+			return filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Programs returns the executables invoked by a shell command, in order,
+// looking through privilege escalation wrappers such as sudo
+func Programs(cmd string) []string {
+	var programs []string
+	for _, segment := range segments(cmd) {
+		fields := strings.Fields(segment)
+		for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+		for len(fields) > 0 && privilegedPrograms[strings.ToLower(fields[0])] {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		program := strings.Trim(fields[0], `"'()`)
+		if program != "" {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// splitProgram returns the lowercased program name and its arguments,
+// skipping leading environment variable assignments
+func splitProgram(segment string) (string, []string) {
+	fields := strings.Fields(segment)
+	for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "-") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	program := strings.ToLower(filepath.Base(strings.Trim(fields[0], `"'(`)))
+	return program, fields[1:]
+}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"devos/internal/config"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want []Class
+	}{
+		{"ls -la", []Class{ClassReadOnly}},
+		{"cat README.md | grep devos", []Class{ClassReadOnly}},
+		{"git status", []Class{ClassReadOnly}},
+		{"git log --oneline -5", []Class{ClassReadOnly}},
+		{"kubectl get pods 2>&1", []Class{ClassReadOnly}},
+		{"ls > /dev/null", []Class{ClassReadOnly}},
+		{"find . -name '*.go'", []Class{ClassReadOnly}},
+
+		// Read-only programs that write with some flags or operands
+		{"sort -o out.txt in.txt", []Class{ClassUnknown}},
+		{"sort --output=out.txt in.txt", []Class{ClassUnknown}},
+		{"uniq in.txt out.txt", []Class{ClassUnknown}},
+		{"hostname newname", []Class{ClassUnknown}},
+		{"date -s 2020-01-01", []Class{ClassUnknown}},
+		{"tree -o tree.txt", []Class{ClassUnknown}},
+		{"git log --output=log.txt", []Class{ClassUnknown}},
+		{"find . -delete", []Class{ClassUnknown}},
+		{"find . -fprint files.txt", []Class{ClassUnknown}},
+		{"find . -exec rm {} ;", []Class{ClassUnknown}},
+		{"less /var/log/syslog", []Class{ClassUnknown}},
+
+		{"ls > files.txt", []Class{ClassFileWrite}},
+		{"echo $(whoami)", []Class{ClassUnknown}},
+		{"sudo ls", []Class{ClassPrivileged}},
+		{"rm -rf build", []Class{ClassDestructive}},
+		{"npm install express", []Class{ClassPackage}},
+		{"curl https://example.com", []Class{ClassNetwork}},
+		{"kill 1234", []Class{ClassProcess}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			got := Classify(tt.cmd)
+			for _, want := range tt.want {
+				if !slices.Contains(got, want) {
+					t.Errorf("Classify(%q) = %v, want it to include %s", tt.cmd, got, want)
+				}
+			}
+			if readOnly := slices.Equal(tt.want, []Class{ClassReadOnly}); readOnly != IsReadOnly(tt.cmd) {
+				t.Errorf("IsReadOnly(%q) = %v, want %v", tt.cmd, !readOnly, readOnly)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := []config.ApprovalRule{
+		{Name: "no-destroy", Classes: []string{"destructive"}, Action: "deny"},
+		{Name: "ask-docker", Programs: []string{"docker"}, Action: "ask"},
+		{Name: "read-only", Classes: []string{"read-only"}, Action: "allow"},
+	}
+	tests := []struct {
+		cmd    string
+		action Action
+		rule   string
+	}{
+		{cmd: "ls", action: ActionAllow, rule: "read-only"},
+		{cmd: "rm -rf /tmp/x", action: ActionDeny, rule: "no-destroy"},
+		{cmd: "sudo docker ps", action: ActionAsk, rule: "ask-docker"},
+		{cmd: "make build", action: ActionAsk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			got := Evaluate(rules, tt.cmd, ActionAsk)
+			if got.Action != tt.action || got.Rule != tt.rule {
+				t.Errorf("Evaluate(%q) = %s by %q, want %s by %q", tt.cmd, got.Action, got.Rule, tt.action, tt.rule)
+			}
+		})
+	}
+
+	decisions := []Decision{{Action: ActionAllow}, {Action: ActionAsk}, {Action: ActionDeny}}
+	for i, want := range []Action{ActionAllow, ActionAsk, ActionDeny} {
+		if got := Strictest(decisions[:i+1]); got != want {
+			t.Errorf("Strictest(%v) = %s, want %s", decisions[:i+1], got, want)
+		}
+	}
+}
+
+func TestTouchesPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	work := t.TempDir()
+	chdir(t, work)
+
+	tests := []struct {
+		cmd      string
+		prefixes []string
+		want     bool
+	}{
+		{"cat /etc/shadow", []string{"/etc"}, true},
+		{"cat /etcetera/file", []string{"/etc"}, false},
+		{"cd / && cat etc/shadow", []string{"/etc"}, true},
+		{"cat ~/.ssh/id_ed25519", []string{"~/.ssh"}, true},
+		{"cat $HOME/.ssh/config", []string{filepath.Join(home, ".ssh")}, true},
+		{"cat ${HOME}/.aws/credentials", []string{"~/.aws"}, true},
+		{"cp notes.txt --target-directory=" + filepath.Join(home, ".ssh"), []string{"~/.ssh"}, true},
+		{"cat .env", []string{filepath.Join(work, ".env")}, true},
+		{"cat ../" + filepath.Base(work) + "/.env", []string{filepath.Join(work, ".env")}, true},
+		{"cat README.md", []string{"~/.ssh"}, false},
+		{"ls", []string{work}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			if got := touchesPath(tt.cmd, tt.prefixes); got != tt.want {
+				t.Errorf("touchesPath(%q, %v) = %v, want %v", tt.cmd, tt.prefixes, got, tt.want)
+			}
+		})
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	prev, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(prev) })
+}
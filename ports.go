@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// maxPortSearch bounds how far above a busy port a free one is looked for
+const maxPortSearch = 100
+
+// viteCommand matches commands that start Vite, which takes --port
+var viteCommand = regexp.MustCompile(`\bvite\b`)
+
+// ssUser matches the owning process in ss -p output: users:(("node",pid=1234,fd=20))
+var ssUser = regexp.MustCompile(`\(\("([^"]+)",pid=(\d+)`)
+
+// PortConflict is a plan step that would bind a port something else holds
+type PortConflict struct {
+	Step     int    // Index into the plan's commands
+	Port     int    // Port the step binds
+	PID      int    // Process holding it, 0 if unknown
+	Process  string // Name of that process
+	FreePort int    // Nearest free port above Port, 0 if none found
+}
+
+// Owner describes the process holding the port
+func (c PortConflict) Owner() string {
+	if c.PID == 0 {
+		return "another process"
+	}
+	return fmt.Sprintf("%s (PID %d)", c.Process, c.PID)
+}
+
+// PortConflicts returns the plan steps that start servers on ports already
+// in use, found before anything runs
+func (e *Executor) PortConflicts(ctx context.Context, result *ExecutionResult) []PortConflict {
+	var conflicts []PortConflict
+	claimed := map[int]bool{}
+	for i, cmd := range result.Commands {
+		port := serverPort(cmd)
+		if port == 0 || claimed[port] {
+			continue
+		}
+		claimed[port] = true
+		if portFree(port) {
+			continue
+		}
+
+		conflict := PortConflict{Step: i, Port: port}
+		conflict.PID, conflict.Process = portOwner(ctx, port)
+		for p := port + 1; p <= port+maxPortSearch && p < 65536; p++ {
+			if !claimed[p] && portFree(p) {
+				conflict.FreePort = p
+				break
+			}
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
+
+// UsePort rewrites a conflicting step to listen on port instead. Structured
+// steps are dropped so the rewritten commands are what runs.
+func (e *Executor) UsePort(result *ExecutionResult, conflict PortConflict, port int) {
+	result.Commands[conflict.Step] = rewritePort(result.Commands[conflict.Step], conflict.Port, port, e.config.OS)
+	result.Steps = nil
+}
+
+// StopPortOwner terminates the process holding a conflicting port
+func (e *Executor) StopPortOwner(conflict PortConflict) error {
+	if conflict.PID == 0 {
+		return fmt.Errorf("the process holding port %d is unknown", conflict.Port)
+	}
+	process, err := os.FindProcess(conflict.PID)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		err = process.Kill()
+	} else {
+		err = process.Signal(syscall.SIGTERM)
+	}
+	e.audit.Record("process_stopped", map[string]string{
+		"pid": strconv.Itoa(conflict.PID), "process": conflict.Process, "port": strconv.Itoa(conflict.Port), "ok": fmt.Sprint(err == nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stop %s: %w", conflict.Owner(), err)
+	}
+	return nil
+}
+
+// rewritePort changes the port a server command listens on, replacing an
+// explicit port or adding the framework's option for it
+func rewritePort(cmd string, from, to int, goos string) string {
+	if m := portFlag.FindStringSubmatchIndex(cmd); m != nil && cmd[m[2]:m[3]] == strconv.Itoa(from) {
+		return cmd[:m[2]] + strconv.Itoa(to) + cmd[m[3]:]
+	}
+
+	port := strconv.Itoa(to)
+	switch {
+	case strings.Contains(cmd, "manage.py runserver"):
+		return strings.Replace(cmd, "runserver", "runserver "+port, 1)
+	case strings.Contains(cmd, "http.server"):
+		return strings.Replace(cmd, "http.server", "http.server "+port, 1)
+	case strings.Contains(cmd, "gunicorn"):
+		return cmd + " --bind 0.0.0.0:" + port
+	case strings.Contains(cmd, "uvicorn"), strings.Contains(cmd, "flask run"),
+		strings.Contains(cmd, "rails s"), strings.Contains(cmd, "webpack serve"):
+		return cmd + " --port " + port
+	case viteCommand.MatchString(cmd):
+		if strings.HasPrefix(strings.TrimSpace(cmd), "vite") {
+			return cmd + " --port " + port
+		}
+		return cmd + " -- --port " + port
+	default:
+		// npm/yarn scripts (Next.js, Create React App, Express) read PORT
+		if goos == "windows" {
+			return "$env:PORT=" + port + "; " + cmd
+		}
+		return "PORT=" + port + " " + cmd
+	}
+}
+
+// portFree reports whether nothing is listening on port
+func portFree(port int) bool {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// portOwner finds the process listening on port with ss, lsof, or
+// Get-NetTCPConnection
+func portOwner(ctx context.Context, port int) (int, string) {
+	p := strconv.Itoa(port)
+	switch runtime.GOOS {
+	case "windows":
+		script := "$c = Get-NetTCPConnection -LocalPort " + p + " -State Listen -ErrorAction SilentlyContinue | Select-Object -First 1; " +
+			"if ($c) { $p = Get-Process -Id $c.OwningProcess; \"$($p.Id) $($p.ProcessName)\" }"
+		out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script).Output()
+		if err != nil {
+			return 0, ""
+		}
+		if fields := strings.Fields(string(out)); len(fields) == 2 {
+			pid, _ := strconv.Atoi(fields[0])
+			return pid, fields[1]
+		}
+		return 0, ""
+	case "linux":
+		if out, err := exec.CommandContext(ctx, "ss", "-ltnpH", "sport = :"+p).Output(); err == nil {
+			if m := ssUser.FindStringSubmatch(string(out)); m != nil {
+				pid, _ := strconv.Atoi(m[2])
+				return pid, m[1]
+			}
+		}
+	}
+
+	// lsof -F prints one field per line: p<pid>, c<command>
+	out, err := exec.CommandContext(ctx, "lsof", "-nP", "-iTCP:"+p, "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return 0, ""
+	}
+	pid, name := 0, ""
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == 0:
+			pid, _ = strconv.Atoi(line[1:])
+		case strings.HasPrefix(line, "c") && name == "":
+			name = line[1:]
+		}
+	}
+	return pid, name
+}
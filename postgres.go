@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresTypes translates the SQLite column types in schema
+var postgresTypes = strings.NewReplacer(
+	"INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY",
+	"TIMESTAMP", "TIMESTAMPTZ",
+)
+
+// OpenPostgres opens a shared memory database, e.g.
+// "postgres://devos@db.internal/devos?sslmode=verify-full". The password
+// may come from PGPASSWORD or ~/.pgpass instead of the DSN.
+func OpenPostgres(dsn string, maxSize int) (*Store, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("memory_dsn is required for the postgres memory backend")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to memory database: %w", err)
+	}
+
+	for _, stmt := range schema {
+		if _, err := db.Exec(postgresTypes.Replace(stmt)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize memory database: %w", err)
+		}
+	}
+
+	for _, col := range addedColumns {
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", col.table, col.name, postgresTypes.Replace(col.definition))
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate memory database: %w", err)
+		}
+	}
+
+	if maxSize <= 0 {
+		maxSize = 100
+	}
+
+	return &Store{db: db, backend: BackendPostgres, maxSize: maxSize}, nil
+}
@@ -0,0 +1,163 @@
+package postprocess
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Processor names
+const (
+	Auto      = "auto"       // Pretty-print JSON or tabulate CSV/TSV when the output is one
+	None      = "none"       // Show output as is
+	JSON      = "json"       // Indent JSON
+	Table     = "table"      // Align CSV or TSV into columns
+	StripANSI = "strip-ansi" // Remove colors and other terminal escapes
+)
+
+// maxCell bounds how wide a table column grows before cells are cut short
+const maxCell = 48
+
+// ansi matches CSI sequences (colors, cursor movement) and OSC sequences
+// (titles, hyperlinks)
+var ansi = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// Pipeline is a list of processors applied in order
+type Pipeline []string
+
+// Parse checks processor names, defaulting to auto-detection
+func Parse(names []string) (Pipeline, error) {
+	if len(names) == 0 {
+		return Pipeline{Auto}, nil
+	}
+	for _, name := range names {
+		switch name {
+		case Auto, None, JSON, Table, StripANSI:
+		default:
+			return nil, fmt.Errorf("unknown output processor %q (expected auto, none, json, table, or strip-ansi)", name)
+		}
+	}
+	return Pipeline(names), nil
+}
+
+// Apply runs output through the pipeline. A processor that does not fit the
+// output (json on text that is not JSON, say) leaves it unchanged.
+func (p Pipeline) Apply(output string) string {
+	for _, name := range p {
+		switch name {
+		case None:
+			return output
+		case StripANSI:
+			output = Strip(output)
+		case JSON:
+			if pretty, ok := prettyJSON(output); ok {
+				output = pretty
+			}
+		case Table:
+			if table, ok := tabulate(output); ok {
+				output = table
+			}
+		case Auto:
+			output = Detect(output).Apply(output)
+		}
+	}
+	return output
+}
+
+// Detect picks the processors for output from its content: JSON documents
+// are indented and CSV or TSV is aligned, with escapes stripped first
+func Detect(output string) Pipeline {
+	plain := Strip(output)
+	if _, ok := prettyJSON(plain); ok {
+		return Pipeline{StripANSI, JSON}
+	}
+	if _, ok := tabulate(plain); ok {
+		return Pipeline{StripANSI, Table}
+	}
+	return Pipeline{None}
+}
+
+// Strip removes terminal escape sequences
+func Strip(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansi.ReplaceAllString(s, "")
+}
+
+// prettyJSON indents a JSON object or array
+func prettyJSON(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return "", false
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(s), "", "  "); err != nil {
+		return "", false
+	}
+	return out.String(), true
+}
+
+// tabulate aligns CSV or TSV with a header row into columns. Output counts as
+// CSV/TSV when every row has the same number of fields, at least two.
+func tabulate(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	lines := strings.Split(s, "\n")
+	if len(lines) < 2 {
+		return "", false
+	}
+
+	r := csv.NewReader(strings.NewReader(s))
+	if strings.Contains(lines[0], "\t") {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	rows, err := r.ReadAll()
+	if err != nil || len(rows) < 2 || len(rows[0]) < 2 {
+		return "", false
+	}
+	// Headers are short labels; prose that happens to contain commas is not
+	for _, header := range rows[0] {
+		if header == "" || len(header) > maxCell || strings.HasPrefix(header, " ") {
+			return "", false
+		}
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := utf8.RuneCountInString(cell); w > widths[i] {
+				widths[i] = min(w, maxCell)
+			}
+		}
+	}
+
+	var b strings.Builder
+	for n, row := range rows {
+		for i, cell := range row {
+			if utf8.RuneCountInString(cell) > maxCell {
+				cell = string([]rune(cell)[:maxCell-1]) + "…"
+			}
+			if i == len(row)-1 {
+				b.WriteString(cell)
+			} else {
+				fmt.Fprintf(&b, "%-*s  ", widths[i], cell)
+			}
+		}
+		b.WriteString("\n")
+		if n == 0 {
+			for i, w := range widths {
+				if i > 0 {
+					b.WriteString("  ")
+				}
+				b.WriteString(strings.Repeat("─", w))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), true
+}
@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"devos/internal/config"
+	"devos/internal/postprocess"
+)
+
+// outputRule is a configured output rule with its pattern compiled
+type outputRule struct {
+	match    *regexp.Regexp
+	pipeline postprocess.Pipeline
+}
+
+// outputProcessors are the compiled output rules and the default pipeline
+type outputProcessors struct {
+	rules    []outputRule
+	fallback postprocess.Pipeline
+}
+
+// newOutputProcessors compiles the configured output processors and rules
+func newOutputProcessors(cfg *config.Config) (*outputProcessors, error) {
+	fallback, err := postprocess.Parse(cfg.OutputProcessors)
+	if err != nil {
+		return nil, fmt.Errorf("%w: output_processors: %w", config.ErrInvalidConfig, err)
+	}
+	p := &outputProcessors{fallback: fallback}
+	for _, rule := range cfg.OutputRules {
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("%w: output rule %q: %w", config.ErrInvalidConfig, rule.Match, err)
+		}
+		pipeline, err := postprocess.Parse(rule.Processors)
+		if err != nil {
+			return nil, fmt.Errorf("%w: output rule %q: %w", config.ErrInvalidConfig, rule.Match, err)
+		}
+		p.rules = append(p.rules, outputRule{match: match, pipeline: pipeline})
+	}
+	return p, nil
+}
+
+// process transforms a command's output for display
+func (p *outputProcessors) process(cmd, output string) string {
+	for _, rule := range p.rules {
+		if rule.match.MatchString(cmd) {
+			return rule.pipeline.Apply(output)
+		}
+	}
+	return p.fallback.Apply(output)
+}
+
+// showOutput prints a command's output after post-processing it. Output
+// that spans lines is indented below the label so tables stay aligned.
+func (e *Executor) showOutput(cmd, output string) {
+	output = e.output.process(cmd, output)
+	if !strings.Contains(output, "\n") {
+		fmt.Printf("  Output: %s\n", output)
+		return
+	}
+	fmt.Println("  Output:")
+	for _, line := range strings.Split(output, "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+}
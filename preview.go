@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// portFlag matches an explicit port in a server command: --port 8080,
+// -p 8080:80 (host side), PORT=8080, or a trailing host:port bind
+var portFlag = regexp.MustCompile(`(?:--port[= ]|-p |PORT=|--bind[= ](?:[\w.]+):|runserver (?:[\w.]+:)?|http\.server )(\d{2,5})\b`)
+
+// defaultPorts are where common dev servers listen when no port is given
+var defaultPorts = []struct {
+	pattern *regexp.Regexp
+	port    int
+}{
+	{regexp.MustCompile(`\bvite\b|\b(npm|yarn|pnpm|bun) (run )?dev\b.*vite`), 5173},
+	{regexp.MustCompile(`\b(npm|yarn|pnpm|bun) (run )?(dev|start)\b|\brails s(erver)?\b|\bnext (dev|start)\b`), 3000},
+	{regexp.MustCompile(`\b(uvicorn|manage\.py runserver|python3? -m http\.server|gunicorn)\b`), 8000},
+	{regexp.MustCompile(`\bflask run\b`), 5000},
+	{regexp.MustCompile(`\bwebpack serve\b`), 8080},
+}
+
+// serverCommand matches commands that start something a browser can show
+var serverCommand = regexp.MustCompile(`\b(npm|yarn|pnpm|bun) (run )?(dev|start|serve)\b|\b(vite|next (dev|start)|uvicorn|gunicorn|flask run|rails s(erver)?|webpack serve)\b|manage\.py runserver|http\.server|\bdocker run\b.*-p |\bdocker(-| )compose\b.*\bup\b`)
+
+// ServerURLs returns the local URLs the commands start dev servers on
+func ServerURLs(commands []string) []string {
+	var urls []string
+	seen := map[int]bool{}
+	for _, cmd := range commands {
+		port := serverPort(cmd)
+		if port > 0 && !seen[port] {
+			seen[port] = true
+			urls = append(urls, "http://localhost:"+strconv.Itoa(port))
+		}
+	}
+	return urls
+}
+
+// serverPort returns the port a server command listens on, or 0 if cmd
+// does not start a server
+func serverPort(cmd string) int {
+	if !serverCommand.MatchString(cmd) {
+		return 0
+	}
+	if m := portFlag.FindStringSubmatch(cmd); m != nil {
+		if port, _ := strconv.Atoi(m[1]); port < 65536 {
+			return port
+		}
+		return 0
+	}
+	for _, d := range defaultPorts {
+		if d.pattern.MatchString(cmd) {
+			return d.port
+		}
+	}
+	return 0
+}
+
+// WaitForServer polls a local URL's port until something accepts
+// connections or the timeout passes
+func WaitForServer(ctx context.Context, url string, timeout time.Duration) bool {
+	_, port, err := net.SplitHostPort(url[len("http://"):])
+	if err != nil {
+		return false
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", port), time.Second)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return false
+}
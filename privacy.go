@@ -0,0 +1,45 @@
+package privacy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Match returns the local-only path that covers dir, or "" if its context may
+// be sent to cloud providers. Paths may start with "~/" and may be globs such
+// as "~/clients/*", which cover everything inside the matching directories.
+func Match(paths []string, dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+	for _, p := range paths {
+		pattern := Expand(p)
+		if strings.ContainsAny(pattern, "*?[") {
+			for d := dir; ; d = filepath.Dir(d) {
+				if ok, _ := filepath.Match(pattern, d); ok {
+					return p
+				}
+				if d == filepath.Dir(d) {
+					break
+				}
+			}
+			continue
+		}
+		if dir == pattern || strings.HasPrefix(dir, pattern+string(filepath.Separator)) {
+			return p
+		}
+	}
+	return ""
+}
+
+// Expand resolves a leading "~/" and cleans a configured path
+func Expand(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return filepath.Clean(path)
+}
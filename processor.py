@@ -4,6 +4,7 @@ Handles natural language to command translation
 """
 
 import json
+import re
 import sys
 import os
 from typing import Dict, List, Any
@@ -17,6 +18,9 @@ class ExecutionResult:
     commands: List[str]
     needs_confirmation: bool
     error: str = ""
+    intent: str = ""
+    # Runbook to instantiate instead of commands: {"name": ..., "params": {...}}
+    runbook: Dict[str, Any] = None
 
 
 class AIProcessor:
@@ -27,6 +31,29 @@ class AIProcessor:
         self.provider = config.get('provider', 'ollama')
         self.model = config.get('model', 'llama3.2')
         self.os = config.get('os', 'linux')
+        # Shell the commands will run under; fish and nu need their own syntax
+        self.shell = config.get('shell', 'sh')
+        self.corrections = config.get('corrections') or []
+        # Plans the user rated for similar requests (good/bad in the REPL)
+        self.examples = config.get('examples') or []
+        # Repository layout detected by the Go side (monorepo tooling, members)
+        self.project = config.get('project') or {}
+        self.helm_charts = config.get('helm_charts') or []
+        # Summarized log excerpt to diagnose from (devos logs --diagnose)
+        self.log_excerpt = config.get('log_excerpt') or ''
+        # DevOS's own log excerpt and last error (devos logs --self --diagnose)
+        self.devos_logs = config.get('devos_logs') or ''
+        self.devos_last_error = config.get('devos_last_error') or ''
+        # Structured DNS/TCP/TLS results to interpret (devos net --diagnose)
+        self.net_report = config.get('net_report') or {}
+        # Parameterized runbooks the Go side can instantiate
+        self.runbooks = config.get('runbooks') or []
+        # Jira or Linear ticket the work is for (devos issue work)
+        self.issue = config.get('issue') or {}
+        # Active execution environment (devos env use <name>)
+        self.environment = config.get('environment') or {}
+        # Registered OpenAPI specs the request mentions (devos api add)
+        self.apis = config.get('apis') or []
         
     def process(self, user_input: str) -> ExecutionResult:
         """
@@ -39,6 +66,27 @@ class AIProcessor:
             ExecutionResult with interpreted commands
         """
         try:
+            # Prefer a runbook written for the task
+            runbook = self._match_runbook(user_input)
+            if runbook:
+                return ExecutionResult(
+                    output=f"📓 Runbook {runbook['name']}",
+                    commands=[],
+                    needs_confirmation=True,
+                    intent='runbook',
+                    runbook=runbook
+                )
+
+            # Answer questions about registered APIs from their specs
+            api_answer = self._describe_apis(user_input)
+            if api_answer:
+                return ExecutionResult(
+                    output=api_answer,
+                    commands=[],
+                    needs_confirmation=False,
+                    intent='api'
+                )
+
             # Classify the intent
             intent = self._classify_intent(user_input)
             
@@ -48,16 +96,25 @@ class AIProcessor:
             # Convert plan to OS-specific commands
             commands = self._plan_to_commands(plan)
             
+            # Apply past user corrections (few-shot memory)
+            commands = self._apply_corrections(commands)
+            
+            # Follow plans rated good for this request; flag ones rated bad
+            commands, note = self._apply_feedback(user_input, commands)
+            
             # Determine if confirmation is needed
             needs_confirmation = self._needs_confirmation(commands)
             
             # Generate human-readable output
             output = self._format_output(plan, commands)
+            if note:
+                output += f"\n\n{note}"
             
             return ExecutionResult(
                 output=output,
                 commands=commands,
-                needs_confirmation=needs_confirmation
+                needs_confirmation=needs_confirmation,
+                intent=intent
             )
             
         except Exception as e:
@@ -73,7 +130,29 @@ class AIProcessor:
         input_lower = user_input.lower()
         
         # Intent classification patterns
-        if any(kw in input_lower for kw in ['setup', 'create', 'init', 'scaffold']):
+        if self.devos_logs:
+            return 'diagnose_self'
+        elif self.log_excerpt:
+            return 'diagnose'
+        elif self.net_report:
+            return 'diagnose_network'
+        elif self._about_devos_failure(input_lower):
+            return 'self'
+        elif re.search(r'\bgrpc\b', input_lower):
+            return 'grpc'
+        elif self._network_target(user_input):
+            return 'network'
+        elif re.search(r'\b(ci|pipeline|github actions|workflow|makefile)\b', input_lower):
+            return 'ci'
+        elif self._service_name(input_lower):
+            return 'service'
+        elif re.search(r'\bssh\b', input_lower) and re.search(r'\b(key|keygen|agent|config|host)\b', input_lower):
+            return 'ssh'
+        elif self.helm_charts and re.search(r'\b(helm|chart|values)\b', input_lower):
+            return 'helm'
+        elif self._is_member_request(input_lower):
+            return 'project_setup'
+        elif any(kw in input_lower for kw in ['setup', 'create', 'init', 'scaffold']):
             return 'project_setup'
         elif any(kw in input_lower for kw in ['fix', 'debug', 'error', 'problem']):
             return 'debug'
@@ -101,6 +180,36 @@ class AIProcessor:
         if intent == 'project_setup':
             plan['steps'] = self._plan_project_setup(user_input)
             plan['description'] = 'Setting up new project'
+        elif intent == 'diagnose':
+            plan['steps'], plan['findings'] = self._plan_diagnose()
+            plan['description'] = 'Diagnosing from log excerpt'
+        elif intent == 'diagnose_self':
+            plan['steps'], plan['findings'] = self._plan_diagnose_self()
+            plan['description'] = "Diagnosing from DevOS's own log"
+        elif intent == 'self':
+            plan['steps'] = self._plan_self(user_input)
+            plan['description'] = "Reading DevOS's own log"
+        elif intent == 'diagnose_network':
+            plan['steps'], plan['findings'] = self._plan_diagnose_network()
+            plan['description'] = 'Interpreting network checks'
+        elif intent == 'grpc':
+            plan['steps'] = self._plan_grpc(user_input)
+            plan['description'] = 'Inspecting the gRPC service through reflection'
+        elif intent == 'network':
+            plan['steps'] = self._plan_network(user_input)
+            plan['description'] = 'Checking DNS, TCP, and TLS layer by layer'
+        elif intent == 'service':
+            plan['steps'] = self._plan_service(user_input)
+            plan['description'] = 'Checking service state'
+        elif intent == 'ssh':
+            plan['steps'] = self._plan_ssh(user_input)
+            plan['description'] = 'Guided SSH setup (existing keys are never overwritten)'
+        elif intent == 'helm':
+            plan['steps'] = self._plan_helm(user_input)
+            plan['description'] = 'Updating Helm values and diffing rendered manifests'
+        elif intent == 'ci':
+            plan['steps'] = self._plan_ci(user_input)
+            plan['description'] = 'Generating validated build automation'
         elif intent == 'debug':
             plan['steps'] = self._plan_debug(user_input)
             plan['description'] = 'Analyzing and fixing errors'
@@ -122,7 +231,10 @@ class AIProcessor:
         input_lower = user_input.lower()
         
         # Detect project type
-        if 'fastapi' in input_lower or 'fast api' in input_lower:
+        if self._is_member_request(input_lower):
+            steps.extend(self._plan_monorepo_member(user_input))
+        
+        elif 'fastapi' in input_lower or 'fast api' in input_lower:
             steps.extend([
                 {'action': 'create_directory', 'name': 'my-fastapi-app'},
                 {'action': 'create_file', 'path': 'my-fastapi-app/main.py', 
@@ -155,6 +267,413 @@ class AIProcessor:
         
         return steps
     
+    def _match_runbook(self, user_input: str) -> Dict[str, Any]:
+        """Runbook whose keywords appear in the input, with the parameters it names
+        as "name=value" or "name value" """
+        input_lower = user_input.lower()
+        for runbook in self.runbooks:
+            if not any(kw in input_lower for kw in runbook.get('keywords') or []):
+                continue
+            params = {}
+            for param in runbook.get('params') or []:
+                name = param['name']
+                match = (re.search(rf'\b{re.escape(name)}=(\S+)', user_input, re.IGNORECASE)
+                         or re.search(rf'\b{re.escape(name)}\s+([^\s=]+)(?:\s|$)', user_input, re.IGNORECASE))
+                if match:
+                    params[name] = match.group(1).strip('"\'')
+            return {'name': runbook['name'], 'params': params}
+        return None
+
+    def _describe_apis(self, user_input: str) -> str:
+        """Servers, auth, and operations of the mentioned APIs, when the input
+        asks what they offer rather than to do something"""
+        if not self.apis or not re.search(
+                r'\b(endpoints?|operations?|routes?|what can|how (do|can|should) i (call|use))\b',
+                user_input.lower()):
+            return ''
+        sections = []
+        for api in self.apis:
+            lines = [f"🔌 {api.get('title') or api['name']} ({api['name']})"]
+            if api.get('servers'):
+                lines.append(f"   Servers: {', '.join(api['servers'])}")
+            if api.get('auth'):
+                lines.append(f"   Auth: {'; '.join(api['auth'])}")
+            lines.extend(f"   • {op}" for op in api.get('operations') or [])
+            if api.get('omitted'):
+                lines.append(f"   … and {api['omitted']} more operations")
+            sections.append('\n'.join(lines))
+        return '\n\n'.join(sections)
+
+    def _service_name(self, input_lower: str) -> str:
+        """Service named in questions like "is postgres running" or "restart the nginx service" """
+        match = (re.search(r'\bis\s+([\w@.-]+)\s+(?:running|up|down|active|started)\b', input_lower)
+                 or re.search(r'\b([\w@.-]+)\s+(?:service|daemon)\b', input_lower)
+                 or re.search(r'\bservice\s+([\w@.-]+)\b', input_lower))
+        if match and match.group(1) not in ('the', 'a', 'this', 'that', 'which', 'my'):
+            return match.group(1)
+        return ''
+    
+    def _network_target(self, user_input: str) -> str:
+        """Host in questions like "why can't I reach api.internal" or "ping db:5432" """
+        match = re.search(
+            r"\b(?:reach|connect(?:ing)? to|ping|resolve|resolving|traceroute(?: to)?|dns (?:for|of)|"
+            r"(?:tls|ssl|cert(?:ificate)?) (?:for|of|on))\s+((?:https?://)?[\w.-]+(?::\d+)?)",
+            user_input, re.IGNORECASE)
+        if match and ('.' in match.group(1) or ':' in match.group(1) or match.group(1) == 'localhost'):
+            return match.group(1).rstrip('.')
+        return ''
+    
+    def _plan_network(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan network checks through DevOS's native diagnostics"""
+        target = self._network_target(user_input)
+        input_lower = user_input.lower()
+        mode = ''
+        if re.search(r'\b(dns|resolve|resolving)\b', input_lower):
+            mode = 'dns '
+        elif re.search(r'\b(tls|ssl|cert|certificate)\b', input_lower):
+            mode = 'tls '
+        elif 'traceroute' in input_lower:
+            mode = 'trace '
+        question = user_input.replace('"', "'")
+        trace = ' --trace' if not mode else ''
+        return [{'action': 'run_command', 'command': f'devos net {mode}{target}{trace} --diagnose "{question}"'}]
+    
+    # Interpretation of the first failed network check, by failure kind
+    NET_FAILURES = {
+        'no_such_host': 'The name does not resolve. Check the spelling, or whether it is only '
+                        'published on an internal DNS server (VPN disconnected, wrong resolver)',
+        'dns_error': 'DNS lookup failed; the resolver itself is unreachable or misconfigured',
+        'connection_refused': 'The host is up but nothing is listening on that port '
+                              '(service stopped, wrong port, or bound to localhost only)',
+        'timeout': 'Packets are dropped on the way: a firewall, security group, or routing problem',
+        'unreachable': 'There is no route to the host from this machine (VPN or network down)',
+        'certificate_expired': 'The server certificate has expired',
+        'certificate_hostname_mismatch': 'The certificate does not cover this hostname',
+        'certificate_untrusted': 'The certificate is signed by an authority this machine does not trust '
+                                 '(internal CA not installed, or a self-signed certificate)',
+        'tls_error': 'The TLS handshake failed (protocol or cipher mismatch, or not a TLS port)',
+    }
+    
+    def _plan_diagnose_network(self):
+        """Interpret structured DNS/TCP/TLS results"""
+        report = self.net_report
+        target = report.get('target', {})
+        host, port = target.get('host', ''), target.get('port', 0)
+        findings, steps = [], []
+        failed = next((c for c in report.get('checks', []) if not c.get('ok')), None)
+        
+        if failed:
+            kind = failed.get('failure', '')
+            findings.append(f"{failed.get('name', '').upper()} failed: "
+                            f"{self.NET_FAILURES.get(kind, failed.get('detail', 'unknown error'))}")
+            if kind in ('no_such_host', 'dns_error') and self.os != 'windows':
+                steps.append({'action': 'run_command', 'command': 'cat /etc/resolv.conf'})
+        elif report.get('checks'):
+            findings.append(f'{host}:{port} is reachable; DNS, TCP'
+                            + (', and TLS' if len(report['checks']) > 2 else '')
+                            + ' all succeeded, so the problem is likely at the application layer')
+        
+        hops = report.get('hops') or []
+        answered = [h for h in hops if h.get('address')]
+        if hops and answered:
+            findings.append(f"Traffic stops after hop {answered[-1]['ttl']} ({answered[-1]['address']})")
+        
+        if not findings:
+            findings.append('No checks were run')
+        return steps, findings
+    
+    def _plan_service(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan service queries through DevOS's structured service tool"""
+        input_lower = user_input.lower()
+        name = self._service_name(input_lower)
+        action = re.search(r'\b(start|stop|restart)\b', input_lower)
+        if action and not re.search(r'\b(why|when)\b', input_lower):
+            return [{'action': 'run_command', 'command': f'devos service {action.group(1)} {name}'}]
+        steps = [{'action': 'run_command', 'command': f'devos service status {name}'}]
+        if re.search(r'\b(why|crash(ed)?|fail(ed)?|died|down|stopped|restart(ed|ing)?)\b', input_lower):
+            since = 'yesterday' if re.search(r'\b(yesterday|last night)\b', input_lower) else '24h'
+            question = user_input.replace('"', "'")
+            steps.append({'action': 'run_command',
+                          'command': f'devos logs --unit {name} --since {since} --errors --diagnose "{question}"'})
+        return steps
+    
+    # Known failure signatures in log excerpts: pattern, finding, follow-up commands
+    LOG_SIGNATURES = [
+        (r'out of memory|oom-kill|killed process|cannot allocate memory',
+         'The process was killed for running out of memory', ['free -h']),
+        (r'no space left on device|disk full',
+         'The disk filled up', ['df -h']),
+        (r'address already in use|bind\(\).*failed|could not bind',
+         'Another process already holds the port it listens on', ['ss -ltnp']),
+        (r'permission denied|operation not permitted|access is denied',
+         'It was denied access to a file or socket it needs', []),
+        (r'could not open|no such file or directory|not found',
+         'A file or binary it needs is missing', []),
+        (r'connection refused|could not connect|timed out|timeout',
+         'A dependency it connects to was unreachable', []),
+        (r'segfault|segmentation fault|core dumped|sigsegv|sigabrt',
+         'The process crashed (segmentation fault or abort)', []),
+        (r'start request repeated too quickly|start-limit-hit|restart counter',
+         'It crashed repeatedly until the service manager stopped restarting it', []),
+        (r'invalid|syntax error|configuration file.*contains errors|parse error',
+         'Its configuration failed to load', []),
+    ]
+    
+    def _plan_diagnose(self):
+        """Match the log excerpt against known failure signatures"""
+        excerpt = self.log_excerpt.lower()
+        findings, steps = [], []
+        for pattern, finding, commands in self.LOG_SIGNATURES:
+            match = re.search(pattern, excerpt)
+            if not match:
+                continue
+            line = next((l for l in self.log_excerpt.splitlines() if re.search(pattern, l.lower())), '')
+            findings.append(f'{finding}:\n     {line.strip()}')
+            for command in commands:
+                if self.os != 'windows':
+                    steps.append({'action': 'run_command', 'command': command})
+        if not findings:
+            findings.append('No known failure signature in the excerpt; the most severe entries are listed above')
+        return steps, findings
+    
+    def _about_devos_failure(self, input_lower: str) -> bool:
+        """Questions like "why did my last devos command fail" """
+        return bool(re.search(r'\bdevos\b', input_lower)) and bool(
+            re.search(r'\b(fail(ed|s|ing)?|error(ed|s)?|crash(ed)?|broke|wrong|not work(ing)?|did(n\'t| not) work)\b', input_lower))
+    
+    def _plan_self(self, user_input: str) -> List[Dict[str, str]]:
+        """Read and diagnose DevOS's own log rather than the system log"""
+        since = '7d' if re.search(r'\b(yesterday|last week|days? ago)\b', user_input.lower()) else '24h'
+        question = user_input.replace('"', "'")
+        return [{'action': 'run_command', 'command': f'devos logs --self --since {since} --diagnose "{question}"'}]
+    
+    # Known DevOS failures: pattern, finding, follow-up commands
+    DEVOS_SIGNATURES = [
+        (r'decrypt|devos_config_passphrase|keychain',
+         'The config has encrypted sections and could not be unlocked; set DEVOS_CONFIG_PASSPHRASE '
+         'or check the OS keychain', []),
+        (r'invalid configuration|failed to parse config',
+         'The config file is invalid', ['devos config']),
+        (r'budget',
+         'An AI budget is used up, so requests were refused', ['devos budget']),
+        (r'change freeze|freeze',
+         'A change freeze held the plan', ['devos freezes']),
+        (r'security validation failed|blocked by|policy',
+         'The approval policy blocked a command in the plan', ['devos policy stats']),
+        (r'timed out|deadline exceeded|did not finish in time',
+         'The AI provider did not answer in time ("ai_timeout" in config)', ['devos status']),
+        (r'ollama.*(connection refused|no such host)|connect: connection refused',
+         'The local model server is not running', ['devos models']),
+        (r'unauthorized|401|invalid api key|incorrect api key',
+         'The AI provider rejected the API key', []),
+        (r'python3|ai_engine|no module named',
+         'The AI engine could not start; its Python package may be missing', []),
+        (r'daemon',
+         'The team daemon was unreachable or refused the request', []),
+        (r'command failed|exit status',
+         'A command in the plan exited with an error; its output is in the entry above', []),
+    ]
+    
+    def _plan_diagnose_self(self):
+        """Explain DevOS's last error from its own log"""
+        findings, steps = [], []
+        last = self.devos_last_error
+        if last:
+            findings.append(f'Last error:\n     {last.strip()}')
+        # Match the cause, not the command line of "devos <args> failed: <cause>"
+        evidence = (last or self.devos_logs).split(' failed: ', 1)[-1].lower()
+        for pattern, finding, commands in self.DEVOS_SIGNATURES:
+            if re.search(pattern, evidence):
+                findings.append(finding)
+                steps.extend({'action': 'run_command', 'command': command} for command in commands)
+                break
+        if not last:
+            findings.append('DevOS logged no errors in this period; the recent entries are listed above')
+        return steps, findings
+    
+    def _plan_ssh(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan SSH tasks through DevOS's guarded SSH assistant"""
+        input_lower = user_input.lower()
+        steps = []
+        
+        name = re.search(r'\bfor\s+(github|gitlab|bitbucket|[\w-]+)\b', input_lower)
+        if re.search(r'\b(generate|create|new)\b.*\bkey\b', input_lower):
+            flags = f' --name {name.group(1)}' if name else ''
+            steps.append({'action': 'run_command', 'command': f'devos ssh keygen{flags}'})
+        if 'agent' in input_lower:
+            key = 'id_ed25519' + (f'_{name.group(1)}' if name else '')
+            steps.append({'action': 'run_command', 'command': f'devos ssh agent-add ~/.ssh/{key}'})
+        
+        host = re.search(r'\bhost\s+([\w.-]+)', input_lower)
+        hostname = re.search(r'\b(?:at|to|hostname)\s+([\w.-]+\.[\w.-]+|\d+\.\d+\.\d+\.\d+)', input_lower)
+        if host and hostname:
+            command = f'devos ssh host {host.group(1)} --hostname {hostname.group(1)}'
+            user = re.search(r'\buser\s+([\w.-]+)', input_lower)
+            if user:
+                command += f' --user {user.group(1)}'
+            steps.append({'action': 'run_command', 'command': command})
+        
+        return steps
+    
+    def _plan_grpc(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan gRPC inspection and calls through server reflection (devos grpc)"""
+        target = re.search(r'\b((?:[\w-]+\.)*[\w-]+:\d+)\b', user_input)
+        address = target.group(1) if target else 'localhost:50051'
+        host = address.rsplit(':', 1)[0]
+        flags = ''
+        # Local and cluster-internal services usually serve without TLS
+        if (host in ('localhost', '127.0.0.1', '0.0.0.0') or '.' not in host
+                or re.search(r'\b(plaintext|insecure|no tls|without tls)\b', user_input, re.IGNORECASE)):
+            flags = ' --plaintext'
+        
+        method = re.search(r'\b((?:[a-z_][\w]*\.)*[A-Z]\w*)[/.]([A-Z]\w*)\b', user_input)
+        service = re.search(r'\b((?:[a-z_][\w]*\.)+[A-Z]\w*)\b', user_input)
+        if method:
+            name = f'{method.group(1)}/{method.group(2)}'
+            request = re.search(r"(\{.*\})", user_input)
+            if request and re.search(r'\b(call|invoke|send|request)\b', user_input, re.IGNORECASE):
+                body = request.group(1).replace("'", '"')
+                return [{'action': 'run_command', 'command': f"devos grpc call {address} {name} '{body}'{flags}"}]
+            return [{'action': 'run_command', 'command': f'devos grpc describe {address} {name}{flags}'},
+                    {'action': 'run_command', 'command': f'devos grpc call {address} {name}{flags}'}]
+        if service:
+            return [{'action': 'run_command', 'command': f'devos grpc list {address} {service.group(1)}{flags}'}]
+        return [{'action': 'run_command', 'command': f'devos grpc list {address}{flags}'}]
+    
+    def _plan_helm(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan Helm value changes through DevOS's values editor, which diffs the render"""
+        input_lower = user_input.lower()
+        flags = ''
+        env = re.search(r'\b(dev|development|staging|stage|prod|production|qa|test)\b', input_lower)
+        if env:
+            flags += f' --env {env.group(1)}'
+        for chart in self.helm_charts:
+            if len(self.helm_charts) > 1 and chart.get('name', '').lower() in input_lower:
+                flags += f' --chart {chart["name"]}'
+                break
+        
+        key = 'image.tag' if 'tag' in input_lower else None
+        key_match = re.search(r'\b([a-z][\w-]*(?:\.[\w-]+)+)\b', input_lower)
+        if key_match and not re.match(r'^\d', key_match.group(1)):
+            key = key_match.group(1)
+        value = re.search(r'\bto\s+["\']?([\w.:-]+)', user_input)
+        
+        if key and value:
+            return [{'action': 'run_command', 'command': f'devos helm set {key} {value.group(1)}{flags}'}]
+        if key:
+            return [{'action': 'run_command', 'command': f'devos helm get {key}{flags}'}]
+        if re.search(r'\b(render|template|diff)\b', input_lower):
+            return [{'action': 'run_command', 'command': f'devos helm template{flags}'}]
+        return [{'action': 'run_command', 'command': 'devos helm list'}]
+    
+    def _plan_ci(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan CI/Makefile generation through DevOS's validating generators"""
+        input_lower = user_input.lower()
+        flags = ' --docker' if 'docker' in input_lower else ''
+        
+        steps = []
+        if re.search(r'\b(ci|pipeline|github actions|workflow)\b', input_lower):
+            steps.append({'action': 'run_command', 'command': f'devos generate ci{flags}'})
+        if 'makefile' in input_lower:
+            steps.append({'action': 'run_command', 'command': f'devos generate makefile{flags}'})
+        return steps
+    
+    def _is_member_request(self, input_lower: str) -> bool:
+        """Whether the user wants a new member (service, package, app) in a monorepo"""
+        return bool(self.project.get('monorepo')) and bool(
+            re.search(r'\b(add|create|new|scaffold)\b', input_lower)) and bool(
+            re.search(r'\b(service|package|app|application|library|lib|module|crate)\b', input_lower))
+    
+    def _plan_monorepo_member(self, user_input: str) -> List[Dict[str, str]]:
+        """Plan a new workspace member placed and registered the way the repo expects"""
+        input_lower = user_input.lower()
+        kinds = self.project.get('kinds') or []
+        languages = self.project.get('languages') or []
+        name = self._member_name(input_lower)
+        path = f'{self._member_parent(input_lower)}/{name}'
+        
+        def wants(language: str, keywords: List[str]) -> bool:
+            return any(re.search(rf'\b{kw}\b', input_lower) for kw in keywords) or languages == [language]
+        
+        if 'go-workspace' in kinds and wants('go', ['go', 'golang']):
+            return [
+                {'action': 'create_directory', 'name': path},
+                {'action': 'run_command', 'command': f'cd "{path}" && go mod init {name}'},
+                {'action': 'run_command', 'command': f'go work use ./{path}'},
+            ]
+        if 'cargo-workspace' in kinds and wants('rust', ['rust', 'crate', 'cargo']):
+            # cargo adds new packages to [workspace] members itself
+            return [{'action': 'run_command', 'command': f'cargo new {path}'}]
+        if re.search(r'\b(python|fastapi|flask|django)\b', input_lower):
+            return [
+                {'action': 'create_directory', 'name': path},
+                {'action': 'create_file', 'path': f'{path}/main.py', 'content': 'Service entry point'},
+                {'action': 'create_file', 'path': f'{path}/requirements.txt', 'content': ''},
+            ]
+        
+        if 'nx' in kinds:
+            return [{'action': 'run_command',
+                     'command': f'npx nx g @nx/node:application {name} --directory={path}'}]
+        if 'pnpm-workspaces' in kinds:
+            steps = [
+                {'action': 'create_directory', 'name': path},
+                {'action': 'run_command', 'command': f'cd "{path}" && pnpm init'},
+            ]
+            if not self._covered_by_workspaces(path):
+                steps.append({'action': 'run_command',
+                              'command': f"printf '  - \"{path}\"\\n' >> pnpm-workspace.yaml"})
+            return steps
+        if 'yarn-workspaces' in kinds:
+            return [
+                {'action': 'create_directory', 'name': path},
+                {'action': 'run_command', 'command': f'cd "{path}" && yarn init -y'},
+            ]
+        # npm registers the new member in package.json "workspaces" itself
+        return [{'action': 'run_command', 'command': f'npm init -y -w ./{path}'}]
+    
+    def _member_name(self, input_lower: str) -> str:
+        """Name for a new workspace member, e.g. "add a billing service" -> billing"""
+        match = re.search(r'\b(?:called|named)\s+([\w.-]+)', input_lower)
+        if match:
+            return match.group(1)
+        
+        filler = {'a', 'an', 'the', 'new', 'go', 'golang', 'rust', 'python', 'node', 'typescript',
+                  'javascript', 'fastapi', 'flask', 'django', 'this', 'another'}
+        match = re.search(r'([\w-]+)\s+(?:service|package|app|application|library|lib|module|crate)\b', input_lower)
+        if match and match.group(1) not in filler:
+            return match.group(1)
+        return 'new-service'
+    
+    def _member_parent(self, input_lower: str) -> str:
+        """Directory new members belong in, following the repo's existing layout"""
+        parents = []
+        for member in self.project.get('members') or []:
+            parents.append(member.rsplit('/', 1)[0] if '/' in member else '.')
+        for pattern in self.project.get('workspaces') or []:
+            if pattern.endswith('/*'):
+                parents.append(pattern[:-2])
+        parents = [p for p in parents if p != '.']
+        
+        preferred = {
+            'service': ['services', 'apps'], 'app': ['apps', 'services'], 'application': ['apps'],
+            'library': ['libs', 'packages'], 'lib': ['libs', 'packages'], 'package': ['packages', 'libs'],
+            'module': ['modules', 'packages'], 'crate': ['crates'],
+        }
+        for keyword, candidates in preferred.items():
+            if re.search(rf'\b{keyword}\b', input_lower):
+                for candidate in candidates:
+                    if candidate in parents:
+                        return candidate
+        
+        if parents:
+            return max(set(parents), key=parents.count)
+        return 'packages'
+    
+    def _covered_by_workspaces(self, path: str) -> bool:
+        """Whether a workspace glob already includes path"""
+        import fnmatch
+        return any(fnmatch.fnmatch(path, pattern) for pattern in self.project.get('workspaces') or [])
+    
     def _plan_debug(self, user_input: str) -> List[Dict[str, str]]:
         """Plan debugging steps"""
         return [
@@ -217,10 +736,37 @@ class AIProcessor:
         
         return commands
     
+    def _apply_corrections(self, commands: List[str]) -> List[str]:
+        """Replace commands the user previously corrected with their edited form"""
+        fixes = {}
+        # Corrections arrive newest first; keep the most recent edit per command
+        for correction in reversed(self.corrections):
+            original = correction.get('original')
+            if original:
+                fixes[original] = correction.get('edited', '')
+        
+        return [fixes.get(cmd, cmd) for cmd in commands if fixes.get(cmd, cmd)]
+    
+    def _apply_feedback(self, user_input: str, commands: List[str]):
+        """Reuse a plan rated good for the same request, and warn when the
+        plan is one the user rated bad"""
+        request = ' '.join(user_input.lower().split())
+        for example in self.examples:
+            same = ' '.join(example.get('input', '').lower().split()) == request
+            if same and example.get('rating') == 'good' and example.get('commands'):
+                return list(example['commands']), '👍 Reusing the plan you rated good for this request'
+        for example in self.examples:
+            if example.get('rating') == 'bad' and example.get('commands') == commands:
+                reason = example.get('reason') or 'no reason given'
+                return commands, f'⚠️  You rated this plan bad before: {reason}'
+        return commands, ''
+    
     def _cmd_mkdir(self, name: str) -> str:
         """OS-specific mkdir command"""
         if self.os == 'windows':
             return f'New-Item -ItemType Directory -Path "{name}"'
+        elif self.shell == 'nu':
+            return f'mkdir "{name}"'
         else:
             return f'mkdir -p "{name}"'
     
@@ -260,6 +806,8 @@ class AIProcessor:
         """OS-specific directory listing"""
         if self.os == 'windows':
             return f'Get-ChildItem -Path "{path}"'
+        elif self.shell == 'nu':
+            return f'^ls -la "{path}"'
         else:
             return f'ls -la "{path}"'
     
@@ -280,8 +828,20 @@ class AIProcessor:
     def _format_output(self, plan: Dict[str, Any], commands: List[str]) -> str:
         """Format human-readable output"""
         output = f"📋 Plan: {plan['description']}\n\n"
+        if self.issue:
+            output = f"🎫 {self.issue.get('key', '')}: {self.issue.get('title', '')}\n" + output
+        if self.environment:
+            output = f"🌍 Environment: {self.environment.get('name', '')}\n" + output
         output += f"Steps to execute:\n"
         
+        if plan.get('findings'):
+            output = f"🔎 {plan['description']}\n\nLikely causes:\n"
+            for finding in plan['findings']:
+                output += f"  • {finding}\n"
+            if not plan['steps']:
+                return output.strip()
+            output += "\nFollow-up checks:\n"
+        
         for i, step in enumerate(plan['steps'], 1):
             action = step.get('action', 'unknown')
             output += f"  {i}. {action.replace('_', ' ').title()}\n"
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killGroupOnCancel runs cmd in its own process group and kills the whole
+// group when cmd's context ends, so children the shell started (a server, a
+// pipeline) do not outlive it
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Stop waiting for output a detached grandchild keeps open
+	cmd.WaitDelay = 5 * time.Second
+}
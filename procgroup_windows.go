@@ -0,0 +1,17 @@
+package executor
+
+import (
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// killGroupOnCancel kills cmd's whole process tree when its context ends,
+// so children the shell started do not outlive it
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+	// Stop waiting for output a detached grandchild keeps open
+	cmd.WaitDelay = 5 * time.Second
+}
@@ -0,0 +1,364 @@
+package profile
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/memory"
+	"devos/internal/vault"
+)
+
+// magic starts every profile archive, followed by the salt, the nonce, and
+// the AES-GCM sealed tar.gz
+const magic = "DEVOSPROFILE1\n"
+
+// ErrBadPassphrase is returned when an archive cannot be decrypted
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted profile")
+
+// dataDirs are directories under the config directory carried in a profile
+var dataDirs = []string{"plugins", "snippets", "workflows", "runbooks", "apis"}
+
+// Manifest describes an archive's contents
+type Manifest struct {
+	Version         int       `json:"version"`
+	Created         time.Time `json:"created"`
+	Host            string    `json:"host"`
+	OS              string    `json:"os"`
+	SecretsIncluded bool      `json:"secrets_included"`
+	Files           []string  `json:"files"`
+}
+
+// Export writes an encrypted archive of the config, memory database, and
+// data directories. Without includeSecrets, API keys and tokens are removed.
+func Export(cfg *config.Config, mem memory.MemoryStore, w io.Writer, passphrase string, includeSecrets bool) (*Manifest, error) {
+	host, _ := os.Hostname()
+	manifest := &Manifest{Version: 1, Created: time.Now().UTC(), Host: host, OS: runtime.GOOS, SecretsIncluded: includeSecrets}
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+
+	saved := *cfg
+	if !includeSecrets {
+		saved = cfg.WithoutSecrets()
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := addFile(tw, "config.json", data); err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, "config.json")
+
+	// Shared Postgres memory stays in its database
+	if mem != nil && cfg.MemoryBackend != memory.BackendPostgres {
+		tmp, err := os.MkdirTemp("", "devos-profile")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		backup := filepath.Join(tmp, "memory.db")
+		if err := mem.Backup(backup); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			return nil, err
+		}
+		if err := addFile(tw, "memory.db", data); err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, "memory.db")
+	}
+
+	for _, dir := range dataDirs {
+		root := filepath.Join(cfg.Dir(), dir)
+		if dir == "plugins" && cfg.PluginPath != "" {
+			root = cfg.PluginPath
+		}
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, p)
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			name := path.Join(dir, filepath.ToSlash(rel))
+			manifest.Files = append(manifest.Files, name)
+			return addFile(tw, name, data)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, _ = json.MarshalIndent(manifest, "", "  ")
+	if err := addFile(tw, "manifest.json", data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	sealed, err := seal(archive.Bytes(), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(sealed)
+	return manifest, err
+}
+
+// Import replaces the config, memory database, and data directories with
+// an archive's contents. Paths are rebased onto this machine's config
+// directory, and secrets the archive left out keep their current values.
+// The memory store must be closed; the previous database is kept as memory.db.bak.
+func Import(cfg *config.Config, r io.Reader, passphrase string) (*Manifest, error) {
+	sealed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	files, err := Read(sealed, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		return nil, fmt.Errorf("profile has no valid manifest: %w", err)
+	}
+
+	var imported config.Config
+	if err := json.Unmarshal(files["config.json"], &imported); err != nil {
+		return nil, fmt.Errorf("%w: profile config: %w", config.ErrInvalidConfig, err)
+	}
+	dir := cfg.Dir()
+	imported.OS = runtime.GOOS
+	imported.ConfigPath = cfg.ConfigPath
+	imported.MemoryPath = filepath.Join(dir, "memory.db")
+	imported.PluginPath = filepath.Join(dir, "plugins")
+	imported.AuditPath = cfg.AuditPath
+	if !manifest.SecretsIncluded {
+		keepSecrets(&imported, cfg)
+	}
+	if err := imported.Validate(); err != nil {
+		return nil, err
+	}
+
+	if db, ok := files["memory.db"]; ok {
+		if current, err := os.ReadFile(cfg.MemoryPath); err == nil {
+			if err := os.WriteFile(cfg.MemoryPath+".bak", current, 0600); err != nil {
+				return nil, err
+			}
+		}
+		if err := os.WriteFile(imported.MemoryPath, db, 0600); err != nil {
+			return nil, err
+		}
+	}
+	for name, data := range files {
+		top, _, _ := strings.Cut(name, "/")
+		if !containsDir(top) {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, data, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	imported.KeepEncryption(cfg)
+	imported.KeepManaged(cfg)
+	*cfg = imported
+	if err := cfg.Save(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Read decrypts an archive into its files, keyed by slash-separated name
+func Read(sealed []byte, passphrase string) (map[string][]byte, error) {
+	archive, err := open(sealed, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg || path.IsAbs(name) || strings.HasPrefix(name, "../") || name == ".." {
+			return nil, fmt.Errorf("profile contains an unsafe entry: %s", header.Name)
+		}
+		if files[name], err = io.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// keepSecrets copies secrets from the current config into an imported one
+// that was exported without them
+func keepSecrets(imported, current *config.Config) {
+	if imported.APIKey == "" && imported.AIProvider == current.AIProvider {
+		imported.APIKey = current.APIKey
+	}
+	if imported.SlackWebhookURL == "" {
+		imported.SlackWebhookURL = current.SlackWebhookURL
+	}
+	if imported.MemoryDSN == "" {
+		imported.MemoryDSN = current.MemoryDSN
+	}
+	if t := imported.IssueTracker; t != nil && t.Token == "" && current.IssueTracker != nil && current.IssueTracker.Type == t.Type && current.IssueTracker.URL == t.URL {
+		t.Token = current.IssueTracker.Token
+	}
+	if imported.OIDC != nil && imported.OIDC.ClientSecret == "" && current.OIDC != nil {
+		imported.OIDC.ClientSecret = current.OIDC.ClientSecret
+	}
+	for i, sink := range imported.LogSinks {
+		for _, kept := range current.LogSinks {
+			if sink.Headers == nil && kept.Type == sink.Type && config.WithoutUserinfo(kept.Target) == sink.Target {
+				imported.LogSinks[i].Target, imported.LogSinks[i].Headers = kept.Target, kept.Headers
+			}
+		}
+	}
+	for i, env := range imported.Environments {
+		for _, kept := range current.Environments {
+			if kept.Name != env.Name {
+				continue
+			}
+			for name, value := range kept.Env {
+				if _, ok := env.Env[name]; ok || !config.SecretVar(name) {
+					continue
+				}
+				if imported.Environments[i].Env == nil {
+					imported.Environments[i].Env = map[string]string{}
+				}
+				imported.Environments[i].Env[name] = value
+			}
+		}
+	}
+	for i, export := range imported.AuditExports {
+		for _, kept := range current.AuditExports {
+			if export.Token == "" && export.Headers == nil && kept.Type == export.Type && kept.Target == export.Target {
+				imported.AuditExports[i].Token, imported.AuditExports[i].Headers = kept.Token, kept.Headers
+			}
+		}
+	}
+	tokens := map[string]string{}
+	for _, user := range current.TeamUsers {
+		tokens[user.Name] = user.Token
+	}
+	for i, user := range imported.TeamUsers {
+		if user.Token == "" {
+			imported.TeamUsers[i].Token = tokens[user.Name]
+		}
+	}
+}
+
+// containsDir reports whether name is one of the carried data directories
+func containsDir(name string) bool {
+	for _, dir := range dataDirs {
+		if dir == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addFile writes one regular file to the archive
+func addFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// seal encrypts data with a key derived from the passphrase
+func seal(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase is required")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(magic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, []byte(magic)), nil
+}
+
+// open decrypts data produced by seal
+func open(sealed []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(sealed, []byte(magic)) {
+		return nil, fmt.Errorf("not a DevOS profile archive")
+	}
+	rest := sealed[len(magic):]
+	if len(rest) < 16+12 {
+		return nil, ErrBadPassphrase
+	}
+	gcm, err := newGCM(passphrase, rest[:16])
+	if err != nil {
+		return nil, err
+	}
+	nonce := rest[16 : 16+gcm.NonceSize()]
+	data, err := gcm.Open(nil, nonce, rest[16+gcm.NonceSize():], []byte(magic))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return data, nil
+}
+
+// newGCM returns AES-256-GCM keyed from the passphrase
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(vault.DeriveKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
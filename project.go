@@ -0,0 +1,227 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"devos/internal/ignore"
+)
+
+// Monorepo tooling recognized by Detect
+const (
+	KindNPMWorkspaces  = "npm-workspaces"
+	KindYarnWorkspaces = "yarn-workspaces"
+	KindPNPMWorkspaces = "pnpm-workspaces"
+	KindNx             = "nx"
+	KindTurborepo      = "turborepo"
+	KindGoWorkspace    = "go-workspace"
+	KindCargoWorkspace = "cargo-workspace"
+)
+
+// Layout describes the repository the user is working in
+type Layout struct {
+	Root       string   `json:"root"`
+	Monorepo   bool     `json:"monorepo"`
+	Kinds      []string `json:"kinds,omitempty"`      // Tooling found, e.g. "pnpm-workspaces", "turborepo"
+	Workspaces []string `json:"workspaces,omitempty"` // Member globs or directories from the manifests
+	Members    []string `json:"members,omitempty"`    // Existing member directories, relative to Root
+	Manifests  []string `json:"manifests,omitempty"`  // Files listing members that new members must be added to
+	Languages  []string `json:"languages,omitempty"`
+}
+
+// Detect inspects dir and its parents up to the repository root
+func Detect(dir string) *Layout {
+	root := findRoot(dir)
+	l := &Layout{Root: root}
+
+	detectNode(l)
+	detectGo(l)
+	detectCargo(l)
+	if exists(filepath.Join(root, "requirements.txt")) || exists(filepath.Join(root, "pyproject.toml")) {
+		l.Languages = appendUnique(l.Languages, "python")
+	}
+
+	l.Monorepo = len(l.Kinds) > 0
+	ignored := ignore.New(root)
+	for _, pattern := range l.Workspaces {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() && !ignored.Ignored(m, true) {
+				rel, _ := filepath.Rel(root, m)
+				l.Members = appendUnique(l.Members, filepath.ToSlash(rel))
+			}
+		}
+	}
+
+	return l
+}
+
+// Root returns the root of the project containing dir: the nearest ancestor
+// with a .git directory, or dir itself
+func Root(dir string) string {
+	return findRoot(dir)
+}
+
+// findRoot returns the nearest ancestor containing .git, or dir itself
+func findRoot(dir string) string {
+	for d := dir; ; {
+		if exists(filepath.Join(d, ".git")) {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+// detectNode recognizes npm/yarn/pnpm workspaces, Nx, and Turborepo
+func detectNode(l *Layout) {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if data, err := os.ReadFile(filepath.Join(l.Root, "package.json")); err == nil {
+		l.Languages = appendUnique(l.Languages, "javascript")
+		if json.Unmarshal(data, &pkg) == nil && len(pkg.Workspaces) > 0 {
+			var globs []string
+			var yarn struct {
+				Packages []string `json:"packages"`
+			}
+			if json.Unmarshal(pkg.Workspaces, &globs) != nil && json.Unmarshal(pkg.Workspaces, &yarn) == nil {
+				globs = yarn.Packages
+			}
+			kind := KindNPMWorkspaces
+			if exists(filepath.Join(l.Root, "yarn.lock")) {
+				kind = KindYarnWorkspaces
+			}
+			l.Kinds = append(l.Kinds, kind)
+			l.Workspaces = append(l.Workspaces, globs...)
+			l.Manifests = append(l.Manifests, "package.json")
+		}
+	}
+
+	if path := filepath.Join(l.Root, "pnpm-workspace.yaml"); exists(path) {
+		l.Kinds = append(l.Kinds, KindPNPMWorkspaces)
+		l.Workspaces = append(l.Workspaces, yamlList(path, "packages")...)
+		l.Manifests = append(l.Manifests, "pnpm-workspace.yaml")
+	}
+	if exists(filepath.Join(l.Root, "nx.json")) {
+		l.Kinds = append(l.Kinds, KindNx)
+		if len(l.Workspaces) == 0 {
+			l.Workspaces = []string{"apps/*", "libs/*", "packages/*"}
+		}
+	}
+	if exists(filepath.Join(l.Root, "turbo.json")) {
+		l.Kinds = append(l.Kinds, KindTurborepo)
+	}
+	if exists(filepath.Join(l.Root, "tsconfig.json")) {
+		l.Languages = appendUnique(l.Languages, "typescript")
+	}
+}
+
+// detectGo recognizes go.work workspaces
+func detectGo(l *Layout) {
+	if exists(filepath.Join(l.Root, "go.mod")) {
+		l.Languages = appendUnique(l.Languages, "go")
+	}
+
+	file, err := os.Open(filepath.Join(l.Root, "go.work"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	l.Kinds = append(l.Kinds, KindGoWorkspace)
+	l.Languages = appendUnique(l.Languages, "go")
+	l.Manifests = append(l.Manifests, "go.work")
+
+	inUse := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "use ("):
+			inUse = true
+		case inUse && line == ")":
+			inUse = false
+		case inUse && line != "":
+			l.Workspaces = append(l.Workspaces, strings.TrimPrefix(line, "./"))
+		case strings.HasPrefix(line, "use "):
+			l.Workspaces = append(l.Workspaces, strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, "use ")), "./"))
+		}
+	}
+}
+
+// detectCargo recognizes Cargo workspaces
+func detectCargo(l *Layout) {
+	path := filepath.Join(l.Root, "Cargo.toml")
+	if !exists(path) {
+		return
+	}
+	l.Languages = appendUnique(l.Languages, "rust")
+
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "[workspace]") {
+		return
+	}
+
+	l.Kinds = append(l.Kinds, KindCargoWorkspace)
+	l.Manifests = append(l.Manifests, "Cargo.toml")
+	if _, rest, ok := strings.Cut(string(data), "members"); ok {
+		if start := strings.Index(rest, "["); start >= 0 {
+			if end := strings.Index(rest[start:], "]"); end >= 0 {
+				for _, member := range strings.Split(rest[start+1:start+end], ",") {
+					if member = strings.Trim(strings.TrimSpace(member), `"'`); member != "" {
+						l.Workspaces = append(l.Workspaces, member)
+					}
+				}
+			}
+		}
+	}
+}
+
+// yamlList reads a top-level list of strings (e.g. "packages:") from a simple YAML file
+func yamlList(path, key string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var values []string
+	inList := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, key+":"):
+			inList = true
+		case inList && strings.HasPrefix(trimmed, "- "):
+			values = append(values, strings.Trim(strings.TrimSpace(trimmed[2:]), `"'`))
+		case inList && trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			inList = false
+		}
+	}
+	return values
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// appendUnique appends s to list unless already present
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,219 @@
+package projectlock
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"devos/internal/config"
+	"devos/internal/timefmt"
+)
+
+// Holder is the session holding a project's lock
+type Holder struct {
+	Project string    `json:"project"`
+	Session string    `json:"session"`
+	Command string    `json:"command"` // How the session was started, e.g. "devos daemon"
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	User    string    `json:"user"`
+	Task    string    `json:"task"`
+	Since   time.Time `json:"since"`
+}
+
+// LockedError reports that another session is executing a plan in the project
+type LockedError struct {
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	h := e.Holder
+	return fmt.Sprintf("%s is locked by session %s (%s, pid %d on %s, since %s, running %q); "+
+		"wait for it to finish, or run \"devos force-unlock %s\" if it is stuck",
+		h.Project, h.Session, h.Command, h.PID, h.Host, timefmt.DateTime(h.Since), h.Task, h.Project)
+}
+
+// session identifies this process in the locks it holds
+var session = newSession()
+
+// newSession returns a short random session ID
+func newSession() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprint(os.Getpid())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Session returns this process's session ID
+func Session() string {
+	return session
+}
+
+// Dir returns the directory holding project locks, shared by every session
+// using the same configuration (including the daemon)
+func Dir(cfg *config.Config) string {
+	return filepath.Join(filepath.Dir(cfg.ConfigPath), "locks")
+}
+
+// path returns the lock file for a project root
+func path(dir, project string) string {
+	sum := sha256.Sum256([]byte(project))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Acquire locks a project for this session while it runs task. It returns
+// a *LockedError if a live session already holds the lock; locks left by
+// processes that have exited are taken over. Locking a project this session
+// already holds succeeds, and only the outermost release unlocks it.
+func Acquire(dir, project, task string) (release func(), err error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	holder := Holder{
+		Project: project,
+		Session: session,
+		Command: command(),
+		PID:     os.Getpid(),
+		Task:    task,
+		Since:   timefmt.Now(),
+	}
+	holder.Host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		holder.User = u.Username
+	}
+	data, err := json.MarshalIndent(holder, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	file := path(dir, project)
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(file)
+				return nil, fmt.Errorf("failed to write lock: %w", err)
+			}
+			return func() { os.Remove(file) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock: %w", err)
+		}
+
+		current, err := read(file)
+		if err != nil {
+			// Unreadable locks are being written or are corrupt; only the
+			// latter is worth taking over
+			if info, statErr := os.Stat(file); statErr == nil && time.Since(info.ModTime()) < time.Minute {
+				return nil, fmt.Errorf("%s is being locked by another session", project)
+			}
+		} else if current.Session == session {
+			return func() {}, nil
+		} else if alive(current) {
+			return nil, &LockedError{Holder: *current}
+		}
+		os.Remove(file)
+	}
+	return nil, fmt.Errorf("failed to lock %s: another session took the lock", project)
+}
+
+// Get returns the lock on a project, or nil if it is not locked
+func Get(dir, project string) (*Holder, error) {
+	h, err := read(path(dir, project))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return h, err
+}
+
+// List returns the current locks, oldest first. Locks of exited processes
+// are removed instead.
+func List(dir string) ([]Holder, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var holders []Holder
+	for _, file := range files {
+		h, err := read(file)
+		if err != nil {
+			continue
+		}
+		if !alive(h) {
+			os.Remove(file)
+			continue
+		}
+		holders = append(holders, *h)
+	}
+	sort.Slice(holders, func(i, j int) bool { return holders[i].Since.Before(holders[j].Since) })
+	return holders, nil
+}
+
+// ForceUnlock removes a project's lock whoever holds it, returning the
+// holder it removed, or nil if the project was not locked
+func ForceUnlock(dir, project string) (*Holder, error) {
+	h, err := Get(dir, project)
+	if h == nil {
+		return nil, err
+	}
+	if err := os.Remove(path(dir, project)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove lock: %w", err)
+	}
+	return h, nil
+}
+
+// read parses a lock file
+func read(file string) (*Holder, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var h Holder
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %w", file, err)
+	}
+	return &h, nil
+}
+
+// alive reports whether the lock holder's process is still running. Holders
+// on other hosts cannot be checked and are assumed to be.
+func alive(h *Holder) bool {
+	if host, _ := os.Hostname(); h.Host != host {
+		return true
+	}
+	process, err := os.FindProcess(h.PID)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens the process on Windows, so it exists
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// command describes how this process was started
+func command() string {
+	if len(os.Args) < 2 {
+		return "devos REPL"
+	}
+	return "devos " + strings.Join(os.Args[1:], " ")
+}
@@ -0,0 +1,119 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// fileCreators match commands that create files, capturing the path
+var fileCreators = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|[;&|]\s*)touch\s+['"]?([^\s'";&|]+)`),
+	regexp.MustCompile(`[^>2&]>\s*['"]?([^\s'";&|>]+)`),
+	regexp.MustCompile(`\btee\s+(?:-a\s+)?['"]?([^\s'";&|]+)`),
+	regexp.MustCompile(`(?i)New-Item\b.*-ItemType\s+File\b.*-Path\s+['"]?([^\s'";]+)`),
+	regexp.MustCompile(`(?i)New-Item\b.*-Path\s+['"]?([^\s'";]+)['"]?.*-ItemType\s+File\b`),
+}
+
+// commentStyles maps source file extensions to their line comment prefix
+var commentStyles = map[string]string{
+	".go": "//", ".js": "//", ".jsx": "//", ".ts": "//", ".tsx": "//", ".java": "//",
+	".c": "//", ".h": "//", ".cc": "//", ".cpp": "//", ".cs": "//", ".rs": "//",
+	".swift": "//", ".kt": "//", ".scala": "//", ".dart": "//", ".php": "//",
+	".py": "#", ".rb": "#", ".sh": "#", ".bash": "#", ".zsh": "#", ".pl": "#",
+	".r": "#", ".yaml": "#", ".yml": "#", ".toml": "#", ".ps1": "#", ".tf": "#",
+	".sql": "--", ".lua": "--", ".hs": "--",
+}
+
+// createdFiles returns source files that commands would create and that do
+// not exist yet
+func createdFiles(commands []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, cmd := range commands {
+		for _, pattern := range fileCreators {
+			for _, m := range pattern.FindAllStringSubmatch(cmd, -1) {
+				path := m[1]
+				if seen[path] || commentStyles[strings.ToLower(filepath.Ext(path))] == "" {
+					continue
+				}
+				seen[path] = true
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					files = append(files, path)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// recordProvenance stamps files generated by a plan with the configured
+// header and records who generated them, with what model and prompt, in the
+// audit log
+func (e *Executor) recordProvenance(prompt string, files []string) {
+	promptHash := ""
+	if prompt != "" {
+		sum := sha256.Sum256([]byte(prompt))
+		promptHash = hex.EncodeToString(sum[:])
+	}
+
+	for _, path := range files {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		if e.config.GeneratedHeader != "" {
+			if err := e.insertHeader(path); err != nil {
+				e.logger.Warn("Failed to add header to %s: %v", path, err)
+			}
+		}
+
+		e.audit.Record("file_generated", map[string]string{
+			"path":        path,
+			"provider":    e.config.AIProvider,
+			"model":       e.config.Model,
+			"prompt_hash": promptHash,
+		})
+	}
+}
+
+// insertHeader prepends the configured header to path as comments, after a
+// shebang line if there is one
+func (e *Executor) insertHeader(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	prefix := commentStyles[strings.ToLower(filepath.Ext(path))]
+	header := strings.NewReplacer(
+		"{model}", e.config.Model,
+		"{provider}", e.config.AIProvider,
+		"{date}", time.Now().Format("2006-01-02"),
+	).Replace(e.config.GeneratedHeader)
+
+	var b strings.Builder
+	content := string(data)
+	if strings.HasPrefix(content, "#!") {
+		shebang, rest, _ := strings.Cut(content, "\n")
+		b.WriteString(shebang + "\n")
+		content = rest
+	}
+	for _, line := range strings.Split(header, "\n") {
+		fmt.Fprintf(&b, "%s %s\n", prefix, line)
+	}
+	if content != "" {
+		b.WriteString("\n" + content)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), info.Mode())
+}
@@ -0,0 +1,330 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"devos/internal/config"
+)
+
+// ReleaseFile is a project's release process, relative to its root; it
+// overrides the "release" config step by step
+const ReleaseFile = ".devos/release.json"
+
+// releaseVersion matches a semantic version, optionally written with a v
+var releaseVersion = regexp.MustCompile(`^v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)$`)
+
+// releasePlaceholder matches {{version}} and {{tag}} in release commands
+var releasePlaceholder = regexp.MustCompile(`\{\{\s*(version|tag)\s*\}\}`)
+
+// versionLine matches the version a manifest declares, by file
+var versionLine = map[string]*regexp.Regexp{
+	"Cargo.toml":     regexp.MustCompile(`(?m)^version = "`),
+	"pyproject.toml": regexp.MustCompile(`(?m)^version = "`),
+	"Chart.yaml":     regexp.MustCompile(`(?m)^version: `),
+}
+
+// ReleaseStep is one step of a release, approved and run on its own. The
+// changelog step adds Notes to the changelog instead of running commands.
+type ReleaseStep struct {
+	Name        string // bump, changelog, tag, build, or publish
+	Description string
+	Commands    []string
+	Undo        []string // Reverses each command, or "" for none; for the changelog step, the notes
+	Notes       string
+}
+
+// Release is the plan for releasing a version of the project
+type Release struct {
+	Version   string
+	Tag       string
+	Root      string
+	Changelog string // Path of the changelog the notes are added to
+	Source    string // Where the release process is configured: ReleaseFile, the config, or "detected"
+	Steps     []ReleaseStep
+	Warnings  []string
+}
+
+// ReleaseVersion returns the version an argument such as "1.4.0" or
+// "v1.4.0" names, without the v, and whether it is a semantic version
+func ReleaseVersion(s string) (string, bool) {
+	m := releaseVersion.FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// PlanRelease plans a release of the git repository the working directory
+// is in: bump the version, add release notes to the changelog, commit and
+// tag, build, and publish. Each step's commands come from the project's
+// ReleaseFile, the "release" config, or what DevOS detects, in that order.
+// The working tree must be clean, so the release commit holds only the
+// release's changes, and the tag must not exist yet.
+func (e *Executor) PlanRelease(ctx context.Context, version string) (*Release, error) {
+	v, ok := ReleaseVersion(version)
+	if !ok {
+		return nil, fmt.Errorf("not a semantic version: %s (expected e.g. 1.4.0)", version)
+	}
+	repo := Repo(ctx)
+	if repo == nil {
+		return nil, fmt.Errorf("not in a git repository")
+	}
+	if repo.Status != "" {
+		return nil, fmt.Errorf("the working tree has uncommitted changes; commit or stash them before cutting a release")
+	}
+	process, source, err := e.releaseProcess(repo.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &Release{Version: v, Root: repo.Root, Source: source}
+	tag := process.Tag
+	if tag == "" {
+		tag = "v{{version}}"
+	}
+	rel.Tag = releasePlaceholder.ReplaceAllLiteralString(tag, v)
+	if _, err := git(ctx, repo.Root, "rev-parse", "-q", "--verify", "refs/tags/"+rel.Tag); err == nil {
+		return nil, fmt.Errorf("tag %s already exists", rel.Tag)
+	}
+	changelog := process.Changelog
+	if changelog == "" {
+		changelog = "CHANGELOG.md"
+	}
+	rel.Changelog = filepath.Join(repo.Root, changelog)
+
+	message := Quote("Release " + v)
+	for _, name := range config.ReleaseSteps {
+		if slices.Contains(process.Skip, name) {
+			continue
+		}
+		step := ReleaseStep{Name: name}
+		switch name {
+		case "bump":
+			step.Description = "Set the version to " + v
+			if step.Commands = process.Bump; len(step.Commands) == 0 {
+				step.Commands, step.Undo = detectBump(repo.Root)
+			}
+			if len(step.Commands) == 0 {
+				rel.Warnings = append(rel.Warnings, "No version to bump was found; the tag alone records the version (set release.bump to bump one)")
+				continue
+			}
+		case "changelog":
+			step.Description = "Add release notes to " + changelog
+			step.Notes = e.releaseNotes(ctx, rel)
+			if _, err := os.Stat(rel.Changelog); err == nil {
+				step.Undo = []string{"git checkout HEAD -- " + Quote(changelog)}
+			} else {
+				step.Undo = []string{"rm -f " + Quote(changelog)}
+			}
+		case "tag":
+			step.Description = "Commit the release and tag it " + rel.Tag
+			step.Commands = []string{"git add -A", "git commit -m " + message, "git tag -a " + Quote(rel.Tag) + " -m " + message}
+			step.Undo = []string{"git reset -q", "git reset --soft HEAD~1", "git tag -d " + Quote(rel.Tag)}
+		case "build":
+			step.Description = "Build the release"
+			if step.Commands = process.Build; len(step.Commands) == 0 {
+				step.Commands = detectBuild(repo.Root)
+			}
+			if len(step.Commands) == 0 {
+				rel.Warnings = append(rel.Warnings, "No build was found to run (set release.build to build the release)")
+				continue
+			}
+		case "publish":
+			step.Description = "Publish the release"
+			if step.Commands = process.Publish; len(step.Commands) == 0 {
+				step.Commands = []string{"git push --follow-tags"}
+			}
+		}
+		step.Commands = expandRelease(step.Commands, rel)
+		rel.Steps = append(rel.Steps, step)
+	}
+
+	var commands []string
+	for _, step := range rel.Steps {
+		commands = append(commands, step.Commands...)
+		for _, undo := range step.Undo {
+			if undo != "" {
+				commands = append(commands, undo)
+			}
+		}
+	}
+	if err := e.Validate(commands); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// RunReleaseStep runs step i of a release, returning the plan it ran with
+// the commands that reverse it
+func (e *Executor) RunReleaseStep(ctx context.Context, rel *Release, i int) (*ExecutionResult, error) {
+	step := rel.Steps[i]
+	result := &ExecutionResult{Commands: step.Commands, Undo: step.Undo, Prompt: "release " + rel.Version}
+	if step.Name == "changelog" {
+		heading := fmt.Sprintf("[%s] - %s", rel.Version, time.Now().Format("2006-01-02"))
+		if err := addChangelogSection(rel.Changelog, heading, step.Notes); err != nil {
+			return result, err
+		}
+		result.Rollback = step.Undo
+		return result, nil
+	}
+	err := e.ExecutePlan(ctx, result)
+	return result, err
+}
+
+// releaseProcess returns the release process configured for a project: its
+// ReleaseFile over the "release" config, step by step, and where it came from
+func (e *Executor) releaseProcess(root string) (*config.Release, string, error) {
+	process := config.Release{}
+	source := "detected"
+	if e.config.Release != nil {
+		process, source = *e.config.Release, "config"
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, ReleaseFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &process, source, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", ReleaseFile, err)
+	}
+	var project config.Release
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, "", fmt.Errorf("invalid %s: %w", ReleaseFile, err)
+	}
+	if err := project.Validate(); err != nil {
+		return nil, "", fmt.Errorf("invalid %s: %w", ReleaseFile, err)
+	}
+	if project.Tag != "" {
+		process.Tag = project.Tag
+	}
+	if len(project.Bump) > 0 {
+		process.Bump = project.Bump
+	}
+	if project.Changelog != "" {
+		process.Changelog = project.Changelog
+	}
+	if len(project.Build) > 0 {
+		process.Build = project.Build
+	}
+	if len(project.Publish) > 0 {
+		process.Publish = project.Publish
+	}
+	if project.Skip != nil {
+		process.Skip = project.Skip
+	}
+	return &process, ReleaseFile, nil
+}
+
+// releaseNotes writes the release notes for the commits since the last
+// tag, asking the AI engine and falling back to the commit subjects
+func (e *Executor) releaseNotes(ctx context.Context, rel *Release) string {
+	since := "HEAD"
+	last, err := git(ctx, rel.Root, "describe", "--tags", "--abbrev=0")
+	if err == nil {
+		since = last + "..HEAD"
+	}
+	log, _ := git(ctx, rel.Root, "log", "--no-merges", "--format=%s", since)
+	if log == "" {
+		rel.Warnings = append(rel.Warnings, "No commits since the last release "+last)
+		return "- No changes"
+	}
+	fallback := "- " + strings.Join(lines(log), "\n- ")
+
+	stat := ""
+	if last != "" {
+		stat, _ = git(ctx, rel.Root, "diff", "--stat", last, "HEAD")
+	}
+	e.logger.Info("Writing release notes for %s", rel.Version)
+	notes, err := e.callAIEngine(ctx, summaryInstructions[SummaryChangelog], map[string]interface{}{
+		"task":          "release " + rel.Version,
+		"git_commits":   lines(log),
+		"git_diff_stat": stat,
+	})
+	if err != nil {
+		e.logger.Warn("Failed to write release notes: %v", err)
+		rel.Warnings = append(rel.Warnings, "The release notes list the commits, as the AI engine could not write them")
+		return fallback
+	}
+	if strings.TrimSpace(notes.Output) == "" {
+		return fallback
+	}
+	return strings.TrimSpace(notes.Output)
+}
+
+// detectBump returns the commands that set the version in the project's
+// manifests, and those that restore them
+func detectBump(root string) (commands, undo []string) {
+	add := func(cmd string, files ...string) {
+		commands = append(commands, cmd)
+		undo = append(undo, "git checkout HEAD -- "+strings.Join(files, " "))
+	}
+	if fileExists(filepath.Join(root, "package.json")) {
+		files := []string{"package.json"}
+		if fileExists(filepath.Join(root, "package-lock.json")) {
+			files = append(files, "package-lock.json")
+		}
+		add("npm version {{version}} --no-git-tag-version --allow-same-version", files...)
+	}
+	for _, file := range []string{"Cargo.toml", "pyproject.toml"} {
+		if data, err := os.ReadFile(filepath.Join(root, file)); err == nil && versionLine[file].Match(data) {
+			add(fmt.Sprintf(`sed -i.bak -e 's/^version = ".*"/version = "{{version}}"/' %s && rm %s.bak`, file, file), file)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "Chart.yaml")); err == nil && versionLine["Chart.yaml"].Match(data) {
+		add(`sed -i.bak -e 's/^version: .*/version: {{version}}/' Chart.yaml && rm Chart.yaml.bak`, "Chart.yaml")
+	}
+	if fileExists(filepath.Join(root, "VERSION")) {
+		add(`printf '%s\n' {{version}} > VERSION`, "VERSION")
+	}
+	return commands, undo
+}
+
+// detectBuild returns the commands that build the project, by ecosystem,
+// falling back to make
+func detectBuild(root string) []string {
+	var commands []string
+	if data, err := os.ReadFile(filepath.Join(root, "package.json")); err == nil {
+		var manifest struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if json.Unmarshal(data, &manifest) == nil && manifest.Scripts["build"] != "" {
+			commands = append(commands, "npm run build")
+		}
+	}
+	if fileExists(filepath.Join(root, "go.mod")) {
+		commands = append(commands, "go build ./...")
+	}
+	if fileExists(filepath.Join(root, "Cargo.toml")) {
+		commands = append(commands, "cargo build --release")
+	}
+	if fileExists(filepath.Join(root, "pyproject.toml")) {
+		commands = append(commands, "python3 -m build")
+	}
+	if len(commands) == 0 && fileExists(filepath.Join(root, "Makefile")) {
+		commands = append(commands, "make")
+	}
+	return commands
+}
+
+// expandRelease fills in {{version}} and {{tag}} in release commands
+func expandRelease(commands []string, rel *Release) []string {
+	expanded := make([]string, len(commands))
+	for i, cmd := range commands {
+		expanded[i] = releasePlaceholder.ReplaceAllStringFunc(cmd, func(m string) string {
+			if releasePlaceholder.FindStringSubmatch(m)[1] == "tag" {
+				return Quote(rel.Tag)
+			}
+			return rel.Version
+		})
+	}
+	return expanded
+}
@@ -0,0 +1,137 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"devos/internal/config"
+)
+
+// HostResult is the outcome of running a plan on one remote target
+type HostResult struct {
+	Target  string
+	Canary  bool
+	Skipped bool
+	Err     error
+}
+
+// Rollout runs commands on remote targets over SSH. The canary target runs
+// first and must pass the health check before the remaining targets proceed;
+// on canary failure the rollout aborts and the other targets are skipped.
+func (e *Executor) Rollout(ctx context.Context, commands []string, targets []config.Target) ([]HostResult, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets configured")
+	}
+	if err := e.Validate(commands); err != nil {
+		return nil, err
+	}
+
+	canary := 0
+	for i, t := range targets {
+		if t.Canary {
+			canary = i
+			break
+		}
+	}
+
+	results := make([]HostResult, 0, len(targets))
+	canaryTarget := targets[canary]
+
+	fmt.Printf("\n🐤 Canary: %s\n", canaryTarget.Name)
+	err := e.runOnTarget(ctx, canaryTarget, commands)
+	if err == nil {
+		err = e.healthCheck(ctx, canaryTarget)
+	}
+	results = append(results, HostResult{Target: canaryTarget.Name, Canary: true, Err: err})
+
+	if err != nil {
+		e.logger.Error("Canary %s failed, aborting rollout: %v", canaryTarget.Name, err)
+		e.audit.Record("rollout_aborted", map[string]string{"canary": canaryTarget.Name, "error": err.Error()})
+		for i, t := range targets {
+			if i != canary {
+				results = append(results, HostResult{Target: t.Name, Skipped: true})
+			}
+		}
+		return results, fmt.Errorf("canary %s failed: %w", canaryTarget.Name, err)
+	}
+
+	var failed []string
+	for i, t := range targets {
+		if i == canary {
+			continue
+		}
+		fmt.Printf("\n🖥️  Target: %s\n", t.Name)
+		err := e.runOnTarget(ctx, t, commands)
+		if err != nil {
+			failed = append(failed, t.Name)
+		}
+		results = append(results, HostResult{Target: t.Name, Err: err})
+	}
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("rollout failed on: %s", strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// runOnTarget executes commands on a single target, stopping at the first failure
+func (e *Executor) runOnTarget(ctx context.Context, target config.Target, commands []string) error {
+	for i, cmdStr := range commands {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("rollout interrupted on %s: %w", target.Name, err)
+		}
+
+		e.logger.Info("Executing command %d/%d on %s: %s", i+1, len(commands), target.Name, cmdStr)
+
+		output, err := e.executeRemoteCommand(ctx, target, cmdStr)
+		e.recordExecution(target.Name+": "+cmdStr, err)
+		if err != nil {
+			e.logger.Error("Command failed on %s: %s - Error: %v", target.Name, cmdStr, err)
+			return fmt.Errorf("command failed: %s - %w", cmdStr, err)
+		}
+
+		if output != "" {
+			e.showOutput(cmdStr, output)
+		}
+	}
+	return nil
+}
+
+// healthCheck runs the configured health check command on a target
+func (e *Executor) healthCheck(ctx context.Context, target config.Target) error {
+	check := target.HealthCheck
+	if check == "" {
+		check = e.config.CanaryHealthCheck
+	}
+	if check == "" {
+		return nil
+	}
+
+	fmt.Printf("  🩺 Health check: %s\n", check)
+	if _, err := e.executeRemoteCommand(ctx, target, check); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	return nil
+}
+
+// executeRemoteCommand runs a shell command on a target via ssh
+func (e *Executor) executeRemoteCommand(ctx context.Context, target config.Target, cmdStr string) (string, error) {
+	args := []string{"-o", "BatchMode=yes"}
+	if target.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(target.Port))
+	}
+	if target.IdentityFile != "" {
+		args = append(args, "-i", target.IdentityFile)
+	}
+
+	host := target.Host
+	if target.User != "" {
+		host = target.User + "@" + host
+	}
+	args = append(args, host, "--", cmdStr)
+
+	return runProcess(exec.CommandContext(ctx, "ssh", args...), cmdStr)
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"devos/internal/memory"
+	"devos/internal/timefmt"
+)
+
+// manualSecondsPerCommand estimates how long a developer spends looking up
+// and typing a command by hand; used for time-saved estimates
+const manualSecondsPerCommand = 90
+
+// CategoryCount is the number of tasks in a category
+type CategoryCount struct {
+	Category string
+	Count    int
+}
+
+// Report summarizes DevOS activity over a period
+type Report struct {
+	Since            time.Time
+	Until            time.Time
+	Tasks            int
+	Succeeded        int
+	Commands         int
+	TimeSaved        time.Duration
+	TokensByProvider map[string]int
+	Categories       []CategoryCount
+}
+
+// Build aggregates recorded tasks into a report
+func Build(tasks []memory.Task, since, until time.Time) *Report {
+	r := &Report{
+		Since:            since,
+		Until:            until,
+		TokensByProvider: make(map[string]int),
+	}
+
+	categories := make(map[string]int)
+	for _, t := range tasks {
+		r.Tasks++
+		r.Commands += t.Commands
+		r.TokensByProvider[t.Provider] += t.Tokens
+		categories[categoryName(t.Category)]++
+
+		if t.Success {
+			r.Succeeded++
+			manual := time.Duration(t.Commands*manualSecondsPerCommand) * time.Second
+			if manual > t.Duration {
+				r.TimeSaved += manual - t.Duration
+			}
+		}
+	}
+
+	for name, count := range categories {
+		r.Categories = append(r.Categories, CategoryCount{Category: name, Count: count})
+	}
+	sort.Slice(r.Categories, func(i, j int) bool {
+		if r.Categories[i].Count != r.Categories[j].Count {
+			return r.Categories[i].Count > r.Categories[j].Count
+		}
+		return r.Categories[i].Category < r.Categories[j].Category
+	})
+
+	return r
+}
+
+// SuccessRate returns the percentage of successful tasks
+func (r *Report) SuccessRate() float64 {
+	if r.Tasks == 0 {
+		return 0
+	}
+	return float64(r.Succeeded) / float64(r.Tasks) * 100
+}
+
+// Terminal renders the report for interactive display
+func (r *Report) Terminal() string {
+	var b strings.Builder
+
+	b.WriteString("\n📈 DevOS Activity Report\n")
+	b.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Fprintf(&b, "  Period:          %s → %s (%s)\n", timefmt.Date(r.Since), timefmt.Date(r.Until), timefmt.Zone(r.Until))
+	fmt.Fprintf(&b, "  Tasks Run:       %d\n", r.Tasks)
+	fmt.Fprintf(&b, "  Success Rate:    %.1f%%\n", r.SuccessRate())
+	fmt.Fprintf(&b, "  Commands:        %d\n", r.Commands)
+	fmt.Fprintf(&b, "  Time Saved:      ~%s\n", formatDuration(r.TimeSaved))
+
+	b.WriteString("\n  Token Spend by Provider:\n")
+	for _, provider := range r.providers() {
+		fmt.Fprintf(&b, "    %-16s %d\n", provider, r.TokensByProvider[provider])
+	}
+
+	b.WriteString("\n  Top Task Categories:\n")
+	for _, c := range r.topCategories() {
+		fmt.Fprintf(&b, "    %-16s %d\n", c.Category, c.Count)
+	}
+	b.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	return b.String()
+}
+
+// Markdown renders the report as a Markdown document
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# DevOS Activity Report\n\n")
+	fmt.Fprintf(&b, "_%s to %s (%s)_\n\n", timefmt.Date(r.Since), timefmt.Date(r.Until), timefmt.Zone(r.Until))
+
+	b.WriteString("| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Tasks run | %d |\n", r.Tasks)
+	fmt.Fprintf(&b, "| Success rate | %.1f%% |\n", r.SuccessRate())
+	fmt.Fprintf(&b, "| Commands executed | %d |\n", r.Commands)
+	fmt.Fprintf(&b, "| Estimated time saved | %s |\n\n", formatDuration(r.TimeSaved))
+
+	b.WriteString("## Token Spend by Provider\n\n| Provider | Tokens |\n|---|---|\n")
+	for _, provider := range r.providers() {
+		fmt.Fprintf(&b, "| %s | %d |\n", provider, r.TokensByProvider[provider])
+	}
+
+	b.WriteString("\n## Most Common Task Categories\n\n| Category | Tasks |\n|---|---|\n")
+	for _, c := range r.topCategories() {
+		fmt.Fprintf(&b, "| %s | %d |\n", c.Category, c.Count)
+	}
+
+	return b.String()
+}
+
+// providers returns provider names in a stable order
+func (r *Report) providers() []string {
+	providers := make([]string, 0, len(r.TokensByProvider))
+	for provider := range r.TokensByProvider {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// topCategories returns the five most common categories
+func (r *Report) topCategories() []CategoryCount {
+	if len(r.Categories) > 5 {
+		return r.Categories[:5]
+	}
+	return r.Categories
+}
+
+// categoryName normalizes an empty category
+func categoryName(category string) string {
+	if category == "" {
+		return "general"
+	}
+	return category
+}
+
+// formatDuration renders a duration rounded to minutes
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
@@ -0,0 +1,229 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Error classes a policy can retry
+const (
+	ClassTimeout   = "timeout"    // The operation or a connection timed out
+	ClassNetwork   = "network"    // A connection was refused, reset, or could not resolve
+	ClassRateLimit = "rate_limit" // HTTP 429 or a rate limit message
+	ClassServer    = "server"     // HTTP 5xx, or the service is overloaded
+)
+
+// Classes lists the error classes
+var Classes = []string{ClassTimeout, ClassNetwork, ClassRateLimit, ClassServer}
+
+// Policy says how often and how patiently a failed operation is retried
+type Policy struct {
+	Attempts   int      `json:"attempts,omitempty"`    // Tries in all, including the first; 1 disables retries
+	Backoff    float64  `json:"backoff,omitempty"`     // Seconds before the first retry, doubling after each
+	MaxBackoff float64  `json:"max_backoff,omitempty"` // Longest wait between tries, in seconds
+	Jitter     float64  `json:"jitter,omitempty"`      // Fraction of each wait that is random, 0 to 1
+	Retryable  []string `json:"retryable,omitempty"`   // Error classes retried; see Classes
+
+	// Regular expressions for failures to retry whatever their class,
+	// matched against the error message (for commands, their stderr), e.g.
+	// "Could not get lock" for apt-get waiting on another package manager
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// Default retries transient failures twice, about 1 and 2 seconds apart
+var Default = Policy{Attempts: 3, Backoff: 1, MaxBackoff: 30, Jitter: 0.5, Retryable: Classes}
+
+// Merge returns p with the fields set in override replacing its own
+func (p Policy) Merge(override Policy) Policy {
+	if override.Attempts != 0 {
+		p.Attempts = override.Attempts
+	}
+	if override.Backoff != 0 {
+		p.Backoff = override.Backoff
+	}
+	if override.MaxBackoff != 0 {
+		p.MaxBackoff = override.MaxBackoff
+	}
+	if override.Jitter != 0 {
+		p.Jitter = override.Jitter
+	}
+	if override.Retryable != nil {
+		p.Retryable = override.Retryable
+	}
+	if override.Patterns != nil {
+		p.Patterns = override.Patterns
+	}
+	return p
+}
+
+// Validate checks a policy's fields
+func (p Policy) Validate() error {
+	if p.Attempts < 0 || p.Backoff < 0 || p.MaxBackoff < 0 {
+		return errors.New("attempts, backoff, and max_backoff must not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1, not %g", p.Jitter)
+	}
+	for _, class := range p.Retryable {
+		if !slices.Contains(Classes, class) {
+			return fmt.Errorf("unknown error class %q (expected %s)", class, strings.Join(Classes, ", "))
+		}
+	}
+	for _, pattern := range p.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Retries reports whether the policy retries a failure: its class is one
+// the policy retries, or its message matches one of the patterns
+func (p Policy) Retries(class, message string) bool {
+	if class != "" && slices.Contains(p.Retryable, class) {
+		return true
+	}
+	for _, pattern := range p.Patterns {
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns how long to wait before retry n (counting from 1): the
+// backoff doubled for each earlier retry, capped, with the jitter fraction
+// of it chosen at random
+func (p Policy) Delay(n int) time.Duration {
+	wait := p.Backoff * math.Pow(2, float64(n-1))
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	wait -= wait * p.Jitter * rand.Float64()
+	return time.Duration(wait * float64(time.Second))
+}
+
+// Do runs fn until it succeeds, fails with an error the policy does not
+// retry (by class or pattern), or runs out of attempts, and returns its last error. classify maps
+// errors to classes (nil uses Classify); notify, if not nil, is told about
+// each retry before the wait.
+func Do(ctx context.Context, p Policy, classify func(error) string, notify func(attempt int, err error, wait time.Duration), fn func(ctx context.Context) error) error {
+	if classify == nil {
+		classify = Classify
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil {
+			return err
+		}
+		if !p.Retries(classify(err), err.Error()) {
+			return err
+		}
+
+		wait := p.Delay(attempt)
+		if notify != nil {
+			notify(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// statusError is an error carrying an HTTP status code
+type statusError interface {
+	HTTPStatus() int
+}
+
+// marked is an error whose class is already known
+type marked struct {
+	class string
+	err   error
+}
+
+func (m *marked) Error() string { return m.err.Error() }
+func (m *marked) Unwrap() error { return m.err }
+
+// Mark records the class of an error that Classify cannot tell from its
+// type, such as one reported as text; an empty class leaves err unchanged
+func Mark(err error, class string) error {
+	if class == "" || err == nil {
+		return err
+	}
+	return &marked{class: class, err: err}
+}
+
+// Classify returns the class of an error, or "" if it is not transient
+func Classify(err error) string {
+	var m *marked
+	if errors.As(err, &m) {
+		return m.class
+	}
+	var status statusError
+	if errors.As(err, &status) {
+		return StatusClass(status.HTTPStatus())
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.ErrUnexpectedEOF):
+		return ClassNetwork
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) {
+		return ClassNetwork
+	}
+	return ""
+}
+
+// StatusClass returns the class of an HTTP status, or "" if it is not
+// transient
+func StatusClass(code int) string {
+	switch {
+	case code == 429:
+		return ClassRateLimit
+	case code == 408:
+		return ClassTimeout
+	case code >= 500:
+		return ClassServer
+	}
+	return ""
+}
+
+// Messages of transient failures from tools and services, by class
+var textClasses = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{ClassRateLimit, regexp.MustCompile(`(?i)\b429\b|rate.?limit|too many requests`)},
+	{ClassServer, regexp.MustCompile(`(?i)\b50[0234]\b|service unavailable|bad gateway|overloaded`)},
+	{ClassTimeout, regexp.MustCompile(`(?i)timed out|timeout`)},
+	{ClassNetwork, regexp.MustCompile(`(?i)connection (refused|reset)|could not resolve|temporary failure (in name resolution|resolving)|(could not|unable to) connect to|name or service not known|network is unreachable|no route to host|unexpected eof`)},
+}
+
+// ClassifyText returns the class of a failure from its message, for errors
+// that only arrive as text, such as a tool's stderr
+func ClassifyText(message string) string {
+	for _, t := range textClasses {
+		if t.pattern.MatchString(message) {
+			return t.class
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"devos/internal/pkgmgr"
+)
+
+// rollbackCommands returns the commands undoing package changes: packages
+// the plan installed are removed, then removed or changed ones are
+// reinstalled at their earlier versions
+func rollbackCommands(changes []PackageChange) []string {
+	installed := map[string][]string{}         // Manager → new packages
+	restored := map[string]map[string]string{} // Manager → package → earlier version
+	for _, c := range changes {
+		if c.Before == "" {
+			installed[c.Manager] = append(installed[c.Manager], c.Name)
+			continue
+		}
+		if restored[c.Manager] == nil {
+			restored[c.Manager] = map[string]string{}
+		}
+		restored[c.Manager][c.Name] = c.Before
+	}
+
+	var removals, restores []string
+	for _, manager := range managerNames(changes) {
+		if packages := installed[manager]; len(packages) > 0 {
+			if cmd := removeCommand(manager, packages); cmd != "" {
+				removals = append(removals, cmd)
+			}
+		}
+		if versions := restored[manager]; len(versions) > 0 {
+			restores = append(restores, restoreCommands(manager, versions)...)
+		}
+	}
+	return append(removals, restores...)
+}
+
+// removeCommand uninstalls packages with one manager
+func removeCommand(manager string, packages []string) string {
+	sort.Strings(packages)
+	switch manager {
+	case "pip":
+		return "pip3 uninstall -y " + strings.Join(packages, " ")
+	case "npm":
+		return "npm uninstall -g " + strings.Join(packages, " ")
+	}
+	if m := pkgmgr.Lookup(manager); m != nil {
+		return m.RemoveCommand(packages...)
+	}
+	return ""
+}
+
+// restoreCommands installs packages at the given versions with one manager
+func restoreCommands(manager string, versions map[string]string) []string {
+	var specs []string
+	switch manager {
+	case "pip", "npm":
+		sep := "=="
+		if manager == "npm" {
+			sep = "@"
+		}
+		for _, name := range packageNames(versions) {
+			specs = append(specs, fmt.Sprintf("%s%s%s", name, sep, versions[name]))
+		}
+		if manager == "pip" {
+			return []string{"pip3 install " + strings.Join(specs, " ")}
+		}
+		return []string{"npm install -g " + strings.Join(specs, " ")}
+	}
+	if m := pkgmgr.Lookup(manager); m != nil {
+		return m.RestoreCommands(versions)
+	}
+	return nil
+}
+
+// managerNames returns the managers with changed packages, in order
+func managerNames(changes []PackageChange) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, c := range changes {
+		if !seen[c.Manager] {
+			seen[c.Manager] = true
+			names = append(names, c.Manager)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// packageNames returns the packages of a name → version map in order
+func packageNames(versions map[string]string) []string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
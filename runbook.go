@@ -0,0 +1,269 @@
+package runbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned for a runbook that is neither built in nor saved
+var ErrNotFound = errors.New("runbook not found")
+
+// ErrMissingParams is returned when required parameters are not given
+var ErrMissingParams = errors.New("missing runbook parameters")
+
+// Runbook is a parameterized sequence of commands for a routine operation.
+// Built-in runbooks can be customized by saving a copy under the runbooks
+// directory, which then takes precedence.
+type Runbook struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Keywords    []string `json:"keywords,omitempty"` // Phrases that suggest the runbook, e.g. "renew certificate"
+	Params      []Param  `json:"params,omitempty"`
+	Steps       []Step   `json:"steps"`
+
+	// Path is the file the runbook was loaded from; empty for built-ins
+	Path string `json:"-"`
+}
+
+// Param is a value substituted for {{name}} in step commands
+type Param struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Step is one command of a runbook
+type Step struct {
+	Description string `json:"description"`
+	Command     string `json:"command"`
+	If          string `json:"if,omitempty"` // Parameter that must be non-empty for the step to run
+}
+
+// placeholder matches {{name}} in step commands
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// Builtins returns the runbooks that ship with DevOS
+func Builtins() []Runbook {
+	return []Runbook{
+		{
+			Name:        "rotate-certificate",
+			Description: "Renew a Let's Encrypt certificate, reload the web server, and verify the served expiry date",
+			Keywords:    []string{"rotate certificate", "rotate cert", "renew certificate", "renew cert", "renew tls", "renew ssl"},
+			Params: []Param{
+				{Name: "domain", Description: "Certificate name, usually the primary domain", Required: true},
+				{Name: "service", Description: "Service to reload once the certificate is renewed", Default: "nginx"},
+				{Name: "port", Description: "Port serving the certificate, checked afterwards", Default: "443"},
+			},
+			Steps: []Step{
+				{Description: "Show the current expiry date", Command: "sudo openssl x509 -noout -enddate -in /etc/letsencrypt/live/{{domain}}/fullchain.pem"},
+				{Description: "Renew the certificate", Command: "sudo certbot renew --cert-name {{domain}} --force-renewal"},
+				{Description: "Reload the service", Command: "sudo systemctl reload {{service}}"},
+				{Description: "Verify the served certificate", Command: "echo | openssl s_client -connect {{domain}}:{{port}} -servername {{domain}} 2>/dev/null | openssl x509 -noout -enddate"},
+			},
+		},
+		{
+			Name:        "resize-volume",
+			Description: "Grow a partition and its filesystem after the underlying disk or cloud volume was enlarged",
+			Keywords:    []string{"resize volume", "resize disk", "grow volume", "grow disk", "extend volume", "extend disk", "expand volume", "expand disk", "grow filesystem"},
+			Params: []Param{
+				{Name: "device", Description: "Disk holding the partition, e.g. /dev/nvme1n1", Required: true},
+				{Name: "partition", Description: "Partition number to grow", Default: "1"},
+				{Name: "mount", Description: "Mount point of the filesystem", Default: "/"},
+			},
+			Steps: []Step{
+				{Description: "Show the current size", Command: "df -h {{mount}} && lsblk {{device}}"},
+				{Description: "Grow the partition", Command: "sudo growpart {{device}} {{partition}}"},
+				{Description: "Grow the filesystem", Command: `case "$(findmnt -n -o FSTYPE {{mount}})" in xfs) sudo xfs_growfs {{mount}} ;; ext2|ext3|ext4) sudo resize2fs "$(findmnt -n -o SOURCE {{mount}})" ;; btrfs) sudo btrfs filesystem resize max {{mount}} ;; *) echo "unsupported filesystem on {{mount}}" >&2; exit 1 ;; esac`},
+				{Description: "Verify the new size", Command: "df -h {{mount}}"},
+			},
+		},
+		{
+			Name:        "restart-service",
+			Description: "Restart a systemd service and wait until it is active and, if given, its health endpoint responds",
+			Keywords:    []string{"restart service", "restart the service", "bounce service", "restart and verify", "restart with health check"},
+			Params: []Param{
+				{Name: "service", Description: "systemd unit to restart", Required: true},
+				{Name: "health_url", Description: "URL that returns 2xx once the service is healthy"},
+				{Name: "attempts", Description: "How many times to check before giving up", Default: "10"},
+				{Name: "interval", Description: "Seconds between checks", Default: "3"},
+			},
+			Steps: []Step{
+				{Description: "Show the current state", Command: "systemctl status {{service}} --no-pager --lines=0 || true"},
+				{Description: "Restart the service", Command: "sudo systemctl restart {{service}}"},
+				{Description: "Wait until the service is active", Command: "for i in $(seq 1 {{attempts}}); do systemctl is-active --quiet {{service}} && exit 0; sleep {{interval}}; done; journalctl -u {{service}} -n 30 --no-pager; exit 1"},
+				{Description: "Wait until the health check passes", Command: "for i in $(seq 1 {{attempts}}); do curl -fsS -o /dev/null {{health_url}} && exit 0; sleep {{interval}}; done; echo \"health check failed: \"{{health_url}} >&2; journalctl -u {{service}} -n 30 --no-pager; exit 1", If: "health_url"},
+			},
+		},
+	}
+}
+
+// Load returns the built-in runbooks merged with those saved in dir, sorted
+// by name. A saved runbook replaces the built-in one of the same name.
+func Load(dir string) ([]Runbook, error) {
+	byName := map[string]Runbook{}
+	for _, r := range Builtins() {
+		byName[r.Name] = r
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		r, err := read(path)
+		if err != nil {
+			return nil, err
+		}
+		byName[r.Name] = *r
+	}
+
+	runbooks := make([]Runbook, 0, len(byName))
+	for _, r := range byName {
+		runbooks = append(runbooks, r)
+	}
+	sort.Slice(runbooks, func(i, j int) bool { return runbooks[i].Name < runbooks[j].Name })
+	return runbooks, nil
+}
+
+// Find returns the runbook called name from Load
+func Find(dir, name string) (*Runbook, error) {
+	runbooks, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range runbooks {
+		if r.Name == name {
+			return &runbooks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Customize saves a copy of a runbook in dir for the user to edit and
+// returns its path. A runbook that is already saved is left as it is.
+func Customize(dir, name string) (string, error) {
+	r, err := Find(dir, name)
+	if err != nil {
+		return "", err
+	}
+	if r.Path != "" {
+		return r.Path, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Instantiate renders the runbook's commands with params, falling back to
+// parameter defaults. Values are shell-quoted where needed, and steps whose
+// If parameter is empty are left out.
+func (r *Runbook) Instantiate(params map[string]string) ([]string, error) {
+	values := map[string]string{}
+	var missing []string
+	for _, p := range r.Params {
+		value, ok := params[p.Name]
+		if !ok || value == "" {
+			value = p.Default
+		}
+		if value == "" && p.Required {
+			missing = append(missing, p.Name)
+		}
+		values[p.Name] = value
+	}
+	for name := range params {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("runbook %s has no parameter %q", r.Name, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s needs %s", ErrMissingParams, r.Name, strings.Join(missing, ", "))
+	}
+
+	var commands []string
+	for _, step := range r.Steps {
+		if step.If != "" && values[step.If] == "" {
+			continue
+		}
+		commands = append(commands, placeholder.ReplaceAllStringFunc(step.Command, func(m string) string {
+			return quote(values[placeholder.FindStringSubmatch(m)[1]])
+		}))
+	}
+	return commands, nil
+}
+
+// Validate checks that every placeholder names a parameter and every step
+// has a command
+func (r *Runbook) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("runbook has no name")
+	}
+	params := map[string]bool{}
+	for _, p := range r.Params {
+		params[p.Name] = true
+	}
+	for i, step := range r.Steps {
+		if strings.TrimSpace(step.Command) == "" {
+			return fmt.Errorf("runbook %s: step %d has no command", r.Name, i+1)
+		}
+		if step.If != "" && !params[step.If] {
+			return fmt.Errorf("runbook %s: step %d depends on unknown parameter %q", r.Name, i+1, step.If)
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(step.Command, -1) {
+			if !params[m[1]] {
+				return fmt.Errorf("runbook %s: step %d uses unknown parameter %q", r.Name, i+1, m[1])
+			}
+		}
+	}
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("runbook %s has no steps", r.Name)
+	}
+	return nil
+}
+
+// read loads and validates a saved runbook, named after its file if the
+// name is omitted
+func read(path string) (*Runbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Runbook
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid runbook %s: %w", path, err)
+	}
+	if r.Name == "" {
+		r.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid runbook %s: %w", path, err)
+	}
+	r.Path = path
+	return &r, nil
+}
+
+// safeValue matches values that need no quoting in a POSIX shell
+var safeValue = regexp.MustCompile(`^[A-Za-z0-9@%+=:,./_-]+$`)
+
+// quote quotes a parameter value for a POSIX shell unless it is plainly safe
+func quote(s string) string {
+	if safeValue.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"devos/internal/runbook"
+)
+
+// RunbookCall is a plan that instantiates a runbook instead of listing
+// commands itself
+type RunbookCall struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// runbookSummary describes a runbook to the AI engine
+type runbookSummary struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Keywords    []string        `json:"keywords,omitempty"`
+	Params      []runbook.Param `json:"params,omitempty"`
+}
+
+// RunbookDir is where saved and customized runbooks live
+func (e *Executor) RunbookDir() string {
+	return filepath.Join(e.config.Dir(), "runbooks")
+}
+
+// Runbook prepares a plan from a runbook, as if the AI engine had chosen it
+func (e *Executor) Runbook(name string, params map[string]string) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		NeedsConfirmation: true,
+		Intent:            "runbook",
+		Runbook:           &RunbookCall{Name: name, Params: params},
+	}
+	if err := e.instantiateRunbook(result); err != nil {
+		return nil, err
+	}
+	return e.prepare(result)
+}
+
+// runbookCatalog lists the available runbooks for the engine request
+func (e *Executor) runbookCatalog() []runbookSummary {
+	runbooks, err := runbook.Load(e.RunbookDir())
+	if err != nil {
+		e.logger.Warn("Failed to load runbooks: %v", err)
+		runbooks = runbook.Builtins()
+	}
+	catalog := make([]runbookSummary, len(runbooks))
+	for i, r := range runbooks {
+		catalog[i] = runbookSummary{Name: r.Name, Description: r.Description, Keywords: r.Keywords, Params: r.Params}
+	}
+	return catalog
+}
+
+// instantiateRunbook fills in the commands of a plan that names a runbook.
+// Runbook plans always need confirmation, whatever the engine said.
+func (e *Executor) instantiateRunbook(result *ExecutionResult) error {
+	call := result.Runbook
+	r, err := runbook.Find(e.RunbookDir(), call.Name)
+	if err != nil {
+		return err
+	}
+	commands, err := r.Instantiate(call.Params)
+	if err != nil {
+		return err
+	}
+
+	result.Commands = commands
+	result.Steps = nil
+	result.NeedsConfirmation = true
+	if result.Prompt == "" {
+		result.Prompt = runbookPrompt(call)
+	}
+	if result.Output == "" {
+		result.Output = fmt.Sprintf("📓 Runbook %s: %s", r.Name, r.Description)
+	}
+	return nil
+}
+
+// runbookPrompt renders a runbook call as a task, e.g.
+// "runbook restart-service service=nginx"
+func runbookPrompt(call *RunbookCall) string {
+	parts := []string{"runbook", call.Name}
+	names := make([]string, 0, len(call.Params))
+	for name := range call.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+call.Params[name])
+	}
+	return strings.Join(parts, " ")
+}
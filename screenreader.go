@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// screenReaders are process names of Windows screen readers
+var screenReaders = []string{"nvda.exe", "jfw.exe", "narrator.exe", "zt.exe"}
+
+// ScreenReader reports whether a screen reader appears to be running, so
+// output can switch to plain text without being configured
+func ScreenReader() bool {
+	if os.Getenv("TERM") == "dumb" || os.Getenv("EMACSPEAK_DIR") != "" {
+		return true
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return commandOutput("defaults", "read", "com.apple.universalaccess", "voiceOverOnOffKey") == "1"
+	case "windows":
+		tasks := strings.ToLower(commandOutput("tasklist", "/fo", "csv", "/nh"))
+		for _, name := range screenReaders {
+			if strings.Contains(tasks, `"`+name+`"`) {
+				return true
+			}
+		}
+		return false
+	case "linux":
+		// GNOME's toggle for Orca; other desktops leave it unset
+		return commandOutput("gsettings", "get", "org.gnome.desktop.a11y.applications", "screen-reader-enabled") == "true"
+	default:
+		return false
+	}
+}
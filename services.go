@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Service is the state of a system service
+type Service struct {
+	Name     string    `json:"name"`
+	Manager  string    `json:"manager"` // systemd, launchd, or windows
+	Active   bool      `json:"active"`
+	State    string    `json:"state"`              // e.g. "active (running)", "failed", "stopped"
+	Enabled  string    `json:"enabled,omitempty"`  // Starts at boot: "enabled", "disabled", "auto", ...
+	PID      int       `json:"pid,omitempty"`      // Main process, 0 when not running
+	Since    time.Time `json:"since,omitempty"`    // Last state change
+	ExitCode int       `json:"exit_code"`          // Last exit status of the main process
+	Result   string    `json:"result,omitempty"`   // Why it last stopped, e.g. "signal", "exit-code", "success"
+	Restarts int       `json:"restarts,omitempty"` // Automatic restarts since boot
+}
+
+// Healthy reports whether the service is running and last stopped cleanly
+func (s *Service) Healthy() bool {
+	return s.Active && (s.Result == "" || s.Result == "success")
+}
+
+// Manager returns the service manager for this platform
+func Manager() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "launchd"
+	case "windows":
+		return "windows"
+	default:
+		return "systemd"
+	}
+}
+
+// Status returns the state of a service, resolving short names such as
+// "postgres" to the installed unit (e.g. "postgresql@16-main")
+func Status(ctx context.Context, name string) (*Service, error) {
+	switch Manager() {
+	case "launchd":
+		return launchdStatus(ctx, name)
+	case "windows":
+		return windowsStatus(ctx, name)
+	default:
+		return systemdStatus(ctx, name)
+	}
+}
+
+// List returns services whose names contain filter (all services if empty)
+func List(ctx context.Context, filter string) ([]Service, error) {
+	switch Manager() {
+	case "launchd":
+		return launchdList(ctx, filter)
+	case "windows":
+		return windowsList(ctx, filter)
+	default:
+		return systemdList(ctx, filter)
+	}
+}
+
+// Control starts, stops, or restarts a service
+func Control(ctx context.Context, action, name string) error {
+	if action != "start" && action != "stop" && action != "restart" {
+		return fmt.Errorf("unsupported service action: %s", action)
+	}
+
+	var cmd *exec.Cmd
+	switch Manager() {
+	case "launchd":
+		verb := map[string]string{"start": "kickstart", "stop": "kill", "restart": "kickstart"}[action]
+		args := []string{verb}
+		if action == "restart" {
+			args = append(args, "-k")
+		}
+		if action == "stop" {
+			args = append(args, "SIGTERM")
+		}
+		cmd = exec.CommandContext(ctx, "launchctl", append(args, "system/"+name)...)
+	case "windows":
+		verb := map[string]string{"start": "Start-Service", "stop": "Stop-Service", "restart": "Restart-Service"}[action]
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", verb+" -Name '"+strings.ReplaceAll(name, "'", "''")+"'")
+	default:
+		cmd = exec.CommandContext(ctx, "systemctl", action, name)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %s", action, name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// systemdStatus reads unit properties with systemctl show
+func systemdStatus(ctx context.Context, name string) (*Service, error) {
+	unit, err := resolveUnit(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.CommandContext(ctx, "systemctl", "show", unit, "--no-pager",
+		"-p", "Id,ActiveState,SubState,UnitFileState,MainPID,ExecMainStatus,Result,NRestarts,StateChangeTimestamp,LoadState").Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show %s: %w", unit, err)
+	}
+
+	props := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			props[key] = value
+		}
+	}
+	if props["LoadState"] == "not-found" {
+		return nil, fmt.Errorf("service not found: %s", name)
+	}
+
+	s := &Service{
+		Name:     props["Id"],
+		Manager:  "systemd",
+		Active:   props["ActiveState"] == "active",
+		State:    props["ActiveState"] + " (" + props["SubState"] + ")",
+		Enabled:  props["UnitFileState"],
+		Result:   props["Result"],
+		PID:      atoi(props["MainPID"]),
+		ExitCode: atoi(props["ExecMainStatus"]),
+		Restarts: atoi(props["NRestarts"]),
+	}
+	// e.g. "Tue 2024-05-14 03:12:09 UTC"
+	if t, err := time.Parse("Mon 2006-01-02 15:04:05 MST", props["StateChangeTimestamp"]); err == nil {
+		s.Since = t
+	}
+	return s, nil
+}
+
+// resolveUnit maps a short name to an installed systemd service unit
+func resolveUnit(ctx context.Context, name string) (string, error) {
+	services, err := systemdList(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range services {
+		if s.Name == name || s.Name == name+".service" {
+			return s.Name, nil
+		}
+	}
+	if len(services) > 0 {
+		return services[0].Name, nil
+	}
+	return name, nil
+}
+
+// systemdList lists service units with systemctl list-units
+func systemdList(ctx context.Context, filter string) ([]Service, error) {
+	out, err := exec.CommandContext(ctx, "systemctl", "list-units", "--all", "--type=service", "--no-legend", "--no-pager", "--plain").Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl list-units: %w", err)
+	}
+
+	var services []Service
+	for _, line := range strings.Split(string(out), "\n") {
+		// UNIT LOAD ACTIVE SUB DESCRIPTION...
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.Contains(fields[0], filter) {
+			continue
+		}
+		services = append(services, Service{
+			Name:    fields[0],
+			Manager: "systemd",
+			Active:  fields[2] == "active",
+			State:   fields[2] + " (" + fields[3] + ")",
+		})
+	}
+	return services, nil
+}
+
+// launchdStatus finds a job by label with launchctl list
+func launchdStatus(ctx context.Context, name string) (*Service, error) {
+	services, err := launchdList(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("service not found: %s", name)
+	}
+	return &services[0], nil
+}
+
+// launchdList parses `launchctl list` (PID, last exit status, label)
+func launchdList(ctx context.Context, filter string) ([]Service, error) {
+	out, err := exec.CommandContext(ctx, "launchctl", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("launchctl list: %w", err)
+	}
+
+	var services []Service
+	for _, line := range strings.Split(string(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !strings.Contains(strings.ToLower(fields[2]), strings.ToLower(filter)) {
+			continue
+		}
+		s := Service{Name: fields[2], Manager: "launchd", PID: atoi(fields[0]), ExitCode: atoi(fields[1])}
+		s.Active = s.PID > 0
+		s.State = "stopped"
+		if s.Active {
+			s.State = "running"
+		}
+		if s.ExitCode != 0 {
+			s.Result = "exit-code"
+		}
+		services = append(services, s)
+	}
+	return services, nil
+}
+
+// windowsService is the JSON shape of Get-Service output
+type windowsService struct {
+	Name      string
+	Status    int // 1 stopped, 4 running, ...
+	StartType int // 2 automatic, 3 manual, 4 disabled
+}
+
+// windowsStatus queries one service with Get-Service
+func windowsStatus(ctx context.Context, name string) (*Service, error) {
+	services, err := windowsList(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if strings.EqualFold(s.Name, name) {
+			return &s, nil
+		}
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("service not found: %s", name)
+	}
+	return &services[0], nil
+}
+
+// windowsList lists services with Get-Service
+func windowsList(ctx context.Context, filter string) ([]Service, error) {
+	script := "Get-Service -Name '*" + strings.ReplaceAll(filter, "'", "''") + "*' | Select-Object Name,Status,StartType | ConvertTo-Json"
+	out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Get-Service: %w", err)
+	}
+
+	var raw []windowsService
+	trimmed := strings.TrimSpace(string(out))
+	if strings.HasPrefix(trimmed, "{") {
+		trimmed = "[" + trimmed + "]" // A single result is not wrapped in an array
+	}
+	if trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse Get-Service output: %w", err)
+		}
+	}
+
+	states := map[int]string{1: "stopped", 2: "start pending", 3: "stop pending", 4: "running", 7: "paused"}
+	starts := map[int]string{0: "boot", 1: "system", 2: "auto", 3: "manual", 4: "disabled"}
+	services := make([]Service, 0, len(raw))
+	for _, r := range raw {
+		services = append(services, Service{
+			Name:    r.Name,
+			Manager: "windows",
+			Active:  r.Status == 4,
+			State:   states[r.Status],
+			Enabled: starts[r.StartType],
+		})
+	}
+	return services, nil
+}
+
+// atoi parses an integer, returning 0 for empty or invalid input
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
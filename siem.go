@@ -0,0 +1,358 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export destinations
+const (
+	ExportSplunk  = "splunk"  // Splunk HTTP Event Collector
+	ExportElastic = "elastic" // Elasticsearch bulk API
+	ExportHTTP    = "http"    // Any endpoint accepting a POST per batch
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCEF  = "cef"
+)
+
+// DefaultIndex is the Splunk or Elasticsearch index events go to when the
+// export does not name one
+const DefaultIndex = "devos-audit"
+
+// Batching limits for exports. Events are sent within exportInterval of
+// being recorded; a batch that cannot be delivered is kept and retried, up
+// to exportQueue events, after which the oldest are dropped.
+const (
+	exportBatch    = 200
+	exportQueue    = 5000
+	exportInterval = 2 * time.Second
+)
+
+// ExportOptions configures forwarding audit events to a SIEM
+type ExportOptions struct {
+	Type    string            // splunk, elastic, or http
+	Target  string            // Base URL: Splunk HEC, Elasticsearch, or the full http endpoint
+	Format  string            // json (default) or cef
+	Token   string            // Splunk HEC token or Elasticsearch API key
+	Index   string            // Splunk or Elasticsearch index (default devos-audit)
+	Headers map[string]string // Extra request headers
+	Version string            // DevOS version, for CEF headers
+}
+
+// Exporter forwards audit events to a SIEM in near real time, in batches
+// sent in the background so a slow or unreachable SIEM never stalls DevOS
+type Exporter struct {
+	opts    ExportOptions
+	url     string
+	host    string
+	client  *http.Client
+	failing bool // Only the sender goroutine touches this
+
+	mu      sync.Mutex
+	pending []Event
+	dropped int // Events dropped from the front of a full queue
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// NewExporter starts forwarding events as opts describes
+func NewExporter(opts ExportOptions) (*Exporter, error) {
+	if opts.Format == "" {
+		opts.Format = FormatJSON
+	}
+	if opts.Format != FormatJSON && opts.Format != FormatCEF {
+		return nil, fmt.Errorf("unknown audit export format: %s (expected json or cef)", opts.Format)
+	}
+	if opts.Index == "" {
+		opts.Index = DefaultIndex
+	}
+	u, err := url.Parse(opts.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid audit export URL: %q", opts.Target)
+	}
+
+	endpoint := opts.Target
+	switch opts.Type {
+	case ExportSplunk:
+		endpoint, err = url.JoinPath(opts.Target, "/services/collector/event")
+	case ExportElastic:
+		endpoint, err = url.JoinPath(opts.Target, "/_bulk")
+	case ExportHTTP:
+	default:
+		return nil, fmt.Errorf("unknown audit export type: %s (expected splunk, elastic, or http)", opts.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit export URL: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	e := &Exporter{
+		opts:   opts,
+		url:    endpoint,
+		host:   host,
+		client: &http.Client{Timeout: 10 * time.Second},
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// RecordEvent queues an event for the next batch
+func (e *Exporter) RecordEvent(event Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.pending = append(e.pending, event)
+	if over := len(e.pending) - exportQueue; over > 0 {
+		e.pending = e.pending[over:]
+		e.dropped += over
+	}
+	if len(e.pending) >= exportBatch {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close sends the queued events and stops the sender
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.wake)
+	}
+	e.mu.Unlock()
+	<-e.done
+	return nil
+}
+
+// run sends the queued events when a batch fills up or exportInterval
+// passes, and once more on Close
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case _, ok := <-e.wake:
+			e.flush()
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+// flush sends the queued events in batches, keeping a batch that fails to
+// retry with the next flush. Delivery problems cannot go to the audit log
+// they come from, so the first failure is reported on stderr, and then
+// recovery.
+func (e *Exporter) flush() {
+	for {
+		e.mu.Lock()
+		n := min(len(e.pending), exportBatch)
+		batch := e.pending[:n:n]
+		dropped := e.dropped
+		e.mu.Unlock()
+		if n == 0 {
+			return
+		}
+
+		err := e.post(batch)
+		if err != nil && !e.failing {
+			fmt.Fprintf(os.Stderr, "Warning: failed to export audit events to %s: %v\n", e.url, err)
+		} else if err == nil && e.failing {
+			fmt.Fprintf(os.Stderr, "Audit export to %s recovered\n", e.url)
+		}
+		e.failing = err != nil
+		if err != nil {
+			return
+		}
+
+		e.mu.Lock()
+		// Events of the batch dropped from a full queue meanwhile are gone already
+		if sent := n - (e.dropped - dropped); sent > 0 {
+			e.pending = e.pending[sent:]
+		}
+		e.mu.Unlock()
+	}
+}
+
+// post sends one batch in the destination's format
+func (e *Exporter) post(batch []Event) error {
+	var body bytes.Buffer
+	contentType := "application/json"
+	switch e.opts.Type {
+	case ExportSplunk:
+		// HEC takes concatenated event objects
+		for _, event := range batch {
+			json.NewEncoder(&body).Encode(map[string]interface{}{
+				"time":       float64(event.Time.UnixMilli()) / 1000,
+				"host":       e.host,
+				"source":     "devos",
+				"sourcetype": "devos:audit:" + e.opts.Format,
+				"index":      e.opts.Index,
+				"event":      e.document(event),
+			})
+		}
+	case ExportElastic:
+		contentType = "application/x-ndjson"
+		for _, event := range batch {
+			json.NewEncoder(&body).Encode(map[string]interface{}{"create": map[string]string{"_index": e.opts.Index}})
+			json.NewEncoder(&body).Encode(e.document(event))
+		}
+	default:
+		if e.opts.Format == FormatCEF {
+			contentType = "text/plain"
+			for _, event := range batch {
+				body.WriteString(e.cef(event) + "\n")
+			}
+		} else {
+			docs := make([]interface{}, len(batch))
+			for i, event := range batch {
+				docs[i] = e.document(event)
+			}
+			json.NewEncoder(&body).Encode(docs)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case e.opts.Token == "":
+	case e.opts.Type == ExportSplunk:
+		req.Header.Set("Authorization", "Splunk "+e.opts.Token)
+	case e.opts.Type == ExportElastic:
+		req.Header.Set("Authorization", "ApiKey "+e.opts.Token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+e.opts.Token)
+	}
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	if e.opts.Type == ExportElastic {
+		// The bulk API answers 200 even when documents are rejected. They
+		// would be rejected again, so they are not retried.
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&result) == nil && result.Errors {
+			fmt.Fprintf(os.Stderr, "Warning: Elasticsearch at %s rejected some audit events\n", e.url)
+		}
+	}
+	return nil
+}
+
+// document renders an event for a SIEM: as JSON with the host it happened
+// on, or a CEF message, which Splunk takes as is
+func (e *Exporter) document(event Event) interface{} {
+	doc := map[string]interface{}{"@timestamp": event.Time.UTC().Format(time.RFC3339Nano), "host": e.host}
+	if e.opts.Format == FormatCEF {
+		if e.opts.Type == ExportSplunk {
+			return e.cef(event)
+		}
+		doc["message"] = e.cef(event)
+		return doc
+	}
+	doc["type"] = event.Type
+	doc["user"] = event.User
+	if len(event.Fields) > 0 {
+		doc["fields"] = event.Fields
+	}
+	return doc
+}
+
+// cef renders an event in ArcSight Common Event Format. Its fields go in
+// the custom string extensions cs1 to cs6, in name order, and any beyond
+// those in msg.
+func (e *Exporter) cef(event Event) string {
+	header := []string{"CEF:0", "DevOS", "DevOS", e.opts.Version, event.Type, strings.ReplaceAll(event.Type, "_", " "), strconv.Itoa(Severity(event.Type))}
+	for i, h := range header[1:] {
+		header[i+1] = cefHeader(h)
+	}
+
+	ext := []string{
+		"rt=" + strconv.FormatInt(event.Time.UnixMilli(), 10),
+		"suser=" + cefValue(event.User),
+		"shost=" + cefValue(e.host),
+	}
+	names := make([]string, 0, len(event.Fields))
+	for name := range event.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var rest []string
+	for i, name := range names {
+		if i < 6 {
+			ext = append(ext, fmt.Sprintf("cs%dLabel=%s cs%d=%s", i+1, cefValue(name), i+1, cefValue(event.Fields[name])))
+		} else {
+			rest = append(rest, name+"="+event.Fields[name])
+		}
+	}
+	if len(rest) > 0 {
+		ext = append(ext, "msg="+cefValue(strings.Join(rest, "; ")))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " ")
+}
+
+// Severity rates an audit event type from 0 to 10 for SIEMs: blocks and
+// denials, and safeguards being bypassed or relaxed, rank highest
+func Severity(eventType string) int {
+	for _, word := range []string{"blocked", "denied", "override", "bypassed", "exempted", "unlocked", "exception", "unavailable", "vulnerable", "decrypted"} {
+		if strings.Contains(eventType, word) {
+			return 8
+		}
+	}
+	for _, word := range []string{"failed", "timeout", "aborted", "drift", "failover"} {
+		if strings.Contains(eventType, word) {
+			return 5
+		}
+	}
+	return 3
+}
+
+// cefHeader escapes a CEF header field
+func cefHeader(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// cefValue escapes a CEF extension value
+func cefValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "=", `\=`)
+	s = strings.ReplaceAll(s, "\r", `\r`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
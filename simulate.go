@@ -0,0 +1,226 @@
+package simulate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"devos/internal/platform"
+)
+
+// ErrUnsupported is returned when plans cannot be rehearsed on this machine
+var ErrUnsupported = errors.New("simulation unavailable")
+
+// DefaultImage is used when the host distribution has no matching image
+const DefaultImage = "debian:stable-slim"
+
+// maxOutput is how much of each step's output a report keeps
+const maxOutput = 4000
+
+// Step markers the rehearsal script prints around each command
+const (
+	stepMarker = "@@devos-step "
+	exitMarker = "@@devos-exit "
+)
+
+// setupFailed is the exit status of the script when the snapshot cannot be made
+const setupFailed = 97
+
+// images maps distributions to the official images that match them; the
+// host's version is used as the tag when the image publishes it
+var images = map[string]string{
+	"ubuntu":        "ubuntu",
+	"debian":        "debian",
+	"fedora":        "fedora",
+	"alpine":        "alpine",
+	"arch":          "archlinux",
+	"centos":        "quay.io/centos/centos",
+	"rocky":         "rockylinux",
+	"amzn":          "amazonlinux",
+	"opensuse-leap": "opensuse/leap",
+}
+
+// Options describes one rehearsal
+type Options struct {
+	Image    string   // Container image; empty picks one matching the host
+	Root     string   // Project root copied into the container
+	Dir      string   // Working directory, inside Root
+	Commands []string // Plan commands, run in order until one fails
+}
+
+// StepResult is the outcome of one command in the rehearsal
+type StepResult struct {
+	Command  string
+	ExitCode int
+	Output   string // Tail of the combined output
+}
+
+// Report is the outcome of a rehearsal
+type Report struct {
+	Runtime  string
+	Image    string
+	Commands int          // Commands in the plan
+	Steps    []StepResult // Steps that ran; a failed step is last
+	Duration time.Duration
+}
+
+// Succeeded reports whether every command ran and exited zero
+func (r *Report) Succeeded() bool {
+	return len(r.Steps) == r.Commands && r.Failed() == nil
+}
+
+// Failed returns the step that failed, or nil
+func (r *Report) Failed() *StepResult {
+	if n := len(r.Steps); n > 0 && r.Steps[n-1].ExitCode != 0 {
+		return &r.Steps[n-1]
+	}
+	return nil
+}
+
+// ContainerRuntime returns docker or podman, whichever is installed
+func ContainerRuntime() (string, error) {
+	for _, name := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: install Docker or Podman to rehearse plans in a container", ErrUnsupported)
+}
+
+// ImageFor picks an image matching the host distribution, so package
+// managers and paths behave as they will on the host
+func ImageFor(info *platform.Info) string {
+	repo, ok := images[strings.ToLower(info.Distro)]
+	if !ok {
+		return DefaultImage
+	}
+	if info.DistroVersion == "" || repo == "archlinux" {
+		return repo + ":latest"
+	}
+	return repo + ":" + info.DistroVersion
+}
+
+// Run copies the project into a throwaway container and runs the commands
+// there, stopping at the first failure. The host project is mounted
+// read-only, so nothing the plan does escapes the container.
+func Run(ctx context.Context, opts Options, info *platform.Info) (*Report, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("%w: plans for %s cannot be rehearsed in a Linux container", ErrUnsupported, runtime.GOOS)
+	}
+	name, err := ContainerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Image == "" {
+		opts.Image = ImageFor(info)
+	}
+	rel, err := filepath.Rel(opts.Root, opts.Dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = "."
+	}
+
+	args := []string{"run", "--rm", "-i",
+		"-v", opts.Root + ":/snapshot:ro",
+		"--label", "devos.simulation=true",
+		opts.Image, "sh", "-s"}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(script(opts.Commands, filepath.ToSlash(rel)))
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+
+	start := time.Now()
+	runErr := cmd.Run()
+	report := &Report{
+		Runtime:  name,
+		Image:    opts.Image,
+		Commands: len(opts.Commands),
+		Steps:    parse(out.String(), opts.Commands),
+		Duration: time.Since(start),
+	}
+	if ctx.Err() != nil {
+		return report, fmt.Errorf("simulation stopped: %w", ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) && exitErr.ExitCode() == setupFailed {
+		return report, fmt.Errorf("failed to copy the project into the container: %s", tail(out.String()))
+	}
+	if runErr != nil && len(report.Steps) == 0 {
+		// The container never reached the first step, e.g. the image is missing
+		return report, fmt.Errorf("failed to start %s container: %w: %s", opts.Image, runErr, tail(out.String()))
+	}
+	return report, nil
+}
+
+// script builds the shell script that snapshots the project and runs each
+// command with markers around it. Containers run as root without sudo, so
+// sudo runs its arguments directly.
+func script(commands []string, rel string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sudo() { while [ \"${1#-}\" != \"$1\" ]; do shift; done; \"$@\"; }\n")
+	fmt.Fprintf(&b, "mkdir -p /work && cp -a /snapshot/. /work && cd %s || exit %d\n", shellQuote("/work/"+rel), setupFailed)
+	for i, c := range commands {
+		fmt.Fprintf(&b, "printf '\\n%s%d\\n'\n", stepMarker, i)
+		fmt.Fprintf(&b, "( %s\n) </dev/null 2>&1\n", c)
+		fmt.Fprintf(&b, "s=$?; printf '\\n%s%d %%d\\n' \"$s\"; [ \"$s\" -eq 0 ] || exit \"$s\"\n", exitMarker, i)
+	}
+	return b.String()
+}
+
+// parse splits the script output into step results
+func parse(output string, commands []string) []StepResult {
+	var steps []StepResult
+	var current *StepResult
+	var buf strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, stepMarker):
+			i, err := strconv.Atoi(strings.TrimPrefix(line, stepMarker))
+			if err != nil || i < 0 || i >= len(commands) {
+				continue
+			}
+			// A step without an exit marker was cut short
+			steps = append(steps, StepResult{Command: commands[i], ExitCode: -1})
+			current = &steps[len(steps)-1]
+			buf.Reset()
+		case strings.HasPrefix(line, exitMarker) && current != nil:
+			fields := strings.Fields(strings.TrimPrefix(line, exitMarker))
+			if len(fields) == 2 {
+				current.ExitCode, _ = strconv.Atoi(fields[1])
+			}
+			current.Output = tail(buf.String())
+			current = nil
+		case current != nil:
+			buf.WriteString(line + "\n")
+		}
+	}
+	if current != nil {
+		current.Output = tail(buf.String())
+	}
+	return steps
+}
+
+// tail keeps the end of output, where errors usually are
+func tail(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxOutput {
+		output = "…" + output[len(output)-maxOutput:]
+	}
+	return output
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,55 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Speak reads text aloud with the platform's speech synthesizer without
+// waiting for it to finish
+func Speak(text string) error {
+	name, args, err := speaker()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(name, args...)
+	if name == "powershell" || name == "powershell.exe" || name == "festival" {
+		cmd.Stdin = strings.NewReader(text)
+	} else {
+		cmd.Args = append(cmd.Args, text)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to speak: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// windowsSpeech reads stdin with the built-in System.Speech synthesizer
+const windowsSpeech = `Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())`
+
+// speaker returns the command that speaks text; the text is appended as an
+// argument, or piped to stdin for PowerShell and festival
+func speaker() (string, []string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "say", nil, nil
+	case "windows":
+		return "powershell", []string{"-NoProfile", "-Command", windowsSpeech}, nil
+	}
+
+	for _, name := range []string{"spd-say", "espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil, nil
+		}
+	}
+	if _, err := exec.LookPath("festival"); err == nil {
+		return "festival", []string{"--tts"}, nil
+	}
+	if isWSL() {
+		return "powershell.exe", []string{"-NoProfile", "-Command", windowsSpeech}, nil
+	}
+	return "", nil, fmt.Errorf("no speech synthesizer found (install speech-dispatcher or espeak-ng)")
+}
@@ -0,0 +1,255 @@
+package sshsetup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"devos/internal/a11y"
+	"devos/internal/textdiff"
+)
+
+// ErrKeyExists is returned instead of ever overwriting an existing key
+var ErrKeyExists = errors.New("key already exists")
+
+// keygenOutput matches the output path of an ssh-keygen invocation
+var keygenOutput = regexp.MustCompile(`\bssh-keygen\b.*\s-f\s*['"]?([^\s'"]+)`)
+
+// keygenModes are ssh-keygen flags that inspect or convert keys rather than
+// generating a new pair at -f
+var keygenModes = []string{"-l", "-y", "-R", "-F", "-p", "-e", "-i", "-c", "-H", "-L", "-Y", "-k", "-Q", "-D", "-s"}
+
+// keyRedirect matches shell redirects into a private or public key file
+var keyRedirect = regexp.MustCompile(`>\s*['"]?((?:~|\$HOME)?[^\s'"]*\.ssh/id_[^\s'"]+)`)
+
+// Dir returns the user's ~/.ssh directory
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh"), nil
+}
+
+// KeyPath returns where a key of the given type and optional name lives,
+// e.g. ~/.ssh/id_ed25519 or ~/.ssh/id_ed25519_github
+func KeyPath(keyType, name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	file := "id_" + keyType
+	if name != "" {
+		file += "_" + name
+	}
+	return filepath.Join(dir, file), nil
+}
+
+// CheckNewKey fails with ErrKeyExists if either half of the key pair at path exists
+func CheckNewKey(path string) error {
+	for _, p := range []string{path, path + ".pub"} {
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Errorf("%w: %s", ErrKeyExists, p)
+		}
+	}
+	return nil
+}
+
+// OverwritesKey returns the existing key file cmd would overwrite, or ""
+func OverwritesKey(cmd string) string {
+	var target string
+	if m := keygenOutput.FindStringSubmatch(cmd); m != nil {
+		for _, field := range strings.Fields(cmd) {
+			for _, mode := range keygenModes {
+				if field == mode {
+					return ""
+				}
+			}
+		}
+		target = m[1]
+	} else if m := keyRedirect.FindStringSubmatch(cmd); m != nil {
+		target = m[1]
+	} else {
+		return ""
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		target = strings.Replace(target, "$HOME", home, 1)
+		if strings.HasPrefix(target, "~/") {
+			target = filepath.Join(home, target[2:])
+		}
+	}
+	if err := CheckNewKey(strings.TrimSuffix(target, ".pub")); err != nil {
+		return target
+	}
+	return ""
+}
+
+// GenerateKey creates a new key pair with ssh-keygen, which prompts for the
+// passphrase on the terminal. Existing keys are never overwritten.
+func GenerateKey(ctx context.Context, path, keyType, comment string) error {
+	if err := CheckNewKey(path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	args := []string{"-t", keyType, "-f", path}
+	if comment != "" {
+		args = append(args, "-C", comment)
+	}
+	return interactive(ctx, "ssh-keygen", args...)
+}
+
+// AddToAgent loads a private key into the running ssh-agent
+func AddToAgent(ctx context.Context, path string) error {
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return fmt.Errorf("no ssh-agent is running (SSH_AUTH_SOCK is unset)")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("key not found: %s", path)
+	}
+	return interactive(ctx, "ssh-add", path)
+}
+
+// interactive runs a command attached to the terminal
+func interactive(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, a11y.Terminal(), os.Stderr
+	return cmd.Run()
+}
+
+// HostEntry is a Host block in ~/.ssh/config
+type HostEntry struct {
+	Alias        string
+	HostName     string
+	User         string
+	Port         int
+	IdentityFile string
+}
+
+// block renders the entry in ssh_config syntax
+func (h HostEntry) block() []string {
+	lines := []string{"Host " + h.Alias}
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, "    "+key+" "+value)
+		}
+	}
+	add("HostName", h.HostName)
+	add("User", h.User)
+	if h.Port != 0 {
+		add("Port", strconv.Itoa(h.Port))
+	}
+	if h.IdentityFile != "" {
+		add("IdentityFile", h.IdentityFile)
+		add("IdentitiesOnly", "yes")
+	}
+	return lines
+}
+
+// ConfigChange is a proposed edit to ~/.ssh/config
+type ConfigChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// Diff returns the exact lines the change adds and removes
+func (c *ConfigChange) Diff() string {
+	return textdiff.Unified(c.Before, c.After, 2)
+}
+
+// Apply writes the change, keeping the previous file as config.bak. It
+// fails if the file changed since the change was proposed.
+func (c *ConfigChange) Apply() error {
+	current, err := os.ReadFile(c.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if string(current) != c.Before {
+		return fmt.Errorf("%s changed since the diff was shown; not writing", c.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+		return err
+	}
+	if len(current) > 0 {
+		if err := os.WriteFile(c.Path+".bak", current, 0600); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(c.Path, []byte(c.After), 0600)
+}
+
+// UpsertHost proposes adding the entry to ~/.ssh/config, or replacing the
+// existing Host block with the same alias
+func UpsertHost(entry HostEntry) (*ConfigChange, error) {
+	if entry.Alias == "" || strings.ContainsAny(entry.Alias, " \t") {
+		return nil, fmt.Errorf("invalid host alias: %q", entry.Alias)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "config")
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	before := string(data)
+
+	lines := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	if before == "" {
+		lines = nil
+	}
+
+	start, end := findHost(lines, entry.Alias)
+	var out []string
+	if start < 0 {
+		out = append(out, lines...)
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, entry.block()...)
+	} else {
+		out = append(out, lines[:start]...)
+		out = append(out, entry.block()...)
+		out = append(out, lines[end:]...)
+	}
+
+	return &ConfigChange{Path: path, Before: before, After: strings.Join(out, "\n") + "\n"}, nil
+}
+
+// findHost returns the line range [start, end) of the Host block for alias,
+// or -1 if there is none. Trailing blank lines stay outside the block.
+func findHost(lines []string, alias string) (int, int) {
+	start := -1
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		isHeader := len(fields) > 0 && (strings.EqualFold(fields[0], "Host") || strings.EqualFold(fields[0], "Match"))
+		if start >= 0 && isHeader {
+			end := i
+			for end > start && strings.TrimSpace(lines[end-1]) == "" {
+				end--
+			}
+			return start, end
+		}
+		if isHeader && strings.EqualFold(fields[0], "Host") && len(fields) == 2 && fields[1] == alias {
+			start = i
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+	return start, len(lines)
+}
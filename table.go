@@ -0,0 +1,404 @@
+package table
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"devos/internal/timefmt"
+)
+
+// Output formats
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatTable, FormatJSON, FormatYAML}
+
+// Table is rows of values under named columns. Tables render as aligned
+// text, or as a list of objects keyed by column name in JSON and YAML.
+type Table struct {
+	Columns []string
+	Rows    [][]interface{}
+
+	// Group names a column whose rows are drawn as branches under each of
+	// its values in the text format, e.g. models under their source
+	Group string
+}
+
+// New creates a table with the given column names
+func New(columns ...string) *Table {
+	return &Table{Columns: columns}
+}
+
+// Add appends a row; values line up with the columns
+func (t *Table) Add(values ...interface{}) {
+	t.Rows = append(t.Rows, values)
+}
+
+// Select keeps only the named columns, in the order given
+func (t *Table) Select(columns []string) (*Table, error) {
+	if len(columns) == 0 {
+		return t, nil
+	}
+	index := make([]int, len(columns))
+	for i, name := range columns {
+		index[i] = -1
+		for j, column := range t.Columns {
+			if strings.EqualFold(name, column) {
+				index[i] = j
+			}
+		}
+		if index[i] < 0 {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(t.Columns, ", "))
+		}
+	}
+
+	selected := &Table{Columns: make([]string, len(index))}
+	for i, j := range index {
+		selected.Columns[i] = t.Columns[j]
+		if t.Columns[j] == t.Group {
+			selected.Group = t.Group
+		}
+	}
+	for _, row := range t.Rows {
+		values := make([]interface{}, len(index))
+		for i, j := range index {
+			if j < len(row) {
+				values[i] = row[j]
+			}
+		}
+		selected.Rows = append(selected.Rows, values)
+	}
+	return selected, nil
+}
+
+// Render writes the table in the given format
+func (t *Table) Render(w io.Writer, format string) error {
+	switch format {
+	case "", FormatTable:
+		t.text(w)
+		return nil
+	case FormatJSON:
+		items := make([]object, len(t.Rows))
+		for i, row := range t.Rows {
+			items[i] = object{keys: t.Columns, values: row}
+		}
+		return writeJSON(w, items)
+	case FormatYAML:
+		if len(t.Rows) == 0 {
+			fmt.Fprintln(w, "[]")
+		}
+		for _, row := range t.Rows {
+			writeYAMLObject(w, t.Columns, row, "- ", "  ")
+		}
+		return nil
+	default:
+		return CheckFormat(format)
+	}
+}
+
+// text writes the table as aligned columns under a header
+func (t *Table) text(w io.Writer) {
+	group := -1
+	for i, column := range t.Columns {
+		if column == t.Group {
+			group = i
+		}
+	}
+
+	// Cells exclude the group column, which becomes the branch labels
+	var header []string
+	for i, column := range t.Columns {
+		if i != group {
+			header = append(header, strings.ToUpper(column))
+		}
+	}
+	cells := make([][]string, len(t.Rows))
+	widths := make([]int, len(header))
+	for i, w := range header {
+		widths[i] = utf8.RuneCountInString(w)
+	}
+	for r, row := range t.Rows {
+		for i := range t.Columns {
+			if i == group {
+				continue
+			}
+			var value interface{}
+			if i < len(row) {
+				value = row[i]
+			}
+			cell := Text(value)
+			widths[len(cells[r])] = max(widths[len(cells[r])], utf8.RuneCountInString(cell))
+			cells[r] = append(cells[r], cell)
+		}
+	}
+
+	line := func(prefix string, cells []string) {
+		var b strings.Builder
+		b.WriteString(prefix)
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				b.WriteString(cell)
+			} else {
+				fmt.Fprintf(&b, "%-*s  ", widths[i], cell)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+
+	if group < 0 {
+		line("", header)
+		for _, row := range cells {
+			line("", row)
+		}
+		return
+	}
+
+	// Groups appear in the order their first row does
+	var labels []string
+	members := map[string][]int{}
+	for r, row := range t.Rows {
+		label := Text(row[group])
+		if _, ok := members[label]; !ok {
+			labels = append(labels, label)
+		}
+		members[label] = append(members[label], r)
+	}
+	line("   ", header)
+	for _, label := range labels {
+		fmt.Fprintln(w, label)
+		for n, r := range members[label] {
+			branch := "├─ "
+			if n == len(members[label])-1 {
+				branch = "└─ "
+			}
+			line(branch, cells[r])
+		}
+	}
+}
+
+// Record is one item's fields in order, such as a status summary. Records
+// render as aligned "Label: value" lines, or as a single object whose keys
+// are the labels in snake case.
+type Record struct {
+	Labels []string
+	Values []interface{}
+}
+
+// Set appends a field
+func (r *Record) Set(label string, value interface{}) {
+	r.Labels = append(r.Labels, label)
+	r.Values = append(r.Values, value)
+}
+
+// Keys returns the field names used in JSON and YAML
+func (r *Record) Keys() []string {
+	keys := make([]string, len(r.Labels))
+	for i, label := range r.Labels {
+		keys[i] = strings.ReplaceAll(strings.ToLower(label), " ", "_")
+	}
+	return keys
+}
+
+// Select keeps only the named fields, in the order given; names are labels
+// or keys
+func (r *Record) Select(names []string) (*Record, error) {
+	if len(names) == 0 {
+		return r, nil
+	}
+	keys := r.Keys()
+	selected := &Record{}
+	for _, name := range names {
+		found := false
+		for i, label := range r.Labels {
+			if strings.EqualFold(name, label) || strings.EqualFold(name, keys[i]) {
+				selected.Set(label, r.Values[i])
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(keys, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// Render writes the record in the given format
+func (r *Record) Render(w io.Writer, format string) error {
+	switch format {
+	case "", FormatTable:
+		width := 0
+		for _, label := range r.Labels {
+			width = max(width, utf8.RuneCountInString(label)+1)
+		}
+		for i, label := range r.Labels {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, label+":", Text(r.Values[i]))
+		}
+		return nil
+	case FormatJSON:
+		return writeJSON(w, object{keys: r.Keys(), values: r.Values})
+	case FormatYAML:
+		writeYAMLObject(w, r.Keys(), r.Values, "", "")
+		return nil
+	default:
+		return CheckFormat(format)
+	}
+}
+
+// CheckFormat returns an error unless format is supported
+func CheckFormat(format string) error {
+	for _, f := range Formats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(Formats, ", "))
+}
+
+// Text formats a value for the text format
+func Text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return timefmt.DateTime(v)
+	case time.Duration:
+		return v.Round(time.Millisecond).String()
+	case []string:
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// object is an ordered set of fields, so JSON output keeps column order
+type object struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(jsonValue(o.values[i]))
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
+
+// jsonValue converts values without a natural JSON form; durations become
+// seconds
+func jsonValue(value interface{}) interface{} {
+	if d, ok := value.(time.Duration); ok {
+		return d.Seconds()
+	}
+	return value
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeYAMLObject writes a mapping; first prefixes its first line (for list
+// items) and indent the others
+func writeYAMLObject(w io.Writer, keys []string, values []interface{}, first, indent string) {
+	if len(keys) == 0 {
+		fmt.Fprintln(w, first+"{}")
+		return
+	}
+	for i, key := range keys {
+		prefix := indent
+		if i == 0 {
+			prefix = first
+		}
+		var value interface{}
+		if i < len(values) {
+			value = values[i]
+		}
+		if list, ok := value.([]string); ok && len(list) > 0 {
+			fmt.Fprintf(w, "%s%s:\n", prefix, yamlScalar(key))
+			for _, item := range list {
+				fmt.Fprintf(w, "%s  - %s\n", indent, yamlScalar(item))
+			}
+			continue
+		}
+		fmt.Fprintf(w, "%s%s: %s\n", prefix, yamlScalar(key), yamlValue(value))
+	}
+}
+
+// yamlValue formats a scalar value for YAML
+func yamlValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlScalar(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case time.Duration:
+		return strconv.FormatFloat(v.Seconds(), 'f', -1, 64)
+	case []string:
+		return "[]"
+	case int, int64, uint64, float64:
+		return fmt.Sprint(v)
+	default:
+		return yamlScalar(fmt.Sprint(v))
+	}
+}
+
+// yamlScalar writes a string plainly when YAML would read it back as the
+// same string, and double-quoted otherwise
+func yamlScalar(s string) string {
+	plain := s != "" &&
+		!strings.ContainsAny(s, ":#\n\t\"'{}[],&*!|>%@`") &&
+		strings.TrimSpace(s) == s &&
+		!strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "?")
+	if plain {
+		switch strings.ToLower(s) {
+		case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+			plain = false
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			plain = false
+		}
+	}
+	if plain {
+		return s
+	}
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"devos/internal/timefmt"
+)
+
+// Task is a single processed request, recorded for reporting
+type Task struct {
+	ID        int64         `json:"id,omitempty"`
+	Input     string        `json:"input"`
+	Category  string        `json:"category"`
+	Provider  string        `json:"provider"`
+	Model     string        `json:"model"`
+	Commands  int           `json:"commands"`
+	Success   bool          `json:"success"`
+	Duration  time.Duration `json:"duration"`
+	Tokens    int           `json:"tokens"`
+	Rollback  []string      `json:"rollback,omitempty"` // Commands reversing its steps and package changes
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// RecordTask stores a processed task in the history
+func (s *Store) RecordTask(t Task) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = timefmt.Now()
+	}
+
+	_, err := s.exec(
+		`INSERT INTO tasks (input, category, provider, model, commands, success, duration_ms, tokens, rollback, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Input, t.Category, t.Provider, t.Model, t.Commands, t.Success,
+		t.Duration.Milliseconds(), t.Tokens, strings.Join(t.Rollback, "\n"), t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record task: %w", err)
+	}
+
+	return nil
+}
+
+// TasksSince returns all tasks recorded after the given time, oldest first
+func (s *Store) TasksSince(since time.Time) ([]Task, error) {
+	rows, err := s.query(
+		`SELECT input, category, provider, model, commands, success, duration_ms, tokens, created_at
+		FROM tasks WHERE created_at >= ? ORDER BY id`,
+		since.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		var durationMs int64
+		if err := rows.Scan(&t.Input, &t.Category, &t.Provider, &t.Model, &t.Commands,
+			&t.Success, &durationMs, &t.Tokens, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read task: %w", err)
+		}
+		t.Duration = time.Duration(durationMs) * time.Millisecond
+		tasks = append(tasks, t)
+	}
+
+	return tasks, rows.Err()
+}
+
+// LastUndoable returns the most recent task with rollback commands that has
+// not been undone yet, or nil if there is none
+func (s *Store) LastUndoable() (*Task, error) {
+	row := s.queryRow(
+		`SELECT id, input, category, provider, model, commands, success, duration_ms, tokens, rollback, created_at
+		FROM tasks WHERE rollback != '' AND undone = ? ORDER BY id DESC LIMIT 1`,
+		false,
+	)
+
+	var t Task
+	var durationMs int64
+	var rollback string
+	err := row.Scan(&t.ID, &t.Input, &t.Category, &t.Provider, &t.Model, &t.Commands,
+		&t.Success, &durationMs, &t.Tokens, &rollback, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task: %w", err)
+	}
+	t.Duration = time.Duration(durationMs) * time.Millisecond
+	t.Rollback = strings.Split(rollback, "\n")
+	return &t, nil
+}
+
+// MarkUndone records that a task's rollback commands have been run
+func (s *Store) MarkUndone(id int64) error {
+	if _, err := s.exec(`UPDATE tasks SET undone = ? WHERE id = ?`, true, id); err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+	return nil
+}
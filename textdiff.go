@@ -0,0 +1,81 @@
+package textdiff
+
+import "strings"
+
+// Op is one line of a diff: ' ' unchanged, '-' removed, '+' added
+type Op struct {
+	Kind byte
+	Text string
+}
+
+// Lines diffs two texts line by line using a longest common subsequence
+func Lines(before, after string) []Op {
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []Op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, Op{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, Op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, Op{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// Unified renders changed lines with up to context unchanged lines around
+// each change, separating distant hunks with "@@"
+func Unified(before, after string, context int) string {
+	ops := Lines(before, after)
+
+	show := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.Kind == ' ' {
+			continue
+		}
+		for k := i - context; k <= i+context; k++ {
+			if k >= 0 && k < len(ops) {
+				show[k] = true
+			}
+		}
+	}
+
+	var out strings.Builder
+	gap := false
+	for i, op := range ops {
+		if !show[i] {
+			gap = true
+			continue
+		}
+		if gap && out.Len() > 0 {
+			out.WriteString("@@\n")
+		}
+		gap = false
+		out.WriteString(string(op.Kind) + " " + op.Text + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,118 @@
+package timefmt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dateLayouts are date formats by locale prefix, most specific first
+var dateLayouts = []struct {
+	prefix string
+	layout string
+}{
+	{"en_us", "01/02/2006"}, {"en_ph", "01/02/2006"},
+	{"en_ca", "2006-01-02"}, {"en", "02/01/2006"},
+	{"de", "02.01.2006"}, {"ru", "02.01.2006"}, {"pl", "02.01.2006"}, {"cs", "02.01.2006"},
+	{"fi", "02.01.2006"}, {"nb", "02.01.2006"}, {"tr", "02.01.2006"}, {"uk", "02.01.2006"},
+	{"fr", "02/01/2006"}, {"es", "02/01/2006"}, {"it", "02/01/2006"}, {"pt", "02/01/2006"},
+	{"nl", "02-01-2006"}, {"ja", "2006/01/02"}, {"zh", "2006/01/02"}, {"ko", "2006. 01. 02."},
+	{"sv", "2006-01-02"}, {"da", "02.01.2006"},
+}
+
+// twelveHour lists locale prefixes that use a 12-hour clock
+var twelveHour = []string{"en_us", "en_ph", "en_ca", "en_au", "en_in", "hi", "ar"}
+
+var (
+	mu       sync.RWMutex
+	location = time.Local
+	date     = "2006-01-02"
+	clock    = "15:04"
+)
+
+// Configure sets the display timezone (an IANA name such as
+// "Europe/Berlin"; empty for the system zone) and locale (such as "en_US";
+// empty to read LC_ALL, LC_TIME, or LANG)
+func Configure(timezone, locale string) error {
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q: %w", timezone, err)
+		}
+	}
+
+	if locale == "" {
+		for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+			if locale = os.Getenv(name); locale != "" {
+				break
+			}
+		}
+	}
+	locale = strings.ToLower(strings.ReplaceAll(locale, "-", "_"))
+
+	newDate, newClock := "2006-01-02", "15:04"
+	if locale != "" && locale != "c" && locale != "posix" && !strings.HasPrefix(locale, "c.") {
+		for _, l := range dateLayouts {
+			if strings.HasPrefix(locale, l.prefix) {
+				newDate = l.layout
+				break
+			}
+		}
+		for _, prefix := range twelveHour {
+			if strings.HasPrefix(locale, prefix) {
+				newClock = "3:04 PM"
+				break
+			}
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	location, date, clock = loc, newDate, newClock
+	return nil
+}
+
+// Now returns the current time in UTC, the form every stored timestamp uses
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Location returns the display timezone
+func Location() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
+	return location
+}
+
+// In converts t to the display timezone
+func In(t time.Time) time.Time {
+	return t.In(Location())
+}
+
+// Date renders the date of t in the display timezone and locale
+func Date(t time.Time) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return t.In(location).Format(date)
+}
+
+// Clock renders the time of day of t in the display timezone and locale
+func Clock(t time.Time) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return t.In(location).Format(clock)
+}
+
+// DateTime renders t as a date and time of day
+func DateTime(t time.Time) string {
+	return Date(t) + " " + Clock(t)
+}
+
+// Zone returns the display timezone's abbreviation at t, e.g. "CEST"
+func Zone(t time.Time) string {
+	name, _ := t.In(Location()).Zone()
+	return name
+}
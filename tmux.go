@@ -0,0 +1,249 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"devos/internal/a11y"
+)
+
+// tmuxSession is the tmux session that holds every DevOS task window
+const tmuxSession = "devos"
+
+// tmuxHistory is the scrollback kept per task, and so the most output captured
+const tmuxHistory = 50000
+
+// longRunning matches commands that run until stopped or typically take minutes
+var longRunning = regexp.MustCompile(`(?i)(` +
+	`\b(npm|yarn|pnpm|bun) (run )?(dev|start|serve|watch)\b|` +
+	`\bdocker(-| )compose\b.*\bup\b|` +
+	`\b(uvicorn|gunicorn|flask run|rails s(erver)?|nodemon|webpack serve|vite)\b|` +
+	`\bpython3? -m http\.server\b|\bmanage\.py runserver\b|` +
+	`\b(tail|journalctl) .*-f\b|\bkubectl (logs .*-f|port-forward)\b|\bwatch\b|` +
+	`\bcargo (build --release|watch)\b|\bdocker build\b|\bterraform apply\b|\bnpm (ci|install)\b|` +
+	`\b(python3?|ffmpeg|rsync) .*\b(train|encode|backup)\b)`)
+
+// taskNameChars are the characters kept when naming a task after its command
+var taskNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Task is a long-running command DevOS started in its tmux session
+type Task struct {
+	Name     string
+	Command  string
+	Started  time.Time
+	Running  bool
+	ExitCode int    // Valid once the task has finished; -1 if its window vanished
+	Output   string // Path to the output captured when the task finished
+}
+
+// LongRunning reports whether cmd is expected to run for minutes or until stopped
+func LongRunning(cmd string) bool {
+	return longRunning.MatchString(cmd)
+}
+
+// useTmux reports whether cmd should run as a tmux task instead of in the foreground
+func (e *Executor) useTmux(cmd string) bool {
+	if !e.config.Tmux || runtime.GOOS == "windows" || !LongRunning(cmd) {
+		return false
+	}
+	_, err := exec.LookPath("tmux")
+	return err == nil
+}
+
+// tasksDir holds one directory per task with its command, output, and exit code
+func (e *Executor) tasksDir() string {
+	return filepath.Join(e.config.Dir(), "tasks")
+}
+
+// startTask runs cmd in a new window of the DevOS tmux session. When the
+// command exits, the window's scrollback is saved as the task's output.
+func (e *Executor) startTask(ctx context.Context, cmd string) (*Task, error) {
+	dir, name, err := e.newTaskDir(cmd)
+	if err != nil {
+		return nil, err
+	}
+	task := &Task{Name: name, Command: cmd, Started: time.Now(), Running: true, Output: filepath.Join(dir, "output.log")}
+	if err := os.WriteFile(filepath.Join(dir, "command"), []byte(cmd+"\n"), 0600); err != nil {
+		return nil, err
+	}
+
+	// The window's shell runs the command under the configured shell, then
+	// captures the pane and records the exit status
+	wrapper := fmt.Sprintf(`%s -c %s; code=$?; tmux capture-pane -p -J -S - -t "$TMUX_PANE" > %s; echo $code > %s`,
+		e.shell(), Quote(cmd), Quote(task.Output), Quote(filepath.Join(dir, "exit")))
+
+	args := []string{"new-window", "-d", "-t", tmuxSession + ":", "-n", name, "-c", currentDir()}
+	for _, v := range e.environmentVars() {
+		args = append(args, "-e", v)
+	}
+	args = append(args, "sh -c "+Quote(wrapper))
+	if exec.CommandContext(ctx, "tmux", "has-session", "-t", tmuxSession).Run() != nil {
+		// Scrollback is fixed when a pane is created, so create the session
+		// with a placeholder window, raise its limit, then swap in the task
+		create := []string{"new-session", "-d", "-s", tmuxSession, "-n", "_", ";",
+			"set-option", "-t", tmuxSession, "history-limit", strconv.Itoa(tmuxHistory), ";"}
+		args = append(append(create, args...), ";", "kill-window", "-t", tmuxSession+":_")
+	}
+
+	if out, err := exec.CommandContext(ctx, "tmux", args...).CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to start tmux task: %s", strings.TrimSpace(string(out)))
+	}
+
+	e.audit.Record("task_started", map[string]string{"task": name, "command": cmd})
+	return task, nil
+}
+
+// newTaskDir creates a uniquely named directory for a task named after cmd
+func (e *Executor) newTaskDir(cmd string) (string, string, error) {
+	words := strings.Fields(cmd)
+	if len(words) > 2 {
+		words = words[:2]
+	}
+	base := strings.Trim(taskNameChars.ReplaceAllString(strings.ToLower(strings.Join(words, "-")), "-"), "-")
+	if base == "" {
+		base = "task"
+	}
+
+	if err := os.MkdirAll(e.tasksDir(), 0700); err != nil {
+		return "", "", err
+	}
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name += "-" + strconv.Itoa(i)
+		}
+		dir := filepath.Join(e.tasksDir(), name)
+		if err := os.Mkdir(dir, 0700); err == nil {
+			return dir, name, nil
+		} else if !os.IsExist(err) {
+			return "", "", err
+		}
+	}
+}
+
+// Tasks returns the tmux tasks DevOS has started, newest first
+func (e *Executor) Tasks() ([]*Task, error) {
+	entries, err := os.ReadDir(e.tasksDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	windows := tmuxWindows()
+	var tasks []*Task
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if task := e.loadTask(entry.Name(), windows); task != nil {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Started.After(tasks[j].Started) })
+	return tasks, nil
+}
+
+// Task returns the named task
+func (e *Executor) Task(name string) (*Task, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid task name: %s", name)
+	}
+	if task := e.loadTask(name, tmuxWindows()); task != nil {
+		return task, nil
+	}
+	return nil, fmt.Errorf("no such task: %s", name)
+}
+
+// FinishedTasks returns tasks that finished since they were last reported
+func (e *Executor) FinishedTasks() []*Task {
+	tasks, err := e.Tasks()
+	if err != nil {
+		return nil
+	}
+	var finished []*Task
+	for _, task := range tasks {
+		marker := filepath.Join(e.tasksDir(), task.Name, "reported")
+		if task.Running || fileExists(marker) {
+			continue
+		}
+		os.WriteFile(marker, nil, 0600)
+		e.audit.Record("task_finished", map[string]string{"task": task.Name, "exit_code": strconv.Itoa(task.ExitCode)})
+		finished = append(finished, task)
+	}
+	return finished
+}
+
+// Attach shows a running task's window; inside tmux it switches to it
+func (e *Executor) Attach(task *Task) error {
+	target := tmuxSession + ":" + task.Name
+	verb := "attach-session"
+	if os.Getenv("TMUX") != "" {
+		verb = "switch-client"
+	}
+	cmd := exec.Command("tmux", verb, "-t", target)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, a11y.Terminal(), os.Stderr
+	return cmd.Run()
+}
+
+// loadTask reads a task's state from its directory
+func (e *Executor) loadTask(name string, windows map[string]bool) *Task {
+	dir := filepath.Join(e.tasksDir(), name)
+	info, err := os.Stat(filepath.Join(dir, "command"))
+	if err != nil {
+		return nil
+	}
+	command, _ := os.ReadFile(filepath.Join(dir, "command"))
+	task := &Task{
+		Name:    name,
+		Command: strings.TrimSpace(string(command)),
+		Started: info.ModTime(),
+		Output:  filepath.Join(dir, "output.log"),
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "exit")); err == nil {
+		task.ExitCode, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	} else if windows[name] {
+		task.Running = true
+	} else {
+		task.ExitCode = -1 // Killed along with its window or the tmux server
+	}
+	return task
+}
+
+// tmuxWindows returns the window names in the DevOS session
+func tmuxWindows() map[string]bool {
+	windows := map[string]bool{}
+	out, err := exec.Command("tmux", "list-windows", "-t", tmuxSession, "-F", "#{window_name}").Output()
+	if err != nil {
+		return windows
+	}
+	for _, name := range strings.Fields(string(out)) {
+		windows[name] = true
+	}
+	return windows
+}
+
+// currentDir returns the working directory tasks start in
+func currentDir() string {
+	if dir, err := os.Getwd(); err == nil {
+		return dir
+	}
+	return "."
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,643 @@
+package configfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// bareKey matches TOML keys that need no quotes
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlParser reads a TOML document: tables, arrays of tables, dotted keys,
+// strings of every kind, numbers, booleans, arrays, and inline tables.
+// Dates and times are kept as strings.
+type tomlParser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+// parseTOML reads a TOML document
+func parseTOML(data []byte) (*Map, error) {
+	p := &tomlParser{data: data, line: 1}
+	root := NewMap()
+	current := root
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.data) {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			array := strings.HasPrefix(string(p.data[p.pos:]), "[[")
+			if array {
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+			p.skipSpace(false)
+			path, err := p.keyPath()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			if !strings.HasPrefix(string(p.data[p.pos:]), closing) {
+				return nil, p.errorf("expected %s after table name", closing)
+			}
+			p.pos += len(closing)
+			if current, err = p.table(root, path, array); err != nil {
+				return nil, err
+			}
+		} else {
+			path, err := p.keyPath()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			if p.peek() != '=' {
+				return nil, p.errorf("expected = after key %s", strings.Join(path, "."))
+			}
+			p.pos++
+			p.skipSpace(false)
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.assign(current, path, value); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipSpace(false)
+		if p.pos < len(p.data) && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, p.errorf("unexpected %q after value", p.peek())
+		}
+	}
+}
+
+// table returns the table a header names, creating it, or for an array of
+// tables appends a new one
+func (p *tomlParser) table(root *Map, path []string, array bool) (*Map, error) {
+	m := root
+	for i, key := range path {
+		last := i == len(path)-1
+		existing, ok := m.Get(key)
+		switch v := existing.(type) {
+		case nil:
+			if ok {
+				return nil, p.errorf("key %s is already defined", key)
+			}
+			if last && array {
+				t := NewMap()
+				m.Set(key, []interface{}{t})
+				return t, nil
+			}
+			t := NewMap()
+			m.Set(key, t)
+			m = t
+		case *Map:
+			if last && array {
+				return nil, p.errorf("%s is a table, not an array of tables", strings.Join(path, "."))
+			}
+			m = v
+		case []interface{}:
+			var t *Map
+			if len(v) > 0 {
+				t, _ = v[len(v)-1].(*Map)
+			}
+			if t == nil {
+				return nil, p.errorf("%s is not a table", key)
+			}
+			if last && array {
+				t = NewMap()
+				m.Set(key, append(v, t))
+				return t, nil
+			}
+			m = t
+		default:
+			return nil, p.errorf("key %s is already defined", key)
+		}
+	}
+	return m, nil
+}
+
+// assign sets a dotted key in a table
+func (p *tomlParser) assign(m *Map, path []string, value interface{}) error {
+	for _, key := range path[:len(path)-1] {
+		existing, ok := m.Get(key)
+		if !ok {
+			t := NewMap()
+			m.Set(key, t)
+			m = t
+			continue
+		}
+		t, isTable := existing.(*Map)
+		if !isTable {
+			return p.errorf("key %s is already defined", key)
+		}
+		m = t
+	}
+	key := path[len(path)-1]
+	if _, dup := m.Get(key); dup {
+		return p.errorf("key %s is already defined", strings.Join(path, "."))
+	}
+	m.Set(key, value)
+	return nil
+}
+
+// keyPath reads a dotted key
+func (p *tomlParser) keyPath() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace(false)
+		var key string
+		switch p.peek() {
+		case '"':
+			s, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && isBareKeyChar(p.data[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			key = string(p.data[start:p.pos])
+		}
+		path = append(path, key)
+		p.skipSpace(false)
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+// value reads a value
+func (p *tomlParser) value() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(string(p.data[p.pos:]), `"""`) {
+			return p.multilineString('"')
+		}
+		return p.basicString()
+	case c == '\'':
+		if strings.HasPrefix(string(p.data[p.pos:]), `'''`) {
+			return p.multilineString('\'')
+		}
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	default:
+		return p.scalar()
+	}
+}
+
+// array reads [v, v, ...], which may span lines and hold comments
+func (p *tomlParser) array() (interface{}, error) {
+	p.pos++
+	list := []interface{}{}
+	for {
+		p.skipSpace(true)
+		if p.peek() == ']' {
+			p.pos++
+			return list, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		p.skipSpace(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable reads { k = v, ... } on one line
+func (p *tomlParser) inlineTable() (interface{}, error) {
+	p.pos++
+	m := NewMap()
+	p.skipSpace(false)
+	if p.peek() == '}' {
+		p.pos++
+		return m, nil
+	}
+	for {
+		path, err := p.keyPath()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if p.peek() != '=' {
+			return nil, p.errorf("expected = in inline table")
+		}
+		p.pos++
+		p.skipSpace(false)
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.assign(m, path, v); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return m, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// scalar reads a boolean, number, or date/time
+func (p *tomlParser) scalar() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.data) && !strings.ContainsRune(",]}#\r\n", rune(p.data[p.pos])) {
+		p.pos++
+	}
+	text := strings.TrimSpace(string(p.data[start:p.pos]))
+	// A space separates a date from a time; anything else ends the value
+	if i := strings.IndexAny(text, " \t"); i >= 0 && !isDateTime(text) {
+		p.pos = start + i
+		text = text[:i]
+	}
+
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	case "":
+		return nil, p.errorf("expected a value")
+	}
+	if isDateTime(text) {
+		return text, nil
+	}
+	digits := strings.ReplaceAll(text, "_", "")
+	if n, err := strconv.ParseInt(digits, 0, 64); err == nil {
+		if unsigned := strings.TrimLeft(digits, "+-"); len(unsigned) > 1 && unsigned[0] == '0' && unsigned[1] >= '0' && unsigned[1] <= '9' {
+			return nil, p.errorf("leading zeros are not allowed in %s", text)
+		}
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(digits, 64); err == nil {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %s (strings need quotes)", text)
+}
+
+// isDateTime reports whether text looks like a TOML date or time
+func isDateTime(text string) bool {
+	return len(text) >= 8 && (text[4] == '-' && text[7] == '-' || text[2] == ':')
+}
+
+// basicString reads "..." with escapes
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// literalString reads '...' as is
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++
+	end := bytes.IndexAny(p.data[p.pos:], "'\n")
+	if end < 0 || p.data[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := string(p.data[p.pos : p.pos+end])
+	p.pos += end + 1
+	return s, nil
+}
+
+// multilineString reads a multi-line basic string (with escapes) or literal
+// string (as is). A newline right after the opening quotes is dropped.
+func (p *tomlParser) multilineString(quote byte) (string, error) {
+	delim := strings.Repeat(string(quote), 3)
+	p.pos += 3
+	if strings.HasPrefix(string(p.data[p.pos:]), "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if p.peek() == '\n' {
+		p.pos++
+		p.line++
+	}
+
+	var b strings.Builder
+	for p.pos < len(p.data) {
+		if strings.HasPrefix(string(p.data[p.pos:]), delim) {
+			p.pos += 3
+			// Up to two more quotes may end the content
+			for i := 0; i < 2 && p.peek() == quote; i++ {
+				b.WriteByte(quote)
+				p.pos++
+			}
+			return b.String(), nil
+		}
+		c := p.data[p.pos]
+		if c == '\\' && quote == '"' {
+			// A backslash at the end of a line trims the line break and
+			// the whitespace after it
+			rest := strings.TrimLeft(string(p.data[p.pos+1:]), " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.pos++
+				for p.pos < len(p.data) && strings.ContainsRune(" \t\r\n", rune(p.data[p.pos])) {
+					if p.data[p.pos] == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if c == '\n' {
+			p.line++
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", p.errorf("unterminated multi-line string")
+}
+
+// escape reads a backslash escape into b
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.pos+1 >= len(p.data) {
+		return p.errorf("unterminated escape")
+	}
+	c := p.data[p.pos+1]
+	p.pos += 2
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.data) {
+			return p.errorf("short unicode escape")
+		}
+		n, err := strconv.ParseUint(string(p.data[p.pos:p.pos+size]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return p.errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(n))
+		p.pos += size
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// skipSpace moves past spaces, tabs, and comments, and past line breaks
+// when newlines is set
+func (p *tomlParser) skipSpace(newlines bool) {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		case newlines && (c == '\n' || c == '\r'):
+			if c == '\n' {
+				p.line++
+			}
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the current byte, or 0 at the end
+func (p *tomlParser) peek() byte {
+	if p.pos >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// errorf reports an error at the current line
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// isBareKeyChar reports whether c may appear in an unquoted key
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// writeTOMLTable writes a table's plain keys, then its subtables and arrays
+// of tables under their headers. TOML has no null, so null values are left
+// out.
+func writeTOMLTable(w io.Writer, m *Map, path []string) error {
+	var tables, arrays []string
+	for _, key := range m.Keys() {
+		switch v := m.values[key].(type) {
+		case nil:
+			continue
+		case *Map:
+			if len(v.Keys()) > 0 {
+				tables = append(tables, key)
+				continue
+			}
+		case []interface{}:
+			if isTableArray(v) {
+				arrays = append(arrays, key)
+				continue
+			}
+		}
+		value, err := tomlValue(m.values[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, key), "."), err)
+		}
+		fmt.Fprintf(w, "%s = %s\n", tomlKey(key), value)
+	}
+
+	for _, key := range tables {
+		sub := append(append([]string{}, path...), key)
+		fmt.Fprintf(w, "\n[%s]\n", tomlPath(sub))
+		if err := writeTOMLTable(w, m.values[key].(*Map), sub); err != nil {
+			return err
+		}
+	}
+	for _, key := range arrays {
+		sub := append(append([]string{}, path...), key)
+		for _, item := range m.values[key].([]interface{}) {
+			fmt.Fprintf(w, "\n[[%s]]\n", tomlPath(sub))
+			if err := writeTOMLTable(w, item.(*Map), sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTableArray reports whether a list holds only tables, so it can be
+// written as [[name]] sections
+func isTableArray(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(*Map); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlValue formats an inline value
+func tomlValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.Encode(v)
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		case math.IsNaN(v):
+			return "nan", nil
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if item == nil {
+				return "", fmt.Errorf("TOML arrays cannot hold null")
+			}
+			s, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case *Map:
+		items := make([]string, 0, len(v.Keys()))
+		for _, key := range v.Keys() {
+			if v.values[key] == nil {
+				continue
+			}
+			s, err := tomlValue(v.values[key])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(key)+" = "+s)
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	default:
+		return "", fmt.Errorf("cannot write %T as TOML", value)
+	}
+}
+
+// tomlKey quotes a key unless it is bare
+func tomlKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	s, _ := tomlValue(key)
+	return s
+}
+
+// tomlPath formats a table header's dotted path
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"devos/internal/config"
+	"devos/internal/policy"
+)
+
+// undoRule gives the command compensating commands that match a pattern;
+// $1 and ${name} in it expand to the pattern's groups
+type undoRule struct {
+	match *regexp.Regexp
+	undo  string
+}
+
+// builtinUndoRules compensate common commands that are safe to reverse
+// without knowing what was there before; the configured undo_rules are
+// tried first
+var builtinUndoRules = []undoRule{
+	{regexp.MustCompile(`^kubectl (?:apply|create) (-f \S+)$`), "kubectl delete --ignore-not-found $1"},
+	{regexp.MustCompile(`^kubectl create (namespace|ns) (\S+)$`), "kubectl delete $1 $2"},
+	{regexp.MustCompile(`^helm install (\S+) \S+`), "helm uninstall $1"},
+	{regexp.MustCompile(`^docker run\b.* --name[= ](\S+)`), "docker rm -f $1"},
+	{regexp.MustCompile(`^docker (network|volume) create (\S+)$`), "docker $1 rm $2"},
+	{regexp.MustCompile(`^docker(?: |-)compose up\b`), "docker compose down"},
+	{regexp.MustCompile(`^git (?:checkout -b|switch -c) (\S+)$`), "git checkout - && git branch -D $1"},
+	{regexp.MustCompile(`^git tag (\S+)$`), "git tag -d $1"},
+	{regexp.MustCompile(`^git commit\b`), "git reset --soft HEAD~1"},
+	{regexp.MustCompile(`^git stash$`), "git stash pop"},
+	{regexp.MustCompile(`^mkdir (\S+)$`), "rmdir $1"},
+}
+
+// newUndoRules compiles the configured undo rules, ahead of the built-in ones
+func newUndoRules(cfg *config.Config) ([]undoRule, error) {
+	var rules []undoRule
+	for _, rule := range cfg.UndoRules {
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("%w: undo rule %q: %w", config.ErrInvalidConfig, rule.Match, err)
+		}
+		rules = append(rules, undoRule{match: match, undo: rule.Undo})
+	}
+	return append(rules, builtinUndoRules...), nil
+}
+
+// compensation returns the command that reverses step i of a plan: the
+// plan's own undo command for it, or the first undo rule matching it. It
+// is empty when the step has none.
+func (e *Executor) compensation(result *ExecutionResult, i int) string {
+	if i < len(result.Undo) && result.Undo[i] != "" {
+		return result.Undo[i]
+	}
+	cmd := result.Commands[i]
+	for _, rule := range e.undoRules {
+		if m := rule.match.FindStringSubmatchIndex(cmd); m != nil {
+			return string(rule.match.ExpandString(nil, rule.undo, cmd, m))
+		}
+	}
+	return ""
+}
+
+// compensate returns the commands that reverse the first completed steps
+// of a plan, last step first, and the completed steps that change things
+// but have no compensating command. Package changes are left to the
+// package rollback, and cd to the user.
+func (e *Executor) compensate(result *ExecutionResult, completed int) (undo, irreversible []string) {
+	for i := completed - 1; i >= 0; i-- {
+		cmd := result.Commands[i]
+		if c := e.compensation(result, i); c != "" {
+			undo = append(undo, c)
+			continue
+		}
+		if !policy.IsReadOnly(cmd) && len(trackedManagers([]string{cmd})) == 0 && !cdPrograms[program(cmd)] {
+			irreversible = append(irreversible, cmd)
+		}
+	}
+	return undo, irreversible
+}
+
+// checkUndo drops, with a warning, compensating commands that are not one
+// per command, span lines, or fail the security checks, so none run
+// unchecked when a plan is rolled back
+func (e *Executor) checkUndo(result *ExecutionResult) {
+	if len(result.Undo) == 0 {
+		return
+	}
+	if len(result.Undo) != len(result.Commands) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Ignoring the plan's undo commands: %d for %d commands", len(result.Undo), len(result.Commands)))
+		result.Undo = nil
+		return
+	}
+	for i, cmd := range result.Undo {
+		if cmd == "" {
+			continue
+		}
+		if strings.Contains(cmd, "\n") {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Step %d cannot be rolled back: its undo command spans several lines", i+1))
+			result.Undo[i] = ""
+			continue
+		}
+		if err := e.Validate([]string{cmd}); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Step %d cannot be rolled back: %v", i+1, err))
+			result.Undo[i] = ""
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"devos/internal/timefmt"
+)
+
+// PolicyTrigger is one time a policy rule blocked a command or held it for
+// confirmation. Triggers stay on this machine; they exist so users can see
+// which rules get in their way.
+type PolicyTrigger struct {
+	Rule      string    `json:"rule"`   // Approval rule name, or "sandbox" for the built-in checks
+	Action    string    `json:"action"` // deny or ask
+	Detail    string    `json:"detail"` // What matched, e.g. the blocked pattern
+	Command   string    `json:"command"`
+	Project   string    `json:"project"` // Project root the command was planned in
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordPolicyTrigger stores a policy trigger
+func (s *Store) RecordPolicyTrigger(t PolicyTrigger) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = timefmt.Now()
+	}
+
+	_, err := s.exec(
+		`INSERT INTO policy_triggers (rule, action, detail, command, project, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		t.Rule, t.Action, t.Detail, t.Command, t.Project, t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record policy trigger: %w", err)
+	}
+
+	return nil
+}
+
+// PolicyTriggersSince returns the triggers recorded after the given time,
+// oldest first
+func (s *Store) PolicyTriggersSince(since time.Time) ([]PolicyTrigger, error) {
+	rows, err := s.query(
+		`SELECT rule, action, detail, command, project, created_at
+		FROM policy_triggers WHERE created_at >= ? ORDER BY id`,
+		since.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var triggers []PolicyTrigger
+	for rows.Next() {
+		var t PolicyTrigger
+		if err := rows.Scan(&t.Rule, &t.Action, &t.Detail, &t.Command, &t.Project, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read policy trigger: %w", err)
+		}
+		triggers = append(triggers, t)
+	}
+
+	return triggers, rows.Err()
+}
@@ -0,0 +1,183 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix starts every sealed value, followed by the key source and the
+// base64 of the salt (passphrase keys only), the nonce, and the AES-GCM
+// ciphertext
+const Prefix = "devos-vault:v1:"
+
+// Key sources
+const (
+	SourceKeychain   = "keychain"
+	SourcePassphrase = "passphrase"
+)
+
+// PassphraseEnv names the environment variable read for the passphrase
+// when config sections are sealed with one
+const PassphraseEnv = "DEVOS_CONFIG_PASSPHRASE"
+
+// KDFIterations is the PBKDF2-HMAC-SHA256 work factor for passphrases
+const KDFIterations = 600000
+
+// ErrBadKey is returned when a sealed value cannot be decrypted
+var ErrBadKey = errors.New("wrong key or passphrase, or corrupted data")
+
+// ErrNoPassphrase is returned when a value sealed with a passphrase is
+// opened without one
+var ErrNoPassphrase = fmt.Errorf("sealed with a passphrase; set %s to unlock it", PassphraseEnv)
+
+// Key encrypts and decrypts sealed values, with a random key kept in the OS
+// keychain or one derived from a passphrase
+type Key struct {
+	Source     string
+	secret     []byte // keychain key
+	passphrase string
+}
+
+// PassphraseKey returns a key derived from a passphrase
+func PassphraseKey(passphrase string) *Key {
+	return &Key{Source: SourcePassphrase, passphrase: passphrase}
+}
+
+// EnvKey returns the passphrase key from DEVOS_CONFIG_PASSPHRASE, or nil
+// when it is not set
+func EnvKey() *Key {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return PassphraseKey(passphrase)
+	}
+	return nil
+}
+
+// Source returns the key source a sealed value needs, or "" when the value
+// is not sealed
+func Source(sealed string) string {
+	rest, ok := strings.CutPrefix(sealed, Prefix)
+	if !ok {
+		return ""
+	}
+	source, _, _ := strings.Cut(rest, ":")
+	return source
+}
+
+// KeyFor returns the key that opens a sealed value: the keychain key, or
+// the passphrase from DEVOS_CONFIG_PASSPHRASE
+func KeyFor(sealed string) (*Key, error) {
+	switch Source(sealed) {
+	case SourceKeychain:
+		return KeychainKey(false)
+	case SourcePassphrase:
+		if key := EnvKey(); key != nil {
+			return key, nil
+		}
+		return nil, ErrNoPassphrase
+	default:
+		return nil, fmt.Errorf("not a sealed value")
+	}
+}
+
+// Seal encrypts data
+func (k *Key) Seal(data []byte) (string, error) {
+	var salt []byte
+	if k.Source == SourcePassphrase {
+		if k.passphrase == "" {
+			return "", fmt.Errorf("a passphrase is required")
+		}
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+	}
+	gcm, err := k.gcm(salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, data, []byte(Prefix+k.Source))
+	return Prefix + k.Source + ":" + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a value produced by Seal
+func (k *Key) Open(sealed string) ([]byte, error) {
+	if source := Source(sealed); source != k.Source {
+		return nil, fmt.Errorf("value is sealed with a %s key, not a %s key", source, k.Source)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, Prefix+k.Source+":"))
+	if err != nil {
+		return nil, ErrBadKey
+	}
+	var salt []byte
+	if k.Source == SourcePassphrase {
+		if len(raw) < 16 {
+			return nil, ErrBadKey
+		}
+		salt, raw = raw[:16], raw[16:]
+	}
+	gcm, err := k.gcm(salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, ErrBadKey
+	}
+	data, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], []byte(Prefix+k.Source))
+	if err != nil {
+		return nil, ErrBadKey
+	}
+	return data, nil
+}
+
+// gcm returns AES-256-GCM under the key
+func (k *Key) gcm(salt []byte) (cipher.AEAD, error) {
+	secret := k.secret
+	if k.Source == SourcePassphrase {
+		secret = DeriveKey(k.passphrase, salt)
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// DeriveKey derives a 256-bit key from a passphrase with
+// PBKDF2-HMAC-SHA256 (RFC 8018)
+func DeriveKey(passphrase string, salt []byte) []byte {
+	const keyLen = 32
+	prf := hmac.New(sha256.New, []byte(passphrase))
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < KDFIterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
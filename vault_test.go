@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	// Vectors computed independently with Python's hashlib.pbkdf2_hmac
+	// ("sha256", passphrase, salt, KDFIterations)
+	tests := []struct {
+		passphrase string
+		salt       []byte
+		want       string
+	}{
+		{"password", []byte("salt"), "669cfe52482116fda1aa2cbe409b2f56c8e4563752b7a28f6eaab614ee005178"},
+		{"correct horse battery staple", []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, "ef177144eec9420cbc1093d2a8b344a92bc506d0d4ec9c028dd19f8324d8c1e6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.passphrase, func(t *testing.T) {
+			if got := hex.EncodeToString(DeriveKey(tt.passphrase, tt.salt)); got != tt.want {
+				t.Errorf("DeriveKey(%q, %x) = %s, want %s", tt.passphrase, tt.salt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	key := PassphraseKey("s3cret")
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"text", "api_key: sk-123"},
+		{"json", `{"token":"abc","headers":{"Authorization":"Bearer x"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := key.Seal([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(sealed, Prefix+SourcePassphrase+":") || Source(sealed) != SourcePassphrase {
+				t.Fatalf("sealed value %q does not name its key source", sealed)
+			}
+			if tt.data != "" && strings.Contains(sealed, tt.data) {
+				t.Fatalf("sealed value %q contains the plaintext", sealed)
+			}
+			got, err := key.Open(sealed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.data {
+				t.Errorf("Open(Seal(%q)) = %q", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	key := PassphraseKey("s3cret")
+	sealed, err := key.Seal([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, Prefix+SourcePassphrase+":"))
+	raw[len(raw)-1] ^= 1
+	tampered := Prefix + SourcePassphrase + ":" + base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name   string
+		key    *Key
+		sealed string
+		want   error
+	}{
+		{"wrong passphrase", PassphraseKey("guess"), sealed, ErrBadKey},
+		{"tampered", key, tampered, ErrBadKey},
+		{"truncated", key, Prefix + SourcePassphrase + ":AAAA", ErrBadKey},
+		{"not base64", key, Prefix + SourcePassphrase + ":!!!", ErrBadKey},
+		{"other source", &Key{Source: SourceKeychain, secret: make([]byte, 32)}, sealed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.key.Open(tt.sealed)
+			if err == nil {
+				t.Fatal("Open succeeded")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Open error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealWithoutPassphrase(t *testing.T) {
+	if _, err := PassphraseKey("").Seal([]byte("value")); err == nil {
+		t.Error("Seal with an empty passphrase succeeded")
+	}
+}
+
+func TestKeyFor(t *testing.T) {
+	sealed, err := PassphraseKey("s3cret").Seal([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(PassphraseEnv, "")
+	if _, err := KeyFor(sealed); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("KeyFor without %s = %v, want ErrNoPassphrase", PassphraseEnv, err)
+	}
+	t.Setenv(PassphraseEnv, "s3cret")
+	key, err := KeyFor(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := key.Open(sealed); err != nil || string(got) != "value" {
+		t.Errorf("Open with the key from %s = %q, %v", PassphraseEnv, got, err)
+	}
+	if _, err := KeyFor("plain text"); err == nil {
+		t.Error("KeyFor accepted a value that is not sealed")
+	}
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// cdSeparator splits a command into the steps of a chain that runs in one
+// shell. Pipelines, alternatives (||), and subshells run cd elsewhere, so
+// commands with them are not followed.
+var cdSeparator = regexp.MustCompile(`&&|;`)
+
+// cdPrograms change the shell's directory, in sh and PowerShell
+var cdPrograms = map[string]bool{"cd": true, "chdir": true, "set-location": true, "sl": true}
+
+// Chdir changes the session's working directory, where later commands run,
+// as cd does. The session's directory is the process's, so project
+// detection, locks, and the context sent to the AI engine follow it. It
+// returns the new directory.
+func (e *Executor) Chdir(dir string) (string, error) {
+	cwd, _ := os.Getwd()
+	target, err := e.cdPath(cwd, dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chdir(target); err != nil {
+		return "", fmt.Errorf("cd: %w", err)
+	}
+	e.prevDir = cwd
+	e.logger.Info("Working directory is now %s", target)
+	return target, nil
+}
+
+// cdPath returns the directory cd in from changes to: "" and "~" are the
+// home directory, and "-" the previous one
+func (e *Executor) cdPath(from, dir string) (string, error) {
+	switch {
+	case dir == "-":
+		if e.prevDir == "" {
+			return "", fmt.Errorf("cd: no previous directory")
+		}
+		return e.prevDir, nil
+	case dir == "" || dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, `~\`):
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cd: %w", err)
+		}
+		return filepath.Join(home, strings.TrimPrefix(dir, "~")), nil
+	case filepath.IsAbs(dir):
+		return filepath.Clean(dir), nil
+	default:
+		return filepath.Join(from, dir), nil
+	}
+}
+
+// stepDir returns the directory a cd step run in from changes to, which
+// must exist, for commands that follow cd without changing the session's
+func (e *Executor) stepDir(from string, step Command) (string, error) {
+	target := ""
+	if n := len(step.Args); n > 0 {
+		target = step.Args[n-1]
+	}
+	dir, err := e.cdPath(from, target)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("cd: %w", err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("cd: not a directory: %s", dir)
+	}
+	return dir, nil
+}
+
+// followCd changes the session's directory as a command that succeeded
+// changed its shell's, so the next command runs where it left off
+func (e *Executor) followCd(cmdStr string) {
+	for _, target := range cdTargets(cmdStr) {
+		dir, err := e.Chdir(target)
+		if err != nil {
+			e.logger.Warn("Not following %q: %v", cmdStr, err)
+			return
+		}
+		fmt.Printf("  📂 Now in %s\n", dir)
+	}
+}
+
+// shellDir returns the directory a shell that ran a command in dir ends up
+// in, for commands run outside the session's directory. "cd -" is not
+// followed there.
+func (e *Executor) shellDir(dir, cmdStr string) string {
+	for _, target := range cdTargets(cmdStr) {
+		if target == "-" {
+			continue
+		}
+		if next, err := e.cdPath(dir, target); err == nil {
+			dir = next
+		}
+	}
+	return dir
+}
+
+// cdTargets returns the directories a command changes to, in order. It is
+// empty when the command does not change directory, or does so in a way
+// that cannot be followed, e.g. to a directory named by a variable.
+func cdTargets(cmdStr string) []string {
+	if strings.ContainsAny(cmdStr, "|()`\n") || strings.Contains(cmdStr, "$") {
+		return nil
+	}
+	var targets []string
+	for _, segment := range cdSeparator.Split(cmdStr, -1) {
+		segment = strings.TrimSpace(segment)
+		program, rest, _ := strings.Cut(segment, " ")
+		if !cdPrograms[strings.ToLower(program)] {
+			continue
+		}
+		target := strings.TrimSpace(rest)
+		// Flags such as -P and -L change how links resolve, not where to
+		for strings.HasPrefix(target, "-") && len(target) > 1 {
+			_, target, _ = strings.Cut(target, " ")
+			target = strings.TrimSpace(target)
+		}
+		if unquoted, ok := unquote(target); ok {
+			target = unquoted
+		} else if strings.ContainsAny(target, ` "'*?`) {
+			return nil
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// unquote strips matching single or double quotes around s
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	return s, false
+}
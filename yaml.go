@@ -0,0 +1,519 @@
+package configfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document with its indentation measured
+type yamlLine struct {
+	number int    // 1-based, for errors
+	indent int    // Leading spaces
+	text   string // Without indentation or trailing comment
+	raw    string // As written, for block scalars
+}
+
+// yamlParser reads the block structure of a YAML document. It supports the
+// subset configs use: nested mappings and sequences, flow collections on
+// one line, quoted and plain scalars, literal (|) and folded (>) blocks,
+// and comments. Anchors, tags, and multiple documents are not supported.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML reads a YAML document whose top level is a mapping
+func parseYAML(data []byte) (*Map, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.Contains(raw, "\t") && strings.TrimLeft(raw, " ") != strings.TrimLeft(raw, " \t") {
+			return nil, fmt.Errorf("yaml line %d: tabs cannot indent YAML", i+1)
+		}
+		text := strings.TrimLeft(raw, " ")
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: len(raw) - len(text), text: stripYAMLComment(text), raw: raw})
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) && p.lines[p.pos].text == "---" {
+		p.pos++
+		p.skipBlank()
+	}
+	if p.pos >= len(p.lines) {
+		return NewMap(), nil
+	}
+	line := p.lines[p.pos]
+	if isSequenceItem(line.text) {
+		return nil, fmt.Errorf("yaml line %d: config must be a mapping, not a list", line.number)
+	}
+	doc, err := p.mapping(line.indent)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return doc, nil
+}
+
+// skipBlank moves past empty and comment-only lines
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].text == "" {
+		p.pos++
+	}
+}
+
+// block reads the mapping or sequence starting at the next line, which must
+// be indented more than parent; a sequence may also sit at parent's indent
+func (p *yamlParser) block(parent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	line := p.lines[p.pos]
+	switch {
+	case line.indent > parent && isSequenceItem(line.text):
+		return p.sequence(line.indent)
+	case line.indent > parent:
+		return p.mapping(line.indent)
+	case line.indent == parent && isSequenceItem(line.text):
+		return p.sequence(line.indent)
+	default:
+		return nil, nil
+	}
+}
+
+// mapping reads "key: value" lines at the given indentation
+func (p *yamlParser) mapping(indent int) (*Map, error) {
+	m := NewMap()
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) || p.lines[p.pos].indent < indent {
+			return m, nil
+		}
+		line := p.lines[p.pos]
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml line %d: unexpected indentation", line.number)
+		}
+		if isSequenceItem(line.text) {
+			return nil, fmt.Errorf("yaml line %d: list item where a key was expected", line.number)
+		}
+
+		key, rest, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m.Get(key); dup {
+			return nil, fmt.Errorf("yaml line %d: duplicate key %q", line.number, key)
+		}
+		p.pos++
+
+		value, err := p.value(line, indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(key, value)
+	}
+}
+
+// sequence reads "- item" lines at the given indentation
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	list := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) || p.lines[p.pos].indent != indent || !isSequenceItem(p.lines[p.pos].text) {
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].number)
+			}
+			return list, nil
+		}
+		line := p.lines[p.pos]
+		rest := strings.TrimPrefix(line.text, "-")
+		item := strings.TrimLeft(rest, " ")
+
+		// "- key: value" starts a mapping indented to where the key is
+		if item != "" && !strings.HasPrefix(item, "[") && !strings.HasPrefix(item, "{") && !isQuoted(item) {
+			if _, _, err := splitYAMLKey(yamlLine{text: item}); err == nil {
+				p.lines[p.pos] = yamlLine{
+					number: line.number,
+					indent: indent + 1 + len(rest) - len(item),
+					text:   item,
+					raw:    line.raw,
+				}
+				m, err := p.mapping(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, m)
+				continue
+			}
+		}
+
+		p.pos++
+		value, err := p.value(line, indent, item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+}
+
+// value reads what follows a key or list marker: a scalar or flow
+// collection on the same line, a block scalar, or a nested block
+func (p *yamlParser) value(line yamlLine, indent int, rest string) (interface{}, error) {
+	switch {
+	case rest == "":
+		return p.block(indent)
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		return p.blockScalar(indent, rest), nil
+	case strings.HasPrefix(rest, "&") || strings.HasPrefix(rest, "*") || strings.HasPrefix(rest, "!"):
+		return nil, fmt.Errorf("yaml line %d: anchors, aliases, and tags are not supported", line.number)
+	}
+	v, err := parseYAMLFlow(rest)
+	if err != nil {
+		return nil, fmt.Errorf("yaml line %d: %w", line.number, err)
+	}
+	return v, nil
+}
+
+// blockScalar reads a literal (|) or folded (>) block indented below indent
+func (p *yamlParser) blockScalar(indent int, header string) string {
+	folded := strings.HasPrefix(header, ">")
+	chomp := strings.TrimLeft(header[1:], "0123456789")
+
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line.raw) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if line.indent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = line.indent
+		}
+		lines = append(lines, line.raw[min(blockIndent, line.indent):])
+		p.pos++
+	}
+
+	// Trailing blank lines belong to the block only with "+"
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, line := range lines {
+			switch {
+			case i == 0:
+			case line == "" || lines[i-1] == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+	switch {
+	case strings.HasPrefix(chomp, "-"):
+	case strings.HasPrefix(chomp, "+"):
+		text += "\n" + strings.Repeat("\n", trailing)
+	case text != "":
+		text += "\n"
+	}
+	return text
+}
+
+// isSequenceItem reports whether a line starts a list item
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isQuoted reports whether text starts with a quote
+func isQuoted(text string) bool {
+	return strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'")
+}
+
+// splitYAMLKey splits "key: rest" at the first colon followed by a space or
+// the end of the line, outside quotes
+func splitYAMLKey(line yamlLine) (string, string, error) {
+	text := line.text
+	if isQuoted(text) {
+		end := closingQuote(text)
+		if end < 0 || !strings.HasPrefix(text[end+1:], ":") {
+			return "", "", fmt.Errorf("yaml line %d: expected a key", line.number)
+		}
+		key, err := parseYAMLScalar(text[:end+1])
+		if err != nil {
+			return "", "", fmt.Errorf("yaml line %d: %w", line.number, err)
+		}
+		return fmt.Sprint(key), strings.TrimSpace(text[end+2:]), nil
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), nil
+		}
+	}
+	return "", "", fmt.Errorf("yaml line %d: expected \"key: value\"", line.number)
+}
+
+// closingQuote returns the index of the quote that closes the string text
+// starts with, or -1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment removes a trailing comment: a # at the start of the text
+// or after whitespace, outside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" \t[{,:-", rune(text[i-1]))):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+	return strings.TrimRight(text, " \t")
+}
+
+// parseYAMLFlow parses a scalar or a flow collection ([a, b] or {k: v})
+func parseYAMLFlow(text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated list %s (flow lists must fit on one line)", text)
+		}
+		list := []interface{}{}
+		items, err := splitFlow(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			v, err := parseYAMLFlow(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("unterminated mapping %s (flow mappings must fit on one line)", text)
+		}
+		m := NewMap()
+		items, err := splitFlow(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			key, rest, err := splitYAMLKey(yamlLine{text: item})
+			if err != nil {
+				return nil, fmt.Errorf("expected \"key: value\" in %s", text)
+			}
+			v, err := parseYAMLFlow(rest)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, v)
+		}
+		return m, nil
+	default:
+		return parseYAMLScalar(text)
+	}
+}
+
+// splitFlow splits the inside of a flow collection at top-level commas
+func splitFlow(text string) ([]string, error) {
+	var items []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, fmt.Errorf("unbalanced quotes or brackets in [%s]", text)
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items, nil
+}
+
+// parseYAMLScalar resolves a scalar: quoted strings, null, booleans,
+// numbers, and otherwise plain strings
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated or trailing text after string %s", text)
+		}
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return nil, fmt.Errorf("invalid string %s", text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated or trailing text after string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case ".inf", "+.inf":
+		return math.Inf(1), nil
+	case "-.inf":
+		return math.Inf(-1), nil
+	}
+	if n, err := strconv.ParseInt(text, 0, 64); err == nil && !strings.HasPrefix(strings.TrimLeft(text, "+-"), "0") || text == "0" {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !strings.ContainsAny(text, "xX_") {
+		return f, nil
+	}
+	return text, nil
+}
+
+// writeYAMLMap writes a mapping with each key at indent
+func writeYAMLMap(w io.Writer, m *Map, indent string) {
+	for i, key := range m.Keys() {
+		// The first key of a list item shares the marker's line
+		prefix := indent
+		if i > 0 && strings.HasSuffix(indent, "- ") {
+			prefix = strings.Repeat(" ", len(indent))
+		}
+		writeYAMLEntry(w, prefix, yamlString(key)+":", m.values[key], strings.Repeat(" ", len(indent)))
+	}
+}
+
+// writeYAMLEntry writes "label value", nesting collections below the label
+func writeYAMLEntry(w io.Writer, prefix, label string, value interface{}, indent string) {
+	switch v := value.(type) {
+	case *Map:
+		if len(v.Keys()) == 0 {
+			fmt.Fprintf(w, "%s%s {}\n", prefix, label)
+			return
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, label)
+		writeYAMLMap(w, v, indent+"  ")
+	case []interface{}:
+		if len(v) == 0 {
+			fmt.Fprintf(w, "%s%s []\n", prefix, label)
+			return
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, label)
+		for _, item := range v {
+			switch item := item.(type) {
+			case *Map:
+				if len(item.Keys()) == 0 {
+					fmt.Fprintf(w, "%s  - {}\n", indent)
+					continue
+				}
+				writeYAMLMap(w, item, indent+"  - ")
+			case []interface{}:
+				flow, _ := json.Marshal(item)
+				fmt.Fprintf(w, "%s  - %s\n", indent, flow)
+			default:
+				fmt.Fprintf(w, "%s  - %s\n", indent, yamlScalarValue(item))
+			}
+		}
+	default:
+		fmt.Fprintf(w, "%s%s %s\n", prefix, label, yamlScalarValue(v))
+	}
+}
+
+// yamlScalarValue formats a scalar
+func yamlScalarValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// yamlString writes a string plainly when YAML reads it back as the same
+// string, and double-quoted otherwise
+func yamlString(s string) string {
+	plain := s != "" &&
+		!strings.ContainsAny(s, ":#\n\t\"'{}[],&*!|>%@`\\") &&
+		strings.TrimSpace(s) == s &&
+		!strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "?")
+	if plain {
+		if v, _ := parseYAMLScalar(s); v != s {
+			plain = false
+		}
+	}
+	if plain {
+		return s
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}